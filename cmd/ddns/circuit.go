@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// startRound closes the circuit breaker of the handle, if any, so that each
+// round of updating gets a fresh chance to call the API. It returns the pretty
+// printer for the round, which hides the errors of the calls skipped once the
+// breaker opens; [reportOpenCircuit] summarizes them instead.
+func startRound(ppfmt pp.PP, h api.Handle) pp.PP {
+	breaker, ok := h.(api.CircuitBreaker)
+	if !ok {
+		return ppfmt
+	}
+	breaker.ResetCircuit()
+	return api.HideSkippedCalls(ppfmt)
+}
+
+// reportOpenCircuit replaces the message of a round with one failure when the
+// circuit breaker of the handle opened during the round.
+func reportOpenCircuit(ppfmt pp.PP, h api.Handle, msg updater.Message, failureKind notifier.Kind) updater.Message {
+	breaker, ok := h.(api.CircuitBreaker)
+	if !ok {
+		return msg
+	}
+	skipped, open := breaker.CircuitOpen()
+	if !open {
+		return msg
+	}
+
+	ppfmt.Noticef(pp.EmojiError,
+		"Stopped calling the Cloudflare API for the rest of this round after repeated server errors (%d calls skipped)",
+		skipped)
+	return updater.ReportCircuitOpen(msg, failureKind, skipped)
+}
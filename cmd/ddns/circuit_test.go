@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// fakeBreaker is a handle with a circuit breaker in a fixed state.
+type fakeBreaker struct {
+	*mocks.MockHandle
+	skipped int
+	open    bool
+	resets  int
+}
+
+func (b *fakeBreaker) ResetCircuit()            { b.resets++ }
+func (b *fakeBreaker) CircuitOpen() (int, bool) { return b.skipped, b.open }
+
+func TestStartRound(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	breaker := &fakeBreaker{MockHandle: mocks.NewMockHandle(mockCtrl), skipped: 0, open: false, resets: 0}
+	mockPP := mocks.NewMockPP(mockCtrl)
+	require.NotEqual(t, pp.PP(mockPP), startRound(mockPP, breaker))
+	require.Equal(t, 1, breaker.resets)
+
+	// Handles without circuit breakers are left alone.
+	require.Equal(t, pp.PP(mockPP), startRound(mockPP, mocks.NewMockHandle(mockCtrl)))
+}
+
+func TestReportOpenCircuit(t *testing.T) {
+	t.Parallel()
+
+	msg := updater.Message{
+		HeartbeatMessage: heartbeat.NewMessagef(false, "Failed to set A records for example.org"),
+		NotifierMessage:  notifier.NewMessagef("Could not confirm that A records of example.org were updated."),
+		NotificationKind: notifier.KindUpdateFailure,
+		Report:           nil,
+	}
+
+	for name, tc := range map[string]struct {
+		open          bool
+		prepareMockPP func(*mocks.MockPP)
+		expected      updater.Message
+	}{
+		"closed": {false, nil, msg},
+		"open": {
+			true,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiError,
+					"Stopped calling the Cloudflare API for the rest of this round after repeated server errors (%d calls skipped)",
+					4)
+			},
+			updater.ReportCircuitOpen(msg, notifier.KindUpdateFailure, 4),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			breaker := &fakeBreaker{MockHandle: mocks.NewMockHandle(mockCtrl), skipped: 4, open: tc.open, resets: 0}
+			require.Equal(t, tc.expected, reportOpenCircuit(mockPP, breaker, msg, notifier.KindUpdateFailure))
+		})
+	}
+
+	mockCtrl := gomock.NewController(t)
+	require.Equal(t, msg, reportOpenCircuit(mocks.NewMockPP(mockCtrl), mocks.NewMockHandle(mockCtrl), msg,
+		notifier.KindUpdateFailure))
+}
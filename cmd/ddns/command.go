@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// Exit codes of the subcommands. When several profiles are selected, the
+// largest code among them is used.
+const (
+	exitOK            = 0 // the command succeeded
+	exitFailure       = 1 // the configuration is valid, but some operation failed
+	exitUsage         = 2 // the command line is invalid
+	exitConfigFailure = 3 // the configuration is invalid
+)
+
+// A command is a subcommand of the updater. It runs once for each selected profile.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, p *profile) int
+}
+
+// commands lists the subcommands in the order of the usage message.
+func commands() []command {
+	return []command{
+		{"check", "validate the configuration and print it, without calling the Cloudflare API", runCheck},
+		{"detect", "detect the IP addresses, without updating anything", runDetect},
+		{"once", "run one round of updating, as UPDATE_CRON=@once would", runOnce},
+		{"cleanup", "delete the managed DNS records and WAF list items, as DELETE_ON_STOP=true would", runCleanup},
+		{"status", "print the managed DNS records and WAF list items", runStatus},
+	}
+}
+
+// printUsage prints the usage message.
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: ddns [command [profile ...]]\n\n")
+	fmt.Fprintf(w, "Without a command, the updater keeps running and updates on the schedule UPDATE_CRON.\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "  %-8s %s\n", "help", "print this message")
+	fmt.Fprintf(w, "\nA command runs for every profile in CONFIG_FILE unless some profiles are named.\n")
+	fmt.Fprintf(w, "\nExit codes:\n")
+	fmt.Fprintf(w, "  %d  success\n", exitOK)
+	fmt.Fprintf(w, "  %d  some operation failed\n", exitFailure)
+	fmt.Fprintf(w, "  %d  invalid command line\n", exitUsage)
+	fmt.Fprintf(w, "  %d  invalid configuration\n", exitConfigFailure)
+}
+
+// selectProfiles returns the profiles named on the command line, or all
+// profiles when none is named.
+func selectProfiles(ppfmt pp.PP, file *config.File, args []string) ([]string, bool) {
+	names := profileNames(file)
+	if len(args) == 0 {
+		return names, true
+	}
+	for _, name := range args {
+		if name == "" || !slices.Contains(names, name) {
+			ppfmt.Noticef(pp.EmojiUserError, "There is no profile named %q in CONFIG_FILE", name)
+			return nil, false
+		}
+	}
+	return args, true
+}
+
+// parseCommand finds the subcommand named on the command line, before any
+// settings are read. The daemon runs when there are no arguments, in which
+// case cmd is nil. When done is true, the command line was handled (for
+// example, by printing the usage message) or is invalid, and the updater
+// should exit with the code.
+func parseCommand(ppfmt pp.PP, args []string) (cmd *command, code int, done bool) {
+	if len(args) == 0 {
+		return nil, exitOK, false
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return nil, exitOK, true
+	}
+
+	for _, c := range commands() {
+		if c.name == args[0] {
+			return &c, exitOK, false
+		}
+	}
+
+	ppfmt.Noticef(pp.EmojiUserError, "Unknown command %q; run \"ddns help\" for the list of commands", args[0])
+	return nil, exitUsage, true
+}
+
+// runCommand runs the subcommand for the profiles named in args, or for all
+// profiles, and returns the exit code.
+func runCommand(ctx context.Context, ppfmt pp.PP, file *config.File, cmd *command, args []string) int {
+	names, ok := selectProfiles(ppfmt, file, args)
+	if !ok {
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, name := range names {
+		p, ok := newProfile(ppfmt, file, name, metrics.Noop{}, nil, nil)
+		if !ok {
+			code = max(code, exitConfigFailure)
+			continue
+		}
+		code = max(code, cmd.run(ctx, p))
+	}
+	ppfmt.Infof(pp.EmojiBye, "Bye!")
+	return code
+}
+
+// exitCode turns the outcome of an operation into an exit code.
+func exitCode(ok bool) int {
+	if ok {
+		return exitOK
+	}
+	return exitFailure
+}
+
+func runCheck(_ context.Context, p *profile) int {
+	if !p.load() {
+		return exitConfigFailure
+	}
+	p.ppfmt.Noticef(pp.EmojiGood, "The configuration is valid")
+	return exitOK
+}
+
+func runDetect(ctx context.Context, p *profile) int {
+	if !p.load() {
+		return exitConfigFailure
+	}
+	_, ok := updater.DetectIPs(ctx, p.ppfmt, p.updateConfig)
+	return exitCode(ok)
+}
+
+// start reads the configuration and starts the heartbeats, reporting a
+// misconfiguration as the updater would do on start.
+func (p *profile) start(ctx context.Context) bool {
+	ok := p.load()
+	p.hb.Start(ctx, p.ppfmt, formatName())
+	if !ok {
+		p.hb.Ping(ctx, p.ppfmt, heartbeat.NewMessagef(false, "Configuration errors"))
+		p.nt.Send(ctx, p.ppfmt, startupFailureNotification())
+	}
+	return ok
+}
+
+func runOnce(ctx context.Context, p *profile) int {
+	if !p.start(ctx) {
+		return exitConfigFailure
+	}
+	msg, _ := p.runUpdate(ctx, ctx, p.updateConfig)
+	p.nt.Send(ctx, p.ppfmt, msg.Notification())
+	p.hb.Exit(ctx, p.ppfmt, "Stopped")
+	return exitCode(msg.HeartbeatMessage.OK)
+}
+
+func runCleanup(ctx context.Context, p *profile) int {
+	if !p.start(ctx) {
+		return exitConfigFailure
+	}
+	roundPP := startRound(p.ppfmt, p.h)
+	msg := updater.FinalDeleteIPs(ctx, roundPP, p.updateConfig, p.s)
+	msg = reportOpenCircuit(p.ppfmt, p.h, msg, notifier.KindCleanupFailure)
+	p.hb.Log(ctx, p.ppfmt, msg.HeartbeatMessage)
+	p.nt.Send(ctx, p.ppfmt, msg.Notification())
+	writeReport(p.ppfmt, os.Stdout, p.lifecycleConfig.JSONReport, msg.Report)
+	p.keeper.save(p.ppfmt, time.Now())
+	p.hb.Exit(ctx, p.ppfmt, "Stopped")
+	return exitCode(msg.HeartbeatMessage.OK)
+}
+
+func runStatus(ctx context.Context, p *profile) int {
+	if !p.load() {
+		return exitConfigFailure
+	}
+	roundPP := startRound(p.ppfmt, p.h)
+	status, ok := updater.ReadStatus(ctx, roundPP, p.updateConfig, p.h)
+	printStatus(p.ppfmt, status)
+	return exitCode(ok)
+}
+
+// printStatus prints the managed DNS records and WAF list items.
+func printStatus(ppfmt pp.PP, status updater.Status) {
+	for _, r := range status.Records {
+		ppfmt.Noticef(pp.EmojiBullet, "%s records of %s: %s",
+			r.IPFamily.RecordType(), r.Domain.Describe(), pp.JoinMap(netip.Addr.String, r.IPs))
+	}
+	for _, l := range status.WAFLists {
+		ppfmt.Noticef(pp.EmojiBullet, "Items of the WAF list %s: %s",
+			api.WAFList.Describe(l.List), pp.JoinMap(netip.Prefix.String, l.Prefixes))
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func TestPrintUsage(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	printUsage(&b)
+	for _, cmd := range commands() {
+		require.Contains(t, b.String(), "  "+cmd.name+" ")
+	}
+	require.Contains(t, b.String(), "  3  invalid configuration\n")
+}
+
+func TestParseCommand(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		args          []string
+		cmd           string
+		code          int
+		done          bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"daemon": {nil, "", exitOK, false, nil},
+		"once":   {[]string{"once", "home"}, "once", exitOK, false, nil},
+		"unknown": {[]string{"update"}, "", exitUsage, true, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError,
+				"Unknown command %q; run \"ddns help\" for the list of commands", "update")
+		}},
+		"flag": {[]string{"--once"}, "", exitUsage, true, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError,
+				"Unknown command %q; run \"ddns help\" for the list of commands", "--once")
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			cmd, code, done := parseCommand(mockPP, tc.args)
+			require.Equal(t, tc.code, code)
+			require.Equal(t, tc.done, done)
+			if tc.cmd == "" {
+				require.Nil(t, cmd)
+			} else {
+				require.NotNil(t, cmd)
+				require.Equal(t, tc.cmd, cmd.name)
+			}
+		})
+	}
+}
+
+func TestSelectProfiles(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		args          []string
+		names         []string
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"all": {nil, []string{""}, true, nil},
+		"named": {[]string{"home"}, nil, false, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "There is no profile named %q in CONFIG_FILE", "home")
+		}},
+		"empty": {[]string{""}, nil, false, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "There is no profile named %q in CONFIG_FILE", "")
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			names, ok := selectProfiles(mockPP, nil, tc.args)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.names, names)
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, exitOK, exitCode(true))
+	require.Equal(t, exitFailure, exitCode(false))
+}
@@ -5,11 +5,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
+	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/config"
-	"github.com/favonia/cloudflare-ddns/internal/cron"
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
 	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
 	"github.com/favonia/cloudflare-ddns/internal/notifier"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/setter"
@@ -29,48 +32,90 @@ func formatName() string {
 }
 
 // initConfig reads and builds updater config, prints the resulting settings,
-// and constructs the API handle and setter.
+// and constructs the API handle and the setter. The returned handles (the
+// one for Cloudflare and the one for the RFC 2136 server, if any) are the
+// ones before any dry-run wrapping, so that their caches and the circuit
+// breaker can be reached.
 //
 // It does not set up output formatting or reporter services; those are created
 // earlier in bootstrap and passed in so that config printing and later startup
-// failures use the same heartbeat/notifier instances.
-func initConfig(ppfmt pp.PP, hb heartbeat.Heartbeat, nt notifier.Notifier) (*config.BuiltConfig, setter.Setter, bool) {
+// failures use the same heartbeat/notifier instances. The recorder of the
+// metrics is passed to both the updater and the API handle.
+func initConfig(ppfmt pp.PP, file *config.File, hb heartbeat.Heartbeat, nt notifier.Notifier, m metrics.Recorder,
+) (*config.BuiltConfig, setter.Setter, api.Handle, api.RecordHandle, bool) {
 	raw := config.DefaultRaw()
 
 	// Read and build the config.
-	if !raw.ReadEnv(ppfmt) {
-		return nil, nil, false
+	if !raw.ReadEnv(ppfmt, file) {
+		return nil, nil, nil, nil, false
 	}
 	builtConfig, ok := raw.BuildConfig(ppfmt)
 	if !ok {
-		return nil, nil, false
+		return nil, nil, nil, nil, false
 	}
 
 	// Print the config.
 	config.Print(ppfmt, builtConfig, hb, nt)
 
+	// Record the measurements.
+	builtConfig.Update.Metrics = m
+	builtConfig.Handle.Options.Metrics = m
+
 	// Get the handle.
 	h, ok := builtConfig.Handle.Auth.New(ppfmt, builtConfig.Handle.Options)
 	if !ok {
-		return builtConfig, nil, false
+		return builtConfig, nil, nil, nil, false
+	}
+
+	// Only record the writes in dry-run mode.
+	wrapped := h
+	if builtConfig.Update.DryRun {
+		ppfmt.Noticef(pp.EmojiDryRun, "Dry run enabled; DNS records and WAF lists will not be changed")
+		wrapped = api.NewDryRunHandle(h)
 	}
 
 	// Get the setter.
-	s := setter.New(ppfmt, h)
+	s := setter.New(ppfmt, wrapped)
+
+	// Mirror the DNS records to an RFC 2136 server, if any.
+	var mirror api.RecordHandle
+	if auth := builtConfig.Handle.RFC2136; auth != nil {
+		mirror, ok = auth.New(ppfmt, builtConfig.Handle.Options)
+		if !ok {
+			return builtConfig, nil, nil, nil, false
+		}
+		wrappedMirror := mirror
+		if builtConfig.Update.DryRun {
+			wrappedMirror = api.NewDryRunRecordHandle(mirror)
+		}
+		s = setter.NewMirrored(s, setter.NewRecordSetter(ppfmt, wrappedMirror), "the RFC 2136 server "+auth.Server)
+	}
+
+	return builtConfig, s, h, mirror, true
+}
 
-	return builtConfig, s, true
+// recorderForProfile returns the recorder of the metrics of a profile, which
+// discards everything when the metrics are disabled.
+func recorderForProfile(registry *metrics.Registry, name string) metrics.Recorder {
+	if registry == nil {
+		return metrics.Noop{}
+	}
+	return registry.ForProfile(name)
 }
 
 func stopUpdating(
 	ctx context.Context, ppfmt pp.PP,
 	lifecycleConfig *config.LifecycleConfig, updateConfig *config.UpdateConfig,
 	hb heartbeat.Heartbeat, nt notifier.Notifier,
-	s setter.Setter,
+	s setter.Setter, h api.Handle,
 ) {
 	if lifecycleConfig.DeleteOnStop {
-		msg := updater.FinalDeleteIPs(ctx, ppfmt, updateConfig, s)
+		roundPP := startRound(ppfmt, h)
+		msg := updater.FinalDeleteIPs(ctx, roundPP, updateConfig, s)
+		msg = reportOpenCircuit(ppfmt, h, msg, notifier.KindCleanupFailure)
 		hb.Log(ctx, ppfmt, msg.HeartbeatMessage)
 		nt.Send(ctx, ppfmt, msg.Notification())
+		writeReport(ppfmt, os.Stdout, lifecycleConfig.JSONReport, msg.Report)
 	}
 }
 
@@ -97,36 +142,80 @@ func realMain() int {
 	// Show the name and the version of the updater
 	ppfmt.Infof(pp.EmojiStar, "%s", formatName())
 
+	// Find the subcommand, if any. Without arguments, the updater keeps running.
+	cmd, code, done := parseCommand(ppfmt, os.Args[1:])
+	if done {
+		return code
+	}
+
 	// Warn about root privileges
 	config.CheckRoot(ppfmt)
 
-	// Set up reporting services before reading the updater config so startup
-	// failures during config/handle/setter setup can still be reported through
-	// the same heartbeat/notifier instances used after startup.
-	hb, nt, reportersOK := config.SetupReporters(ppfmt)
-	if !reportersOK {
+	// Read CONFIG_FILE, if any, before reading any other settings.
+	file, ok := config.LoadConfigFile(ppfmt)
+	if !ok {
+		ppfmt.Infof(pp.EmojiBye, "Bye!")
+		if cmd != nil {
+			return exitConfigFailure
+		}
+		return 1
+	}
+
+	// Run the subcommand instead, if any.
+	if cmd != nil {
+		return runCommand(ctxWithSignals, ppfmt, file, cmd, os.Args[2:])
+	}
+
+	// Serve the metrics and the health endpoints, if enabled.
+	svc, ok := setupServers(ppfmt, file, sig)
+	if !ok {
 		ppfmt.Infof(pp.EmojiBye, "Bye!")
 		return 1
 	}
+	defer svc.stop()
 
-	// Read the config and get the handle and the setter.
-	builtConfig, s, configOK := initConfig(ppfmt, hb, nt)
-	// Start heartbeats regardless of whether initConfig succeeded.
-	hb.Start(ctx, ppfmt, formatName())
-	// Bail out now if initConfig failed
+	// Set up each profile. The unnamed profile is used when CONFIG_FILE has no profiles.
+	names := profileNames(file)
+	profiles := make([]*profile, 0, len(names))
+	configOK := true
+	for _, name := range names {
+		// Set up reporting services before reading the updater config so startup
+		// failures during config/handle/setter setup can still be reported through
+		// the same heartbeat/notifier instances used after startup.
+		st := svc.monitor.ForProfile(name)
+		pushed := dyndns2.NewStore()
+		svc.pushes.AddStore(pushed)
+		p, reportersOK := newProfile(ppfmt, file, name,
+			metrics.NewComposed(recorderForProfile(svc.registry, name), st), st, pushed)
+		if !reportersOK {
+			ppfmt.Infof(pp.EmojiBye, "Bye!")
+			return 1
+		}
+
+		// Read the config and get the handle and the setter.
+		profileOK := p.load()
+		// Start heartbeats regardless of whether the config is valid.
+		p.hb.Start(ctx, p.ppfmt, formatName())
+		if !profileOK {
+			p.hb.Ping(ctx, p.ppfmt, heartbeat.NewMessagef(false, "Configuration errors"))
+			p.nt.Send(ctx, p.ppfmt, startupFailureNotification())
+			configOK = false
+		}
+		profiles = append(profiles, p)
+	}
+	// Bail out now if any profile is misconfigured
 	if !configOK {
-		hb.Ping(ctx, ppfmt, heartbeat.NewMessagef(false, "Configuration errors"))
-		nt.Send(ctx, ppfmt, startupFailureNotification())
 		ppfmt.Infof(pp.EmojiBye, "Bye!")
 		return 1
 	}
-	// We only needs lifecycleConfig and updateConfig from now on, and builtConfig should not be used.
-	lifecycleConfig := builtConfig.Lifecycle
-	updateConfig := builtConfig.Update
 
 	// If UPDATE_CRON is not `@once` (not single-run mode), then send a notification to signal the start.
-	if lifecycleConfig.UpdateCron != nil {
-		nt.Send(ctx, ppfmt, startupNotification())
+	cronEnabled := false
+	for _, p := range profiles {
+		if p.lifecycleConfig.UpdateCron != nil {
+			p.nt.Send(ctx, p.ppfmt, startupNotification())
+			cronEnabled = true
+		}
 	}
 
 	// Without the following line, the quiet mode can be too quiet, and some system (Portainer)
@@ -136,67 +225,93 @@ func realMain() int {
 	// We still want to keep the quiet mode extremely quiet for the single-run mode (UPDATE_CRON=@once),
 	// hence we are checking whether cron is enabled or not. (The single-run mode is defined as
 	// having the internal cron disabled.)
-	if lifecycleConfig.UpdateCron != nil && !ppfmt.IsShowing(pp.Verbose) {
+	if cronEnabled && !ppfmt.IsShowing(pp.Verbose) {
 		ppfmt.Noticef(pp.EmojiMute, "Quiet mode enabled")
 	}
 
-	first := true
+	// Report missing permissions before the first update instead of letting the update fail.
+	for _, p := range profiles {
+		if p.lifecycleConfig.CheckPermissionsOnStart {
+			p.announce()
+			p.ppfmt.BlankLineIfVerbose()
+
+			roundPP := startRound(p.ppfmt, p.h)
+			msg := updater.CheckPermissions(ctxWithSignals, roundPP, p.updateConfig, p.s)
+			msg = reportOpenCircuit(p.ppfmt, p.h, msg, notifier.KindPermissionFailure)
+			p.hb.Log(ctx, p.ppfmt, msg.HeartbeatMessage)
+			p.nt.Send(ctx, p.ppfmt, msg.Notification())
+		}
+	}
+
+	// shutdown cleans up every profile after a signal was caught.
+	shutdown := func() int {
+		for _, p := range profiles {
+			p.shutdown(ctx)
+		}
+		ppfmt.Infof(pp.EmojiBye, "Bye!")
+		return 0
+	}
+
+	exitCode := 0
 	for {
-		// The next time to run the updater.
-		// This is called before running the updater so that the timer would not be delayed by the updating.
-		next := cron.Next(lifecycleConfig.UpdateCron)
-
-		// Update the IP addresses
-		if first && !lifecycleConfig.UpdateOnStart {
-			hb.Ping(ctx, ppfmt, heartbeat.NewMessagef(true, "Started (no updates performed yet)"))
-		} else {
-			// Improve readability of the logging by separating each round of checks with blank lines.
-			ppfmt.BlankLineIfVerbose()
-
-			msg := updater.UpdateIPs(ctxWithSignals, ppfmt, updateConfig, s)
-			hb.Ping(ctx, ppfmt, msg.HeartbeatMessage)
-			nt.Send(ctx, ppfmt, msg.Notification())
+		// Run the profile that is due first.
+		p := nextProfile(profiles)
+		svc.monitor.Expect(p.due())
+		event := sig.WaitUntil(ppfmt, p.due())
+		svc.monitor.Start()
+		switch event {
+		case signal.Stop:
+			return shutdown()
+		case signal.Reload:
+			reloadProfiles(ctx, ppfmt, names, profiles)
+			continue
+		case signal.Trigger:
+			if req, ok := svc.triggers.Take(); ok {
+				ppfmt.Noticef(pp.EmojiNow, "Update triggered by %s", req.DescribeSources())
+				for _, q := range profiles {
+					q.trigger(ctx, ctxWithSignals, req)
+				}
+				if ctxWithSignals.Err() != nil {
+					sig.WaitForSignalsUntil(ppfmt, time.Now().Add(time.Second))
+					return shutdown()
+				}
+			}
+			continue
+		case signal.Alarm:
 		}
 
+		p.round(ctx, ctxWithSignals)
+
+		// A signal interrupted the round; catch it before anything else.
 		if ctxWithSignals.Err() != nil {
-			goto signaled
+			sig.WaitForSignalsUntil(ppfmt, time.Now().Add(time.Second))
+			return shutdown()
 		}
 
-		// Check if cron was disabled
-		if lifecycleConfig.UpdateCron == nil {
-			ppfmt.Infof(pp.EmojiBye, "Bye!")
-			return 0
-		}
+		switch {
+		case p.retry != nil:
+			p.printCountdown()
+			continue
 
-		first = false
+		// Check if cron was disabled
+		case p.lifecycleConfig.UpdateCron == nil:
 
 		// If there's nothing scheduled in the near future
-		if next.IsZero() {
-			ppfmt.Noticef(pp.EmojiUserError,
-				"No scheduled updates in the near future; consider changing UPDATE_CRON=%s",
-				cron.DescribeSchedule(lifecycleConfig.UpdateCron),
-			)
-			stopUpdating(ctx, ppfmt, lifecycleConfig, updateConfig, hb, nt, s)
-			hb.Ping(ctx, ppfmt, heartbeat.NewMessagef(false, "No scheduled updates"))
-			nt.Send(ctx, ppfmt, schedulingFailureNotification(
-				cron.DescribeSchedule(lifecycleConfig.UpdateCron)))
-			ppfmt.Infof(pp.EmojiBye, "Bye!")
-			return 1
-		}
+		case p.next.IsZero():
+			p.stopForNoSchedule(ctx)
+			exitCode = 1
 
-		// Display the remaining time interval
-		cron.PrintCountdown(ppfmt, "Checking the IP addresses", time.Now(), next)
+		default:
+			// Display the remaining time interval
+			p.printCountdown()
+			continue
+		}
 
-	signaled:
-		// Wait for the next signal or the alarm, whichever comes first
-		if sig.WaitForSignalsUntil(ppfmt, next) {
-			stopUpdating(ctx, ppfmt, lifecycleConfig, updateConfig, hb, nt, s)
-			hb.Exit(ctx, ppfmt, "Stopped")
-			if lifecycleConfig.UpdateCron != nil {
-				nt.Send(ctx, ppfmt, shutdownNotification())
-			}
+		// The profile will not run again.
+		profiles = slices.DeleteFunc(profiles, func(q *profile) bool { return q == p })
+		if len(profiles) == 0 {
 			ppfmt.Infof(pp.EmojiBye, "Bye!")
-			return 0
+			return exitCode
 		}
 	} // mainLoop
 }
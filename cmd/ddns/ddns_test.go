@@ -15,6 +15,7 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
 	"github.com/favonia/cloudflare-ddns/internal/ipfilter"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
 	"github.com/favonia/cloudflare-ddns/internal/mocks"
 	"github.com/favonia/cloudflare-ddns/internal/notifier"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
@@ -44,10 +45,12 @@ func TestInitConfigManagedRecordsCommentRegex(t *testing.T) {
 
 	// Run the production initialization path silently; the assertions below define
 	// the successful return contract for initConfig.
-	builtConfig, s, ok := initConfig(
+	builtConfig, s, _, _, ok := initConfig(
 		pp.NewSilent(),
+		nil,
 		heartbeat.NewComposed(),
 		notifier.NewComposed(),
+		metrics.Noop{},
 	)
 	require.True(t, ok)
 	require.NotNil(t, builtConfig)
@@ -90,14 +93,38 @@ func TestInitConfigManagedRecordsCommentRegex(t *testing.T) {
 	require.Equal(t, 30*time.Second, updateConfig.UpdateTimeout)
 }
 
+func TestInitConfigRFC2136Mirror(t *testing.T) {
+	testenv.ClearAll(t)
+	t.Setenv("CLOUDFLARE_API_TOKEN", "deadbeaf")
+	t.Setenv("DOMAINS", "example.org")
+	t.Setenv("DRY_RUN", "true")
+	t.Setenv("RFC2136_SERVER", "192.0.2.53")
+	t.Setenv("RFC2136_TSIG_KEY", "ddns:c2VjcmV0")
+
+	builtConfig, s, _, _, ok := initConfig(
+		pp.NewSilent(),
+		nil,
+		heartbeat.NewComposed(),
+		notifier.NewComposed(),
+		metrics.Noop{},
+	)
+	require.True(t, ok)
+	require.Equal(t, &api.RFC2136Auth{Server: "192.0.2.53:53", KeyName: "ddns.", Secret: []byte("secret")},
+		builtConfig.Handle.RFC2136)
+	require.NotNil(t, s)
+	require.Empty(t, s.TakePlan())
+}
+
 //nolint:paralleltest // environment variables are global
 func TestInitConfigReadFailure(t *testing.T) {
 	testenv.ClearAll(t)
 
-	builtConfig, s, ok := initConfig(
+	builtConfig, s, _, _, ok := initConfig(
 		pp.NewSilent(),
+		nil,
 		heartbeat.NewComposed(),
 		notifier.NewComposed(),
+		metrics.Noop{},
 	)
 	require.False(t, ok)
 	require.Nil(t, builtConfig)
@@ -110,10 +137,12 @@ func TestInitConfigBuildFailure(t *testing.T) {
 	t.Setenv("DOMAINS", "example.org")
 	t.Setenv("MANAGED_RECORDS_COMMENT_REGEX", "(")
 
-	builtConfig, s, ok := initConfig(
+	builtConfig, s, _, _, ok := initConfig(
 		pp.NewSilent(),
+		nil,
 		heartbeat.NewComposed(),
 		notifier.NewComposed(),
+		metrics.Noop{},
 	)
 	require.False(t, ok)
 	require.Nil(t, builtConfig)
@@ -203,7 +232,8 @@ func TestStopUpdatingDeleteOnStop(t *testing.T) {
 		},
 	)
 
-	stopUpdating(context.Background(), ppfmt, lifecycleConfig, updateConfig, mockHeartbeat, mockNotifier, mockSetter)
+	stopUpdating(context.Background(), ppfmt, lifecycleConfig, updateConfig, mockHeartbeat, mockNotifier, mockSetter,
+		mocks.NewMockHandle(mockCtrl))
 }
 
 func TestStopUpdatingSkipsDeleteOnStop(t *testing.T) {
@@ -243,5 +273,6 @@ func TestStopUpdatingSkipsDeleteOnStop(t *testing.T) {
 		mockHeartbeat,
 		mockNotifier,
 		mockSetter,
+		mocks.NewMockHandle(mockCtrl),
 	)
 }
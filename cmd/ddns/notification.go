@@ -23,3 +23,18 @@ func schedulingFailureNotification(schedule string) notifier.Notification {
 func shutdownNotification() notifier.Notification {
 	return notifier.NewNotificationf(notifier.KindShutdown, "Cloudflare DDNS has stopped.")
 }
+
+// stateChangeNotification reports the address changes since the last run,
+// as found by comparing the state file with the records after the first round.
+func stateChangeNotification(changes []string) notifier.Notification {
+	return notifier.NewNotification(notifier.KindUpdate, notifier.Message(changes))
+}
+
+func reloadNotification() notifier.Notification {
+	return notifier.NewNotificationf(notifier.KindReload, "Cloudflare DDNS has reloaded its configuration.")
+}
+
+func reloadFailureNotification() notifier.Notification {
+	return notifier.NewNotificationf(notifier.KindReloadFailure,
+		"Cloudflare DDNS could not reload its configuration and kept the old one. Please check the logs for details.")
+}
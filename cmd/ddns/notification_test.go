@@ -37,6 +37,17 @@ func TestLifecycleNotifications(t *testing.T) {
 			notifier.KindShutdown,
 			"Cloudflare DDNS has stopped.",
 		},
+		"reload": {
+			reloadNotification,
+			notifier.KindReload,
+			"Cloudflare DDNS has reloaded its configuration.",
+		},
+		"reload failure": {
+			reloadFailureNotification,
+			notifier.KindReloadFailure,
+			"Cloudflare DDNS could not reload its configuration and kept the old one. " +
+				"Please check the logs for details.",
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/cron"
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/health"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// A profile is one set of settings with its own configuration, setter,
+// heartbeat, and notifier. Profiles are scheduled independently of each other.
+// The unnamed profile is used when CONFIG_FILE has no profiles, and its
+// output is not indented.
+type profile struct {
+	name  string
+	file  *config.File // CONFIG_FILE with this profile selected, or nil
+	top   pp.PP        // the printer for the lines about the profile
+	ppfmt pp.PP        // the printer for everything else, indented for named profiles
+	hb    heartbeat.Heartbeat
+	nt    notifier.Notifier
+	m     metrics.Recorder
+	st    *health.Profile // the status served at HEALTH_ADDR, if kept
+
+	pushed *dyndns2.Store // the addresses pushed to DYNDNS2_ADDR for the profile, if kept
+
+	lifecycleConfig *config.LifecycleConfig
+	updateConfig    *config.UpdateConfig
+	s               setter.Setter
+	h               api.Handle
+	keeper          *stateKeeper
+
+	first bool          // whether no rounds have been run yet
+	next  time.Time     // the next scheduled round
+	retry *pendingRetry // the retries of the last round, if any
+}
+
+// A pendingRetry is the progress of retrying the failed parts of a round.
+// Only the final outcome is notified.
+type pendingRetry struct {
+	first    updater.Message
+	last     updater.Message
+	failures updater.Failures
+	retries  int
+	at       time.Time
+}
+
+// profileNames returns the names of the profiles to run.
+func profileNames(file *config.File) []string {
+	if names := file.Profiles(); len(names) > 0 {
+		return names
+	}
+	return []string{""}
+}
+
+// newProfile selects the named profile of CONFIG_FILE and sets up its
+// reporting services. The measurements of the profile go to m, and the
+// outcomes of its rounds go to st unless it is nil. The dyndns2-server
+// providers of the profile read the addresses pushed to pushed, if any.
+func newProfile(ppfmt pp.PP, file *config.File, name string, m metrics.Recorder, st *health.Profile,
+	pushed *dyndns2.Store,
+) (*profile, bool) {
+	p := &profile{ //nolint:exhaustruct // the configuration is read by load
+		name:   name,
+		file:   file.WithProfile(name),
+		top:    ppfmt,
+		ppfmt:  ppfmt,
+		m:      m,
+		st:     st,
+		pushed: pushed,
+		first:  true,
+	}
+	if name != "" {
+		p.ppfmt = ppfmt.Indent()
+	}
+	p.announce()
+
+	var ok bool
+	p.hb, p.nt, ok = config.SetupReporters(p.ppfmt, p.file)
+	return p, ok
+}
+
+// announce prints the name of the profile before its output, if it has one.
+func (p *profile) announce() {
+	if p.name != "" {
+		p.top.Infof(pp.EmojiConfig, "Profile %s:", p.name)
+	}
+}
+
+// separate starts a new round in the logging.
+func (p *profile) separate() {
+	// Improve readability of the logging by separating each round of checks with blank lines.
+	p.top.BlankLineIfVerbose()
+	p.announce()
+}
+
+// load reads the configuration of the profile selected by [newProfile].
+func (p *profile) load() bool {
+	builtConfig, s, h, mirror, ok := initConfig(p.ppfmt, p.file, p.hb, p.nt, p.m)
+	if !ok {
+		return false
+	}
+	p.use(builtConfig, s, h, mirror)
+	return true
+}
+
+// use switches the profile to a new configuration.
+func (p *profile) use(builtConfig *config.BuiltConfig, s setter.Setter, h api.Handle, mirror api.RecordHandle) {
+	p.lifecycleConfig, p.updateConfig = builtConfig.Lifecycle, builtConfig.Update
+	p.bindPushed()
+	p.s, p.h = s, h
+	p.keeper = newStateKeeper(p.ppfmt, p.lifecycleConfig.StateFile, h, mirror, p.updateConfig.DryRun, time.Now())
+	if p.st != nil {
+		p.st.SetRoundTimeout(updater.RoundTimeout(p.updateConfig))
+	}
+}
+
+// bindPushed binds the dyndns2-server providers of the profile to its store of
+// pushed addresses. The store accepts the updates of the name and the domains
+// of the profile, or of every hostname for the unnamed profile.
+func (p *profile) bindPushed() {
+	for ipFamily, pv := range p.updateConfig.Provider {
+		p.updateConfig.Provider[ipFamily] = provider.WithPushedStore(pv, p.pushed)
+	}
+	if p.pushed == nil {
+		return
+	}
+	if p.name == "" {
+		p.pushed.SetHostnames(nil)
+		return
+	}
+	hostnames := []string{p.name}
+	for _, domains := range p.updateConfig.Domains {
+		for _, d := range domains {
+			hostnames = append(hostnames, d.DNSNameASCII())
+		}
+	}
+	p.pushed.SetHostnames(hostnames)
+}
+
+// due returns when the profile needs to run next.
+func (p *profile) due() time.Time {
+	switch {
+	case p.first:
+		return time.Time{}
+	case p.retry != nil:
+		return p.retry.at
+	default:
+		return p.next
+	}
+}
+
+// nextProfile returns the profile that needs to run first.
+func nextProfile(profiles []*profile) *profile {
+	return slices.MinFunc(profiles, func(a, b *profile) int { return a.due().Compare(b.due()) })
+}
+
+// runUpdate runs a round of updating and reports it to everything but the
+// notifiers, because the notification waits for the retries.
+func (p *profile) runUpdate(ctx, ctxWithSignals context.Context, c *config.UpdateConfig,
+) (updater.Message, updater.Failures) {
+	roundPP := startRound(p.ppfmt, p.h)
+	msg, failures := updater.UpdateIPsWithFailures(ctxWithSignals, roundPP, c, p.s)
+	msg = reportOpenCircuit(p.ppfmt, p.h, msg, notifier.KindUpdateFailure)
+	p.hb.Ping(ctx, p.ppfmt, msg.HeartbeatMessage)
+	if p.st != nil {
+		p.st.RecordRound(msg, time.Now())
+	}
+	writeReport(p.ppfmt, os.Stdout, p.lifecycleConfig.JSONReport, msg.Report)
+	if changes := p.keeper.save(p.ppfmt, time.Now()); len(changes) > 0 {
+		p.nt.Send(ctx, p.ppfmt, stateChangeNotification(changes))
+	}
+	return msg, failures
+}
+
+// round runs the next scheduled round of updating, or the next retry of the
+// last round.
+func (p *profile) round(ctx, ctxWithSignals context.Context) {
+	if r := p.retry; r != nil {
+		p.separate()
+		r.last, r.failures = p.runUpdate(ctx, ctxWithSignals, r.failures.Restrict(p.updateConfig))
+		r.retries++
+		p.scheduleRetry(ctx, ctxWithSignals)
+		p.recordNextRun()
+		return
+	}
+
+	// The next time to run the updater.
+	// This is called before running the updater so that the timer would not be delayed by the updating.
+	p.next = cron.Next(p.lifecycleConfig.UpdateCron)
+
+	if p.first && !p.lifecycleConfig.UpdateOnStart {
+		p.announce()
+		p.hb.Ping(ctx, p.ppfmt, heartbeat.NewMessagef(true, "Started (no updates performed yet)"))
+	} else {
+		p.separate()
+		msg, failures := p.runUpdate(ctx, ctxWithSignals, p.updateConfig)
+		p.retry = &pendingRetry{first: msg, last: msg, failures: failures, retries: 0, at: time.Time{}}
+		p.scheduleRetry(ctx, ctxWithSignals)
+	}
+	p.first = false
+	p.recordNextRun()
+}
+
+// trigger runs a round of updating out of schedule, as requested through the
+// webhook. The pending retries of the last round are given up, and the schedule
+// is not changed. When only some IP families are requested, only their DNS
+// records and their share of the WAF lists and other resources are updated.
+// A trigger before the first round takes its place, and the schedule starts.
+func (p *profile) trigger(ctx, ctxWithSignals context.Context, req trigger.Request) {
+	p.finishRetry(ctx)
+	p.separate()
+
+	if p.first {
+		p.next = cron.Next(p.lifecycleConfig.UpdateCron)
+		p.first = false
+	}
+
+	c := p.updateConfig
+	if req.Families != nil {
+		c = updater.NewFamilyFailures(req.Families).Restrict(c)
+	}
+	msg, failures := p.runUpdate(ctx, ctxWithSignals, c)
+	if !msg.NotifierMessage.IsEmpty() {
+		msg.NotifierMessage = notifier.MergeMessages(
+			notifier.NewMessagef("Triggered by %s.", req.DescribeSources()), msg.NotifierMessage)
+	}
+	p.retry = &pendingRetry{first: msg, last: msg, failures: failures, retries: 0, at: time.Time{}}
+	p.scheduleRetry(ctx, ctxWithSignals)
+	p.recordNextRun()
+}
+
+// recordNextRun records when the profile runs again, if it will.
+func (p *profile) recordNextRun() {
+	if due := p.due(); !due.IsZero() {
+		p.m.SetNextRun(due)
+	}
+}
+
+// scheduleRetry decides when to retry the failed parts of the last round, if
+// allowed, and sends the notification of the round when there is nothing more
+// to retry.
+func (p *profile) scheduleRetry(ctx, ctxWithSignals context.Context) {
+	r := p.retry
+	if !r.failures.IsEmpty() && p.lifecycleConfig.UpdateCron != nil && ctxWithSignals.Err() == nil {
+		if at, ok := nextRetry(time.Now(), p.next, r.retries, p.lifecycleConfig.UpdateRetries); ok {
+			r.at = at
+			return
+		}
+	}
+	p.finishRetry(ctx)
+}
+
+// finishRetry sends the notification of the last round, if it is still waiting
+// for retries.
+func (p *profile) finishRetry(ctx context.Context) {
+	if r := p.retry; r != nil {
+		p.nt.Send(ctx, p.ppfmt, retryNotification(r.first, r.last, r.retries))
+		p.retry = nil
+	}
+}
+
+// printCountdown displays the remaining time until the profile runs again.
+func (p *profile) printCountdown() {
+	if r := p.retry; r != nil {
+		cron.PrintCountdown(p.ppfmt, fmt.Sprintf("Retrying %s (%d/%d)",
+			r.failures.Describe(), r.retries+1, p.lifecycleConfig.UpdateRetries), time.Now(), r.at)
+		return
+	}
+	cron.PrintCountdown(p.ppfmt, "Checking the IP addresses", time.Now(), p.next)
+}
+
+// stopForNoSchedule stops the profile because UPDATE_CRON has no upcoming rounds.
+func (p *profile) stopForNoSchedule(ctx context.Context) {
+	p.ppfmt.Noticef(pp.EmojiUserError,
+		"No scheduled updates in the near future; consider changing UPDATE_CRON=%s",
+		cron.DescribeSchedule(p.lifecycleConfig.UpdateCron),
+	)
+	stopUpdating(ctx, p.ppfmt, p.lifecycleConfig, p.updateConfig, p.hb, p.nt, p.s, p.h)
+	p.keeper.save(p.ppfmt, time.Now())
+	p.hb.Ping(ctx, p.ppfmt, heartbeat.NewMessagef(false, "No scheduled updates"))
+	p.nt.Send(ctx, p.ppfmt, schedulingFailureNotification(
+		cron.DescribeSchedule(p.lifecycleConfig.UpdateCron)))
+}
+
+// shutdown cleans up after a signal was caught.
+func (p *profile) shutdown(ctx context.Context) {
+	p.announce()
+	p.finishRetry(ctx)
+	stopUpdating(ctx, p.ppfmt, p.lifecycleConfig, p.updateConfig, p.hb, p.nt, p.s, p.h)
+	p.keeper.save(p.ppfmt, time.Now())
+	p.hb.Exit(ctx, p.ppfmt, "Stopped")
+	if p.lifecycleConfig.UpdateCron != nil {
+		p.nt.Send(ctx, p.ppfmt, shutdownNotification())
+	}
+}
+
+// reload switches the profile to its new configuration in the new CONFIG_FILE,
+// if it is valid. The pending retries of the last round are given up.
+func (p *profile) reload(ctx context.Context, file *config.File) {
+	p.announce()
+	file = file.WithProfile(p.name)
+
+	builtConfig, s, h, mirror, ok := reloadConfig(p.ppfmt, file, p.hb, p.nt, p.m)
+	if !ok {
+		p.nt.Send(ctx, p.ppfmt, reloadFailureNotification())
+		return
+	}
+	p.file = file
+
+	p.finishRetry(ctx)
+	if builtConfig.Lifecycle.DeleteRemovedOnReload {
+		cleanUpRemoved(ctx, p.ppfmt, p.lifecycleConfig, p.updateConfig, builtConfig.Update, p.hb, p.nt, p.s, p.h)
+	}
+	p.keeper.save(p.ppfmt, time.Now())
+	p.use(builtConfig, s, h, mirror)
+	p.nt.Send(ctx, p.ppfmt, reloadNotification())
+	if !p.first {
+		p.next = cron.Next(p.lifecycleConfig.UpdateCron)
+		p.recordNextRun()
+	}
+}
+
+// reloadProfiles reads CONFIG_FILE again and reloads the running profiles.
+// A reload cannot add, remove, or rename profiles, so the new names must be
+// the same as the names when the updater started.
+func reloadProfiles(ctx context.Context, ppfmt pp.PP, names []string, profiles []*profile) {
+	ppfmt.Noticef(pp.EmojiEnvVars, "Reloading the configuration . . .")
+
+	file, ok := config.LoadConfigFile(ppfmt)
+	if ok {
+		if !slices.Equal(names, profileNames(file)) {
+			ppfmt.Noticef(pp.EmojiUserError, "Profiles cannot be added, removed, or renamed by a reload")
+			ok = false
+		}
+	}
+	if !ok {
+		ppfmt.Noticef(pp.EmojiUserError, "The new configuration is invalid; keeping the old configuration")
+		for _, p := range profiles {
+			p.nt.Send(ctx, p.ppfmt, reloadFailureNotification())
+		}
+		return
+	}
+
+	for _, p := range profiles {
+		p.reload(ctx, file)
+		if !p.first {
+			p.printCountdown()
+		}
+	}
+}
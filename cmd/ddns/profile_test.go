@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/cron"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/provider/protocol"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+)
+
+//nolint:exhaustruct // only the scheduling fields matter
+func TestProfileDue(t *testing.T) {
+	t.Parallel()
+
+	next := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	retryAt := next.Add(-time.Minute)
+
+	require.True(t, (&profile{first: true, next: next}).due().IsZero())
+	require.Equal(t, next, (&profile{first: false, next: next}).due())
+	require.Equal(t, retryAt, (&profile{
+		first: false, next: next, retry: &pendingRetry{at: retryAt},
+	}).due())
+}
+
+//nolint:exhaustruct // only the scheduling fields matter
+func TestNextProfile(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	home := &profile{name: "home", next: now.Add(time.Hour)}
+	work := &profile{name: "work", next: now.Add(time.Minute)}
+	family := &profile{name: "family", next: now.Add(time.Minute)}
+	fresh := &profile{name: "fresh", first: true}
+	retrying := &profile{name: "retrying", next: now.Add(time.Hour), retry: &pendingRetry{
+		at: now.Add(time.Second),
+	}}
+
+	require.Same(t, work, nextProfile([]*profile{home, work, family}))
+	require.Same(t, fresh, nextProfile([]*profile{home, work, fresh}))
+	require.Same(t, retrying, nextProfile([]*profile{home, work, retrying}))
+}
+
+//nolint:exhaustruct // only the scheduling fields and the reporters matter
+func TestProfileTriggerBeforeFirstRound(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockHeartbeat := mocks.NewMockHeartbeat(mockCtrl)
+	mockNotifier := mocks.NewMockNotifier(mockCtrl)
+	ppfmt := pp.NewSilent()
+
+	p := &profile{
+		top:             ppfmt,
+		ppfmt:           ppfmt,
+		hb:              mockHeartbeat,
+		nt:              mockNotifier,
+		m:               metrics.Noop{},
+		lifecycleConfig: &config.LifecycleConfig{UpdateCron: cron.MustNew("@every 5m")},
+		updateConfig:    &config.UpdateConfig{},
+		s:               mocks.NewMockSetter(mockCtrl),
+		h:               mocks.NewMockHandle(mockCtrl),
+		first:           true,
+	}
+
+	mockHeartbeat.EXPECT().Ping(gomock.Any(), ppfmt, gomock.Any()).Return(true)
+	mockNotifier.EXPECT().Send(gomock.Any(), ppfmt, gomock.Any()).Return(true).AnyTimes()
+
+	before := time.Now()
+	p.trigger(context.Background(), context.Background(), trigger.Request{})
+
+	// The trigger took the place of the first round, so the profile waits for
+	// the schedule instead of running again at once.
+	require.False(t, p.first)
+	require.Nil(t, p.retry)
+	require.True(t, p.due().After(before))
+}
+
+//nolint:exhaustruct // only the providers and the domains matter
+func TestProfileBindPushed(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		name     string
+		accepted string
+		rejected string
+	}{
+		"unnamed": {"", "other.example.org", ""},
+		"named":   {"home", "home.example.org", "other.example.org"},
+		"by-name": {"home", "home", "work"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := dyndns2.NewStore()
+			s := dyndns2.NewServer("user", "pass", false, trigger.NewLimiter(trigger.NewQueue(func() {}), 0))
+			s.AddStore(store)
+			p := &profile{
+				name:   tc.name,
+				pushed: store,
+				updateConfig: &config.UpdateConfig{
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP4: provider.NewDynDNS2Server(),
+						ipnet.IP6: nil,
+					},
+					Domains: map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain.FQDN("home.example.org")}},
+				},
+			}
+			p.bindPushed()
+			require.Equal(t, protocol.Pushed{ProviderName: "dyndns2-server", Store: store},
+				p.updateConfig.Provider[ipnet.IP4])
+			require.Nil(t, p.updateConfig.Provider[ipnet.IP6])
+
+			push := func(hostname string) string {
+				mux := http.NewServeMux()
+				s.Register(mux)
+				req := httptest.NewRequestWithContext(t.Context(), http.MethodGet,
+					"/nic/update?myip=203.0.113.1&hostname="+hostname, nil)
+				req.SetBasicAuth("user", "pass")
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+			require.NotEqual(t, "nohost\n", push(tc.accepted))
+			if tc.rejected != "" {
+				require.Equal(t, "nohost\n", push(tc.rejected))
+			}
+		})
+	}
+}
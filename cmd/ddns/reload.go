@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// reloadConfig builds the configuration of the selected profile again after a
+// reload signal. An invalid configuration is rejected so that the old one keeps
+// running. The single-run mode (UPDATE_CRON=@once) is also rejected, because the
+// updater is already running.
+func reloadConfig(ppfmt pp.PP, file *config.File, hb heartbeat.Heartbeat, nt notifier.Notifier, m metrics.Recorder,
+) (*config.BuiltConfig, setter.Setter, api.Handle, api.RecordHandle, bool) {
+	builtConfig, s, h, mirror, ok := initConfig(ppfmt, file, hb, nt, m)
+	if ok && builtConfig.Lifecycle.UpdateCron == nil {
+		ppfmt.Noticef(pp.EmojiUserError, "UPDATE_CRON=@once cannot be used when reloading the configuration")
+		ok = false
+	}
+	if !ok {
+		ppfmt.Noticef(pp.EmojiUserError, "The new configuration is invalid; keeping the old configuration")
+		return nil, nil, nil, nil, false
+	}
+	return builtConfig, s, h, mirror, true
+}
+
+// removedOf returns the elements of old that are missing from current.
+func removedOf[T comparable](old, current []T) []T {
+	var removed []T
+	for _, x := range old {
+		if !slices.Contains(current, x) {
+			removed = append(removed, x)
+		}
+	}
+	return removed
+}
+
+// removedScope returns a copy of old that only manages the resources that are
+// missing from current. A domain is considered removed from an IP family when
+// it is no longer updated for that family. The resources that are still
+// managed, possibly for fewer IP families, are left alone. It returns nil when
+// nothing was removed.
+func removedScope(old, current *config.UpdateConfig) *config.UpdateConfig {
+	removed := *old
+	removed.Domains = map[ipnet.Family][]domain.Domain{}
+	found := false
+	for ipFamily, ds := range old.Domains {
+		if r := removedOf(ds, current.Domains[ipFamily]); len(r) > 0 {
+			removed.Domains[ipFamily] = r
+			found = true
+		}
+	}
+
+	removed.WAFLists = removedOf(old.WAFLists, current.WAFLists)
+	removed.LBPoolOrigins = removedOf(old.LBPoolOrigins, current.LBPoolOrigins)
+	removed.GatewayLocations = removedOf(old.GatewayLocations, current.GatewayLocations)
+	removed.AccessGroups = removedOf(old.AccessGroups, current.AccessGroups)
+	removed.IPAccessRules = removedOf(old.IPAccessRules, current.IPAccessRules)
+	removed.SpectrumApps = removedOf(old.SpectrumApps, current.SpectrumApps)
+	removed.NFTablesSets = removedOf(old.NFTablesSets, current.NFTablesSets)
+	if old.WAFListRule.ZoneID == current.WAFListRule.ZoneID {
+		removed.WAFListRule = api.WAFListRule{ZoneID: "", Action: ""}
+	}
+	if old.WorkersKV == current.WorkersKV {
+		removed.WorkersKV = api.WorkersKVKey{AccountID: "", NamespaceID: "", Key: ""}
+	}
+	if old.LocalResolver == nil || current.LocalResolver != nil &&
+		current.LocalResolver.Sink.Path == old.LocalResolver.Sink.Path {
+		removed.LocalResolver = nil
+	}
+
+	found = found || len(removed.WAFLists) > 0 || len(removed.LBPoolOrigins) > 0 ||
+		len(removed.GatewayLocations) > 0 || len(removed.AccessGroups) > 0 ||
+		len(removed.IPAccessRules) > 0 || len(removed.SpectrumApps) > 0 ||
+		len(removed.NFTablesSets) > 0 || removed.WAFListRule.ZoneID != "" ||
+		removed.WorkersKV.Key != "" || removed.LocalResolver != nil
+	if !found {
+		return nil
+	}
+	return &removed
+}
+
+// cleanUpRemoved deletes the DNS records and clears the WAF lists and other
+// resources that a reload removed from the configuration, in the same way as
+// DELETE_ON_STOP. It uses
+// the old configuration, setter, and handle, because they manage the removed
+// resources.
+func cleanUpRemoved(
+	ctx context.Context, ppfmt pp.PP,
+	lifecycleConfig *config.LifecycleConfig, old, current *config.UpdateConfig,
+	hb heartbeat.Heartbeat, nt notifier.Notifier,
+	s setter.Setter, h api.Handle,
+) {
+	removed := removedScope(old, current)
+	if removed == nil {
+		return
+	}
+
+	roundPP := startRound(ppfmt, h)
+	msg := updater.FinalDeleteIPs(ctx, roundPP, removed, s)
+	msg = reportOpenCircuit(ppfmt, h, msg, notifier.KindCleanupFailure)
+	hb.Log(ctx, ppfmt, msg.HeartbeatMessage)
+	nt.Send(ctx, ppfmt, msg.Notification())
+	writeReport(ppfmt, os.Stdout, lifecycleConfig.JSONReport, msg.Report)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+func TestRemovedScope(t *testing.T) {
+	t.Parallel()
+
+	list1 := api.WAFList{AccountID: "account", Name: "list1"}
+	list2 := api.WAFList{AccountID: "account", Name: "list2"}
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "origin"}
+
+	old := &config.UpdateConfig{} //nolint:exhaustruct
+	old.TTL = api.TTLAuto
+	old.Domains = map[ipnet.Family][]domain.Domain{
+		ipnet.IP4: {domain.FQDN("a.org"), domain.FQDN("b.org")},
+		ipnet.IP6: {domain.FQDN("a.org")},
+	}
+	old.WAFLists = []api.WAFList{list1, list2}
+	old.LBPoolOrigins = []api.LBPoolOrigin{origin}
+
+	for name, tc := range map[string]struct {
+		domains     map[ipnet.Family][]domain.Domain
+		lists       []api.WAFList
+		origins     []api.LBPoolOrigin
+		ok          bool
+		wantDomains map[ipnet.Family][]domain.Domain
+		wantLists   []api.WAFList
+		wantOrigins []api.LBPoolOrigin
+	}{
+		"unchanged": {
+			old.Domains, old.WAFLists, old.LBPoolOrigins,
+			false, nil, nil, nil,
+		},
+		"domain": {
+			map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain.FQDN("a.org")}, ipnet.IP6: {domain.FQDN("a.org")}},
+			old.WAFLists, old.LBPoolOrigins,
+			true, map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain.FQDN("b.org")}}, nil, nil,
+		},
+		"family": {
+			map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain.FQDN("a.org"), domain.FQDN("b.org")}},
+			old.WAFLists, old.LBPoolOrigins,
+			true, map[ipnet.Family][]domain.Domain{ipnet.IP6: {domain.FQDN("a.org")}}, nil, nil,
+		},
+		"list": {
+			old.Domains, []api.WAFList{list2}, old.LBPoolOrigins,
+			true, map[ipnet.Family][]domain.Domain{}, []api.WAFList{list1}, nil,
+		},
+		"origin": {
+			old.Domains, old.WAFLists, nil,
+			true, map[ipnet.Family][]domain.Domain{}, nil, []api.LBPoolOrigin{origin},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			current := *old
+			current.Domains = tc.domains
+			current.WAFLists = tc.lists
+			current.LBPoolOrigins = tc.origins
+
+			removed := removedScope(old, &current)
+			if !tc.ok {
+				require.Nil(t, removed)
+				return
+			}
+			require.Equal(t, tc.wantDomains, removed.Domains)
+			require.Equal(t, tc.wantLists, removed.WAFLists)
+			require.Equal(t, tc.wantOrigins, removed.LBPoolOrigins)
+			require.Equal(t, old.TTL, removed.TTL)
+		})
+	}
+
+	// The old config is untouched.
+	require.Equal(t, []api.LBPoolOrigin{origin}, old.LBPoolOrigins)
+}
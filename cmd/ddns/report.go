@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// writeReport writes the machine-readable report of one round as one line of JSON
+// to the destination set by JSON_REPORT. Files are appended to, one report per line.
+func writeReport(ppfmt pp.PP, stdout io.Writer, destination string, report *updater.Report) {
+	if destination == "" || report == nil {
+		return
+	}
+
+	line, err := json.Marshal(report)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible,
+			"Could not encode the JSON report: %v; please report this at %s", err, pp.IssueReportingURL)
+		return
+	}
+	line = append(line, '\n')
+
+	if destination == config.JSONReportStdout {
+		_, err = stdout.Write(line)
+	} else {
+		err = appendToFile(destination, line)
+	}
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Could not write the JSON report to %s: %v", destination, err)
+	}
+}
+
+func appendToFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	return file.Close() //nolint:wrapcheck // The caller reports the path.
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+func testReport() *updater.Report {
+	return &updater.Report{
+		Kind:     "update",
+		OK:       true,
+		Families: []updater.FamilyReport{{Family: "IPv4", Available: true, RawEntries: []string{"192.0.2.1/32"}}},
+		Domains:  []updater.DomainReport{},
+		WAFLists: []updater.WAFListReport{},
+
+		LBPoolOrigins:    []updater.LBPoolOriginReport{},
+		GatewayLocations: []updater.GatewayLocationReport{},
+		AccessGroups:     []updater.AccessGroupReport{},
+		IPAccessRules:    []updater.IPAccessRulesReport{},
+		SpectrumApps:     []updater.SpectrumAppReport{},
+		WAFListRules:     []updater.WAFListRuleReport{},
+		WorkersKV:        []updater.WorkersKVReport{},
+
+		MirroredDomains: []updater.MirroredDomainReport{},
+	}
+}
+
+const testReportLine = `{"kind":"update","ok":true,` +
+	`"families":[{"family":"IPv4","available":true,"rawEntries":["192.0.2.1/32"]}],"domains":[],"wafLists":[],` +
+	`"lbPoolOrigins":[],"gatewayLocations":[],"accessGroups":[],"ipAccessRules":[],"spectrumApps":[],"wafListRules":[],"workersKV":[],` +
+	`"mirroredDomains":[]}` + "\n"
+
+func TestWriteReportStdout(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	var stdout bytes.Buffer
+
+	writeReport(mockPP, &stdout, "stdout", testReport())
+	writeReport(mockPP, &stdout, "stdout", nil)
+	writeReport(mockPP, &stdout, "", testReport())
+	require.Equal(t, testReportLine, stdout.String())
+}
+
+func TestWriteReportFile(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	var stdout bytes.Buffer
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+
+	writeReport(mockPP, &stdout, path, testReport())
+	writeReport(mockPP, &stdout, path, testReport())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat(testReportLine, 2), string(content))
+	require.Empty(t, stdout.String())
+}
+
+func TestWriteReportFileError(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	path := filepath.Join(t.TempDir(), "missing", "report.jsonl")
+
+	mockPP.EXPECT().Noticef(pp.EmojiError, "Could not write the JSON report to %s: %v", path, gomock.Any())
+	writeReport(mockPP, &bytes.Buffer{}, path, testReport())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+const (
+	// The failed parts of a round are first retried after retryMinDelay,
+	// and the delay doubles after each retry up to retryMaxDelay.
+	retryMinDelay = time.Minute
+	retryMaxDelay = 30 * time.Minute
+)
+
+// retryDelay gives the delay before the given retry, counting from 0.
+func retryDelay(retry int) time.Duration {
+	delay := retryMinDelay
+	for range retry {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// nextRetry gives the time of the next retry. It returns false when the
+// retries ran out or when the next scheduled round would come first.
+func nextRetry(now, next time.Time, retries, limit int) (time.Time, bool) {
+	if retries >= limit {
+		return time.Time{}, false
+	}
+	at := now.Add(retryDelay(retries))
+	if !next.IsZero() && !at.Before(next) {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+func timesWord(n int) string {
+	if n == 1 {
+		return "time"
+	}
+	return "times"
+}
+
+// retryNotification describes a round of updating and its retries in one
+// notification, so that transient failures fixed by the retries are not
+// notified on their own.
+func retryNotification(first, last updater.Message, retries int) notifier.Notification {
+	if retries == 0 {
+		return first.Notification()
+	}
+
+	var summary notifier.Message
+	if last.HeartbeatMessage.OK {
+		summary = notifier.NewMessagef("The failed updates succeeded after retrying %d %s.", retries, timesWord(retries))
+	} else {
+		summary = notifier.NewMessagef("Some updates still failed after retrying %d %s; "+
+			"they will be tried again in the next scheduled round.", retries, timesWord(retries))
+	}
+	return notifier.NewNotification(last.NotificationKind,
+		notifier.MergeMessages(first.NotifierMessage, summary, last.NotifierMessage))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, time.Minute, retryDelay(0))
+	require.Equal(t, 2*time.Minute, retryDelay(1))
+	require.Equal(t, 16*time.Minute, retryDelay(4))
+	require.Equal(t, 30*time.Minute, retryDelay(5))
+	require.Equal(t, 30*time.Minute, retryDelay(100))
+}
+
+func TestNextRetry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		next     time.Time
+		retries  int
+		limit    int
+		expected time.Time
+		ok       bool
+	}{
+		"first":     {now.Add(time.Hour), 0, 3, now.Add(time.Minute), true},
+		"third":     {now.Add(time.Hour), 2, 3, now.Add(4 * time.Minute), true},
+		"ran-out":   {now.Add(time.Hour), 3, 3, time.Time{}, false},
+		"disabled":  {now.Add(time.Hour), 0, 0, time.Time{}, false},
+		"too-late":  {now.Add(time.Minute), 0, 3, time.Time{}, false},
+		"no-next":   {time.Time{}, 1, 3, now.Add(2 * time.Minute), true},
+		"just-fits": {now.Add(time.Minute + time.Second), 0, 3, now.Add(time.Minute), true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			at, ok := nextRetry(now, tc.next, tc.retries, tc.limit)
+			require.Equal(t, tc.expected, at)
+			require.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestRetryNotification(t *testing.T) {
+	t.Parallel()
+
+	first := updater.Message{
+		HeartbeatMessage: heartbeat.NewMessagef(false, "Failed to set A records for example.org"),
+		NotifierMessage:  notifier.NewMessagef("Could not confirm that A records of example.org were updated."),
+		NotificationKind: notifier.KindUpdateFailure,
+		Report:           nil,
+	}
+	success := updater.Message{
+		HeartbeatMessage: heartbeat.NewMessagef(true, "Set A records for example.org to 192.0.2.1"),
+		NotifierMessage:  notifier.NewMessagef("Updated A records for example.org to 192.0.2.1."),
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}
+
+	for name, tc := range map[string]struct {
+		last     updater.Message
+		retries  int
+		expected notifier.Notification
+	}{
+		"no-retries": {first, 0, first.Notification()},
+		"success": {
+			success, 1,
+			notifier.NewNotification(notifier.KindUpdate, notifier.Message{
+				"Could not confirm that A records of example.org were updated.",
+				"The failed updates succeeded after retrying 1 time.",
+				"Updated A records for example.org to 192.0.2.1.",
+			}),
+		},
+		"failure": {
+			first, 3,
+			notifier.NewNotification(notifier.KindUpdateFailure, notifier.Message{
+				"Could not confirm that A records of example.org were updated.",
+				"Some updates still failed after retrying 3 times; they will be tried again in the next scheduled round.",
+				"Could not confirm that A records of example.org were updated.",
+			}),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, retryNotification(first, tc.last, tc.retries))
+		})
+	}
+}
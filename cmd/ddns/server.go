@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/health"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/signal"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+)
+
+const (
+	// serverReadHeaderTimeout limits slow clients of the HTTP servers.
+	serverReadHeaderTimeout = 10 * time.Second
+	// serverShutdownTimeout is how long the HTTP servers wait for the open
+	// requests when the updater stops.
+	serverShutdownTimeout = time.Second
+)
+
+// A listener is an HTTP server to start. The settings whose addresses are the
+// same share one listener.
+type listener struct {
+	addr     string
+	settings []string
+	mux      *http.ServeMux
+}
+
+// addListener returns the listener for addr, adding one if needed.
+func addListener(listeners []*listener, setting, addr string) ([]*listener, *http.ServeMux) {
+	for _, l := range listeners {
+		if l.addr == addr {
+			l.settings = append(l.settings, setting)
+			return listeners, l.mux
+		}
+	}
+	l := &listener{addr: addr, settings: []string{setting}, mux: http.NewServeMux()}
+	return append(listeners, l), l.mux
+}
+
+// The services are what the HTTP endpoints serve.
+type services struct {
+	registry *metrics.Registry // the metrics, or nil when they are disabled
+	monitor  *health.Monitor   // always present so that the main loop can report to it unconditionally
+	triggers *trigger.Queue    // the rounds requested through the webhook or the DynDNS2 server
+	pushes   *dyndns2.Server   // always present so that every profile can add its store of pushed addresses
+	servers  []*http.Server
+}
+
+// setupServers reads the settings of the HTTP endpoints and starts serving
+// them in the background. A new trigger wakes up sig.
+func setupServers(ppfmt pp.PP, file *config.File, sig signal.Handle) (services, bool) {
+	var svc services
+
+	metricsAddr, ok := config.ReadMetricsAddr(ppfmt, file)
+	if !ok {
+		return svc, false
+	}
+	healthAddr, ok := config.ReadHealthAddr(ppfmt, file)
+	if !ok {
+		return svc, false
+	}
+	readiness, ok := config.ReadReadiness(ppfmt, file)
+	if !ok {
+		return svc, false
+	}
+	triggerConfig, ok := config.ReadTrigger(ppfmt, file)
+	if !ok {
+		return svc, false
+	}
+	dyndns2Config, ok := config.ReadDynDNS2(ppfmt, file)
+	if !ok {
+		return svc, false
+	}
+
+	svc.monitor = health.NewMonitor(readiness, time.Now())
+	svc.triggers = trigger.NewQueue(sig.Trigger)
+	limiter := trigger.NewLimiter(svc.triggers, triggerConfig.MinInterval)
+	svc.pushes = dyndns2.NewServer(dyndns2Config.Username, dyndns2Config.Password,
+		dyndns2Config.UseClientAddress, limiter)
+	var listeners []*listener
+	var mux *http.ServeMux
+	if metricsAddr != "" {
+		listeners, mux = addListener(listeners, "METRICS_ADDR", metricsAddr)
+		svc.registry = metrics.NewRegistry()
+		mux.Handle("GET /metrics", svc.registry)
+	}
+	if healthAddr != "" {
+		listeners, mux = addListener(listeners, "HEALTH_ADDR", healthAddr)
+		svc.monitor.Register(mux, triggerConfig.Token)
+	}
+	if triggerConfig.Addr != "" {
+		listeners, mux = addListener(listeners, "TRIGGER_ADDR", triggerConfig.Addr)
+		trigger.New(triggerConfig.Token, limiter).Register(mux)
+	}
+	if dyndns2Config.Addr != "" {
+		listeners, mux = addListener(listeners, "DYNDNS2_ADDR", dyndns2Config.Addr)
+		svc.pushes.Register(mux)
+	}
+
+	for _, l := range listeners {
+		server, ok := startServer(ppfmt, strings.Join(l.settings, " and "), l.addr, l.mux)
+		if !ok {
+			svc.stop()
+			return services{}, false
+		}
+		svc.servers = append(svc.servers, server)
+	}
+	return svc, true
+}
+
+// startServer serves handler at addr in the background. The names of the
+// settings holding addr are used in the messages.
+func startServer(ppfmt pp.PP, settings, addr string, handler http.Handler) (*http.Server, bool) {
+	netListener, err := net.Listen("tcp", addr)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiUserError, "Failed to listen on %s (%s): %v", addr, settings, err)
+		return nil, false
+	}
+
+	server := &http.Server{ //nolint:exhaustruct
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+	}
+	go func() {
+		if err := server.Serve(netListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ppfmt.Noticef(pp.EmojiError, "The HTTP server on %s (%s) stopped: %v", addr, settings, err)
+		}
+	}()
+
+	ppfmt.Infof(pp.EmojiConfig, "Listening on %s (%s)", netListener.Addr(), settings)
+	return server, true
+}
+
+// stop stops the HTTP servers started by [setupServers].
+func (svc services) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	for _, server := range svc.servers {
+		_ = server.Shutdown(ctx)
+	}
+}
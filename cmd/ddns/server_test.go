@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddListener(t *testing.T) {
+	t.Parallel()
+
+	listeners, metricsMux := addListener(nil, "METRICS_ADDR", ":8080")
+	listeners, healthMux := addListener(listeners, "HEALTH_ADDR", ":8080")
+	require.Same(t, metricsMux, healthMux)
+	listeners, otherMux := addListener(listeners, "OTHER_ADDR", ":8081")
+	require.NotSame(t, metricsMux, otherMux)
+
+	require.Len(t, listeners, 2)
+	require.Equal(t, []string{"METRICS_ADDR", "HEALTH_ADDR"}, listeners[0].settings)
+	require.Equal(t, []string{"OTHER_ADDR"}, listeners[1].settings)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/state"
+)
+
+// A stateKeeper carries the cached DNS records across restarts through the
+// file set by STATE_FILE. The records last seen on the RFC 2136 server, if any,
+// are kept as well, but only to tell what changed. A nil stateKeeper keeps nothing.
+type stateKeeper struct {
+	path     string
+	cache    api.RecordCache
+	mirror   api.RecordSnapshotter // nil without a mirror
+	dryRun   bool
+	previous state.State // the state left by the last run
+	current  state.State
+	reported bool // whether the changes since the last run were reported
+}
+
+// newStateKeeper loads the state file and seeds the caches of the handle with
+// the records that have not expired. It returns nil when STATE_FILE is empty.
+// The mirror handle may be nil.
+func newStateKeeper(ppfmt pp.PP, path string, h api.Handle, mirror api.RecordHandle, dryRun bool, now time.Time,
+) *stateKeeper {
+	if path == "" {
+		return nil
+	}
+	cache, ok := h.(api.RecordCache)
+	if !ok {
+		ppfmt.Noticef(pp.EmojiImpossible,
+			"The API handle cannot keep its caches in the state file; please report this at %s", pp.IssueReportingURL)
+		return nil
+	}
+
+	var snapshotter api.RecordSnapshotter
+	if mirror != nil {
+		if snapshotter, ok = mirror.(api.RecordSnapshotter); !ok {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"The RFC 2136 handle cannot keep its records in the state file; please report this at %s",
+				pp.IssueReportingURL)
+		}
+	}
+
+	// Records saved under another credential or selector are dropped by Load.
+	previous := state.Load(ppfmt, path, state.NewScope(cache.RecordCacheScope()))
+	if n := cache.SeedRecords(previous.Snapshots(), now); n > 0 {
+		ppfmt.Infof(pp.EmojiNow, "Restored %d cached record list(s) from the state file %s",
+			n, pp.QuoteIfUnsafeInSentence(path))
+	}
+
+	return &stateKeeper{
+		path:     path,
+		cache:    cache,
+		mirror:   snapshotter,
+		dryRun:   dryRun,
+		previous: previous,
+		current:  previous,
+		reported: false,
+	}
+}
+
+// save merges the cached records into the state and writes the state file,
+// except in dry-run mode. The first call returns the address changes since
+// the last run, so that they can be notified once.
+func (k *stateKeeper) save(ppfmt pp.PP, now time.Time) []string {
+	if k == nil {
+		return nil
+	}
+
+	snapshots := k.cache.SnapshotRecords()
+	if k.mirror != nil {
+		snapshots = append(snapshots, k.mirror.SnapshotRecords()...)
+	}
+	k.current = state.Merge(k.current, snapshots, now)
+
+	var changes []string
+	if !k.reported {
+		k.reported = true
+		if !k.previous.IsEmpty() {
+			changes = state.Changes(k.previous, k.current)
+		}
+	}
+
+	if !k.dryRun {
+		state.Save(ppfmt, k.path, k.current)
+	}
+	return changes
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/state"
+)
+
+func newTestHandle(t *testing.T) api.Handle {
+	t.Helper()
+	return newTestHandleWithToken(t, "deadbeaf")
+}
+
+func newTestHandleWithToken(t *testing.T, token string) api.Handle {
+	t.Helper()
+
+	h, ok := api.CloudflareAuth{Token: token, BaseURL: ""}.New(pp.NewSilent(), api.HandleOptions{
+		CacheExpiration: 6 * time.Hour,
+		HandleOwnershipPolicy: api.HandleOwnershipPolicy{
+			ManagedRecordsCommentRegex:        nil,
+			ManagedWAFListItemsCommentRegex:   nil,
+			AllowWholeWAFListDeleteOnShutdown: false,
+			ManagedIPAccessRulesNotesRegex:    nil,
+			ManagedAccessGroupsNameRegex:      nil,
+		},
+	})
+	require.True(t, ok)
+	return h
+}
+
+func testScope(t *testing.T, h api.Handle) state.Scope {
+	t.Helper()
+
+	cache, ok := h.(api.RecordCache)
+	require.True(t, ok)
+	return state.NewScope(cache.RecordCacheScope())
+}
+
+func testSnapshot(ip string, fetchedAt time.Time) api.RecordSnapshot {
+	return api.RecordSnapshot{
+		IPFamily:  ipnet.IP4,
+		Domain:    "example.org",
+		ZoneID:    "zone",
+		AccountID: "account",
+		Records: []api.Record{{
+			ID: "record",
+			IP: netip.MustParseAddr(ip),
+			RecordParams: api.RecordParams{
+				TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil,
+			},
+		}},
+		FetchedAt: fetchedAt,
+	}
+}
+
+func TestStateKeeperDisabled(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	keeper := newStateKeeper(mockPP, "", newTestHandle(t), nil, false, time.Now())
+	require.Nil(t, keeper)
+	require.Nil(t, keeper.save(mockPP, time.Now()))
+}
+
+func TestStateKeeperUnsupportedHandle(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	mockPP.EXPECT().Noticef(pp.EmojiImpossible,
+		"The API handle cannot keep its caches in the state file; please report this at %s", pp.IssueReportingURL)
+	require.Nil(t, newStateKeeper(mockPP, "/data/state.json", mocks.NewMockHandle(mockCtrl), nil, false, time.Now()))
+}
+
+func TestStateKeeper(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	lastRun := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	h := newTestHandle(t)
+	require.True(t, state.Save(pp.NewSilent(), path,
+		state.Merge(state.New(testScope(t, h)), []api.RecordSnapshot{testSnapshot("192.0.2.1", lastRun)}, lastRun)))
+
+	mockPP.EXPECT().Infof(pp.EmojiNow, "Restored %d cached record list(s) from the state file %s", 1, path)
+	keeper := newStateKeeper(mockPP, path, h, nil, false, time.Now())
+	require.NotNil(t, keeper)
+
+	cache, ok := h.(api.RecordCache)
+	require.True(t, ok)
+	require.Len(t, cache.SnapshotRecords(), 1)
+
+	// The address changes during the first round.
+	now := time.Now()
+	require.Equal(t, 1, cache.SeedRecords([]api.RecordSnapshot{testSnapshot("192.0.2.2", now)}, now))
+	require.Equal(t, []string{
+		"Since the last run at " + lastRun.Format(time.RFC3339) +
+			", the A records for example.org changed from 192.0.2.1 to 192.0.2.2.",
+	}, keeper.save(mockPP, now))
+
+	saved := state.Load(mockPP, path, testScope(t, h))
+	require.Len(t, saved.Entries, 1)
+	require.Equal(t, "192.0.2.2", saved.Entries[0].Records[0].IP.String())
+
+	// The changes are only reported once.
+	require.Nil(t, keeper.save(mockPP, time.Now()))
+}
+
+func TestStateKeeperOtherScope(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	lastRun := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	require.True(t, state.Save(pp.NewSilent(), path,
+		state.Merge(state.New(testScope(t, newTestHandleWithToken(t, "another"))),
+			[]api.RecordSnapshot{testSnapshot("192.0.2.1", lastRun)}, lastRun)))
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	h := newTestHandle(t)
+
+	mockPP.EXPECT().Noticef(pp.EmojiWarning,
+		"The state file %s was saved with another API credential or MANAGED_RECORDS_COMMENT_REGEX; starting afresh",
+		path)
+	keeper := newStateKeeper(mockPP, path, h, nil, false, time.Now())
+	require.NotNil(t, keeper)
+
+	cache, ok := h.(api.RecordCache)
+	require.True(t, ok)
+	require.Empty(t, cache.SnapshotRecords())
+
+	// Nothing is reported, and the state is saved in the new scope.
+	now := time.Now()
+	cache.SeedRecords([]api.RecordSnapshot{testSnapshot("192.0.2.2", now)}, now)
+	require.Nil(t, keeper.save(mockPP, now))
+	require.Len(t, state.Load(mockPP, path, testScope(t, h)).Entries, 1)
+}
+
+func TestStateKeeperDryRun(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	h := newTestHandle(t)
+
+	keeper := newStateKeeper(mockPP, path, h, nil, true, time.Now())
+	require.NotNil(t, keeper)
+
+	cache, ok := h.(api.RecordCache)
+	require.True(t, ok)
+	now := time.Now()
+	cache.SeedRecords([]api.RecordSnapshot{testSnapshot("192.0.2.1", now)}, now)
+	require.Nil(t, keeper.save(mockPP, now))
+
+	_, err := os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -5,11 +5,13 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"net"
 	"net/netip"
 	"time"
 
 	"github.com/favonia/cloudflare-ddns/internal/domain"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
@@ -37,6 +39,182 @@ func CompareWAFList(l1, l2 WAFList) int {
 	)
 }
 
+// LBPoolOrigin represents one origin of a load balancer pool, identified by its name.
+type LBPoolOrigin struct {
+	AccountID  ID
+	PoolID     ID
+	OriginName string
+}
+
+// Describe formats LBPoolOrigin as a string.
+func (o LBPoolOrigin) Describe() string {
+	return fmt.Sprintf("%s/%s:%s", string(o.AccountID), string(o.PoolID), o.OriginName)
+}
+
+// CompareLBPoolOrigin compares two origins by account ID, pool ID, and then origin name.
+func CompareLBPoolOrigin(o1, o2 LBPoolOrigin) int {
+	return cmp.Or(
+		cmp.Compare(o1.AccountID, o2.AccountID),
+		cmp.Compare(o1.PoolID, o2.PoolID),
+		cmp.Compare(o1.OriginName, o2.OriginName),
+	)
+}
+
+// GatewayLocation represents a Zero Trust Gateway DNS location, identified by its name.
+type GatewayLocation struct {
+	AccountID ID
+	Name      string
+}
+
+// Describe formats GatewayLocation as a string.
+func (l GatewayLocation) Describe() string { return fmt.Sprintf("%s/%s", string(l.AccountID), l.Name) }
+
+// CompareGatewayLocation compares two locations first by account ID and then by name.
+func CompareGatewayLocation(l1, l2 GatewayLocation) int {
+	return cmp.Or(
+		cmp.Compare(l1.AccountID, l2.AccountID),
+		cmp.Compare(l1.Name, l2.Name),
+	)
+}
+
+// AccessGroup represents a Cloudflare Access group, identified by its name.
+type AccessGroup struct {
+	AccountID ID
+	Name      string
+}
+
+// Describe formats AccessGroup as a string.
+func (g AccessGroup) Describe() string { return fmt.Sprintf("%s/%s", string(g.AccountID), g.Name) }
+
+// CompareAccessGroup compares two groups first by account ID and then by name.
+func CompareAccessGroup(g1, g2 AccessGroup) int {
+	return cmp.Or(
+		cmp.Compare(g1.AccountID, g2.AccountID),
+		cmp.Compare(g1.Name, g2.Name),
+	)
+}
+
+// IPAccessRuleScope is the level at which a set of IP Access Rules applies.
+type IPAccessRuleScope string
+
+const (
+	// IPAccessRuleScopeZone means the rules apply to one zone.
+	IPAccessRuleScopeZone IPAccessRuleScope = "zone"
+	// IPAccessRuleScopeAccount means the rules apply to all zones of an account.
+	IPAccessRuleScopeAccount IPAccessRuleScope = "account"
+)
+
+// IPAccessRuleMode is the action of IP Access Rules, as written in the configuration.
+type IPAccessRuleMode string
+
+const (
+	// IPAccessRuleModeAllow allows the requests (called "whitelist" by the API).
+	IPAccessRuleModeAllow IPAccessRuleMode = "allow"
+	// IPAccessRuleModeBlock blocks the requests.
+	IPAccessRuleModeBlock IPAccessRuleMode = "block"
+	// IPAccessRuleModeChallenge challenges the requests.
+	IPAccessRuleModeChallenge IPAccessRuleMode = "challenge"
+)
+
+// IPAccessRuleSet represents the IP Access Rules of one mode in one zone or account.
+type IPAccessRuleSet struct {
+	Scope   IPAccessRuleScope
+	ScopeID ID
+	Mode    IPAccessRuleMode
+}
+
+// Describe formats IPAccessRuleSet as a string.
+func (s IPAccessRuleSet) Describe() string {
+	return fmt.Sprintf("%s/%s:%s", string(s.Scope), string(s.ScopeID), string(s.Mode))
+}
+
+// CompareIPAccessRuleSet compares two sets by scope, scope ID, and then mode.
+func CompareIPAccessRuleSet(s1, s2 IPAccessRuleSet) int {
+	return cmp.Or(
+		cmp.Compare(s1.Scope, s2.Scope),
+		cmp.Compare(s1.ScopeID, s2.ScopeID),
+		cmp.Compare(s1.Mode, s2.Mode),
+	)
+}
+
+// IPAccessRule represents one IP Access Rule: ID, IP range, and notes.
+type IPAccessRule struct {
+	ID     ID
+	Prefix netip.Prefix
+	Notes  string
+}
+
+// SpectrumApp represents a Spectrum application, identified by its zone ID and application ID.
+type SpectrumApp struct {
+	ZoneID ID
+	AppID  ID
+}
+
+// Describe formats SpectrumApp as a string.
+func (a SpectrumApp) Describe() string {
+	return fmt.Sprintf("%s/%s", string(a.ZoneID), string(a.AppID))
+}
+
+// CompareSpectrumApp compares two Spectrum applications by zone ID and then application ID.
+func CompareSpectrumApp(a1, a2 SpectrumApp) int {
+	return cmp.Or(
+		cmp.Compare(a1.ZoneID, a2.ZoneID),
+		cmp.Compare(a1.AppID, a2.AppID),
+	)
+}
+
+// SpectrumOrigin is one direct origin of a Spectrum application, such as
+// "tcp://192.0.2.1:22". The port is kept as text because it may be a range.
+type SpectrumOrigin struct {
+	Scheme  string
+	Address netip.Addr
+	Port    string
+}
+
+// String formats SpectrumOrigin in the format used by the API.
+func (o SpectrumOrigin) String() string {
+	return o.Scheme + "://" + net.JoinHostPort(o.Address.String(), o.Port)
+}
+
+// WAFListRule identifies the WAF custom rule of a zone that references the
+// managed WAF lists. The updater keeps at most one such rule in each zone.
+type WAFListRule struct {
+	ZoneID ID
+	Action string
+}
+
+// Describe formats WAFListRule as a string.
+func (r WAFListRule) Describe() string {
+	return fmt.Sprintf("%s:%s", string(r.ZoneID), r.Action)
+}
+
+// WAFCustomRule is a rule in the entry point ruleset of the custom rules of a zone.
+type WAFCustomRule struct {
+	ID          ID
+	Action      string
+	Expression  string
+	Description string
+	Enabled     bool
+}
+
+// WorkersKVKey identifies a key in a Workers KV namespace.
+type WorkersKVKey struct {
+	AccountID   ID
+	NamespaceID ID
+	Key         string
+}
+
+// Describe formats WorkersKVKey as a string.
+func (k WorkersKVKey) Describe() string {
+	return fmt.Sprintf("%s/%s:%s", string(k.AccountID), string(k.NamespaceID), k.Key)
+}
+
+// LBPoolOriginState is the part of a load balancer pool origin managed by the updater.
+type LBPoolOriginState struct {
+	Address netip.Addr
+	Enabled bool
+}
+
 // RecordParams bundles parameters of a DNS record.
 type RecordParams struct {
 	TTL     TTL
@@ -82,6 +260,35 @@ const (
 	WAFListCleanupFailed
 )
 
+// PermissionReport summarizes the startup check of the credentials against the
+// configured domains and WAF lists. The check is advisory; problems it cannot
+// decide are left to the first update.
+type PermissionReport struct {
+	// Verified means the credentials were confirmed to be accepted by the API.
+	Verified bool
+	// Inspected means the permissions were compared against all the domains and WAF lists.
+	Inspected bool
+	// CredentialProblem is a short description (such as "expired") of why the
+	// credentials are unusable. It is empty if no such problem was found.
+	CredentialProblem string
+	// ExpiresOn is the upcoming expiry of the credentials, if it is soon.
+	ExpiresOn time.Time
+	// DomainsWithoutZone lists the domains whose zones could not be found.
+	DomainsWithoutZone []domain.Domain
+	// DomainsWithoutDNSEdit lists the domains whose zones the credentials cannot edit.
+	DomainsWithoutDNSEdit []domain.Domain
+	// AccountsWithoutWAFListEdit lists the accounts whose WAF lists the credentials cannot edit.
+	AccountsWithoutWAFListEdit []ID
+}
+
+// HasProblems checks whether the report found anything that will make updates fail.
+func (r PermissionReport) HasProblems() bool {
+	return r.CredentialProblem != "" ||
+		len(r.DomainsWithoutZone) > 0 ||
+		len(r.DomainsWithoutDNSEdit) > 0 ||
+		len(r.AccountsWithoutWAFListEdit) > 0
+}
+
 // DeletionMode tells the deletion updater whether a careful re-reading of lists
 // must be enforced if an error happens.
 type DeletionMode bool
@@ -99,11 +306,14 @@ type HandleOptions struct {
 	HandleOwnershipPolicy
 
 	CacheExpiration time.Duration
+
+	// Metrics receives the measurements of the API calls and the caches; nil
+	// means that nothing is recorded.
+	Metrics metrics.Recorder
 }
 
-// A Handle represents a generic API to update DNS records and WAF lists.
-// Currently, the only implementation is Cloudflare.
-type Handle interface {
+// A RecordHandle represents a generic API to update DNS records.
+type RecordHandle interface {
 	// ListRecords lists managed DNS records matching the given domain/IP-family scope.
 	// The managed-record selector is bound into the handle options because
 	// implementations may cache filtered records by domain/IP-family scope.
@@ -134,7 +344,10 @@ type Handle interface {
 	DeleteRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family,
 		domain domain.Domain, id ID, mode DeletionMode,
 	) bool
+}
 
+// A WAFListHandle represents a generic API to update WAF lists.
+type WAFListHandle interface {
 	// ListWAFListItems returns managed WAF list items with their IP ranges.
 	// It does not create the list if it does not exist.
 	//
@@ -167,6 +380,120 @@ type Handle interface {
 		items []WAFListCreateItem) bool
 }
 
+// An LBPoolHandle represents a generic API to update load balancer pool origins.
+type LBPoolHandle interface {
+	// GetLBPoolOrigin reads the address and the enabled state of one origin of
+	// a load balancer pool. It fails if the pool does not have exactly one origin
+	// with the name, or if the address of the origin is not an IP address.
+	GetLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin LBPoolOrigin) (LBPoolOriginState, bool)
+
+	// UpdateLBPoolOrigin sets the address and the enabled state of one origin of
+	// a load balancer pool. Other origins and other settings of the pool are kept.
+	UpdateLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin LBPoolOrigin, desired LBPoolOriginState) bool
+}
+
+// A GatewayLocationHandle represents a generic API to update Zero Trust Gateway DNS locations.
+type GatewayLocationHandle interface {
+	// ListGatewayLocationNetworks reads the source networks of a Zero Trust
+	// Gateway DNS location. It fails if the account does not have exactly one
+	// location with the name.
+	ListGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location GatewayLocation) ([]netip.Prefix, bool)
+
+	// SetGatewayLocationNetworks replaces the source networks of a Zero Trust
+	// Gateway DNS location. Other settings of the location are kept.
+	SetGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location GatewayLocation,
+		networks []netip.Prefix) bool
+}
+
+// An AccessGroupHandle represents a generic API to update Access groups.
+type AccessGroupHandle interface {
+	// ListAccessGroupIPRules reads the IP ranges of the "ip" include rules of an
+	// Access group. It fails if the account does not have exactly one group
+	// with the name.
+	ListAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group AccessGroup) ([]netip.Prefix, bool)
+
+	// SetAccessGroupIPRules replaces the "ip" include rules of an Access group.
+	// Other rules and other settings of the group are kept.
+	SetAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group AccessGroup, prefixes []netip.Prefix) bool
+}
+
+// An IPAccessRuleHandle represents a generic API to update IP Access Rules.
+type IPAccessRuleHandle interface {
+	// ListIPAccessRules lists the managed IP Access Rules of a set. Rules whose
+	// notes are not selected by the ownership policy are not returned.
+	ListIPAccessRules(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet) ([]IPAccessRule, bool)
+
+	// CreateIPAccessRule creates an IP Access Rule for an IP range.
+	CreateIPAccessRule(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet, prefix netip.Prefix, notes string,
+	) (ID, bool)
+
+	// DeleteIPAccessRule deletes an IP Access Rule.
+	DeleteIPAccessRule(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet, id ID) bool
+}
+
+// A SpectrumAppHandle represents a generic API to update Spectrum applications.
+type SpectrumAppHandle interface {
+	// GetSpectrumAppOrigins reads the direct origins of a Spectrum application.
+	// It fails if the application does not use direct origins, or if an origin
+	// does not use an IP address.
+	GetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app SpectrumApp) ([]SpectrumOrigin, bool)
+
+	// SetSpectrumAppOrigins replaces the direct origins of a Spectrum application.
+	// Other settings of the application are kept.
+	SetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app SpectrumApp, origins []SpectrumOrigin) bool
+}
+
+// A WAFCustomRuleHandle represents a generic API to update the WAF custom rules of zones.
+type WAFCustomRuleHandle interface {
+	// ListWAFCustomRules lists the custom rules of a zone. The returned ruleset ID
+	// is empty if the zone does not have any custom rules yet.
+	ListWAFCustomRules(ctx context.Context, ppfmt pp.PP, zoneID ID) (ID, []WAFCustomRule, bool)
+
+	// CreateWAFCustomRule adds a custom rule to a zone. An empty ruleset ID means
+	// the ruleset of the custom rules does not exist yet and should be created.
+	CreateWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID ID, rulesetID ID, rule WAFCustomRule) (ID, bool)
+
+	// UpdateWAFCustomRule replaces the action, the expression, the description,
+	// and the status of a custom rule. The position of the rule is kept.
+	UpdateWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID ID, rulesetID ID, rule WAFCustomRule) bool
+
+	// DeleteWAFCustomRule deletes a custom rule.
+	DeleteWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID ID, rulesetID ID, ruleID ID) bool
+}
+
+// A WorkersKVHandle represents a generic API to update Workers KV keys.
+type WorkersKVHandle interface {
+	// GetWorkersKVValue reads the value of a key in a Workers KV namespace.
+	// The value is nil if the key does not exist.
+	GetWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey) ([]byte, bool)
+
+	// PutWorkersKVValue writes the value of a key in a Workers KV namespace.
+	PutWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey, value []byte) bool
+
+	// DeleteWorkersKVValue deletes a key in a Workers KV namespace.
+	// Deleting a key that does not exist is not an error.
+	DeleteWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey) bool
+}
+
+// A Handle represents a generic API to update DNS records, WAF lists, and the
+// other Cloudflare resources. Currently, the only implementation is Cloudflare.
+type Handle interface {
+	RecordHandle
+	WAFListHandle
+	LBPoolHandle
+	GatewayLocationHandle
+	AccessGroupHandle
+	IPAccessRuleHandle
+	SpectrumAppHandle
+	WAFCustomRuleHandle
+	WorkersKVHandle
+
+	// CheckPermissions verifies the credentials and compares their permissions
+	// against the zones of the domains and the accounts of the WAF lists.
+	// It never changes remote state.
+	CheckPermissions(ctx context.Context, ppfmt pp.PP, domains []domain.Domain, lists []WAFList) PermissionReport
+}
+
 // An Auth contains authentication information.
 type Auth interface {
 	// New uses the authentication information to create a Handle.
@@ -2,12 +2,15 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/jellydator/ttlcache/v3"
+	"golang.org/x/time/rate"
 
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
@@ -47,6 +50,7 @@ func newCache[K comparable, V any](cacheExpiration time.Duration) *ttlcache.Cach
 // A cloudflareHandle implements the [Handle] interface with the Cloudflare API.
 type cloudflareHandle struct {
 	cf      *cloudflare.API
+	guard   *apiGuard
 	options HandleOptions
 	cache   cloudflareCache
 }
@@ -59,16 +63,88 @@ type CloudflareAuth struct {
 
 // New creates a [cloudflareHandle] from the authentication data and handle options.
 func (t CloudflareAuth) New(ppfmt pp.PP, options HandleOptions) (Handle, bool) {
-	handle, err := t.newClient()
+	guard := newSharedAPIGuard(http.DefaultTransport, options.Metrics, fingerprint(t.BaseURL, t.Token, "", ""))
+	handle, err := t.newClient(guard)
 	if err != nil {
 		ppfmt.Noticef(pp.EmojiUserError, "Failed to prepare the Cloudflare API client: %v", err)
 		return nil, false
 	}
 
+	return newCloudflareHandle(ppfmt, handle, guard, options), true
+}
+
+func (t CloudflareAuth) newClient(guard *apiGuard) (*cloudflare.API, error) {
+	handle, err := cloudflare.NewWithAPIToken(t.Token, guardedClientOptions(guard)...)
+	if err != nil {
+		return nil, fmt.Errorf("create Cloudflare API client: %w", err)
+	}
+
+	// set the base URL (mostly for testing)
+	if t.BaseURL != "" {
+		handle.BaseURL = t.BaseURL
+	}
+
+	return handle, nil
+}
+
+// A CloudflareGlobalKeyAuth implements the [Auth] interface with the legacy
+// global API key and the email address of its owner.
+//
+// A global API key carries every permission of its owner across all accounts
+// and zones. It exists only for deployments that cannot create scoped tokens;
+// prefer [CloudflareAuth] whenever possible.
+type CloudflareGlobalKeyAuth struct {
+	Key     string
+	Email   string
+	BaseURL string
+}
+
+// New creates a [cloudflareHandle] from the legacy global API key and handle options.
+func (t CloudflareGlobalKeyAuth) New(ppfmt pp.PP, options HandleOptions) (Handle, bool) {
+	guard := newSharedAPIGuard(http.DefaultTransport, options.Metrics, fingerprint(t.BaseURL, "", t.Email, t.Key))
+	handle, err := t.newClient(guard)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiUserError, "Failed to prepare the Cloudflare API client: %v", err)
+		return nil, false
+	}
+
+	return newCloudflareHandle(ppfmt, handle, guard, options), true
+}
+
+func (t CloudflareGlobalKeyAuth) newClient(guard *apiGuard) (*cloudflare.API, error) {
+	handle, err := cloudflare.New(t.Key, t.Email, guardedClientOptions(guard)...)
+	if err != nil {
+		return nil, fmt.Errorf("create Cloudflare API client: %w", err)
+	}
+
+	// set the base URL (mostly for testing)
+	if t.BaseURL != "" {
+		handle.BaseURL = t.BaseURL
+	}
+
+	return handle, nil
+}
+
+// guardedClientOptions sends every call through the guard, which takes over
+// rate limiting and retrying from cloudflare-go. The built-in limiter of
+// cloudflare-go ignores Retry-After, and its retries would also retry the
+// calls refused by the circuit breaker.
+func guardedClientOptions(guard *apiGuard) []cloudflare.Option {
+	return []cloudflare.Option{
+		cloudflare.HTTPClient(&http.Client{Transport: guard}), //nolint:exhaustruct
+		cloudflare.UsingRateLimit(float64(rate.Inf)),
+		cloudflare.UsingRetryPolicy(0, 0, 0),
+	}
+}
+
+// newCloudflareHandle wraps a prepared Cloudflare API client with fresh caches.
+// It is shared by every [Auth] implementation backed by the Cloudflare API.
+func newCloudflareHandle(ppfmt pp.PP, cf *cloudflare.API, guard *apiGuard, options HandleOptions) cloudflareHandle {
 	options.HandleOwnershipPolicy = options.Sanitize(ppfmt)
 
 	h := cloudflareHandle{
-		cf:      handle,
+		cf:      cf,
+		guard:   guard,
 		options: options,
 		cache: cloudflareCache{
 			listZones:    newCache[string, []zoneMeta](options.CacheExpiration),
@@ -82,22 +158,30 @@ func (t CloudflareAuth) New(ppfmt pp.PP, options HandleOptions) (Handle, bool) {
 			listListItems: newCache[WAFList, *[]WAFListItem](options.CacheExpiration),
 		},
 	}
-
-	return h, true
+	h.observeCaches(metrics.OrNoop(options.Metrics))
+	return h
 }
 
-func (t CloudflareAuth) newClient() (*cloudflare.API, error) {
-	handle, err := cloudflare.NewWithAPIToken(t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("create Cloudflare API client: %w", err)
-	}
-
-	// set the base URL (mostly for testing)
-	if t.BaseURL != "" {
-		handle.BaseURL = t.BaseURL
+// cacheStats reads the hits and misses of some caches.
+func cacheStats[K comparable, V any](caches ...*ttlcache.Cache[K, V]) func() (uint64, uint64) {
+	return func() (uint64, uint64) {
+		var hits, misses uint64
+		for _, cache := range caches {
+			m := cache.Metrics()
+			hits, misses = hits+m.Hits, misses+m.Misses
+		}
+		return hits, misses
 	}
+}
 
-	return handle, nil
+// observeCaches registers the caches with the recorder of the metrics.
+func (h cloudflareHandle) observeCaches(m metrics.Recorder) {
+	m.ObserveCache("zones", cacheStats(h.cache.listZones))
+	m.ObserveCache("zone_of_domain", cacheStats(h.cache.zoneOfDomain))
+	m.ObserveCache("records", cacheStats(h.cache.listRecords[ipnet.IP4], h.cache.listRecords[ipnet.IP6]))
+	m.ObserveCache("waf_lists", cacheStats(h.cache.listLists))
+	m.ObserveCache("waf_list_ids", cacheStats(h.cache.listID))
+	m.ObserveCache("waf_list_items", cacheStats(h.cache.listListItems))
 }
 
 // flushCache flushes the API cache.
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// accessIPRule is the body of an "ip" rule of an Access group.
+type accessIPRule struct {
+	IP string `json:"ip"`
+}
+
+func hintAccessGroupPermission(ppfmt pp.PP, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if errors.As(err, &authentication) || errors.As(err, &authorization) {
+		pp.HintEditPermission(ppfmt, pp.FeatureAccessGroups,
+			"Account - Access: Organizations, Identity Providers, and Groups", "account ID")
+	}
+}
+
+// readAccessGroup finds the group by its name and checks that the name marks it
+// as managed. Like Gateway locations, the group is kept as a raw JSON object so
+// that the rules unknown to the updater are sent back unchanged.
+func (h cloudflareHandle) readAccessGroup(ctx context.Context, ppfmt pp.PP, group AccessGroup,
+) (map[string]json.RawMessage, bool) {
+	res, err := h.cf.Raw(ctx, http.MethodGet, fmt.Sprintf("/accounts/%s/access/groups", group.AccountID), nil, nil)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to list Access groups of the account %s: %v", group.AccountID, err)
+		hintAccessGroupPermission(ppfmt, err)
+		return nil, false
+	}
+
+	var groups []map[string]json.RawMessage
+	if err := json.Unmarshal(res.Result, &groups); err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to parse Access groups of the account %s: %v", group.AccountID, err)
+		return nil, false
+	}
+
+	var found map[string]json.RawMessage
+	for _, g := range groups {
+		var name string
+		if err := json.Unmarshal(g["name"], &name); err != nil || name != group.Name {
+			continue
+		}
+		if found != nil {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"Found multiple Access groups named %q in the account %s; the updater will not change them",
+				group.Name, group.AccountID)
+			return nil, false
+		}
+		found = g
+	}
+	if found == nil {
+		ppfmt.Noticef(pp.EmojiUserError, "Found no Access group named %q in the account %s", group.Name, group.AccountID)
+		return nil, false
+	}
+	if !h.options.MatchManagedAccessGroupName(group.Name) {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The name of the Access group %s does not match MANAGED_ACCESS_GROUPS_NAME_REGEX=%q; "+
+				"the updater will not change its rules",
+			group.Describe(), h.options.ManagedAccessGroupsNameRegex.String())
+		return nil, false
+	}
+
+	return found, true
+}
+
+// parseAccessIPRule parses the IP range of an "ip" rule. A single address is
+// read as a full-length prefix.
+func parseAccessIPRule(raw json.RawMessage) (netip.Prefix, error) {
+	var rule accessIPRule
+	if err := json.Unmarshal(raw, &rule); err != nil {
+		return netip.Prefix{}, err
+	}
+	if ip, err := netip.ParseAddr(rule.IP); err == nil {
+		return netip.PrefixFrom(ip, ip.BitLen()), nil
+	}
+	return netip.ParsePrefix(rule.IP)
+}
+
+// splitAccessGroupIncludeRules separates the "ip" include rules, which are managed
+// by the updater once the group is marked, from the other include rules, which
+// are kept as they are.
+func splitAccessGroupIncludeRules(ppfmt pp.PP, group AccessGroup, raw map[string]json.RawMessage,
+) ([]netip.Prefix, []map[string]json.RawMessage, bool) {
+	var rules []map[string]json.RawMessage
+	if value, ok := raw["include"]; ok && string(value) != "null" {
+		if err := json.Unmarshal(value, &rules); err != nil {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"Failed to parse the include rules of the Access group %s: %v", group.Describe(), err)
+			return nil, nil, false
+		}
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(rules))
+	others := make([]map[string]json.RawMessage, 0, len(rules))
+	for _, rule := range rules {
+		value, ok := rule["ip"]
+		if !ok {
+			others = append(others, rule)
+			continue
+		}
+		prefix, err := parseAccessIPRule(value)
+		if err != nil {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"Failed to parse the IP rule %s of the Access group %s: %v", string(value), group.Describe(), err)
+			return nil, nil, false
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, others, true
+}
+
+// ListAccessGroupIPRules reads the IP ranges of the "ip" include rules of an Access group.
+func (h cloudflareHandle) ListAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group AccessGroup,
+) ([]netip.Prefix, bool) {
+	raw, ok := h.readAccessGroup(ctx, ppfmt, group)
+	if !ok {
+		return nil, false
+	}
+	prefixes, _, ok := splitAccessGroupIncludeRules(ppfmt, group, raw)
+	return prefixes, ok
+}
+
+// SetAccessGroupIPRules re-reads the group and sends it back with new "ip"
+// include rules. Other include rules are kept before the new "ip" rules.
+func (h cloudflareHandle) SetAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group AccessGroup,
+	prefixes []netip.Prefix,
+) bool {
+	raw, ok := h.readAccessGroup(ctx, ppfmt, group)
+	if !ok {
+		return false
+	}
+
+	var id string
+	if err := json.Unmarshal(raw["id"], &id); err != nil || id == "" {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to find the ID of the Access group %s", group.Describe())
+		return false
+	}
+
+	_, rules, ok := splitAccessGroupIncludeRules(ppfmt, group, raw)
+	if !ok {
+		return false
+	}
+	for _, prefix := range prefixes {
+		value, _ := json.Marshal(accessIPRule{IP: prefix.String()})
+		rules = append(rules, map[string]json.RawMessage{"ip": value})
+	}
+	include, err := json.Marshal(rules)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the include rules of the Access group %s: %v",
+			group.Describe(), err)
+		return false
+	}
+	raw["include"] = include
+
+	if _, err := h.cf.Raw(ctx, http.MethodPut,
+		fmt.Sprintf("/accounts/%s/access/groups/%s", group.AccountID, id), raw, nil); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the Access group %s: %v", group.Describe(), err)
+		hintAccessGroupPermission(ppfmt, err)
+		return false
+	}
+
+	return true
+}
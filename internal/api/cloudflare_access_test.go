@@ -0,0 +1,193 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func mockAccessGroup() api.AccessGroup {
+	return api.AccessGroup{AccountID: mockAccountID, Name: "office"}
+}
+
+func ipRule(ip string) map[string]any { return map[string]any{"ip": map[string]any{"ip": ip}} }
+
+// handleAccessGroups serves the Access group endpoints. GET requests return the
+// groups; PUT requests are decoded into updated.
+func handleAccessGroups(t *testing.T, serveMux *http.ServeMux, groups []map[string]any, updated *map[string]any,
+) httpHandler {
+	t.Helper()
+
+	requestLimit := new(int)
+	serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/access/groups", mockAccountID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			assert.Equal(t, http.MethodGet, r.Method)
+			writeJSON(t, w, http.StatusOK, mockResultResponse(groups))
+		})
+	serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/access/groups/{id}", mockAccountID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.Equal(t, "group1", r.PathValue("id"))
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(body, updated))
+			writeJSON(t, w, http.StatusOK, mockResultResponse(*updated))
+		})
+
+	return httpHandler{requestLimit: requestLimit}
+}
+
+func TestListAccessGroupIPRules(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		regex         string
+		groups        []map[string]any
+		expected      []netip.Prefix
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"ip-rules": {
+			"",
+			[]map[string]any{
+				{"id": "group0", "name": "lab", "include": []any{ipRule("203.0.113.0/24")}},
+				{"id": "group1", "name": "office", "include": []any{
+					map[string]any{"email": map[string]any{"email": "admin@example.org"}},
+					ipRule("192.0.2.1"),
+					ipRule("2001:db8::/48"),
+				}},
+			},
+			[]netip.Prefix{netip.MustParsePrefix("192.0.2.1/32"), netip.MustParsePrefix("2001:db8::/48")},
+			true,
+			nil,
+		},
+		"marked": {
+			"^office$",
+			[]map[string]any{{"id": "group1", "name": "office", "include": []any{ipRule("192.0.2.1")}}},
+			[]netip.Prefix{netip.MustParsePrefix("192.0.2.1/32")},
+			true,
+			nil,
+		},
+		"unmarked": {
+			"^ddns-",
+			[]map[string]any{{"id": "group1", "name": "office", "include": []any{ipRule("192.0.2.1")}}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The name of the Access group %s does not match MANAGED_ACCESS_GROUPS_NAME_REGEX=%q; the updater will not change its rules", "account456/office", "^ddns-")
+			},
+		},
+		"missing": {
+			"",
+			[]map[string]any{{"id": "group0", "name": "lab"}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "Found no Access group named %q in the account %s", "office", mockAccountID)
+			},
+		},
+		"duplicate": {
+			"",
+			[]map[string]any{{"id": "group1", "name": "office"}, {"id": "group2", "name": "office"}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "Found multiple Access groups named %q in the account %s; the updater will not change them", "office", mockAccountID)
+			},
+		},
+		"invalid-rule": {
+			"",
+			[]map[string]any{{"id": "group1", "name": "office", "include": []any{ipRule("office")}}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiImpossible, "Failed to parse the IP rule %s of the Access group %s: %v", `{"ip":"office"}`, "account456/office", gomock.Any())
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			options := defaultHandleOptions()
+			options.ManagedAccessGroupsNameRegex = regexp.MustCompile(tc.regex)
+			f := newCloudflareHarnessWithOptions(t, options)
+			handler := handleAccessGroups(t, f.serveMux, tc.groups, nil)
+			handler.setRequestLimit(1)
+
+			mockPP := f.newPreparedPP(tc.prepareMockPP)
+			prefixes, ok := f.handle.ListAccessGroupIPRules(context.Background(), mockPP, mockAccessGroup())
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, prefixes)
+			assertHandlersExhausted(t, handler)
+		})
+	}
+}
+
+func TestSetAccessGroupIPRulesKeepsOtherRules(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	var updated map[string]any
+	email := map[string]any{"email": map[string]any{"email": "admin@example.org"}}
+	handler := handleAccessGroups(t, f.serveMux, []map[string]any{{
+		"id": "group1", "name": "office",
+		"include": []any{ipRule("192.0.2.1/32"), email},
+		"require": []any{map[string]any{"everyone": map[string]any{}}},
+	}}, &updated)
+	handler.setRequestLimit(2)
+
+	ok := f.handle.SetAccessGroupIPRules(context.Background(), f.newPP(), mockAccessGroup(),
+		[]netip.Prefix{netip.MustParsePrefix("198.51.100.8/32")})
+	require.True(t, ok)
+	require.Equal(t, map[string]any{
+		"id": "group1", "name": "office",
+		"include": []any{email, ipRule("198.51.100.8/32")},
+		"require": []any{map[string]any{"everyone": map[string]any{}}},
+	}, updated)
+	assertHandlersExhausted(t, handler)
+}
+
+func TestSetAccessGroupIPRulesUnmarked(t *testing.T) {
+	t.Parallel()
+
+	options := defaultHandleOptions()
+	options.ManagedAccessGroupsNameRegex = regexp.MustCompile("^ddns-")
+	f := newCloudflareHarnessWithOptions(t, options)
+	var updated map[string]any
+	handler := handleAccessGroups(t, f.serveMux, []map[string]any{{
+		"id": "group1", "name": "office", "include": []any{ipRule("192.0.2.1/32")},
+	}}, &updated)
+	handler.setRequestLimit(1)
+
+	mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+		m.EXPECT().Noticef(pp.EmojiUserError, "The name of the Access group %s does not match MANAGED_ACCESS_GROUPS_NAME_REGEX=%q; the updater will not change its rules", "account456/office", "^ddns-")
+	})
+	ok := f.handle.SetAccessGroupIPRules(context.Background(), mockPP, mockAccessGroup(),
+		[]netip.Prefix{netip.MustParsePrefix("198.51.100.8/32")})
+	require.False(t, ok)
+	require.Nil(t, updated)
+	assertHandlersExhausted(t, handler)
+}
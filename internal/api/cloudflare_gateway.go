@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// gatewayLocationNetwork is one source network of a Gateway DNS location.
+type gatewayLocationNetwork struct {
+	Network string `json:"network"`
+}
+
+func hintGatewayLocationPermission(ppfmt pp.PP, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if errors.As(err, &authentication) || errors.As(err, &authorization) {
+		pp.HintEditPermission(ppfmt, pp.FeatureGatewayLocations, "Account - Zero Trust", "account ID")
+	}
+}
+
+// readGatewayLocation finds the location by its name. The location is kept as
+// a raw JSON object so that the settings unknown to the updater can be sent
+// back unchanged; the Gateway API only supports replacing whole locations.
+func (h cloudflareHandle) readGatewayLocation(ctx context.Context, ppfmt pp.PP, location GatewayLocation,
+) (map[string]json.RawMessage, bool) {
+	res, err := h.cf.Raw(ctx, http.MethodGet, fmt.Sprintf("/accounts/%s/gateway/locations", location.AccountID), nil, nil)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to list Gateway locations of the account %s: %v", location.AccountID, err)
+		hintGatewayLocationPermission(ppfmt, err)
+		return nil, false
+	}
+
+	var locations []map[string]json.RawMessage
+	if err := json.Unmarshal(res.Result, &locations); err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible,
+			"Failed to parse Gateway locations of the account %s: %v", location.AccountID, err)
+		return nil, false
+	}
+
+	var found map[string]json.RawMessage
+	for _, l := range locations {
+		var name string
+		if err := json.Unmarshal(l["name"], &name); err != nil || name != location.Name {
+			continue
+		}
+		if found != nil {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"Found multiple Gateway locations named %q in the account %s; the updater will not change them",
+				location.Name, location.AccountID)
+			return nil, false
+		}
+		found = l
+	}
+	if found == nil {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"Found no Gateway location named %q in the account %s", location.Name, location.AccountID)
+		return nil, false
+	}
+
+	return found, true
+}
+
+func parseGatewayLocationNetworks(ppfmt pp.PP, location GatewayLocation, raw map[string]json.RawMessage,
+) ([]netip.Prefix, bool) {
+	var networks []gatewayLocationNetwork
+	if value, ok := raw["networks"]; ok && string(value) != "null" {
+		if err := json.Unmarshal(value, &networks); err != nil {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"Failed to parse the networks of the Gateway location %s: %v", location.Describe(), err)
+			return nil, false
+		}
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, n := range networks {
+		prefix, err := netip.ParsePrefix(n.Network)
+		if err != nil {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"Failed to parse the network %q of the Gateway location %s: %v", n.Network, location.Describe(), err)
+			return nil, false
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, true
+}
+
+// ListGatewayLocationNetworks reads the source networks of a Gateway location.
+func (h cloudflareHandle) ListGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location GatewayLocation,
+) ([]netip.Prefix, bool) {
+	raw, ok := h.readGatewayLocation(ctx, ppfmt, location)
+	if !ok {
+		return nil, false
+	}
+	return parseGatewayLocationNetworks(ppfmt, location, raw)
+}
+
+// SetGatewayLocationNetworks re-reads the location and sends it back with new networks.
+func (h cloudflareHandle) SetGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location GatewayLocation,
+	networks []netip.Prefix,
+) bool {
+	raw, ok := h.readGatewayLocation(ctx, ppfmt, location)
+	if !ok {
+		return false
+	}
+
+	var id string
+	if err := json.Unmarshal(raw["id"], &id); err != nil || id == "" {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to find the ID of the Gateway location %s", location.Describe())
+		return false
+	}
+
+	items := make([]gatewayLocationNetwork, 0, len(networks))
+	for _, network := range networks {
+		items = append(items, gatewayLocationNetwork{Network: network.String()})
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the networks of the Gateway location %s: %v",
+			location.Describe(), err)
+		return false
+	}
+	raw["networks"] = encoded
+
+	if _, err := h.cf.Raw(ctx, http.MethodPut,
+		fmt.Sprintf("/accounts/%s/gateway/locations/%s", location.AccountID, id), raw, nil); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the Gateway location %s: %v", location.Describe(), err)
+		hintGatewayLocationPermission(ppfmt, err)
+		return false
+	}
+
+	return true
+}
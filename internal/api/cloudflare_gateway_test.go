@@ -0,0 +1,180 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func mockGatewayLocation() api.GatewayLocation {
+	return api.GatewayLocation{AccountID: mockAccountID, Name: "office"}
+}
+
+// handleGatewayLocations serves the Gateway location endpoints. GET requests
+// return the locations; PUT requests are decoded into updated.
+func handleGatewayLocations(t *testing.T, serveMux *http.ServeMux, locations []map[string]any,
+	updated *map[string]any,
+) httpHandler {
+	t.Helper()
+
+	requestLimit := new(int)
+	serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/gateway/locations", mockAccountID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			assert.Equal(t, http.MethodGet, r.Method)
+			writeJSON(t, w, http.StatusOK, mockResultResponse(locations))
+		})
+	serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/gateway/locations/{id}", mockAccountID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.Equal(t, "loc1", r.PathValue("id"))
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(body, updated))
+			writeJSON(t, w, http.StatusOK, mockResultResponse(*updated))
+		})
+
+	return httpHandler{requestLimit: requestLimit}
+}
+
+func TestListGatewayLocationNetworks(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		locations     []map[string]any
+		expected      []netip.Prefix
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"networks": {
+			[]map[string]any{
+				{"id": "loc0", "name": "lab", "networks": []any{map[string]any{"network": "203.0.113.0/24"}}},
+				{"id": "loc1", "name": "office", "networks": []any{
+					map[string]any{"network": "192.0.2.1/32"},
+					map[string]any{"network": "2001:db8::/48"},
+				}},
+			},
+			[]netip.Prefix{netip.MustParsePrefix("192.0.2.1/32"), netip.MustParsePrefix("2001:db8::/48")},
+			true,
+			nil,
+		},
+		"no-networks": {
+			[]map[string]any{{"id": "loc1", "name": "office", "networks": nil}},
+			[]netip.Prefix{},
+			true,
+			nil,
+		},
+		"missing": {
+			[]map[string]any{{"id": "loc0", "name": "lab"}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "Found no Gateway location named %q in the account %s", "office", mockAccountID)
+			},
+		},
+		"duplicate": {
+			[]map[string]any{{"id": "loc1", "name": "office"}, {"id": "loc2", "name": "office"}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "Found multiple Gateway locations named %q in the account %s; the updater will not change them", "office", mockAccountID)
+			},
+		},
+		"invalid-network": {
+			[]map[string]any{{"id": "loc1", "name": "office", "networks": []any{map[string]any{"network": "192.0.2.1"}}}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiImpossible, "Failed to parse the network %q of the Gateway location %s: %v", "192.0.2.1", "account456/office", gomock.Any())
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			handler := handleGatewayLocations(t, f.serveMux, tc.locations, nil)
+			handler.setRequestLimit(1)
+
+			mockPP := f.newPreparedPP(tc.prepareMockPP)
+			networks, ok := f.handle.ListGatewayLocationNetworks(context.Background(), mockPP, mockGatewayLocation())
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, networks)
+			assertHandlersExhausted(t, handler)
+		})
+	}
+}
+
+func TestSetGatewayLocationNetworksKeepsOtherFields(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	var updated map[string]any
+	handler := handleGatewayLocations(t, f.serveMux, []map[string]any{
+		{"id": "loc1", "name": "office", "client_default": true, "networks": []any{map[string]any{"network": "192.0.2.1/32"}}},
+	}, &updated)
+	handler.setRequestLimit(2)
+
+	ok := f.handle.SetGatewayLocationNetworks(context.Background(), f.newPP(), mockGatewayLocation(),
+		[]netip.Prefix{netip.MustParsePrefix("198.51.100.0/24"), netip.MustParsePrefix("2001:db8::/48")})
+	require.True(t, ok)
+	require.Equal(t, map[string]any{
+		"id": "loc1", "name": "office", "client_default": true,
+		"networks": []any{
+			map[string]any{"network": "198.51.100.0/24"},
+			map[string]any{"network": "2001:db8::/48"},
+		},
+	}, updated)
+	assertHandlersExhausted(t, handler)
+}
+
+func TestSetGatewayLocationNetworksFailed(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	requestLimit := 1
+	f.serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/gateway/locations", mockAccountID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			writeJSON(t, w, http.StatusForbidden, cloudflare.Response{
+				Success:  false,
+				Errors:   []cloudflare.ResponseInfo{{Code: 10000, Message: "Authentication error"}}, //nolint:exhaustruct
+				Messages: []cloudflare.ResponseInfo{},
+			})
+		})
+
+	mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+		gomock.InOrder(
+			m.EXPECT().Noticef(pp.EmojiError, "Failed to list Gateway locations of the account %s: %v", mockAccountID, gomock.Any()),
+			m.EXPECT().NoticeOncef(pp.MessagePermission(pp.FeatureGatewayLocations), pp.EmojiHint, `Double-check your %s. Make sure you granted the "Edit" permission of "%s"`, "API token and account ID", "Account - Zero Trust"),
+		)
+	})
+	ok := f.handle.SetGatewayLocationNetworks(context.Background(), mockPP, mockGatewayLocation(), nil)
+	require.False(t, ok)
+	require.Zero(t, requestLimit)
+}
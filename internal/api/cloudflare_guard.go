@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const (
+	// Cloudflare allows 1200 requests per five minutes for each user.
+	guardRequestRate  rate.Limit = 1200.0 / 300.0
+	guardRequestBurst            = 10
+
+	// Retries of rate-limited requests and server errors, as cloudflare-go
+	// would do by default.
+	guardMaxRetries    = 3
+	guardMinRetryDelay = time.Second
+	guardMaxRetryDelay = 30 * time.Second
+
+	// guardCircuitThreshold is the number of server errors in a row that
+	// opens the circuit breaker.
+	guardCircuitThreshold = 5
+)
+
+// errCircuitOpen is returned for the calls skipped by an open circuit breaker.
+var errCircuitOpen = errors.New("skipped because the Cloudflare API returned too many server errors in a row")
+
+// A CircuitBreaker is a [Handle] that stops calling the API for the rest of a
+// round after repeated server errors, so that an outage of the API leads to
+// one failure instead of one failure for each domain or list.
+type CircuitBreaker interface {
+	// ResetCircuit closes the circuit breaker at the start of a round.
+	ResetCircuit()
+
+	// CircuitOpen reports whether the circuit breaker opened since the last
+	// reset, and how many calls were skipped since then.
+	CircuitOpen() (skipped int, open bool)
+}
+
+var _ CircuitBreaker = cloudflareHandle{} //nolint:exhaustruct
+
+// An apiGuard is the HTTP transport shared by all calls of a [cloudflareHandle].
+// It spreads the calls with a token bucket, pauses all calls as instructed by
+// the Retry-After header of 429 responses, retries rate-limited requests and
+// server errors with exponential backoff, and refuses to make more calls after
+// repeated server errors until the circuit breaker is reset.
+type apiGuard struct {
+	base          http.RoundTripper
+	minRetryDelay time.Duration
+	maxRetryDelay time.Duration
+	metrics       metrics.Recorder
+
+	*guardState
+}
+
+// A guardState is the part of an [apiGuard] shared by all the handles using
+// the same credential, because Cloudflare limits the calls of each user and
+// an outage of the API affects all the handles alike.
+type guardState struct {
+	limiter *rate.Limiter
+
+	mutex        sync.Mutex
+	pausedUntil  time.Time // no calls before this time, set by 429 responses
+	serverErrors int       // the number of server errors in a row
+	open         bool
+	skipped      int
+}
+
+func newGuardState() *guardState {
+	return &guardState{
+		limiter:      rate.NewLimiter(guardRequestRate, guardRequestBurst),
+		mutex:        sync.Mutex{},
+		pausedUntil:  time.Time{},
+		serverErrors: 0,
+		open:         false,
+		skipped:      0,
+	}
+}
+
+//nolint:gochecknoglobals // The rate limits of Cloudflare apply to the whole process.
+var (
+	guardStatesMutex sync.Mutex
+	guardStates      = map[string]*guardState{}
+)
+
+// sharedGuardState returns the state of the guards for one credential,
+// identified by its fingerprint, creating it if needed.
+func sharedGuardState(credential string) *guardState {
+	guardStatesMutex.Lock()
+	defer guardStatesMutex.Unlock()
+	state, ok := guardStates[credential]
+	if !ok {
+		state = newGuardState()
+		guardStates[credential] = state
+	}
+	return state
+}
+
+// fingerprint hashes the parts of a credential, so that it can be compared
+// and saved without being revealed.
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func newAPIGuardWithState(base http.RoundTripper, m metrics.Recorder, state *guardState) *apiGuard {
+	return &apiGuard{
+		base:          base,
+		minRetryDelay: guardMinRetryDelay,
+		maxRetryDelay: guardMaxRetryDelay,
+		metrics:       metrics.OrNoop(m),
+		guardState:    state,
+	}
+}
+
+// newAPIGuard creates a guard with its own state.
+func newAPIGuard(base http.RoundTripper, m metrics.Recorder) *apiGuard {
+	return newAPIGuardWithState(base, m, newGuardState())
+}
+
+// newSharedAPIGuard creates a guard sharing its state with all the other
+// guards for the same credential in this process.
+func newSharedAPIGuard(base http.RoundTripper, m metrics.Recorder, credential string) *apiGuard {
+	return newAPIGuardWithState(base, m, sharedGuardState(credential))
+}
+
+// reset closes the circuit breaker.
+func (g *guardState) reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.serverErrors = 0
+	g.open = false
+	g.skipped = 0
+}
+
+// status reports whether the circuit breaker is open and how many calls were skipped.
+func (g *guardState) status() (int, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.skipped, g.open
+}
+
+// refuse reports whether a call should be skipped because the circuit breaker is open.
+func (g *guardState) refuse() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.open {
+		g.skipped++
+	}
+	return g.open
+}
+
+// wait blocks until a call is allowed by both the Retry-After pause and the token bucket.
+func (g *guardState) wait(ctx context.Context) error {
+	g.mutex.Lock()
+	pausedUntil := g.pausedUntil
+	g.mutex.Unlock()
+
+	if delay := time.Until(pausedUntil); delay > 0 {
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(pausedUntil) {
+			return fmt.Errorf("rate-limited by Cloudflare until %s", pausedUntil.Format(time.TimeOnly))
+		}
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck // The context error is self-explanatory.
+		case <-timer.C:
+		}
+	}
+
+	return g.limiter.Wait(ctx) //nolint:wrapcheck // The limiter error is self-explanatory.
+}
+
+// backoff gives the delay before the retry after the given attempt.
+func (g *apiGuard) backoff(attempt int) time.Duration {
+	return min(g.minRetryDelay<<attempt, g.maxRetryDelay)
+}
+
+// parseRetryAfter reads the Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// observe updates the guard with a response and decides whether to retry
+// the request and how long to wait before that.
+func (g *apiGuard) observe(resp *http.Response, attempt int) (time.Duration, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		now := time.Now()
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+		if !ok {
+			delay = g.backoff(attempt)
+		}
+		if until := now.Add(delay); until.After(g.pausedUntil) {
+			g.pausedUntil = until
+		}
+		return delay, true
+
+	case resp.StatusCode >= http.StatusInternalServerError:
+		g.serverErrors++
+		if g.serverErrors >= guardCircuitThreshold {
+			g.open = true
+		}
+		return g.backoff(attempt), !g.open
+
+	default:
+		g.serverErrors = 0
+		return 0, false
+	}
+}
+
+// describeEndpoint turns the path of an API call into a label for the metrics
+// by replacing the IDs and the Workers KV keys with placeholders. The version
+// prefix "/client/v4" is removed.
+func describeEndpoint(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/client/v4"), "/")
+	for i, segment := range segments {
+		switch {
+		case i > 0 && segments[i-1] == "values":
+			segments[i] = ":key"
+		case strings.Trim(segment, "abcdefghijklmnopqrstuvwxyz_") != "":
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (g *apiGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if g.refuse() {
+			return nil, errCircuitOpen
+		}
+		if err := g.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := g.base.RoundTrip(req)
+		if err != nil {
+			g.metrics.CountAPICall(req.Method, describeEndpoint(req.URL.Path), "error")
+			return nil, err //nolint:wrapcheck // cloudflare-go wraps the error.
+		}
+		g.metrics.CountAPICall(req.Method, describeEndpoint(req.URL.Path), strconv.Itoa(resp.StatusCode))
+
+		delay, retry := g.observe(resp, attempt)
+		if !retry || attempt >= guardMaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, nil
+		}
+
+		// The response is discarded in favor of the retry.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err() //nolint:wrapcheck // The context error is self-explanatory.
+			case <-timer.C:
+			}
+		}
+
+		next := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err //nolint:wrapcheck // cloudflare-go wraps the error.
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+// ResetCircuit implements [CircuitBreaker].
+func (h cloudflareHandle) ResetCircuit() { h.guard.reset() }
+
+// CircuitOpen implements [CircuitBreaker].
+func (h cloudflareHandle) CircuitOpen() (int, bool) { return h.guard.status() }
+
+// skippedCallFilter is a pretty printer dropping the messages about calls
+// skipped by an open circuit breaker; the breaker is reported once per round.
+type skippedCallFilter struct {
+	pp.PP
+}
+
+// HideSkippedCalls wraps a pretty printer so that the error messages of the
+// calls skipped by an open circuit breaker are not printed one by one.
+func HideSkippedCalls(ppfmt pp.PP) pp.PP {
+	if _, ok := ppfmt.(skippedCallFilter); ok {
+		return ppfmt
+	}
+	return skippedCallFilter{PP: ppfmt}
+}
+
+func isSkippedCall(args []any) bool {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && errors.Is(err, errCircuitOpen) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f skippedCallFilter) Indent() pp.PP { return skippedCallFilter{PP: f.PP.Indent()} }
+
+func (f skippedCallFilter) Infof(emoji pp.Emoji, format string, args ...any) {
+	if !isSkippedCall(args) {
+		f.PP.Infof(emoji, format, args...)
+	}
+}
+
+func (f skippedCallFilter) Noticef(emoji pp.Emoji, format string, args ...any) {
+	if !isSkippedCall(args) {
+		f.PP.Noticef(emoji, format, args...)
+	}
+}
+
+func (f skippedCallFilter) InfoOncef(id pp.ID, emoji pp.Emoji, format string, args ...any) {
+	if !isSkippedCall(args) {
+		f.PP.InfoOncef(id, emoji, format, args...)
+	}
+}
+
+func (f skippedCallFilter) NoticeOncef(id pp.ID, emoji pp.Emoji, format string, args ...any) {
+	if !isSkippedCall(args) {
+		f.PP.NoticeOncef(id, emoji, format, args...)
+	}
+}
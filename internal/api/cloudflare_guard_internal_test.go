@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+)
+
+// newFastGuard creates a guard whose retries happen almost immediately.
+func newFastGuard() *apiGuard {
+	g := newAPIGuard(http.DefaultTransport, nil)
+	g.minRetryDelay = time.Millisecond
+	g.maxRetryDelay = time.Millisecond
+	return g
+}
+
+// serveStatuses answers the requests with the given status codes in order,
+// repeating the last one, and counts the requests.
+func serveStatuses(t *testing.T, header http.Header, statuses ...int) (string, *atomic.Int32) {
+	t.Helper()
+
+	var count atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(count.Add(1)) - 1
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for key, vals := range header {
+			w.Header()[key] = vals
+		}
+		w.WriteHeader(statuses[min(i, len(statuses)-1)])
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &count
+}
+
+func post(t *testing.T, g *apiGuard, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, strings.NewReader("hello"))
+	require.NoError(t, err)
+	return (&http.Client{Transport: g}).Do(req) //nolint:exhaustruct
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		"seconds":  {"120", 2 * time.Minute, true},
+		"date":     {"Fri, 01 Mar 2024 12:00:30 GMT", 30 * time.Second, true},
+		"past":     {"Fri, 01 Mar 2024 11:00:00 GMT", 0, true},
+		"negative": {"-1", 0, false},
+		"empty":    {"", 0, false},
+		"invalid":  {"soon", 0, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			delay, ok := parseRetryAfter(tc.header, now)
+			require.Equal(t, tc.expected, delay)
+			require.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestGuardRetriesRateLimitedRequests(t *testing.T) {
+	t.Parallel()
+
+	url, count := serveStatuses(t, http.Header{"Retry-After": {"0"}}, http.StatusTooManyRequests, http.StatusOK)
+	g := newFastGuard()
+
+	resp, err := post(t, g, url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body)) // the body is sent again
+	require.Equal(t, int32(2), count.Load())
+}
+
+func TestGuardPausesForRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	url, count := serveStatuses(t, http.Header{"Retry-After": {"3600"}}, http.StatusTooManyRequests)
+	g := newFastGuard()
+
+	// The pause is longer than the deadline, so the request is not retried.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: g}).Do(req) //nolint:exhaustruct
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, int32(1), count.Load())
+
+	// Other calls are paused as well.
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: g}).Do(req) //nolint:exhaustruct,bodyclose
+	require.ErrorContains(t, err, "rate-limited by Cloudflare until")
+	require.Equal(t, int32(1), count.Load())
+}
+
+func TestGuardRetriesServerErrors(t *testing.T) {
+	t.Parallel()
+
+	url, count := serveStatuses(t, nil, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	g := newFastGuard()
+
+	resp, err := post(t, g, url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(3), count.Load())
+
+	skipped, open := g.status()
+	require.False(t, open)
+	require.Zero(t, skipped)
+}
+
+func TestGuardCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	url, count := serveStatuses(t, nil, http.StatusInternalServerError)
+	g := newFastGuard()
+
+	// The first call is tried 1+3 times.
+	resp, err := post(t, g, url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, int32(guardMaxRetries+1), count.Load())
+
+	// The second call opens the circuit breaker.
+	resp, err = post(t, g, url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int32(guardCircuitThreshold), count.Load())
+
+	// Later calls are skipped.
+	_, err = post(t, g, url) //nolint:bodyclose
+	require.ErrorIs(t, err, errCircuitOpen)
+	_, err = post(t, g, url) //nolint:bodyclose
+	require.ErrorIs(t, err, errCircuitOpen)
+	require.Equal(t, int32(guardCircuitThreshold), count.Load())
+	skipped, open := g.status()
+	require.True(t, open)
+	require.Equal(t, 2, skipped)
+
+	// A new round calls the API again.
+	g.reset()
+	skipped, open = g.status()
+	require.False(t, open)
+	require.Zero(t, skipped)
+	resp, err = post(t, g, url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int32(guardCircuitThreshold+guardMaxRetries+1), count.Load())
+}
+
+func TestDescribeEndpoint(t *testing.T) {
+	t.Parallel()
+
+	const account, list = "01a7362d577a6c3019a474fd6f485823", "2c0fc9fa937b11eaa1b71c4d701ab86e"
+	for _, tc := range [...]struct {
+		path     string
+		expected string
+	}{
+		{"/client/v4/zones", "/zones"},
+		{"/client/v4/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", "/zones/:id/dns_records"},
+		{"/client/v4/accounts/" + account + "/rules/lists/" + list + "/items", "/accounts/:id/rules/lists/:id/items"},
+		{
+			"/client/v4/accounts/" + account + "/storage/kv/namespaces/" + list + "/values/home",
+			"/accounts/:id/storage/kv/namespaces/:id/values/:key",
+		},
+		{"/client/v4/user/tokens/verify", "/user/tokens/verify"},
+	} {
+		require.Equal(t, tc.expected, describeEndpoint(tc.path), tc.path)
+	}
+}
+
+func TestGuardCountsAPICalls(t *testing.T) {
+	t.Parallel()
+
+	url, _ := serveStatuses(t, nil, http.StatusInternalServerError, http.StatusOK)
+	registry := metrics.NewRegistry()
+	g := newAPIGuard(http.DefaultTransport, registry.ForProfile(""))
+	g.minRetryDelay = time.Millisecond
+	g.maxRetryDelay = time.Millisecond
+
+	resp, err := post(t, g, url+"/client/v4/zones")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	var b strings.Builder
+	_, err = registry.WriteTo(&b)
+	require.NoError(t, err)
+	require.Contains(t, b.String(),
+		`ddns_cloudflare_api_requests_total{method="POST",endpoint="/zones",status="200"} 1`+"\n")
+	require.Contains(t, b.String(),
+		`ddns_cloudflare_api_requests_total{method="POST",endpoint="/zones",status="500"} 1`+"\n")
+}
+
+func TestSharedGuardState(t *testing.T) {
+	t.Parallel()
+
+	credential := fingerprint(t.Name(), "token")
+	g1 := newSharedAPIGuard(http.DefaultTransport, nil, credential)
+	g2 := newSharedAPIGuard(http.DefaultTransport, nil, credential)
+	other := newSharedAPIGuard(http.DefaultTransport, nil, fingerprint(t.Name(), "another token"))
+	require.Same(t, g1.guardState, g2.guardState)
+	require.NotSame(t, g1.guardState, other.guardState)
+	require.NotSame(t, g1.guardState, newAPIGuard(http.DefaultTransport, nil).guardState)
+
+	// The circuit breaker opened by one handle stops the others.
+	g1.mutex.Lock()
+	g1.open = true
+	g1.mutex.Unlock()
+	_, open := g2.status()
+	require.True(t, open)
+	_, open = other.status()
+	require.False(t, open)
+}
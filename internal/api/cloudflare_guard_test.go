@@ -0,0 +1,40 @@
+package api_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func TestHideSkippedCalls(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	indented := mocks.NewMockPP(mockCtrl)
+	otherErr := errors.New("oops")
+	skipped := fmt.Errorf("wrapped: %w", api.ErrCircuitOpen)
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiError, "Failed: %v", otherErr),
+		mockPP.EXPECT().Infof(pp.EmojiBullet, "Done"),
+		mockPP.EXPECT().Indent().Return(indented),
+		indented.EXPECT().NoticeOncef(pp.ID(0), pp.EmojiError, "Failed: %v", otherErr),
+	)
+
+	ppfmt := api.HideSkippedCalls(mockPP)
+	require.Equal(t, ppfmt, api.HideSkippedCalls(ppfmt))
+	ppfmt.Noticef(pp.EmojiError, "Failed: %v", otherErr)
+	ppfmt.Noticef(pp.EmojiError, "Failed: %v", skipped)
+	ppfmt.Infof(pp.EmojiBullet, "Done")
+	ppfmt.InfoOncef(pp.ID(0), pp.EmojiError, "Failed: %v", skipped)
+	indentedPP := ppfmt.Indent()
+	indentedPP.NoticeOncef(pp.ID(0), pp.EmojiError, "Failed: %v", otherErr)
+	indentedPP.Noticef(pp.EmojiError, "Failed: %v", skipped)
+}
@@ -111,6 +111,16 @@ func mockResponse() cloudflare.Response {
 	}
 }
 
+// mockResultResponse wraps a result for endpoints accessed through raw requests.
+func mockResultResponse(result any) map[string]any {
+	return map[string]any{
+		"success":  true,
+		"errors":   []any{},
+		"messages": []any{},
+		"result":   result,
+	}
+}
+
 const (
 	mockToken      = "token123"
 	mockAuthString = "Bearer " + mockToken
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func hintIPAccessRulePermission(ppfmt pp.PP, set IPAccessRuleSet, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if !errors.As(err, &authentication) && !errors.As(err, &authorization) {
+		return
+	}
+	switch set.Scope {
+	case IPAccessRuleScopeZone:
+		pp.HintEditPermission(ppfmt, pp.FeatureIPAccessRules, "Zone - Firewall Services", "zone ID")
+	case IPAccessRuleScopeAccount:
+		pp.HintEditPermission(ppfmt, pp.FeatureIPAccessRules, "Account - Account Firewall Access Rules", "account ID")
+	}
+}
+
+// apiMode gives the name of the mode used by the API.
+func (m IPAccessRuleMode) apiMode() string {
+	if m == IPAccessRuleModeAllow {
+		return "whitelist"
+	}
+	return string(m)
+}
+
+// ipAccessRuleConfiguration gives the target and the value of a rule for an IP range.
+// Single addresses use the "ip" and "ip6" targets; the API rejects them as ranges.
+func ipAccessRuleConfiguration(prefix netip.Prefix) cloudflare.AccessRuleConfiguration {
+	switch {
+	case prefix.IsSingleIP() && prefix.Addr().Is4():
+		return cloudflare.AccessRuleConfiguration{Target: "ip", Value: prefix.Addr().String()}
+	case prefix.IsSingleIP():
+		return cloudflare.AccessRuleConfiguration{Target: "ip6", Value: prefix.Addr().String()}
+	default:
+		return cloudflare.AccessRuleConfiguration{Target: "ip_range", Value: prefix.Masked().String()}
+	}
+}
+
+func (h cloudflareHandle) listIPAccessRulesPage(ctx context.Context, set IPAccessRuleSet, page int,
+) (*cloudflare.AccessRuleListResponse, error) {
+	//nolint:exhaustruct // only the fields used for filtering are set
+	filter := cloudflare.AccessRule{Mode: set.Mode.apiMode()}
+	if set.Scope == IPAccessRuleScopeZone {
+		return h.cf.ListZoneAccessRules(ctx, string(set.ScopeID), filter, page)
+	}
+	return h.cf.ListAccountAccessRules(ctx, string(set.ScopeID), filter, page)
+}
+
+// ListIPAccessRules calls cloudflare.ListZoneAccessRules or cloudflare.ListAccountAccessRules
+// and keeps the IP rules of the set whose notes are selected by the ownership policy.
+func (h cloudflareHandle) ListIPAccessRules(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet,
+) ([]IPAccessRule, bool) {
+	var rules []IPAccessRule
+	for page := 1; ; page++ {
+		res, err := h.listIPAccessRulesPage(ctx, set, page)
+		if err != nil {
+			ppfmt.Noticef(pp.EmojiError, "Failed to list the IP access rules %s: %v", set.Describe(), err)
+			hintIPAccessRulePermission(ppfmt, set, err)
+			return nil, false
+		}
+
+		for _, raw := range res.Result {
+			// Zone-level listings also show the inherited account-level rules.
+			if set.Scope == IPAccessRuleScopeZone && raw.Scope.Type != "" && raw.Scope.Type != "zone" {
+				continue
+			}
+			if set.Scope == IPAccessRuleScopeAccount && raw.Scope.Type == "zone" {
+				continue
+			}
+			switch raw.Configuration.Target {
+			case "ip", "ip6", "ip_range":
+			default:
+				continue
+			}
+			if !h.options.MatchManagedIPAccessRuleNotes(raw.Notes) {
+				continue
+			}
+
+			prefix, err := netip.ParsePrefix(raw.Configuration.Value)
+			if err != nil {
+				ip, errAddr := netip.ParseAddr(raw.Configuration.Value)
+				if errAddr != nil {
+					ppfmt.Noticef(pp.EmojiImpossible, "Found an invalid IP range or IP address %q in the IP access rules %s",
+						raw.Configuration.Value, set.Describe())
+					return nil, false
+				}
+				prefix = netip.PrefixFrom(ip, ip.BitLen())
+			}
+			rules = append(rules, IPAccessRule{ID: ID(raw.ID), Prefix: prefix, Notes: raw.Notes})
+		}
+
+		if page >= res.TotalPages {
+			return rules, true
+		}
+	}
+}
+
+// CreateIPAccessRule calls cloudflare.CreateZoneAccessRule or cloudflare.CreateAccountAccessRule.
+func (h cloudflareHandle) CreateIPAccessRule(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet,
+	prefix netip.Prefix, notes string,
+) (ID, bool) {
+	//nolint:exhaustruct // the other fields are filled by Cloudflare
+	rule := cloudflare.AccessRule{
+		Notes:         notes,
+		Mode:          set.Mode.apiMode(),
+		Configuration: ipAccessRuleConfiguration(prefix),
+	}
+
+	var res *cloudflare.AccessRuleResponse
+	var err error
+	if set.Scope == IPAccessRuleScopeZone {
+		res, err = h.cf.CreateZoneAccessRule(ctx, string(set.ScopeID), rule)
+	} else {
+		res, err = h.cf.CreateAccountAccessRule(ctx, string(set.ScopeID), rule)
+	}
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to add a rule for %s to the IP access rules %s: %v",
+			prefix.Masked().String(), set.Describe(), err)
+		hintIPAccessRulePermission(ppfmt, set, err)
+		return "", false
+	}
+	return ID(res.Result.ID), true
+}
+
+// DeleteIPAccessRule calls cloudflare.DeleteZoneAccessRule or cloudflare.DeleteAccountAccessRule.
+func (h cloudflareHandle) DeleteIPAccessRule(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet, id ID) bool {
+	var err error
+	if set.Scope == IPAccessRuleScopeZone {
+		_, err = h.cf.DeleteZoneAccessRule(ctx, string(set.ScopeID), string(id))
+	} else {
+		_, err = h.cf.DeleteAccountAccessRule(ctx, string(set.ScopeID), string(id))
+	}
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to delete the rule %s from the IP access rules %s: %v",
+			id, set.Describe(), err)
+		hintIPAccessRulePermission(ppfmt, set, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const mockIPAccessZoneID = api.ID("zone789")
+
+func mockZoneIPAccessRuleSet() api.IPAccessRuleSet {
+	return api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeZone, ScopeID: mockIPAccessZoneID, Mode: api.IPAccessRuleModeBlock}
+}
+
+func mockAccountIPAccessRuleSet() api.IPAccessRuleSet {
+	return api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeAccount, ScopeID: mockAccountID, Mode: api.IPAccessRuleModeAllow}
+}
+
+func accessRule(id, target, value, notes, scopeType string) cloudflare.AccessRule {
+	return cloudflare.AccessRule{ //nolint:exhaustruct
+		ID:            id,
+		Notes:         notes,
+		Configuration: cloudflare.AccessRuleConfiguration{Target: target, Value: value},
+		Scope:         cloudflare.AccessRuleScope{Type: scopeType}, //nolint:exhaustruct
+	}
+}
+
+// handleIPAccessRules serves the listing endpoint of IP Access Rules, one page per request.
+func handleIPAccessRules(t *testing.T, serveMux *http.ServeMux, path, mode string, pages [][]cloudflare.AccessRule,
+) httpHandler {
+	t.Helper()
+
+	requestLimit := new(int)
+	serveMux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, mode, r.URL.Query().Get("mode"))
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if !assert.NoError(t, err) || !assert.LessOrEqual(t, page, len(pages)) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(t, w, http.StatusOK, map[string]any{
+			"success":     true,
+			"errors":      []any{},
+			"messages":    []any{},
+			"result":      pages[page-1],
+			"result_info": map[string]any{"page": page, "total_pages": len(pages)},
+		})
+	})
+
+	return httpHandler{requestLimit: requestLimit}
+}
+
+func TestListIPAccessRules(t *testing.T) {
+	t.Parallel()
+
+	zonePath := fmt.Sprintf("/zones/%s/firewall/access_rules/rules", mockIPAccessZoneID)
+	accountPath := fmt.Sprintf("/accounts/%s/firewall/access_rules/rules", mockAccountID)
+
+	for name, tc := range map[string]struct {
+		set           api.IPAccessRuleSet
+		path          string
+		mode          string
+		regex         string
+		pages         [][]cloudflare.AccessRule
+		expected      []api.IPAccessRule
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"zone/paged": {
+			mockZoneIPAccessRuleSet(), zonePath, "block", "",
+			[][]cloudflare.AccessRule{
+				{
+					accessRule("rule1", "ip", "192.0.2.1", "home", "zone"),
+					accessRule("rule2", "country", "US", "home", "zone"),
+				},
+				{
+					accessRule("rule3", "ip_range", "2001:db8::/48", "home", "zone"),
+					accessRule("rule4", "ip", "198.51.100.1", "inherited", "account"),
+				},
+			},
+			[]api.IPAccessRule{
+				{ID: "rule1", Prefix: netip.MustParsePrefix("192.0.2.1/32"), Notes: "home"},
+				{ID: "rule3", Prefix: netip.MustParsePrefix("2001:db8::/48"), Notes: "home"},
+			},
+			true,
+			nil,
+		},
+		"account/skips-zone-rules": {
+			mockAccountIPAccessRuleSet(), accountPath, "whitelist", "",
+			[][]cloudflare.AccessRule{{
+				accessRule("rule1", "ip6", "2001:db8::1", "", "account"),
+				accessRule("rule2", "ip", "192.0.2.1", "", "zone"),
+			}},
+			[]api.IPAccessRule{
+				{ID: "rule1", Prefix: netip.MustParsePrefix("2001:db8::1/128"), Notes: ""},
+			},
+			true,
+			nil,
+		},
+		"notes-regex": {
+			mockZoneIPAccessRuleSet(), zonePath, "block", "^ddns$",
+			[][]cloudflare.AccessRule{{
+				accessRule("rule1", "ip", "192.0.2.1", "ddns", "zone"),
+				accessRule("rule2", "ip", "192.0.2.2", "manual", "zone"),
+			}},
+			[]api.IPAccessRule{
+				{ID: "rule1", Prefix: netip.MustParsePrefix("192.0.2.1/32"), Notes: "ddns"},
+			},
+			true,
+			nil,
+		},
+		"invalid": {
+			mockZoneIPAccessRuleSet(), zonePath, "block", "",
+			[][]cloudflare.AccessRule{{accessRule("rule1", "ip", "home", "", "zone")}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiImpossible, "Found an invalid IP range or IP address %q in the IP access rules %s", "home", "zone/zone789:block")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			options := defaultHandleOptions()
+			options.ManagedIPAccessRulesNotesRegex = regexp.MustCompile(tc.regex)
+			f := newCloudflareHarnessWithOptions(t, options)
+			handler := handleIPAccessRules(t, f.serveMux, tc.path, tc.mode, tc.pages)
+			handler.setRequestLimit(len(tc.pages))
+
+			mockPP := f.newPreparedPP(tc.prepareMockPP)
+			rules, ok := f.handle.ListIPAccessRules(context.Background(), mockPP, tc.set)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, rules)
+			assertHandlersExhausted(t, handler)
+		})
+	}
+}
+
+func TestListIPAccessRulesFailed(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	requestLimit := 1
+	f.serveMux.HandleFunc(fmt.Sprintf("/zones/%s/firewall/access_rules/rules", mockIPAccessZoneID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			writeJSON(t, w, http.StatusForbidden, cloudflare.Response{
+				Success:  false,
+				Errors:   []cloudflare.ResponseInfo{{Code: 10000, Message: "Authentication error"}}, //nolint:exhaustruct
+				Messages: []cloudflare.ResponseInfo{},
+			})
+		})
+
+	mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+		gomock.InOrder(
+			m.EXPECT().Noticef(pp.EmojiError, "Failed to list the IP access rules %s: %v", "zone/zone789:block", gomock.Any()),
+			m.EXPECT().NoticeOncef(pp.MessagePermission(pp.FeatureIPAccessRules), pp.EmojiHint, `Double-check your %s. Make sure you granted the "Edit" permission of "%s"`, "API token and zone ID", "Zone - Firewall Services"),
+		)
+	})
+	rules, ok := f.handle.ListIPAccessRules(context.Background(), mockPP, mockZoneIPAccessRuleSet())
+	require.False(t, ok)
+	require.Nil(t, rules)
+	require.Zero(t, requestLimit)
+}
+
+func TestCreateIPAccessRule(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		prefix netip.Prefix
+		target string
+		value  string
+	}{
+		"ip":       {netip.MustParsePrefix("192.0.2.1/32"), "ip", "192.0.2.1"},
+		"ip6":      {netip.MustParsePrefix("2001:db8::1/128"), "ip6", "2001:db8::1"},
+		"ip_range": {netip.MustParsePrefix("2001:db8::1/64"), "ip_range", "2001:db8::/64"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			requestLimit := 1
+			f.serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/firewall/access_rules/rules", mockAccountID),
+				func(w http.ResponseWriter, r *http.Request) {
+					if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					assert.Equal(t, http.MethodPost, r.Method)
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					var rule cloudflare.AccessRule
+					assert.NoError(t, json.Unmarshal(body, &rule))
+					assert.Equal(t, "whitelist", rule.Mode)
+					assert.Equal(t, "ddns", rule.Notes)
+					assert.Equal(t, cloudflare.AccessRuleConfiguration{Target: tc.target, Value: tc.value}, rule.Configuration)
+					rule.ID = "rule1"
+					writeJSON(t, w, http.StatusOK, mockResultResponse(rule))
+				})
+
+			id, ok := f.handle.CreateIPAccessRule(context.Background(), f.newPP(), mockAccountIPAccessRuleSet(), tc.prefix, "ddns")
+			require.True(t, ok)
+			require.Equal(t, api.ID("rule1"), id)
+			require.Zero(t, requestLimit)
+		})
+	}
+}
+
+func TestDeleteIPAccessRule(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	requestLimit := 1
+	f.serveMux.HandleFunc(fmt.Sprintf("/zones/%s/firewall/access_rules/rules/{id}", mockIPAccessZoneID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			assert.Equal(t, http.MethodDelete, r.Method)
+			assert.Equal(t, "rule1", r.PathValue("id"))
+			writeJSON(t, w, http.StatusOK, mockResultResponse(map[string]any{"id": "rule1"}))
+		})
+
+	ok := f.handle.DeleteIPAccessRule(context.Background(), f.newPP(), mockZoneIPAccessRuleSet(), "rule1")
+	require.True(t, ok)
+	require.Zero(t, requestLimit)
+}
+
+func TestDeleteIPAccessRuleFailed(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+		m.EXPECT().Noticef(pp.EmojiError, "Failed to delete the rule %s from the IP access rules %s: %v", api.ID("rule1"), "account/account456:allow", gomock.Any())
+	})
+	ok := f.handle.DeleteIPAccessRule(context.Background(), mockPP, mockAccountIPAccessRuleSet(), "rule1")
+	require.False(t, ok)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// lbPool is the part of a load balancer pool used by the updater.
+//
+// The origins are kept as raw JSON objects so that the fields unknown to the
+// updater (weights, headers, virtual networks, ...) survive a round trip
+// unchanged. The typed API of cloudflare-go replaces the whole pool and would
+// silently drop such fields.
+type lbPool struct {
+	Origins []map[string]json.RawMessage `json:"origins"`
+}
+
+func lbPoolEndpoint(origin LBPoolOrigin) string {
+	return fmt.Sprintf("/accounts/%s/load_balancers/pools/%s", origin.AccountID, origin.PoolID)
+}
+
+func describeLBPool(origin LBPoolOrigin) string {
+	return fmt.Sprintf("%s/%s", origin.AccountID, origin.PoolID)
+}
+
+func hintLBPoolPermission(ppfmt pp.PP, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if errors.As(err, &authentication) || errors.As(err, &authorization) {
+		pp.HintEditPermission(ppfmt, pp.FeatureLBPoolOrigins, "Account - Load Balancing: Monitors and Pools", "account ID")
+	}
+}
+
+// readLBPool reads a load balancer pool and finds the origin in it.
+// The second return value is the index of the origin in the pool.
+func (h cloudflareHandle) readLBPool(ctx context.Context, ppfmt pp.PP, origin LBPoolOrigin) (lbPool, int, bool) {
+	var pool lbPool
+
+	res, err := h.cf.Raw(ctx, http.MethodGet, lbPoolEndpoint(origin), nil, nil)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to read the load balancer pool %s: %v", describeLBPool(origin), err)
+		hintLBPoolPermission(ppfmt, err)
+		return pool, 0, false
+	}
+	if err := json.Unmarshal(res.Result, &pool); err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to parse the load balancer pool %s: %v", describeLBPool(origin), err)
+		return pool, 0, false
+	}
+
+	index := -1
+	for i, o := range pool.Origins {
+		var name string
+		if err := json.Unmarshal(o["name"], &name); err != nil || name != origin.OriginName {
+			continue
+		}
+		if index >= 0 {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"The load balancer pool %s has more than one origin named %q; the updater will not change it",
+				describeLBPool(origin), origin.OriginName)
+			return pool, 0, false
+		}
+		index = i
+	}
+	if index < 0 {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The load balancer pool %s has no origin named %q", describeLBPool(origin), origin.OriginName)
+		return pool, 0, false
+	}
+
+	return pool, index, true
+}
+
+// parseLBPoolOriginState extracts the managed state of an origin. Origins whose
+// addresses are hostnames are not owned by the updater and are refused.
+func parseLBPoolOriginState(ppfmt pp.PP, origin LBPoolOrigin, raw map[string]json.RawMessage,
+) (LBPoolOriginState, bool) {
+	var address string
+	_ = json.Unmarshal(raw["address"], &address)
+	ip, err := netip.ParseAddr(address)
+	if err != nil || ip.Zone() != "" {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The origin %s has the address %s, which is not an IP address; "+
+				"the updater only manages origins configured by IP addresses",
+			origin.Describe(), pp.QuoteOrEmptyLabel(address, "empty"))
+		return LBPoolOriginState{}, false
+	}
+
+	// Cloudflare enables an origin unless it is explicitly disabled.
+	enabled := true
+	if value, ok := raw["enabled"]; ok {
+		if err := json.Unmarshal(value, &enabled); err != nil {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"Failed to parse the enabled state of the origin %s: %v", origin.Describe(), err)
+			return LBPoolOriginState{}, false
+		}
+	}
+
+	return LBPoolOriginState{Address: ip.Unmap(), Enabled: enabled}, true
+}
+
+// GetLBPoolOrigin reads one origin of a load balancer pool.
+func (h cloudflareHandle) GetLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin LBPoolOrigin,
+) (LBPoolOriginState, bool) {
+	pool, index, ok := h.readLBPool(ctx, ppfmt, origin)
+	if !ok {
+		return LBPoolOriginState{}, false
+	}
+	return parseLBPoolOriginState(ppfmt, origin, pool.Origins[index])
+}
+
+// UpdateLBPoolOrigin re-reads the pool and patches the address and the enabled
+// state of one origin, sending back the other origins as they were read.
+func (h cloudflareHandle) UpdateLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin LBPoolOrigin,
+	desired LBPoolOriginState,
+) bool {
+	pool, index, ok := h.readLBPool(ctx, ppfmt, origin)
+	if !ok {
+		return false
+	}
+	// Check the ownership again in case the origin was changed in the meantime.
+	if _, ok := parseLBPoolOriginState(ppfmt, origin, pool.Origins[index]); !ok {
+		return false
+	}
+
+	address, _ := json.Marshal(desired.Address.String())
+	enabled, _ := json.Marshal(desired.Enabled)
+	pool.Origins[index]["address"] = address
+	pool.Origins[index]["enabled"] = enabled
+
+	if _, err := h.cf.Raw(ctx, http.MethodPatch, lbPoolEndpoint(origin), pool, nil); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the origin %s: %v", origin.Describe(), err)
+		hintLBPoolPermission(ppfmt, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,189 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const mockPoolID = api.ID("pool789")
+
+func mockLBPoolOrigin() api.LBPoolOrigin {
+	return api.LBPoolOrigin{AccountID: mockAccountID, PoolID: mockPoolID, OriginName: "home"}
+}
+
+func mockPoolResponse(origins ...map[string]any) map[string]any {
+	return map[string]any{
+		"success":  true,
+		"errors":   []any{},
+		"messages": []any{},
+		"result":   map[string]any{"id": mockPoolID, "name": "pool", "origins": origins},
+	}
+}
+
+// handleLBPool serves the pool endpoint. GET requests return the origins;
+// PATCH requests are decoded into patched.
+func handleLBPool(t *testing.T, serveMux *http.ServeMux, origins []map[string]any, patched *[]map[string]any,
+) httpHandler {
+	t.Helper()
+
+	requestLimit := new(int)
+	serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/load_balancers/pools/%s", mockAccountID, mockPoolID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(t, w, http.StatusOK, mockPoolResponse(origins...))
+			case http.MethodPatch:
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				var pool struct {
+					Origins []map[string]any `json:"origins"`
+				}
+				assert.NoError(t, json.Unmarshal(body, &pool))
+				*patched = pool.Origins
+				writeJSON(t, w, http.StatusOK, mockPoolResponse(pool.Origins...))
+			default:
+				t.Errorf("unexpected method %s", r.Method)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		})
+
+	return httpHandler{requestLimit: requestLimit}
+}
+
+func TestGetLBPoolOrigin(t *testing.T) {
+	t.Parallel()
+
+	origin := mockLBPoolOrigin()
+
+	for name, tc := range map[string]struct {
+		origins       []map[string]any
+		expected      api.LBPoolOriginState
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"ip4": {
+			[]map[string]any{
+				{"name": "other", "address": "192.0.2.9"},
+				{"name": "home", "address": "192.0.2.1", "enabled": false},
+			},
+			api.LBPoolOriginState{Address: mustIP("192.0.2.1"), Enabled: false},
+			true,
+			nil,
+		},
+		"enabled-by-default": {
+			[]map[string]any{{"name": "home", "address": "2001:db8::1"}},
+			api.LBPoolOriginState{Address: mustIP("2001:db8::1"), Enabled: true},
+			true,
+			nil,
+		},
+		"missing": {
+			[]map[string]any{{"name": "other", "address": "192.0.2.9"}},
+			api.LBPoolOriginState{},
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The load balancer pool %s has no origin named %q", "account456/pool789", "home")
+			},
+		},
+		"duplicate": {
+			[]map[string]any{{"name": "home", "address": "192.0.2.1"}, {"name": "home", "address": "192.0.2.2"}},
+			api.LBPoolOriginState{},
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The load balancer pool %s has more than one origin named %q; the updater will not change it", "account456/pool789", "home")
+			},
+		},
+		"hostname": {
+			[]map[string]any{{"name": "home", "address": "home.example.org"}},
+			api.LBPoolOriginState{},
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The origin %s has the address %s, which is not an IP address; the updater only manages origins configured by IP addresses", "account456/pool789:home", `"home.example.org"`)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			handler := handleLBPool(t, f.serveMux, tc.origins, nil)
+			handler.setRequestLimit(1)
+
+			mockPP := f.newPreparedPP(tc.prepareMockPP)
+			state, ok := f.handle.GetLBPoolOrigin(context.Background(), mockPP, origin)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, state)
+			assertHandlersExhausted(t, handler)
+		})
+	}
+}
+
+func TestUpdateLBPoolOriginKeepsOtherFields(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	var patched []map[string]any
+	handler := handleLBPool(t, f.serveMux, []map[string]any{
+		{"name": "other", "address": "192.0.2.9", "weight": 0.5},
+		{"name": "home", "address": "192.0.2.1", "enabled": false, "header": map[string]any{"Host": []any{"example.org"}}},
+	}, &patched)
+	handler.setRequestLimit(2)
+
+	ok := f.handle.UpdateLBPoolOrigin(context.Background(), f.newPP(), mockLBPoolOrigin(),
+		api.LBPoolOriginState{Address: mustIP("198.51.100.1"), Enabled: true})
+	require.True(t, ok)
+	require.Equal(t, []map[string]any{
+		{"name": "other", "address": "192.0.2.9", "weight": 0.5},
+		{"name": "home", "address": "198.51.100.1", "enabled": true, "header": map[string]any{"Host": []any{"example.org"}}},
+	}, patched)
+	assertHandlersExhausted(t, handler)
+}
+
+func TestUpdateLBPoolOriginFailed(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	requestLimit := 1
+	f.serveMux.HandleFunc(fmt.Sprintf("/accounts/%s/load_balancers/pools/%s", mockAccountID, mockPoolID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			writeJSON(t, w, http.StatusForbidden, cloudflare.Response{
+				Success:  false,
+				Errors:   []cloudflare.ResponseInfo{{Code: 10000, Message: "Authentication error"}}, //nolint:exhaustruct
+				Messages: []cloudflare.ResponseInfo{},
+			})
+		})
+
+	mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+		gomock.InOrder(
+			m.EXPECT().Noticef(pp.EmojiError, "Failed to read the load balancer pool %s: %v", "account456/pool789", gomock.Any()),
+			m.EXPECT().NoticeOncef(pp.MessagePermission(pp.FeatureLBPoolOrigins), pp.EmojiHint, `Double-check your %s. Make sure you granted the "Edit" permission of "%s"`, "API token and account ID", "Account - Load Balancing: Monitors and Pools"),
+		)
+	})
+	ok := f.handle.UpdateLBPoolOrigin(context.Background(), mockPP, mockLBPoolOrigin(),
+		api.LBPoolOriginState{Address: mustIP("198.51.100.1"), Enabled: true})
+	require.False(t, ok)
+	require.Zero(t, requestLimit)
+}
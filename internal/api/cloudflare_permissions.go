@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// permissionExpiryWarning is how early an upcoming token expiry is reported.
+const permissionExpiryWarning = time.Hour * 24 * 7
+
+// Cloudflare names the permission groups "... Write" in the API even though
+// the dashboard shows them as "Edit". Both spellings are accepted because the
+// WAF-list group has been renamed before.
+//
+//nolint:gochecknoglobals
+var (
+	dnsEditPermissionGroups     = []string{"DNS Write"}
+	wafListEditPermissionGroups = []string{"Account Filter Lists Write", "Account Filter Lists Edit"}
+)
+
+// resourceKeyPrefix is the common prefix of resource keys in token policies.
+const resourceKeyPrefix = "com.cloudflare.api.account."
+
+// tokenKind tells which endpoints describe the API token.
+type tokenKind int
+
+const (
+	userToken tokenKind = iota
+	accountToken
+)
+
+// tokenInfo is the result of verifying an API token.
+type tokenInfo struct {
+	kind      tokenKind
+	accountID ID // only meaningful for account tokens
+	body      cloudflare.APITokenVerifyBody
+}
+
+// isCredentialRejection checks whether the API rejected the credentials
+// themselves, as opposed to failing for an unrelated reason.
+func isCredentialRejection(err error) bool {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	return errors.As(err, &authentication) || errors.As(err, &authorization)
+}
+
+// verifyAccountToken verifies the API token as an account-owned token.
+func (h cloudflareHandle) verifyAccountToken(ctx context.Context, accountID ID) (cloudflare.APITokenVerifyBody, error) {
+	var body cloudflare.APITokenVerifyBody
+
+	res, err := h.cf.Raw(ctx, http.MethodGet, fmt.Sprintf("/accounts/%s/tokens/verify", accountID), nil, nil)
+	if err != nil {
+		return body, err //nolint:wrapcheck
+	}
+	if err := json.Unmarshal(res.Result, &body); err != nil {
+		return body, fmt.Errorf("parse the verification result: %w", err)
+	}
+	return body, nil
+}
+
+// verifyAccountTokens tries to verify the API token as an account-owned token
+// through each candidate account. The second return value is true when one of
+// the attempts succeeded, and the third one is true when every attempt
+// conclusively rejected the token as invalid.
+func (h cloudflareHandle) verifyAccountTokens(ctx context.Context, accountIDs []ID) (tokenInfo, bool, bool) {
+	rejected := true
+	for _, accountID := range accountIDs {
+		body, err := h.verifyAccountToken(ctx, accountID)
+		if err == nil {
+			return tokenInfo{kind: accountToken, accountID: accountID, body: body}, true, false
+		}
+		rejected = rejected && isCredentialRejection(err)
+	}
+	return tokenInfo{}, false, rejected
+}
+
+// zonesRejected checks whether the API token is rejected even when listing the
+// zones. An account-owned token can only be verified through the accounts of
+// its zones, and this tells whether looking them up is worth trying.
+func (h cloudflareHandle) zonesRejected(ctx context.Context) bool {
+	_, err := h.cf.Raw(ctx, http.MethodGet, "/zones?per_page=1", nil, nil)
+	return isCredentialRejection(err)
+}
+
+// readTokenPolicies reads the policies of a verified token. Reading them needs
+// the "Read" permission of "User - API Tokens" (or "Account - Account API
+// Tokens"), which most tokens for this updater do not have.
+func (h cloudflareHandle) readTokenPolicies(ctx context.Context, info tokenInfo,
+) ([]cloudflare.APITokenPolicies, error) {
+	switch info.kind {
+	case accountToken:
+		res, err := h.cf.Raw(ctx, http.MethodGet,
+			fmt.Sprintf("/accounts/%s/tokens/%s", info.accountID, info.body.ID), nil, nil)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		var token cloudflare.APIToken
+		if err := json.Unmarshal(res.Result, &token); err != nil {
+			return nil, fmt.Errorf("parse the token: %w", err)
+		}
+		return token.Policies, nil
+	default:
+		token, err := h.cf.GetAPIToken(ctx, info.body.ID)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		return token.Policies, nil
+	}
+}
+
+// resourcesCoverZone checks whether the policy resources include the zone,
+// either directly, through a zone wildcard, or through its account.
+func resourcesCoverZone(resources map[string]any, zone zoneMeta) bool {
+	for key, value := range resources {
+		switch key {
+		case resourceKeyPrefix + "zone." + string(zone.ID), resourceKeyPrefix + "zone.*":
+			return true
+		case resourceKeyPrefix + string(zone.AccountID), resourceKeyPrefix + "*":
+			if nested, ok := value.(map[string]any); ok && resourcesCoverZone(nested, zone) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resourcesCoverAccount checks whether the policy resources include the account.
+func resourcesCoverAccount(resources map[string]any, accountID ID) bool {
+	_, direct := resources[resourceKeyPrefix+string(accountID)]
+	_, wildcard := resources[resourceKeyPrefix+"*"]
+	return direct || wildcard
+}
+
+// policiesGrant evaluates token policies for one permission on one resource.
+// A matching "deny" policy always wins over matching "allow" policies.
+func policiesGrant(policies []cloudflare.APITokenPolicies, groups []string, covers func(map[string]any) bool) bool {
+	allowed := false
+	for _, policy := range policies {
+		if !slices.ContainsFunc(policy.PermissionGroups, func(g cloudflare.APITokenPermissionGroups) bool {
+			return slices.Contains(groups, g.Name)
+		}) || !covers(policy.Resources) {
+			continue
+		}
+		switch policy.Effect {
+		case "deny":
+			return false
+		case "allow":
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// checkTokenStatus compares the status and the expiry of a verified token.
+func checkTokenStatus(ppfmt pp.PP, body cloudflare.APITokenVerifyBody, now time.Time, report *PermissionReport) {
+	switch {
+	case body.Status != "active":
+		ppfmt.Noticef(pp.EmojiUserError, "The API token is %s; replace it with an active token", body.Status)
+		report.CredentialProblem = body.Status
+	case !body.ExpiresOn.IsZero() && !now.Before(body.ExpiresOn):
+		ppfmt.Noticef(pp.EmojiUserError, "The API token expired at %s; replace it with an active token",
+			body.ExpiresOn.Format(time.RFC3339))
+		report.CredentialProblem = "expired"
+	case !body.ExpiresOn.IsZero() && body.ExpiresOn.Sub(now) < permissionExpiryWarning:
+		ppfmt.Noticef(pp.EmojiUserWarning, "The API token will expire at %s; remember to replace it before then",
+			body.ExpiresOn.Format(time.RFC3339))
+		report.ExpiresOn = body.ExpiresOn
+	}
+}
+
+// lookupZones finds the zones of the domains, recording the domains without
+// zones in the report. It also returns the accounts of the zones found.
+func (h cloudflareHandle) lookupZones(ctx context.Context, ppfmt pp.PP, domains []domain.Domain,
+	report *PermissionReport,
+) (map[domain.Domain]zoneMeta, []ID) {
+	zones := make(map[domain.Domain]zoneMeta, len(domains))
+	accountIDs := []ID{}
+	for _, d := range domains {
+		zone, ok := h.zoneMetaOfDomain(ctx, ppfmt, d)
+		if !ok {
+			report.DomainsWithoutZone = append(report.DomainsWithoutZone, d)
+			continue
+		}
+		zones[d] = zone
+		accountIDs = append(accountIDs, zone.AccountID)
+	}
+	slices.Sort(accountIDs)
+	return zones, slices.Compact(accountIDs)
+}
+
+// CheckPermissions verifies the credentials and, when the token can read its
+// own policies, compares them against the zones of the domains and the
+// accounts of the WAF lists.
+func (h cloudflareHandle) CheckPermissions(ctx context.Context, ppfmt pp.PP,
+	domains []domain.Domain, lists []WAFList,
+) PermissionReport {
+	report := PermissionReport{
+		Verified:                   false,
+		Inspected:                  false,
+		CredentialProblem:          "",
+		ExpiresOn:                  time.Time{},
+		DomainsWithoutZone:         nil,
+		DomainsWithoutDNSEdit:      nil,
+		AccountsWithoutWAFListEdit: nil,
+	}
+
+	// Step 1: verify the credentials before looking up the zones, so that a
+	// wrong credential is reported once instead of failing every lookup.
+	if h.cf.APIKey != "" {
+		// The legacy global API key has every permission of its owner.
+		if _, err := h.cf.UserDetails(ctx); err != nil {
+			if isCredentialRejection(err) {
+				ppfmt.Noticef(pp.EmojiUserError, "The global API key or the email address was rejected by Cloudflare")
+				report.CredentialProblem = "invalid"
+			} else {
+				ppfmt.Noticef(pp.EmojiWarning, "Could not verify the global API key: %v", err)
+			}
+			return report
+		}
+		report.Verified = true
+		report.Inspected = true
+		h.lookupZones(ctx, ppfmt, domains, &report)
+		return report
+	}
+
+	// User-owned tokens are verified through /user/tokens/verify; account-owned
+	// tokens are rejected there and are verified through their accounts instead.
+	listAccountIDs := make([]ID, 0, len(lists))
+	for _, list := range lists {
+		listAccountIDs = append(listAccountIDs, list.AccountID)
+	}
+	slices.Sort(listAccountIDs)
+	listAccountIDs = slices.Compact(listAccountIDs)
+
+	body, userErr := h.cf.VerifyAPIToken(ctx)
+	info := tokenInfo{kind: userToken, accountID: "", body: body}
+	verified := userErr == nil
+	rejected := isCredentialRejection(userErr)
+	if !verified {
+		var listRejected bool
+		info, verified, listRejected = h.verifyAccountTokens(ctx, listAccountIDs)
+		rejected = rejected && listRejected
+	}
+	if !verified && rejected && h.zonesRejected(ctx) {
+		ppfmt.Noticef(pp.EmojiUserError, "The API token was rejected by Cloudflare; double-check its value")
+		report.CredentialProblem = "invalid"
+		return report
+	}
+
+	// An account-owned token that is not used for WAF lists can only be
+	// verified through the accounts of its zones.
+	var zones map[domain.Domain]zoneMeta
+	if !verified {
+		var zoneAccountIDs []ID
+		zones, zoneAccountIDs = h.lookupZones(ctx, ppfmt, domains, &report)
+		zoneAccountIDs = slices.DeleteFunc(zoneAccountIDs, func(id ID) bool {
+			_, found := slices.BinarySearch(listAccountIDs, id)
+			return found
+		})
+		var zoneRejected bool
+		info, verified, zoneRejected = h.verifyAccountTokens(ctx, zoneAccountIDs)
+		rejected = rejected && zoneRejected
+		if !verified {
+			if rejected && len(listAccountIDs)+len(zoneAccountIDs) > 0 {
+				ppfmt.Noticef(pp.EmojiUserError, "The API token was rejected by Cloudflare; double-check its value")
+				report.CredentialProblem = "invalid"
+			} else {
+				ppfmt.Noticef(pp.EmojiWarning, "Could not verify the API token: %v", userErr)
+			}
+			return report
+		}
+	}
+	report.Verified = true
+
+	checkTokenStatus(ppfmt, info.body, time.Now(), &report)
+	if report.CredentialProblem != "" {
+		return report
+	}
+
+	// Step 2: find the zones; this is also what the first update does.
+	if zones == nil {
+		zones, _ = h.lookupZones(ctx, ppfmt, domains, &report)
+	}
+
+	// Step 3: compare the policies against the zones and the accounts.
+	policies, err := h.readTokenPolicies(ctx, info)
+	if err != nil {
+		ppfmt.Infof(pp.EmojiWarning,
+			"Could not read the permissions of the API token (%v); they will be tested by the first update instead", err)
+		return report
+	}
+	report.Inspected = true
+
+	reportedZones := map[ID]bool{}
+	for _, d := range domains {
+		zone, found := zones[d]
+		if !found || policiesGrant(policies, dnsEditPermissionGroups,
+			func(r map[string]any) bool { return resourcesCoverZone(r, zone) }) {
+			continue
+		}
+		report.DomainsWithoutDNSEdit = append(report.DomainsWithoutDNSEdit, d)
+		if !reportedZones[zone.ID] {
+			reportedZones[zone.ID] = true
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The API token lacks the "Edit" permission of "Zone - DNS" for the zone of %s (ID: %s)`,
+				d.Describe(), zone.ID)
+		}
+	}
+
+	for _, accountID := range listAccountIDs {
+		if policiesGrant(policies, wafListEditPermissionGroups,
+			func(r map[string]any) bool { return resourcesCoverAccount(r, accountID) }) {
+			continue
+		}
+		report.AccountsWithoutWAFListEdit = append(report.AccountsWithoutWAFListEdit, accountID)
+		ppfmt.Noticef(pp.EmojiUserError,
+			`The API token lacks the "Edit" permission of "Account - Account Filter Lists" for the account %s`,
+			accountID)
+	}
+
+	return report
+}
@@ -0,0 +1,352 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const mockTokenID = "token789"
+
+func mockVerifyResponse(status string, expiresOn time.Time) cloudflare.APITokenVerifyResponse {
+	return cloudflare.APITokenVerifyResponse{
+		Response: mockResponse(),
+		Result: cloudflare.APITokenVerifyBody{
+			ID:        mockTokenID,
+			Status:    status,
+			NotBefore: time.Time{},
+			ExpiresOn: expiresOn,
+		},
+	}
+}
+
+func mockPolicy(effect string, group string, resources map[string]any) cloudflare.APITokenPolicies {
+	return cloudflare.APITokenPolicies{
+		ID:               "",
+		Effect:           effect,
+		Resources:        resources,
+		PermissionGroups: []cloudflare.APITokenPermissionGroups{{ID: "", Name: group, Scopes: nil}},
+	}
+}
+
+func mockTokenResponse(policies []cloudflare.APITokenPolicies) cloudflare.APITokenResponse {
+	return cloudflare.APITokenResponse{
+		Response: mockResponse(),
+		Result: cloudflare.APIToken{ //nolint:exhaustruct
+			ID:       mockTokenID,
+			Name:     "ddns",
+			Status:   "active",
+			Policies: policies,
+		},
+	}
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, status int, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	assert.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+func mockRejection() cloudflare.Response {
+	return cloudflare.Response{
+		Success:  false,
+		Errors:   []cloudflare.ResponseInfo{{Code: 1000, Message: "Invalid API Token"}}, //nolint:exhaustruct
+		Messages: []cloudflare.ResponseInfo{},
+	}
+}
+
+func zoneResource() string {
+	return "com.cloudflare.api.account.zone." + string(mockID("test.org", 0))
+}
+
+func accountResource() string {
+	return "com.cloudflare.api.account." + string(mockAccountID)
+}
+
+//nolint:funlen
+func TestCheckPermissions(t *testing.T) {
+	t.Parallel()
+
+	list := api.WAFList{AccountID: mockAccountID, Name: "list"}
+	dnsEdit := mockPolicy("allow", "DNS Write", map[string]any{zoneResource(): "*"})
+	wafEdit := mockPolicy("allow", "Account Filter Lists Edit", map[string]any{accountResource(): "*"})
+
+	for name, tc := range map[string]struct {
+		userVerify    func(t *testing.T, w http.ResponseWriter)
+		accountVerify func(t *testing.T, w http.ResponseWriter)
+		zonesProbe    func(t *testing.T, w http.ResponseWriter) // nil means the zones are not probed
+		zoneRequests  int
+		policies      []cloudflare.APITokenPolicies // nil means the token cannot read itself
+		lists         []api.WAFList
+		expected      api.PermissionReport
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"all-granted": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, mockVerifyResponse("active", time.Time{}))
+			},
+			nil,
+			nil,
+			2,
+			[]cloudflare.APITokenPolicies{dnsEdit, wafEdit},
+			[]api.WAFList{list},
+			api.PermissionReport{Verified: true, Inspected: true}, //nolint:exhaustruct
+			nil,
+		},
+		"account-wide": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, mockVerifyResponse("active", time.Time{}))
+			},
+			nil,
+			nil,
+			2,
+			[]cloudflare.APITokenPolicies{
+				mockPolicy("allow", "DNS Write", map[string]any{accountResource(): map[string]any{"com.cloudflare.api.account.zone.*": "*"}}),
+			},
+			nil,
+			api.PermissionReport{Verified: true, Inspected: true}, //nolint:exhaustruct
+			nil,
+		},
+		"missing": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, mockVerifyResponse("active", time.Time{}))
+			},
+			nil,
+			nil,
+			2,
+			[]cloudflare.APITokenPolicies{
+				dnsEdit,
+				mockPolicy("deny", "DNS Write", map[string]any{zoneResource(): "*"}),
+				mockPolicy("allow", "Account Filter Lists Read", map[string]any{accountResource(): "*"}),
+			},
+			[]api.WAFList{list},
+			api.PermissionReport{ //nolint:exhaustruct
+				Verified:                   true,
+				Inspected:                  true,
+				DomainsWithoutDNSEdit:      []domain.Domain{domain.FQDN("sub.test.org")},
+				AccountsWithoutWAFListEdit: []api.ID{mockAccountID},
+			},
+			func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().Noticef(pp.EmojiUserError, `The API token lacks the "Edit" permission of "Zone - DNS" for the zone of %s (ID: %s)`, "sub.test.org", mockID("test.org", 0)),
+					m.EXPECT().Noticef(pp.EmojiUserError, `The API token lacks the "Edit" permission of "Account - Account Filter Lists" for the account %s`, mockAccountID),
+				)
+			},
+		},
+		"account-token": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusUnauthorized, mockRejection())
+			},
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, mockVerifyResponse("active", time.Time{}))
+			},
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, cloudflare.ZonesResponse{Response: mockResponse(), Result: []cloudflare.Zone{}}) //nolint:exhaustruct
+			},
+			2,
+			[]cloudflare.APITokenPolicies{dnsEdit},
+			nil,
+			api.PermissionReport{Verified: true, Inspected: true}, //nolint:exhaustruct
+			nil,
+		},
+		"rejected": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusUnauthorized, mockRejection())
+			},
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusUnauthorized, mockRejection())
+			},
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusUnauthorized, mockRejection())
+			},
+			0,
+			nil,
+			nil,
+			api.PermissionReport{CredentialProblem: "invalid"}, //nolint:exhaustruct
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The API token was rejected by Cloudflare; double-check its value")
+			},
+		},
+		"expired": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, mockVerifyResponse("expired", time.Time{}))
+			},
+			nil,
+			nil,
+			0,
+			nil,
+			nil,
+			api.PermissionReport{Verified: true, CredentialProblem: "expired"}, //nolint:exhaustruct
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The API token is %s; replace it with an active token", "expired")
+			},
+		},
+		"unreadable": {
+			func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+				writeJSON(t, w, http.StatusOK, mockVerifyResponse("active", time.Time{}))
+			},
+			nil,
+			nil,
+			2,
+			nil,
+			nil,
+			api.PermissionReport{Verified: true}, //nolint:exhaustruct
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiWarning, "Could not read the permissions of the API token (%v); they will be tested by the first update instead", gomock.Any())
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			// The probe for a rejected token lists the zones without a name.
+			zonesMux := http.NewServeMux()
+			zh := newZonesHandler(t, zonesMux, map[string][]string{"test.org": {"active"}})
+			zh.setRequestLimit(tc.zoneRequests)
+			probed := false
+			f.serveMux.HandleFunc("GET /zones", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Has("name") {
+					zonesMux.ServeHTTP(w, r)
+					return
+				}
+				if !checkToken(t, r) || tc.zonesProbe == nil || probed {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				probed = true
+				tc.zonesProbe(t, w)
+			})
+
+			f.serveMux.HandleFunc("GET /user/tokens/verify", func(w http.ResponseWriter, r *http.Request) {
+				if !checkToken(t, r) {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				tc.userVerify(t, w)
+			})
+			f.serveMux.HandleFunc(fmt.Sprintf("GET /accounts/%s/tokens/verify", mockAccountID), func(w http.ResponseWriter, r *http.Request) {
+				if !checkToken(t, r) || tc.accountVerify == nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				tc.accountVerify(t, w)
+			})
+			readToken := func(w http.ResponseWriter, r *http.Request) {
+				if !checkToken(t, r) {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if tc.policies == nil {
+					writeJSON(t, w, http.StatusForbidden, mockRejection())
+					return
+				}
+				writeJSON(t, w, http.StatusOK, mockTokenResponse(tc.policies))
+			}
+			f.serveMux.HandleFunc("GET /user/tokens/"+mockTokenID, readToken)
+			f.serveMux.HandleFunc(fmt.Sprintf("GET /accounts/%s/tokens/%s", mockAccountID, mockTokenID), readToken)
+
+			report := f.handle.CheckPermissions(context.Background(), f.newPreparedPP(tc.prepareMockPP),
+				[]domain.Domain{domain.FQDN("sub.test.org")}, tc.lists)
+			require.Equal(t, tc.expected, report)
+			assertHandlersExhausted(t, zh)
+			require.Equal(t, tc.zonesProbe != nil, probed)
+		})
+	}
+}
+
+func TestCheckPermissionsExpiringSoon(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	expiresOn := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	f.serveMux.HandleFunc("GET /user/tokens/verify", func(w http.ResponseWriter, r *http.Request) {
+		if !checkToken(t, r) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJSON(t, w, http.StatusOK, mockVerifyResponse("active", expiresOn))
+	})
+	f.serveMux.HandleFunc("GET /user/tokens/"+mockTokenID, func(w http.ResponseWriter, r *http.Request) {
+		if !checkToken(t, r) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJSON(t, w, http.StatusOK, mockTokenResponse(nil))
+	})
+
+	mockPP := f.newPP()
+	mockPP.EXPECT().Noticef(pp.EmojiUserWarning, "The API token will expire at %s; remember to replace it before then", expiresOn.Format(time.RFC3339))
+	report := f.handle.CheckPermissions(context.Background(), mockPP, nil, nil)
+	require.Equal(t, api.PermissionReport{Verified: true, Inspected: true, ExpiresOn: expiresOn}, report) //nolint:exhaustruct
+	require.False(t, report.HasProblems())
+}
+
+func TestCheckPermissionsGlobalKey(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+
+	for name, tc := range map[string]struct {
+		status        int
+		expected      api.PermissionReport
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"valid": {http.StatusOK, api.PermissionReport{Verified: true, Inspected: true}, nil}, //nolint:exhaustruct
+		"rejected": {
+			http.StatusForbidden,
+			api.PermissionReport{CredentialProblem: "invalid"}, //nolint:exhaustruct
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The global API key or the email address was rejected by Cloudflare")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			serveMux, auth := newServerAuth(t)
+			serveMux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, []string{"0123456789abcdef"}, r.Header["X-Auth-Key"])
+				if tc.status != http.StatusOK {
+					writeJSON(t, w, tc.status, mockRejection())
+					return
+				}
+				writeJSON(t, w, http.StatusOK, cloudflare.UserResponse{Response: mockResponse(), Result: cloudflare.User{}}) //nolint:exhaustruct
+			})
+
+			globalAuth := api.CloudflareGlobalKeyAuth{Key: "0123456789abcdef", Email: "operator@example.org", BaseURL: auth.BaseURL}
+			h, ok := globalAuth.New(mocks.NewMockPP(mockCtrl), defaultHandleOptions())
+			require.True(t, ok)
+
+			mockPP := mocks.NewMockPP(mockCtrl)
+			prepareMockPP(mockPP, tc.prepareMockPP)
+			require.Equal(t, tc.expected, h.CheckPermissions(context.Background(), mockPP, nil, nil))
+		})
+	}
+}
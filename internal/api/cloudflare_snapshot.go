@@ -0,0 +1,121 @@
+package api
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// A RecordSnapshot is the cached view of the managed records of one domain,
+// together with the zone holding them. It is used to carry the caches of a
+// handle across restarts.
+type RecordSnapshot struct {
+	IPFamily  ipnet.Family
+	Domain    string // the ASCII DNS name, as used in the API calls
+	ZoneID    ID     // empty if the zone is no longer cached
+	AccountID ID
+	Server    string // the RFC 2136 server holding the records, or empty for Cloudflare
+	Records   []Record
+	FetchedAt time.Time // when the records were retrieved from the API
+}
+
+// A RecordSnapshotter is a handle whose knowledge of the DNS records can be exported.
+type RecordSnapshotter interface {
+	// SnapshotRecords returns the records known to the handle, sorted by
+	// IP family and domain.
+	SnapshotRecords() []RecordSnapshot
+}
+
+// A RecordCacheScope identifies what the cached records depend on besides the
+// remote state: the credential, which decides what can be seen, and the
+// managed-record selector, which filters the records. Snapshots taken in one
+// scope must not be used to seed the caches of another.
+type RecordCacheScope struct {
+	// Credential is a fingerprint of the credential, never the credential itself.
+	Credential                 string
+	ManagedRecordsCommentRegex string
+}
+
+// A RecordCache is a handle whose caches of zones and DNS records can be
+// exported and seeded. Its snapshots only hold the unexpired cached records.
+type RecordCache interface {
+	RecordSnapshotter
+
+	// RecordCacheScope returns the scope of the cached records.
+	RecordCacheScope() RecordCacheScope
+
+	// SeedRecords fills the caches with the snapshots, as if the records were
+	// retrieved at their FetchedAt. Snapshots that would already have expired
+	// at now are skipped. It returns the number of snapshots used.
+	SeedRecords(snapshots []RecordSnapshot, now time.Time) int
+}
+
+var _ RecordCache = cloudflareHandle{} //nolint:exhaustruct
+
+// RecordCacheScope implements [RecordCache].
+func (h cloudflareHandle) RecordCacheScope() RecordCacheScope {
+	regex := ""
+	if h.options.ManagedRecordsCommentRegex != nil {
+		regex = h.options.ManagedRecordsCommentRegex.String()
+	}
+	return RecordCacheScope{
+		Credential:                 fingerprint(h.cf.BaseURL, h.cf.APIToken, h.cf.APIEmail, h.cf.APIKey),
+		ManagedRecordsCommentRegex: regex,
+	}
+}
+
+// SnapshotRecords implements [RecordCache].
+func (h cloudflareHandle) SnapshotRecords() []RecordSnapshot {
+	var snapshots []RecordSnapshot
+	for ipFamily := range ipnet.All {
+		for domain, item := range h.cache.listRecords[ipFamily].Items() {
+			if item.IsExpired() {
+				continue
+			}
+			snapshot := RecordSnapshot{
+				IPFamily:  ipFamily,
+				Domain:    domain,
+				ZoneID:    "",
+				AccountID: "",
+				Server:    "",
+				Records:   slices.Clone(*item.Value()),
+				FetchedAt: item.ExpiresAt().Add(-h.options.CacheExpiration),
+			}
+			if zone := h.cache.zoneOfDomain.Get(domain); zone != nil {
+				snapshot.ZoneID = zone.Value().ID
+				snapshot.AccountID = zone.Value().AccountID
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	slices.SortFunc(snapshots, func(a, b RecordSnapshot) int {
+		return cmp.Or(cmp.Compare(a.IPFamily, b.IPFamily), cmp.Compare(a.Domain, b.Domain))
+	})
+	return snapshots
+}
+
+// SeedRecords implements [RecordCache].
+func (h cloudflareHandle) SeedRecords(snapshots []RecordSnapshot, now time.Time) int {
+	seeded := 0
+	for _, snapshot := range snapshots {
+		cache, ok := h.cache.listRecords[snapshot.IPFamily]
+		if !ok {
+			continue
+		}
+		age := now.Sub(snapshot.FetchedAt)
+		if age < 0 || age >= h.options.CacheExpiration {
+			continue
+		}
+		ttl := h.options.CacheExpiration - age
+
+		records := slices.Clone(snapshot.Records)
+		cache.Set(snapshot.Domain, &records, ttl)
+		if snapshot.ZoneID != "" {
+			h.cache.zoneOfDomain.Set(snapshot.Domain, zoneMeta{ID: snapshot.ZoneID, AccountID: snapshot.AccountID}, ttl)
+		}
+		seeded++
+	}
+	return seeded
+}
@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func TestSnapshotRecords(t *testing.T) {
+	t.Parallel()
+
+	params := api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil}
+
+	f := newCloudflareHarness(t)
+	require.Empty(t, f.cfHandle.SnapshotRecords())
+
+	zh := newZonesHandler(t, f.serveMux, map[string][]string{"test.org": {"active"}})
+	lrh := newListRecordsHandler(t, f.serveMux, ipnet.IP6, "sub.test.org", []formattedRecord{
+		{ID: "record1", IP: "::1", Comment: "", Tags: nil},
+	})
+
+	zh.setRequestLimit(2)
+	lrh.setRequestLimit(1)
+	before := time.Now()
+	_, _, ok := f.handle.ListRecords(context.Background(), f.newPP(), ipnet.IP6, domain.FQDN("sub.test.org"), params)
+	require.True(t, ok)
+	after := time.Now()
+	assertHandlersExhausted(t, zh, lrh)
+
+	snapshots := f.cfHandle.SnapshotRecords()
+	require.Len(t, snapshots, 1)
+	require.WithinRange(t, snapshots[0].FetchedAt, before.Add(-time.Second), after.Add(time.Second))
+	snapshots[0].FetchedAt = time.Time{}
+	require.Equal(t, api.RecordSnapshot{
+		IPFamily:  ipnet.IP6,
+		Domain:    "sub.test.org",
+		ZoneID:    mockID("test.org", 0),
+		AccountID: mockAccountID,
+		Server:    "",
+		Records:   []api.Record{{"record1", mustIP("::1"), params}},
+		FetchedAt: time.Time{},
+	}, snapshots[0])
+}
+
+func TestSeedRecords(t *testing.T) {
+	t.Parallel()
+
+	params := api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil}
+	now := time.Now()
+	snapshot := func(domain string, age time.Duration) api.RecordSnapshot {
+		return api.RecordSnapshot{
+			IPFamily:  ipnet.IP4,
+			Domain:    domain,
+			ZoneID:    mockID("test.org", 0),
+			AccountID: mockAccountID,
+			Records:   []api.Record{{"record1", mustIP("1.1.1.1"), params}},
+			FetchedAt: now.Add(-age),
+		}
+	}
+
+	f := newCloudflareHarness(t)
+	zh := newZonesHandler(t, f.serveMux, map[string][]string{"test.org": {"active"}})
+	lrh := newListRecordsHandler(t, f.serveMux, ipnet.IP4, "sub.test.org", nil)
+
+	require.Equal(t, 1, f.cfHandle.SeedRecords([]api.RecordSnapshot{
+		snapshot("sub.test.org", time.Hour),
+		snapshot("old.test.org", defaultHandleOptions().CacheExpiration),
+		snapshot("future.test.org", -time.Hour),
+	}, now))
+
+	// The seeded records and zones are served without any API calls.
+	zh.setRequestLimit(0)
+	lrh.setRequestLimit(0)
+	rs, cached, ok := f.handle.ListRecords(context.Background(), f.newPP(), ipnet.IP4, domain.FQDN("sub.test.org"), params)
+	require.True(t, ok)
+	require.True(t, cached)
+	require.Equal(t, []api.Record{{"record1", mustIP("1.1.1.1"), params}}, rs)
+	zoneID, ok := f.cfHandle.ZoneIDOfDomain(context.Background(), f.newPP(), domain.FQDN("sub.test.org"))
+	require.True(t, ok)
+	require.Equal(t, mockID("test.org", 0), zoneID)
+	assertHandlersExhausted(t, zh, lrh)
+
+	// The original retrieval time is kept.
+	snapshots := f.cfHandle.SnapshotRecords()
+	require.Len(t, snapshots, 1)
+	require.WithinDuration(t, now.Add(-time.Hour), snapshots[0].FetchedAt, time.Second)
+}
+
+func TestRecordCacheScope(t *testing.T) {
+	t.Parallel()
+
+	scopeOf := func(auth api.Auth, regex *regexp.Regexp) api.RecordCacheScope {
+		options := defaultHandleOptions()
+		options.ManagedRecordsCommentRegex = regex
+		h, ok := auth.New(pp.NewSilent(), options)
+		require.True(t, ok)
+		cache, ok := h.(api.RecordCache)
+		require.True(t, ok)
+		return cache.RecordCacheScope()
+	}
+
+	token := api.CloudflareAuth{Token: "token1", BaseURL: ""}
+	scope := scopeOf(token, regexp.MustCompile("^ddns$"))
+	require.Equal(t, "^ddns$", scope.ManagedRecordsCommentRegex)
+	require.NotContains(t, scope.Credential, "token1")
+	require.Equal(t, scope, scopeOf(token, regexp.MustCompile("^ddns$")))
+
+	require.NotEqual(t, scope.Credential,
+		scopeOf(api.CloudflareAuth{Token: "token2", BaseURL: ""}, nil).Credential)
+	require.NotEqual(t, scope.Credential,
+		scopeOf(api.CloudflareGlobalKeyAuth{Key: "token1", Email: "user@example.org", BaseURL: ""}, nil).Credential)
+	require.Empty(t, scopeOf(token, nil).ManagedRecordsCommentRegex)
+}
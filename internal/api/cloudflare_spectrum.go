@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func hintSpectrumAppPermission(ppfmt pp.PP, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if errors.As(err, &authentication) || errors.As(err, &authorization) {
+		pp.HintEditPermission(ppfmt, pp.FeatureSpectrumApps, "Zone - Zone Settings", "zone ID", "application ID")
+	}
+}
+
+// parseSpectrumOrigin parses a direct origin such as "tcp://192.0.2.1:22" or
+// "udp://[2001:db8::1]:27015".
+func parseSpectrumOrigin(origin string) (SpectrumOrigin, bool) {
+	scheme, hostPort, found := strings.Cut(origin, "://")
+	if !found || scheme == "" {
+		return SpectrumOrigin{}, false //nolint:exhaustruct
+	}
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil || port == "" {
+		return SpectrumOrigin{}, false //nolint:exhaustruct
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || addr.Zone() != "" {
+		return SpectrumOrigin{}, false //nolint:exhaustruct
+	}
+	return SpectrumOrigin{Scheme: scheme, Address: addr.Unmap(), Port: port}, true
+}
+
+func spectrumAppPath(app SpectrumApp) string {
+	return fmt.Sprintf("/zones/%s/spectrum/apps/%s", app.ZoneID, app.AppID)
+}
+
+// readSpectrumApp reads the application as a raw JSON object. Like Gateway
+// locations, the settings unknown to the updater are sent back unchanged; the
+// Spectrum API only supports replacing whole applications.
+func (h cloudflareHandle) readSpectrumApp(ctx context.Context, ppfmt pp.PP, app SpectrumApp,
+) (map[string]json.RawMessage, bool) {
+	res, err := h.cf.Raw(ctx, http.MethodGet, spectrumAppPath(app), nil, nil)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to read the Spectrum app %s: %v", app.Describe(), err)
+		hintSpectrumAppPermission(ppfmt, err)
+		return nil, false
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(res.Result, &raw); err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to parse the Spectrum app %s: %v", app.Describe(), err)
+		return nil, false
+	}
+	if raw == nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Found no Spectrum app %s", app.Describe())
+		return nil, false
+	}
+	return raw, true
+}
+
+func parseSpectrumAppOrigins(ppfmt pp.PP, app SpectrumApp, raw map[string]json.RawMessage,
+) ([]SpectrumOrigin, bool) {
+	var direct []string
+	if value, ok := raw["origin_direct"]; ok && string(value) != "null" {
+		if err := json.Unmarshal(value, &direct); err != nil {
+			ppfmt.Noticef(pp.EmojiImpossible,
+				"Failed to parse the origins of the Spectrum app %s: %v", app.Describe(), err)
+			return nil, false
+		}
+	}
+	if len(direct) == 0 {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The Spectrum app %s does not use direct origins; the updater will not change it", app.Describe())
+		return nil, false
+	}
+
+	origins := make([]SpectrumOrigin, 0, len(direct))
+	for _, d := range direct {
+		origin, ok := parseSpectrumOrigin(d)
+		if !ok {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"The origin %q of the Spectrum app %s is not in the format scheme://ip:port; the updater will not change it",
+				d, app.Describe())
+			return nil, false
+		}
+		origins = append(origins, origin)
+	}
+	return origins, true
+}
+
+// GetSpectrumAppOrigins reads the direct origins of a Spectrum application.
+func (h cloudflareHandle) GetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app SpectrumApp,
+) ([]SpectrumOrigin, bool) {
+	raw, ok := h.readSpectrumApp(ctx, ppfmt, app)
+	if !ok {
+		return nil, false
+	}
+	return parseSpectrumAppOrigins(ppfmt, app, raw)
+}
+
+// SetSpectrumAppOrigins re-reads the application and sends it back with new origins.
+func (h cloudflareHandle) SetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app SpectrumApp,
+	origins []SpectrumOrigin,
+) bool {
+	raw, ok := h.readSpectrumApp(ctx, ppfmt, app)
+	if !ok {
+		return false
+	}
+
+	direct := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		direct = append(direct, origin.String())
+	}
+	encoded, err := json.Marshal(direct)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the origins of the Spectrum app %s: %v",
+			app.Describe(), err)
+		return false
+	}
+	raw["origin_direct"] = encoded
+
+	if _, err := h.cf.Raw(ctx, http.MethodPut, spectrumAppPath(app), raw, nil); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the Spectrum app %s: %v", app.Describe(), err)
+		hintSpectrumAppPermission(ppfmt, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,175 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const mockSpectrumZoneID = api.ID("zone789")
+
+func mockSpectrumApp() api.SpectrumApp {
+	return api.SpectrumApp{ZoneID: mockSpectrumZoneID, AppID: "app1"}
+}
+
+// handleSpectrumApp serves the endpoint of one Spectrum application. GET requests
+// return the application; PUT requests are decoded into updated.
+func handleSpectrumApp(t *testing.T, serveMux *http.ServeMux, app map[string]any, updated *map[string]any,
+) httpHandler {
+	t.Helper()
+
+	requestLimit := new(int)
+	serveMux.HandleFunc(fmt.Sprintf("/zones/%s/spectrum/apps/app1", mockSpectrumZoneID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(t, w, http.StatusOK, mockResultResponse(app))
+			case http.MethodPut:
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.NoError(t, json.Unmarshal(body, updated))
+				writeJSON(t, w, http.StatusOK, mockResultResponse(*updated))
+			default:
+				t.Errorf("unexpected method %s", r.Method)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		})
+
+	return httpHandler{requestLimit: requestLimit}
+}
+
+func TestGetSpectrumAppOrigins(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		app           map[string]any
+		expected      []api.SpectrumOrigin
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"direct": {
+			map[string]any{"id": "app1", "origin_direct": []any{"tcp://192.0.2.1:22", "udp://[2001:db8::1]:27015-27020"}},
+			[]api.SpectrumOrigin{
+				{Scheme: "tcp", Address: netip.MustParseAddr("192.0.2.1"), Port: "22"},
+				{Scheme: "udp", Address: netip.MustParseAddr("2001:db8::1"), Port: "27015-27020"},
+			},
+			true,
+			nil,
+		},
+		"dns": {
+			map[string]any{"id": "app1", "origin_dns": map[string]any{"name": "origin.example.org"}, "origin_port": 22},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The Spectrum app %s does not use direct origins; the updater will not change it", "zone789/app1")
+			},
+		},
+		"hostname": {
+			map[string]any{"id": "app1", "origin_direct": []any{"tcp://origin.example.org:22"}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The origin %q of the Spectrum app %s is not in the format scheme://ip:port; the updater will not change it", "tcp://origin.example.org:22", "zone789/app1")
+			},
+		},
+		"no-port": {
+			map[string]any{"id": "app1", "origin_direct": []any{"tcp://192.0.2.1"}},
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The origin %q of the Spectrum app %s is not in the format scheme://ip:port; the updater will not change it", "tcp://192.0.2.1", "zone789/app1")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			handler := handleSpectrumApp(t, f.serveMux, tc.app, nil)
+			handler.setRequestLimit(1)
+
+			mockPP := f.newPreparedPP(tc.prepareMockPP)
+			origins, ok := f.handle.GetSpectrumAppOrigins(context.Background(), mockPP, mockSpectrumApp())
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, origins)
+			assertHandlersExhausted(t, handler)
+		})
+	}
+}
+
+func TestSetSpectrumAppOriginsKeepsOtherSettings(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	var updated map[string]any
+	handler := handleSpectrumApp(t, f.serveMux, map[string]any{
+		"id":            "app1",
+		"protocol":      "tcp/22",
+		"dns":           map[string]any{"type": "CNAME", "name": "ssh.example.org"},
+		"origin_direct": []any{"tcp://192.0.2.1:22"},
+		"ip_firewall":   true,
+	}, &updated)
+	handler.setRequestLimit(2)
+
+	ok := f.handle.SetSpectrumAppOrigins(context.Background(), f.newPP(), mockSpectrumApp(), []api.SpectrumOrigin{
+		{Scheme: "tcp", Address: netip.MustParseAddr("198.51.100.8"), Port: "22"},
+		{Scheme: "tcp", Address: netip.MustParseAddr("2001:db8::8"), Port: "22"},
+	})
+	require.True(t, ok)
+	require.Equal(t, map[string]any{
+		"id":            "app1",
+		"protocol":      "tcp/22",
+		"dns":           map[string]any{"type": "CNAME", "name": "ssh.example.org"},
+		"origin_direct": []any{"tcp://198.51.100.8:22", "tcp://[2001:db8::8]:22"},
+		"ip_firewall":   true,
+	}, updated)
+	assertHandlersExhausted(t, handler)
+}
+
+func TestSetSpectrumAppOriginsFailed(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	requestLimit := 1
+	f.serveMux.HandleFunc(fmt.Sprintf("/zones/%s/spectrum/apps/app1", mockSpectrumZoneID),
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			writeJSON(t, w, http.StatusForbidden, cloudflare.Response{
+				Success:  false,
+				Errors:   []cloudflare.ResponseInfo{{Code: 10000, Message: "Authentication error"}}, //nolint:exhaustruct
+				Messages: []cloudflare.ResponseInfo{},
+			})
+		})
+
+	mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+		gomock.InOrder(
+			m.EXPECT().Noticef(pp.EmojiError, "Failed to read the Spectrum app %s: %v", "zone789/app1", gomock.Any()),
+			m.EXPECT().NoticeOncef(pp.MessagePermission(pp.FeatureSpectrumApps), pp.EmojiHint, `Double-check your %s. Make sure you granted the "Edit" permission of "%s"`, "API token, zone ID, and application ID", "Zone - Zone Settings"),
+		)
+	})
+	ok := f.handle.SetSpectrumAppOrigins(context.Background(), mockPP, mockSpectrumApp(), nil)
+	require.False(t, ok)
+	require.Zero(t, requestLimit)
+}
@@ -1,11 +1,17 @@
 package api_test
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/mocks"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
@@ -32,3 +38,48 @@ func TestNewEmptyToken(t *testing.T) {
 	require.False(t, ok)
 	require.Nil(t, h)
 }
+
+func TestNewGlobalKeySendsLegacyHeaders(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	serveMux := http.NewServeMux()
+	ts := httptest.NewServer(serveMux)
+	t.Cleanup(ts.Close)
+
+	serveMux.HandleFunc("GET /zones", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"0123456789abcdef"}, r.Header["X-Auth-Key"])
+		assert.Equal(t, []string{"operator@example.org"}, r.Header["X-Auth-Email"])
+		assert.Empty(t, r.Header["Authorization"])
+
+		w.Header().Set("content-type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(mockZonesResponse("example.org", []string{"active"})))
+	})
+
+	auth := api.CloudflareGlobalKeyAuth{
+		Key:     "0123456789abcdef",
+		Email:   "operator@example.org",
+		BaseURL: ts.URL,
+	}
+	h, ok := auth.New(mockPP, defaultHandleOptions())
+	require.True(t, ok)
+
+	ch, ok := h.(api.CloudflareHandle)
+	require.True(t, ok)
+	zones, ok := ch.ListZones(context.Background(), mockPP, "example.org")
+	require.True(t, ok)
+	require.Equal(t, []api.ID{mockID("example.org", 0)}, zones)
+}
+
+func TestNewGlobalKeyEmptyKey(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	auth := api.CloudflareGlobalKeyAuth{Key: "", Email: "operator@example.org", BaseURL: ""}
+	mockPP.EXPECT().Noticef(pp.EmojiUserError, "Failed to prepare the Cloudflare API client: %v", gomock.Any())
+	h, ok := auth.New(mockPP, defaultHandleOptions())
+	require.False(t, ok)
+	require.Nil(t, h)
+}
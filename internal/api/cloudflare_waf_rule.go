@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// wafCustomRulePhase is the phase of the rulesets holding the custom rules of a zone.
+const wafCustomRulePhase = "http_request_firewall_custom"
+
+// wafCustomRule is the part of a ruleset rule read and written by the updater.
+type wafCustomRule struct {
+	ID               string         `json:"id,omitempty"`
+	Action           string         `json:"action"`
+	ActionParameters map[string]any `json:"action_parameters,omitempty"`
+	Expression       string         `json:"expression"`
+	Description      string         `json:"description"`
+	Enabled          bool           `json:"enabled"`
+	Position         map[string]int `json:"position,omitempty"`
+}
+
+type wafCustomRuleset struct {
+	ID    string          `json:"id"`
+	Rules []wafCustomRule `json:"rules"`
+}
+
+func hintWAFListRulePermission(ppfmt pp.PP, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if errors.As(err, &authentication) || errors.As(err, &authorization) {
+		pp.HintEditPermission(ppfmt, pp.FeatureWAFListRule, "Zone - Zone WAF", "zone ID")
+	}
+}
+
+// newWAFCustomRule converts a rule to the format used by the API. The "skip"
+// action skips the remaining custom rules, which is what the dashboard does
+// by default; the API requires the rules to skip to be specified.
+func newWAFCustomRule(rule WAFCustomRule) wafCustomRule {
+	var parameters map[string]any
+	if rule.Action == "skip" {
+		parameters = map[string]any{"ruleset": "current"}
+	}
+	return wafCustomRule{
+		ID:               "",
+		Action:           rule.Action,
+		ActionParameters: parameters,
+		Expression:       rule.Expression,
+		Description:      rule.Description,
+		Enabled:          rule.Enabled,
+		Position:         nil,
+	}
+}
+
+func parseWAFCustomRuleset(ppfmt pp.PP, zoneID ID, res cloudflare.RawResponse) (wafCustomRuleset, bool) {
+	var ruleset wafCustomRuleset
+	if err := json.Unmarshal(res.Result, &ruleset); err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to parse the custom rules of the zone %s: %v", zoneID, err)
+		return wafCustomRuleset{}, false //nolint:exhaustruct
+	}
+	return ruleset, true
+}
+
+// ListWAFCustomRules reads the entry point ruleset of the custom rules of a zone.
+func (h cloudflareHandle) ListWAFCustomRules(ctx context.Context, ppfmt pp.PP, zoneID ID,
+) (ID, []WAFCustomRule, bool) {
+	res, err := h.cf.Raw(ctx, http.MethodGet,
+		fmt.Sprintf("/zones/%s/rulesets/phases/%s/entrypoint", zoneID, wafCustomRulePhase), nil, nil)
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+		if errors.As(err, &notFound) {
+			return "", []WAFCustomRule{}, true
+		}
+		ppfmt.Noticef(pp.EmojiError, "Failed to read the custom rules of the zone %s: %v", zoneID, err)
+		hintWAFListRulePermission(ppfmt, err)
+		return "", nil, false
+	}
+
+	ruleset, ok := parseWAFCustomRuleset(ppfmt, zoneID, res)
+	if !ok {
+		return "", nil, false
+	}
+
+	rules := make([]WAFCustomRule, 0, len(ruleset.Rules))
+	for _, r := range ruleset.Rules {
+		rules = append(rules, WAFCustomRule{
+			ID:          ID(r.ID),
+			Action:      r.Action,
+			Expression:  r.Expression,
+			Description: r.Description,
+			Enabled:     r.Enabled,
+		})
+	}
+	return ID(ruleset.ID), rules, true
+}
+
+// CreateWAFCustomRule adds the rule as the first custom rule, so that the
+// "skip" action can skip the other custom rules. If the zone does not have the
+// ruleset yet, the ruleset is created with the rule as its only rule.
+func (h cloudflareHandle) CreateWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID ID, rulesetID ID,
+	rule WAFCustomRule,
+) (ID, bool) {
+	raw := newWAFCustomRule(rule)
+
+	var res cloudflare.RawResponse
+	var err error
+	if rulesetID == "" {
+		res, err = h.cf.Raw(ctx, http.MethodPut,
+			fmt.Sprintf("/zones/%s/rulesets/phases/%s/entrypoint", zoneID, wafCustomRulePhase),
+			map[string]any{"rules": []wafCustomRule{raw}}, nil)
+	} else {
+		raw.Position = map[string]int{"index": 1}
+		res, err = h.cf.Raw(ctx, http.MethodPost,
+			fmt.Sprintf("/zones/%s/rulesets/%s/rules", zoneID, rulesetID), raw, nil)
+	}
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to create a custom rule in the zone %s: %v", zoneID, err)
+		hintWAFListRulePermission(ppfmt, err)
+		return "", false
+	}
+
+	ruleset, ok := parseWAFCustomRuleset(ppfmt, zoneID, res)
+	if !ok {
+		return "", false
+	}
+	if len(ruleset.Rules) == 0 || ruleset.Rules[0].Description != rule.Description {
+		ppfmt.Noticef(pp.EmojiImpossible,
+			"Failed to find the new custom rule in the zone %s; please report this at %s",
+			zoneID, pp.IssueReportingURL)
+		return "", false
+	}
+	return ID(ruleset.Rules[0].ID), true
+}
+
+// UpdateWAFCustomRule calls the API to replace the rule without moving it.
+func (h cloudflareHandle) UpdateWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID ID, rulesetID ID,
+	rule WAFCustomRule,
+) bool {
+	if _, err := h.cf.Raw(ctx, http.MethodPatch,
+		fmt.Sprintf("/zones/%s/rulesets/%s/rules/%s", zoneID, rulesetID, rule.ID),
+		newWAFCustomRule(rule), nil); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the custom rule %s in the zone %s: %v", rule.ID, zoneID, err)
+		hintWAFListRulePermission(ppfmt, err)
+		return false
+	}
+	return true
+}
+
+// DeleteWAFCustomRule calls the API to delete the rule. (cloudflare.DeleteRulesetRule
+// is not used because it treats the updated ruleset in the response as an error.)
+func (h cloudflareHandle) DeleteWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID ID, rulesetID ID,
+	ruleID ID,
+) bool {
+	if _, err := h.cf.Raw(ctx, http.MethodDelete,
+		fmt.Sprintf("/zones/%s/rulesets/%s/rules/%s", zoneID, rulesetID, ruleID), nil, nil); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to delete the custom rule %s in the zone %s: %v", ruleID, zoneID, err)
+		hintWAFListRulePermission(ppfmt, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const (
+	mockCustomRuleZoneID    = api.ID("zone789")
+	mockCustomRuleRulesetID = "ruleset1"
+)
+
+// fakeCustomRules is a small in-memory implementation of the ruleset endpoints
+// used for the custom rules of one zone.
+type fakeCustomRules struct {
+	t        *testing.T
+	exists   bool
+	rules    []map[string]any
+	nextID   int
+	requests []string
+}
+
+func (f *fakeCustomRules) respond(w http.ResponseWriter) {
+	writeJSON(f.t, w, http.StatusOK, mockResultResponse(map[string]any{
+		"id":    mockCustomRuleRulesetID,
+		"phase": "http_request_firewall_custom",
+		"rules": f.rules,
+	}))
+}
+
+func (f *fakeCustomRules) decodeRule(r *http.Request) map[string]any {
+	var rule map[string]any
+	assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&rule))
+	return rule
+}
+
+func (f *fakeCustomRules) newRule(rule map[string]any) map[string]any {
+	f.nextID++
+	rule["id"] = fmt.Sprintf("rule%d", f.nextID)
+	delete(rule, "position")
+	return rule
+}
+
+func (f *fakeCustomRules) find(id string) int {
+	return slices.IndexFunc(f.rules, func(rule map[string]any) bool { return rule["id"] == id })
+}
+
+func (f *fakeCustomRules) register(serveMux *http.ServeMux) {
+	entrypoint := fmt.Sprintf("/zones/%s/rulesets/phases/http_request_firewall_custom/entrypoint", mockCustomRuleZoneID)
+	rules := fmt.Sprintf("/zones/%s/rulesets/%s/rules", mockCustomRuleZoneID, mockCustomRuleRulesetID)
+
+	serveMux.HandleFunc(entrypoint, func(w http.ResponseWriter, r *http.Request) {
+		f.requests = append(f.requests, r.Method+" entrypoint")
+		if !checkToken(f.t, r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if !f.exists {
+				writeJSON(f.t, w, http.StatusNotFound, cloudflare.Response{
+					Success:  false,
+					Errors:   []cloudflare.ResponseInfo{{Code: 10003, Message: "Could not find entrypoint ruleset in the http_request_firewall_custom phase"}}, //nolint:exhaustruct
+					Messages: []cloudflare.ResponseInfo{},
+				})
+				return
+			}
+		case http.MethodPut:
+			var body struct {
+				Rules []map[string]any `json:"rules"`
+			}
+			assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+			f.exists = true
+			f.rules = nil
+			for _, rule := range body.Rules {
+				f.rules = append(f.rules, f.newRule(rule))
+			}
+		default:
+			f.t.Errorf("unexpected method %s", r.Method)
+		}
+		f.respond(w)
+	})
+
+	serveMux.HandleFunc(rules, func(w http.ResponseWriter, r *http.Request) {
+		f.requests = append(f.requests, r.Method+" rules")
+		if !assert.Equal(f.t, http.MethodPost, r.Method) || !checkToken(f.t, r) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rule := f.decodeRule(r)
+		assert.Equal(f.t, map[string]any{"index": float64(1)}, rule["position"])
+		f.rules = slices.Insert(f.rules, 0, f.newRule(rule))
+		f.respond(w)
+	})
+
+	serveMux.HandleFunc(rules+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.requests = append(f.requests, r.Method+" "+r.PathValue("id"))
+		i := f.find(r.PathValue("id"))
+		if !checkToken(f.t, r) || !assert.NotEqual(f.t, -1, i) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			rule := f.decodeRule(r)
+			rule["id"] = r.PathValue("id")
+			f.rules[i] = rule
+		case http.MethodDelete:
+			f.rules = slices.Delete(f.rules, i, i+1)
+		default:
+			f.t.Errorf("unexpected method %s", r.Method)
+		}
+		f.respond(w)
+	})
+}
+
+func TestWAFCustomRules(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	fake := &fakeCustomRules{t: t, exists: false, rules: nil, nextID: 0, requests: nil}
+	fake.register(f.serveMux)
+	ctx := context.Background()
+	mockPP := f.newPP()
+
+	rulesetID, rules, ok := f.handle.ListWAFCustomRules(ctx, mockPP, mockCustomRuleZoneID)
+	require.True(t, ok)
+	require.Empty(t, rulesetID)
+	require.Empty(t, rules)
+
+	rule := api.WAFCustomRule{ID: "", Action: "skip", Expression: "ip.src in $home", Description: "ddns", Enabled: true}
+	id, ok := f.handle.CreateWAFCustomRule(ctx, mockPP, mockCustomRuleZoneID, "", rule)
+	require.True(t, ok)
+	require.Equal(t, api.ID("rule1"), id)
+	require.Equal(t, map[string]any{"ruleset": "current"}, fake.rules[0]["action_parameters"])
+
+	other := api.WAFCustomRule{ID: "", Action: "block", Expression: "ip.src in $bad", Description: "other", Enabled: false}
+	id, ok = f.handle.CreateWAFCustomRule(ctx, mockPP, mockCustomRuleZoneID, mockCustomRuleRulesetID, other)
+	require.True(t, ok)
+	require.Equal(t, api.ID("rule2"), id)
+	require.NotContains(t, fake.rules[0], "action_parameters")
+
+	rulesetID, rules, ok = f.handle.ListWAFCustomRules(ctx, mockPP, mockCustomRuleZoneID)
+	require.True(t, ok)
+	require.Equal(t, api.ID(mockCustomRuleRulesetID), rulesetID)
+	other.ID = "rule2"
+	rule.ID = "rule1"
+	require.Equal(t, []api.WAFCustomRule{other, rule}, rules)
+
+	rule.Action = "managed_challenge"
+	require.True(t, f.handle.UpdateWAFCustomRule(ctx, mockPP, mockCustomRuleZoneID, mockCustomRuleRulesetID, rule))
+	require.True(t, f.handle.DeleteWAFCustomRule(ctx, mockPP, mockCustomRuleZoneID, mockCustomRuleRulesetID, "rule2"))
+
+	_, rules, ok = f.handle.ListWAFCustomRules(ctx, mockPP, mockCustomRuleZoneID)
+	require.True(t, ok)
+	require.Equal(t, []api.WAFCustomRule{rule}, rules)
+	require.NotContains(t, fake.rules[0], "action_parameters")
+
+	require.Equal(t, []string{
+		"GET entrypoint", "PUT entrypoint", "POST rules", "GET entrypoint",
+		"PATCH rule1", "DELETE rule2", "GET entrypoint",
+	}, fake.requests)
+}
+
+func TestWAFCustomRulesPermission(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		call    func(context.Context, pp.PP, api.Handle) bool
+		message string
+		args    []any
+	}{
+		"list": {
+			func(ctx context.Context, ppfmt pp.PP, h api.Handle) bool {
+				_, _, ok := h.ListWAFCustomRules(ctx, ppfmt, mockCustomRuleZoneID)
+				return ok
+			},
+			"Failed to read the custom rules of the zone %s: %v",
+			[]any{mockCustomRuleZoneID, gomock.Any()},
+		},
+		"create": {
+			func(ctx context.Context, ppfmt pp.PP, h api.Handle) bool {
+				_, ok := h.CreateWAFCustomRule(ctx, ppfmt, mockCustomRuleZoneID, "", api.WAFCustomRule{}) //nolint:exhaustruct
+				return ok
+			},
+			"Failed to create a custom rule in the zone %s: %v",
+			[]any{mockCustomRuleZoneID, gomock.Any()},
+		},
+		"update": {
+			func(ctx context.Context, ppfmt pp.PP, h api.Handle) bool {
+				return h.UpdateWAFCustomRule(ctx, ppfmt, mockCustomRuleZoneID, mockCustomRuleRulesetID,
+					api.WAFCustomRule{ID: "rule1"}) //nolint:exhaustruct
+			},
+			"Failed to update the custom rule %s in the zone %s: %v",
+			[]any{api.ID("rule1"), mockCustomRuleZoneID, gomock.Any()},
+		},
+		"delete": {
+			func(ctx context.Context, ppfmt pp.PP, h api.Handle) bool {
+				return h.DeleteWAFCustomRule(ctx, ppfmt, mockCustomRuleZoneID, mockCustomRuleRulesetID, "rule1")
+			},
+			"Failed to delete the custom rule %s in the zone %s: %v",
+			[]any{api.ID("rule1"), mockCustomRuleZoneID, gomock.Any()},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			requestLimit := 1
+			f.serveMux.HandleFunc(fmt.Sprintf("/zones/%s/rulesets/", mockCustomRuleZoneID),
+				func(w http.ResponseWriter, r *http.Request) {
+					if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					writeJSON(t, w, http.StatusForbidden, cloudflare.Response{
+						Success:  false,
+						Errors:   []cloudflare.ResponseInfo{{Code: 10000, Message: "Authentication error"}}, //nolint:exhaustruct
+						Messages: []cloudflare.ResponseInfo{},
+					})
+				})
+
+			mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().Noticef(pp.EmojiError, tc.message, tc.args...),
+					m.EXPECT().NoticeOncef(pp.MessagePermission(pp.FeatureWAFListRule), pp.EmojiHint, `Double-check your %s. Make sure you granted the "Edit" permission of "%s"`, "API token and zone ID", "Zone - Zone WAF"),
+				)
+			})
+			require.False(t, tc.call(context.Background(), mockPP, f.handle))
+			require.Zero(t, requestLimit)
+		})
+	}
+}
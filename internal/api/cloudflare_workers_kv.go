@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func hintWorkersKVPermission(ppfmt pp.PP, err error) {
+	var authentication *cloudflare.AuthenticationError
+	var authorization *cloudflare.AuthorizationError
+	if errors.As(err, &authentication) || errors.As(err, &authorization) {
+		pp.HintEditPermission(ppfmt, pp.FeatureWorkersKV, "Account - Workers KV Storage", "account ID", "namespace ID")
+	}
+}
+
+// GetWorkersKVValue calls the API to read the value of a key.
+func (h cloudflareHandle) GetWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey) ([]byte, bool) {
+	value, err := h.cf.GetWorkersKV(ctx, cloudflare.AccountIdentifier(string(key.AccountID)),
+		cloudflare.GetWorkersKVParams{NamespaceID: string(key.NamespaceID), Key: key.Key})
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, true
+		}
+		ppfmt.Noticef(pp.EmojiError, "Failed to read the Workers KV key %s: %v", key.Describe(), err)
+		hintWorkersKVPermission(ppfmt, err)
+		return nil, false
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, true
+}
+
+// PutWorkersKVValue calls the API to write the value of a key.
+func (h cloudflareHandle) PutWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey, value []byte,
+) bool {
+	if _, err := h.cf.WriteWorkersKVEntry(ctx, cloudflare.AccountIdentifier(string(key.AccountID)),
+		cloudflare.WriteWorkersKVEntryParams{NamespaceID: string(key.NamespaceID), Key: key.Key, Value: value},
+	); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to write the Workers KV key %s: %v", key.Describe(), err)
+		hintWorkersKVPermission(ppfmt, err)
+		return false
+	}
+	return true
+}
+
+// DeleteWorkersKVValue calls the API to delete a key.
+func (h cloudflareHandle) DeleteWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey) bool {
+	if _, err := h.cf.DeleteWorkersKVEntry(ctx, cloudflare.AccountIdentifier(string(key.AccountID)),
+		cloudflare.DeleteWorkersKVEntryParams{NamespaceID: string(key.NamespaceID), Key: key.Key},
+	); err != nil {
+		var notFound *cloudflare.NotFoundError
+		if errors.As(err, &notFound) {
+			return true
+		}
+		ppfmt.Noticef(pp.EmojiError, "Failed to delete the Workers KV key %s: %v", key.Describe(), err)
+		hintWorkersKVPermission(ppfmt, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func mockWorkersKVKey() api.WorkersKVKey {
+	return api.WorkersKVKey{AccountID: mockAccountID, NamespaceID: "namespace1", Key: "home/ip"}
+}
+
+// handleWorkersKV serves the value endpoint of the namespace "namespace1" backed by values.
+func handleWorkersKV(t *testing.T, serveMux *http.ServeMux, values map[string][]byte) httpHandler {
+	t.Helper()
+
+	requestLimit := new(int)
+	serveMux.HandleFunc("/accounts/"+string(mockAccountID)+"/storage/kv/namespaces/namespace1/values/{key}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !assert.True(t, checkRequestLimit(t, requestLimit)) || !checkToken(t, r) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			key := r.PathValue("key")
+			switch r.Method {
+			case http.MethodGet:
+				value, found := values[key]
+				if !found {
+					writeJSON(t, w, http.StatusNotFound, cloudflare.Response{
+						Success:  false,
+						Errors:   []cloudflare.ResponseInfo{{Code: 10009, Message: "get: 'key not found'"}}, //nolint:exhaustruct
+						Messages: []cloudflare.ResponseInfo{},
+					})
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(value)
+				assert.NoError(t, err)
+			case http.MethodPut:
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				values[key] = body
+				writeJSON(t, w, http.StatusOK, mockResultResponse(nil))
+			case http.MethodDelete:
+				delete(values, key)
+				writeJSON(t, w, http.StatusOK, mockResultResponse(nil))
+			default:
+				t.Errorf("unexpected method %s", r.Method)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		})
+
+	return httpHandler{requestLimit: requestLimit}
+}
+
+func TestWorkersKV(t *testing.T) {
+	t.Parallel()
+
+	f := newCloudflareHarness(t)
+	values := map[string][]byte{}
+	handler := handleWorkersKV(t, f.serveMux, values)
+	handler.setRequestLimit(5)
+	ctx := context.Background()
+	key := mockWorkersKVKey()
+
+	value, ok := f.handle.GetWorkersKVValue(ctx, f.newPP(), key)
+	require.True(t, ok)
+	require.Nil(t, value)
+
+	require.True(t, f.handle.PutWorkersKVValue(ctx, f.newPP(), key, []byte(`{"ipv4":["198.51.100.8"]}`)))
+	require.Equal(t, map[string][]byte{"home/ip": []byte(`{"ipv4":["198.51.100.8"]}`)}, values)
+
+	value, ok = f.handle.GetWorkersKVValue(ctx, f.newPP(), key)
+	require.True(t, ok)
+	require.Equal(t, []byte(`{"ipv4":["198.51.100.8"]}`), value)
+
+	require.True(t, f.handle.DeleteWorkersKVValue(ctx, f.newPP(), key))
+	require.Empty(t, values)
+
+	value, ok = f.handle.GetWorkersKVValue(ctx, f.newPP(), key)
+	require.True(t, ok)
+	require.Nil(t, value)
+
+	assertHandlersExhausted(t, handler)
+}
+
+func TestWorkersKVPermission(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		call    func(context.Context, api.Handle, pp.PP) bool
+		message string
+	}{
+		"get": {
+			func(ctx context.Context, h api.Handle, ppfmt pp.PP) bool {
+				_, ok := h.GetWorkersKVValue(ctx, ppfmt, mockWorkersKVKey())
+				return ok
+			},
+			"Failed to read the Workers KV key %s: %v",
+		},
+		"put": {
+			func(ctx context.Context, h api.Handle, ppfmt pp.PP) bool {
+				return h.PutWorkersKVValue(ctx, ppfmt, mockWorkersKVKey(), []byte("{}"))
+			},
+			"Failed to write the Workers KV key %s: %v",
+		},
+		"delete": {
+			func(ctx context.Context, h api.Handle, ppfmt pp.PP) bool {
+				return h.DeleteWorkersKVValue(ctx, ppfmt, mockWorkersKVKey())
+			},
+			"Failed to delete the Workers KV key %s: %v",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newCloudflareHarness(t)
+			requestLimit := 1
+			f.serveMux.HandleFunc("/accounts/"+string(mockAccountID)+"/storage/kv/namespaces/namespace1/values/{key}",
+				func(w http.ResponseWriter, r *http.Request) {
+					if !assert.True(t, checkRequestLimit(t, &requestLimit)) || !checkToken(t, r) {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					writeJSON(t, w, http.StatusForbidden, cloudflare.Response{
+						Success:  false,
+						Errors:   []cloudflare.ResponseInfo{{Code: 10000, Message: "Authentication error"}}, //nolint:exhaustruct
+						Messages: []cloudflare.ResponseInfo{},
+					})
+				})
+
+			mockPP := f.newPreparedPP(func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().Noticef(pp.EmojiError, tc.message, mockWorkersKVKey().Describe(), gomock.Any()),
+					m.EXPECT().NoticeOncef(pp.MessagePermission(pp.FeatureWorkersKV), pp.EmojiHint, `Double-check your %s. Make sure you granted the "Edit" permission of "%s"`, "API token, account ID, and namespace ID", "Account - Workers KV Storage"),
+				)
+			})
+			require.False(t, tc.call(context.Background(), f.handle, mockPP))
+			require.Zero(t, requestLimit)
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// PlannedChange is one write that a [DryRunHandle] recorded instead of performing.
+type PlannedChange struct {
+	// Subject is the description of the domain, the WAF list, or another resource to change.
+	Subject string
+	// Action describes the change, such as "delete the A record 123".
+	Action string
+}
+
+// A Planner records writes as [PlannedChange] values instead of performing them.
+type Planner interface {
+	// TakePlan returns the changes recorded since the last call and forgets them.
+	TakePlan() []PlannedChange
+}
+
+// DryRunRecordHandle wraps another [RecordHandle] so that reads go through but
+// writes are only recorded as [PlannedChange] values and reported as successful.
+type DryRunRecordHandle struct {
+	inner RecordHandle
+
+	mutex *sync.Mutex
+	plan  *[]PlannedChange
+}
+
+var (
+	_ RecordHandle = DryRunRecordHandle{} //nolint:exhaustruct
+	_ Planner      = DryRunRecordHandle{} //nolint:exhaustruct
+)
+
+// NewDryRunRecordHandle wraps a record handle so that it never changes remote state.
+func NewDryRunRecordHandle(inner RecordHandle) DryRunRecordHandle {
+	return DryRunRecordHandle{
+		inner: inner,
+		mutex: &sync.Mutex{},
+		plan:  new([]PlannedChange),
+	}
+}
+
+func (h DryRunRecordHandle) record(subject, format string, args ...any) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	*h.plan = append(*h.plan, PlannedChange{Subject: subject, Action: fmt.Sprintf(format, args...)})
+}
+
+// TakePlan returns the changes recorded since the last call and forgets them.
+func (h DryRunRecordHandle) TakePlan() []PlannedChange {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	plan := *h.plan
+	*h.plan = nil
+	return plan
+}
+
+// ListRecords calls the wrapped handle.
+func (h DryRunRecordHandle) ListRecords(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family,
+	domain domain.Domain, fallbackParams RecordParams,
+) ([]Record, bool, bool) {
+	return h.inner.ListRecords(ctx, ppfmt, ipFamily, domain, fallbackParams)
+}
+
+// UpdateRecord records the update without performing it.
+func (h DryRunRecordHandle) UpdateRecord(_ context.Context, _ pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	id ID, ip netip.Addr, _ RecordParams,
+) bool {
+	h.record(domain.Describe(), "update the %s record %s to %s", ipFamily.RecordType(), id, ip)
+	return true
+}
+
+// CreateRecord records the creation without performing it. The returned ID is empty.
+func (h DryRunRecordHandle) CreateRecord(_ context.Context, _ pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	ip netip.Addr, _ RecordParams,
+) (ID, bool) {
+	h.record(domain.Describe(), "add an %s record for %s", ipFamily.RecordType(), ip)
+	return "", true
+}
+
+// DeleteRecord records the deletion without performing it.
+func (h DryRunRecordHandle) DeleteRecord(_ context.Context, _ pp.PP, ipFamily ipnet.Family,
+	domain domain.Domain, id ID, _ DeletionMode,
+) bool {
+	h.record(domain.Describe(), "delete the %s record %s", ipFamily.RecordType(), id)
+	return true
+}
+
+// DryRunHandle wraps another [Handle] so that reads go through but writes are
+// only recorded as [PlannedChange] values and reported as successful.
+//
+// Remote state (and thus the cache of the wrapped handle) is never changed,
+// so every round plans its changes against the same remote state.
+//
+// The wrapped handle is deliberately not embedded: every method is spelled out
+// below, so that a new write method cannot reach the live API by accident.
+type DryRunHandle struct {
+	inner   Handle
+	records DryRunRecordHandle
+
+	mutex *sync.Mutex
+	// items remembers the prefixes of the WAF list items seen by ListWAFListItems,
+	// so that planned deletions can show prefixes instead of item IDs.
+	items map[WAFList]map[ID]netip.Prefix
+}
+
+var (
+	_ Handle  = DryRunHandle{} //nolint:exhaustruct
+	_ Planner = DryRunHandle{} //nolint:exhaustruct
+)
+
+// NewDryRunHandle wraps a handle so that it never changes remote state.
+func NewDryRunHandle(inner Handle) DryRunHandle {
+	records := NewDryRunRecordHandle(inner)
+	return DryRunHandle{
+		inner:   inner,
+		records: records,
+		mutex:   &sync.Mutex{},
+		items:   map[WAFList]map[ID]netip.Prefix{},
+	}
+}
+
+func (h DryRunHandle) record(subject, format string, args ...any) {
+	h.records.record(subject, format, args...)
+}
+
+// TakePlan returns the changes recorded since the last call and forgets them.
+func (h DryRunHandle) TakePlan() []PlannedChange {
+	return h.records.TakePlan()
+}
+
+// ListRecords calls the wrapped handle.
+func (h DryRunHandle) ListRecords(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	fallbackParams RecordParams,
+) ([]Record, bool, bool) {
+	return h.records.ListRecords(ctx, ppfmt, ipFamily, domain, fallbackParams)
+}
+
+// UpdateRecord records the update without performing it.
+func (h DryRunHandle) UpdateRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	id ID, ip netip.Addr, desiredParams RecordParams,
+) bool {
+	return h.records.UpdateRecord(ctx, ppfmt, ipFamily, domain, id, ip, desiredParams)
+}
+
+// CreateRecord records the creation without performing it. The returned ID is empty.
+func (h DryRunHandle) CreateRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	ip netip.Addr, desiredParams RecordParams,
+) (ID, bool) {
+	return h.records.CreateRecord(ctx, ppfmt, ipFamily, domain, ip, desiredParams)
+}
+
+// DeleteRecord records the deletion without performing it.
+func (h DryRunHandle) DeleteRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family,
+	domain domain.Domain, id ID, mode DeletionMode,
+) bool {
+	return h.records.DeleteRecord(ctx, ppfmt, ipFamily, domain, id, mode)
+}
+
+// ListWAFListItems calls the wrapped handle and remembers the prefixes of the items.
+func (h DryRunHandle) ListWAFListItems(ctx context.Context, ppfmt pp.PP, list WAFList,
+	fallbackDescription, fallbackItemComment string,
+) ([]WAFListItem, bool, bool, bool) {
+	items, exists, cached, ok := h.inner.ListWAFListItems(ctx, ppfmt, list, fallbackDescription, fallbackItemComment)
+	if ok {
+		prefixes := make(map[ID]netip.Prefix, len(items))
+		for _, item := range items {
+			prefixes[item.ID] = item.Prefix
+		}
+		h.mutex.Lock()
+		h.items[list] = prefixes
+		h.mutex.Unlock()
+	}
+	return items, exists, cached, ok
+}
+
+// FinalCleanWAFList records the deletion of the managed items in scope without
+// performing it. The plan lists the items even when the wrapped handle would
+// have deleted the whole list instead.
+func (h DryRunHandle) FinalCleanWAFList(ctx context.Context, ppfmt pp.PP, list WAFList,
+	fallbackDescription string, managedFamilies map[ipnet.Family]bool,
+) WAFListCleanupCode {
+	items, exists, _, ok := h.ListWAFListItems(ctx, ppfmt, list, fallbackDescription, "")
+	if !ok {
+		return WAFListCleanupFailed
+	}
+
+	planned := false
+	if exists {
+		for _, item := range items {
+			for ipFamily := range ipnet.All {
+				if managedFamilies[ipFamily] && ipFamily.Matches(item.Prefix.Addr()) {
+					h.record(list.Describe(), "delete %s", item.Prefix.Masked())
+					planned = true
+					break
+				}
+			}
+		}
+	}
+
+	if !planned {
+		ppfmt.Infof(pp.EmojiAlreadyDone, finalWAFListManagedItemsAlreadyDeletedMessage, list.Describe())
+		return WAFListCleanupNoop
+	}
+	ppfmt.Noticef(pp.EmojiClear, "Would delete managed items in the list %s", list.Describe())
+	return WAFListCleanupUpdated
+}
+
+// DeleteWAFListItems records the deletions without performing them.
+func (h DryRunHandle) DeleteWAFListItems(_ context.Context, _ pp.PP, list WAFList, _ string, ids []ID) bool {
+	h.mutex.Lock()
+	prefixes := h.items[list]
+	h.mutex.Unlock()
+
+	for _, id := range ids {
+		if prefix, ok := prefixes[id]; ok {
+			h.record(list.Describe(), "delete %s", prefix.Masked())
+		} else {
+			h.record(list.Describe(), "delete the item %s", id)
+		}
+	}
+	return true
+}
+
+// CreateWAFListItems records the creations without performing them.
+func (h DryRunHandle) CreateWAFListItems(_ context.Context, _ pp.PP, list WAFList, _ string,
+	items []WAFListCreateItem,
+) bool {
+	for _, item := range items {
+		h.record(list.Describe(), "add %s", item.Prefix.Masked())
+	}
+	return true
+}
+
+// GetLBPoolOrigin calls the wrapped handle.
+func (h DryRunHandle) GetLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin LBPoolOrigin,
+) (LBPoolOriginState, bool) {
+	return h.inner.GetLBPoolOrigin(ctx, ppfmt, origin)
+}
+
+// UpdateLBPoolOrigin records the update without performing it.
+func (h DryRunHandle) UpdateLBPoolOrigin(_ context.Context, _ pp.PP, origin LBPoolOrigin,
+	desired LBPoolOriginState,
+) bool {
+	if desired.Enabled {
+		h.record(origin.Describe(), "set the address to %s and enable the origin", desired.Address)
+	} else {
+		h.record(origin.Describe(), "disable the origin")
+	}
+	return true
+}
+
+// ListGatewayLocationNetworks calls the wrapped handle.
+func (h DryRunHandle) ListGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location GatewayLocation,
+) ([]netip.Prefix, bool) {
+	return h.inner.ListGatewayLocationNetworks(ctx, ppfmt, location)
+}
+
+// SetGatewayLocationNetworks records the update without performing it.
+func (h DryRunHandle) SetGatewayLocationNetworks(_ context.Context, _ pp.PP, location GatewayLocation,
+	networks []netip.Prefix,
+) bool {
+	h.record(location.Describe(), "set the networks to %s",
+		pp.EnglishJoinMapOrEmptyLabel(netip.Prefix.String, networks, "(none)"))
+	return true
+}
+
+// ListAccessGroupIPRules calls the wrapped handle.
+func (h DryRunHandle) ListAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group AccessGroup,
+) ([]netip.Prefix, bool) {
+	return h.inner.ListAccessGroupIPRules(ctx, ppfmt, group)
+}
+
+// SetAccessGroupIPRules records the update without performing it.
+func (h DryRunHandle) SetAccessGroupIPRules(_ context.Context, _ pp.PP, group AccessGroup,
+	prefixes []netip.Prefix,
+) bool {
+	h.record(group.Describe(), "set the IP rules to %s",
+		pp.EnglishJoinMapOrEmptyLabel(netip.Prefix.String, prefixes, "(none)"))
+	return true
+}
+
+// ListIPAccessRules calls the wrapped handle.
+func (h DryRunHandle) ListIPAccessRules(ctx context.Context, ppfmt pp.PP, set IPAccessRuleSet,
+) ([]IPAccessRule, bool) {
+	return h.inner.ListIPAccessRules(ctx, ppfmt, set)
+}
+
+// CreateIPAccessRule records the creation without performing it.
+func (h DryRunHandle) CreateIPAccessRule(_ context.Context, _ pp.PP, set IPAccessRuleSet,
+	prefix netip.Prefix, _ string,
+) (ID, bool) {
+	h.record(set.Describe(), "add a rule for %s", prefix.Masked().String())
+	return "", true
+}
+
+// DeleteIPAccessRule records the deletion without performing it.
+func (h DryRunHandle) DeleteIPAccessRule(_ context.Context, _ pp.PP, set IPAccessRuleSet, id ID) bool {
+	h.record(set.Describe(), "delete the rule %s", id)
+	return true
+}
+
+// GetSpectrumAppOrigins calls the wrapped handle.
+func (h DryRunHandle) GetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app SpectrumApp,
+) ([]SpectrumOrigin, bool) {
+	return h.inner.GetSpectrumAppOrigins(ctx, ppfmt, app)
+}
+
+// SetSpectrumAppOrigins records the update without performing it.
+func (h DryRunHandle) SetSpectrumAppOrigins(_ context.Context, _ pp.PP, app SpectrumApp,
+	origins []SpectrumOrigin,
+) bool {
+	h.record(app.Describe(), "set the origins to %s",
+		pp.EnglishJoinMapOrEmptyLabel(SpectrumOrigin.String, origins, "(none)"))
+	return true
+}
+
+// ListWAFCustomRules calls the wrapped handle.
+func (h DryRunHandle) ListWAFCustomRules(ctx context.Context, ppfmt pp.PP, zoneID ID) (ID, []WAFCustomRule, bool) {
+	return h.inner.ListWAFCustomRules(ctx, ppfmt, zoneID)
+}
+
+// CreateWAFCustomRule records the creation without performing it. The returned ID is empty.
+func (h DryRunHandle) CreateWAFCustomRule(_ context.Context, _ pp.PP, zoneID ID, _ ID, rule WAFCustomRule,
+) (ID, bool) {
+	h.record(string(zoneID), "add a custom rule to %s if %s", rule.Action, rule.Expression)
+	return "", true
+}
+
+// UpdateWAFCustomRule records the update without performing it.
+func (h DryRunHandle) UpdateWAFCustomRule(_ context.Context, _ pp.PP, zoneID ID, _ ID, rule WAFCustomRule) bool {
+	h.record(string(zoneID), "update the custom rule %s to %s if %s", rule.ID, rule.Action, rule.Expression)
+	return true
+}
+
+// DeleteWAFCustomRule records the deletion without performing it.
+func (h DryRunHandle) DeleteWAFCustomRule(_ context.Context, _ pp.PP, zoneID ID, _ ID, ruleID ID) bool {
+	h.record(string(zoneID), "delete the custom rule %s", ruleID)
+	return true
+}
+
+// GetWorkersKVValue calls the wrapped handle.
+func (h DryRunHandle) GetWorkersKVValue(ctx context.Context, ppfmt pp.PP, key WorkersKVKey) ([]byte, bool) {
+	return h.inner.GetWorkersKVValue(ctx, ppfmt, key)
+}
+
+// PutWorkersKVValue records the write without performing it.
+func (h DryRunHandle) PutWorkersKVValue(_ context.Context, _ pp.PP, key WorkersKVKey, value []byte) bool {
+	h.record(key.Describe(), "write %s", string(value))
+	return true
+}
+
+// DeleteWorkersKVValue records the deletion without performing it.
+func (h DryRunHandle) DeleteWorkersKVValue(_ context.Context, _ pp.PP, key WorkersKVKey) bool {
+	h.record(key.Describe(), "delete the key")
+	return true
+}
+
+// CheckPermissions calls the wrapped handle, which never changes remote state.
+func (h DryRunHandle) CheckPermissions(ctx context.Context, ppfmt pp.PP, domains []domain.Domain, lists []WAFList,
+) PermissionReport {
+	return h.inner.CheckPermissions(ctx, ppfmt, domains, lists)
+}
@@ -0,0 +1,173 @@
+package api_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func TestDryRunHandleRecords(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	inner := mocks.NewMockHandle(mockCtrl)
+	ctx := context.Background()
+	d := domain.FQDN("sub.test.org")
+	ip := netip.MustParseAddr("1.2.3.4")
+
+	// Writes never reach the wrapped handle.
+	h := api.NewDryRunHandle(inner)
+	require.True(t, h.UpdateRecord(ctx, mockPP, ipnet.IP4, d, "record1", ip, api.RecordParams{})) //nolint:exhaustruct
+	id, ok := h.CreateRecord(ctx, mockPP, ipnet.IP4, d, ip, api.RecordParams{})                   //nolint:exhaustruct
+	require.True(t, ok)
+	require.Empty(t, id)
+	require.True(t, h.DeleteRecord(ctx, mockPP, ipnet.IP4, d, "record2", api.RegularDeletionMode))
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	require.True(t, h.UpdateLBPoolOrigin(ctx, mockPP, origin, api.LBPoolOriginState{Address: ip, Enabled: true}))
+	require.True(t, h.UpdateLBPoolOrigin(ctx, mockPP, origin, api.LBPoolOriginState{Address: ip, Enabled: false}))
+	location := api.GatewayLocation{AccountID: "account", Name: "office"}
+	require.True(t, h.SetGatewayLocationNetworks(ctx, mockPP, location, []netip.Prefix{netip.PrefixFrom(ip, 32)}))
+	require.True(t, h.SetGatewayLocationNetworks(ctx, mockPP, location, nil))
+	group := api.AccessGroup{AccountID: "account", Name: "office"}
+	require.True(t, h.SetAccessGroupIPRules(ctx, mockPP, group, []netip.Prefix{netip.PrefixFrom(ip, 32)}))
+	set := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone", Mode: api.IPAccessRuleModeBlock}
+	id, ok = h.CreateIPAccessRule(ctx, mockPP, set, netip.PrefixFrom(ip, 32), "notes")
+	require.True(t, ok)
+	require.Empty(t, id)
+	require.True(t, h.DeleteIPAccessRule(ctx, mockPP, set, "rule1"))
+	app := api.SpectrumApp{ZoneID: "zone", AppID: "app"}
+	require.True(t, h.SetSpectrumAppOrigins(ctx, mockPP, app, []api.SpectrumOrigin{{Scheme: "tcp", Address: ip, Port: "22"}}))
+	rule := api.WAFCustomRule{ID: "rule1", Action: "skip", Expression: "ip.src in $list", Description: "ddns", Enabled: true}
+	id, ok = h.CreateWAFCustomRule(ctx, mockPP, "zone", "ruleset", rule)
+	require.True(t, ok)
+	require.Empty(t, id)
+	require.True(t, h.UpdateWAFCustomRule(ctx, mockPP, "zone", "ruleset", rule))
+	require.True(t, h.DeleteWAFCustomRule(ctx, mockPP, "zone", "ruleset", "rule1"))
+	kvKey := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+	require.True(t, h.PutWorkersKVValue(ctx, mockPP, kvKey, []byte(`{"ipv4":["1.2.3.4"]}`)))
+	require.True(t, h.DeleteWorkersKVValue(ctx, mockPP, kvKey))
+
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "sub.test.org", Action: "update the A record record1 to 1.2.3.4"},
+		{Subject: "sub.test.org", Action: "add an A record for 1.2.3.4"},
+		{Subject: "sub.test.org", Action: "delete the A record record2"},
+		{Subject: "account/pool:home", Action: "set the address to 1.2.3.4 and enable the origin"},
+		{Subject: "account/pool:home", Action: "disable the origin"},
+		{Subject: "account/office", Action: "set the networks to 1.2.3.4/32"},
+		{Subject: "account/office", Action: "set the networks to (none)"},
+		{Subject: "account/office", Action: "set the IP rules to 1.2.3.4/32"},
+		{Subject: "zone/zone:block", Action: "add a rule for 1.2.3.4/32"},
+		{Subject: "zone/zone:block", Action: "delete the rule rule1"},
+		{Subject: "zone/app", Action: "set the origins to tcp://1.2.3.4:22"},
+		{Subject: "zone", Action: "add a custom rule to skip if ip.src in $list"},
+		{Subject: "zone", Action: "update the custom rule rule1 to skip if ip.src in $list"},
+		{Subject: "zone", Action: "delete the custom rule rule1"},
+		{Subject: "account/namespace:home", Action: `write {"ipv4":["1.2.3.4"]}`},
+		{Subject: "account/namespace:home", Action: "delete the key"},
+	}, h.TakePlan())
+	require.Empty(t, h.TakePlan())
+}
+
+func TestDryRunHandleReads(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	inner := mocks.NewMockHandle(mockCtrl)
+	ctx := context.Background()
+	ip := netip.MustParseAddr("1.2.3.4")
+
+	// Reads go through to the wrapped handle.
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	kvKey := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+	gomock.InOrder(
+		inner.EXPECT().GetLBPoolOrigin(ctx, mockPP, origin).Return(api.LBPoolOriginState{Address: ip, Enabled: true}, true),
+		inner.EXPECT().GetWorkersKVValue(ctx, mockPP, kvKey).Return([]byte("value"), true),
+		inner.EXPECT().CheckPermissions(ctx, mockPP, nil, nil).Return(api.PermissionReport{Verified: true}), //nolint:exhaustruct
+	)
+
+	h := api.NewDryRunHandle(inner)
+	state, ok := h.GetLBPoolOrigin(ctx, mockPP, origin)
+	require.True(t, ok)
+	require.Equal(t, api.LBPoolOriginState{Address: ip, Enabled: true}, state)
+	value, ok := h.GetWorkersKVValue(ctx, mockPP, kvKey)
+	require.True(t, ok)
+	require.Equal(t, []byte("value"), value)
+	require.True(t, h.CheckPermissions(ctx, mockPP, nil, nil).Verified)
+	require.Empty(t, h.TakePlan())
+}
+
+func TestDryRunHandleFinalCleanWAFList(t *testing.T) {
+	t.Parallel()
+
+	list := api.WAFList{AccountID: "account", Name: "list"}
+	items := []api.WAFListItem{
+		{ID: "item4", Prefix: netip.MustParsePrefix("10.0.0.1/32"), Comment: ""},
+		{ID: "item6", Prefix: netip.MustParsePrefix("2001:db8::/64"), Comment: ""},
+	}
+
+	for name, tc := range map[string]struct {
+		items           []api.WAFListItem
+		exists          bool
+		ok              bool
+		managedFamilies map[ipnet.Family]bool
+		code            api.WAFListCleanupCode
+		plan            []api.PlannedChange
+		prepareMockPP   func(*mocks.MockPP)
+	}{
+		"ip4-only": {
+			items, true, true,
+			map[ipnet.Family]bool{ipnet.IP4: true},
+			api.WAFListCleanupUpdated,
+			[]api.PlannedChange{{Subject: "account/list", Action: "delete 10.0.0.1/32"}},
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiClear, "Would delete managed items in the list %s", "account/list")
+			},
+		},
+		"missing": {
+			nil, false, true,
+			map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true},
+			api.WAFListCleanupNoop,
+			nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiAlreadyDone, "Managed items in the list %s were already deleted", "account/list")
+			},
+		},
+		"failed": {
+			nil, false, false,
+			map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true},
+			api.WAFListCleanupFailed,
+			nil,
+			nil,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			inner := mocks.NewMockHandle(mockCtrl)
+			ctx := context.Background()
+
+			inner.EXPECT().ListWAFListItems(ctx, mockPP, list, "description", "").
+				Return(tc.items, tc.exists, false, tc.ok)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			h := api.NewDryRunHandle(inner)
+			require.Equal(t, tc.code, h.FinalCleanWAFList(ctx, mockPP, list, "description", tc.managedFamilies))
+			require.Equal(t, tc.plan, h.TakePlan())
+		})
+	}
+}
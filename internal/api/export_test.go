@@ -73,3 +73,7 @@ func (h cloudflareHandle) ListZones(ctx context.Context, ppfmt pp.PP, name strin
 func (h cloudflareHandle) ZoneIDOfDomain(ctx context.Context, ppfmt pp.PP, domain domain.Domain) (ID, bool) {
 	return h.zoneIDOfDomain(ctx, ppfmt, domain)
 }
+
+// ErrCircuitOpen is the test-only name of the error of the calls skipped by an
+// open circuit breaker, so that api_test can check how they are reported.
+var ErrCircuitOpen = errCircuitOpen //nolint:gochecknoglobals
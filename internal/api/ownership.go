@@ -12,6 +12,8 @@ type HandleOwnershipPolicy struct {
 	ManagedRecordsCommentRegex        *regexp.Regexp
 	ManagedWAFListItemsCommentRegex   *regexp.Regexp
 	AllowWholeWAFListDeleteOnShutdown bool
+	ManagedIPAccessRulesNotesRegex    *regexp.Regexp
+	ManagedAccessGroupsNameRegex      *regexp.Regexp
 }
 
 // MatchManagedRecordComment reports whether a DNS record comment is in scope.
@@ -30,6 +32,23 @@ func (p HandleOwnershipPolicy) MatchManagedWAFListItemComment(comment string) bo
 	return p.ManagedWAFListItemsCommentRegex.MatchString(comment)
 }
 
+// MatchManagedIPAccessRuleNotes reports whether the notes of an IP Access Rule are in scope.
+func (p HandleOwnershipPolicy) MatchManagedIPAccessRuleNotes(notes string) bool {
+	if p.ManagedIPAccessRulesNotesRegex == nil {
+		return true
+	}
+	return p.ManagedIPAccessRulesNotesRegex.MatchString(notes)
+}
+
+// MatchManagedAccessGroupName reports whether the name of an Access group marks
+// it as managed. Access rules cannot carry comments, so the whole group is marked.
+func (p HandleOwnershipPolicy) MatchManagedAccessGroupName(name string) bool {
+	if p.ManagedAccessGroupsNameRegex == nil {
+		return true
+	}
+	return p.ManagedAccessGroupsNameRegex.MatchString(name)
+}
+
 // Sanitize normalizes contradictory ownership settings and logs advisories.
 func (p HandleOwnershipPolicy) Sanitize(ppfmt pp.PP) HandleOwnershipPolicy {
 	if !p.AllowWholeWAFListDeleteOnShutdown {
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+const (
+	// rfc2136AutoTTL is the TTL used for [TTLAuto], matching what Cloudflare uses.
+	rfc2136AutoTTL = 300
+	// rfc2136OpCodeUpdate is the operation code of UPDATE messages.
+	rfc2136OpCodeUpdate dnsmessage.OpCode = 5
+	// rfc2136ClassNONE is the class to delete one resource record from an RRset.
+	rfc2136ClassNONE dnsmessage.Class = 254
+	// rfc2136RCodeNotAuth is the response code for unauthorized updates.
+	rfc2136RCodeNotAuth dnsmessage.RCode = 9
+)
+
+// An RFC2136Auth holds the address of a DNS server accepting dynamic updates
+// (RFC 2136) and the TSIG key to sign the messages with HMAC-SHA256 (RFC 8945).
+type RFC2136Auth struct {
+	// Server is the host and the port of the server.
+	Server string
+	// KeyName is the name of the TSIG key.
+	KeyName string
+	// Secret is the shared secret of the TSIG key.
+	Secret []byte
+}
+
+// Describe formats the server and the key name as a string.
+func (a RFC2136Auth) Describe() string {
+	return fmt.Sprintf("%s (TSIG key %s)", a.Server, a.KeyName)
+}
+
+// New creates a [RecordHandle] updating the DNS records on the server. The
+// handle also implements [RecordSnapshotter].
+//
+// A record is identified by its address. Proxy statuses, comments, and tags
+// do not exist in standard DNS and are ignored, so a record is in scope
+// exactly when the managed-record selector accepts the empty comment; the
+// configuration rejects selectors that do not, and thus the whole A or AAAA
+// RRset of a domain is managed.
+func (a RFC2136Auth) New(_ pp.PP, options HandleOptions) (RecordHandle, bool) {
+	return rfc2136Handle{
+		server:  a.Server,
+		key:     tsigKey{name: a.KeyName, secret: a.Secret},
+		zones:   newCache[string, string](options.CacheExpiration),
+		records: &rfc2136Records{mutex: sync.Mutex{}, seen: map[rfc2136RecordsKey]RecordSnapshot{}},
+	}, true
+}
+
+// An rfc2136Handle implements [RecordHandle] with RFC 2136 dynamic updates.
+type rfc2136Handle struct {
+	server  string
+	key     tsigKey
+	zones   *ttlcache.Cache[string, string] // domain names to zone names
+	records *rfc2136Records
+}
+
+var (
+	_ RecordHandle      = rfc2136Handle{} //nolint:exhaustruct
+	_ RecordSnapshotter = rfc2136Handle{} //nolint:exhaustruct
+)
+
+type rfc2136RecordsKey struct {
+	ipFamily ipnet.Family
+	domain   string
+}
+
+// rfc2136Records remembers the records last seen on the server, so that they
+// can be kept in the state file. Nothing is read back from it.
+type rfc2136Records struct {
+	mutex sync.Mutex
+	seen  map[rfc2136RecordsKey]RecordSnapshot
+}
+
+// set remembers the records just read from the server.
+func (r *rfc2136Records) set(server string, ipFamily ipnet.Family, domain string, records []Record) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.seen[rfc2136RecordsKey{ipFamily: ipFamily, domain: domain}] = RecordSnapshot{
+		IPFamily:  ipFamily,
+		Domain:    domain,
+		ZoneID:    "",
+		AccountID: "",
+		Server:    server,
+		Records:   slices.Clone(records),
+		FetchedAt: time.Now(),
+	}
+}
+
+// apply reflects a successful update in the records seen, if any.
+func (r *rfc2136Records) apply(ipFamily ipnet.Family, domain string, deleted, added []netip.Addr, ttl TTL) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := rfc2136RecordsKey{ipFamily: ipFamily, domain: domain}
+	snapshot, ok := r.seen[key]
+	if !ok {
+		return
+	}
+	records := slices.DeleteFunc(slices.Clone(snapshot.Records), func(record Record) bool {
+		return slices.Contains(deleted, record.IP) || slices.Contains(added, record.IP)
+	})
+	for _, ip := range added {
+		records = append(records, Record{
+			ID: ID(ip.String()),
+			IP: ip,
+			RecordParams: RecordParams{
+				TTL:     TTL(rfc2136TTL(ttl)),
+				Proxied: false,
+				Comment: "",
+				Tags:    nil,
+			},
+		})
+	}
+	snapshot.Records = records
+	r.seen[key] = snapshot
+}
+
+// SnapshotRecords implements [RecordSnapshotter]. It returns the records last
+// seen on the server, sorted by IP family and domain.
+func (h rfc2136Handle) SnapshotRecords() []RecordSnapshot {
+	h.records.mutex.Lock()
+	defer h.records.mutex.Unlock()
+	snapshots := make([]RecordSnapshot, 0, len(h.records.seen))
+	for _, snapshot := range h.records.seen {
+		snapshot.Records = slices.Clone(snapshot.Records)
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.SortFunc(snapshots, func(a, b RecordSnapshot) int {
+		return cmp.Or(cmp.Compare(a.IPFamily, b.IPFamily), cmp.Compare(a.Domain, b.Domain))
+	})
+	return snapshots
+}
+
+func hintRFC2136Permission(ppfmt pp.PP, err error) {
+	var rcode rfc2136RCodeError
+	if errors.Is(err, errRFC2136TSIG) ||
+		errors.As(err, &rcode) && (rcode.RCode == dnsmessage.RCodeRefused || rcode.RCode == rfc2136RCodeNotAuth) {
+		ppfmt.NoticeOncef(pp.MessageRFC2136Permission, pp.EmojiHint,
+			"Double-check the TSIG key name and secret, "+
+				"and make sure the server allows the key to update the zone")
+	}
+}
+
+func rfc2136RecordType(ipFamily ipnet.Family) dnsmessage.Type {
+	if ipFamily == ipnet.IP4 {
+		return dnsmessage.TypeA
+	}
+	return dnsmessage.TypeAAAA
+}
+
+func rfc2136TTL(ttl TTL) uint32 {
+	if ttl == TTLAuto || ttl.Int() <= 0 {
+		return rfc2136AutoTTL
+	}
+	return uint32(ttl.Int()) //nolint:gosec // checked above
+}
+
+func rfc2136Name(name string) (dnsmessage.Name, error) {
+	return dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".") //nolint:wrapcheck
+}
+
+// exchange sends a signed message and checks the response.
+func (h rfc2136Handle) exchange(ctx context.Context, opCode dnsmessage.OpCode,
+	question dnsmessage.Question, updates []rfc2136Resource,
+) (dnsmessage.Message, error) {
+	var zero dnsmessage.Message
+
+	id := newMessageID()
+	msg, err := buildMessage(dnsmessage.Header{ID: id, OpCode: opCode}, question, updates) //nolint:exhaustruct
+	if err != nil {
+		return zero, fmt.Errorf("build the message: %w", err)
+	}
+	signed, mac := h.key.sign(msg, time.Now())
+
+	resp, err := exchangeTCP(ctx, h.server, signed)
+	if err != nil {
+		return zero, err
+	}
+	if err := h.key.verify(resp, mac, time.Now()); err != nil {
+		return zero, err
+	}
+
+	var m dnsmessage.Message
+	if err := m.Unpack(resp); err != nil {
+		return zero, fmt.Errorf("parse the response: %w", err)
+	}
+	if !m.Response || m.ID != id {
+		return zero, errors.New("the response does not match the request")
+	}
+	if m.RCode != dnsmessage.RCodeSuccess {
+		return zero, rfc2136RCodeError{RCode: m.RCode}
+	}
+	return m, nil
+}
+
+// zoneOfDomain finds the zone of a domain by querying the SOA records of its suffixes.
+func (h rfc2136Handle) zoneOfDomain(ctx context.Context, ppfmt pp.PP, domain domain.Domain) (string, bool) {
+	if zone := h.zones.Get(domain.DNSNameASCII()); zone != nil {
+		return zone.Value(), true
+	}
+
+	for zoneName := range domain.Zones {
+		name, err := rfc2136Name(zoneName.DNSNameASCII())
+		if err != nil {
+			break
+		}
+		m, err := h.exchange(ctx, 0,
+			dnsmessage.Question{Name: name, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}, nil)
+		if err != nil {
+			var rcode rfc2136RCodeError
+			if errors.As(err, &rcode) &&
+				(rcode.RCode == dnsmessage.RCodeNameError || rcode.RCode == dnsmessage.RCodeRefused) {
+				continue
+			}
+			ppfmt.Noticef(pp.EmojiError, "Failed to find the zone of %s on the RFC 2136 server %s: %v",
+				domain.Describe(), h.server, err)
+			hintRFC2136Permission(ppfmt, err)
+			return "", false
+		}
+		for _, answer := range m.Answers {
+			if answer.Header.Type == dnsmessage.TypeSOA && strings.EqualFold(answer.Header.Name.String(), name.String()) {
+				h.zones.DeleteExpired()
+				h.zones.Set(domain.DNSNameASCII(), name.String(), ttlcache.DefaultTTL)
+				return name.String(), true
+			}
+		}
+	}
+
+	ppfmt.Noticef(pp.EmojiError, "Failed to find the zone of %s on the RFC 2136 server %s",
+		domain.Describe(), h.server)
+	return "", false
+}
+
+// ListRecords queries the A or AAAA records of a domain.
+func (h rfc2136Handle) ListRecords(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family,
+	domain domain.Domain, _ RecordParams,
+) ([]Record, bool, bool) {
+	name, err := rfc2136Name(domain.DNSNameASCII())
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the domain %s: %v", domain.Describe(), err)
+		return nil, false, false
+	}
+	recordType := rfc2136RecordType(ipFamily)
+
+	m, err := h.exchange(ctx, 0, dnsmessage.Question{Name: name, Type: recordType, Class: dnsmessage.ClassINET}, nil)
+	if err != nil {
+		var rcode rfc2136RCodeError
+		if errors.As(err, &rcode) && rcode.RCode == dnsmessage.RCodeNameError {
+			h.records.set(h.server, ipFamily, domain.DNSNameASCII(), nil)
+			return []Record{}, false, true
+		}
+		ppfmt.Noticef(pp.EmojiError, "Failed to read the %s records of %s from the RFC 2136 server %s: %v",
+			ipFamily.RecordType(), domain.Describe(), h.server, err)
+		hintRFC2136Permission(ppfmt, err)
+		return nil, false, false
+	}
+
+	rs := make([]Record, 0, len(m.Answers))
+	for _, answer := range m.Answers {
+		if answer.Header.Type != recordType || !strings.EqualFold(answer.Header.Name.String(), name.String()) {
+			continue
+		}
+		var ip netip.Addr
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = netip.AddrFrom4(body.A)
+		case *dnsmessage.AAAAResource:
+			ip = netip.AddrFrom16(body.AAAA)
+		default:
+			continue
+		}
+		rs = append(rs, Record{
+			ID: ID(ip.String()),
+			IP: ip,
+			RecordParams: RecordParams{
+				TTL:     TTL(answer.Header.TTL),
+				Proxied: false,
+				Comment: "",
+				Tags:    nil,
+			},
+		})
+	}
+	h.records.set(h.server, ipFamily, domain.DNSNameASCII(), rs)
+	return rs, false, true
+}
+
+func (h rfc2136Handle) newResource(ipFamily ipnet.Family, name dnsmessage.Name,
+	class dnsmessage.Class, ttl uint32, ip netip.Addr,
+) rfc2136Resource {
+	recordType := rfc2136RecordType(ipFamily)
+	return rfc2136Resource{
+		Header: dnsmessage.ResourceHeader{Name: name, Type: recordType, Class: class, TTL: ttl, Length: 0},
+		Body:   dnsmessage.UnknownResource{Type: recordType, Data: ip.Unmap().AsSlice()},
+	}
+}
+
+// update sends one UPDATE message to add or delete records of a domain.
+func (h rfc2136Handle) update(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	deleted []netip.Addr, added []netip.Addr, ttl TTL,
+) bool {
+	zone, ok := h.zoneOfDomain(ctx, ppfmt, domain)
+	if !ok {
+		return false
+	}
+	zoneName, err := rfc2136Name(zone)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the zone %s: %v", zone, err)
+		return false
+	}
+	name, err := rfc2136Name(domain.DNSNameASCII())
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the domain %s: %v", domain.Describe(), err)
+		return false
+	}
+
+	updates := make([]rfc2136Resource, 0, len(deleted)+len(added))
+	for _, ip := range deleted {
+		updates = append(updates, h.newResource(ipFamily, name, rfc2136ClassNONE, 0, ip))
+	}
+	for _, ip := range added {
+		updates = append(updates, h.newResource(ipFamily, name, dnsmessage.ClassINET, rfc2136TTL(ttl), ip))
+	}
+
+	if _, err := h.exchange(ctx, rfc2136OpCodeUpdate,
+		dnsmessage.Question{Name: zoneName, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}, updates,
+	); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the %s records of %s on the RFC 2136 server %s: %v",
+			ipFamily.RecordType(), domain.Describe(), h.server, err)
+		hintRFC2136Permission(ppfmt, err)
+		return false
+	}
+	h.records.apply(ipFamily, domain.DNSNameASCII(), deleted, added, ttl)
+	return true
+}
+
+func parseRFC2136RecordID(ppfmt pp.PP, id ID) (netip.Addr, bool) {
+	ip, err := netip.ParseAddr(string(id))
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible,
+			"The record ID %q is not an IP address; please report this at %s", id, pp.IssueReportingURL)
+		return netip.Addr{}, false
+	}
+	return ip, true
+}
+
+// UpdateRecord replaces the record in one UPDATE message.
+func (h rfc2136Handle) UpdateRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	id ID, ip netip.Addr, desiredParams RecordParams,
+) bool {
+	old, ok := parseRFC2136RecordID(ppfmt, id)
+	if !ok {
+		return false
+	}
+	return h.update(ctx, ppfmt, ipFamily, domain, []netip.Addr{old}, []netip.Addr{ip}, desiredParams.TTL)
+}
+
+// CreateRecord adds a record. The ID of the new record is its address.
+func (h rfc2136Handle) CreateRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+	ip netip.Addr, desiredParams RecordParams,
+) (ID, bool) {
+	if !h.update(ctx, ppfmt, ipFamily, domain, nil, []netip.Addr{ip}, desiredParams.TTL) {
+		return "", false
+	}
+	return ID(ip.String()), true
+}
+
+// DeleteRecord deletes a record. Nothing is cached, so the deletion mode does not matter.
+func (h rfc2136Handle) DeleteRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family,
+	domain domain.Domain, id ID, _ DeletionMode,
+) bool {
+	ip, ok := parseRFC2136RecordID(ppfmt, id)
+	if !ok {
+		return false
+	}
+	return h.update(ctx, ppfmt, ipFamily, domain, []netip.Addr{ip}, nil, TTLAuto)
+}
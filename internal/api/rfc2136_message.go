@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// The TSIG algorithm and settings used to sign RFC 2136 messages (RFC 8945).
+const (
+	tsigAlgorithmHMACSHA256 = "hmac-sha256."
+	tsigType                = dnsmessage.Type(250)
+	tsigFudge               = 300
+	// tsigHeaderLen is the length of the type, class, TTL, and RDLENGTH fields of a resource record.
+	tsigHeaderLen = 10
+)
+
+// tsigErrorNames are the TSIG error codes that a server may return (RFC 8945, Section 5.3).
+var tsigErrorNames = map[uint16]string{ //nolint:gochecknoglobals
+	16: "BADSIG",
+	17: "BADKEY",
+	18: "BADTIME",
+	22: "BADTRUNC",
+}
+
+// errRFC2136TSIG marks the errors caused by TSIG verification.
+var errRFC2136TSIG = errors.New("TSIG verification failed")
+
+// rfc2136RCodeError is a response code other than NOERROR returned by a server.
+type rfc2136RCodeError struct {
+	RCode dnsmessage.RCode
+}
+
+func (e rfc2136RCodeError) Error() string {
+	switch e.RCode { //nolint:exhaustive // other codes use the generic names
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	case 9:
+		return "NOTAUTH"
+	case 8:
+		return "NXRRSET"
+	case 6:
+		return "YXDOMAIN"
+	default:
+		return e.RCode.String()
+	}
+}
+
+// A tsigKey is a named shared secret for HMAC-SHA256.
+type tsigKey struct {
+	name   string
+	secret []byte
+}
+
+// appendWireName appends the uncompressed, lowercase wire format of a domain name.
+func appendWireName(b []byte, name string) []byte {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name != "" {
+		for label := range strings.SplitSeq(name, ".") {
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0)
+}
+
+// readWireName reads an uncompressed domain name at the beginning of b.
+func readWireName(b []byte) (string, []byte, bool) {
+	var labels []string
+	for {
+		if len(b) == 0 {
+			return "", nil, false
+		}
+		n := int(b[0])
+		b = b[1:]
+		if n == 0 {
+			return strings.Join(labels, ".") + ".", b, true
+		}
+		if n > 63 || len(b) < n { //nolint:mnd // labels are at most 63 octets; larger values are pointers
+			return "", nil, false
+		}
+		labels = append(labels, string(b[:n]))
+		b = b[n:]
+	}
+}
+
+// tsigRecord holds the RDATA fields of a TSIG resource record.
+type tsigRecord struct {
+	algorithm  string
+	timeSigned uint64
+	fudge      uint16
+	mac        []byte
+	originalID uint16
+	errorCode  uint16
+	otherData  []byte
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	return append(b, byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v)) //nolint:mnd
+}
+
+// variables gives the TSIG variables covered by the MAC (RFC 8945, Section 4.3.3).
+func (r tsigRecord) variables(keyName string) []byte {
+	b := appendWireName(nil, keyName)
+	b = binary.BigEndian.AppendUint16(b, uint16(dnsmessage.ClassANY))
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = appendWireName(b, r.algorithm)
+	b = appendUint48(b, r.timeSigned)
+	b = binary.BigEndian.AppendUint16(b, r.fudge)
+	b = binary.BigEndian.AppendUint16(b, r.errorCode)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(r.otherData))) //nolint:gosec
+	return append(b, r.otherData...)
+}
+
+func (r tsigRecord) rdata() []byte {
+	b := appendWireName(nil, r.algorithm)
+	b = appendUint48(b, r.timeSigned)
+	b = binary.BigEndian.AppendUint16(b, r.fudge)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(r.mac))) //nolint:gosec
+	b = append(b, r.mac...)
+	b = binary.BigEndian.AppendUint16(b, r.originalID)
+	b = binary.BigEndian.AppendUint16(b, r.errorCode)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(r.otherData))) //nolint:gosec
+	return append(b, r.otherData...)
+}
+
+func parseTSIGRecord(b []byte) (tsigRecord, bool) {
+	var r tsigRecord
+	var ok bool
+	if r.algorithm, b, ok = readWireName(b); !ok || len(b) < 10 { //nolint:mnd
+		return r, false
+	}
+	r.timeSigned = uint64(binary.BigEndian.Uint16(b))<<32 | uint64(binary.BigEndian.Uint32(b[2:])) //nolint:mnd
+	r.fudge = binary.BigEndian.Uint16(b[6:])
+	macLen := int(binary.BigEndian.Uint16(b[8:]))
+	b = b[10:]
+	if len(b) < macLen+6 { //nolint:mnd
+		return r, false
+	}
+	r.mac, b = b[:macLen], b[macLen:]
+	r.originalID = binary.BigEndian.Uint16(b)
+	r.errorCode = binary.BigEndian.Uint16(b[2:])
+	otherLen := int(binary.BigEndian.Uint16(b[4:]))
+	b = b[6:]
+	if len(b) != otherLen {
+		return r, false
+	}
+	r.otherData = b
+	return r, true
+}
+
+func (k tsigKey) mac(prefix, msg []byte, r tsigRecord) []byte {
+	m := hmac.New(sha256.New, k.secret)
+	m.Write(prefix)
+	m.Write(msg)
+	m.Write(r.variables(k.name))
+	return m.Sum(nil)
+}
+
+// sign appends a TSIG record to a message and returns the signed message and its MAC.
+func (k tsigKey) sign(msg []byte, now time.Time) ([]byte, []byte) {
+	r := tsigRecord{
+		algorithm:  tsigAlgorithmHMACSHA256,
+		timeSigned: uint64(now.Unix()), //nolint:gosec
+		fudge:      tsigFudge,
+		mac:        nil,
+		originalID: binary.BigEndian.Uint16(msg),
+		errorCode:  0,
+		otherData:  nil,
+	}
+	r.mac = k.mac(nil, msg, r)
+
+	rdata := r.rdata()
+	signed := make([]byte, 0, len(msg)+len(k.name)+tsigHeaderLen+len(rdata)+2) //nolint:mnd
+	signed = append(signed, msg...)
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(msg[10:])+1)
+	signed = appendWireName(signed, k.name)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(tsigType))
+	signed = binary.BigEndian.AppendUint16(signed, uint16(dnsmessage.ClassANY))
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata))) //nolint:gosec
+	signed = append(signed, rdata...)
+	return signed, r.mac
+}
+
+// verify checks the TSIG record at the end of a response to a request with the MAC requestMAC.
+func (k tsigKey) verify(resp []byte, requestMAC []byte, now time.Time) error {
+	var p dnsmessage.Parser
+	header, err := p.Start(resp)
+	if err != nil {
+		return fmt.Errorf("parse the response: %w", err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return fmt.Errorf("parse the response: %w", err)
+	}
+	if err := p.SkipAllAnswers(); err != nil {
+		return fmt.Errorf("parse the response: %w", err)
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		return fmt.Errorf("parse the response: %w", err)
+	}
+
+	var tsig *dnsmessage.UnknownResource
+	var tsigOwner string
+	for {
+		h, err := p.AdditionalHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("parse the response: %w", err)
+		}
+		if tsig != nil {
+			return fmt.Errorf("%w: the TSIG record is not the last record", errRFC2136TSIG)
+		}
+		if h.Type != tsigType {
+			if err := p.SkipAdditional(); err != nil {
+				return fmt.Errorf("parse the response: %w", err)
+			}
+			continue
+		}
+		r, err := p.UnknownResource()
+		if err != nil {
+			return fmt.Errorf("parse the response: %w", err)
+		}
+		tsig, tsigOwner = &r, h.Name.String()
+	}
+	if tsig == nil {
+		if header.RCode != dnsmessage.RCodeSuccess {
+			// Servers may reject requests without signing the response.
+			return nil
+		}
+		return fmt.Errorf("%w: the response is not signed", errRFC2136TSIG)
+	}
+
+	r, ok := parseTSIGRecord(tsig.Data)
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: malformed TSIG record", errRFC2136TSIG)
+	case r.errorCode != 0:
+		name, known := tsigErrorNames[r.errorCode]
+		if !known {
+			name = fmt.Sprintf("error %d", r.errorCode)
+		}
+		return fmt.Errorf("%w: the server returned %s", errRFC2136TSIG, name)
+	case !strings.EqualFold(tsigOwner, k.name) || !strings.EqualFold(r.algorithm, tsigAlgorithmHMACSHA256):
+		return fmt.Errorf("%w: the response is signed with another key", errRFC2136TSIG)
+	}
+
+	tsigLen := len(appendWireName(nil, tsigOwner)) + tsigHeaderLen + len(tsig.Data)
+	if tsigLen > len(resp)-12 { //nolint:mnd
+		return fmt.Errorf("%w: the TSIG record is compressed", errRFC2136TSIG)
+	}
+	unsigned := append([]byte{}, resp[:len(resp)-tsigLen]...)
+	binary.BigEndian.PutUint16(unsigned, r.originalID)
+	binary.BigEndian.PutUint16(unsigned[10:], binary.BigEndian.Uint16(unsigned[10:])-1)
+
+	prefix := binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC))) //nolint:gosec
+	prefix = append(prefix, requestMAC...)
+	if !hmac.Equal(k.mac(prefix, unsigned, r), r.mac) {
+		return fmt.Errorf("%w: the response has a wrong MAC", errRFC2136TSIG)
+	}
+
+	if diff := now.Unix() - int64(r.timeSigned); diff > int64(r.fudge) || -diff > int64(r.fudge) { //nolint:gosec
+		return fmt.Errorf("%w: the clocks of the updater and the server differ too much", errRFC2136TSIG)
+	}
+	return nil
+}
+
+// newMessageID picks a random message ID.
+func newMessageID() uint16 {
+	var b [2]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint16(b[:])
+}
+
+// rfc2136Resource is one resource record in a query or an update.
+type rfc2136Resource struct {
+	Header dnsmessage.ResourceHeader
+	Body   dnsmessage.UnknownResource
+}
+
+// buildMessage builds a message with one question (or the zone of an update)
+// and the resource records in the authority (or update) section.
+func buildMessage(header dnsmessage.Header, question dnsmessage.Question, updates []rfc2136Resource,
+) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, header)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if err := b.Question(question); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if err := b.StartAuthorities(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	for _, r := range updates {
+		if err := b.UnknownResource(r.Header, r.Body); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+	return b.Finish() //nolint:wrapcheck
+}
+
+// exchangeTCP sends a message over TCP and reads the response (RFC 1035, Section 4.2.2).
+func exchangeTCP(ctx context.Context, server string, msg []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer conn.Close()
+
+	// Unblock reading and writing when the context is canceled.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(msg)))); err != nil { //nolint:gosec
+		return nil, err //nolint:wrapcheck
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return resp, nil
+}
@@ -0,0 +1,575 @@
+package api_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+const (
+	rfc2136Zone    = "example.org."
+	rfc2136KeyName = "ddns-key."
+)
+
+var rfc2136Secret = []byte("0123456789abcdef0123456789abcdef") //nolint:gochecknoglobals
+
+// rfc2136Stored is one A or AAAA record held by an [rfc2136Server].
+type rfc2136Stored struct {
+	IP  netip.Addr
+	TTL uint32
+}
+
+// rfc2136Server is an in-process stand-in of an authoritative DNS server for
+// the zone [rfc2136Zone], accepting queries and dynamic updates over TCP when
+// they are signed with the TSIG key [rfc2136KeyName]. It verifies and signs
+// messages on its own, independently of the implementation being tested.
+type rfc2136Server struct {
+	t        *testing.T
+	listener net.Listener
+
+	mutex sync.Mutex
+	// records maps lowercase owner names without the final dot to their records.
+	records map[string][]rfc2136Stored
+	// responseSecret is the secret used to sign responses; it is rfc2136Secret by default.
+	responseSecret []byte
+	updates        int
+}
+
+func newRFC2136Server(t *testing.T) *rfc2136Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &rfc2136Server{
+		t:              t,
+		listener:       listener,
+		mutex:          sync.Mutex{},
+		records:        map[string][]rfc2136Stored{},
+		responseSecret: rfc2136Secret,
+		updates:        0,
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *rfc2136Server) addr() string { return s.listener.Addr().String() }
+
+func (s *rfc2136Server) set(name string, records ...rfc2136Stored) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[name] = records
+}
+
+func (s *rfc2136Server) get(name string) []rfc2136Stored {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return slices.Clone(s.records[name])
+}
+
+func (s *rfc2136Server) setResponseSecret(secret []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responseSecret = secret
+}
+
+func (s *rfc2136Server) updateCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.updates
+}
+
+func (s *rfc2136Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *rfc2136Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		resp := s.handle(msg)
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...)); err != nil { //nolint:gosec
+			return
+		}
+	}
+}
+
+func wireName(name string) []byte {
+	var b []byte
+	for label := range strings.SplitSeq(strings.TrimSuffix(strings.ToLower(name), "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// tsigVariables encodes the TSIG variables of RFC 8945, Section 4.3.3, without other data.
+func tsigVariables(timeSigned uint64, fudge, errorCode uint16) []byte {
+	b := wireName(rfc2136KeyName)
+	b = append(b, 0, 255, 0, 0, 0, 0)
+	b = append(b, wireName("hmac-sha256.")...)
+	b = append(b, byte(timeSigned>>40), byte(timeSigned>>32), byte(timeSigned>>24),
+		byte(timeSigned>>16), byte(timeSigned>>8), byte(timeSigned))
+	b = binary.BigEndian.AppendUint16(b, fudge)
+	b = binary.BigEndian.AppendUint16(b, errorCode)
+	return append(b, 0, 0)
+}
+
+func hmacSHA256(secret []byte, parts ...[]byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	for _, part := range parts {
+		m.Write(part)
+	}
+	return m.Sum(nil)
+}
+
+// verifyRequest checks the TSIG record of a request and returns its MAC and time.
+func (s *rfc2136Server) verifyRequest(msg []byte) ([]byte, uint64, bool) {
+	// The TSIG record must be the last one, with the owner name and algorithm name uncompressed.
+	keyName, algorithm := wireName(rfc2136KeyName), wireName("hmac-sha256.")
+	fixed := len(keyName) + 10 + len(algorithm) + 10
+	if binary.BigEndian.Uint16(msg[10:]) == 0 || len(msg) < 12+fixed+32+6 {
+		return nil, 0, false
+	}
+	start := len(msg) - (fixed + 32 + 6)
+	rr := msg[start:]
+	if !slices.Equal(rr[:len(keyName)], keyName) ||
+		binary.BigEndian.Uint16(rr[len(keyName):]) != 250 ||
+		!slices.Equal(rr[len(keyName)+10:len(keyName)+10+len(algorithm)], algorithm) {
+		return nil, 0, false
+	}
+	rdata := rr[len(keyName)+10+len(algorithm):]
+	timeSigned := uint64(binary.BigEndian.Uint16(rdata))<<32 | uint64(binary.BigEndian.Uint32(rdata[2:]))
+	fudge := binary.BigEndian.Uint16(rdata[6:])
+	if binary.BigEndian.Uint16(rdata[8:]) != 32 {
+		return nil, 0, false
+	}
+	mac := rdata[10:42]
+
+	unsigned := slices.Clone(msg[:start])
+	binary.BigEndian.PutUint16(unsigned[10:], binary.BigEndian.Uint16(unsigned[10:])-1)
+	if !hmac.Equal(mac, hmacSHA256(rfc2136Secret, unsigned, tsigVariables(timeSigned, fudge, 0))) {
+		return nil, 0, false
+	}
+	return mac, timeSigned, true
+}
+
+// appendTSIG appends a TSIG record to the response.
+func appendTSIG(resp []byte, mac []byte, timeSigned uint64, errorCode uint16) []byte {
+	rdata := wireName("hmac-sha256.")
+	rdata = append(rdata, byte(timeSigned>>40), byte(timeSigned>>32), byte(timeSigned>>24),
+		byte(timeSigned>>16), byte(timeSigned>>8), byte(timeSigned))
+	rdata = binary.BigEndian.AppendUint16(rdata, 300)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(mac))) //nolint:gosec
+	rdata = append(rdata, mac...)
+	rdata = append(rdata, resp[0], resp[1])
+	rdata = binary.BigEndian.AppendUint16(rdata, errorCode)
+	rdata = append(rdata, 0, 0)
+
+	signed := slices.Clone(resp)
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1)
+	signed = append(signed, wireName(rfc2136KeyName)...)
+	signed = append(signed, 0, 250, 0, 255, 0, 0, 0, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata))) //nolint:gosec
+	return append(signed, rdata...)
+}
+
+func (s *rfc2136Server) handle(msg []byte) []byte {
+	var request dnsmessage.Message
+	if !assert.NoError(s.t, request.Unpack(msg)) || !assert.Len(s.t, request.Questions, 1) {
+		return nil
+	}
+	question := request.Questions[0]
+	header := dnsmessage.Header{ //nolint:exhaustruct
+		ID:            request.ID,
+		Response:      true,
+		OpCode:        request.OpCode,
+		Authoritative: true,
+		RCode:         dnsmessage.RCodeSuccess,
+	}
+
+	requestMAC, timeSigned, ok := s.verifyRequest(msg)
+	if !ok {
+		header.RCode = 9 // NOTAUTH
+		b := dnsmessage.NewBuilder(nil, header)
+		assert.NoError(s.t, b.StartQuestions())
+		assert.NoError(s.t, b.Question(question))
+		resp, err := b.Finish()
+		assert.NoError(s.t, err)
+		return appendTSIG(resp, nil, uint64(time.Now().Unix()), 16) //nolint:gosec // BADSIG
+	}
+
+	s.mutex.Lock()
+	answers, rcode := s.respond(request)
+	responseSecret := s.responseSecret
+	s.mutex.Unlock()
+	header.RCode = rcode
+
+	b := dnsmessage.NewBuilder(nil, header)
+	assert.NoError(s.t, b.StartQuestions())
+	assert.NoError(s.t, b.Question(question))
+	assert.NoError(s.t, b.StartAnswers())
+	for _, answer := range answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			assert.NoError(s.t, b.AResource(answer.Header, *body))
+		case *dnsmessage.AAAAResource:
+			assert.NoError(s.t, b.AAAAResource(answer.Header, *body))
+		case *dnsmessage.SOAResource:
+			assert.NoError(s.t, b.SOAResource(answer.Header, *body))
+		}
+	}
+	resp, err := b.Finish()
+	assert.NoError(s.t, err)
+
+	prefix := append(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC))), requestMAC...) //nolint:gosec
+	mac := hmacSHA256(responseSecret, prefix, resp, tsigVariables(timeSigned, 300, 0))
+	return appendTSIG(resp, mac, timeSigned, 0)
+}
+
+func ownerName(name dnsmessage.Name) string {
+	return strings.TrimSuffix(strings.ToLower(name.String()), ".")
+}
+
+func inZone(name string) bool {
+	zone := strings.TrimSuffix(rfc2136Zone, ".")
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// respond answers a query or applies an update. The mutex must be held.
+func (s *rfc2136Server) respond(request dnsmessage.Message) ([]dnsmessage.Resource, dnsmessage.RCode) {
+	question := request.Questions[0]
+	name := ownerName(question.Name)
+
+	switch request.OpCode {
+	case 0:
+		if !inZone(name) {
+			return nil, dnsmessage.RCodeRefused
+		}
+		if question.Type == dnsmessage.TypeSOA && name == strings.TrimSuffix(rfc2136Zone, ".") {
+			soa := dnsmessage.MustNewName("ns." + rfc2136Zone)
+			return []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600}, //nolint:exhaustruct
+				Body:   &dnsmessage.SOAResource{NS: soa, MBox: soa, Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: 300},
+			}}, dnsmessage.RCodeSuccess
+		}
+		records, found := s.records[name]
+		if !found && name != strings.TrimSuffix(rfc2136Zone, ".") {
+			return nil, dnsmessage.RCodeNameError
+		}
+		var answers []dnsmessage.Resource
+		for _, r := range records {
+			header := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: r.TTL} //nolint:exhaustruct
+			switch {
+			case question.Type == dnsmessage.TypeA && r.IP.Is4():
+				header.Type = dnsmessage.TypeA
+				answers = append(answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: r.IP.As4()}})
+			case question.Type == dnsmessage.TypeAAAA && r.IP.Is6():
+				header.Type = dnsmessage.TypeAAAA
+				answers = append(answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: r.IP.As16()}})
+			}
+		}
+		return answers, dnsmessage.RCodeSuccess
+
+	case 5:
+		if question.Type != dnsmessage.TypeSOA || name != strings.TrimSuffix(rfc2136Zone, ".") {
+			return nil, 9 // NOTAUTH
+		}
+		s.updates++
+		for _, update := range request.Authorities {
+			owner := ownerName(update.Header.Name)
+			if !inZone(owner) {
+				return nil, 10 // NOTZONE
+			}
+			var ip netip.Addr
+			switch body := update.Body.(type) {
+			case *dnsmessage.AResource:
+				ip = netip.AddrFrom4(body.A)
+			case *dnsmessage.AAAAResource:
+				ip = netip.AddrFrom16(body.AAAA)
+			default:
+				return nil, dnsmessage.RCodeFormatError
+			}
+			switch update.Header.Class {
+			case dnsmessage.ClassINET:
+				s.records[owner] = slices.DeleteFunc(s.records[owner], func(r rfc2136Stored) bool { return r.IP == ip })
+				s.records[owner] = append(s.records[owner], rfc2136Stored{IP: ip, TTL: update.Header.TTL})
+			case 254: // NONE
+				s.records[owner] = slices.DeleteFunc(s.records[owner], func(r rfc2136Stored) bool { return r.IP == ip })
+				if len(s.records[owner]) == 0 {
+					delete(s.records, owner)
+				}
+			default:
+				return nil, dnsmessage.RCodeFormatError
+			}
+		}
+		return nil, dnsmessage.RCodeSuccess
+
+	default:
+		return nil, dnsmessage.RCodeNotImplemented
+	}
+}
+
+func rfc2136Params() api.RecordParams {
+	return api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil}
+}
+
+func newRFC2136Handle(t *testing.T, server *rfc2136Server, secret []byte) api.RecordHandle {
+	t.Helper()
+
+	auth := api.RFC2136Auth{Server: server.addr(), KeyName: rfc2136KeyName, Secret: secret}
+	h, ok := auth.New(mocks.NewMockPP(gomock.NewController(t)), defaultHandleOptions())
+	require.True(t, ok)
+	return h
+}
+
+func TestRFC2136AuthDescribe(t *testing.T) {
+	t.Parallel()
+
+	auth := api.RFC2136Auth{Server: "ns.example.org:53", KeyName: "ddns-key.", Secret: nil}
+	require.Equal(t, "ns.example.org:53 (TSIG key ddns-key.)", auth.Describe())
+}
+
+func TestRFC2136ListRecords(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	server.set("www.example.org",
+		rfc2136Stored{IP: netip.MustParseAddr("1.1.1.1"), TTL: 300},
+		rfc2136Stored{IP: netip.MustParseAddr("::1"), TTL: 60},
+		rfc2136Stored{IP: netip.MustParseAddr("2.2.2.2"), TTL: 120},
+	)
+	h := newRFC2136Handle(t, server, rfc2136Secret)
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+
+	rs, cached, ok := h.ListRecords(context.Background(), mockPP, ipnet.IP4, domain.FQDN("www.example.org"), rfc2136Params())
+	require.True(t, ok)
+	require.False(t, cached)
+	require.Equal(t, []api.Record{
+		{ID: "1.1.1.1", IP: netip.MustParseAddr("1.1.1.1"), RecordParams: api.RecordParams{TTL: 300, Proxied: false, Comment: "", Tags: nil}},
+		{ID: "2.2.2.2", IP: netip.MustParseAddr("2.2.2.2"), RecordParams: api.RecordParams{TTL: 120, Proxied: false, Comment: "", Tags: nil}},
+	}, rs)
+
+	rs, _, ok = h.ListRecords(context.Background(), mockPP, ipnet.IP6, domain.FQDN("www.example.org"), rfc2136Params())
+	require.True(t, ok)
+	require.Equal(t, []api.Record{
+		{ID: "::1", IP: netip.MustParseAddr("::1"), RecordParams: api.RecordParams{TTL: 60, Proxied: false, Comment: "", Tags: nil}},
+	}, rs)
+
+	rs, _, ok = h.ListRecords(context.Background(), mockPP, ipnet.IP4, domain.FQDN("missing.example.org"), rfc2136Params())
+	require.True(t, ok)
+	require.Empty(t, rs)
+}
+
+func TestRFC2136CreateUpdateDelete(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	h := newRFC2136Handle(t, server, rfc2136Secret)
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	ctx := context.Background()
+	www := domain.FQDN("www.example.org")
+
+	id, ok := h.CreateRecord(ctx, mockPP, ipnet.IP4, www, netip.MustParseAddr("1.1.1.1"), rfc2136Params())
+	require.True(t, ok)
+	require.Equal(t, api.ID("1.1.1.1"), id)
+	require.Equal(t, []rfc2136Stored{{IP: netip.MustParseAddr("1.1.1.1"), TTL: 300}}, server.get("www.example.org"))
+
+	ok = h.UpdateRecord(ctx, mockPP, ipnet.IP4, www, id, netip.MustParseAddr("2.2.2.2"),
+		api.RecordParams{TTL: 120, Proxied: true, Comment: "hello", Tags: nil})
+	require.True(t, ok)
+	require.Equal(t, []rfc2136Stored{{IP: netip.MustParseAddr("2.2.2.2"), TTL: 120}}, server.get("www.example.org"))
+
+	ok = h.DeleteRecord(ctx, mockPP, ipnet.IP4, www, "2.2.2.2", api.RegularDeletionMode)
+	require.True(t, ok)
+	require.Empty(t, server.get("www.example.org"))
+
+	// The zone is found once and then cached.
+	require.Equal(t, 3, server.updateCount())
+}
+
+func TestRFC2136WrongKey(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	h := newRFC2136Handle(t, server, []byte("wrong secret"))
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to read the %s records of %s from the RFC 2136 server %s: %v",
+			"A", "www.example.org", server.addr(), gomock.Any()).
+			Do(func(_ pp.Emoji, _ string, args ...any) {
+				err, ok := args[3].(error)
+				require.True(t, ok)
+				require.ErrorContains(t, err, "BADSIG")
+			}),
+		mockPP.EXPECT().NoticeOncef(pp.MessageRFC2136Permission, pp.EmojiHint,
+			"Double-check the TSIG key name and secret, and make sure the server allows the key to update the zone"),
+	)
+
+	_, _, ok := h.ListRecords(context.Background(), mockPP, ipnet.IP4, domain.FQDN("www.example.org"), rfc2136Params())
+	require.False(t, ok)
+}
+
+func TestRFC2136WrongResponseMAC(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	server.setResponseSecret([]byte("another secret"))
+	h := newRFC2136Handle(t, server, rfc2136Secret)
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to read the %s records of %s from the RFC 2136 server %s: %v",
+			"AAAA", "www.example.org", server.addr(), gomock.Any()).
+			Do(func(_ pp.Emoji, _ string, args ...any) {
+				err, ok := args[3].(error)
+				require.True(t, ok)
+				require.ErrorContains(t, err, "wrong MAC")
+			}),
+		mockPP.EXPECT().NoticeOncef(pp.MessageRFC2136Permission, pp.EmojiHint, gomock.Any()),
+	)
+
+	_, _, ok := h.ListRecords(context.Background(), mockPP, ipnet.IP6, domain.FQDN("www.example.org"), rfc2136Params())
+	require.False(t, ok)
+}
+
+func TestRFC2136ZoneNotFound(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	h := newRFC2136Handle(t, server, rfc2136Secret)
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to find the zone of %s on the RFC 2136 server %s",
+		"www.example.com", server.addr())
+
+	_, ok := h.CreateRecord(context.Background(), mockPP, ipnet.IP4, domain.FQDN("www.example.com"),
+		netip.MustParseAddr("1.1.1.1"), rfc2136Params())
+	require.False(t, ok)
+	require.Equal(t, 0, server.updateCount())
+}
+
+func TestRFC2136Unreachable(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	auth := api.RFC2136Auth{Server: addr, KeyName: rfc2136KeyName, Secret: rfc2136Secret}
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	h, ok := auth.New(mockPP, defaultHandleOptions())
+	require.True(t, ok)
+
+	mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to find the zone of %s on the RFC 2136 server %s: %v",
+		"www.example.org", addr, gomock.Any()).
+		Do(func(_ pp.Emoji, _ string, args ...any) {
+			err, ok := args[2].(error)
+			require.True(t, ok)
+			var opErr *net.OpError
+			require.ErrorAs(t, err, &opErr)
+		})
+
+	ok = h.DeleteRecord(context.Background(), mockPP, ipnet.IP4, domain.FQDN("www.example.org"), "1.1.1.1",
+		api.FinalDeletionMode)
+	require.False(t, ok)
+}
+
+func TestRFC2136SnapshotRecords(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	server.set("www.example.org", rfc2136Stored{IP: netip.MustParseAddr("1.1.1.1"), TTL: 120})
+	h := newRFC2136Handle(t, server, rfc2136Secret)
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	ctx := context.Background()
+	www := domain.FQDN("www.example.org")
+
+	snapshotter, ok := h.(api.RecordSnapshotter)
+	require.True(t, ok)
+	require.Empty(t, snapshotter.SnapshotRecords())
+
+	_, _, ok = h.ListRecords(ctx, mockPP, ipnet.IP4, www, rfc2136Params())
+	require.True(t, ok)
+	ok = h.UpdateRecord(ctx, mockPP, ipnet.IP4, www, "1.1.1.1", netip.MustParseAddr("2.2.2.2"), rfc2136Params())
+	require.True(t, ok)
+	_, ok = h.CreateRecord(ctx, mockPP, ipnet.IP4, www, netip.MustParseAddr("3.3.3.3"), rfc2136Params())
+	require.True(t, ok)
+
+	// Writes to domains that were never listed are not remembered.
+	_, ok = h.CreateRecord(ctx, mockPP, ipnet.IP6, www, netip.MustParseAddr("::1"), rfc2136Params())
+	require.True(t, ok)
+
+	snapshots := snapshotter.SnapshotRecords()
+	require.Len(t, snapshots, 1)
+	require.Equal(t, ipnet.IP4, snapshots[0].IPFamily)
+	require.Equal(t, "www.example.org", snapshots[0].Domain)
+	require.Equal(t, server.addr(), snapshots[0].Server)
+	require.Equal(t, []api.Record{
+		{ID: "2.2.2.2", IP: netip.MustParseAddr("2.2.2.2"), RecordParams: api.RecordParams{TTL: 300, Proxied: false, Comment: "", Tags: nil}},
+		{ID: "3.3.3.3", IP: netip.MustParseAddr("3.3.3.3"), RecordParams: api.RecordParams{TTL: 300, Proxied: false, Comment: "", Tags: nil}},
+	}, snapshots[0].Records)
+}
+
+func TestRFC2136ReconciledBySetter(t *testing.T) {
+	t.Parallel()
+
+	server := newRFC2136Server(t)
+	server.set("www.example.org",
+		rfc2136Stored{IP: netip.MustParseAddr("1.1.1.1"), TTL: 120},
+		rfc2136Stored{IP: netip.MustParseAddr("2.2.2.2"), TTL: 120},
+	)
+	h := newRFC2136Handle(t, server, rfc2136Secret)
+	mockPP := mocks.NewMockPP(gomock.NewController(t))
+	ctx := context.Background()
+	www := domain.FQDN("www.example.org")
+	target := []netip.Addr{netip.MustParseAddr("3.3.3.3")}
+
+	s := setter.NewRecordSetter(mockPP, h)
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Updated an outdated %s record for %s (ID: %s)",
+			"A", "www.example.org", api.ID("1.1.1.1")),
+		mockPP.EXPECT().Noticef(pp.EmojiDeletion, "Deleted an outdated %s record for %s (ID: %s)",
+			"A", "www.example.org", api.ID("2.2.2.2")),
+	)
+	require.Equal(t, setter.ResponseUpdated, s.SetIPs(ctx, mockPP, ipnet.IP4, www, target, rfc2136Params()))
+	require.Equal(t, []rfc2136Stored{{IP: netip.MustParseAddr("3.3.3.3"), TTL: 120}}, server.get("www.example.org"))
+
+	mockPP.EXPECT().Infof(pp.EmojiAlreadyDone, "The %s records for %s are already up to date", "A", "www.example.org")
+	require.Equal(t, setter.ResponseNoop, s.SetIPs(ctx, mockPP, ipnet.IP4, www, target, rfc2136Params()))
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"strings"
 	"syscall"
 
 	"github.com/favonia/cloudflare-ddns/internal/pp"
@@ -13,13 +15,13 @@ func CheckRoot(ppfmt pp.PP) {
 	}
 
 	useDeprecated := false
-	if val := getenv("PUID"); val != "" {
+	if val := strings.TrimSpace(os.Getenv("PUID")); val != "" {
 		ppfmt.Noticef(pp.EmojiUserWarning,
 			"PUID=%s is ignored since 1.13.0; use Docker's built-in mechanism to set user ID",
 			val)
 		useDeprecated = true
 	}
-	if val := getenv("PGID"); val != "" {
+	if val := strings.TrimSpace(os.Getenv("PGID")); val != "" {
 		ppfmt.Noticef(pp.EmojiUserWarning,
 			"PGID=%s is ignored since 1.13.0; use Docker's built-in mechanism to set group ID",
 			val)
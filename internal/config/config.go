@@ -16,6 +16,9 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipfilter"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
 )
 
@@ -34,9 +37,25 @@ type RawConfig struct {
 	IP4DetectionFilter              ipfilter.Filter
 	IP6DetectionFilter              ipfilter.Filter
 	WAFLists                        []api.WAFList
+	LBPoolOrigins                   []api.LBPoolOrigin
+	GatewayLocations                []api.GatewayLocation
+	AccessGroups                    []api.AccessGroup
+	IPAccessRules                   []api.IPAccessRuleSet
+	SpectrumApps                    []api.SpectrumApp
+	WAFListRule                     api.WAFListRule
+	WorkersKV                       api.WorkersKVKey
+	RFC2136                         *api.RFC2136Auth
+	LocalResolver                   *LocalResolverConfig
+	NFTablesSets                    []nftset.Set
 	UpdateCron                      cron.Schedule
 	UpdateOnStart                   bool
+	CheckPermissionsOnStart         bool
 	DeleteOnStop                    bool
+	DeleteRemovedOnReload           bool
+	UpdateRetries                   int
+	DryRun                          bool
+	JSONReport                      string
+	StateFile                       string
 	TTL                             api.TTL
 	ProxiedExpression               string
 	RecordComment                   string
@@ -44,6 +63,11 @@ type RawConfig struct {
 	WAFListDescription              string
 	WAFListItemComment              string
 	ManagedWAFListItemsCommentRegex string
+	IPAccessRuleNotes               string
+	ManagedIPAccessRulesNotesRegex  string
+	ManagedAccessGroupsNameRegex    string
+	WAFListRuleExpression           string
+	WAFListRuleDescription          string
 	CacheExpiration                 time.Duration
 	IP4DefaultPrefixLen             int
 	IP6DefaultPrefixLen             int
@@ -66,17 +90,46 @@ type BuiltConfig struct {
 type HandleConfig struct {
 	Auth    api.Auth
 	Options api.HandleOptions
+	// RFC2136 is the DNS server where the DNS records are mirrored with dynamic
+	// updates; it is nil when disabled.
+	RFC2136 *api.RFC2136Auth
 }
 
 // LifecycleConfig holds validated process-lifecycle settings such as scheduling
 // and shutdown behavior.
 // (The timezone is handled directly by the standard library reading the TZ environment variable.)
 type LifecycleConfig struct {
-	UpdateCron    cron.Schedule
-	UpdateOnStart bool
-	DeleteOnStop  bool
+	UpdateCron              cron.Schedule
+	UpdateOnStart           bool
+	CheckPermissionsOnStart bool
+	DeleteOnStop            bool
+	// DeleteRemovedOnReload means the domains and WAF lists removed from the
+	// configuration by a reload are cleaned up as [DeleteOnStop] would do.
+	DeleteRemovedOnReload bool
+	// UpdateRetries is the maximum number of retries of the failed parts of
+	// a round before the next scheduled round; 0 disables retries.
+	UpdateRetries int
+	// JSONReport is where the machine-readable report of each round is written:
+	// empty for nowhere, [JSONReportStdout] for the standard output, or a file path.
+	JSONReport string
+	// StateFile is the file where the records are kept across restarts; empty
+	// for nowhere.
+	StateFile string
 }
 
+// LocalResolverConfig holds the settings of the local resolver fragment, which
+// lets clients inside the network resolve its domains to internal addresses.
+type LocalResolverConfig struct {
+	Sink localdns.Sink
+	// Provider contains only the families written to the fragment. The providers
+	// detect internal addresses, so only "local.iface:..." and static ones are allowed.
+	Provider map[ipnet.Family]provider.Provider
+	Domains  []domain.Domain
+}
+
+// JSONReportStdout is the value of JSON_REPORT that writes reports to the standard output.
+const JSONReportStdout = "stdout"
+
 // UpdateConfig holds the validated settings used during IP detection and
 // DNS/WAF reconciliation.
 type UpdateConfig struct {
@@ -86,6 +139,29 @@ type UpdateConfig struct {
 	Domains  map[ipnet.Family][]domain.Domain
 	HostID6  map[domain.Domain]hostid6.Set
 	WAFLists []api.WAFList
+	// LBPoolOrigins are the load balancer pool origins pointed to the detected addresses.
+	LBPoolOrigins []api.LBPoolOrigin
+	// GatewayLocations are the Gateway locations whose networks follow the detected prefixes.
+	GatewayLocations []api.GatewayLocation
+	// AccessGroups are the Access groups whose "ip" include rules follow the detected prefixes.
+	AccessGroups []api.AccessGroup
+	// IPAccessRules are the sets of IP Access Rules with one rule per detected prefix.
+	IPAccessRules []api.IPAccessRuleSet
+	// SpectrumApps are the Spectrum applications whose direct origins follow the detected addresses.
+	SpectrumApps []api.SpectrumApp
+	// WAFListRule is the WAF custom rule referencing WAF lists; its zone ID is empty when disabled.
+	// The expression already references the lists, and the description identifies the rule.
+	WAFListRule            api.WAFListRule
+	WAFListRuleExpression  string
+	WAFListRuleDescription string
+	// WorkersKV is the Workers KV key where the detected addresses are published; its key is empty when disabled.
+	WorkersKV api.WorkersKVKey
+	// LocalResolver is the local resolver fragment written for split-horizon DNS; it is nil when disabled.
+	LocalResolver *LocalResolverConfig
+	// NFTablesSets are the nftables sets kept equal to the detected prefixes,
+	// and NFTablesRunner runs nft for them; it is nil when there are no sets.
+	NFTablesSets   []nftset.Set
+	NFTablesRunner nftset.Runner
 	// DetectionFilter contains only managed families in the built config.
 	DetectionFilter map[ipnet.Family]ipfilter.Filter
 	// DefaultPrefixLen stores the derivation default prefix length for each family
@@ -96,8 +172,16 @@ type UpdateConfig struct {
 	RecordComment      string
 	WAFListDescription string
 	WAFListItemComment string
+	IPAccessRuleNotes  string
 	DetectionTimeout   time.Duration
 	UpdateTimeout      time.Duration
+	// DryRun means the API handle only records writes; see [api.DryRunHandle].
+	DryRun bool
+	// Report means each round should produce a machine-readable report.
+	Report bool
+	// Metrics receives the measurements of the updating; nil means that
+	// nothing is recorded. It is not read from the environment.
+	Metrics metrics.Recorder
 }
 
 // DefaultRaw gives the canonical explicit defaults for updater settings before
@@ -119,9 +203,25 @@ func DefaultRaw() *RawConfig {
 		IP4DetectionFilter:              ipfilter.KeepAll(),
 		IP6DetectionFilter:              ipfilter.KeepAll(),
 		WAFLists:                        nil,
+		LBPoolOrigins:                   nil,
+		GatewayLocations:                nil,
+		AccessGroups:                    nil,
+		IPAccessRules:                   nil,
+		SpectrumApps:                    nil,
+		WAFListRule:                     api.WAFListRule{ZoneID: "", Action: ""},
+		WorkersKV:                       api.WorkersKVKey{AccountID: "", NamespaceID: "", Key: ""},
+		RFC2136:                         nil,
+		LocalResolver:                   nil,
+		NFTablesSets:                    nil,
 		UpdateCron:                      cron.MustNew("@every 5m"),
 		UpdateOnStart:                   true,
+		CheckPermissionsOnStart:         false,
 		DeleteOnStop:                    false,
+		DeleteRemovedOnReload:           false,
+		UpdateRetries:                   0,
+		DryRun:                          false,
+		JSONReport:                      "",
+		StateFile:                       "",
 		TTL:                             api.TTLAuto,
 		ProxiedExpression:               "false",
 		RecordComment:                   "",
@@ -129,6 +229,11 @@ func DefaultRaw() *RawConfig {
 		WAFListDescription:              "",
 		WAFListItemComment:              "",
 		ManagedWAFListItemsCommentRegex: "",
+		IPAccessRuleNotes:               "",
+		ManagedIPAccessRulesNotesRegex:  "",
+		ManagedAccessGroupsNameRegex:    "",
+		WAFListRuleExpression:           defaultWAFListRuleExpression,
+		WAFListRuleDescription:          defaultWAFListRuleDescription,
 		IP4DefaultPrefixLen:             32,
 		IP6DefaultPrefixLen:             64,
 		CacheExpiration:                 time.Hour * 6,
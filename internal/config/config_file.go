@@ -0,0 +1,508 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/favonia/cloudflare-ddns/internal/file"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// A fileSetting is a value read from CONFIG_FILE, together with the positions
+// of its name and its value for diagnostics.
+type fileSetting struct {
+	value  string
+	line   int // the line of the setting name
+	valPos position
+}
+
+// A position is a line and a column in CONFIG_FILE.
+type position struct {
+	line   int
+	column int
+}
+
+// A fileProfile is a named set of settings under "profiles" in CONFIG_FILE.
+type fileProfile struct {
+	name     string
+	settings map[string]fileSetting
+}
+
+// A File holds the settings read from CONFIG_FILE by [LoadConfigFile], with
+// the profile selected by [File.WithProfile], if any. The settings are
+// consulted when the environment variable of the same name is empty, so that
+// both sources go through the same parsing and validation. A nil *File stands
+// for no CONFIG_FILE, and only the environment is read.
+type File struct {
+	path     string
+	settings map[string]fileSetting
+	profiles []fileProfile
+	profile  map[string]fileSetting // the settings of the selected profile
+}
+
+const (
+	// configFileKey is the only setting that must come from the environment.
+	configFileKey = "CONFIG_FILE"
+	// profilesKey is the key in CONFIG_FILE for the named profiles.
+	profilesKey = "PROFILES"
+)
+
+// settingNames are the settings that can be set in CONFIG_FILE: the keys read by
+// ReadEnv and by the readers of the reporters and the servers, including the
+// deprecated ones. A test checks it against the keys in the readers.
+var settingNames = map[string]bool{
+	"ACCESS_GROUPS":                        true,
+	"CACHE_EXPIRATION":                     true,
+	"CF_ACCOUNT_ID":                        true,
+	"CF_API_TOKEN":                         true,
+	"CF_API_TOKEN_FILE":                    true,
+	"CHECK_PERMISSIONS_ON_START":           true,
+	"CLOUDFLARE_API_EMAIL":                 true,
+	"CLOUDFLARE_API_EMAIL_FILE":            true,
+	"CLOUDFLARE_API_KEY":                   true,
+	"CLOUDFLARE_API_KEY_FILE":              true,
+	"CLOUDFLARE_API_TOKEN":                 true,
+	"CLOUDFLARE_API_TOKEN_FILE":            true,
+	"DELETE_ON_STOP":                       true,
+	"DELETE_REMOVED_ON_RELOAD":             true,
+	"DETECTION_TIMEOUT":                    true,
+	"DOMAINS":                              true,
+	"DRY_RUN":                              true,
+	"DYNDNS2_ADDR":                         true,
+	"DYNDNS2_PASSWORD_FILE":                true,
+	"DYNDNS2_USERNAME":                     true,
+	"DYNDNS2_USE_CLIENT_ADDRESS":           true,
+	"GATEWAY_LOCATIONS":                    true,
+	"HEALTHCHECKS":                         true,
+	"HEALTH_ADDR":                          true,
+	"IP4_DEFAULT_PREFIX_LEN":               true,
+	"IP4_DETECTION_FILTER":                 true,
+	"IP4_DOMAINS":                          true,
+	"IP4_POLICY":                           true,
+	"IP4_PROVIDER":                         true,
+	"IP6_DEFAULT_PREFIX_LEN":               true,
+	"IP6_DETECTION_FILTER":                 true,
+	"IP6_DOMAINS":                          true,
+	"IP6_POLICY":                           true,
+	"IP6_PROVIDER":                         true,
+	"IP_ACCESS_RULES":                      true,
+	"IP_ACCESS_RULE_NOTES":                 true,
+	"JSON_REPORT":                          true,
+	"LB_POOL_ORIGINS":                      true,
+	"LOCAL_RESOLVER_DOMAINS":               true,
+	"LOCAL_RESOLVER_FILE":                  true,
+	"LOCAL_RESOLVER_FORMAT":                true,
+	"LOCAL_RESOLVER_IP4_PROVIDER":          true,
+	"LOCAL_RESOLVER_IP6_PROVIDER":          true,
+	"LOCAL_RESOLVER_RELOAD_COMMAND":        true,
+	"MANAGED_ACCESS_GROUPS_NAME_REGEX":     true,
+	"MANAGED_IP_ACCESS_RULES_NOTES_REGEX":  true,
+	"MANAGED_RECORDS_COMMENT_REGEX":        true,
+	"MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX": true,
+	"METRICS_ADDR":                         true,
+	"NFTABLES_SETS":                        true,
+	"PROXIED":                              true,
+	"READINESS":                            true,
+	"RECORD_COMMENT":                       true,
+	"RFC2136_SERVER":                       true,
+	"RFC2136_TSIG_KEY":                     true,
+	"SHOUTRRR":                             true,
+	"SHOUTRRR_FILE":                        true,
+	"SPECTRUM_APPS":                        true,
+	"STATE_FILE":                           true,
+	"TRIGGER_ADDR":                         true,
+	"TRIGGER_MIN_INTERVAL":                 true,
+	"TRIGGER_TOKEN_FILE":                   true,
+	"TTL":                                  true,
+	"UPDATE_CRON":                          true,
+	"UPDATE_ON_START":                      true,
+	"UPDATE_RETRIES":                       true,
+	"UPDATE_TIMEOUT":                       true,
+	"UPTIMEKUMA":                           true,
+	"WAF_LISTS":                            true,
+	"WAF_LIST_DESCRIPTION":                 true,
+	"WAF_LIST_ITEM_COMMENT":                true,
+	"WAF_LIST_RULE":                        true,
+	"WAF_LIST_RULE_DESCRIPTION":            true,
+	"WAF_LIST_RULE_EXPRESSION":             true,
+	"WORKERS_KV":                           true,
+}
+
+// setting returns the setting in CONFIG_FILE that gives the value of key, if
+// the value comes from the file: the one in the selected profile, if any;
+// otherwise the one in the rest of the file when the environment variable is
+// empty.
+func (f *File) setting(key string) (fileSetting, bool) {
+	if f == nil {
+		return fileSetting{}, false //nolint:exhaustruct
+	}
+	if setting, ok := f.profile[key]; ok {
+		return setting, true
+	}
+	if os.Getenv(key) != "" {
+		return fileSetting{}, false //nolint:exhaustruct
+	}
+	setting, ok := f.settings[key]
+	return setting, ok
+}
+
+// lookup returns the value of a setting: the value in the selected profile, if
+// any; otherwise the environment variable if it is not empty; and otherwise the
+// value from CONFIG_FILE, if any.
+func (f *File) lookup(key string) string {
+	if setting, ok := f.setting(key); ok {
+		return setting.value
+	}
+	return os.Getenv(key)
+}
+
+// Profiles returns the names of the profiles in CONFIG_FILE, in the order of
+// the file. It returns nil when there are no profiles.
+func (f *File) Profiles() []string {
+	if f == nil {
+		return nil
+	}
+	var names []string
+	for _, p := range f.profiles {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// WithProfile returns a copy of f whose settings are read from the named
+// profile. The empty name, or a name that is not in CONFIG_FILE, selects no
+// profile.
+func (f *File) WithProfile(name string) *File {
+	if f == nil {
+		return nil
+	}
+	selected := *f
+	selected.profile = nil
+	for _, p := range f.profiles {
+		if p.name == name {
+			selected.profile = p.settings
+		}
+	}
+	return &selected
+}
+
+// settingNameRegex matches the names of the settings in messages.
+var settingNameRegex = regexp.MustCompile(`\b[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)*\b`)
+
+// describeSource tells where in CONFIG_FILE the first setting mentioned by a
+// message comes from, if any.
+func (f *File) describeSource(format string, args []any) (string, bool) {
+	names := settingNameRegex.FindAllString(format, -1)
+	for _, arg := range args {
+		if name, ok := arg.(string); ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if setting, ok := f.setting(name); ok {
+			return fmt.Sprintf("%s is set in %s (line %d, column %d)", name,
+				pp.QuoteIfUnsafeInSentence(f.path), setting.valPos.line, setting.valPos.column), true
+		}
+	}
+	return "", false
+}
+
+// A sourcePP is a pretty printer adding to each notice where in CONFIG_FILE
+// the setting it mentions comes from, so that a bad value can be found.
+type sourcePP struct {
+	pp.PP
+	file *File
+}
+
+// withSources wraps a pretty printer so that the notices about the settings
+// from CONFIG_FILE tell where they are in the file.
+func (f *File) withSources(ppfmt pp.PP) pp.PP {
+	if f == nil {
+		return ppfmt
+	}
+	if s, ok := ppfmt.(sourcePP); ok {
+		ppfmt = s.PP
+	}
+	return sourcePP{PP: ppfmt, file: f}
+}
+
+func (s sourcePP) Indent() pp.PP { return sourcePP{PP: s.PP.Indent(), file: s.file} }
+
+func (s sourcePP) Noticef(emoji pp.Emoji, format string, args ...any) {
+	if source, ok := s.file.describeSource(format, args); ok {
+		format, args = format+"; %s", append(slices.Clone(args), source)
+	}
+	s.PP.Noticef(emoji, format, args...)
+}
+
+func (s sourcePP) NoticeOncef(id pp.ID, emoji pp.Emoji, format string, args ...any) {
+	if source, ok := s.file.describeSource(format, args); ok {
+		format, args = format+"; %s", append(slices.Clone(args), source)
+	}
+	s.PP.NoticeOncef(id, emoji, format, args...)
+}
+
+// listSeparator is the separator used to join a YAML list into one value.
+func listSeparator(key string) string {
+	if key == "SHOUTRRR" {
+		return "\n"
+	}
+	return ","
+}
+
+// LoadConfigFile reads the YAML file named by CONFIG_FILE, if any. The file is
+// a mapping from the names of the environment variables (in any letter case)
+// to strings, numbers, booleans, or lists of them. Lists are joined with
+// commas, except for SHOUTRRR, whose items are joined with newlines.
+//
+// The file may also have a mapping "profiles" from names to settings. The
+// settings of the profile selected by [File.WithProfile] take precedence over
+// the environment, and non-empty environment variables take precedence over the
+// rest of the file. EMOJI and QUIET are read before the file, and PUID and PGID
+// are only checked in the environment, so they are ignored in it. Unknown
+// settings, such as misspelled ones, are rejected.
+//
+// It returns nil when CONFIG_FILE is not set.
+func LoadConfigFile(ppfmt pp.PP) (*File, bool) {
+	path := strings.TrimSpace(os.Getenv(configFileKey))
+	if path == "" {
+		return nil, true
+	}
+
+	ppfmt.Infof(pp.EmojiEnvVars, "Reading settings from %s=%s", configFileKey, path)
+
+	content, ok := file.ReadRawString(ppfmt, path)
+	if !ok {
+		return nil, false
+	}
+
+	settings, profiles, ok := parseConfigFile(ppfmt, path, content)
+	if !ok {
+		return nil, false
+	}
+
+	for key, setting := range settings {
+		if os.Getenv(key) != "" {
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"%s is set in both the environment and %s (line %d); using the environment variable",
+				key, pp.QuoteIfUnsafeInSentence(path), setting.line)
+		}
+	}
+
+	f := &File{path: path, settings: settings, profiles: profiles, profile: nil}
+	if !f.checkStateFiles(ppfmt) {
+		return nil, false
+	}
+	return f, true
+}
+
+// checkStateFiles makes sure that no two profiles use the same STATE_FILE.
+// A STATE_FILE in the environment or outside of "profiles" is inherited by
+// every profile that does not set its own, and each profile would overwrite
+// the records saved by the others.
+func (f *File) checkStateFiles(ppfmt pp.PP) bool {
+	owners := map[string]string{}
+	for _, name := range f.Profiles() {
+		path := strings.TrimSpace(f.WithProfile(name).lookup("STATE_FILE"))
+		if path == "" {
+			continue
+		}
+		path = filepath.Clean(path)
+		if owner, ok := owners[path]; ok {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"The profiles %q and %q both use STATE_FILE=%s; set a different STATE_FILE in each profile",
+				owner, name, path)
+			return false
+		}
+		owners[path] = name
+	}
+	return true
+}
+
+// parseConfigFile parses the content of CONFIG_FILE.
+func parseConfigFile(ppfmt pp.PP, path, content string) (map[string]fileSetting, []fileProfile, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		ppfmt.Noticef(pp.EmojiUserError, "Failed to parse %s: %v", pp.QuoteIfUnsafeInSentence(path), err)
+		return nil, nil, false
+	}
+
+	if len(doc.Content) == 0 {
+		return map[string]fileSetting{}, nil, true // an empty file
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"%s (line %d, column %d) should be a mapping from setting names to values",
+			pp.QuoteIfUnsafeInSentence(path), root.Line, root.Column)
+		return nil, nil, false
+	}
+
+	return parseSettings(ppfmt, path, root, true)
+}
+
+// parseSettings parses a mapping from setting names to values. The mapping
+// may contain profiles only when allowProfiles is true.
+func parseSettings(ppfmt pp.PP, path string, mapping *yaml.Node, allowProfiles bool,
+) (map[string]fileSetting, []fileProfile, bool) {
+	settings := map[string]fileSetting{}
+	var profiles []fileProfile
+	profilesLine := 0
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valNode := mapping.Content[i], mapping.Content[i+1]
+		if keyNode.Kind != yaml.ScalarNode || keyNode.Value == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): the setting name should be a non-empty string",
+				pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column)
+			return nil, nil, false
+		}
+		key := strings.ToUpper(keyNode.Value)
+
+		switch key {
+		case configFileKey:
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): %s cannot be set in the configuration file",
+				pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column, key)
+			return nil, nil, false
+		case "EMOJI", "QUIET", "PUID", "PGID":
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"%s (line %d, column %d): %s is ignored in the configuration file; set it as an environment variable",
+				pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column, key)
+			continue
+		case profilesKey:
+			if !allowProfiles {
+				ppfmt.Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): profiles cannot be nested",
+					pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column)
+				return nil, nil, false
+			}
+			if profilesLine != 0 {
+				ppfmt.Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): %s was already set on line %d",
+					pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column, key, profilesLine)
+				return nil, nil, false
+			}
+			profilesLine = keyNode.Line
+
+			var ok bool
+			if profiles, ok = parseProfiles(ppfmt, path, valNode); !ok {
+				return nil, nil, false
+			}
+			continue
+		}
+
+		if !settingNames[key] {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): %s is not a known setting",
+				pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column, key)
+			return nil, nil, false
+		}
+
+		if prev, ok := settings[key]; ok {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): %s was already set on line %d",
+				pp.QuoteIfUnsafeInSentence(path), keyNode.Line, keyNode.Column, key, prev.line)
+			return nil, nil, false
+		}
+
+		val, ok := settingValue(ppfmt, path, key, valNode)
+		if !ok {
+			return nil, nil, false
+		}
+		settings[key] = fileSetting{
+			value:  val,
+			line:   keyNode.Line,
+			valPos: position{line: valNode.Line, column: valNode.Column},
+		}
+	}
+
+	return settings, profiles, true
+}
+
+// parseProfiles parses the mapping from profile names to their settings.
+func parseProfiles(ppfmt pp.PP, path string, node *yaml.Node) ([]fileProfile, bool) {
+	if node.Kind != yaml.MappingNode {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"%s (line %d, column %d): profiles should be a mapping from names to settings",
+			pp.QuoteIfUnsafeInSentence(path), node.Line, node.Column)
+		return nil, false
+	}
+
+	profiles := make([]fileProfile, 0, len(node.Content)/2)
+	lines := map[string]int{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, valNode := node.Content[i], node.Content[i+1]
+		if nameNode.Kind != yaml.ScalarNode || nameNode.Value == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): the profile name should be a non-empty string",
+				pp.QuoteIfUnsafeInSentence(path), nameNode.Line, nameNode.Column)
+			return nil, false
+		}
+		name := nameNode.Value
+		if prev, ok := lines[name]; ok {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): the profile %q was already defined on line %d",
+				pp.QuoteIfUnsafeInSentence(path), nameNode.Line, nameNode.Column, name, prev)
+			return nil, false
+		}
+		lines[name] = nameNode.Line
+
+		settings := map[string]fileSetting{}
+		switch {
+		case valNode.Kind == yaml.ScalarNode && valNode.Tag == "!!null":
+			// a profile that only uses the shared settings
+		case valNode.Kind == yaml.MappingNode:
+			var ok bool
+			if settings, _, ok = parseSettings(ppfmt, path, valNode, false); !ok {
+				return nil, false
+			}
+		default:
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s (line %d, column %d): the profile %q should be a mapping from setting names to values",
+				pp.QuoteIfUnsafeInSentence(path), valNode.Line, valNode.Column, name)
+			return nil, false
+		}
+		profiles = append(profiles, fileProfile{name: name, settings: settings})
+	}
+
+	return profiles, true
+}
+
+// settingValue turns a YAML scalar or a list of scalars into the string an
+// environment variable would hold.
+func settingValue(ppfmt pp.PP, path, key string, node *yaml.Node) (string, bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "", true
+		}
+		return node.Value, true
+
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				ppfmt.Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): the items of %s should be strings",
+					pp.QuoteIfUnsafeInSentence(path), item.Line, item.Column, key)
+				return "", false
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, listSeparator(key)), true
+
+	default:
+		ppfmt.Noticef(pp.EmojiUserError,
+			"%s (line %d, column %d): %s should be a string or a list of strings",
+			pp.QuoteIfUnsafeInSentence(path), node.Line, node.Column, key)
+		return "", false
+	}
+}
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/file"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func useConfigFile(t *testing.T, content string) {
+	t.Helper()
+	file.SetFSForTesting(fstest.MapFS{
+		"etc/ddns.yaml": &fstest.MapFile{Data: []byte(content), Mode: 0o644, ModTime: time.Unix(1234, 5678), Sys: nil},
+	})
+	t.Setenv("CONFIG_FILE", "/etc/ddns.yaml")
+	t.Cleanup(file.ResetFSForTesting)
+}
+
+//nolint:paralleltest // environment vars and file.FS are global
+func TestLoadConfigFile(t *testing.T) {
+	for name, tc := range map[string]struct {
+		content       string
+		env           map[string]string
+		ok            bool
+		settings      map[string]string
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"empty": {
+			"", nil, true, map[string]string{}, nil,
+		},
+		"scalars": {
+			"domains: a.org\nPROXIED: true\nttl: 300\nip6_provider:\n",
+			nil, true,
+			map[string]string{"DOMAINS": "a.org", "PROXIED": "true", "TTL": "300", "IP6_PROVIDER": ""},
+			nil,
+		},
+		"lists": {
+			"DOMAINS:\n  - a.org\n  - b.org\nSHOUTRRR:\n  - generic://a\n  - generic://b\n",
+			nil, true,
+			map[string]string{"DOMAINS": "a.org,b.org", "SHOUTRRR": "generic://a\ngeneric://b"},
+			nil,
+		},
+		"env-precedence": {
+			"DOMAINS: a.org\n",
+			map[string]string{"DOMAINS": "b.org"}, true,
+			map[string]string{"DOMAINS": "a.org"},
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserWarning,
+					"%s is set in both the environment and %s (line %d); using the environment variable",
+					"DOMAINS", "/etc/ddns.yaml", 1)
+			},
+		},
+		"emoji": {
+			"TTL: 1\nemoji: false\n",
+			nil, true,
+			map[string]string{"TTL": "1"},
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserWarning,
+					"%s (line %d, column %d): %s is ignored in the configuration file; set it as an environment variable",
+					"/etc/ddns.yaml", 2, 1, "EMOJI")
+			},
+		},
+		"puid": {
+			"TTL: 1\npuid: 1000\n",
+			nil, true,
+			map[string]string{"TTL": "1"},
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserWarning,
+					"%s (line %d, column %d): %s is ignored in the configuration file; set it as an environment variable",
+					"/etc/ddns.yaml", 2, 1, "PUID")
+			},
+		},
+		"misspelled": {
+			"DOMAINS: a.org\nproxyed: true\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): %s is not a known setting",
+					"/etc/ddns.yaml", 2, 1, "PROXYED")
+			},
+		},
+		"misspelled-in-profile": {
+			"profiles:\n  home:\n    domian: a.org\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): %s is not a known setting",
+					"/etc/ddns.yaml", 3, 5, "DOMIAN")
+			},
+		},
+		"syntax": {
+			"DOMAINS: [a.org\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "Failed to parse %s: %v", "/etc/ddns.yaml", gomock.Any())
+			},
+		},
+		"not-mapping": {
+			"- a.org\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d) should be a mapping from setting names to values",
+					"/etc/ddns.yaml", 1, 1)
+			},
+		},
+		"duplicate": {
+			"DOMAINS: a.org\nTTL: 1\ndomains: b.org\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): %s was already set on line %d",
+					"/etc/ddns.yaml", 3, 1, "DOMAINS", 1)
+			},
+		},
+		"config-file": {
+			"CONFIG_FILE: /etc/other.yaml\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): %s cannot be set in the configuration file",
+					"/etc/ddns.yaml", 1, 1, "CONFIG_FILE")
+			},
+		},
+		"nested": {
+			"DOMAINS:\n  a: b\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): %s should be a string or a list of strings",
+					"/etc/ddns.yaml", 2, 3, "DOMAINS")
+			},
+		},
+		"profiles-nested": {
+			"PROFILES:\n  home:\n    profiles:\n      a:\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): profiles cannot be nested",
+					"/etc/ddns.yaml", 3, 5)
+			},
+		},
+		"profiles-duplicate": {
+			"profiles:\n  home:\n  work:\n  home:\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): the profile %q was already defined on line %d",
+					"/etc/ddns.yaml", 4, 3, "home", 2)
+			},
+		},
+		"profiles-list": {
+			"profiles:\n  - home\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): profiles should be a mapping from names to settings",
+					"/etc/ddns.yaml", 2, 3)
+			},
+		},
+		"profile-scalar": {
+			"profiles:\n  home: a.org\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): the profile %q should be a mapping from setting names to values",
+					"/etc/ddns.yaml", 2, 9, "home")
+			},
+		},
+		"profiles-shared-state-file": {
+			"STATE_FILE: /var/lib/ddns/state.json\nprofiles:\n  home:\n  work:\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"The profiles %q and %q both use STATE_FILE=%s; set a different STATE_FILE in each profile",
+					"home", "work", "/var/lib/ddns/state.json")
+			},
+		},
+		"profiles-shared-state-file-env": {
+			"profiles:\n  home:\n    state_file: /var/lib/ddns/home.json\n  work:\n  lab:\n",
+			map[string]string{"STATE_FILE": "/var/lib/ddns/state.json"}, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"The profiles %q and %q both use STATE_FILE=%s; set a different STATE_FILE in each profile",
+					"work", "lab", "/var/lib/ddns/state.json")
+			},
+		},
+		"profiles-own-state-files": {
+			"STATE_FILE: /var/lib/ddns/state.json\nprofiles:\n  home:\n    state_file: /var/lib/ddns/home.json\n  work:\n",
+			nil, true,
+			map[string]string{"STATE_FILE": "/var/lib/ddns/state.json"},
+			nil,
+		},
+		"nested-item": {
+			"DOMAINS:\n  - [a.org]\n",
+			nil, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"%s (line %d, column %d): the items of %s should be strings",
+					"/etc/ddns.yaml", 2, 5, "DOMAINS")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			useConfigFile(t, tc.content)
+			for key, val := range tc.env {
+				t.Setenv(key, val)
+			}
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			mockPP.EXPECT().Infof(pp.EmojiEnvVars, "Reading settings from %s=%s", "CONFIG_FILE", "/etc/ddns.yaml")
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			f, ok := LoadConfigFile(mockPP)
+			require.Equal(t, tc.ok, ok)
+			if !tc.ok {
+				require.Nil(t, f)
+				return
+			}
+			values := map[string]string{}
+			for key, setting := range f.settings {
+				values[key] = setting.value
+			}
+			require.Equal(t, tc.settings, values)
+		})
+	}
+}
+
+//nolint:paralleltest // environment vars and file.FS are global
+func TestLookupFallsBackToConfigFile(t *testing.T) {
+	useConfigFile(t, "DOMAINS:\n  - a.org\n  - b.org\nTTL: 300\n")
+	t.Setenv("TTL", "1")
+	t.Setenv("PROXIED", "")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	gomock.InOrder(
+		mockPP.EXPECT().Infof(pp.EmojiEnvVars, "Reading settings from %s=%s", "CONFIG_FILE", "/etc/ddns.yaml"),
+		mockPP.EXPECT().Noticef(pp.EmojiUserWarning,
+			"%s is set in both the environment and %s (line %d); using the environment variable",
+			"TTL", "/etc/ddns.yaml", 4),
+	)
+	f, ok := LoadConfigFile(mockPP)
+	require.True(t, ok)
+
+	require.Equal(t, []string{"a.org", "b.org"}, f.getenvAsList("DOMAINS", ","))
+	require.Equal(t, "1", f.getenv("TTL"))
+	require.Empty(t, f.getenv("PROXIED"))
+
+	// Without CONFIG_FILE, there is no file.
+	t.Setenv("CONFIG_FILE", "")
+	f, ok = LoadConfigFile(mockPP)
+	require.True(t, ok)
+	require.Nil(t, f)
+	require.Empty(t, f.getenv("DOMAINS"))
+	require.Nil(t, f.Profiles())
+	require.Nil(t, f.WithProfile("home"))
+}
+
+//nolint:paralleltest // environment vars and file.FS are global
+func TestWithProfile(t *testing.T) {
+	useConfigFile(t, `TTL: 300
+PROXIED: true
+profiles:
+  work:
+    domains: work.org
+    ttl: 60
+  home:
+    domains:
+      - home.org
+      - www.home.org
+  empty:
+`)
+	t.Setenv("PROXIED", "false")
+	t.Setenv("DOMAINS", "env.org")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	gomock.InOrder(
+		mockPP.EXPECT().Infof(pp.EmojiEnvVars, "Reading settings from %s=%s", "CONFIG_FILE", "/etc/ddns.yaml"),
+		mockPP.EXPECT().Noticef(pp.EmojiUserWarning,
+			"%s is set in both the environment and %s (line %d); using the environment variable",
+			"PROXIED", "/etc/ddns.yaml", 2),
+	)
+	f, ok := LoadConfigFile(mockPP)
+	require.True(t, ok)
+	require.Equal(t, []string{"work", "home", "empty"}, f.Profiles())
+
+	for _, tc := range [...]struct {
+		profile string
+		domains string
+		ttl     string
+		proxied string
+	}{
+		{"", "env.org", "300", "false"},
+		{"work", "work.org", "60", "false"},
+		{"home", "home.org,www.home.org", "300", "false"},
+		{"empty", "env.org", "300", "false"},
+		{"unknown", "env.org", "300", "false"},
+	} {
+		selected := f.WithProfile(tc.profile)
+		require.Equal(t, tc.domains, selected.getenv("DOMAINS"), tc.profile)
+		require.Equal(t, tc.ttl, selected.getenv("TTL"), tc.profile)
+		require.Equal(t, tc.proxied, selected.getenv("PROXIED"), tc.profile)
+	}
+
+	// Selecting a profile does not change the file.
+	require.Equal(t, "300", f.getenv("TTL"))
+}
+
+//nolint:paralleltest // environment vars and file.FS are global
+func TestWithSources(t *testing.T) {
+	useConfigFile(t, "TTL: 2\nprofiles:\n  home:\n    update_cron: \"@hourly\"\n")
+	t.Setenv("PROXIED", "true")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	indented := mocks.NewMockPP(mockCtrl)
+	mockPP.EXPECT().Infof(pp.EmojiEnvVars, "Reading settings from %s=%s", "CONFIG_FILE", "/etc/ddns.yaml")
+	f, ok := LoadConfigFile(mockPP)
+	require.True(t, ok)
+	require.Equal(t, pp.PP(mockPP), (*File)(nil).withSources(mockPP))
+
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiUserError, "%s (%d) should be 1 (auto) or between 30 and 86400; %s",
+			"TTL", 2, `TTL is set in /etc/ddns.yaml (line 1, column 6)`),
+		mockPP.EXPECT().Noticef(pp.EmojiUserError, "PROXIED is bad"),
+		mockPP.EXPECT().Indent().Return(indented),
+		indented.EXPECT().NoticeOncef(pp.ID(0), pp.EmojiUserError, "UPDATE_CRON=%s is bad; %s",
+			"@hourly", `UPDATE_CRON is set in /etc/ddns.yaml (line 4, column 18)`),
+		mockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%d", "TTL", 2),
+	)
+	ppfmt := f.WithProfile("home").withSources(mockPP)
+	ppfmt.Noticef(pp.EmojiUserError, "%s (%d) should be 1 (auto) or between 30 and 86400", "TTL", 2)
+	ppfmt.Noticef(pp.EmojiUserError, "PROXIED is bad")
+	ppfmt.Indent().NoticeOncef(pp.ID(0), pp.EmojiUserError, "UPDATE_CRON=%s is bad", "@hourly")
+	ppfmt.Infof(pp.EmojiBullet, "Using default %s=%d", "TTL", 2)
+}
+
+// TestSettingNames checks that settingNames lists exactly the keys the readers
+// in this package use, apart from those that only come from the environment.
+func TestSettingNames(t *testing.T) {
+	t.Parallel()
+
+	envOnly := map[string]bool{
+		configFileKey: true, profilesKey: true, "EMOJI": true, "QUIET": true, "PUID": true, "PGID": true,
+	}
+
+	paths, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	used := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		require.NoError(t, err)
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.ValueSpec); ok && spec.Names[0].Name == "settingNames" {
+				return false // the list itself
+			}
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			name, err := strconv.Unquote(lit.Value)
+			if err == nil && settingNameRegex.MatchString(name) && settingNameRegex.FindString(name) == name &&
+				!envOnly[name] {
+				used[name] = true
+			}
+			return true
+		})
+	}
+
+	require.Equal(t, used, settingNames)
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/cron"
@@ -11,6 +13,7 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/notifier"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
@@ -60,6 +63,59 @@ func describeWAFListItemCommentRegex(regex string) string {
 	return describeNonemptyCommentRegex(regex)
 }
 
+func describeIPAccessRuleNotesRegex(regex string) string {
+	if regex == "" {
+		return "(empty regex; manages all IP access rules)"
+	}
+	return describeNonemptyCommentRegex(regex)
+}
+
+// describeWAFListRule shows the zone and the action of the WAF custom rule,
+// followed by its expression after the lists are filled in.
+func describeWAFListRule(rule api.WAFListRule, expression string) string {
+	if rule.ZoneID == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("%s (%s)", rule.Describe(), describeLiteralText(expression))
+}
+
+func describeWorkersKVKey(key api.WorkersKVKey) string {
+	if key.Key == "" {
+		return "(none)"
+	}
+	return key.Describe()
+}
+
+func describeJSONReport(destination string) string {
+	switch destination {
+	case "":
+		return "(none)"
+	case JSONReportStdout:
+		return "standard output"
+	default:
+		return destination
+	}
+}
+
+func describeStateFile(path string) string {
+	if path == "" {
+		return "(none)"
+	}
+	return path
+}
+
+// describeRedactedEmail keeps only the first character of the local part and
+// the domain, which is enough for operators to recognize the account without
+// copying the full address into shared logs.
+func describeRedactedEmail(email string) string {
+	local, host, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return "(redacted)"
+	}
+	first, _ := utf8.DecodeRuneInString(local)
+	return string(first) + "***@" + host
+}
+
 func computeInverseMap[V comparable](m map[domain.Domain]V) ([]V, map[V][]domain.Domain) {
 	inverse := map[V][]domain.Domain{}
 
@@ -128,6 +184,15 @@ func Print(ppfmt pp.PP, built *BuiltConfig, hb heartbeat.Heartbeat, nt notifier.
 	lifecycle := built.Lifecycle
 	update := built.Update
 
+	// The default token-based authentication is not shown; only the legacy
+	// global API key deserves a reminder in the summary. Secrets are never printed.
+	if auth, ok := handle.Auth.(*api.CloudflareGlobalKeyAuth); ok {
+		section("Authentication:")
+		item("Method:", "%s", "legacy global API key (full account access)")
+		item("Global API key:", "%s", "(redacted)")
+		item("Account email:", "%s", describeRedactedEmail(auth.Email))
+	}
+
 	section("Domains, IP providers, and WAF lists:")
 	for ipFamily, p := range ipnet.Bindings(update.Provider) {
 		if p != nil {
@@ -152,6 +217,33 @@ func Print(ppfmt pp.PP, built *BuiltConfig, hb heartbeat.Heartbeat, nt notifier.
 		}
 	}
 	item("WAF lists:", "%s", pp.JoinMap(api.WAFList.Describe, update.WAFLists))
+	item("WAF custom rule:", "%s", describeWAFListRule(update.WAFListRule, update.WAFListRuleExpression))
+	item("LB pool origins:", "%s", pp.JoinMap(api.LBPoolOrigin.Describe, update.LBPoolOrigins))
+	item("Gateway locations:", "%s", pp.JoinMap(api.GatewayLocation.Describe, update.GatewayLocations))
+	item("Access groups:", "%s", pp.JoinMap(api.AccessGroup.Describe, update.AccessGroups))
+	item("IP access rules:", "%s", pp.JoinMap(api.IPAccessRuleSet.Describe, update.IPAccessRules))
+	item("Spectrum apps:", "%s", pp.JoinMap(api.SpectrumApp.Describe, update.SpectrumApps))
+	item("Workers KV key:", "%s", describeWorkersKVKey(update.WorkersKV))
+	item("nftables sets:", "%s", pp.JoinMap(nftset.Set.Describe, update.NFTablesSets))
+
+	// The secret of the TSIG key is never printed.
+	if handle.RFC2136 != nil {
+		section("RFC 2136 mirror:")
+		item("Server:", "%s", handle.RFC2136.Server)
+		item("TSIG key:", "%s (HMAC-SHA256)", handle.RFC2136.KeyName)
+	}
+
+	if local := update.LocalResolver; local != nil {
+		section("Local resolver:")
+		item("File:", "%s (%s)", local.Sink.Path, local.Sink.Format)
+		item("Domains:", "%s", pp.JoinMap(domain.Domain.Describe, local.Domains))
+		for ipFamily, p := range ipnet.Bindings(local.Provider) {
+			item(ipFamily.Describe()+" provider:", "%s", provider.Name(p))
+		}
+		if len(local.Sink.ReloadCommand) > 0 {
+			item("Reload command:", "%s", strings.Join(local.Sink.ReloadCommand, " "))
+		}
+	}
 
 	managedRecordsCommentRegex := ""
 	if handle.Options.ManagedRecordsCommentRegex != nil {
@@ -161,25 +253,53 @@ func Print(ppfmt pp.PP, built *BuiltConfig, hb heartbeat.Heartbeat, nt notifier.
 	if handle.Options.ManagedWAFListItemsCommentRegex != nil {
 		managedWAFListItemsCommentRegex = handle.Options.ManagedWAFListItemsCommentRegex.String()
 	}
+	managedIPAccessRulesNotesRegex := ""
+	if handle.Options.ManagedIPAccessRulesNotesRegex != nil {
+		managedIPAccessRulesNotesRegex = handle.Options.ManagedIPAccessRulesNotesRegex.String()
+	}
+
+	managedAccessGroupsNameRegex := ""
+	if handle.Options.ManagedAccessGroupsNameRegex != nil {
+		managedAccessGroupsNameRegex = handle.Options.ManagedAccessGroupsNameRegex.String()
+	}
 
 	// Hide inactive filters to keep the default output focused.
-	if managedRecordsCommentRegex != "" || managedWAFListItemsCommentRegex != "" {
+	if managedRecordsCommentRegex != "" || managedWAFListItemsCommentRegex != "" ||
+		managedIPAccessRulesNotesRegex != "" || managedAccessGroupsNameRegex != "" ||
+		update.WAFListRule.ZoneID != "" {
 		section("Ownership filters:")
-		// These regexes select which DNS records and WAF list items this
-		// instance considers managed (both existing and newly created).
+		// These regexes select which DNS records, WAF list items, IP access rules,
+		// and Access groups this instance considers managed (both existing and
+		// newly created).
+		// The WAF custom rule is selected by its exact description instead.
 		if managedRecordsCommentRegex != "" {
 			item("DNS record comment regex:", "%s", describeDNSRecordCommentRegex(managedRecordsCommentRegex))
 		}
 		if managedWAFListItemsCommentRegex != "" {
 			item("WAF list item comment regex:", "%s", describeWAFListItemCommentRegex(managedWAFListItemsCommentRegex))
 		}
+		if managedIPAccessRulesNotesRegex != "" {
+			item("IP access rule notes regex:", "%s", describeIPAccessRuleNotesRegex(managedIPAccessRulesNotesRegex))
+		}
+		if managedAccessGroupsNameRegex != "" {
+			item("Access group name regex:", "%s", describeNonemptyCommentRegex(managedAccessGroupsNameRegex))
+		}
+		if update.WAFListRule.ZoneID != "" {
+			item("WAF custom rule description:", "%s", describeLiteralText(update.WAFListRuleDescription))
+		}
 	}
 
 	section("Scheduling:")
 	item("Timezone:", "%s", cron.DescribeLocation(time.Local))
 	item("Update schedule:", "%s", cron.DescribeSchedule(lifecycle.UpdateCron))
 	item("Update on start?", "%t", lifecycle.UpdateOnStart)
+	item("Check permissions on start?", "%t", lifecycle.CheckPermissionsOnStart)
 	item("Delete on stop?", "%t", lifecycle.DeleteOnStop)
+	item("Delete removed on reload?", "%t", lifecycle.DeleteRemovedOnReload)
+	item("Update retries:", "%d", lifecycle.UpdateRetries)
+	item("Dry run?", "%t", update.DryRun)
+	item("JSON report:", "%s", describeJSONReport(lifecycle.JSONReport))
+	item("State file:", "%s", describeStateFile(lifecycle.StateFile))
 	item("Cache expiration:", "%v", handle.Options.CacheExpiration)
 
 	section("DNS and WAF fallback values:")
@@ -193,6 +313,7 @@ func Print(ppfmt pp.PP, built *BuiltConfig, hb heartbeat.Heartbeat, nt notifier.
 	item("DNS record comment:", "%s", describeLiteralText(update.RecordComment))
 	item("WAF list description:", "%s", describeLiteralText(update.WAFListDescription))
 	item("WAF list item comment:", "%s", describeLiteralText(update.WAFListItemComment))
+	item("IP access rule notes:", "%s", describeLiteralText(update.IPAccessRuleNotes))
 
 	section("Timeouts:")
 	item("IP detection:", "%v", update.DetectionTimeout)
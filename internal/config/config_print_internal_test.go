@@ -104,3 +104,43 @@ func TestDescribeNonemptyCommentRegex(t *testing.T) {
 		})
 	}
 }
+
+func TestDescribeRedactedEmail(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{
+			name:  "ordinary",
+			email: "operator@example.org",
+			want:  "o***@example.org",
+		},
+		{
+			name:  "non-ascii local part",
+			email: "貓貓@example.org",
+			want:  "貓***@example.org",
+		},
+		{
+			name:  "missing at sign",
+			email: "operator",
+			want:  "(redacted)",
+		},
+		{
+			name:  "empty local part",
+			email: "@example.org",
+			want:  "(redacted)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if got := describeRedactedEmail(test.email); got != test.want {
+				t.Fatalf("describeRedactedEmail(%q) = %q, want %q", test.email, got, test.want)
+			}
+		})
+	}
+}
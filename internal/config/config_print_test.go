@@ -9,13 +9,16 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/config"
 	"github.com/favonia/cloudflare-ddns/internal/domain"
 	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipfilter"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
 	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/notifier"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
@@ -37,11 +40,17 @@ func defaultPrintedConfig(raw *config.RawConfig) *config.BuiltConfig {
 	handleConfig.Options.CacheExpiration = raw.CacheExpiration
 	handleConfig.Options.ManagedRecordsCommentRegex = regexp.MustCompile(raw.ManagedRecordsCommentRegex)
 	handleConfig.Options.ManagedWAFListItemsCommentRegex = regexp.MustCompile(raw.ManagedWAFListItemsCommentRegex)
+	handleConfig.Options.ManagedIPAccessRulesNotesRegex = regexp.MustCompile(raw.ManagedIPAccessRulesNotesRegex)
+	handleConfig.Options.ManagedAccessGroupsNameRegex = regexp.MustCompile(raw.ManagedAccessGroupsNameRegex)
+	handleConfig.RFC2136 = raw.RFC2136
 
 	lifecycleConfig := &config.LifecycleConfig{} //nolint:exhaustruct // This helper intentionally starts from the zero value and fills only the fields print tests use.
 	lifecycleConfig.UpdateCron = raw.UpdateCron
 	lifecycleConfig.UpdateOnStart = raw.UpdateOnStart
 	lifecycleConfig.DeleteOnStop = raw.DeleteOnStop
+	lifecycleConfig.DeleteRemovedOnReload = raw.DeleteRemovedOnReload
+	lifecycleConfig.UpdateRetries = raw.UpdateRetries
+	lifecycleConfig.StateFile = raw.StateFile
 
 	updateConfig := &config.UpdateConfig{} //nolint:exhaustruct // This helper intentionally starts from the zero value and fills only the fields print tests use.
 	updateConfig.Provider = map[ipnet.Family]provider.Provider{
@@ -54,11 +63,15 @@ func defaultPrintedConfig(raw *config.RawConfig) *config.BuiltConfig {
 	}
 	updateConfig.HostID6 = map[domain.Domain]hostid6.Set{}
 	updateConfig.WAFLists = raw.WAFLists
+	updateConfig.IPAccessRules = raw.IPAccessRules
+	updateConfig.LocalResolver = raw.LocalResolver
+	updateConfig.NFTablesSets = raw.NFTablesSets
 	updateConfig.TTL = raw.TTL
 	updateConfig.Proxied = map[domain.Domain]bool{}
 	updateConfig.RecordComment = raw.RecordComment
 	updateConfig.WAFListDescription = raw.WAFListDescription
 	updateConfig.WAFListItemComment = raw.WAFListItemComment
+	updateConfig.IPAccessRuleNotes = raw.IPAccessRuleNotes
 	updateConfig.DefaultPrefixLen = map[ipnet.Family]int{
 		ipnet.IP4: raw.IP4DefaultPrefixLen,
 		ipnet.IP6: raw.IP6DefaultPrefixLen,
@@ -98,11 +111,25 @@ func TestPrintDefault(t *testing.T) {
 		printItem(t, innerMockPP, "IPv6 provider:", "cloudflare.trace"),
 		printItem(t, innerMockPP, "IPv6 default prefix length:", "/64"),
 		printItem(t, innerMockPP, "WAF lists:", "(none)"),
+		printItem(t, innerMockPP, "WAF custom rule:", "(none)"),
+		printItem(t, innerMockPP, "LB pool origins:", "(none)"),
+		printItem(t, innerMockPP, "Gateway locations:", "(none)"),
+		printItem(t, innerMockPP, "Access groups:", "(none)"),
+		printItem(t, innerMockPP, "IP access rules:", "(none)"),
+		printItem(t, innerMockPP, "Spectrum apps:", "(none)"),
+		printItem(t, innerMockPP, "Workers KV key:", "(none)"),
+		printItem(t, innerMockPP, "nftables sets:", "(none)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Scheduling:"),
 		printItem(t, innerMockPP, "Timezone:", gomock.AnyOf("UTC (currently UTC+00)", "Local (currently UTC+00)")),
 		printItem(t, innerMockPP, "Update schedule:", "@every 5m"),
 		printItem(t, innerMockPP, "Update on start?", "true"),
+		printItem(t, innerMockPP, "Check permissions on start?", "false"),
 		printItem(t, innerMockPP, "Delete on stop?", "false"),
+		printItem(t, innerMockPP, "Delete removed on reload?", "false"),
+		printItem(t, innerMockPP, "Update retries:", "0"),
+		printItem(t, innerMockPP, "Dry run?", "false"),
+		printItem(t, innerMockPP, "JSON report:", "(none)"),
+		printItem(t, innerMockPP, "State file:", "(none)"),
 		printItem(t, innerMockPP, "Cache expiration:", "6h0m0s"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "DNS and WAF fallback values:"),
 		printItem(t, innerMockPP, "TTL:", "1 (auto)"),
@@ -111,6 +138,7 @@ func TestPrintDefault(t *testing.T) {
 		printItem(t, innerMockPP, "DNS record comment:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list description:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list item comment:", "(empty)"),
+		printItem(t, innerMockPP, "IP access rule notes:", "(empty)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Timeouts:"),
 		printItem(t, innerMockPP, "IP detection:", "5s"),
 		printItem(t, innerMockPP, "Record/list updating:", "30s"),
@@ -147,14 +175,30 @@ func TestPrintValues(t *testing.T) {
 		printSubItem(t, subInnerMockPP, "::1", "test6.org"),
 		printSubItem(t, subInnerMockPP, "mac(00-11-22-33-44-55)", "test6.org"),
 		printItem(t, innerMockPP, "WAF lists:", "(none)"),
+		printItem(t, innerMockPP, "WAF custom rule:", "(none)"),
+		printItem(t, innerMockPP, "LB pool origins:", "(none)"),
+		printItem(t, innerMockPP, "Gateway locations:", "(none)"),
+		printItem(t, innerMockPP, "Access groups:", "(none)"),
+		printItem(t, innerMockPP, "IP access rules:", "zone/zone123:block"),
+		printItem(t, innerMockPP, "Spectrum apps:", "(none)"),
+		printItem(t, innerMockPP, "Workers KV key:", "(none)"),
+		printItem(t, innerMockPP, "nftables sets:", "(none)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Ownership filters:"),
 		printItem(t, innerMockPP, "DNS record comment regex:", "^Created by Cloudflare DDNS$"),
 		printItem(t, innerMockPP, "WAF list item comment regex:", "^managed-waf-item$"),
+		printItem(t, innerMockPP, "IP access rule notes regex:", "^ddns$"),
+		printItem(t, innerMockPP, "Access group name regex:", "^ddns-"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Scheduling:"),
 		printItem(t, innerMockPP, "Timezone:", gomock.AnyOf("UTC (currently UTC+00)", "Local (currently UTC+00)")),
 		printItem(t, innerMockPP, "Update schedule:", "@every 5m"),
 		printItem(t, innerMockPP, "Update on start?", "true"),
+		printItem(t, innerMockPP, "Check permissions on start?", "false"),
 		printItem(t, innerMockPP, "Delete on stop?", "false"),
+		printItem(t, innerMockPP, "Delete removed on reload?", "false"),
+		printItem(t, innerMockPP, "Update retries:", "0"),
+		printItem(t, innerMockPP, "Dry run?", "false"),
+		printItem(t, innerMockPP, "JSON report:", "(none)"),
+		printItem(t, innerMockPP, "State file:", "(none)"),
 		printItem(t, innerMockPP, "Cache expiration:", "6h0m0s"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "DNS and WAF fallback values:"),
 		printItem(t, innerMockPP, "TTL:", "30000"),
@@ -163,6 +207,7 @@ func TestPrintValues(t *testing.T) {
 		printItem(t, innerMockPP, "DNS record comment:", "\"Created by Cloudflare DDNS\""),
 		printItem(t, innerMockPP, "WAF list description:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list item comment:", "\"managed-waf-item\""),
+		printItem(t, innerMockPP, "IP access rule notes:", "\"ddns\""),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Timeouts:"),
 		printItem(t, innerMockPP, "IP detection:", "5s"),
 		printItem(t, innerMockPP, "Record/list updating:", "30s"),
@@ -177,6 +222,12 @@ func TestPrintValues(t *testing.T) {
 	raw.ManagedRecordsCommentRegex = "^Created by Cloudflare DDNS$"
 	raw.WAFListItemComment = "managed-waf-item"
 	raw.ManagedWAFListItemsCommentRegex = "^managed-waf-item$"
+	raw.IPAccessRules = []api.IPAccessRuleSet{
+		{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone123", Mode: api.IPAccessRuleModeBlock},
+	}
+	raw.IPAccessRuleNotes = "ddns"
+	raw.ManagedIPAccessRulesNotesRegex = "^ddns$"
+	raw.ManagedAccessGroupsNameRegex = "^ddns-"
 
 	builtConfig := defaultPrintedConfig(raw)
 	builtConfig.Update.Domains[ipnet.IP4] = []domain.Domain{domain.FQDN("test4.org"), domain.Wildcard("test4.org")}
@@ -225,6 +276,117 @@ func TestPrintShowsNonDefaultDetectionFilters(t *testing.T) {
 	require.NotContains(t, output.String(), "IPv6 detection filter:")
 }
 
+//nolint:paralleltest // changing the environment variable TZ
+func TestPrintGlobalKeyAuthIsRedacted(t *testing.T) {
+	store(t, "TZ", "UTC")
+
+	raw := config.DefaultRaw()
+	raw.Auth = &api.CloudflareGlobalKeyAuth{
+		Key:     "0123456789abcdef0123456789abcdef01234",
+		Email:   "operator@example.org",
+		BaseURL: "",
+	}
+	builtConfig := defaultPrintedConfig(raw)
+
+	var output bytes.Buffer
+	config.Print(pp.New(&output, false, pp.Info), builtConfig, heartbeat.NewComposed(), notifier.NewComposed())
+
+	require.Contains(t, output.String(), "Authentication:")
+	require.Contains(t, output.String(), "legacy global API key (full account access)")
+	require.Contains(t, output.String(), "o***@example.org")
+	require.NotContains(t, output.String(), "0123456789abcdef0123456789abcdef01234")
+	require.NotContains(t, output.String(), "operator@example.org")
+}
+
+//nolint:paralleltest // changing the environment variable TZ
+func TestPrintRFC2136SecretIsRedacted(t *testing.T) {
+	store(t, "TZ", "UTC")
+
+	raw := config.DefaultRaw()
+	raw.RFC2136 = &api.RFC2136Auth{Server: "ns.example.org:53", KeyName: "ddns.", Secret: []byte("top-secret")}
+	builtConfig := defaultPrintedConfig(raw)
+
+	var output bytes.Buffer
+	config.Print(pp.New(&output, false, pp.Info), builtConfig, heartbeat.NewComposed(), notifier.NewComposed())
+
+	require.Contains(t, output.String(), "RFC 2136 mirror:")
+	require.Contains(t, output.String(), "ns.example.org:53")
+	require.Contains(t, output.String(), "ddns. (HMAC-SHA256)")
+	require.NotContains(t, output.String(), "top-secret")
+}
+
+//nolint:paralleltest // changing the environment variable TZ
+func TestPrintLocalResolver(t *testing.T) {
+	store(t, "TZ", "UTC")
+
+	raw := config.DefaultRaw()
+	raw.LocalResolver = &config.LocalResolverConfig{
+		Sink: localdns.Sink{
+			Path: "/etc/dnsmasq.d/ddns.conf", Format: localdns.FormatDnsmasq,
+			ReloadCommand: []string{"systemctl", "reload", "dnsmasq"},
+		},
+		Provider: map[ipnet.Family]provider.Provider{ipnet.IP6: provider.MustNewLocalWithInterface("eth0")},
+		Domains:  []domain.Domain{domain.FQDN("a.example.org"), domain.Wildcard("lab.example.org")},
+	}
+	builtConfig := defaultPrintedConfig(raw)
+
+	var output bytes.Buffer
+	config.Print(pp.New(&output, false, pp.Info), builtConfig, heartbeat.NewComposed(), notifier.NewComposed())
+
+	require.Contains(t, output.String(), "Local resolver:")
+	require.Contains(t, output.String(), "/etc/dnsmasq.d/ddns.conf (dnsmasq)")
+	require.Contains(t, output.String(), "a.example.org, *.lab.example.org")
+	require.Contains(t, output.String(), "local.iface:eth0")
+	require.Contains(t, output.String(), "systemctl reload dnsmasq")
+}
+
+//nolint:paralleltest // changing the environment variable TZ
+func TestPrintNFTablesSets(t *testing.T) {
+	store(t, "TZ", "UTC")
+
+	raw := config.DefaultRaw()
+	raw.NFTablesSets = []nftset.Set{
+		{Family: "inet", Table: "filter", Name: "home_prefixes"},
+		{Family: "ip6", Table: "fw", Name: "lan"},
+	}
+	builtConfig := defaultPrintedConfig(raw)
+
+	var output bytes.Buffer
+	config.Print(pp.New(&output, false, pp.Info), builtConfig, heartbeat.NewComposed(), notifier.NewComposed())
+
+	require.Contains(t, output.String(), "nftables sets:")
+	require.Contains(t, output.String(), "inet filter home_prefixes, ip6 fw lan")
+}
+
+//nolint:paralleltest // changing the environment variable TZ
+func TestPrintStateFile(t *testing.T) {
+	store(t, "TZ", "UTC")
+
+	raw := config.DefaultRaw()
+	raw.StateFile = "/data/state.json"
+	builtConfig := defaultPrintedConfig(raw)
+
+	var output bytes.Buffer
+	config.Print(pp.New(&output, false, pp.Info), builtConfig, heartbeat.NewComposed(), notifier.NewComposed())
+
+	require.Contains(t, output.String(), "State file:")
+	require.Contains(t, output.String(), "/data/state.json")
+}
+
+//nolint:paralleltest // changing the environment variable TZ
+func TestPrintUpdateRetries(t *testing.T) {
+	store(t, "TZ", "UTC")
+
+	raw := config.DefaultRaw()
+	raw.UpdateRetries = 3
+	builtConfig := defaultPrintedConfig(raw)
+
+	var output bytes.Buffer
+	config.Print(pp.New(&output, false, pp.Info), builtConfig, heartbeat.NewComposed(), notifier.NewComposed())
+
+	require.Regexp(t, `Update retries:\s+3\n`, output.String())
+}
+
 //nolint:paralleltest // changing the environment variable TZ
 func TestPrintCommentRegexQuotedWhenNeeded(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
@@ -246,6 +408,14 @@ func TestPrintCommentRegexQuotedWhenNeeded(t *testing.T) {
 		printItem(t, innerMockPP, "IPv6 provider:", "cloudflare.trace"),
 		printItem(t, innerMockPP, "IPv6 default prefix length:", "/64"),
 		printItem(t, innerMockPP, "WAF lists:", "(none)"),
+		printItem(t, innerMockPP, "WAF custom rule:", "(none)"),
+		printItem(t, innerMockPP, "LB pool origins:", "(none)"),
+		printItem(t, innerMockPP, "Gateway locations:", "(none)"),
+		printItem(t, innerMockPP, "Access groups:", "(none)"),
+		printItem(t, innerMockPP, "IP access rules:", "(none)"),
+		printItem(t, innerMockPP, "Spectrum apps:", "(none)"),
+		printItem(t, innerMockPP, "Workers KV key:", "(none)"),
+		printItem(t, innerMockPP, "nftables sets:", "(none)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Ownership filters:"),
 		printItem(t, innerMockPP, "DNS record comment regex:", "\"^Created by\\tCloudflare DDNS$\""),
 		printItem(t, innerMockPP, "WAF list item comment regex:", "\"^managed\\twaf$\""),
@@ -253,7 +423,13 @@ func TestPrintCommentRegexQuotedWhenNeeded(t *testing.T) {
 		printItem(t, innerMockPP, "Timezone:", gomock.AnyOf("UTC (currently UTC+00)", "Local (currently UTC+00)")),
 		printItem(t, innerMockPP, "Update schedule:", "@every 5m"),
 		printItem(t, innerMockPP, "Update on start?", "true"),
+		printItem(t, innerMockPP, "Check permissions on start?", "false"),
 		printItem(t, innerMockPP, "Delete on stop?", "false"),
+		printItem(t, innerMockPP, "Delete removed on reload?", "false"),
+		printItem(t, innerMockPP, "Update retries:", "0"),
+		printItem(t, innerMockPP, "Dry run?", "false"),
+		printItem(t, innerMockPP, "JSON report:", "(none)"),
+		printItem(t, innerMockPP, "State file:", "(none)"),
 		printItem(t, innerMockPP, "Cache expiration:", "6h0m0s"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "DNS and WAF fallback values:"),
 		printItem(t, innerMockPP, "TTL:", "1 (auto)"),
@@ -262,6 +438,7 @@ func TestPrintCommentRegexQuotedWhenNeeded(t *testing.T) {
 		printItem(t, innerMockPP, "DNS record comment:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list description:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list item comment:", "(empty)"),
+		printItem(t, innerMockPP, "IP access rule notes:", "(empty)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Timeouts:"),
 		printItem(t, innerMockPP, "IP detection:", "5s"),
 		printItem(t, innerMockPP, "Record/list updating:", "30s"),
@@ -290,11 +467,25 @@ func TestPrintEmpty(t *testing.T) {
 		mockPP.EXPECT().Indent().Return(innerMockPP),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Domains, IP providers, and WAF lists:"),
 		printItem(t, innerMockPP, "WAF lists:", "(none)"),
+		printItem(t, innerMockPP, "WAF custom rule:", "(none)"),
+		printItem(t, innerMockPP, "LB pool origins:", "(none)"),
+		printItem(t, innerMockPP, "Gateway locations:", "(none)"),
+		printItem(t, innerMockPP, "Access groups:", "(none)"),
+		printItem(t, innerMockPP, "IP access rules:", "(none)"),
+		printItem(t, innerMockPP, "Spectrum apps:", "(none)"),
+		printItem(t, innerMockPP, "Workers KV key:", "(none)"),
+		printItem(t, innerMockPP, "nftables sets:", "(none)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Scheduling:"),
 		printItem(t, innerMockPP, "Timezone:", gomock.AnyOf("UTC (currently UTC+00)", "Local (currently UTC+00)")),
 		printItem(t, innerMockPP, "Update schedule:", "@once"),
 		printItem(t, innerMockPP, "Update on start?", "false"),
+		printItem(t, innerMockPP, "Check permissions on start?", "false"),
 		printItem(t, innerMockPP, "Delete on stop?", "false"),
+		printItem(t, innerMockPP, "Delete removed on reload?", "false"),
+		printItem(t, innerMockPP, "Update retries:", "0"),
+		printItem(t, innerMockPP, "Dry run?", "false"),
+		printItem(t, innerMockPP, "JSON report:", "(none)"),
+		printItem(t, innerMockPP, "State file:", "(none)"),
 		printItem(t, innerMockPP, "Cache expiration:", "0s"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "DNS and WAF fallback values:"),
 		printItem(t, innerMockPP, "TTL:", "0"),
@@ -303,6 +494,7 @@ func TestPrintEmpty(t *testing.T) {
 		printItem(t, innerMockPP, "DNS record comment:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list description:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list item comment:", "(empty)"),
+		printItem(t, innerMockPP, "IP access rule notes:", "(empty)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Timeouts:"),
 		printItem(t, innerMockPP, "IP detection:", "0s"),
 		printItem(t, innerMockPP, "Record/list updating:", "0s"),
@@ -333,11 +525,25 @@ func TestPrintSkipsHostID6SummaryWhenIPv6Disabled(t *testing.T) {
 		printItem(t, innerMockPP, "IPv4 provider:", "cloudflare.trace"),
 		printItem(t, innerMockPP, "IPv4 default prefix length:", "/32"),
 		printItem(t, innerMockPP, "WAF lists:", "(none)"),
+		printItem(t, innerMockPP, "WAF custom rule:", "(none)"),
+		printItem(t, innerMockPP, "LB pool origins:", "(none)"),
+		printItem(t, innerMockPP, "Gateway locations:", "(none)"),
+		printItem(t, innerMockPP, "Access groups:", "(none)"),
+		printItem(t, innerMockPP, "IP access rules:", "(none)"),
+		printItem(t, innerMockPP, "Spectrum apps:", "(none)"),
+		printItem(t, innerMockPP, "Workers KV key:", "(none)"),
+		printItem(t, innerMockPP, "nftables sets:", "(none)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Scheduling:"),
 		printItem(t, innerMockPP, "Timezone:", gomock.AnyOf("UTC (currently UTC+00)", "Local (currently UTC+00)")),
 		printItem(t, innerMockPP, "Update schedule:", "@every 5m"),
 		printItem(t, innerMockPP, "Update on start?", "true"),
+		printItem(t, innerMockPP, "Check permissions on start?", "false"),
 		printItem(t, innerMockPP, "Delete on stop?", "false"),
+		printItem(t, innerMockPP, "Delete removed on reload?", "false"),
+		printItem(t, innerMockPP, "Update retries:", "0"),
+		printItem(t, innerMockPP, "Dry run?", "false"),
+		printItem(t, innerMockPP, "JSON report:", "(none)"),
+		printItem(t, innerMockPP, "State file:", "(none)"),
 		printItem(t, innerMockPP, "Cache expiration:", "6h0m0s"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "DNS and WAF fallback values:"),
 		printItem(t, innerMockPP, "TTL:", "1 (auto)"),
@@ -346,6 +552,7 @@ func TestPrintSkipsHostID6SummaryWhenIPv6Disabled(t *testing.T) {
 		printItem(t, innerMockPP, "DNS record comment:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list description:", "(empty)"),
 		printItem(t, innerMockPP, "WAF list item comment:", "(empty)"),
+		printItem(t, innerMockPP, "IP access rule notes:", "(empty)"),
 		mockPP.EXPECT().Infof(pp.EmojiConfig, "%s", "Timeouts:"),
 		printItem(t, innerMockPP, "IP detection:", "5s"),
 		printItem(t, innerMockPP, "Record/list updating:", "30s"),
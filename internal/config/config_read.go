@@ -4,6 +4,7 @@ package config
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/domain"
@@ -11,6 +12,7 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipfilter"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
 )
@@ -31,44 +33,80 @@ func previewSettingValue(value string) string {
 //
 // This method overlays environment values onto the existing [RawConfig]. Callers
 // that want the standard updater defaults must start from [DefaultRaw] before
-// calling [ReadEnv].
-func (c *RawConfig) ReadEnv(ppfmt pp.PP) bool {
+// calling [ReadEnv]. The settings missing from the environment are read from f,
+// the file loaded by [LoadConfigFile], and the errors about them tell where
+// they are in the file.
+func (c *RawConfig) ReadEnv(ppfmt pp.PP, f *File) bool {
+	ppfmt = f.withSources(ppfmt)
 	if ppfmt.IsShowing(pp.Info) {
 		ppfmt.Infof(pp.EmojiEnvVars, "Reading settings . . .")
 		ppfmt = ppfmt.Indent()
 	}
-	if !readAuth(ppfmt, &c.Auth) ||
-		!readPrefixLen(ppfmt, "IP4_DEFAULT_PREFIX_LEN", &c.IP4DefaultPrefixLen, ipnet.IP4) ||
-		!readPrefixLen(ppfmt, "IP6_DEFAULT_PREFIX_LEN", &c.IP6DefaultPrefixLen, ipnet.IP6) ||
-		!readProviderMap(ppfmt, map[ipnet.Family]int{
+	if !readAuth(ppfmt, f, &c.Auth) ||
+		!readPrefixLen(ppfmt, f, "IP4_DEFAULT_PREFIX_LEN", &c.IP4DefaultPrefixLen, ipnet.IP4) ||
+		!readPrefixLen(ppfmt, f, "IP6_DEFAULT_PREFIX_LEN", &c.IP6DefaultPrefixLen, ipnet.IP6) ||
+		!readProviderMap(ppfmt, f, map[ipnet.Family]int{
 			ipnet.IP4: c.IP4DefaultPrefixLen,
 			ipnet.IP6: c.IP6DefaultPrefixLen,
 		}, &c.Provider) ||
-		!readDetectionFilter(ppfmt, "IP4_DETECTION_FILTER", ipnet.IP4, &c.IP4DetectionFilter) ||
-		!readDetectionFilter(ppfmt, "IP6_DETECTION_FILTER", ipnet.IP6, &c.IP6DetectionFilter) ||
-		!readDomains(ppfmt, "DOMAINS", nil, &c.Domains) ||
-		!readDomains(ppfmt, "IP4_DOMAINS", new(ipnet.IP4), &c.IP4Domains) ||
-		!readDomains(ppfmt, "IP6_DOMAINS", new(ipnet.IP6), &c.IP6Domains) ||
-		!readWAFListNames(ppfmt, "WAF_LISTS", &c.WAFLists) ||
-		!readCron(ppfmt, "UPDATE_CRON", &c.UpdateCron) ||
-		!readBool(ppfmt, "UPDATE_ON_START", &c.UpdateOnStart) ||
-		!readBool(ppfmt, "DELETE_ON_STOP", &c.DeleteOnStop) ||
-		!readNonnegDuration(ppfmt, "CACHE_EXPIRATION", &c.CacheExpiration) ||
-		!readTTL(ppfmt, "TTL", &c.TTL) ||
-		!readString(ppfmt, "PROXIED", &c.ProxiedExpression) ||
-		!readString(ppfmt, "RECORD_COMMENT", &c.RecordComment) ||
-		!readString(ppfmt, "MANAGED_RECORDS_COMMENT_REGEX", &c.ManagedRecordsCommentRegex) ||
-		!readString(ppfmt, "WAF_LIST_DESCRIPTION", &c.WAFListDescription) ||
-		!readString(ppfmt, "WAF_LIST_ITEM_COMMENT", &c.WAFListItemComment) ||
-		!readString(ppfmt, "MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX", &c.ManagedWAFListItemsCommentRegex) ||
-		!readNonnegDuration(ppfmt, "DETECTION_TIMEOUT", &c.DetectionTimeout) ||
-		!readNonnegDuration(ppfmt, "UPDATE_TIMEOUT", &c.UpdateTimeout) {
+		!readDetectionFilter(ppfmt, f, "IP4_DETECTION_FILTER", ipnet.IP4, &c.IP4DetectionFilter) ||
+		!readDetectionFilter(ppfmt, f, "IP6_DETECTION_FILTER", ipnet.IP6, &c.IP6DetectionFilter) ||
+		!readDomains(ppfmt, f, "DOMAINS", nil, &c.Domains) ||
+		!readDomains(ppfmt, f, "IP4_DOMAINS", new(ipnet.IP4), &c.IP4Domains) ||
+		!readDomains(ppfmt, f, "IP6_DOMAINS", new(ipnet.IP6), &c.IP6Domains) ||
+		!readWAFListNames(ppfmt, f, "WAF_LISTS", &c.WAFLists) ||
+		!readLBPoolOrigins(ppfmt, f, "LB_POOL_ORIGINS", &c.LBPoolOrigins) ||
+		!readGatewayLocations(ppfmt, f, "GATEWAY_LOCATIONS", &c.GatewayLocations) ||
+		!readAccessGroups(ppfmt, f, "ACCESS_GROUPS", &c.AccessGroups) ||
+		!readIPAccessRules(ppfmt, f, "IP_ACCESS_RULES", &c.IPAccessRules) ||
+		!readSpectrumApps(ppfmt, f, "SPECTRUM_APPS", &c.SpectrumApps) ||
+		!readWAFListRule(ppfmt, f, "WAF_LIST_RULE", &c.WAFListRule) ||
+		!readWorkersKV(ppfmt, f, "WORKERS_KV", &c.WorkersKV) ||
+		!readRFC2136(ppfmt, f, "RFC2136_SERVER", "RFC2136_TSIG_KEY", &c.RFC2136) ||
+		!readLocalResolver(ppfmt, f, map[ipnet.Family]int{
+			ipnet.IP4: c.IP4DefaultPrefixLen,
+			ipnet.IP6: c.IP6DefaultPrefixLen,
+		}, &c.LocalResolver) ||
+		!readNFTablesSets(ppfmt, f, "NFTABLES_SETS", &c.NFTablesSets) ||
+		!readCron(ppfmt, f, "UPDATE_CRON", &c.UpdateCron) ||
+		!readBool(ppfmt, f, "UPDATE_ON_START", &c.UpdateOnStart) ||
+		!readBool(ppfmt, f, "CHECK_PERMISSIONS_ON_START", &c.CheckPermissionsOnStart) ||
+		!readBool(ppfmt, f, "DELETE_ON_STOP", &c.DeleteOnStop) ||
+		!readBool(ppfmt, f, "DELETE_REMOVED_ON_RELOAD", &c.DeleteRemovedOnReload) ||
+		!readNonnegInt(ppfmt, f, "UPDATE_RETRIES", &c.UpdateRetries) ||
+		!readBool(ppfmt, f, "DRY_RUN", &c.DryRun) ||
+		!readString(ppfmt, f, "JSON_REPORT", &c.JSONReport) ||
+		!readAbsolutePath(ppfmt, f, "STATE_FILE", &c.StateFile) ||
+		!readNonnegDuration(ppfmt, f, "CACHE_EXPIRATION", &c.CacheExpiration) ||
+		!readTTL(ppfmt, f, "TTL", &c.TTL) ||
+		!readString(ppfmt, f, "PROXIED", &c.ProxiedExpression) ||
+		!readString(ppfmt, f, "RECORD_COMMENT", &c.RecordComment) ||
+		!readString(ppfmt, f, "MANAGED_RECORDS_COMMENT_REGEX", &c.ManagedRecordsCommentRegex) ||
+		!readString(ppfmt, f, "WAF_LIST_DESCRIPTION", &c.WAFListDescription) ||
+		!readString(ppfmt, f, "WAF_LIST_ITEM_COMMENT", &c.WAFListItemComment) ||
+		!readString(ppfmt, f, "MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX", &c.ManagedWAFListItemsCommentRegex) ||
+		!readString(ppfmt, f, "IP_ACCESS_RULE_NOTES", &c.IPAccessRuleNotes) ||
+		!readString(ppfmt, f, "MANAGED_IP_ACCESS_RULES_NOTES_REGEX", &c.ManagedIPAccessRulesNotesRegex) ||
+		!readString(ppfmt, f, "MANAGED_ACCESS_GROUPS_NAME_REGEX", &c.ManagedAccessGroupsNameRegex) ||
+		!readString(ppfmt, f, "WAF_LIST_RULE_EXPRESSION", &c.WAFListRuleExpression) ||
+		!readString(ppfmt, f, "WAF_LIST_RULE_DESCRIPTION", &c.WAFListRuleDescription) ||
+		!readNonnegDuration(ppfmt, f, "DETECTION_TIMEOUT", &c.DetectionTimeout) ||
+		!readNonnegDuration(ppfmt, f, "UPDATE_TIMEOUT", &c.UpdateTimeout) {
 		return false
 	}
 
 	return true
 }
 
+// hasResourceTargets reports whether any target other than DNS records is configured.
+// Such targets use all managed IP families: the IP family of an origin, for
+// example, is only known after reading its pool.
+func (c *RawConfig) hasResourceTargets() bool {
+	return len(c.WAFLists) > 0 || len(c.LBPoolOrigins) > 0 || len(c.GatewayLocations) > 0 ||
+		len(c.AccessGroups) > 0 || len(c.IPAccessRules) > 0 || len(c.SpectrumApps) > 0 ||
+		c.WorkersKV.Key != "" || len(c.NFTablesSets) > 0
+}
+
 // BuildConfig checks and derives configuration invariants, including:
 // - provider and domain canonicalization
 // - [HandleConfig.Options]'s managed-record selector compilation
@@ -92,8 +130,10 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 	domains := normalized.ByFamily
 
 	// Check 1: is there anything to do? {{{
-	if len(domains[ipnet.IP4]) == 0 && len(domains[ipnet.IP6]) == 0 && len(c.WAFLists) == 0 {
-		ppfmt.Noticef(pp.EmojiUserError, "Nothing was specified in DOMAINS, IP4_DOMAINS, IP6_DOMAINS, or WAF_LISTS")
+	if len(domains[ipnet.IP4]) == 0 && len(domains[ipnet.IP6]) == 0 && !c.hasResourceTargets() {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"Nothing was specified in DOMAINS, IP4_DOMAINS, IP6_DOMAINS, WAF_LISTS, LB_POOL_ORIGINS, "+
+				"GATEWAY_LOCATIONS, ACCESS_GROUPS, IP_ACCESS_RULES, or SPECTRUM_APPS")
 		return nil, false
 	}
 	if c.UpdateCron == nil && !c.UpdateOnStart {
@@ -110,7 +150,7 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 		if p != nil {
 			domainsForFamily := domains[ipFamily]
 
-			if len(domainsForFamily) == 0 && len(c.WAFLists) == 0 {
+			if len(domainsForFamily) == 0 && !c.hasResourceTargets() {
 				ppfmt.Noticef(pp.EmojiUserWarning,
 					"IP%d_PROVIDER (%s) is ignored because no domains or WAF lists use %s",
 					ipFamily.Int(), previewSettingValue(provider.Name(p)), ipFamily.Describe())
@@ -214,6 +254,15 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 				c.RecordComment, c.ManagedRecordsCommentRegex)
 			return nil, false
 		}
+		// Records on the RFC 2136 server have no comments, so the selector
+		// must match the empty comment to apply the same ownership rules there.
+		if c.RFC2136 != nil && !regex.MatchString("") {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"MANAGED_RECORDS_COMMENT_REGEX=%q does not match the empty comment, "+
+					"but DNS records on the RFC 2136 server %s have no comments",
+				c.ManagedRecordsCommentRegex, c.RFC2136.Server)
+			return nil, false
+		}
 		managedRecordsCommentRegex = regex
 	}
 	// MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX
@@ -236,6 +285,63 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 		managedWAFListItemsCommentRegex = regex
 		allowWholeWAFListDeleteOnShutdown = regex.String() == ""
 	}
+	// MANAGED_IP_ACCESS_RULES_NOTES_REGEX
+	managedIPAccessRulesNotesRegex := regexp.MustCompile("")
+	if len(c.IPAccessRules) > 0 {
+		regex, err := regexp.Compile(c.ManagedIPAccessRulesNotesRegex)
+		if err != nil {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"MANAGED_IP_ACCESS_RULES_NOTES_REGEX=%q is invalid: %v",
+				c.ManagedIPAccessRulesNotesRegex, err)
+			return nil, false
+		}
+		if !regex.MatchString(c.IPAccessRuleNotes) {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"IP_ACCESS_RULE_NOTES=%q does not match MANAGED_IP_ACCESS_RULES_NOTES_REGEX=%q",
+				c.IPAccessRuleNotes, c.ManagedIPAccessRulesNotesRegex)
+			return nil, false
+		}
+		managedIPAccessRulesNotesRegex = regex
+	}
+	// MANAGED_ACCESS_GROUPS_NAME_REGEX
+	// Access rules cannot carry comments, so the name of the group is the only
+	// ownership marker. Unlike the other selectors, it must be set explicitly.
+	managedAccessGroupsNameRegex := regexp.MustCompile("")
+	if len(c.AccessGroups) > 0 {
+		if c.ManagedAccessGroupsNameRegex == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"ACCESS_GROUPS requires MANAGED_ACCESS_GROUPS_NAME_REGEX to mark the Access groups managed by the updater")
+			return nil, false
+		}
+		regex, err := regexp.Compile(c.ManagedAccessGroupsNameRegex)
+		if err != nil {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"MANAGED_ACCESS_GROUPS_NAME_REGEX=%q is invalid: %v",
+				c.ManagedAccessGroupsNameRegex, err)
+			return nil, false
+		}
+		managedAccessGroupsNameRegex = regex
+	}
+	// WAF_LIST_RULE
+	wafListRuleExpression := ""
+	if c.WAFListRule.ZoneID != "" {
+		if len(c.WAFLists) == 0 {
+			ppfmt.Noticef(pp.EmojiUserError, "WAF_LIST_RULE (%s) requires WAF_LISTS to be set",
+				previewSettingValue(c.WAFListRule.Describe()))
+			return nil, false
+		}
+		if !strings.Contains(c.WAFListRuleExpression, wafListRulePlaceholder) {
+			ppfmt.Noticef(pp.EmojiUserError, "WAF_LIST_RULE_EXPRESSION=%q does not contain %q",
+				c.WAFListRuleExpression, wafListRulePlaceholder)
+			return nil, false
+		}
+		if c.WAFListRuleDescription == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"WAF_LIST_RULE_DESCRIPTION must not be empty because it identifies the custom rule managed by the updater")
+			return nil, false
+		}
+		wafListRuleExpression = instantiateWAFListRuleExpression(c.WAFListRuleExpression, c.WAFLists)
+	}
 	// }}}
 
 	// Check 4: are DNS and WAF's shared ownership settings suspicious? {{{
@@ -294,6 +400,10 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 				"MANAGED_RECORDS_COMMENT_REGEX (%s) is ignored because no domains will be updated",
 				previewSettingValue(c.ManagedRecordsCommentRegex))
 		}
+		if c.RFC2136 != nil {
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"RFC2136_SERVER (%s) is ignored because no domains will be updated", c.RFC2136.Server)
+		}
 	}
 	if len(c.WAFLists) == 0 { // We are only updating domains.
 		if c.WAFListDescription != "" {
@@ -312,6 +422,36 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 				previewSettingValue(c.ManagedWAFListItemsCommentRegex))
 		}
 	}
+	if len(c.IPAccessRules) == 0 {
+		if c.IPAccessRuleNotes != "" {
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"IP_ACCESS_RULE_NOTES (%s) is ignored because IP_ACCESS_RULES is empty",
+				previewSettingValue(c.IPAccessRuleNotes))
+		}
+		if c.ManagedIPAccessRulesNotesRegex != "" {
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"MANAGED_IP_ACCESS_RULES_NOTES_REGEX (%s) is ignored because IP_ACCESS_RULES is empty",
+				previewSettingValue(c.ManagedIPAccessRulesNotesRegex))
+		}
+	}
+	if len(c.AccessGroups) == 0 && c.ManagedAccessGroupsNameRegex != "" {
+		ppfmt.Noticef(pp.EmojiUserWarning,
+			"MANAGED_ACCESS_GROUPS_NAME_REGEX (%s) is ignored because ACCESS_GROUPS is empty",
+			previewSettingValue(c.ManagedAccessGroupsNameRegex))
+	}
+	if c.WAFListRule.ZoneID == "" {
+		// Empty values cannot come from the environment; readString keeps the defaults.
+		if c.WAFListRuleExpression != "" && c.WAFListRuleExpression != defaultWAFListRuleExpression {
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"WAF_LIST_RULE_EXPRESSION (%s) is ignored because WAF_LIST_RULE is empty",
+				previewSettingValue(c.WAFListRuleExpression))
+		}
+		if c.WAFListRuleDescription != "" && c.WAFListRuleDescription != defaultWAFListRuleDescription {
+			ppfmt.Noticef(pp.EmojiUserWarning,
+				"WAF_LIST_RULE_DESCRIPTION (%s) is ignored because WAF_LIST_RULE is empty",
+				previewSettingValue(c.WAFListRuleDescription))
+		}
+	}
 	if providerMap[ipnet.IP4] == nil {
 		if c.IP4DefaultPrefixLen != 32 {
 			ppfmt.Noticef(pp.EmojiUserWarning,
@@ -336,6 +476,14 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 			"IP6_DETECTION_FILTER (%s) is ignored because no domains or WAF lists use IPv6",
 			previewSettingValue(c.IP6DetectionFilter.String()))
 	}
+	if c.UpdateCron == nil && c.DeleteRemovedOnReload {
+		ppfmt.Noticef(pp.EmojiUserWarning,
+			"DELETE_REMOVED_ON_RELOAD=true is ignored because UPDATE_CRON=@once")
+	}
+	if c.UpdateCron == nil && c.UpdateRetries > 0 {
+		ppfmt.Noticef(pp.EmojiUserWarning,
+			"UPDATE_RETRIES=%d is ignored because UPDATE_CRON=@once", c.UpdateRetries)
+	}
 	// }}}
 
 	// Check 6: are we doing cleaning up only? {{{
@@ -348,6 +496,20 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 			targetDesc = "managed DNS records for the configured domains"
 		case len(c.WAFLists) > 0:
 			targetDesc = "managed WAF IP items for the configured lists"
+		case len(c.LBPoolOrigins) > 0:
+			targetDesc = "the configured load balancer pool origins"
+		case len(c.GatewayLocations) > 0:
+			targetDesc = "managed networks of the configured Gateway locations"
+		case len(c.AccessGroups) > 0:
+			targetDesc = "managed IP rules of the configured Access groups"
+		case len(c.IPAccessRules) > 0:
+			targetDesc = "managed rules of the configured IP access rules"
+		case len(c.SpectrumApps) > 0:
+			targetDesc = "the origins of the configured Spectrum apps"
+		case c.WorkersKV.Key != "":
+			targetDesc = "the addresses published in the configured Workers KV key"
+		default:
+			targetDesc = "the elements of the configured nftables sets"
 		}
 
 		switch {
@@ -366,6 +528,10 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 	}
 	// }}}
 
+	rfc2136 := c.RFC2136
+	if len(activeDomainSet) == 0 {
+		rfc2136 = nil
+	}
 	handleConfig := &HandleConfig{
 		Auth: c.Auth,
 		Options: api.HandleOptions{
@@ -374,13 +540,21 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 				ManagedRecordsCommentRegex:        managedRecordsCommentRegex,
 				ManagedWAFListItemsCommentRegex:   managedWAFListItemsCommentRegex,
 				AllowWholeWAFListDeleteOnShutdown: allowWholeWAFListDeleteOnShutdown,
+				ManagedIPAccessRulesNotesRegex:    managedIPAccessRulesNotesRegex,
+				ManagedAccessGroupsNameRegex:      managedAccessGroupsNameRegex,
 			},
 		},
+		RFC2136: rfc2136,
 	}
 	lifecycleConfig := &LifecycleConfig{
-		UpdateCron:    c.UpdateCron,
-		UpdateOnStart: c.UpdateOnStart,
-		DeleteOnStop:  c.DeleteOnStop,
+		UpdateCron:              c.UpdateCron,
+		UpdateOnStart:           c.UpdateOnStart,
+		CheckPermissionsOnStart: c.CheckPermissionsOnStart,
+		DeleteOnStop:            c.DeleteOnStop,
+		DeleteRemovedOnReload:   c.DeleteRemovedOnReload,
+		UpdateRetries:           c.UpdateRetries,
+		JSONReport:              c.JSONReport,
+		StateFile:               c.StateFile,
 	}
 	hostID6Policies := map[domain.Domain]hostid6.Set{}
 	if ip6Managed {
@@ -393,23 +567,43 @@ func (c *RawConfig) BuildConfig(ppfmt pp.PP) (*BuiltConfig, bool) {
 	if ip6Managed {
 		detectionFilter[ipnet.IP6] = c.IP6DetectionFilter
 	}
+	// nft is only run when there are sets to keep.
+	var nftablesRunner nftset.Runner
+	if len(c.NFTablesSets) > 0 {
+		nftablesRunner = nftset.NewCommandRunner()
+	}
 	updateConfig := &UpdateConfig{
-		Provider:        providerMap,
-		Domains:         domains,
-		HostID6:         hostID6Policies,
-		WAFLists:        c.WAFLists,
-		DetectionFilter: detectionFilter,
+		Provider:         providerMap,
+		Domains:          domains,
+		HostID6:          hostID6Policies,
+		WAFLists:         c.WAFLists,
+		LBPoolOrigins:    c.LBPoolOrigins,
+		GatewayLocations: c.GatewayLocations,
+		AccessGroups:     c.AccessGroups,
+		IPAccessRules:    c.IPAccessRules,
+		SpectrumApps:     c.SpectrumApps,
+		WAFListRule:      c.WAFListRule,
+		WorkersKV:        c.WorkersKV,
+		LocalResolver:    c.LocalResolver,
+		NFTablesSets:     c.NFTablesSets,
+		NFTablesRunner:   nftablesRunner,
+		DetectionFilter:  detectionFilter,
 		DefaultPrefixLen: map[ipnet.Family]int{
 			ipnet.IP4: c.IP4DefaultPrefixLen,
 			ipnet.IP6: c.IP6DefaultPrefixLen,
 		},
-		TTL:                c.TTL,
-		Proxied:            proxiedMap,
-		RecordComment:      c.RecordComment,
-		WAFListDescription: c.WAFListDescription,
-		WAFListItemComment: c.WAFListItemComment,
-		DetectionTimeout:   c.DetectionTimeout,
-		UpdateTimeout:      c.UpdateTimeout,
+		TTL:                    c.TTL,
+		Proxied:                proxiedMap,
+		RecordComment:          c.RecordComment,
+		WAFListDescription:     c.WAFListDescription,
+		WAFListItemComment:     c.WAFListItemComment,
+		IPAccessRuleNotes:      c.IPAccessRuleNotes,
+		WAFListRuleExpression:  wafListRuleExpression,
+		WAFListRuleDescription: c.WAFListRuleDescription,
+		DetectionTimeout:       c.DetectionTimeout,
+		UpdateTimeout:          c.UpdateTimeout,
+		DryRun:                 c.DryRun,
+		Report:                 c.JSONReport != "",
 	}
 
 	return &BuiltConfig{
@@ -19,7 +19,9 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipfilter"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
 	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
 	"github.com/favonia/cloudflare-ddns/internal/syntax"
@@ -97,13 +99,17 @@ func TestReadEnvWithOnlyToken(t *testing.T) {
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%s", "IP6_DETECTION_FILTER", "keep-all"),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%s", "UPDATE_CRON", "@once"),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%t", "UPDATE_ON_START", false),
+		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%t", "CHECK_PERMISSIONS_ON_START", false),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%t", "DELETE_ON_STOP", false),
+		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%t", "DELETE_REMOVED_ON_RELOAD", false),
+		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%d", "UPDATE_RETRIES", 0),
+		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%t", "DRY_RUN", false),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%v", "CACHE_EXPIRATION", time.Duration(0)),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%d", "TTL", api.TTL(0)),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%v", "DETECTION_TIMEOUT", time.Duration(0)),
 		innerMockPP.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%v", "UPDATE_TIMEOUT", time.Duration(0)),
 	)
-	ok := cfg.ReadEnv(mockPP)
+	ok := cfg.ReadEnv(mockPP, nil)
 	require.True(t, ok)
 }
 
@@ -123,7 +129,7 @@ func TestReadEnvEmpty(t *testing.T) {
 		innerMockPP.EXPECT().Noticef(pp.EmojiUserError,
 			"Either %s or %s must be set", "CLOUDFLARE_API_TOKEN", "CLOUDFLARE_API_TOKEN_FILE"),
 	)
-	ok := cfg.ReadEnv(mockPP)
+	ok := cfg.ReadEnv(mockPP, nil)
 	require.False(t, ok)
 }
 
@@ -157,7 +163,7 @@ func TestBuildConfig(t *testing.T) {
 					m.EXPECT().IsShowing(pp.Info).Return(true),
 					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
 					m.EXPECT().Indent().Return(m),
-					m.EXPECT().Noticef(pp.EmojiUserError, "Nothing was specified in DOMAINS, IP4_DOMAINS, IP6_DOMAINS, or WAF_LISTS"),
+					m.EXPECT().Noticef(pp.EmojiUserError, "Nothing was specified in DOMAINS, IP4_DOMAINS, IP6_DOMAINS, WAF_LISTS, LB_POOL_ORIGINS, GATEWAY_LOCATIONS, ACCESS_GROUPS, IP_ACCESS_RULES, or SPECTRUM_APPS"),
 				)
 			},
 		},
@@ -857,6 +863,32 @@ func TestBuildConfig(t *testing.T) {
 				)
 			},
 		},
+		"managed-record-regex/rfc2136": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart: true,
+				RecordComment: "hello",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:                 entries(domain.FQDN("a.b.c")),
+				ProxiedExpression:          "false",
+				ManagedRecordsCommentRegex: "^hello$",
+				RFC2136:                    &api.RFC2136Auth{Server: "ns.b.c:53", KeyName: "ddns.", Secret: []byte("secret")},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError,
+						"MANAGED_RECORDS_COMMENT_REGEX=%q does not match the empty comment, "+
+							"but DNS records on the RFC 2136 server %s have no comments",
+						"^hello$", "ns.b.c:53"),
+				)
+			},
+		},
 		"managed-waf-item-regex/valid": {
 			input: &config.RawConfig{ //nolint:exhaustruct
 				IP4DefaultPrefixLen:             32,
@@ -869,28 +901,943 @@ func TestBuildConfig(t *testing.T) {
 				ManagedWAFListItemsCommentRegex: `^managed-[0-9]+$`,
 				DetectionTimeout:                5 * time.Second,
 				Provider: map[ipnet.Family]provider.Provider{
-					ipnet.IP6: provider.NewCloudflareTrace(),
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{ //nolint:exhaustruct
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
+							ManagedWAFListItemsCommentRegex: regexp.MustCompile(`^managed-[0-9]+$`),
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
+					TTL:                api.TTLAuto,
+					WAFListItemComment: "managed-123",
+					DetectionTimeout:   5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"ownership-warning/dns-isolated-waf-not": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:        32,
+				IP6DefaultPrefixLen:        64,
+				UpdateOnStart:              true,
+				RecordComment:              "managed-dns",
+				ManagedRecordsCommentRegex: "^managed-dns$",
+				WAFLists:                   []api.WAFList{{AccountID: "account", Name: "list"}},
+				WAFListItemComment:         "managed-waf",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "false",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{
+						CacheExpiration: 0,
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
+							ManagedRecordsCommentRegex:        regexp.MustCompile("^managed-dns$"),
+							ManagedWAFListItemsCommentRegex:   nil,
+							AllowWholeWAFListDeleteOnShutdown: false,
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
+					DefaultPrefixLen:   defaultPrefixLen(),
+					Proxied:            map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
+					RecordComment:      "managed-dns",
+					WAFListItemComment: "managed-waf",
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"DNS ownership isolation is enabled via MANAGED_RECORDS_COMMENT_REGEX (%s), but "+
+							"WAF_LIST_ITEM_COMMENT (%s) is set while MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX is empty; "+
+							"the comment only affects newly written WAF list items, so WAF mutation scope is still not ownership-isolated",
+						`"^managed-dns$"`,
+						`"managed-waf"`,
+					),
+				)
+			},
+		},
+		"ownership-warning/waf-isolated-dns-not": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:             32,
+				IP6DefaultPrefixLen:             64,
+				UpdateOnStart:                   true,
+				RecordComment:                   "managed-dns",
+				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
+				WAFListItemComment:              "managed-waf",
+				ManagedWAFListItemsCommentRegex: "^managed-waf$",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "false",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{
+						CacheExpiration: 0,
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
+							ManagedRecordsCommentRegex:        nil,
+							ManagedWAFListItemsCommentRegex:   regexp.MustCompile("^managed-waf$"),
+							AllowWholeWAFListDeleteOnShutdown: false,
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
+					DefaultPrefixLen:   defaultPrefixLen(),
+					Proxied:            map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
+					RecordComment:      "managed-dns",
+					WAFListItemComment: "managed-waf",
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"WAF ownership isolation is enabled via MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX (%s), but "+
+							"RECORD_COMMENT (%s) is set while MANAGED_RECORDS_COMMENT_REGEX is empty; "+
+							"the comment only affects newly written DNS records, so DNS mutation scope is still not ownership-isolated",
+						`"^managed-waf$"`,
+						`"managed-dns"`,
+					),
+				)
+			},
+		},
+		"ownership-warning/dns-isolated-waf-not-without-comment-signal": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:        32,
+				IP6DefaultPrefixLen:        64,
+				UpdateOnStart:              true,
+				RecordComment:              "managed-dns",
+				ManagedRecordsCommentRegex: "^managed-dns$",
+				WAFLists:                   []api.WAFList{{AccountID: "account", Name: "list"}},
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "false",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{
+						CacheExpiration: 0,
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
+							ManagedRecordsCommentRegex:        regexp.MustCompile("^managed-dns$"),
+							ManagedWAFListItemsCommentRegex:   nil,
+							AllowWholeWAFListDeleteOnShutdown: false,
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					WAFLists:         []api.WAFList{{AccountID: "account", Name: "list"}},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
+					RecordComment:    "managed-dns",
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"ownership-warning/waf-isolated-dns-not-without-comment-signal": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:             32,
+				IP6DefaultPrefixLen:             64,
+				UpdateOnStart:                   true,
+				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
+				WAFListItemComment:              "managed-waf",
+				ManagedWAFListItemsCommentRegex: "^managed-waf$",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "false",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{
+						CacheExpiration: 0,
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
+							ManagedRecordsCommentRegex:        nil,
+							ManagedWAFListItemsCommentRegex:   regexp.MustCompile("^managed-waf$"),
+							AllowWholeWAFListDeleteOnShutdown: false,
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
+					DefaultPrefixLen:   defaultPrefixLen(),
+					Proxied:            map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
+					WAFListItemComment: "managed-waf",
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"managed-waf-item-regex/invalid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:                   true,
+				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
+				TTL:                             api.TTLAuto,
+				ProxiedExpression:               "false",
+				ManagedWAFListItemsCommentRegex: "(",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, keyManagedWAFListItemsCommentRegex+"=%q is invalid: %v", "(", gomock.Any()),
+				)
+			},
+		},
+		"managed-waf-item-regex/mismatch": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:                   true,
+				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
+				TTL:                             api.TTLAuto,
+				ProxiedExpression:               "false",
+				WAFListItemComment:              "hello",
+				ManagedWAFListItemsCommentRegex: "^world$",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "WAF_LIST_ITEM_COMMENT=%q does not match MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX=%q", "hello", "^world$"),
+				)
+			},
+		},
+		"managed-ip-access-rule-regex/valid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				IPAccessRules: []api.IPAccessRuleSet{
+					{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone", Mode: api.IPAccessRuleModeBlock},
+				},
+				TTL:                            api.TTLAuto,
+				ProxiedExpression:              "false",
+				IPAccessRuleNotes:              "managed-123",
+				ManagedIPAccessRulesNotesRegex: `^managed-[0-9]+$`,
+				DetectionTimeout:               5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{ //nolint:exhaustruct
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{ //nolint:exhaustruct
+							ManagedIPAccessRulesNotesRegex: regexp.MustCompile(`^managed-[0-9]+$`),
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					IPAccessRules: []api.IPAccessRuleSet{
+						{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone", Mode: api.IPAccessRuleModeBlock},
+					},
+					TTL:               api.TTLAuto,
+					IPAccessRuleNotes: "managed-123",
+					DetectionTimeout:  5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"managed-ip-access-rule-regex/invalid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart: true,
+				IPAccessRules: []api.IPAccessRuleSet{
+					{Scope: api.IPAccessRuleScopeAccount, ScopeID: "account", Mode: api.IPAccessRuleModeAllow},
+				},
+				TTL:                            api.TTLAuto,
+				ProxiedExpression:              "false",
+				ManagedIPAccessRulesNotesRegex: "(",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "MANAGED_IP_ACCESS_RULES_NOTES_REGEX=%q is invalid: %v", "(", gomock.Any()),
+				)
+			},
+		},
+		"managed-ip-access-rule-regex/mismatch": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart: true,
+				IPAccessRules: []api.IPAccessRuleSet{
+					{Scope: api.IPAccessRuleScopeAccount, ScopeID: "account", Mode: api.IPAccessRuleModeAllow},
+				},
+				TTL:                            api.TTLAuto,
+				ProxiedExpression:              "false",
+				IPAccessRuleNotes:              "hello",
+				ManagedIPAccessRulesNotesRegex: "^world$",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "IP_ACCESS_RULE_NOTES=%q does not match MANAGED_IP_ACCESS_RULES_NOTES_REGEX=%q", "hello", "^world$"),
+				)
+			},
+		},
+		"ignored/ip-access-rules": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:            32,
+				IP6DefaultPrefixLen:            64,
+				UpdateOnStart:                  true,
+				IPAccessRuleNotes:              "ddns",
+				ManagedIPAccessRulesNotesRegex: "^ddns$",
+				DetectionTimeout:               5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "true",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					IPAccessRuleNotes: "ddns",
+					DetectionTimeout:  5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied: map[domain.Domain]bool{
+						domain.FQDN("a.b.c"): true,
+					},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"IP_ACCESS_RULE_NOTES (%s) is ignored because IP_ACCESS_RULES is empty", `"ddns"`),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"MANAGED_IP_ACCESS_RULES_NOTES_REGEX (%s) is ignored because IP_ACCESS_RULES is empty", `"^ddns$"`),
+				)
+			},
+		},
+		"managed-access-group-regex/valid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:          32,
+				IP6DefaultPrefixLen:          64,
+				UpdateOnStart:                true,
+				AccessGroups:                 []api.AccessGroup{{AccountID: "account", Name: "ddns-office"}},
+				TTL:                          api.TTLAuto,
+				ProxiedExpression:            "false",
+				ManagedAccessGroupsNameRegex: "^ddns-",
+				DetectionTimeout:             5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{ //nolint:exhaustruct
+						HandleOwnershipPolicy: api.HandleOwnershipPolicy{ //nolint:exhaustruct
+							ManagedAccessGroupsNameRegex: regexp.MustCompile("^ddns-"),
+						},
+					},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					AccessGroups:     []api.AccessGroup{{AccountID: "account", Name: "ddns-office"}},
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"managed-access-group-regex/missing": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:     true,
+				AccessGroups:      []api.AccessGroup{{AccountID: "account", Name: "office"}},
+				TTL:               api.TTLAuto,
+				ProxiedExpression: "false",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "ACCESS_GROUPS requires MANAGED_ACCESS_GROUPS_NAME_REGEX to mark the Access groups managed by the updater"),
+				)
+			},
+		},
+		"managed-access-group-regex/invalid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:                true,
+				AccessGroups:                 []api.AccessGroup{{AccountID: "account", Name: "office"}},
+				TTL:                          api.TTLAuto,
+				ProxiedExpression:            "false",
+				ManagedAccessGroupsNameRegex: "(",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "MANAGED_ACCESS_GROUPS_NAME_REGEX=%q is invalid: %v", "(", gomock.Any()),
+				)
+			},
+		},
+		"ignored/access-groups": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:          32,
+				IP6DefaultPrefixLen:          64,
+				UpdateOnStart:                true,
+				ManagedAccessGroupsNameRegex: "^ddns-",
+				DetectionTimeout:             5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "true",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					DetectionTimeout: 5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied: map[domain.Domain]bool{
+						domain.FQDN("a.b.c"): true,
+					},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"MANAGED_ACCESS_GROUPS_NAME_REGEX (%s) is ignored because ACCESS_GROUPS is empty", `"^ddns-"`),
+				)
+			},
+		},
+		"waf-list-rule/valid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				WAFLists: []api.WAFList{
+					{AccountID: "account", Name: "home"},
+					{AccountID: "account", Name: "office"},
+				},
+				WAFListRule:            api.WAFListRule{ZoneID: "zone", Action: "skip"},
+				WAFListRuleExpression:  "ip.src in {list}",
+				WAFListRuleDescription: "ddns",
+				TTL:                    api.TTLAuto,
+				ProxiedExpression:      "false",
+				DetectionTimeout:       5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					WAFLists: []api.WAFList{
+						{AccountID: "account", Name: "home"},
+						{AccountID: "account", Name: "office"},
+					},
+					WAFListRule:            api.WAFListRule{ZoneID: "zone", Action: "skip"},
+					WAFListRuleExpression:  "(ip.src in $home) or (ip.src in $office)",
+					WAFListRuleDescription: "ddns",
+					TTL:                    api.TTLAuto,
+					DetectionTimeout:       5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"waf-list-rule/no-lists": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:          true,
+				WAFListRule:            api.WAFListRule{ZoneID: "zone", Action: "block"},
+				WAFListRuleExpression:  "ip.src in {list}",
+				WAFListRuleDescription: "ddns",
+				IP6Domains:             entries(domain.FQDN("a.b.c")),
+				ProxiedExpression:      "false",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "WAF_LIST_RULE (%s) requires WAF_LISTS to be set", `"zone:block"`),
+				)
+			},
+		},
+		"waf-list-rule/no-placeholder": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:          true,
+				WAFLists:               []api.WAFList{{AccountID: "account", Name: "list"}},
+				WAFListRule:            api.WAFListRule{ZoneID: "zone", Action: "block"},
+				WAFListRuleExpression:  "ip.src in $list",
+				WAFListRuleDescription: "ddns",
+				ProxiedExpression:      "false",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError, "WAF_LIST_RULE_EXPRESSION=%q does not contain %q", "ip.src in $list", "{list}"),
+				)
+			},
+		},
+		"waf-list-rule/empty-description": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				UpdateOnStart:         true,
+				WAFLists:              []api.WAFList{{AccountID: "account", Name: "list"}},
+				WAFListRule:           api.WAFListRule{ZoneID: "zone", Action: "block"},
+				WAFListRuleExpression: "ip.src in {list}",
+				ProxiedExpression:     "false",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+			},
+			ok:       false,
+			expected: nil,
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserError,
+						"WAF_LIST_RULE_DESCRIPTION must not be empty because it identifies the custom rule managed by the updater"),
+				)
+			},
+		},
+		"ignored/waf-list-rule": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen:    32,
+				IP6DefaultPrefixLen:    64,
+				UpdateOnStart:          true,
+				WAFListRuleExpression:  "ip.src in {list} and http.host eq \"a.b.c\"",
+				WAFListRuleDescription: "ddns",
+				DetectionTimeout:       5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP6: provider.NewCloudflareTrace(),
+				},
+				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				ProxiedExpression: "true",
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					WAFListRuleDescription: "ddns",
+					DetectionTimeout:       5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP6: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: {domain.FQDN("a.b.c")},
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied: map[domain.Domain]bool{
+						domain.FQDN("a.b.c"): true,
+					},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"WAF_LIST_RULE_EXPRESSION (%s) is ignored because WAF_LIST_RULE is empty", `"ip.src in {list} and http.host eq \"a.b.c\""`),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"WAF_LIST_RULE_DESCRIPTION (%s) is ignored because WAF_LIST_RULE is empty", `"ddns"`),
+				)
+			},
+		},
+		"workers-kv/valid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				WorkersKV:           api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"},
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				DetectionTimeout:    5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP4: provider.NewCloudflareTrace(),
+				},
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					WorkersKV:        api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"},
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP4: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"both-static-empty-warning/workers-kv-only": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				WorkersKV:           api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"},
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP4: provider.NewStaticEmpty(),
+					ipnet.IP6: provider.NewStaticEmpty(),
+				},
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					WorkersKV: api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"},
+					TTL:       api.TTLAuto,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP4: provider.NewStaticEmpty(),
+						ipnet.IP6: provider.NewStaticEmpty(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: nil,
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"Both IP4_PROVIDER and IP6_PROVIDER are configured to clear %s",
+						"the addresses published in the configured Workers KV key"),
+				)
+			},
+		},
+		"rfc2136/valid": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				RFC2136:             &api.RFC2136Auth{Server: "ns.b.c:53", KeyName: "ddns.", Secret: []byte("secret")},
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				DetectionTimeout:    5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP4: provider.NewCloudflareTrace(),
+				},
+				IP4Domains: entries(domain.FQDN("a.b.c")),
+			},
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+					RFC2136: &api.RFC2136Auth{Server: "ns.b.c:53", KeyName: "ddns.", Secret: []byte("secret")},
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP4: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: {domain.FQDN("a.b.c")},
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
+				},
+			},
+			prepareMockPP: func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().IsShowing(pp.Info).Return(true),
+					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
+					m.EXPECT().Indent().Return(m),
+				)
+			},
+		},
+		"ignored/rfc2136": {
+			input: &config.RawConfig{ //nolint:exhaustruct
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				WorkersKV:           api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"},
+				RFC2136:             &api.RFC2136Auth{Server: "ns.b.c:53", KeyName: "ddns.", Secret: []byte("secret")},
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				DetectionTimeout:    5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP4: provider.NewCloudflareTrace(),
 				},
 			},
 			ok: true,
 			expected: &builtConfig{
 				handle: &config.HandleConfig{ //nolint:exhaustruct
-					Options: api.HandleOptions{ //nolint:exhaustruct
-						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
-							ManagedWAFListItemsCommentRegex: regexp.MustCompile(`^managed-[0-9]+$`),
-						},
-					},
+					Options: api.HandleOptions{}, //nolint:exhaustruct
 				},
 				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
 					UpdateOnStart: true,
 				},
 				update: &config.UpdateConfig{ //nolint:exhaustruct
-					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
-					TTL:                api.TTLAuto,
-					WAFListItemComment: "managed-123",
-					DetectionTimeout:   5 * time.Second,
+					WorkersKV:        api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"},
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
 					Provider: map[ipnet.Family]provider.Provider{
-						ipnet.IP6: provider.NewCloudflareTrace(),
+						ipnet.IP4: provider.NewCloudflareTrace(),
 					},
 					Domains: map[ipnet.Family][]domain.Domain{
 						ipnet.IP4: nil,
@@ -905,52 +1852,58 @@ func TestBuildConfig(t *testing.T) {
 					m.EXPECT().IsShowing(pp.Info).Return(true),
 					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
 					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"RFC2136_SERVER (%s) is ignored because no domains will be updated", "ns.b.c:53"),
 				)
 			},
 		},
-		"ownership-warning/dns-isolated-waf-not": {
+		"local-resolver/valid": {
 			input: &config.RawConfig{ //nolint:exhaustruct
-				IP4DefaultPrefixLen:        32,
-				IP6DefaultPrefixLen:        64,
-				UpdateOnStart:              true,
-				RecordComment:              "managed-dns",
-				ManagedRecordsCommentRegex: "^managed-dns$",
-				WAFLists:                   []api.WAFList{{AccountID: "account", Name: "list"}},
-				WAFListItemComment:         "managed-waf",
-				Provider: map[ipnet.Family]provider.Provider{
-					ipnet.IP6: provider.NewCloudflareTrace(),
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				LocalResolver: &config.LocalResolverConfig{
+					Sink: localdns.Sink{Path: "/etc/hosts.d/ddns", Format: localdns.FormatHosts, ReloadCommand: nil},
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP4: provider.MustNewStatic(ipnet.IP4, 32, "192.168.1.2"),
+					},
+					Domains: []domain.Domain{domain.FQDN("a.b.c")},
 				},
-				IP6Domains:        entries(domain.FQDN("a.b.c")),
+				TTL:               api.TTLAuto,
 				ProxiedExpression: "false",
+				DetectionTimeout:  5 * time.Second,
+				Provider: map[ipnet.Family]provider.Provider{
+					ipnet.IP4: provider.NewCloudflareTrace(),
+				},
+				IP4Domains: entries(domain.FQDN("a.b.c")),
 			},
 			ok: true,
 			expected: &builtConfig{
 				handle: &config.HandleConfig{ //nolint:exhaustruct
-					Options: api.HandleOptions{
-						CacheExpiration: 0,
-						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
-							ManagedRecordsCommentRegex:        regexp.MustCompile("^managed-dns$"),
-							ManagedWAFListItemsCommentRegex:   nil,
-							AllowWholeWAFListDeleteOnShutdown: false,
-						},
-					},
+					Options: api.HandleOptions{}, //nolint:exhaustruct
 				},
 				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
 					UpdateOnStart: true,
 				},
 				update: &config.UpdateConfig{ //nolint:exhaustruct
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
 					Provider: map[ipnet.Family]provider.Provider{
-						ipnet.IP6: provider.NewCloudflareTrace(),
+						ipnet.IP4: provider.NewCloudflareTrace(),
 					},
 					Domains: map[ipnet.Family][]domain.Domain{
-						ipnet.IP4: nil,
-						ipnet.IP6: {domain.FQDN("a.b.c")},
+						ipnet.IP4: {domain.FQDN("a.b.c")},
+						ipnet.IP6: nil,
 					},
-					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
-					DefaultPrefixLen:   defaultPrefixLen(),
-					Proxied:            map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
-					RecordComment:      "managed-dns",
-					WAFListItemComment: "managed-waf",
+					LocalResolver: &config.LocalResolverConfig{
+						Sink: localdns.Sink{Path: "/etc/hosts.d/ddns", Format: localdns.FormatHosts, ReloadCommand: nil},
+						Provider: map[ipnet.Family]provider.Provider{
+							ipnet.IP4: provider.MustNewStatic(ipnet.IP4, 32, "192.168.1.2"),
+						},
+						Domains: []domain.Domain{domain.FQDN("a.b.c")},
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
 				},
 			},
 			prepareMockPP: func(m *mocks.MockPP) {
@@ -958,59 +1911,44 @@ func TestBuildConfig(t *testing.T) {
 					m.EXPECT().IsShowing(pp.Info).Return(true),
 					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
 					m.EXPECT().Indent().Return(m),
-					m.EXPECT().Noticef(pp.EmojiUserWarning,
-						"DNS ownership isolation is enabled via MANAGED_RECORDS_COMMENT_REGEX (%s), but "+
-							"WAF_LIST_ITEM_COMMENT (%s) is set while MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX is empty; "+
-							"the comment only affects newly written WAF list items, so WAF mutation scope is still not ownership-isolated",
-						`"^managed-dns$"`,
-						`"managed-waf"`,
-					),
 				)
 			},
 		},
-		"ownership-warning/waf-isolated-dns-not": {
+		"nftables/valid": {
 			input: &config.RawConfig{ //nolint:exhaustruct
-				IP4DefaultPrefixLen:             32,
-				IP6DefaultPrefixLen:             64,
-				UpdateOnStart:                   true,
-				RecordComment:                   "managed-dns",
-				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
-				WAFListItemComment:              "managed-waf",
-				ManagedWAFListItemsCommentRegex: "^managed-waf$",
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				NFTablesSets:        []nftset.Set{{Family: "inet", Table: "filter", Name: "home_prefixes"}},
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				DetectionTimeout:    5 * time.Second,
 				Provider: map[ipnet.Family]provider.Provider{
 					ipnet.IP6: provider.NewCloudflareTrace(),
 				},
-				IP6Domains:        entries(domain.FQDN("a.b.c")),
-				ProxiedExpression: "false",
 			},
 			ok: true,
 			expected: &builtConfig{
 				handle: &config.HandleConfig{ //nolint:exhaustruct
-					Options: api.HandleOptions{
-						CacheExpiration: 0,
-						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
-							ManagedRecordsCommentRegex:        nil,
-							ManagedWAFListItemsCommentRegex:   regexp.MustCompile("^managed-waf$"),
-							AllowWholeWAFListDeleteOnShutdown: false,
-						},
-					},
+					Options: api.HandleOptions{}, //nolint:exhaustruct
 				},
 				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
 					UpdateOnStart: true,
 				},
 				update: &config.UpdateConfig{ //nolint:exhaustruct
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
 					Provider: map[ipnet.Family]provider.Provider{
 						ipnet.IP6: provider.NewCloudflareTrace(),
 					},
 					Domains: map[ipnet.Family][]domain.Domain{
 						ipnet.IP4: nil,
-						ipnet.IP6: {domain.FQDN("a.b.c")},
+						ipnet.IP6: nil,
 					},
-					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
-					DefaultPrefixLen:   defaultPrefixLen(),
-					Proxied:            map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
-					RecordComment:      "managed-dns",
-					WAFListItemComment: "managed-waf",
+					NFTablesSets:     []nftset.Set{{Family: "inet", Table: "filter", Name: "home_prefixes"}},
+					NFTablesRunner:   nftset.NewCommandRunner(),
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{},
 				},
 			},
 			prepareMockPP: func(m *mocks.MockPP) {
@@ -1018,57 +1956,44 @@ func TestBuildConfig(t *testing.T) {
 					m.EXPECT().IsShowing(pp.Info).Return(true),
 					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
 					m.EXPECT().Indent().Return(m),
-					m.EXPECT().Noticef(pp.EmojiUserWarning,
-						"WAF ownership isolation is enabled via MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX (%s), but "+
-							"RECORD_COMMENT (%s) is set while MANAGED_RECORDS_COMMENT_REGEX is empty; "+
-							"the comment only affects newly written DNS records, so DNS mutation scope is still not ownership-isolated",
-						`"^managed-waf$"`,
-						`"managed-dns"`,
-					),
 				)
 			},
 		},
-		"ownership-warning/dns-isolated-waf-not-without-comment-signal": {
+		"state-file": {
 			input: &config.RawConfig{ //nolint:exhaustruct
-				IP4DefaultPrefixLen:        32,
-				IP6DefaultPrefixLen:        64,
-				UpdateOnStart:              true,
-				RecordComment:              "managed-dns",
-				ManagedRecordsCommentRegex: "^managed-dns$",
-				WAFLists:                   []api.WAFList{{AccountID: "account", Name: "list"}},
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				StateFile:           "/data/state.json",
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				DetectionTimeout:    5 * time.Second,
 				Provider: map[ipnet.Family]provider.Provider{
-					ipnet.IP6: provider.NewCloudflareTrace(),
+					ipnet.IP4: provider.NewCloudflareTrace(),
 				},
-				IP6Domains:        entries(domain.FQDN("a.b.c")),
-				ProxiedExpression: "false",
+				IP4Domains: entries(domain.FQDN("a.b.c")),
 			},
 			ok: true,
 			expected: &builtConfig{
 				handle: &config.HandleConfig{ //nolint:exhaustruct
-					Options: api.HandleOptions{
-						CacheExpiration: 0,
-						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
-							ManagedRecordsCommentRegex:        regexp.MustCompile("^managed-dns$"),
-							ManagedWAFListItemsCommentRegex:   nil,
-							AllowWholeWAFListDeleteOnShutdown: false,
-						},
-					},
+					Options: api.HandleOptions{}, //nolint:exhaustruct
 				},
 				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
 					UpdateOnStart: true,
+					StateFile:     "/data/state.json",
 				},
 				update: &config.UpdateConfig{ //nolint:exhaustruct
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
 					Provider: map[ipnet.Family]provider.Provider{
-						ipnet.IP6: provider.NewCloudflareTrace(),
+						ipnet.IP4: provider.NewCloudflareTrace(),
 					},
 					Domains: map[ipnet.Family][]domain.Domain{
-						ipnet.IP4: nil,
-						ipnet.IP6: {domain.FQDN("a.b.c")},
+						ipnet.IP4: {domain.FQDN("a.b.c")},
+						ipnet.IP6: nil,
 					},
-					WAFLists:         []api.WAFList{{AccountID: "account", Name: "list"}},
 					DefaultPrefixLen: defaultPrefixLen(),
 					Proxied:          map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
-					RecordComment:    "managed-dns",
 				},
 			},
 			prepareMockPP: func(m *mocks.MockPP) {
@@ -1079,47 +2004,41 @@ func TestBuildConfig(t *testing.T) {
 				)
 			},
 		},
-		"ownership-warning/waf-isolated-dns-not-without-comment-signal": {
+		"ignored/delete-removed-on-reload": {
 			input: &config.RawConfig{ //nolint:exhaustruct
-				IP4DefaultPrefixLen:             32,
-				IP6DefaultPrefixLen:             64,
-				UpdateOnStart:                   true,
-				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
-				WAFListItemComment:              "managed-waf",
-				ManagedWAFListItemsCommentRegex: "^managed-waf$",
+				IP4DefaultPrefixLen:   32,
+				IP6DefaultPrefixLen:   64,
+				UpdateOnStart:         true,
+				DeleteRemovedOnReload: true,
+				TTL:                   api.TTLAuto,
+				ProxiedExpression:     "false",
+				DetectionTimeout:      5 * time.Second,
 				Provider: map[ipnet.Family]provider.Provider{
-					ipnet.IP6: provider.NewCloudflareTrace(),
+					ipnet.IP4: provider.NewCloudflareTrace(),
 				},
-				IP6Domains:        entries(domain.FQDN("a.b.c")),
-				ProxiedExpression: "false",
+				IP4Domains: entries(domain.FQDN("a.b.c")),
 			},
 			ok: true,
 			expected: &builtConfig{
 				handle: &config.HandleConfig{ //nolint:exhaustruct
-					Options: api.HandleOptions{
-						CacheExpiration: 0,
-						HandleOwnershipPolicy: api.HandleOwnershipPolicy{
-							ManagedRecordsCommentRegex:        nil,
-							ManagedWAFListItemsCommentRegex:   regexp.MustCompile("^managed-waf$"),
-							AllowWholeWAFListDeleteOnShutdown: false,
-						},
-					},
+					Options: api.HandleOptions{}, //nolint:exhaustruct
 				},
 				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
-					UpdateOnStart: true,
+					UpdateOnStart:         true,
+					DeleteRemovedOnReload: true,
 				},
 				update: &config.UpdateConfig{ //nolint:exhaustruct
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
 					Provider: map[ipnet.Family]provider.Provider{
-						ipnet.IP6: provider.NewCloudflareTrace(),
+						ipnet.IP4: provider.NewCloudflareTrace(),
 					},
 					Domains: map[ipnet.Family][]domain.Domain{
-						ipnet.IP4: nil,
-						ipnet.IP6: {domain.FQDN("a.b.c")},
+						ipnet.IP4: {domain.FQDN("a.b.c")},
+						ipnet.IP6: nil,
 					},
-					WAFLists:           []api.WAFList{{AccountID: "account", Name: "list"}},
-					DefaultPrefixLen:   defaultPrefixLen(),
-					Proxied:            map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
-					WAFListItemComment: "managed-waf",
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
 				},
 			},
 			prepareMockPP: func(m *mocks.MockPP) {
@@ -1127,51 +2046,55 @@ func TestBuildConfig(t *testing.T) {
 					m.EXPECT().IsShowing(pp.Info).Return(true),
 					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
 					m.EXPECT().Indent().Return(m),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"DELETE_REMOVED_ON_RELOAD=true is ignored because UPDATE_CRON=@once"),
 				)
 			},
 		},
-		"managed-waf-item-regex/invalid": {
+		"ignored/update-retries": {
 			input: &config.RawConfig{ //nolint:exhaustruct
-				UpdateOnStart:                   true,
-				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
-				TTL:                             api.TTLAuto,
-				ProxiedExpression:               "false",
-				ManagedWAFListItemsCommentRegex: "(",
+				IP4DefaultPrefixLen: 32,
+				IP6DefaultPrefixLen: 64,
+				UpdateOnStart:       true,
+				UpdateRetries:       3,
+				TTL:                 api.TTLAuto,
+				ProxiedExpression:   "false",
+				DetectionTimeout:    5 * time.Second,
 				Provider: map[ipnet.Family]provider.Provider{
-					ipnet.IP6: provider.NewCloudflareTrace(),
+					ipnet.IP4: provider.NewCloudflareTrace(),
 				},
+				IP4Domains: entries(domain.FQDN("a.b.c")),
 			},
-			ok:       false,
-			expected: nil,
-			prepareMockPP: func(m *mocks.MockPP) {
-				gomock.InOrder(
-					m.EXPECT().IsShowing(pp.Info).Return(true),
-					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
-					m.EXPECT().Indent().Return(m),
-					m.EXPECT().Noticef(pp.EmojiUserError, keyManagedWAFListItemsCommentRegex+"=%q is invalid: %v", "(", gomock.Any()),
-				)
-			},
-		},
-		"managed-waf-item-regex/mismatch": {
-			input: &config.RawConfig{ //nolint:exhaustruct
-				UpdateOnStart:                   true,
-				WAFLists:                        []api.WAFList{{AccountID: "account", Name: "list"}},
-				TTL:                             api.TTLAuto,
-				ProxiedExpression:               "false",
-				WAFListItemComment:              "hello",
-				ManagedWAFListItemsCommentRegex: "^world$",
-				Provider: map[ipnet.Family]provider.Provider{
-					ipnet.IP6: provider.NewCloudflareTrace(),
+			ok: true,
+			expected: &builtConfig{
+				handle: &config.HandleConfig{ //nolint:exhaustruct
+					Options: api.HandleOptions{}, //nolint:exhaustruct
+				},
+				lifecycle: &config.LifecycleConfig{ //nolint:exhaustruct
+					UpdateOnStart: true,
+					UpdateRetries: 3,
+				},
+				update: &config.UpdateConfig{ //nolint:exhaustruct
+					TTL:              api.TTLAuto,
+					DetectionTimeout: 5 * time.Second,
+					Provider: map[ipnet.Family]provider.Provider{
+						ipnet.IP4: provider.NewCloudflareTrace(),
+					},
+					Domains: map[ipnet.Family][]domain.Domain{
+						ipnet.IP4: {domain.FQDN("a.b.c")},
+						ipnet.IP6: nil,
+					},
+					DefaultPrefixLen: defaultPrefixLen(),
+					Proxied:          map[domain.Domain]bool{domain.FQDN("a.b.c"): false},
 				},
 			},
-			ok:       false,
-			expected: nil,
 			prepareMockPP: func(m *mocks.MockPP) {
 				gomock.InOrder(
 					m.EXPECT().IsShowing(pp.Info).Return(true),
 					m.EXPECT().Infof(pp.EmojiEnvVars, "Checking settings . . ."),
 					m.EXPECT().Indent().Return(m),
-					m.EXPECT().Noticef(pp.EmojiUserError, "WAF_LIST_ITEM_COMMENT=%q does not match MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX=%q", "hello", "^world$"),
+					m.EXPECT().Noticef(pp.EmojiUserWarning,
+						"UPDATE_RETRIES=%d is ignored because UPDATE_CRON=@once", 3),
 				)
 			},
 		},
@@ -1591,6 +2514,8 @@ func TestBuildConfig(t *testing.T) {
 				require.NotNil(t, builtConfig.Update)
 				require.NotNil(t, builtConfig.Handle.Options.ManagedRecordsCommentRegex)
 				require.NotNil(t, builtConfig.Handle.Options.ManagedWAFListItemsCommentRegex)
+				require.NotNil(t, builtConfig.Handle.Options.ManagedIPAccessRulesNotesRegex)
+				require.NotNil(t, builtConfig.Handle.Options.ManagedAccessGroupsNameRegex)
 
 				expectedHandle := *tc.expected.handle
 				if expectedHandle.Options.ManagedRecordsCommentRegex == nil {
@@ -1599,6 +2524,12 @@ func TestBuildConfig(t *testing.T) {
 				if expectedHandle.Options.ManagedWAFListItemsCommentRegex == nil {
 					expectedHandle.Options.ManagedWAFListItemsCommentRegex = regexp.MustCompile("")
 				}
+				if expectedHandle.Options.ManagedIPAccessRulesNotesRegex == nil {
+					expectedHandle.Options.ManagedIPAccessRulesNotesRegex = regexp.MustCompile("")
+				}
+				if expectedHandle.Options.ManagedAccessGroupsNameRegex == nil {
+					expectedHandle.Options.ManagedAccessGroupsNameRegex = regexp.MustCompile("")
+				}
 				expectedHandle.Options.AllowWholeWAFListDeleteOnShutdown = expectedHandle.Options.ManagedWAFListItemsCommentRegex.String() == ""
 				require.Equal(t, &expectedHandle, builtConfig.Handle)
 				require.Equal(t, tc.expected.lifecycle, builtConfig.Lifecycle)
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/domain"
 	"github.com/favonia/cloudflare-ddns/internal/domainentry"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
 	"github.com/favonia/cloudflare-ddns/internal/syntax"
@@ -33,9 +35,25 @@ type rawConfigSummary struct {
 	ip4Domains                      []rawEntrySummary
 	ip6Domains                      []rawEntrySummary
 	wafLists                        []string
+	lbPoolOrigins                   []string
+	gatewayLocations                []string
+	accessGroups                    []string
+	ipAccessRules                   []string
+	spectrumApps                    []string
+	wafListRule                     string
+	workersKV                       string
+	rfc2136                         string
+	localResolver                   string
+	nftablesSets                    []string
 	updateCron                      string
 	updateOnStart                   bool
+	checkPermissionsOnStart         bool
 	deleteOnStop                    bool
+	deleteRemovedOnReload           bool
+	updateRetries                   int
+	dryRun                          bool
+	jsonReport                      string
+	stateFile                       string
 	ttl                             api.TTL
 	proxiedExpression               string
 	recordComment                   string
@@ -43,6 +61,11 @@ type rawConfigSummary struct {
 	wafListDescription              string
 	wafListItemComment              string
 	managedWAFListItemsCommentRegex string
+	ipAccessRuleNotes               string
+	managedIPAccessRulesNotesRegex  string
+	managedAccessGroupsNameRegex    string
+	wafListRuleExpression           string
+	wafListRuleDescription          string
 	cacheExpiration                 time.Duration
 	detectionTimeout                time.Duration
 	updateTimeout                   time.Duration
@@ -89,6 +112,74 @@ func summarizeWAFLists(lists []api.WAFList) []string {
 	return summary
 }
 
+func summarizeLBPoolOrigins(origins []api.LBPoolOrigin) []string {
+	summary := make([]string, 0, len(origins))
+	for _, o := range origins {
+		summary = append(summary, o.Describe())
+	}
+	return summary
+}
+
+func summarizeGatewayLocations(locations []api.GatewayLocation) []string {
+	summary := make([]string, 0, len(locations))
+	for _, l := range locations {
+		summary = append(summary, l.Describe())
+	}
+	return summary
+}
+
+func summarizeAccessGroups(groups []api.AccessGroup) []string {
+	summary := make([]string, 0, len(groups))
+	for _, g := range groups {
+		summary = append(summary, g.Describe())
+	}
+	return summary
+}
+
+func summarizeIPAccessRules(sets []api.IPAccessRuleSet) []string {
+	summary := make([]string, 0, len(sets))
+	for _, set := range sets {
+		summary = append(summary, set.Describe())
+	}
+	return summary
+}
+
+func summarizeSpectrumApps(apps []api.SpectrumApp) []string {
+	summary := make([]string, 0, len(apps))
+	for _, a := range apps {
+		summary = append(summary, a.Describe())
+	}
+	return summary
+}
+
+func summarizeNFTablesSets(sets []nftset.Set) []string {
+	summary := make([]string, 0, len(sets))
+	for _, set := range sets {
+		summary = append(summary, set.Describe())
+	}
+	return summary
+}
+
+func summarizeRFC2136(auth *api.RFC2136Auth) string {
+	if auth == nil {
+		return ""
+	}
+	return auth.Describe()
+}
+
+func summarizeLocalResolver(local *config.LocalResolverConfig) string {
+	if local == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s) for %s via %s",
+		local.Sink.Path, local.Sink.Format,
+		strings.Join(summarizeDomains(local.Domains), ","),
+		strings.Join([]string{
+			provider.Name(local.Provider[ipnet.IP4]),
+			provider.Name(local.Provider[ipnet.IP6]),
+		}, "/"))
+}
+
 func summarizeRawConfig(raw *config.RawConfig) rawConfigSummary {
 	return rawConfigSummary{
 		ip4Provider:                     provider.Name(raw.Provider[ipnet.IP4]),
@@ -97,9 +188,25 @@ func summarizeRawConfig(raw *config.RawConfig) rawConfigSummary {
 		ip4Domains:                      summarizeEntries(raw.IP4Domains),
 		ip6Domains:                      summarizeEntries(raw.IP6Domains),
 		wafLists:                        summarizeWAFLists(raw.WAFLists),
+		lbPoolOrigins:                   summarizeLBPoolOrigins(raw.LBPoolOrigins),
+		gatewayLocations:                summarizeGatewayLocations(raw.GatewayLocations),
+		accessGroups:                    summarizeAccessGroups(raw.AccessGroups),
+		ipAccessRules:                   summarizeIPAccessRules(raw.IPAccessRules),
+		spectrumApps:                    summarizeSpectrumApps(raw.SpectrumApps),
+		wafListRule:                     raw.WAFListRule.Describe(),
+		workersKV:                       raw.WorkersKV.Describe(),
+		rfc2136:                         summarizeRFC2136(raw.RFC2136),
+		localResolver:                   summarizeLocalResolver(raw.LocalResolver),
+		nftablesSets:                    summarizeNFTablesSets(raw.NFTablesSets),
 		updateCron:                      cron.DescribeSchedule(raw.UpdateCron),
 		updateOnStart:                   raw.UpdateOnStart,
+		checkPermissionsOnStart:         raw.CheckPermissionsOnStart,
 		deleteOnStop:                    raw.DeleteOnStop,
+		deleteRemovedOnReload:           raw.DeleteRemovedOnReload,
+		updateRetries:                   raw.UpdateRetries,
+		dryRun:                          raw.DryRun,
+		jsonReport:                      raw.JSONReport,
+		stateFile:                       raw.StateFile,
 		ttl:                             raw.TTL,
 		proxiedExpression:               raw.ProxiedExpression,
 		recordComment:                   raw.RecordComment,
@@ -107,6 +214,11 @@ func summarizeRawConfig(raw *config.RawConfig) rawConfigSummary {
 		wafListDescription:              raw.WAFListDescription,
 		wafListItemComment:              raw.WAFListItemComment,
 		managedWAFListItemsCommentRegex: raw.ManagedWAFListItemsCommentRegex,
+		ipAccessRuleNotes:               raw.IPAccessRuleNotes,
+		managedIPAccessRulesNotesRegex:  raw.ManagedIPAccessRulesNotesRegex,
+		managedAccessGroupsNameRegex:    raw.ManagedAccessGroupsNameRegex,
+		wafListRuleExpression:           raw.WAFListRuleExpression,
+		wafListRuleDescription:          raw.WAFListRuleDescription,
 		cacheExpiration:                 raw.CacheExpiration,
 		detectionTimeout:                raw.DetectionTimeout,
 		updateTimeout:                   raw.UpdateTimeout,
@@ -123,7 +235,7 @@ func readUpdaterSettingsFromDefaultRaw(t *testing.T, env map[string]string) *con
 	}
 
 	raw := config.DefaultRaw()
-	require.True(t, raw.ReadEnv(pp.NewSilent()))
+	require.True(t, raw.ReadEnv(pp.NewSilent(), nil))
 	return raw
 }
 
@@ -137,7 +249,13 @@ func canonicalOmissionEquivalentUpdaterValues() map[string]string {
 		"IP6_PROVIDER":                         "cloudflare.trace",
 		"UPDATE_CRON":                          "@every 5m",
 		"UPDATE_ON_START":                      "true",
+		"CHECK_PERMISSIONS_ON_START":           "false",
 		"DELETE_ON_STOP":                       "false",
+		"DELETE_REMOVED_ON_RELOAD":             "false",
+		"UPDATE_RETRIES":                       "0",
+		"DRY_RUN":                              "false",
+		"JSON_REPORT":                          "",
+		"STATE_FILE":                           "",
 		"CACHE_EXPIRATION":                     "6h0m0s",
 		"TTL":                                  "1",
 		"PROXIED":                              "false",
@@ -146,6 +264,16 @@ func canonicalOmissionEquivalentUpdaterValues() map[string]string {
 		"WAF_LIST_DESCRIPTION":                 "",
 		"WAF_LIST_ITEM_COMMENT":                "",
 		"MANAGED_WAF_LIST_ITEMS_COMMENT_REGEX": "",
+		"IP_ACCESS_RULE_NOTES":                 "",
+		"MANAGED_IP_ACCESS_RULES_NOTES_REGEX":  "",
+		"WAF_LIST_RULE":                        "",
+		"WORKERS_KV":                           "",
+		"RFC2136_SERVER":                       "",
+		"RFC2136_TSIG_KEY":                     "",
+		"LOCAL_RESOLVER_FILE":                  "",
+		"NFTABLES_SETS":                        "",
+		"WAF_LIST_RULE_EXPRESSION":             "ip.src in {list}",
+		"WAF_LIST_RULE_DESCRIPTION":            "Managed by Cloudflare DDNS",
 		"DETECTION_TIMEOUT":                    "5s",
 		"UPDATE_TIMEOUT":                       "30s",
 	}
@@ -214,7 +342,7 @@ func TestReadEnvDomainDiagnostics(t *testing.T) {
 			}
 			var output bytes.Buffer
 
-			ok := cfg.ReadEnv(pp.New(&output, false, pp.Quiet))
+			ok := cfg.ReadEnv(pp.New(&output, false, pp.Quiet), nil)
 
 			require.False(t, ok)
 			require.Equal(t, tc.expected, output.String())
@@ -237,7 +365,7 @@ func TestReadEnvDomainDiagnosticSeverityAndOrder(t *testing.T) {
 	cfg := config.DefaultRaw()
 	var output bytes.Buffer
 
-	ok := cfg.ReadEnv(pp.New(&output, true, pp.Quiet))
+	ok := cfg.ReadEnv(pp.New(&output, true, pp.Quiet), nil)
 
 	require.False(t, ok)
 	require.Equal(t,
@@ -257,7 +385,7 @@ func TestReadEnvDomainCompatibilityWarningUsesBoundedPreview(t *testing.T) {
 	cfg := config.DefaultRaw()
 	var output bytes.Buffer
 
-	ok := cfg.ReadEnv(pp.New(&output, true, pp.Quiet))
+	ok := cfg.ReadEnv(pp.New(&output, true, pp.Quiet), nil)
 
 	require.True(t, ok)
 	require.Equal(t,
@@ -277,12 +405,20 @@ type handleConfigSummary struct {
 	managedRecordsCommentRegex        string
 	managedWAFListItemsCommentRegex   string
 	allowWholeWAFListDeleteOnShutdown bool
+	managedIPAccessRulesNotesRegex    string
+	managedAccessGroupsNameRegex      string
+	rfc2136                           string
 }
 
 type lifecycleConfigSummary struct {
-	updateCron    string
-	updateOnStart bool
-	deleteOnStop  bool
+	updateCron              string
+	updateOnStart           bool
+	checkPermissionsOnStart bool
+	deleteOnStop            bool
+	deleteRemovedOnReload   bool
+	updateRetries           int
+	jsonReport              string
+	stateFile               string
 }
 
 type updateConfigSummary struct {
@@ -291,13 +427,27 @@ type updateConfigSummary struct {
 	ip4Domains         []string
 	ip6Domains         []string
 	wafLists           []string
+	lbPoolOrigins      []string
+	gatewayLocations   []string
+	accessGroups       []string
+	ipAccessRules      []string
+	spectrumApps       []string
+	wafListRule        string
+	wafListRuleExpr    string
+	wafListRuleDesc    string
+	workersKV          string
+	localResolver      string
+	nftablesSets       []string
 	ttl                api.TTL
 	proxied            map[string]bool
 	recordComment      string
 	wafListDesc        string
 	wafListItemComment string
+	ipAccessRuleNotes  string
 	detectionTimeout   time.Duration
 	updateTimeout      time.Duration
+	dryRun             bool
+	report             bool
 }
 
 type builtConfigSummary struct {
@@ -353,11 +503,19 @@ func summarizeBuiltConfig(t *testing.T, built *config.BuiltConfig) builtConfigSu
 			managedRecordsCommentRegex:        built.Handle.Options.ManagedRecordsCommentRegex.String(),
 			managedWAFListItemsCommentRegex:   built.Handle.Options.ManagedWAFListItemsCommentRegex.String(),
 			allowWholeWAFListDeleteOnShutdown: built.Handle.Options.AllowWholeWAFListDeleteOnShutdown,
+			managedIPAccessRulesNotesRegex:    built.Handle.Options.ManagedIPAccessRulesNotesRegex.String(),
+			managedAccessGroupsNameRegex:      built.Handle.Options.ManagedAccessGroupsNameRegex.String(),
+			rfc2136:                           summarizeRFC2136(built.Handle.RFC2136),
 		},
 		lifecycle: lifecycleConfigSummary{
-			updateCron:    cron.DescribeSchedule(built.Lifecycle.UpdateCron),
-			updateOnStart: built.Lifecycle.UpdateOnStart,
-			deleteOnStop:  built.Lifecycle.DeleteOnStop,
+			updateCron:              cron.DescribeSchedule(built.Lifecycle.UpdateCron),
+			updateOnStart:           built.Lifecycle.UpdateOnStart,
+			checkPermissionsOnStart: built.Lifecycle.CheckPermissionsOnStart,
+			deleteOnStop:            built.Lifecycle.DeleteOnStop,
+			deleteRemovedOnReload:   built.Lifecycle.DeleteRemovedOnReload,
+			updateRetries:           built.Lifecycle.UpdateRetries,
+			jsonReport:              built.Lifecycle.JSONReport,
+			stateFile:               built.Lifecycle.StateFile,
 		},
 		update: updateConfigSummary{
 			ip4Provider:        provider.Name(built.Update.Provider[ipnet.IP4]),
@@ -365,13 +523,27 @@ func summarizeBuiltConfig(t *testing.T, built *config.BuiltConfig) builtConfigSu
 			ip4Domains:         summarizeDomains(built.Update.Domains[ipnet.IP4]),
 			ip6Domains:         summarizeDomains(built.Update.Domains[ipnet.IP6]),
 			wafLists:           summarizeWAFLists(built.Update.WAFLists),
+			lbPoolOrigins:      summarizeLBPoolOrigins(built.Update.LBPoolOrigins),
+			gatewayLocations:   summarizeGatewayLocations(built.Update.GatewayLocations),
+			accessGroups:       summarizeAccessGroups(built.Update.AccessGroups),
+			ipAccessRules:      summarizeIPAccessRules(built.Update.IPAccessRules),
+			spectrumApps:       summarizeSpectrumApps(built.Update.SpectrumApps),
+			wafListRule:        built.Update.WAFListRule.Describe(),
+			wafListRuleExpr:    built.Update.WAFListRuleExpression,
+			wafListRuleDesc:    built.Update.WAFListRuleDescription,
+			workersKV:          built.Update.WorkersKV.Describe(),
+			localResolver:      summarizeLocalResolver(built.Update.LocalResolver),
+			nftablesSets:       summarizeNFTablesSets(built.Update.NFTablesSets),
 			ttl:                built.Update.TTL,
 			proxied:            summarizeProxiedMap(built.Update.Proxied),
 			recordComment:      built.Update.RecordComment,
 			wafListDesc:        built.Update.WAFListDescription,
 			wafListItemComment: built.Update.WAFListItemComment,
+			ipAccessRuleNotes:  built.Update.IPAccessRuleNotes,
 			detectionTimeout:   built.Update.DetectionTimeout,
 			updateTimeout:      built.Update.UpdateTimeout,
+			dryRun:             built.Update.DryRun,
+			report:             built.Update.Report,
 		},
 	}
 }
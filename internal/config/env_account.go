@@ -0,0 +1,67 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/sliceutil"
+)
+
+// readAccountResources reads an environment variable as a comma-separated list
+// of account-level resources named in the format "account-id/<nameLabel>".
+// The name is everything after the first slash, so it may contain slashes.
+//
+// Like WAF_LISTS, such a list is a scope declaration: unset or empty input
+// leaves the field empty (nil).
+func readAccountResources[T comparable](ppfmt pp.PP, f *File, key string, feature pp.Feature, nameLabel string,
+	newResource func(accountID api.ID, name string) T, compare func(T, T) int, field *[]T,
+) bool {
+	vals := f.getenvAsList(key, ",")
+	if len(vals) == 1 && vals[0] == "" {
+		*field = nil
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, feature)
+
+	resources := make([]T, 0, len(vals))
+	for i, val := range vals {
+		if val == "" {
+			continue
+		}
+
+		accountID, name, found := strings.Cut(val, "/")
+		if !found || accountID == "" || name == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) should be in the format "account-id/%s"`,
+				pp.Ordinal(i+1), key, val, nameLabel)
+			return false
+		}
+
+		resources = append(resources, newResource(api.ID(accountID), name))
+	}
+
+	*field = sliceutil.SortAndCompact(resources, compare)
+	return true
+}
+
+// readGatewayLocations reads an environment variable as a comma-separated list
+// of Zero Trust Gateway locations in the format "account-id/location-name".
+func readGatewayLocations(ppfmt pp.PP, f *File, key string, field *[]api.GatewayLocation) bool {
+	return readAccountResources(ppfmt, f, key, pp.FeatureGatewayLocations, "location-name",
+		func(accountID api.ID, name string) api.GatewayLocation {
+			return api.GatewayLocation{AccountID: accountID, Name: name}
+		},
+		api.CompareGatewayLocation, field)
+}
+
+// readAccessGroups reads an environment variable as a comma-separated list
+// of Access groups in the format "account-id/group-name".
+func readAccessGroups(ppfmt pp.PP, f *File, key string, field *[]api.AccessGroup) bool {
+	return readAccountResources(ppfmt, f, key, pp.FeatureAccessGroups, "group-name",
+		func(accountID api.ID, name string) api.AccessGroup {
+			return api.AccessGroup{AccountID: accountID, Name: name}
+		},
+		api.CompareAccessGroup, field)
+}
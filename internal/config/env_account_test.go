@@ -0,0 +1,158 @@
+//nolint:testpackage // These tests exercise the unexported account-resource readers directly because they are package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"cmp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+type accountResource struct {
+	accountID api.ID
+	name      string
+}
+
+func newAccountResource(accountID api.ID, name string) accountResource {
+	return accountResource{accountID: accountID, name: name}
+}
+
+func compareAccountResource(r1, r2 accountResource) int {
+	return cmp.Or(cmp.Compare(r1.accountID, r2.accountID), cmp.Compare(r1.name, r2.name))
+}
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadAccountResources(t *testing.T) {
+	key := keyPrefix + "ACCOUNT_RESOURCES"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureGatewayLocations), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Gateway location")
+	}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      []accountResource
+		newField      []accountResource
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {
+			false, "",
+			[]accountResource{{"there", "ciao"}},
+			nil,
+			true,
+			nil,
+		},
+		"empty": {
+			true, "",
+			[]accountResource{{"there", "ciao"}},
+			nil,
+			true,
+			nil,
+		},
+		"one": {
+			true, "hey/main office",
+			nil,
+			[]accountResource{{"hey", "main office"}},
+			true,
+			experimental,
+		},
+		"sorted-and-deduplicated": {
+			true, "hey/b, hey/a,,hey/b",
+			nil,
+			[]accountResource{{"hey", "a"}, {"hey", "b"}},
+			true,
+			experimental,
+		},
+		"name-with-slash": {
+			true, "hey/a/b",
+			nil,
+			[]accountResource{{"hey", "a/b"}},
+			true,
+			experimental,
+		},
+		"missing-name": {
+			true, "hey",
+			[]accountResource{{"there", "ciao"}},
+			[]accountResource{{"there", "ciao"}},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "account-id/%s"`, "1st", key, "hey", "thing-name")
+			},
+		},
+		"missing-account": {
+			true, "hey/a,/b",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "account-id/%s"`, "2nd", key, "/b", "thing-name")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readAccountResources(mockPP, nil, key, pp.FeatureGatewayLocations, "thing-name",
+				newAccountResource, compareAccountResource, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadGatewayLocations(t *testing.T) {
+	key := keyPrefix + "GATEWAY_LOCATIONS"
+	set(t, key, true, "hey/main office,hey")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	gomock.InOrder(
+		mockPP.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureGatewayLocations), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Gateway location"),
+		mockPP.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "account-id/%s"`, "2nd", key, "hey", "location-name"),
+	)
+	var field []api.GatewayLocation
+	require.False(t, readGatewayLocations(mockPP, nil, key, &field))
+
+	set(t, key, true, "hey/main office")
+	mockPP = mocks.NewMockPP(mockCtrl)
+	mockPP.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureGatewayLocations), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Gateway location")
+	require.True(t, readGatewayLocations(mockPP, nil, key, &field))
+	require.Equal(t, []api.GatewayLocation{{AccountID: "hey", Name: "main office"}}, field)
+}
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadAccessGroups(t *testing.T) {
+	key := keyPrefix + "ACCESS_GROUPS"
+	set(t, key, true, "hey/office admins,hey")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	gomock.InOrder(
+		mockPP.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureAccessGroups), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Access group"),
+		mockPP.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "account-id/%s"`, "2nd", key, "hey", "group-name"),
+	)
+	var field []api.AccessGroup
+	require.False(t, readAccessGroups(mockPP, nil, key, &field))
+
+	set(t, key, true, "hey/office admins")
+	mockPP = mocks.NewMockPP(mockCtrl)
+	mockPP.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureAccessGroups), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Access group")
+	require.True(t, readAccessGroups(mockPP, nil, key, &field))
+	require.Equal(t, []api.AccessGroup{{AccountID: "hey", Name: "office admins"}}, field)
+}
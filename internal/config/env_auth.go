@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net/mail"
 	"regexp"
 	"strings"
 
@@ -9,7 +10,10 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
-var oauthBearerRegex = regexp.MustCompile(`^[-a-zA-Z0-9._~+/]+=*$`)
+var (
+	oauthBearerRegex = regexp.MustCompile(`^[-a-zA-Z0-9._~+/]+=*$`)
+	globalKeyRegex   = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
 
 // Keys of environment variables.
 const (
@@ -17,6 +21,11 @@ const (
 	tokenKey2     string = "CF_API_TOKEN"
 	tokenFileKey1 string = "CLOUDFLARE_API_TOKEN_FILE"
 	tokenFileKey2 string = "CF_API_TOKEN_FILE"
+
+	globalKeyKey       string = "CLOUDFLARE_API_KEY"
+	globalKeyFileKey   string = "CLOUDFLARE_API_KEY_FILE"
+	globalEmailKey     string = "CLOUDFLARE_API_EMAIL"
+	globalEmailFileKey string = "CLOUDFLARE_API_EMAIL_FILE"
 )
 
 // hintAuthTokenNewPrefix contains the hint about the transition from
@@ -67,9 +76,9 @@ func sanityCheckToken(ppfmt pp.PP, tokenKey string, fromFile bool, token string)
 	return true
 }
 
-func readPlainAuthTokens(ppfmt pp.PP) (tokenKey, token string, ok bool) {
-	token1 := getenv(tokenKey1)
-	token2 := getenv(tokenKey2)
+func readPlainAuthTokens(ppfmt pp.PP, f *File) (tokenKey, token string, ok bool) {
+	token1 := f.getenv(tokenKey1)
+	token2 := f.getenv(tokenKey2)
 
 	switch {
 	case token1 == "" && token2 == "":
@@ -92,8 +101,8 @@ func readPlainAuthTokens(ppfmt pp.PP) (tokenKey, token string, ok bool) {
 	return tokenKey, token, true
 }
 
-func readAuthTokenFile(ppfmt pp.PP, key string) (string, bool) {
-	tokenFile := getenv(key)
+func readAuthTokenFile(ppfmt pp.PP, f *File, key string) (string, bool) {
+	tokenFile := f.getenv(key)
 	if tokenFile == "" {
 		return "", true
 	}
@@ -115,13 +124,13 @@ func readAuthTokenFile(ppfmt pp.PP, key string) (string, bool) {
 	return token, true
 }
 
-func readAuthTokenFiles(ppfmt pp.PP) (tokenKey, token string, ok bool) {
-	token1, ok := readAuthTokenFile(ppfmt, tokenFileKey1)
+func readAuthTokenFiles(ppfmt pp.PP, f *File) (tokenKey, token string, ok bool) {
+	token1, ok := readAuthTokenFile(ppfmt, f, tokenFileKey1)
 	if !ok {
 		return "", "", false
 	}
 
-	token2, ok := readAuthTokenFile(ppfmt, tokenFileKey2)
+	token2, ok := readAuthTokenFile(ppfmt, f, tokenFileKey2)
 	if !ok {
 		return "", "", false
 	}
@@ -141,13 +150,13 @@ func readAuthTokenFiles(ppfmt pp.PP) (tokenKey, token string, ok bool) {
 	}
 }
 
-func readAuthToken(ppfmt pp.PP) (string, bool) {
-	tokenPlainKey, tokenPlain, ok := readPlainAuthTokens(ppfmt)
+func readAuthToken(ppfmt pp.PP, f *File) (string, bool) {
+	tokenPlainKey, tokenPlain, ok := readPlainAuthTokens(ppfmt, f)
 	if !ok {
 		return "", false
 	}
 
-	tokenFromFileKey, tokenFromFile, ok := readAuthTokenFiles(ppfmt)
+	tokenFromFileKey, tokenFromFile, ok := readAuthTokenFiles(ppfmt, f)
 	if !ok {
 		return "", false
 	}
@@ -168,10 +177,11 @@ func readAuthToken(ppfmt pp.PP) (string, bool) {
 		return "", false
 	}
 
-	// A malformed token must be rejected here, not merely warned about. There is
-	// no longer a startup preflight, so a malformed token would otherwise reach
-	// the gateway as an unhinted RequestError (6003/6111) on the first
-	// operation. Failing offline keeps that path unreachable in normal use.
+	// A malformed token must be rejected here, not merely warned about. The
+	// startup permission check is opt-in (CHECK_PERMISSIONS_ON_START), so a
+	// malformed token would otherwise reach the gateway as an unhinted
+	// RequestError (6003/6111) on the first operation. Failing offline keeps
+	// that path unreachable in normal use.
 	if !oauthBearerRegex.MatchString(token) {
 		ppfmt.Noticef(pp.EmojiUserError,
 			"The API token does not follow the OAuth2 bearer token format; double-check the value of %s", tokenKey)
@@ -181,18 +191,147 @@ func readAuthToken(ppfmt pp.PP) (string, bool) {
 	return token, true
 }
 
+// readAuthValue reads one legacy authentication value from either the plain
+// variable plainKey or the file named by fileKey. The returned key is the one
+// that supplied the value, for use in later diagnostics.
+func readAuthValue(ppfmt pp.PP, f *File, plainKey, fileKey, what string) (key, value string, ok bool) {
+	plain := f.getenv(plainKey)
+
+	var fromFile string
+	if path := f.getenv(fileKey); path != "" {
+		fromFile, ok = file.ReadString(ppfmt, path)
+		if !ok {
+			return "", "", false
+		}
+		if fromFile == "" {
+			ppfmt.Noticef(pp.EmojiUserError, "The file specified by %s does not contain %s", fileKey, what)
+			return "", "", false
+		}
+	}
+
+	switch {
+	case plain != "" && fromFile != "" && plain != fromFile:
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The value of %s does not match the content of the file specified by %s; they must specify the same %s",
+			plainKey, fileKey, what)
+		return "", "", false
+	case plain != "":
+		return plainKey, plain, true
+	case fromFile != "":
+		return fileKey, fromFile, true
+	default:
+		return "", "", true
+	}
+}
+
+func sanityCheckGlobalKey(ppfmt pp.PP, key string, value string) bool {
+	if strings.HasPrefix(value, globalKeyKey+"=") {
+		ppfmt.Noticef(pp.EmojiUserError,
+			`The value of %s appears to be an environment file with %q; it should contain only the key itself`,
+			key, globalKeyKey+"=...")
+		return false
+	}
+
+	if tokenHasMatchingQuotes(value) {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The value of %s appears to include surrounding quotation marks; remove the extra quotes", key)
+		return false
+	}
+
+	if !globalKeyRegex.MatchString(value) {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The global API key is not a hexadecimal string; double-check the value of %s", key)
+		return false
+	}
+
+	return true
+}
+
+func sanityCheckGlobalEmail(ppfmt pp.PP, key string, value string) bool {
+	if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"The value of %s (%s) is not an email address", key, pp.QuoteIfUnsafeInSentence(value))
+		return false
+	}
+
+	return true
+}
+
+// readGlobalKeyAuth reads the legacy global API key and its email address.
+// The second return value reports whether either of them was configured.
+func readGlobalKeyAuth(ppfmt pp.PP, f *File) (*api.CloudflareGlobalKeyAuth, bool, bool) {
+	keyKey, key, ok := readAuthValue(ppfmt, f, globalKeyKey, globalKeyFileKey, "a global API key")
+	if !ok {
+		return nil, true, false
+	}
+
+	emailKey, email, ok := readAuthValue(ppfmt, f, globalEmailKey, globalEmailFileKey, "an email address")
+	if !ok {
+		return nil, true, false
+	}
+
+	switch {
+	case key == "" && email == "":
+		return nil, false, true
+	case email == "":
+		ppfmt.Noticef(pp.EmojiUserError,
+			"%s requires %s or %s to be set to the email address of the account", keyKey, globalEmailKey, globalEmailFileKey)
+		return nil, true, false
+	case key == "":
+		ppfmt.Noticef(pp.EmojiUserError,
+			"%s is only used with the legacy global API key; set %s or %s, or use %s instead",
+			emailKey, globalKeyKey, globalKeyFileKey, tokenKey1)
+		return nil, true, false
+	}
+
+	if !sanityCheckGlobalKey(ppfmt, keyKey, key) || !sanityCheckGlobalEmail(ppfmt, emailKey, email) {
+		return nil, true, false
+	}
+
+	for _, tokenKey := range []string{tokenKey1, tokenFileKey1, tokenKey2, tokenFileKey2} {
+		if f.getenv(tokenKey) != "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				"%s and %s cannot be used together; use either an API token or the legacy global API key",
+				tokenKey, keyKey)
+			return nil, true, false
+		}
+	}
+
+	ppfmt.Noticef(pp.EmojiUserWarning,
+		"You are using the legacy global API key (%s), which grants full access to every account and zone owned by %s; "+
+			"anyone who obtains it can take over the whole Cloudflare account. "+
+			"Use a scoped API token (%s) instead if your organization allows it",
+		keyKey, describeRedactedEmail(email), tokenKey1)
+
+	return &api.CloudflareGlobalKeyAuth{Key: key, Email: email, BaseURL: ""}, true, true
+}
+
 // readAuth reads environment variables CLOUDFLARE_API_TOKEN, CLOUDFLARE_API_TOKEN_FILE,
 // CF_API_TOKEN, CF_API_TOKEN_FILE, and CF_ACCOUNT_ID and creates an [api.CloudflareAuth].
-func readAuth(ppfmt pp.PP, field *api.Auth) bool {
-	token, ok := readAuthToken(ppfmt)
+// When the legacy CLOUDFLARE_API_KEY (or CLOUDFLARE_API_KEY_FILE) is set instead,
+// together with CLOUDFLARE_API_EMAIL (or CLOUDFLARE_API_EMAIL_FILE), it creates an
+// [api.CloudflareGlobalKeyAuth].
+func readAuth(ppfmt pp.PP, f *File, field *api.Auth) bool {
+	globalKeyAuth, globalKeySet, ok := readGlobalKeyAuth(ppfmt, f)
 	if !ok {
 		return false
 	}
 
-	if getenv("CF_ACCOUNT_ID") != "" {
+	var auth api.Auth
+	if globalKeySet {
+		auth = globalKeyAuth
+	} else {
+		token, ok := readAuthToken(ppfmt, f)
+		if !ok {
+			return false
+		}
+		auth = &api.CloudflareAuth{Token: token, BaseURL: ""}
+	}
+
+	if f.getenv("CF_ACCOUNT_ID") != "" {
 		ppfmt.Noticef(pp.EmojiUserWarning, "CF_ACCOUNT_ID is ignored since 1.14.0")
 	}
 
-	*field = &api.CloudflareAuth{Token: token, BaseURL: ""}
+	*field = auth
 	return true
 }
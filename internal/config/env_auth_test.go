@@ -258,7 +258,7 @@ func TestReadAuth(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readAuth(mockPP, &field)
+			ok := readAuth(mockPP, nil, &field)
 			require.Equal(t, tc.ok, ok)
 			if tc.expected != "" {
 				require.Equal(t, &api.CloudflareAuth{Token: tc.expected, BaseURL: ""}, field)
@@ -268,3 +268,170 @@ func TestReadAuth(t *testing.T) {
 		})
 	}
 }
+
+//nolint:paralleltest // environment vars and file system are global
+func TestReadAuthGlobalKey(t *testing.T) {
+	const (
+		key   = "0123456789abcdef0123456789abcdef01234"
+		email = "operator@example.org"
+	)
+
+	expectScopeWarning := func(keyKey string) func(*mocks.MockPP) {
+		return func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserWarning,
+				"You are using the legacy global API key (%s), which grants full access to every account and zone owned by %s; "+
+					"anyone who obtains it can take over the whole Cloudflare account. "+
+					"Use a scoped API token (%s) instead if your organization allows it",
+				keyKey, "o***@example.org", "CLOUDFLARE_API_TOKEN")
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		mapFS         map[string]string
+		env           map[string]string
+		ok            bool
+		expected      api.Auth
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"success": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": key, "CLOUDFLARE_API_EMAIL": email},
+			true, &api.CloudflareGlobalKeyAuth{Key: key, Email: email, BaseURL: ""},
+			expectScopeWarning("CLOUDFLARE_API_KEY"),
+		},
+		"file/success": {
+			map[string]string{"key.txt": key + "\n", "email.txt": email},
+			map[string]string{"CLOUDFLARE_API_KEY_FILE": "/key.txt", "CLOUDFLARE_API_EMAIL_FILE": "/email.txt"},
+			true, &api.CloudflareGlobalKeyAuth{Key: key, Email: email, BaseURL: ""},
+			expectScopeWarning("CLOUDFLARE_API_KEY_FILE"),
+		},
+		"file/same/non-file": {
+			map[string]string{"key.txt": key},
+			map[string]string{"CLOUDFLARE_API_KEY": key, "CLOUDFLARE_API_KEY_FILE": "/key.txt", "CLOUDFLARE_API_EMAIL": email},
+			true, &api.CloudflareGlobalKeyAuth{Key: key, Email: email, BaseURL: ""},
+			expectScopeWarning("CLOUDFLARE_API_KEY"),
+		},
+		"file/conflicting/non-file": {
+			map[string]string{"key.txt": "abcdef"},
+			map[string]string{"CLOUDFLARE_API_KEY": key, "CLOUDFLARE_API_KEY_FILE": "/key.txt", "CLOUDFLARE_API_EMAIL": email},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"The value of %s does not match the content of the file specified by %s; they must specify the same %s",
+					"CLOUDFLARE_API_KEY", "CLOUDFLARE_API_KEY_FILE", "a global API key")
+			},
+		},
+		"file/empty": {
+			map[string]string{"key.txt": ""},
+			map[string]string{"CLOUDFLARE_API_KEY_FILE": "/key.txt", "CLOUDFLARE_API_EMAIL": email},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The file specified by %s does not contain %s", "CLOUDFLARE_API_KEY_FILE", "a global API key")
+			},
+		},
+		"file/wrong.path": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": key, "CLOUDFLARE_API_EMAIL_FILE": "/wrong.txt"},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "Failed to read %s: %v", "/wrong.txt", gomock.Any())
+			},
+		},
+		"missing-email": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": key},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s requires %s or %s to be set to the email address of the account", "CLOUDFLARE_API_KEY", "CLOUDFLARE_API_EMAIL", "CLOUDFLARE_API_EMAIL_FILE")
+			},
+		},
+		"missing-key": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_EMAIL": email},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s is only used with the legacy global API key; set %s or %s, or use %s instead", "CLOUDFLARE_API_EMAIL", "CLOUDFLARE_API_KEY", "CLOUDFLARE_API_KEY_FILE", "CLOUDFLARE_API_TOKEN")
+			},
+		},
+		"invalid-key": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": "not-a-key", "CLOUDFLARE_API_EMAIL": email},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The global API key is not a hexadecimal string; double-check the value of %s", "CLOUDFLARE_API_KEY")
+			},
+		},
+		"quoted-key": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": `"` + key + `"`, "CLOUDFLARE_API_EMAIL": email},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The value of %s appears to include surrounding quotation marks; remove the extra quotes", "CLOUDFLARE_API_KEY")
+			},
+		},
+		"env-file-key": {
+			map[string]string{"key.txt": "CLOUDFLARE_API_KEY=" + key},
+			map[string]string{"CLOUDFLARE_API_KEY_FILE": "/key.txt", "CLOUDFLARE_API_EMAIL": email},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, `The value of %s appears to be an environment file with %q; it should contain only the key itself`, "CLOUDFLARE_API_KEY_FILE", "CLOUDFLARE_API_KEY=...")
+			},
+		},
+		"invalid-email": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": key, "CLOUDFLARE_API_EMAIL": "operator"},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The value of %s (%s) is not an email address", "CLOUDFLARE_API_EMAIL", "operator")
+			},
+		},
+		"conflicting-token": {
+			nil,
+			map[string]string{"CLOUDFLARE_API_KEY": key, "CLOUDFLARE_API_EMAIL": email, "CLOUDFLARE_API_TOKEN": "token"},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s and %s cannot be used together; use either an API token or the legacy global API key", "CLOUDFLARE_API_TOKEN", "CLOUDFLARE_API_KEY")
+			},
+		},
+		"conflicting-token-file": {
+			map[string]string{"key.txt": key},
+			map[string]string{"CLOUDFLARE_API_KEY_FILE": "/key.txt", "CLOUDFLARE_API_EMAIL": email, "CF_API_TOKEN_FILE": "/token.txt"},
+			false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s and %s cannot be used together; use either an API token or the legacy global API key", "CF_API_TOKEN_FILE", "CLOUDFLARE_API_KEY_FILE")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			t.Cleanup(file.ResetFSForTesting)
+
+			for _, key := range []string{
+				"CLOUDFLARE_API_TOKEN", "CLOUDFLARE_API_TOKEN_FILE", "CF_API_TOKEN", "CF_API_TOKEN_FILE", "CF_ACCOUNT_ID",
+				"CLOUDFLARE_API_KEY", "CLOUDFLARE_API_KEY_FILE", "CLOUDFLARE_API_EMAIL", "CLOUDFLARE_API_EMAIL_FILE",
+			} {
+				store(t, key, tc.env[key])
+			}
+
+			mapFS := fstest.MapFS{}
+			for path, content := range tc.mapFS {
+				mapFS[path] = &fstest.MapFile{
+					Data:    []byte(content),
+					Mode:    0o644,
+					ModTime: time.Unix(1234, 5678),
+					Sys:     nil,
+				}
+			}
+			useMemFS(mapFS)
+
+			var field api.Auth
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readAuth(mockPP, nil, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, field)
+		})
+	}
+}
@@ -1,28 +1,29 @@
 package config
 
 import (
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/cron"
+	"github.com/favonia/cloudflare-ddns/internal/file"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
-// getenv reads an environment variable and trim the space.
-func getenv(key string) string {
-	return strings.TrimSpace(os.Getenv(key))
+// getenv reads an environment variable (or its value in CONFIG_FILE) and trim the space.
+func (f *File) getenv(key string) string {
+	return strings.TrimSpace(f.lookup(key))
 }
 
-// getenvAsList reads an environment variable, splits it by sep, and trims each item.
+// getenvAsList reads an environment variable (or its value in CONFIG_FILE),
+// splits it by sep, and trims each item.
 // Empty trimmed entries are preserved. Callers remain responsible for assigning any
 // higher-level meaning to empty items, including comma-placement policy.
-func getenvAsList(key string, sep string) []string {
+func (f *File) getenvAsList(key string, sep string) []string {
 	vals := []string{}
-	for v := range strings.SplitSeq(os.Getenv(key), sep) {
+	for v := range strings.SplitSeq(f.lookup(key), sep) {
 		v = strings.TrimSpace(v)
 		vals = append(vals, v)
 	}
@@ -32,8 +33,8 @@ func getenvAsList(key string, sep string) []string {
 // readString reads an environment variable as a plain string.
 //
 //nolint:unparam // Keep the read* helper signature uniform for ReadEnv.
-func readString(ppfmt pp.PP, key string, field *string) bool {
-	val := getenv(key)
+func readString(ppfmt pp.PP, f *File, key string, field *string) bool {
+	val := f.getenv(key)
 	if val == "" {
 		if *field != "" {
 			ppfmt.Infof(pp.EmojiBullet, "Using default %s=%s", key, *field)
@@ -45,9 +46,25 @@ func readString(ppfmt pp.PP, key string, field *string) bool {
 	return true
 }
 
+// readAbsolutePath reads an environment variable as an optional absolute path.
+// Unset or empty input keeps the field unchanged.
+func readAbsolutePath(ppfmt pp.PP, f *File, key string, field *string) bool {
+	val := f.getenv(key)
+	if val == "" {
+		return true
+	}
+
+	if _, ok := file.RequireAbsolutePath(ppfmt, val); !ok {
+		return false
+	}
+
+	*field = val
+	return true
+}
+
 // readBool reads an environment variable as a boolean value.
-func readBool(ppfmt pp.PP, key string, field *bool) bool {
-	val := getenv(key)
+func readBool(ppfmt pp.PP, f *File, key string, field *bool) bool {
+	val := f.getenv(key)
 	if val == "" {
 		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%t", key, *field)
 		return true
@@ -86,8 +103,8 @@ func prefixLenRange(ipFamily ipnet.Family) (int, int) {
 
 // readPrefixLen reads an environment variable as a prefix length for the given
 // IP family. The valid range is derived from the family.
-func readPrefixLen(ppfmt pp.PP, key string, field *int, ipFamily ipnet.Family) bool {
-	val := getenv(key)
+func readPrefixLen(ppfmt pp.PP, f *File, key string, field *int, ipFamily ipnet.Family) bool {
+	val := f.getenv(key)
 	lo, hi := prefixLenRange(ipFamily)
 	if val == "" {
 		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%d", key, *field)
@@ -125,8 +142,8 @@ func readPrefixLen(ppfmt pp.PP, key string, field *int, ipFamily ipnet.Family) b
 //
 // [API documentation]: https://developers.cloudflare.com/api/resources/dns/subresources/records/methods/create/
 // [DNS documentation]: https://developers.cloudflare.com/dns/manage-dns-records/reference/ttl
-func readTTL(ppfmt pp.PP, key string, field *api.TTL) bool {
-	val := getenv(key)
+func readTTL(ppfmt pp.PP, f *File, key string, field *api.TTL) bool {
+	val := f.getenv(key)
 	if val == "" {
 		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%d", key, *field)
 		return true
@@ -149,8 +166,8 @@ func readTTL(ppfmt pp.PP, key string, field *api.TTL) bool {
 }
 
 // readNonnegDuration reads an environment variable and parses it as a time duration.
-func readNonnegDuration(ppfmt pp.PP, key string, field *time.Duration) bool {
-	val := getenv(key)
+func readNonnegDuration(ppfmt pp.PP, f *File, key string, field *time.Duration) bool {
+	val := f.getenv(key)
 	if val == "" {
 		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%v", key, *field)
 		return true
@@ -171,9 +188,31 @@ func readNonnegDuration(ppfmt pp.PP, key string, field *time.Duration) bool {
 	return true
 }
 
+// readNonnegInt reads an environment variable as a non-negative integer.
+func readNonnegInt(ppfmt pp.PP, f *File, key string, field *int) bool {
+	val := f.getenv(key)
+	if val == "" {
+		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%d", key, *field)
+		return true
+	}
+
+	i, err := strconv.Atoi(val)
+	switch {
+	case err != nil:
+		ppfmt.Noticef(pp.EmojiUserError, "%s (%q) is not a number: %v", key, val, err)
+		return false
+	case i < 0:
+		ppfmt.Noticef(pp.EmojiUserError, "%s (%d) is negative", key, i)
+		return false
+	}
+
+	*field = i
+	return true
+}
+
 // readCron reads an environment variable and parses it as a Cron expression.
-func readCron(ppfmt pp.PP, key string, field *cron.Schedule) bool {
-	switch val := getenv(key); val {
+func readCron(ppfmt pp.PP, f *File, key string, field *cron.Schedule) bool {
+	switch val := f.getenv(key); val {
 	case "":
 		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%s", key, cron.DescribeSchedule(*field))
 		return true
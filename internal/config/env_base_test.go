@@ -33,7 +33,7 @@ func TestGetenv(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			set(t, key, tc.set, tc.val)
-			require.Equal(t, tc.expected, getenv(key))
+			require.Equal(t, tc.expected, (*File)(nil).getenv(key))
 		})
 	}
 }
@@ -57,7 +57,7 @@ func TestGetenvAsList(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			set(t, key, tc.set, tc.val)
-			require.Equal(t, tc.expected, getenvAsList(key, tc.sep))
+			require.Equal(t, tc.expected, (*File)(nil).getenvAsList(key, tc.sep))
 		})
 	}
 }
@@ -101,7 +101,43 @@ func TestReadString(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readString(mockPP, key, &field)
+			ok := readString(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
+
+//nolint:paralleltest // environment vars are global
+func TestReadAbsolutePath(t *testing.T) {
+	key := keyPrefix + "PATH"
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      string
+		newField      string
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset":    {false, "", "", "", true, nil},
+		"empty":    {true, " ", "/old", "/old", true, nil},
+		"absolute": {true, " /data/state.json ", "", "/data/state.json", true, nil},
+		"relative": {
+			true, "state.json", "/old", "/old", false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The path %s is not absolute; to use an absolute path, prefix it with /", "state.json")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readAbsolutePath(mockPP, nil, key, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
@@ -168,7 +204,7 @@ func TestReadBool(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readBool(mockPP, key, &field)
+			ok := readBool(mockPP, nil, key, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
@@ -258,7 +294,7 @@ func TestReadPrefixLen(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readPrefixLen(mockPP, key, &field, tc.ipFamily)
+			ok := readPrefixLen(mockPP, nil, key, &field, tc.ipFamily)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
@@ -322,7 +358,7 @@ func TestReadTTL(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readTTL(mockPP, key, &field)
+			ok := readTTL(mockPP, nil, key, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
@@ -376,7 +412,55 @@ func TestReadNonnegDuration(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readNonnegDuration(mockPP, key, &field)
+			ok := readNonnegDuration(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
+
+//nolint:paralleltest // environment vars are global
+func TestReadNonnegInt(t *testing.T) {
+	key := keyPrefix + "INT"
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      int
+		newField      int
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"nil": {
+			false, "", 3, 3, true,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%d", key, 3)
+			},
+		},
+		"5": {true, "   5\t", 0, 5, true, nil},
+		"0": {true, "0", 2, 0, true, nil},
+		"many": {
+			true, "  many  ", 1, 1, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s (%q) is not a number: %v", key, "many", gomock.Any())
+			},
+		},
+		"-1": {
+			true, "-1", 1, 1, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s (%d) is negative", key, -1)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readNonnegInt(mockPP, nil, key, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
@@ -436,7 +520,7 @@ func TestReadCron(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readCron(mockPP, key, &field)
+			ok := readCron(mockPP, nil, key, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
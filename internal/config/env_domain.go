@@ -50,8 +50,8 @@ func reportEntryParseError(ppfmt pp.PP, key string, input string, err *syntax.Pa
 // optional settings with reader-owned defaults: unset or empty input leaves the
 // field empty (nil). The combined empty-scope case is diagnosed later when the
 // scopes are evaluated together.
-func readDomains(ppfmt pp.PP, key string, family *ipnet.Family, field *[]domainentry.Entry) bool {
-	input := getenv(key)
+func readDomains(ppfmt pp.PP, f *File, key string, family *ipnet.Family, field *[]domainentry.Entry) bool {
+	input := f.getenv(key)
 	entries, diagnostics, err := domainentry.Parse(input)
 	if err != nil {
 		reportEntryParseError(ppfmt, key, input, err)
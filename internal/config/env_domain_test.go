@@ -63,7 +63,7 @@ func TestReadDomainsPlainLists(t *testing.T) {
 			field := tc.oldField
 			mockPP := mocks.NewMockPP(gomock.NewController(t))
 
-			ok := readDomains(mockPP, key, nil, &field)
+			ok := readDomains(mockPP, nil, key, nil, &field)
 
 			require.True(t, ok)
 			require.Equal(t, tc.expected, field)
@@ -85,7 +85,7 @@ func TestReadDomainsAcceptsHostID6ForMixedAndIPv6Settings(t *testing.T) {
 			var field []domainentry.Entry
 			mockPP := mocks.NewMockPP(gomock.NewController(t))
 
-			ok := readDomains(mockPP, tc.key, tc.family, &field)
+			ok := readDomains(mockPP, nil, tc.key, tc.family, &field)
 
 			require.True(t, ok)
 			require.Len(t, field, 1)
@@ -101,7 +101,7 @@ func TestReadDomainsAcceptsPlainIPv4Entry(t *testing.T) {
 	var field []domainentry.Entry
 	mockPP := mocks.NewMockPP(gomock.NewController(t))
 
-	ok := readDomains(mockPP, "IP4_DOMAINS", family(ipnet.IP4), &field)
+	ok := readDomains(mockPP, nil, "IP4_DOMAINS", family(ipnet.IP4), &field)
 
 	require.True(t, ok)
 	require.Equal(t, []domainentry.Entry{{
@@ -125,7 +125,7 @@ func TestReadDomainsRejectsHostID6ForIPv4Setting(t *testing.T) {
 		"IP4_DOMAINS",
 	)
 
-	ok := readDomains(mockPP, "IP4_DOMAINS", family(ipnet.IP4), &field)
+	ok := readDomains(mockPP, nil, "IP4_DOMAINS", family(ipnet.IP4), &field)
 
 	require.False(t, ok)
 	require.Equal(t, oldField, field)
@@ -145,7 +145,7 @@ func TestReadDomainsReportsSemanticDiagnosticsInSourceOrder(t *testing.T) {
 		mockPP.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) has %s`, "DOMAINS", value, `invalid hostid6 MAC address "bad": invalid 48-bit MAC address`),
 	)
 
-	ok := readDomains(mockPP, "DOMAINS", nil, &field)
+	ok := readDomains(mockPP, nil, "DOMAINS", nil, &field)
 
 	require.False(t, ok)
 	require.Equal(t, oldField, field)
@@ -163,7 +163,7 @@ func TestReadDomainsReportsCompatibilityWarningsBeforeLaterRecoveredSemanticErro
 		mockPP.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) has %s`, "DOMAINS", value, `invalid domain "localhost": too few labels`),
 	)
 
-	ok := readDomains(mockPP, "DOMAINS", nil, &field)
+	ok := readDomains(mockPP, nil, "DOMAINS", nil, &field)
 
 	require.False(t, ok)
 	require.Nil(t, field)
@@ -181,7 +181,7 @@ func TestReadDomainsReportsExtraTrailingCommasForVersion2(t *testing.T) {
 		"DOMAINS", `"`+value+`"`,
 	)
 
-	ok := readDomains(mockPP, "DOMAINS", nil, &field)
+	ok := readDomains(mockPP, nil, "DOMAINS", nil, &field)
 
 	require.True(t, ok)
 	require.Len(t, field, 1)
@@ -207,7 +207,7 @@ func TestReadDomainsReportsMalformedEntryWithoutParserFormIDs(t *testing.T) {
 				"DOMAINS", value, ",",
 			)
 
-			ok := readDomains(mockPP, "DOMAINS", nil, &field)
+			ok := readDomains(mockPP, nil, "DOMAINS", nil, &field)
 
 			require.False(t, ok)
 			require.Equal(t, oldField, field)
@@ -263,7 +263,7 @@ func TestReadDomainsReportsStructuredEntryParseErrors(t *testing.T) {
 			mockPP := mocks.NewMockPP(gomock.NewController(t))
 			tc.prepareLog(mockPP)
 
-			ok := readDomains(mockPP, "DOMAINS", nil, &field)
+			ok := readDomains(mockPP, nil, "DOMAINS", nil, &field)
 
 			require.False(t, ok)
 			require.Equal(t, oldField, field)
@@ -6,8 +6,8 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
-func readDetectionFilter(ppfmt pp.PP, key string, family ipnet.Family, field *ipfilter.Filter) bool {
-	val := getenv(key)
+func readDetectionFilter(ppfmt pp.PP, f *File, key string, family ipnet.Family, field *ipfilter.Filter) bool {
+	val := f.getenv(key)
 	if val == "" {
 		ppfmt.Infof(pp.EmojiBullet, "Using default %s=%s", key, field.String())
 		return true
@@ -16,7 +16,7 @@ import (
 func TestReadDetectionFilterDefault(t *testing.T) {
 	t.Setenv("TEST_FILTER", "")
 	filter := ipfilter.KeepAll()
-	require.True(t, readDetectionFilter(pp.NewSilent(), "TEST_FILTER", ipnet.IP4, &filter))
+	require.True(t, readDetectionFilter(pp.NewSilent(), nil, "TEST_FILTER", ipnet.IP4, &filter))
 	require.Equal(t, "keep-all", filter.String())
 }
 
@@ -29,7 +29,7 @@ func TestReadDetectionFilterValid(t *testing.T) {
 	mockPP.EXPECT().InfoOncef(pp.MessageExperimentalDetectionFilters, pp.EmojiExperimental,
 		"You are using experimental detection filters (available since version 1.17.0)")
 
-	require.True(t, readDetectionFilter(mockPP, "TEST_FILTER", ipnet.IP4, &filter))
+	require.True(t, readDetectionFilter(mockPP, nil, "TEST_FILTER", ipnet.IP4, &filter))
 	require.Equal(t, "addr-in(198.51.100.0/24)", filter.String())
 }
 
@@ -37,6 +37,6 @@ func TestReadDetectionFilterInvalid(t *testing.T) {
 	t.Setenv("TEST_FILTER", "addr-in(2001:db8::/32)")
 	filter := ipfilter.KeepAll()
 	var output strings.Builder
-	require.False(t, readDetectionFilter(pp.New(&output, false, pp.Quiet), "TEST_FILTER", ipnet.IP4, &filter))
+	require.False(t, readDetectionFilter(pp.New(&output, false, pp.Quiet), nil, "TEST_FILTER", ipnet.IP4, &filter))
 	require.Contains(t, output.String(), `TEST_FILTER ("addr-in(2001:db8::/32)") contains IPv6 prefix`)
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/sliceutil"
+)
+
+// readIPAccessRules reads an environment variable as a comma-separated list of
+// sets of IP Access Rules in the format "scope/id:mode", where the scope is
+// "zone" or "account" and the mode is "allow", "block", or "challenge".
+//
+// Like WAF_LISTS, IP_ACCESS_RULES is a scope declaration: unset or empty input
+// leaves the field empty (nil).
+func readIPAccessRules(ppfmt pp.PP, f *File, key string, field *[]api.IPAccessRuleSet) bool {
+	vals := f.getenvAsList(key, ",")
+	if len(vals) == 1 && vals[0] == "" {
+		*field = nil
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureIPAccessRules)
+
+	sets := make([]api.IPAccessRuleSet, 0, len(vals))
+	for i, val := range vals {
+		if val == "" {
+			continue
+		}
+
+		scope, rest, foundSlash := strings.Cut(val, "/")
+		id, mode, foundColon := strings.Cut(rest, ":")
+		if !foundSlash || !foundColon || id == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) should be in the format "zone/zone-id:mode" or "account/account-id:mode"`,
+				pp.Ordinal(i+1), key, val)
+			return false
+		}
+
+		switch api.IPAccessRuleScope(scope) {
+		case api.IPAccessRuleScopeZone, api.IPAccessRuleScopeAccount:
+		default:
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) has an unknown scope %q; it should be "zone" or "account"`,
+				pp.Ordinal(i+1), key, val, scope)
+			return false
+		}
+
+		switch api.IPAccessRuleMode(mode) {
+		case api.IPAccessRuleModeAllow, api.IPAccessRuleModeBlock, api.IPAccessRuleModeChallenge:
+		default:
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) has an unknown mode %q; it should be "allow", "block", or "challenge"`,
+				pp.Ordinal(i+1), key, val, mode)
+			return false
+		}
+
+		sets = append(sets, api.IPAccessRuleSet{
+			Scope:   api.IPAccessRuleScope(scope),
+			ScopeID: api.ID(id),
+			Mode:    api.IPAccessRuleMode(mode),
+		})
+	}
+
+	*field = sliceutil.SortAndCompact(sets, api.CompareIPAccessRuleSet)
+	return true
+}
@@ -0,0 +1,123 @@
+//nolint:testpackage // These tests exercise the unexported IP-access-rule reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadIPAccessRules(t *testing.T) {
+	key := keyPrefix + "IP_ACCESS_RULES"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureIPAccessRules), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "IP access rule")
+	}
+	zoneBlock := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone", Mode: api.IPAccessRuleModeBlock}
+	accountAllow := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeAccount, ScopeID: "account", Mode: api.IPAccessRuleModeAllow}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      []api.IPAccessRuleSet
+		newField      []api.IPAccessRuleSet
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {
+			false, "",
+			[]api.IPAccessRuleSet{zoneBlock},
+			nil,
+			true,
+			nil,
+		},
+		"empty": {
+			true, "",
+			[]api.IPAccessRuleSet{zoneBlock},
+			nil,
+			true,
+			nil,
+		},
+		"one": {
+			true, "zone/zone:block",
+			nil,
+			[]api.IPAccessRuleSet{zoneBlock},
+			true,
+			experimental,
+		},
+		"challenge": {
+			true, "account/account:challenge",
+			nil,
+			[]api.IPAccessRuleSet{{Scope: api.IPAccessRuleScopeAccount, ScopeID: "account", Mode: api.IPAccessRuleModeChallenge}},
+			true,
+			experimental,
+		},
+		"sorted-and-deduplicated": {
+			true, "zone/zone:block, account/account:allow,,zone/zone:block",
+			nil,
+			[]api.IPAccessRuleSet{accountAllow, zoneBlock},
+			true,
+			experimental,
+		},
+		"missing-mode": {
+			true, "zone/zone",
+			[]api.IPAccessRuleSet{zoneBlock},
+			[]api.IPAccessRuleSet{zoneBlock},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "zone/zone-id:mode" or "account/account-id:mode"`, "1st", key, "zone/zone")
+			},
+		},
+		"missing-id": {
+			true, "zone/zone:block,account/:allow",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "zone/zone-id:mode" or "account/account-id:mode"`, "2nd", key, "account/:allow")
+			},
+		},
+		"unknown-scope": {
+			true, "user/me:block",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) has an unknown scope %q; it should be "zone" or "account"`, "1st", key, "user/me:block", "user")
+			},
+		},
+		"unknown-mode": {
+			true, "zone/zone:whitelist",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) has an unknown mode %q; it should be "allow", "block", or "challenge"`, "1st", key, "zone/zone:whitelist", "whitelist")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readIPAccessRules(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/sliceutil"
+)
+
+// readLBPoolOrigins reads an environment variable as a comma-separated list of
+// load balancer pool origins in the format "account-id/pool-id:origin-name".
+//
+// Like WAF_LISTS, LB_POOL_ORIGINS is a scope declaration: unset or empty input
+// leaves the field empty (nil).
+func readLBPoolOrigins(ppfmt pp.PP, f *File, key string, field *[]api.LBPoolOrigin) bool {
+	vals := f.getenvAsList(key, ",")
+	if len(vals) == 1 && vals[0] == "" {
+		*field = nil
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureLBPoolOrigins)
+
+	origins := make([]api.LBPoolOrigin, 0, len(vals))
+	for i, val := range vals {
+		if val == "" {
+			continue
+		}
+
+		accountID, rest, foundSlash := strings.Cut(val, "/")
+		poolID, originName, foundColon := strings.Cut(rest, ":")
+		if !foundSlash || !foundColon || accountID == "" || poolID == "" || originName == "" {
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) should be in the format "account-id/pool-id:origin-name"`,
+				pp.Ordinal(i+1), key, val)
+			return false
+		}
+
+		origins = append(origins, api.LBPoolOrigin{
+			AccountID:  api.ID(accountID),
+			PoolID:     api.ID(poolID),
+			OriginName: originName,
+		})
+	}
+
+	*field = sliceutil.SortAndCompact(origins, api.CompareLBPoolOrigin)
+	return true
+}
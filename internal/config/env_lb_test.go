@@ -0,0 +1,104 @@
+//nolint:testpackage // These tests exercise the unexported LB-origin reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadLBPoolOrigins(t *testing.T) {
+	key := keyPrefix + "LB_POOL_ORIGINS"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureLBPoolOrigins), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "load balancer pool origin")
+	}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      []api.LBPoolOrigin
+		newField      []api.LBPoolOrigin
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {
+			false, "",
+			[]api.LBPoolOrigin{{AccountID: "there", PoolID: "pool", OriginName: "ciao"}},
+			nil,
+			true,
+			nil,
+		},
+		"empty": {
+			true, "",
+			[]api.LBPoolOrigin{{AccountID: "there", PoolID: "pool", OriginName: "ciao"}},
+			nil,
+			true,
+			nil,
+		},
+		"one": {
+			true, "hey/pool:home lab",
+			nil,
+			[]api.LBPoolOrigin{{AccountID: "hey", PoolID: "pool", OriginName: "home lab"}},
+			true,
+			experimental,
+		},
+		"sorted-and-deduplicated": {
+			true, "hey/pool2:b, hey/pool1:a,,hey/pool2:b",
+			nil,
+			[]api.LBPoolOrigin{
+				{AccountID: "hey", PoolID: "pool1", OriginName: "a"},
+				{AccountID: "hey", PoolID: "pool2", OriginName: "b"},
+			},
+			true,
+			experimental,
+		},
+		"origin-name-with-colon": {
+			true, "hey/pool:a:b",
+			nil,
+			[]api.LBPoolOrigin{{AccountID: "hey", PoolID: "pool", OriginName: "a:b"}},
+			true,
+			experimental,
+		},
+		"missing-origin": {
+			true, "hey/pool",
+			[]api.LBPoolOrigin{{AccountID: "there", PoolID: "pool", OriginName: "ciao"}},
+			[]api.LBPoolOrigin{{AccountID: "there", PoolID: "pool", OriginName: "ciao"}},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "account-id/pool-id:origin-name"`, "1st", key, "hey/pool")
+			},
+		},
+		"missing-account": {
+			true, "hey/pool:a,/pool:b",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "account-id/pool-id:origin-name"`, "2nd", key, "/pool:b")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readLBPoolOrigins(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/domainentry"
+	"github.com/favonia/cloudflare-ddns/internal/file"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/provider/protocol"
+)
+
+// readLocalResolverProvider reads an environment variable as a provider of
+// internal addresses. Only "local.iface:...", "static:...", "static.empty",
+// and "none" are allowed, because other providers detect public addresses.
+// Unset or empty input means "none".
+func readLocalResolverProvider(ppfmt pp.PP, f *File, key string,
+	ipFamily ipnet.Family, defaultPrefixLen int, field *provider.Provider,
+) bool {
+	val := f.getenv(key)
+	if val == "" {
+		*field = nil
+		return true
+	}
+
+	var p provider.Provider
+	if !readProvider(ppfmt, f, key, "", ipFamily, defaultPrefixLen, &p) {
+		return false
+	}
+	switch p.(type) {
+	case nil, protocol.LocalWithInterface, protocol.Static:
+		*field = p
+		return true
+	default:
+		ppfmt.Noticef(pp.EmojiUserError,
+			`%s (%q) should be "local.iface:...", "static:...", "static.empty", or "none"`, key, val)
+		return false
+	}
+}
+
+// readLocalResolver reads the settings of the local resolver fragment.
+// Unset or empty LOCAL_RESOLVER_FILE disables the fragment.
+func readLocalResolver(ppfmt pp.PP, f *File, defaultPrefixLen map[ipnet.Family]int, field **LocalResolverConfig) bool {
+	const (
+		fileKey    = "LOCAL_RESOLVER_FILE"
+		formatKey  = "LOCAL_RESOLVER_FORMAT"
+		domainsKey = "LOCAL_RESOLVER_DOMAINS"
+		ip4Key     = "LOCAL_RESOLVER_IP4_PROVIDER"
+		ip6Key     = "LOCAL_RESOLVER_IP6_PROVIDER"
+		reloadKey  = "LOCAL_RESOLVER_RELOAD_COMMAND"
+	)
+
+	path := f.getenv(fileKey)
+	if path == "" {
+		for _, key := range []string{formatKey, domainsKey, ip4Key, ip6Key, reloadKey} {
+			if val := f.getenv(key); val != "" {
+				ppfmt.Noticef(pp.EmojiUserWarning, "%s (%s) is ignored because %s is empty",
+					key, previewSettingValue(val), fileKey)
+			}
+		}
+		*field = nil
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureLocalResolver)
+
+	if _, ok := file.RequireAbsolutePath(ppfmt, path); !ok {
+		return false
+	}
+
+	format := localdns.FormatHosts
+	if val := f.getenv(formatKey); val != "" {
+		f, ok := localdns.ParseFormat(val)
+		if !ok {
+			ppfmt.Noticef(pp.EmojiUserError, `%s (%q) should be "hosts", "dnsmasq", or "unbound"`, formatKey, val)
+			return false
+		}
+		format = f
+	}
+
+	var entries []domainentry.Entry
+	if !readDomains(ppfmt, f, domainsKey, nil, &entries) {
+		return false
+	}
+	for _, entry := range entries {
+		if len(entry.HostID6Opinions) > 0 {
+			ppfmt.Noticef(pp.EmojiUserError, "%s configures hostid6 for %s, but hostid6 is not supported in %s",
+				domainsKey, entry.Domain.Describe(), domainsKey)
+			return false
+		}
+		if _, ok := entry.Domain.(domain.Wildcard); ok && !format.SupportsWildcards() {
+			ppfmt.Noticef(pp.EmojiUserError, "%s=%s does not support wildcard domains such as %s",
+				formatKey, format, entry.Domain.Describe())
+			return false
+		}
+	}
+	domains := projectDomains(entries)
+	if len(domains) == 0 {
+		ppfmt.Noticef(pp.EmojiUserError, "%s is set but %s is empty", fileKey, domainsKey)
+		return false
+	}
+
+	var ip4Provider, ip6Provider provider.Provider
+	if !readLocalResolverProvider(ppfmt, f, ip4Key, ipnet.IP4, defaultPrefixLen[ipnet.IP4], &ip4Provider) ||
+		!readLocalResolverProvider(ppfmt, f, ip6Key, ipnet.IP6, defaultPrefixLen[ipnet.IP6], &ip6Provider) {
+		return false
+	}
+	providers := map[ipnet.Family]provider.Provider{}
+	if ip4Provider != nil {
+		providers[ipnet.IP4] = ip4Provider
+	}
+	if ip6Provider != nil {
+		providers[ipnet.IP6] = ip6Provider
+	}
+	if len(providers) == 0 {
+		ppfmt.Noticef(pp.EmojiUserError, "%s is set but both %s and %s are %q",
+			fileKey, ip4Key, ip6Key, provider.Name(nil))
+		return false
+	}
+
+	// The command is split at spaces without a shell; an empty command means no reloading.
+	var reloadCommand []string
+	if fields := strings.Fields(f.getenv(reloadKey)); len(fields) > 0 {
+		reloadCommand = fields
+	}
+
+	*field = &LocalResolverConfig{
+		Sink: localdns.Sink{
+			Path:          path,
+			Format:        format,
+			ReloadCommand: reloadCommand,
+		},
+		Provider: providers,
+		Domains:  domains,
+	}
+	return true
+}
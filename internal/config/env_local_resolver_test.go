@@ -0,0 +1,154 @@
+//nolint:testpackage // These tests exercise the unexported local resolver reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadLocalResolver(t *testing.T) {
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureLocalResolver), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "local resolver")
+	}
+	experimentalIface := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimentalLocalWithInterface, pp.EmojiExperimental, `You are using the experimental "local.iface:..." provider available since version 1.15.0`)
+	}
+	userError := func(format string, args ...any) func(*mocks.MockPP) {
+		return func(m *mocks.MockPP) {
+			experimental(m)
+			m.EXPECT().Noticef(pp.EmojiUserError, format, args...)
+		}
+	}
+	old := &LocalResolverConfig{
+		Sink:     localdns.Sink{Path: "/old", Format: localdns.FormatHosts, ReloadCommand: nil},
+		Provider: map[ipnet.Family]provider.Provider{ipnet.IP4: provider.MustNewStatic(ipnet.IP4, 32, "10.0.0.1")},
+		Domains:  []domain.Domain{domain.FQDN("old.example.org")},
+	}
+
+	for name, tc := range map[string]struct {
+		env           map[string]string
+		oldField      *LocalResolverConfig
+		newField      *LocalResolverConfig
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {nil, old, nil, true, nil},
+		"ignored": {
+			map[string]string{"LOCAL_RESOLVER_DOMAINS": "a.example.org"},
+			old, nil, true,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserWarning, "%s (%s) is ignored because %s is empty", "LOCAL_RESOLVER_DOMAINS", `"a.example.org"`, "LOCAL_RESOLVER_FILE")
+			},
+		},
+		"hosts": {
+			map[string]string{
+				"LOCAL_RESOLVER_FILE":         "/etc/hosts.d/ddns",
+				"LOCAL_RESOLVER_DOMAINS":      "b.example.org, a.example.org",
+				"LOCAL_RESOLVER_IP4_PROVIDER": "static:192.168.1.2",
+			},
+			nil,
+			&LocalResolverConfig{
+				Sink:     localdns.Sink{Path: "/etc/hosts.d/ddns", Format: localdns.FormatHosts, ReloadCommand: nil},
+				Provider: map[ipnet.Family]provider.Provider{ipnet.IP4: provider.MustNewStatic(ipnet.IP4, 32, "192.168.1.2")},
+				Domains:  []domain.Domain{domain.FQDN("a.example.org"), domain.FQDN("b.example.org")},
+			},
+			true, experimental,
+		},
+		"dnsmasq": {
+			map[string]string{
+				"LOCAL_RESOLVER_FILE":           "/etc/dnsmasq.d/ddns.conf",
+				"LOCAL_RESOLVER_FORMAT":         "dnsmasq",
+				"LOCAL_RESOLVER_DOMAINS":        "*.lab.example.org",
+				"LOCAL_RESOLVER_IP4_PROVIDER":   "none",
+				"LOCAL_RESOLVER_IP6_PROVIDER":   "local.iface:eth0",
+				"LOCAL_RESOLVER_RELOAD_COMMAND": "  systemctl   reload dnsmasq ",
+			},
+			nil,
+			&LocalResolverConfig{
+				Sink: localdns.Sink{
+					Path: "/etc/dnsmasq.d/ddns.conf", Format: localdns.FormatDnsmasq,
+					ReloadCommand: []string{"systemctl", "reload", "dnsmasq"},
+				},
+				Provider: map[ipnet.Family]provider.Provider{ipnet.IP6: provider.MustNewLocalWithInterface("eth0")},
+				Domains:  []domain.Domain{domain.Wildcard("lab.example.org")},
+			},
+			true,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				experimentalIface(m)
+			},
+		},
+		"relative-path": {
+			map[string]string{"LOCAL_RESOLVER_FILE": "hosts"}, old, old, false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, "The path %s is not absolute; to use an absolute path, prefix it with /", "hosts")
+			},
+		},
+		"invalid-format": {
+			map[string]string{"LOCAL_RESOLVER_FILE": "/etc/bind/ddns", "LOCAL_RESOLVER_FORMAT": "bind"}, old, old, false,
+			userError(`%s (%q) should be "hosts", "dnsmasq", or "unbound"`, "LOCAL_RESOLVER_FORMAT", "bind"),
+		},
+		"hosts-wildcard": {
+			map[string]string{"LOCAL_RESOLVER_FILE": "/etc/hosts.d/ddns", "LOCAL_RESOLVER_DOMAINS": "*.example.org"}, old, old, false,
+			userError("%s=%s does not support wildcard domains such as %s", "LOCAL_RESOLVER_FORMAT", localdns.FormatHosts, "*.example.org"),
+		},
+		"hostid6": {
+			map[string]string{"LOCAL_RESOLVER_FILE": "/etc/hosts.d/ddns", "LOCAL_RESOLVER_DOMAINS": "example.org{hostid6=::1}"}, old, old, false,
+			userError("%s configures hostid6 for %s, but hostid6 is not supported in %s", "LOCAL_RESOLVER_DOMAINS", "example.org", "LOCAL_RESOLVER_DOMAINS"),
+		},
+		"no-domains": {
+			map[string]string{"LOCAL_RESOLVER_FILE": "/etc/hosts.d/ddns"}, old, old, false,
+			userError("%s is set but %s is empty", "LOCAL_RESOLVER_FILE", "LOCAL_RESOLVER_DOMAINS"),
+		},
+		"public-provider": {
+			map[string]string{
+				"LOCAL_RESOLVER_FILE":         "/etc/hosts.d/ddns",
+				"LOCAL_RESOLVER_DOMAINS":      "a.example.org",
+				"LOCAL_RESOLVER_IP4_PROVIDER": "cloudflare.trace",
+			},
+			old, old, false,
+			userError(`%s (%q) should be "local.iface:...", "static:...", "static.empty", or "none"`, "LOCAL_RESOLVER_IP4_PROVIDER", "cloudflare.trace"),
+		},
+		"no-providers": {
+			map[string]string{
+				"LOCAL_RESOLVER_FILE":         "/etc/hosts.d/ddns",
+				"LOCAL_RESOLVER_DOMAINS":      "a.example.org",
+				"LOCAL_RESOLVER_IP6_PROVIDER": "none",
+			},
+			old, old, false,
+			userError("%s is set but both %s and %s are %q", "LOCAL_RESOLVER_FILE", "LOCAL_RESOLVER_IP4_PROVIDER", "LOCAL_RESOLVER_IP6_PROVIDER", "none"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{
+				"LOCAL_RESOLVER_FILE", "LOCAL_RESOLVER_FORMAT", "LOCAL_RESOLVER_DOMAINS",
+				"LOCAL_RESOLVER_IP4_PROVIDER", "LOCAL_RESOLVER_IP6_PROVIDER", "LOCAL_RESOLVER_RELOAD_COMMAND",
+			} {
+				val, ok := tc.env[key]
+				set(t, key, ok, val)
+			}
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readLocalResolver(mockPP, nil, map[ipnet.Family]int{ipnet.IP4: 32, ipnet.IP6: 64}, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
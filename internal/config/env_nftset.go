@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/sliceutil"
+)
+
+// readNFTablesSets reads an environment variable as a comma-separated list
+// of nftables sets in the format "family table name".
+//
+// Like WAF_LISTS, NFTABLES_SETS is a scope declaration: unset or empty
+// input leaves the field empty (nil).
+func readNFTablesSets(ppfmt pp.PP, f *File, key string, field *[]nftset.Set) bool {
+	vals := f.getenvAsList(key, ",")
+	if len(vals) == 1 && vals[0] == "" {
+		*field = nil
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureNFTablesSets)
+
+	sets := make([]nftset.Set, 0, len(vals))
+	for i, val := range vals {
+		if val == "" {
+			continue
+		}
+
+		set, ok := nftset.ParseSet(val)
+		if !ok {
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) should be in the format "family table name", such as "inet filter home_prefixes"`,
+				pp.Ordinal(i+1), key, val)
+			return false
+		}
+
+		sets = append(sets, set)
+	}
+
+	*field = sliceutil.SortAndCompact(sets, nftset.CompareSet)
+	return true
+}
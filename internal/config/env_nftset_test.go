@@ -0,0 +1,84 @@
+//nolint:testpackage // These tests exercise the unexported nftables-set reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadNFTablesSets(t *testing.T) {
+	key := keyPrefix + "NFTABLES_SETS"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureNFTablesSets), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "nftables set")
+	}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      []nftset.Set
+		newField      []nftset.Set
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {
+			false, "",
+			[]nftset.Set{{Family: "inet", Table: "filter", Name: "old"}},
+			nil,
+			true,
+			nil,
+		},
+		"empty": {
+			true, "",
+			[]nftset.Set{{Family: "inet", Table: "filter", Name: "old"}},
+			nil,
+			true,
+			nil,
+		},
+		"one": {
+			true, "inet filter home_prefixes",
+			nil,
+			[]nftset.Set{{Family: "inet", Table: "filter", Name: "home_prefixes"}},
+			true,
+			experimental,
+		},
+		"sorted-and-deduplicated": {
+			true, "ip6 fw b, inet filter a,,ip6  fw  b",
+			nil,
+			[]nftset.Set{{Family: "inet", Table: "filter", Name: "a"}, {Family: "ip6", Table: "fw", Name: "b"}},
+			true,
+			experimental,
+		},
+		"invalid": {
+			true, "inet filter a, filter b",
+			[]nftset.Set{{Family: "inet", Table: "filter", Name: "old"}},
+			[]nftset.Set{{Family: "inet", Table: "filter", Name: "old"}},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "family table name", such as "inet filter home_prefixes"`, "2nd", key, "filter b")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readNFTablesSets(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
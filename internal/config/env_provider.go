@@ -11,14 +11,14 @@ import (
 // readProvider reads an environment variable and parses it as a provider.
 //
 // keyDeprecated was the name of the deprecated parameters IP4/6_POLICY.
-func readProvider(ppfmt pp.PP, key, keyDeprecated string,
+func readProvider(ppfmt pp.PP, f *File, key, keyDeprecated string,
 	ipFamily ipnet.Family, defaultPrefixLen int, field *provider.Provider,
 ) bool {
-	val := getenv(key)
+	val := f.getenv(key)
 
 	if val == "" {
 		// parsing of the deprecated parameter
-		switch valDeprecated := getenv(keyDeprecated); valDeprecated {
+		switch valDeprecated := f.getenv(keyDeprecated); valDeprecated {
 		case "":
 			ppfmt.Infof(pp.EmojiBullet, "Using default %s=%s", key, provider.Name(*field))
 			return true
@@ -76,7 +76,7 @@ func readProvider(ppfmt pp.PP, key, keyDeprecated string,
 		}
 	}
 
-	if getenv(keyDeprecated) != "" {
+	if f.getenv(keyDeprecated) != "" {
 		ppfmt.Noticef(
 			pp.EmojiUserError,
 			`Cannot have both %s and %s set`,
@@ -170,6 +170,15 @@ func readProvider(ppfmt pp.PP, key, keyDeprecated string,
 		}
 		*field = p
 		return true
+	case len(parts) == 1 && parts[0] == "dyndns2-server":
+		ppfmt.InfoOncef(pp.MessageExperimentalDynDNS2Server, pp.EmojiExperimental,
+			`You are using the experimental "dyndns2-server" provider available since version 1.18.0`)
+		if f.getenv("DYNDNS2_ADDR") == "" {
+			ppfmt.Noticef(pp.EmojiUserError, "%s=dyndns2-server needs DYNDNS2_ADDR to receive the addresses", key)
+			return false
+		}
+		*field = provider.NewDynDNS2Server()
+		return true
 	case len(parts) == 1 && parts[0] == "none":
 		*field = nil
 		return true
@@ -186,7 +195,7 @@ func readProvider(ppfmt pp.PP, key, keyDeprecated string,
 
 // readProviderMap reads the environment variables IP4_PROVIDER and IP6_PROVIDER,
 // with support of deprecated environment variables IP4_POLICY and IP6_POLICY.
-func readProviderMap(ppfmt pp.PP, defaultPrefixLen map[ipnet.Family]int,
+func readProviderMap(ppfmt pp.PP, f *File, defaultPrefixLen map[ipnet.Family]int,
 	field *map[ipnet.Family]provider.Provider,
 ) bool {
 	// Read into temporary values so both families can report errors and neither
@@ -196,6 +205,7 @@ func readProviderMap(ppfmt pp.PP, defaultPrefixLen map[ipnet.Family]int,
 
 	ip4OK := readProvider(
 		ppfmt,
+		f,
 		"IP4_PROVIDER",
 		"IP4_POLICY",
 		ipnet.IP4,
@@ -204,6 +214,7 @@ func readProviderMap(ppfmt pp.PP, defaultPrefixLen map[ipnet.Family]int,
 	)
 	ip6OK := readProvider(
 		ppfmt,
+		f,
 		"IP6_PROVIDER",
 		"IP6_POLICY",
 		ipnet.IP6,
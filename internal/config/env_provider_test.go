@@ -370,13 +370,50 @@ func TestReadProvider(t *testing.T) {
 				tc.prepareMockPP(mockPP)
 			}
 			defaultPrefixLen := map[ipnet.Family]int{ipnet.IP4: 32, ipnet.IP6: 64}[tc.ipFamily]
-			ok := readProvider(mockPP, key, keyDeprecated, tc.ipFamily, defaultPrefixLen, &field)
+			ok := readProvider(mockPP, nil, key, keyDeprecated, tc.ipFamily, defaultPrefixLen, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
 	}
 }
 
+//nolint:paralleltest // environment vars are global
+func TestReadProviderDynDNS2Server(t *testing.T) {
+	key := keyPrefix + "PROVIDER"
+	keyDeprecated := keyPrefix + "DEPRECATED"
+	set(t, key, true, " dyndns2-server ")
+	set(t, keyDeprecated, false, "")
+
+	for name, tc := range map[string]struct {
+		addr          string
+		ok            bool
+		expected      provider.Provider
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"listening": {":8245", true, provider.NewDynDNS2Server(), nil},
+		"not-listening": {"", false, nil, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "%s=dyndns2-server needs DYNDNS2_ADDR to receive the addresses", key)
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, "DYNDNS2_ADDR", true, tc.addr)
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			mockPP.EXPECT().InfoOncef(pp.MessageExperimentalDynDNS2Server, pp.EmojiExperimental,
+				`You are using the experimental "dyndns2-server" provider available since version 1.18.0`)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			var field provider.Provider
+			ok := readProvider(mockPP, nil, key, keyDeprecated, ipnet.IP4, 32, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, field)
+		})
+	}
+}
+
 //nolint:paralleltest // environment vars are global
 func TestRetiredCloudflareTrace(t *testing.T) {
 	retiredProvider := "cloudflare.trace:" + retiredCloudflareTraceURL
@@ -461,6 +498,7 @@ func TestRetiredCloudflareTrace(t *testing.T) {
 			var output strings.Builder
 			ok := readProviderMap(
 				pp.New(&output, false, tc.verbosity),
+				nil,
 				map[ipnet.Family]int{ipnet.IP4: 32, ipnet.IP6: 64},
 				&providers,
 			)
@@ -560,7 +598,7 @@ func TestRetiredCloudflareTraceStartupTranscripts(t *testing.T) {
 			ppfmt := pp.New(&output, true, tc.verbosity)
 			ppfmt.Infof(pp.EmojiStar, "Cloudflare DDNS")
 			raw := DefaultRaw()
-			require.False(t, raw.ReadEnv(ppfmt))
+			require.False(t, raw.ReadEnv(ppfmt, nil))
 			ppfmt.Infof(pp.EmojiBye, "Bye!")
 
 			rendered := output.String()
@@ -716,7 +754,7 @@ func TestReadProviderMap(t *testing.T) {
 				tc.prepareMockPP(mockPP)
 			}
 			mockPP.EXPECT().DrainRequests(pp.MessageRetiredCustomCloudflareTraceProvider).Return(uint(0))
-			ok := readProviderMap(mockPP, map[ipnet.Family]int{ipnet.IP4: 32, ipnet.IP6: 64}, &field)
+			ok := readProviderMap(mockPP, nil, map[ipnet.Family]int{ipnet.IP4: 32, ipnet.IP6: 64}, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.expected, field)
 		})
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/base64"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// rfc2136DefaultPort is the port used when the server does not specify one.
+const rfc2136DefaultPort = "53"
+
+// parseRFC2136Server parses "host" or "host:port", including bracketed IPv6
+// addresses, and fills in the default port.
+func parseRFC2136Server(val string) (string, bool) {
+	if ip, err := netip.ParseAddr(val); err == nil {
+		return net.JoinHostPort(ip.String(), rfc2136DefaultPort), true
+	}
+	if strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]") {
+		val += ":" + rfc2136DefaultPort
+	} else if !strings.Contains(val, ":") {
+		val += ":" + rfc2136DefaultPort
+	}
+
+	host, port, err := net.SplitHostPort(val)
+	if err != nil || host == "" || strings.ContainsAny(host, " /") {
+		return "", false
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return "", false
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// parseTSIGKey parses a TSIG key in the format "[hmac-sha256:]key-name:base64-secret",
+// which is the format of the -y option of nsupdate.
+func parseTSIGKey(val string) (string, []byte, bool) {
+	parts := strings.Split(val, ":")
+	switch {
+	case len(parts) == 3 && strings.EqualFold(parts[0], "hmac-sha256"):
+		parts = parts[1:]
+	case len(parts) != 2:
+		return "", nil, false
+	}
+
+	name := strings.TrimSuffix(parts[0], ".")
+	if name == "" || strings.Contains(name, "..") || strings.HasPrefix(name, ".") {
+		return "", nil, false
+	}
+	secret, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || len(secret) == 0 {
+		return "", nil, false
+	}
+	return strings.ToLower(name) + ".", secret, true
+}
+
+// readRFC2136 reads the server and the TSIG key for mirroring DNS records to
+// a server accepting dynamic updates (RFC 2136). Both must be set together;
+// unset or empty input disables the mirroring. The secret is never printed.
+func readRFC2136(ppfmt pp.PP, f *File, serverKey, tsigKey string, field **api.RFC2136Auth) bool {
+	server := f.getenv(serverKey)
+	key := f.getenv(tsigKey)
+	switch {
+	case server == "" && key == "":
+		*field = nil
+		return true
+	case server == "":
+		ppfmt.Noticef(pp.EmojiUserError, "%s is set but %s is empty", tsigKey, serverKey)
+		return false
+	case key == "":
+		ppfmt.Noticef(pp.EmojiUserError,
+			"%s is set but %s is empty; updates without TSIG keys are not supported", serverKey, tsigKey)
+		return false
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureRFC2136)
+
+	address, ok := parseRFC2136Server(server)
+	if !ok {
+		ppfmt.Noticef(pp.EmojiUserError, `%s (%q) should be in the format "host" or "host:port"`, serverKey, server)
+		return false
+	}
+	keyName, secret, ok := parseTSIGKey(key)
+	if !ok {
+		// The value is not shown because it contains the secret.
+		ppfmt.Noticef(pp.EmojiUserError,
+			`%s should be in the format "[hmac-sha256:]key-name:base64-secret"`, tsigKey)
+		return false
+	}
+
+	*field = &api.RFC2136Auth{Server: address, KeyName: keyName, Secret: secret}
+	return true
+}
@@ -0,0 +1,86 @@
+//nolint:testpackage // These tests exercise the unexported RFC 2136 reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadRFC2136(t *testing.T) {
+	serverKey := keyPrefix + "RFC2136_SERVER"
+	tsigKey := keyPrefix + "RFC2136_TSIG_KEY"
+	secret := "c2VjcmV0" // "secret"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureRFC2136), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "RFC 2136 mirroring")
+	}
+	invalidServer := func(val string) func(*mocks.MockPP) {
+		return func(m *mocks.MockPP) {
+			experimental(m)
+			m.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) should be in the format "host" or "host:port"`, serverKey, val)
+		}
+	}
+	invalidKey := func(m *mocks.MockPP) {
+		experimental(m)
+		m.EXPECT().Noticef(pp.EmojiUserError, `%s should be in the format "[hmac-sha256:]key-name:base64-secret"`, tsigKey)
+	}
+	auth := func(server, keyName string) *api.RFC2136Auth {
+		return &api.RFC2136Auth{Server: server, KeyName: keyName, Secret: []byte("secret")}
+	}
+	old := auth("old.example.org:53", "old.")
+
+	for name, tc := range map[string]struct {
+		server        string
+		key           string
+		oldField      *api.RFC2136Auth
+		newField      *api.RFC2136Auth
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"empty":             {"", "", old, nil, true, nil},
+		"host":              {"ns.example.org", "ddns:" + secret, nil, auth("ns.example.org:53", "ddns."), true, experimental},
+		"host-port":         {"ns.example.org:5353", "hmac-sha256:DDNS.example.org.:" + secret, nil, auth("ns.example.org:5353", "ddns.example.org."), true, experimental},
+		"ip4":               {"192.0.2.53", "ddns:" + secret, nil, auth("192.0.2.53:53", "ddns."), true, experimental},
+		"ip6":               {"2001:db8::53", "ddns:" + secret, nil, auth("[2001:db8::53]:53", "ddns."), true, experimental},
+		"ip6-brackets":      {"[2001:db8::53]", "ddns:" + secret, nil, auth("[2001:db8::53]:53", "ddns."), true, experimental},
+		"ip6-brackets-port": {"[2001:db8::53]:5353", "HMAC-SHA256:ddns:" + secret, nil, auth("[2001:db8::53]:5353", "ddns."), true, experimental},
+		"missing-server": {"", "ddns:" + secret, old, old, false, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "%s is set but %s is empty", tsigKey, serverKey)
+		}},
+		"missing-key": {"ns.example.org", "", old, old, false, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "%s is set but %s is empty; updates without TSIG keys are not supported", serverKey, tsigKey)
+		}},
+		"invalid-port":        {"ns.example.org:dns", "ddns:" + secret, old, old, false, invalidServer("ns.example.org:dns")},
+		"zero-port":           {"ns.example.org:0", "ddns:" + secret, old, old, false, invalidServer("ns.example.org:0")},
+		"missing-host":        {":53", "ddns:" + secret, old, old, false, invalidServer(":53")},
+		"url":                 {"https://ns.example.org", "ddns:" + secret, old, old, false, invalidServer("https://ns.example.org")},
+		"key/other-algorithm": {"ns.example.org", "hmac-md5:ddns:" + secret, old, old, false, invalidKey},
+		"key/missing-name":    {"ns.example.org", ":" + secret, old, old, false, invalidKey},
+		"key/missing-secret":  {"ns.example.org", "ddns", old, old, false, invalidKey},
+		"key/empty-secret":    {"ns.example.org", "ddns:", old, old, false, invalidKey},
+		"key/not-base64":      {"ns.example.org", "ddns:not base64", old, old, false, invalidKey},
+		"key/empty-label":     {"ns.example.org", "ddns..example:" + secret, old, old, false, invalidKey},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, serverKey, tc.server != "", tc.server)
+			set(t, tsigKey, tc.key != "", tc.key)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readRFC2136(mockPP, nil, serverKey, tsigKey, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/sliceutil"
+)
+
+// readSpectrumApps reads an environment variable as a comma-separated list
+// of Spectrum applications in the format "zone-id/app-id".
+//
+// Like WAF_LISTS, SPECTRUM_APPS is a scope declaration: unset or empty
+// input leaves the field empty (nil).
+func readSpectrumApps(ppfmt pp.PP, f *File, key string, field *[]api.SpectrumApp) bool {
+	vals := f.getenvAsList(key, ",")
+	if len(vals) == 1 && vals[0] == "" {
+		*field = nil
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureSpectrumApps)
+
+	apps := make([]api.SpectrumApp, 0, len(vals))
+	for i, val := range vals {
+		if val == "" {
+			continue
+		}
+
+		zoneID, appID, found := strings.Cut(val, "/")
+		if !found || zoneID == "" || appID == "" || strings.Contains(appID, "/") {
+			ppfmt.Noticef(pp.EmojiUserError,
+				`The %s entry of %s (%q) should be in the format "zone-id/app-id"`,
+				pp.Ordinal(i+1), key, val)
+			return false
+		}
+
+		apps = append(apps, api.SpectrumApp{ZoneID: api.ID(zoneID), AppID: api.ID(appID)})
+	}
+
+	*field = sliceutil.SortAndCompact(apps, api.CompareSpectrumApp)
+	return true
+}
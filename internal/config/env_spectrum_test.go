@@ -0,0 +1,104 @@
+//nolint:testpackage // These tests exercise the unexported Spectrum-app reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadSpectrumApps(t *testing.T) {
+	key := keyPrefix + "SPECTRUM_APPS"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureSpectrumApps), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Spectrum app")
+	}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      []api.SpectrumApp
+		newField      []api.SpectrumApp
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {
+			false, "",
+			[]api.SpectrumApp{{ZoneID: "zone", AppID: "app"}},
+			nil,
+			true,
+			nil,
+		},
+		"empty": {
+			true, "",
+			[]api.SpectrumApp{{ZoneID: "zone", AppID: "app"}},
+			nil,
+			true,
+			nil,
+		},
+		"one": {
+			true, "zone/app",
+			nil,
+			[]api.SpectrumApp{{ZoneID: "zone", AppID: "app"}},
+			true,
+			experimental,
+		},
+		"sorted-and-deduplicated": {
+			true, "zone/b, zone/a,,zone/b",
+			nil,
+			[]api.SpectrumApp{{ZoneID: "zone", AppID: "a"}, {ZoneID: "zone", AppID: "b"}},
+			true,
+			experimental,
+		},
+		"missing-app": {
+			true, "zone/app,zone/",
+			[]api.SpectrumApp{{ZoneID: "zone", AppID: "app"}},
+			[]api.SpectrumApp{{ZoneID: "zone", AppID: "app"}},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "zone-id/app-id"`, "2nd", key, "zone/")
+			},
+		},
+		"missing-slash": {
+			true, "app",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "zone-id/app-id"`, "1st", key, "app")
+			},
+		},
+		"extra-slash": {
+			true, "zone/app/extra",
+			nil,
+			nil,
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `The %s entry of %s (%q) should be in the format "zone-id/app-id"`, "1st", key, "zone/app/extra")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readSpectrumApps(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
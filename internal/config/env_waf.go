@@ -33,8 +33,8 @@ var inverseWAFListNameRegex = regexp.MustCompile(`[^a-z0-9_]`)
 // and Enterprise allows 1,000. That quota snapshot is watched by the
 // Cloudflare WAF list availability case in
 // scripts/github-actions/cloudflare-doc-watch/cases.go.
-func readWAFListNames(ppfmt pp.PP, key string, field *[]api.WAFList) bool {
-	vals := getenvAsList(key, ",")
+func readWAFListNames(ppfmt pp.PP, f *File, key string, field *[]api.WAFList) bool {
+	vals := f.getenvAsList(key, ",")
 	if len(vals) == 1 && vals[0] == "" {
 		*field = nil
 		return true
@@ -82,7 +82,7 @@ func readWAFListNames(ppfmt pp.PP, key string, field *[]api.WAFList) bool {
 		ppfmt.Noticef(pp.EmojiUserWarning,
 			"%s (%s) contains extra commas; "+
 				"this is accepted for now but will be rejected in version 2.0.0",
-			key, pp.QuotePreviewOrEmptyLabel(getenv(key), pp.AdvisoryPreviewLimit, "empty"))
+			key, pp.QuotePreviewOrEmptyLabel(f.getenv(key), pp.AdvisoryPreviewLimit, "empty"))
 	}
 
 	*field = sliceutil.SortAndCompact(lists, api.CompareWAFList)
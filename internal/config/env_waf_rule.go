@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// wafListRulePlaceholder is replaced by the references to the managed WAF lists
+// in WAF_LIST_RULE_EXPRESSION.
+const wafListRulePlaceholder = "{list}"
+
+const (
+	defaultWAFListRuleExpression  = "ip.src in " + wafListRulePlaceholder
+	defaultWAFListRuleDescription = "Managed by Cloudflare DDNS"
+)
+
+// readWAFListRule reads an environment variable as a WAF custom rule in the
+// format "zone-id:action". Unset or empty input disables the rule.
+func readWAFListRule(ppfmt pp.PP, f *File, key string, field *api.WAFListRule) bool {
+	val := f.getenv(key)
+	if val == "" {
+		*field = api.WAFListRule{ZoneID: "", Action: ""}
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureWAFListRule)
+
+	zoneID, action, found := strings.Cut(val, ":")
+	if !found || zoneID == "" || strings.Contains(zoneID, "/") {
+		ppfmt.Noticef(pp.EmojiUserError, `%s (%q) should be in the format "zone-id:action"`, key, val)
+		return false
+	}
+	switch action {
+	case "block", "challenge", "js_challenge", "managed_challenge", "log", "skip":
+	default:
+		ppfmt.Noticef(pp.EmojiUserError,
+			`%s (%q) has an unknown action %q; it should be "block", "challenge", "js_challenge", `+
+				`"managed_challenge", "log", or "skip"`,
+			key, val, action)
+		return false
+	}
+
+	*field = api.WAFListRule{ZoneID: api.ID(zoneID), Action: action}
+	return true
+}
+
+// instantiateWAFListRuleExpression replaces the placeholder with the reference
+// to each list. The expressions for multiple lists are joined by "or".
+func instantiateWAFListRuleExpression(template string, lists []api.WAFList) string {
+	expressions := make([]string, 0, len(lists))
+	for _, list := range lists {
+		expressions = append(expressions, strings.ReplaceAll(template, wafListRulePlaceholder, "$"+list.Name))
+	}
+	if len(expressions) == 1 {
+		return expressions[0]
+	}
+	for i := range expressions {
+		expressions[i] = "(" + expressions[i] + ")"
+	}
+	return strings.Join(expressions, " or ")
+}
@@ -0,0 +1,140 @@
+//nolint:testpackage // These tests exercise the unexported WAF custom rule helpers directly because they are package-local logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadWAFListRule(t *testing.T) {
+	key := keyPrefix + "WAF_LIST_RULE"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureWAFListRule), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "WAF custom rule")
+	}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      api.WAFListRule
+		newField      api.WAFListRule
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {
+			false, "",
+			api.WAFListRule{ZoneID: "zone", Action: "block"},
+			api.WAFListRule{ZoneID: "", Action: ""},
+			true,
+			nil,
+		},
+		"empty": {
+			true, "",
+			api.WAFListRule{ZoneID: "zone", Action: "block"},
+			api.WAFListRule{ZoneID: "", Action: ""},
+			true,
+			nil,
+		},
+		"skip": {
+			true, "zone:skip",
+			api.WAFListRule{ZoneID: "", Action: ""},
+			api.WAFListRule{ZoneID: "zone", Action: "skip"},
+			true,
+			experimental,
+		},
+		"managed-challenge": {
+			true, "zone:managed_challenge",
+			api.WAFListRule{ZoneID: "", Action: ""},
+			api.WAFListRule{ZoneID: "zone", Action: "managed_challenge"},
+			true,
+			experimental,
+		},
+		"missing-colon": {
+			true, "zone",
+			api.WAFListRule{ZoneID: "", Action: ""},
+			api.WAFListRule{ZoneID: "", Action: ""},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) should be in the format "zone-id:action"`, key, "zone")
+			},
+		},
+		"missing-zone": {
+			true, ":block",
+			api.WAFListRule{ZoneID: "", Action: ""},
+			api.WAFListRule{ZoneID: "", Action: ""},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) should be in the format "zone-id:action"`, key, ":block")
+			},
+		},
+		"list-reference": {
+			true, "account/list:block",
+			api.WAFListRule{ZoneID: "", Action: ""},
+			api.WAFListRule{ZoneID: "", Action: ""},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) should be in the format "zone-id:action"`, key, "account/list:block")
+			},
+		},
+		"unknown-action": {
+			true, "zone:allow",
+			api.WAFListRule{ZoneID: "", Action: ""},
+			api.WAFListRule{ZoneID: "", Action: ""},
+			false,
+			func(m *mocks.MockPP) {
+				experimental(m)
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					`%s (%q) has an unknown action %q; it should be "block", "challenge", "js_challenge", `+
+						`"managed_challenge", "log", or "skip"`,
+					key, "zone:allow", "allow")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readWAFListRule(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
+
+func TestInstantiateWAFListRuleExpression(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		template string
+		lists    []api.WAFList
+		expected string
+	}{
+		"one":      {"ip.src in {list}", []api.WAFList{{AccountID: "account", Name: "home"}}, "ip.src in $home"},
+		"repeated": {"ip.src in {list} and not ip.src in {list}", []api.WAFList{{AccountID: "account", Name: "home"}}, "ip.src in $home and not ip.src in $home"},
+		"two": {
+			"ip.src in {list}",
+			[]api.WAFList{{AccountID: "account", Name: "a"}, {AccountID: "account", Name: "b"}},
+			"(ip.src in $a) or (ip.src in $b)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, instantiateWAFListRuleExpression(tc.template, tc.lists))
+		})
+	}
+}
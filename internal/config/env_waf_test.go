@@ -151,7 +151,7 @@ func TestReadWAFListNames(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			ok := readWAFListNames(mockPP, key, &field)
+			ok := readWAFListNames(mockPP, nil, key, &field)
 			require.Equal(t, tc.ok, ok)
 			require.Equal(t, tc.newField, field)
 		})
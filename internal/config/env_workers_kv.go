@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// readWorkersKV reads an environment variable as a Workers KV key in the
+// format "account-id/namespace-id:key". The key itself may contain any
+// character, including "/" and ":". Unset or empty input disables publishing.
+func readWorkersKV(ppfmt pp.PP, f *File, key string, field *api.WorkersKVKey) bool {
+	val := f.getenv(key)
+	if val == "" {
+		*field = api.WorkersKVKey{AccountID: "", NamespaceID: "", Key: ""}
+		return true
+	}
+
+	pp.InfoExperimental(ppfmt, pp.FeatureWorkersKV)
+
+	namespace, kvKey, found := strings.Cut(val, ":")
+	accountID, namespaceID, foundSlash := strings.Cut(namespace, "/")
+	if !found || !foundSlash || accountID == "" || namespaceID == "" || kvKey == "" ||
+		strings.Contains(namespaceID, "/") {
+		ppfmt.Noticef(pp.EmojiUserError,
+			`%s (%q) should be in the format "account-id/namespace-id:key"`, key, val)
+		return false
+	}
+
+	*field = api.WorkersKVKey{AccountID: api.ID(accountID), NamespaceID: api.ID(namespaceID), Key: kvKey}
+	return true
+}
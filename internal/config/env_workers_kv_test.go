@@ -0,0 +1,64 @@
+//nolint:testpackage // These tests exercise the unexported Workers KV reader directly because it is package-local helper logic.
+package config
+
+// vim: nowrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // paralleltest should not be used because environment vars are global
+func TestReadWorkersKV(t *testing.T) {
+	key := keyPrefix + "WORKERS_KV"
+	experimental := func(m *mocks.MockPP) {
+		m.EXPECT().InfoOncef(pp.MessageExperimental(pp.FeatureWorkersKV), pp.EmojiExperimental, "You are using the experimental %s feature available since version 1.18.0", "Workers KV")
+	}
+	invalid := func(val string) func(*mocks.MockPP) {
+		return func(m *mocks.MockPP) {
+			experimental(m)
+			m.EXPECT().Noticef(pp.EmojiUserError, `%s (%q) should be in the format "account-id/namespace-id:key"`, key, val)
+		}
+	}
+	none := api.WorkersKVKey{AccountID: "", NamespaceID: "", Key: ""}
+	old := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "old"}
+
+	for name, tc := range map[string]struct {
+		set           bool
+		val           string
+		oldField      api.WorkersKVKey
+		newField      api.WorkersKVKey
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset":             {false, "", old, none, true, nil},
+		"empty":             {true, "", old, none, true, nil},
+		"valid":             {true, "account/namespace:home", none, api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}, true, experimental},
+		"key-with-colon":    {true, "account/namespace:ip:home/v1", none, api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "ip:home/v1"}, true, experimental},
+		"missing-key":       {true, "account/namespace:", old, old, false, invalid("account/namespace:")},
+		"missing-colon":     {true, "account/namespace", old, old, false, invalid("account/namespace")},
+		"missing-slash":     {true, "namespace:home", old, old, false, invalid("namespace:home")},
+		"missing-account":   {true, "/namespace:home", old, old, false, invalid("/namespace:home")},
+		"extra-slash":       {true, "account/namespace/x:home", old, old, false, invalid("account/namespace/x:home")},
+		"missing-namespace": {true, "account/:home", old, old, false, invalid("account/:home")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, key, tc.set, tc.val)
+			field := tc.oldField
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+			ok := readWorkersKV(mockPP, nil, key, &field)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.newField, field)
+		})
+	}
+}
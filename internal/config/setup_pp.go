@@ -2,7 +2,9 @@ package config
 
 import (
 	"io"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
@@ -24,7 +26,7 @@ import (
 func SetupPP(output io.Writer) (pp.PP, bool) {
 	emoji, verbosity := true, pp.Verbose
 
-	valEmoji, valQuiet := getenv("EMOJI"), getenv("QUIET")
+	valEmoji, valQuiet := strings.TrimSpace(os.Getenv("EMOJI")), strings.TrimSpace(os.Getenv("QUIET"))
 
 	if valEmoji != "" {
 		b, err := strconv.ParseBool(valEmoji)
@@ -2,7 +2,6 @@ package config
 
 import (
 	"net/url"
-	"os"
 	"slices"
 	"strings"
 
@@ -252,14 +251,16 @@ func reconcileShoutrrrURLs(ppfmt pp.PP,
 // and SHOUTRRR_FILE.
 //
 // Omitting any of these settings is semantically equivalent to setting that
-// variable to the empty string.
-func SetupReporters(ppfmt pp.PP) (heartbeat.Heartbeat, notifier.Notifier, bool) {
+// variable to the empty string. Like [RawConfig.ReadEnv], it falls back to f,
+// the file loaded by [LoadConfigFile].
+func SetupReporters(ppfmt pp.PP, f *File) (heartbeat.Heartbeat, notifier.Notifier, bool) {
+	ppfmt = f.withSources(ppfmt)
 	emptyHeartbeat := heartbeat.NewComposed()
 	emptyNotifier := notifier.NewComposed()
 	hb := emptyHeartbeat
 	nt := emptyNotifier
 
-	if healthchecksURL := getenv("HEALTHCHECKS"); healthchecksURL != "" {
+	if healthchecksURL := f.getenv("HEALTHCHECKS"); healthchecksURL != "" {
 		h, ok := heartbeat.NewHealthchecks(ppfmt, healthchecksURL)
 		if !ok {
 			return emptyHeartbeat, emptyNotifier, false
@@ -267,7 +268,7 @@ func SetupReporters(ppfmt pp.PP) (heartbeat.Heartbeat, notifier.Notifier, bool)
 		hb = heartbeat.NewComposed(hb, h)
 	}
 
-	if uptimeKumaURL := getenv("UPTIMEKUMA"); uptimeKumaURL != "" {
+	if uptimeKumaURL := f.getenv("UPTIMEKUMA"); uptimeKumaURL != "" {
 		h, ok := heartbeat.NewUptimeKuma(ppfmt, uptimeKumaURL)
 		if !ok {
 			return emptyHeartbeat, emptyNotifier, false
@@ -275,14 +276,14 @@ func SetupReporters(ppfmt pp.PP) (heartbeat.Heartbeat, notifier.Notifier, bool)
 		hb = heartbeat.NewComposed(hb, h)
 	}
 
-	shoutrrrRaw := os.Getenv("SHOUTRRR")
+	shoutrrrRaw := f.lookup("SHOUTRRR")
 	envParticipates := strings.TrimSpace(shoutrrrRaw) != ""
 	envShoutrrrURLs, ok := parseShoutrrrURLs(ppfmt, shoutrrrSource{name: "SHOUTRRR", raw: shoutrrrRaw})
 	if !ok {
 		return emptyHeartbeat, emptyNotifier, false
 	}
 
-	shoutrrrFilePath := getenv("SHOUTRRR_FILE")
+	shoutrrrFilePath := f.getenv("SHOUTRRR_FILE")
 	fileParticipates := shoutrrrFilePath != ""
 	fileShoutrrrURLs, ok := readShoutrrrFileURLs(ppfmt, shoutrrrFilePath)
 	if !ok {
@@ -238,7 +238,7 @@ func TestSetupReportersShoutrrrFile(t *testing.T) {
 				tc.prepareMockPP(mockPP)
 			}
 
-			_, nt, ok := config.SetupReporters(mockPP)
+			_, nt, ok := config.SetupReporters(mockPP, nil)
 			require.Equal(t, tc.ok, ok)
 
 			switch {
@@ -25,7 +25,7 @@ func TestSetupReportersOmissionMatchesCanonicalExplicitValues(t *testing.T) {
 		set(t, "SHOUTRRR", setShoutrrr, shoutrrr)
 		unset(t, "SHOUTRRR_FILE")
 
-		hb, nt, ok := config.SetupReporters(pp.NewSilent())
+		hb, nt, ok := config.SetupReporters(pp.NewSilent(), nil)
 		require.True(t, ok)
 		return hb, nt
 	}
@@ -169,7 +169,7 @@ func TestSetupReportersHeartbeat(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			hb, nt, ok := config.SetupReporters(mockPP)
+			hb, nt, ok := config.SetupReporters(mockPP, nil)
 			require.Equal(t, tc.ok, ok)
 			tc.check(t, hb, nt)
 		})
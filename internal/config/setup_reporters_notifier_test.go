@@ -284,7 +284,7 @@ func TestSetupReportersNotifier(t *testing.T) {
 			if tc.prepareMockPP != nil {
 				tc.prepareMockPP(mockPP)
 			}
-			hb, nt, ok := config.SetupReporters(mockPP)
+			hb, nt, ok := config.SetupReporters(mockPP, nil)
 			require.Equal(t, tc.ok, ok)
 			tc.check(t, hb, nt)
 		})
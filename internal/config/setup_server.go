@@ -0,0 +1,166 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/file"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// The settings read in this file are settings of the whole process, like the
+// ones read by [SetupPP]. They cannot be changed by profiles or by reloading
+// the configuration, so they are read from the environment and the top level
+// of CONFIG_FILE.
+
+// readListenAddr reads the address of an HTTP listener. The empty string means
+// that the listener is disabled.
+func readListenAddr(ppfmt pp.PP, f *File, key string) (string, bool) {
+	ppfmt = f.withSources(ppfmt)
+	addr := f.getenv(key)
+	if addr == "" {
+		return "", true
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		ppfmt.Noticef(pp.EmojiUserError, "%s (%q) is not a valid listening address: %v", key, addr, err)
+		return "", false
+	}
+	return addr, true
+}
+
+// ReadMetricsAddr reads METRICS_ADDR, the address of the HTTP listener for the
+// Prometheus metrics. The empty string means that the listener is disabled.
+func ReadMetricsAddr(ppfmt pp.PP, f *File) (string, bool) {
+	return readListenAddr(ppfmt, f, "METRICS_ADDR")
+}
+
+// ReadHealthAddr reads HEALTH_ADDR, the address of the HTTP listener for the
+// health, readiness, and status endpoints. The empty string means that the
+// listener is disabled.
+func ReadHealthAddr(ppfmt pp.PP, f *File) (string, bool) {
+	return readListenAddr(ppfmt, f, "HEALTH_ADDR")
+}
+
+// Readiness decides when the updater is ready for the readiness probes.
+type Readiness string
+
+const (
+	// ReadinessLastRound means that the last round of every profile succeeded.
+	ReadinessLastRound Readiness = "last-round"
+	// ReadinessAnyRound means that every profile had a successful round since the start.
+	ReadinessAnyRound Readiness = "any-round"
+	// ReadinessAlways means that the updater is ready whenever it is running.
+	ReadinessAlways Readiness = "always"
+)
+
+// ReadReadiness reads READINESS. The default is [ReadinessLastRound].
+func ReadReadiness(ppfmt pp.PP, f *File) (Readiness, bool) {
+	ppfmt = f.withSources(ppfmt)
+	switch val := strings.ToLower(f.getenv("READINESS")); Readiness(val) {
+	case "", ReadinessLastRound:
+		return ReadinessLastRound, true
+	case ReadinessAnyRound, ReadinessAlways:
+		return Readiness(val), true
+	default:
+		ppfmt.Noticef(pp.EmojiUserError, "READINESS (%q) should be %q, %q, or %q",
+			val, ReadinessLastRound, ReadinessAnyRound, ReadinessAlways)
+		return "", false
+	}
+}
+
+// DefaultTriggerMinInterval is the default value of TRIGGER_MIN_INTERVAL.
+const DefaultTriggerMinInterval = time.Minute
+
+// TriggerConfig holds the settings of the webhook that triggers an immediate
+// round of updating.
+type TriggerConfig struct {
+	Addr        string        // the address of the HTTP listener; empty means disabled
+	Token       string        // the bearer token the callers must present
+	MinInterval time.Duration // the minimum time between two accepted triggers, including DynDNS2 pushes
+}
+
+// ReadTrigger reads TRIGGER_ADDR, TRIGGER_TOKEN_FILE, and TRIGGER_MIN_INTERVAL.
+// The token can only be read from a file so that it does not show up in the
+// environment of the process. TRIGGER_MIN_INTERVAL also limits the rounds
+// triggered by the DynDNS2 server, so it is read when either server is enabled.
+func ReadTrigger(ppfmt pp.PP, f *File) (TriggerConfig, bool) {
+	ppfmt = f.withSources(ppfmt)
+	c := TriggerConfig{Addr: "", Token: "", MinInterval: DefaultTriggerMinInterval}
+
+	addr, ok := readListenAddr(ppfmt, f, "TRIGGER_ADDR")
+	if !ok || (addr == "" && f.getenv("DYNDNS2_ADDR") == "") {
+		return c, ok
+	}
+
+	token := ""
+	if addr != "" {
+		tokenFile := f.getenv("TRIGGER_TOKEN_FILE")
+		if tokenFile == "" {
+			ppfmt.Noticef(pp.EmojiUserError, "TRIGGER_TOKEN_FILE must be set when TRIGGER_ADDR is set")
+			return c, false
+		}
+		if token, ok = file.ReadString(ppfmt, tokenFile); !ok {
+			return c, false
+		}
+		if token == "" {
+			ppfmt.Noticef(pp.EmojiUserError, "The file specified by TRIGGER_TOKEN_FILE does not contain a token")
+			return c, false
+		}
+	}
+
+	if !readNonnegDuration(ppfmt, f, "TRIGGER_MIN_INTERVAL", &c.MinInterval) {
+		return c, false
+	}
+
+	c.Addr, c.Token = addr, token
+	return c, true
+}
+
+// DynDNS2Config holds the settings of the DynDNS2 server, which receives the
+// addresses for the dyndns2-server provider.
+type DynDNS2Config struct {
+	Addr     string // the address of the HTTP listener; empty means disabled
+	Username string // the username of the basic authentication
+	Password string // the password of the basic authentication
+
+	// UseClientAddress is whether an update without addresses uses the address
+	// of the client.
+	UseClientAddress bool
+}
+
+// ReadDynDNS2 reads DYNDNS2_ADDR, DYNDNS2_USERNAME, DYNDNS2_PASSWORD_FILE, and
+// DYNDNS2_USE_CLIENT_ADDRESS. Like TRIGGER_TOKEN_FILE, the password can only
+// be read from a file.
+func ReadDynDNS2(ppfmt pp.PP, f *File) (DynDNS2Config, bool) {
+	ppfmt = f.withSources(ppfmt)
+	c := DynDNS2Config{Addr: "", Username: "", Password: "", UseClientAddress: false}
+
+	addr, ok := readListenAddr(ppfmt, f, "DYNDNS2_ADDR")
+	if !ok || addr == "" {
+		return c, ok
+	}
+
+	username := f.getenv("DYNDNS2_USERNAME")
+	passwordFile := f.getenv("DYNDNS2_PASSWORD_FILE")
+	if username == "" || passwordFile == "" {
+		ppfmt.Noticef(pp.EmojiUserError,
+			"DYNDNS2_USERNAME and DYNDNS2_PASSWORD_FILE must be set when DYNDNS2_ADDR is set")
+		return c, false
+	}
+	password, ok := file.ReadString(ppfmt, passwordFile)
+	if !ok {
+		return c, false
+	}
+	if password == "" {
+		ppfmt.Noticef(pp.EmojiUserError, "The file specified by DYNDNS2_PASSWORD_FILE does not contain a password")
+		return c, false
+	}
+	if !readBool(ppfmt, f, "DYNDNS2_USE_CLIENT_ADDRESS", &c.UseClientAddress) {
+		return c, false
+	}
+
+	c.Addr, c.Username, c.Password = addr, username, password
+	return c, true
+}
@@ -0,0 +1,245 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+//nolint:paralleltest // environment vars are global
+func TestReadMetricsAddr(t *testing.T) {
+	for name, tc := range map[string]struct {
+		val           string
+		addr          string
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {"", "", true, nil},
+		"port":  {":9090", ":9090", true, nil},
+		"host":  {" 127.0.0.1:9090 ", "127.0.0.1:9090", true, nil},
+		"ipv6":  {"[::1]:9090", "[::1]:9090", true, nil},
+		"no-port": {"127.0.0.1", "", false, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "%s (%q) is not a valid listening address: %v",
+				"METRICS_ADDR", "127.0.0.1", gomock.Any())
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, "METRICS_ADDR", true, tc.val)
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			addr, ok := config.ReadMetricsAddr(mockPP, nil)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.addr, addr)
+		})
+	}
+}
+
+//nolint:paralleltest // environment vars are global
+func TestReadHealthAddr(t *testing.T) {
+	set(t, "HEALTH_ADDR", true, ":8080")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	addr, ok := config.ReadHealthAddr(mockPP, nil)
+	require.True(t, ok)
+	require.Equal(t, ":8080", addr)
+}
+
+//nolint:paralleltest // environment vars are global
+func TestReadReadiness(t *testing.T) {
+	for name, tc := range map[string]struct {
+		val           string
+		readiness     config.Readiness
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset":      {"", config.ReadinessLastRound, true, nil},
+		"last-round": {"last-round", config.ReadinessLastRound, true, nil},
+		"any-round":  {" Any-Round ", config.ReadinessAnyRound, true, nil},
+		"always":     {"always", config.ReadinessAlways, true, nil},
+		"invalid": {"never", "", false, func(m *mocks.MockPP) {
+			m.EXPECT().Noticef(pp.EmojiUserError, "READINESS (%q) should be %q, %q, or %q",
+				"never", config.ReadinessLastRound, config.ReadinessAnyRound, config.ReadinessAlways)
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, "READINESS", true, tc.val)
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			readiness, ok := config.ReadReadiness(mockPP, nil)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.readiness, readiness)
+		})
+	}
+}
+
+//nolint:paralleltest // environment vars are global
+func TestReadTrigger(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+	for name, tc := range map[string]struct {
+		addr          string
+		tokenFile     string
+		minInterval   string
+		expected      config.TriggerConfig
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {"", tokenFile, "", config.TriggerConfig{Addr: "", Token: "", MinInterval: time.Minute}, true, nil},
+		"default-interval": {
+			":8081", tokenFile, "",
+			config.TriggerConfig{Addr: ":8081", Token: "secret", MinInterval: time.Minute},
+			true,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%v", "TRIGGER_MIN_INTERVAL", time.Minute)
+			},
+		},
+		"interval": {
+			":8081", tokenFile, "10s",
+			config.TriggerConfig{Addr: ":8081", Token: "secret", MinInterval: 10 * time.Second},
+			true, nil,
+		},
+		"no-token": {
+			":8081", "", "", config.TriggerConfig{Addr: "", Token: "", MinInterval: time.Minute}, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "TRIGGER_TOKEN_FILE must be set when TRIGGER_ADDR is set")
+			},
+		},
+		"empty-token": {
+			":8081", emptyFile, "", config.TriggerConfig{Addr: "", Token: "", MinInterval: time.Minute}, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The file specified by TRIGGER_TOKEN_FILE does not contain a token")
+			},
+		},
+		"negative-interval": {
+			":8081", tokenFile, "-1s", config.TriggerConfig{Addr: "", Token: "", MinInterval: time.Minute}, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s (%v) is negative", "TRIGGER_MIN_INTERVAL", -time.Second)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, "TRIGGER_ADDR", true, tc.addr)
+			set(t, "TRIGGER_TOKEN_FILE", true, tc.tokenFile)
+			set(t, "TRIGGER_MIN_INTERVAL", true, tc.minInterval)
+			set(t, "DYNDNS2_ADDR", true, "")
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			c, ok := config.ReadTrigger(mockPP, nil)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, c)
+		})
+	}
+}
+
+//nolint:paralleltest // environment vars are global
+//nolint:paralleltest // environment vars are global
+func TestReadTriggerForDynDNS2(t *testing.T) {
+	set(t, "TRIGGER_ADDR", true, "")
+	set(t, "TRIGGER_TOKEN_FILE", true, "")
+	set(t, "TRIGGER_MIN_INTERVAL", true, "10s")
+	set(t, "DYNDNS2_ADDR", true, ":8245")
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	c, ok := config.ReadTrigger(mockPP, nil)
+	require.True(t, ok)
+	require.Equal(t, config.TriggerConfig{Addr: "", Token: "", MinInterval: 10 * time.Second}, c)
+}
+
+func TestReadDynDNS2(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("secret\n"), 0o600))
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+	for name, tc := range map[string]struct {
+		addr          string
+		username      string
+		passwordFile  string
+		clientAddress string
+		expected      config.DynDNS2Config
+		ok            bool
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"unset": {"", "router", passwordFile, "", config.DynDNS2Config{Addr: "", Username: "", Password: "", UseClientAddress: false}, true, nil},
+		"set": {
+			":8245", "router", passwordFile, "",
+			config.DynDNS2Config{Addr: ":8245", Username: "router", Password: "secret", UseClientAddress: false}, true,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiBullet, "Using default %s=%t", "DYNDNS2_USE_CLIENT_ADDRESS", false)
+			},
+		},
+		"client-address": {
+			":8245", "router", passwordFile, "true",
+			config.DynDNS2Config{Addr: ":8245", Username: "router", Password: "secret", UseClientAddress: true}, true, nil,
+		},
+		"client-address-invalid": {
+			":8245", "router", passwordFile, "maybe",
+			config.DynDNS2Config{Addr: "", Username: "", Password: "", UseClientAddress: false}, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "%s (%q) is not a boolean: %v",
+					"DYNDNS2_USE_CLIENT_ADDRESS", "maybe", gomock.Any())
+			},
+		},
+		"no-username": {
+			":8245", "", passwordFile, "", config.DynDNS2Config{Addr: "", Username: "", Password: "", UseClientAddress: false}, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError,
+					"DYNDNS2_USERNAME and DYNDNS2_PASSWORD_FILE must be set when DYNDNS2_ADDR is set")
+			},
+		},
+		"empty-password": {
+			":8245", "router", emptyFile, "", config.DynDNS2Config{Addr: "", Username: "", Password: "", UseClientAddress: false}, false,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The file specified by DYNDNS2_PASSWORD_FILE does not contain a password")
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			set(t, "DYNDNS2_ADDR", true, tc.addr)
+			set(t, "DYNDNS2_USERNAME", true, tc.username)
+			set(t, "DYNDNS2_PASSWORD_FILE", true, tc.passwordFile)
+			set(t, "DYNDNS2_USE_CLIENT_ADDRESS", true, tc.clientAddress)
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			c, ok := config.ReadDynDNS2(mockPP, nil)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, c)
+		})
+	}
+}
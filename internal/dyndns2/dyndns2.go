@@ -0,0 +1,306 @@
+// Package dyndns2 emulates the server side of the DynDNS2 protocol, so that a
+// router can push its WAN addresses to the updater with "/nic/update" instead
+// of the updater detecting them.
+package dyndns2
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+)
+
+// A Store keeps the addresses pushed last for one profile, by the IP family,
+// and the hostnames whose updates go to the profile.
+type Store struct {
+	mutex     sync.Mutex
+	hostnames map[string]bool
+	ips       map[ipnet.Family][]netip.Addr
+}
+
+// NewStore creates an empty [Store] that accepts no hostnames.
+func NewStore() *Store {
+	return &Store{mutex: sync.Mutex{}, hostnames: map[string]bool{}, ips: map[ipnet.Family][]netip.Addr{}}
+}
+
+// SetHostnames replaces the hostnames whose updates go to the store. The nil
+// list accepts the updates of every hostname.
+func (s *Store) SetHostnames(hostnames []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if hostnames == nil {
+		s.hostnames = nil
+		return
+	}
+	s.hostnames = map[string]bool{}
+	for _, hostname := range hostnames {
+		s.hostnames[domain.StringToASCII(hostname)] = true
+	}
+}
+
+// accepts reports whether the update of any of the hostnames goes to the store.
+func (s *Store) accepts(hostnames []string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.hostnames == nil {
+		return true
+	}
+	return slices.ContainsFunc(hostnames, func(hostname string) bool {
+		return s.hostnames[domain.StringToASCII(hostname)]
+	})
+}
+
+// Set replaces the addresses of an IP family and reports whether they changed.
+func (s *Store) Set(ipFamily ipnet.Family, ips []netip.Addr) bool {
+	ips = slices.Clone(ips)
+	slices.SortFunc(ips, netip.Addr.Compare)
+	ips = slices.Compact(ips)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	old, ok := s.ips[ipFamily]
+	s.ips[ipFamily] = ips
+	return !ok || !slices.Equal(old, ips)
+}
+
+// Get returns the addresses of an IP family, if any were pushed. The nil store
+// never has any.
+func (s *Store) Get(ipFamily ipnet.Family) ([]netip.Addr, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ips, ok := s.ips[ipFamily]
+	return slices.Clone(ips), ok
+}
+
+// A Server answers the DynDNS2 updates. The zero value is not usable; use [NewServer].
+type Server struct {
+	username         []byte
+	password         []byte
+	useClientAddress bool
+	limiter          *trigger.Limiter
+
+	mutex  sync.Mutex
+	stores []*Store
+	// The trigger rejected by the limiter, pushed again when the limiter accepts it.
+	deferredFamilies map[ipnet.Family]bool
+	deferredSources  []string
+	deferredTimer    *time.Timer
+}
+
+// NewServer creates a [Server] that accepts the clients with the credentials.
+// The pushed addresses are saved in the stores added by [Server.AddStore], and
+// a round of updating is triggered through limiter for the IP families whose
+// addresses changed. A trigger rejected by limiter is pushed again as soon as
+// limiter accepts it. When an update has no addresses, the address of the
+// client is used only if useClientAddress is true.
+func NewServer(username, password string, useClientAddress bool, limiter *trigger.Limiter) *Server {
+	return &Server{
+		username:         []byte(username),
+		password:         []byte(password),
+		useClientAddress: useClientAddress,
+		limiter:          limiter,
+		mutex:            sync.Mutex{},
+		stores:           nil,
+		deferredFamilies: nil,
+		deferredSources:  nil,
+		deferredTimer:    nil,
+	}
+}
+
+// AddStore adds the store of a profile, which receives the updates of the
+// hostnames set by [Store.SetHostnames].
+func (s *Server) AddStore(store *Store) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stores = append(s.stores, store)
+}
+
+// storesFor returns the stores that receive the updates of the hostnames.
+func (s *Server) storesFor(hostnames []string) []*Store {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var stores []*Store
+	for _, store := range s.stores {
+		if store.accepts(hostnames) {
+			stores = append(stores, store)
+		}
+	}
+	return stores
+}
+
+// push triggers a round of updating for the IP families. If the limiter rejects
+// the trigger, it is merged into the deferred one, which is pushed again when
+// the limiter is expected to accept it.
+func (s *Server) push(families map[ipnet.Family]bool, source string) {
+	_, wait := s.limiter.Push(families, source, time.Now())
+	if wait <= 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.deferredFamilies == nil {
+		s.deferredFamilies = map[ipnet.Family]bool{}
+	}
+	for ipFamily := range families {
+		s.deferredFamilies[ipFamily] = true
+	}
+	if !slices.Contains(s.deferredSources, source) {
+		s.deferredSources = append(s.deferredSources, source)
+	}
+	if s.deferredTimer == nil {
+		s.deferredTimer = time.AfterFunc(wait, s.pushDeferred)
+	}
+}
+
+// pushDeferred pushes the deferred trigger again. The first source starts a
+// round and the others are merged into it, unless the limiter rejects them
+// again, in which case they are deferred again.
+func (s *Server) pushDeferred() {
+	s.mutex.Lock()
+	families, sources := s.deferredFamilies, s.deferredSources
+	s.deferredFamilies, s.deferredSources, s.deferredTimer = nil, nil, nil
+	s.mutex.Unlock()
+
+	for _, source := range sources {
+		s.push(families, source)
+	}
+}
+
+// Register adds the endpoint GET /nic/update to mux.
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /nic/update", s.serve)
+}
+
+// authorized checks the credentials of the basic authentication in constant time.
+func (s *Server) authorized(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	// Both comparisons are always done so that the time does not tell which one failed.
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), s.username)
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), s.password)
+	return ok && usernameOK&passwordOK == 1
+}
+
+// parseIPs parses the comma-separated addresses in the query parameters myip
+// and myipv6. Without both, the address of the client is used, as the protocol
+// specifies, but only if useClientAddress is true: behind a reverse proxy or a
+// NAT, that address is not the one of the router.
+func parseIPs(r *http.Request, useClientAddress bool) (map[ipnet.Family][]netip.Addr, bool) {
+	query := r.URL.Query()
+	var vals []string
+	for _, key := range []string{"myip", "myipv6"} {
+		for val := range strings.SplitSeq(query.Get(key), ",") {
+			if val = strings.TrimSpace(val); val != "" {
+				vals = append(vals, val)
+			}
+		}
+	}
+	if len(vals) == 0 {
+		if !useClientAddress {
+			return nil, false
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return nil, false
+		}
+		vals = []string{host}
+	}
+
+	ips := map[ipnet.Family][]netip.Addr{}
+	for _, val := range vals {
+		ip, err := netip.ParseAddr(val)
+		if err != nil {
+			return nil, false
+		}
+		ip = ip.Unmap().WithZone("")
+		for ipFamily := range ipnet.All {
+			if ipFamily.Matches(ip) {
+				ips[ipFamily] = append(ips[ipFamily], ip)
+			}
+		}
+	}
+	return ips, true
+}
+
+// The responses are the return codes of the DynDNS2 protocol, one line for each
+// hostname in the request.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="ddns"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, "badauth")
+		return
+	}
+
+	var hostnames []string
+	for hostname := range strings.SplitSeq(r.URL.Query().Get("hostname"), ",") {
+		if hostname = strings.TrimSpace(hostname); hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	if len(hostnames) == 0 {
+		fmt.Fprintln(w, "notfqdn")
+		return
+	}
+
+	stores := s.storesFor(hostnames)
+	if len(stores) == 0 {
+		for range hostnames {
+			fmt.Fprintln(w, "nohost")
+		}
+		return
+	}
+
+	ips, ok := parseIPs(r, s.useClientAddress)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "badip")
+		return
+	}
+
+	changed := map[ipnet.Family]bool{}
+	var all []string
+	for ipFamily := range ipnet.All {
+		if len(ips[ipFamily]) == 0 {
+			continue
+		}
+		for _, store := range stores {
+			if store.Set(ipFamily, ips[ipFamily]) {
+				changed[ipFamily] = true
+			}
+		}
+		for _, ip := range ips[ipFamily] {
+			all = append(all, ip.String())
+		}
+	}
+
+	// A trigger rejected by the limiter is not an error: the addresses are
+	// saved, and the deferred round uses them.
+	code := "nochg"
+	if len(changed) > 0 {
+		code = "good"
+		s.push(changed, "DynDNS2 client ("+strings.Join(hostnames, ", ")+")")
+	}
+	for range hostnames {
+		fmt.Fprintf(w, "%s %s\n", code, strings.Join(all, ","))
+	}
+}
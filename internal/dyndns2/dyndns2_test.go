@@ -0,0 +1,261 @@
+package dyndns2_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+)
+
+func update(t *testing.T, s *dyndns2.Server, query string, username, password string) (int, string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	s.Register(mux)
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/nic/update?"+query, nil)
+	req.RemoteAddr = "198.51.100.7:4321"
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	s := dyndns2.NewStore()
+	_, ok := s.Get(ipnet.IP4)
+	require.False(t, ok)
+
+	a, b := netip.MustParseAddr("203.0.113.1"), netip.MustParseAddr("203.0.113.2")
+	require.True(t, s.Set(ipnet.IP4, []netip.Addr{b, a, b}))
+	require.False(t, s.Set(ipnet.IP4, []netip.Addr{a, b}))
+	ips, ok := s.Get(ipnet.IP4)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{a, b}, ips)
+	require.True(t, s.Set(ipnet.IP4, []netip.Addr{a}))
+
+	var none *dyndns2.Store
+	_, ok = none.Get(ipnet.IP4)
+	require.False(t, ok)
+}
+
+// newServer creates a server with one store that accepts every hostname.
+func newServer(useClientAddress bool, minInterval time.Duration) (*dyndns2.Server, *dyndns2.Store, *trigger.Queue) {
+	store := dyndns2.NewStore()
+	store.SetHostnames(nil)
+	q := trigger.NewQueue(func() {})
+	s := dyndns2.NewServer("user", "pass", useClientAddress, trigger.NewLimiter(q, minInterval))
+	s.AddStore(store)
+	return s, store, q
+}
+
+func TestBadAuth(t *testing.T) {
+	t.Parallel()
+
+	s, _, q := newServer(false, 0)
+	for _, creds := range [][2]string{{"", ""}, {"user", "wrong"}, {"other", "pass"}} {
+		code, body := update(t, s, "hostname=home.example.org&myip=203.0.113.1", creds[0], creds[1])
+		require.Equal(t, http.StatusUnauthorized, code)
+		require.Equal(t, "badauth\n", body)
+	}
+	_, ok := q.Take()
+	require.False(t, ok)
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	s, store, q := newServer(false, 0)
+
+	code, body := update(t, s, "hostname=home.example.org&myip=203.0.113.1&myipv6=2001:db8::1", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.1,2001:db8::1\n", body)
+	r, ok := q.Take()
+	require.True(t, ok)
+	require.Equal(t, trigger.Request{
+		Families: map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true},
+		Sources:  []string{"DynDNS2 client (home.example.org)"},
+	}, r)
+
+	// Only the changed family triggers a round.
+	code, body = update(t, s, "hostname=a.example.org,b.example.org&myip=203.0.113.1,2001:db8::2", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.1,2001:db8::2\ngood 203.0.113.1,2001:db8::2\n", body)
+	r, ok = q.Take()
+	require.True(t, ok)
+	require.Equal(t, map[ipnet.Family]bool{ipnet.IP6: true}, r.Families)
+
+	code, body = update(t, s, "hostname=home.example.org&myip=203.0.113.1", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "nochg 203.0.113.1\n", body)
+	_, ok = q.Take()
+	require.False(t, ok)
+
+	ips, ok := store.Get(ipnet.IP6)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::2")}, ips)
+}
+
+func TestUpdateRateLimit(t *testing.T) {
+	t.Parallel()
+
+	s, store, q := newServer(false, time.Hour)
+
+	code, body := update(t, s, "hostname=home.example.org&myip=203.0.113.1", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.1\n", body)
+	_, ok := q.Take()
+	require.True(t, ok)
+
+	// The address is saved, but the round is deferred until the interval ends.
+	code, body = update(t, s, "hostname=home.example.org&myip=203.0.113.2", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.2\n", body)
+	_, ok = q.Take()
+	require.False(t, ok)
+	ips, ok := store.Get(ipnet.IP4)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("203.0.113.2")}, ips)
+}
+
+func TestUpdateDeferred(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		s, store, q := newServer(false, time.Minute)
+
+		update(t, s, "hostname=home.example.org&myip=203.0.113.1", "user", "pass")
+		_, ok := q.Take()
+		require.True(t, ok)
+
+		// Two pushes inside the interval end up in the same deferred round.
+		time.Sleep(10 * time.Second)
+		code, body := update(t, s, "hostname=home.example.org&myip=203.0.113.2", "user", "pass")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "good 203.0.113.2\n", body)
+		code, body = update(t, s, "hostname=work.example.org&myipv6=2001:db8::1", "user", "pass")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "good 2001:db8::1\n", body)
+		_, ok = q.Take()
+		require.False(t, ok)
+
+		time.Sleep(50*time.Second - time.Nanosecond)
+		synctest.Wait()
+		_, ok = q.Take()
+		require.False(t, ok)
+
+		time.Sleep(time.Nanosecond)
+		synctest.Wait()
+		r, ok := q.Take()
+		require.True(t, ok)
+		require.Equal(t, trigger.Request{
+			Families: map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true},
+			Sources:  []string{"DynDNS2 client (home.example.org)", "DynDNS2 client (work.example.org)"},
+		}, r)
+		ips, ok := store.Get(ipnet.IP4)
+		require.True(t, ok)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("203.0.113.2")}, ips)
+
+		// The deferred round counts as an accepted trigger.
+		update(t, s, "hostname=home.example.org&myip=203.0.113.3", "user", "pass")
+		_, ok = q.Take()
+		require.False(t, ok)
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		r, ok = q.Take()
+		require.True(t, ok)
+		require.Equal(t, map[ipnet.Family]bool{ipnet.IP4: true}, r.Families)
+	})
+}
+
+func TestUpdateClientAddress(t *testing.T) {
+	t.Parallel()
+
+	s, store, _ := newServer(true, 0)
+	code, body := update(t, s, "hostname=home.example.org", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 198.51.100.7\n", body)
+	ips, ok := store.Get(ipnet.IP4)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("198.51.100.7")}, ips)
+
+	s, store, _ = newServer(false, 0)
+	code, body = update(t, s, "hostname=home.example.org", "user", "pass")
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "badip\n", body)
+	_, ok = store.Get(ipnet.IP4)
+	require.False(t, ok)
+}
+
+func TestUpdateProfiles(t *testing.T) {
+	t.Parallel()
+
+	home, work := dyndns2.NewStore(), dyndns2.NewStore()
+	home.SetHostnames([]string{"home", "home.example.org"})
+	work.SetHostnames([]string{"work", "vpn.example.com", "*.example.com"})
+	q := trigger.NewQueue(func() {})
+	s := dyndns2.NewServer("user", "pass", false, trigger.NewLimiter(q, 0))
+	s.AddStore(home)
+	s.AddStore(work)
+
+	code, body := update(t, s, "hostname=VPN.example.com.&myip=203.0.113.1", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.1\n", body)
+	_, ok := home.Get(ipnet.IP4)
+	require.False(t, ok)
+	_, ok = work.Get(ipnet.IP4)
+	require.True(t, ok)
+
+	code, body = update(t, s, "hostname=home,other.example.org&myip=203.0.113.2", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.2\ngood 203.0.113.2\n", body)
+	ips, ok := home.Get(ipnet.IP4)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("203.0.113.2")}, ips)
+
+	code, body = update(t, s, "hostname=other.example.org,*.example.com&myip=203.0.113.3", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "good 203.0.113.3\ngood 203.0.113.3\n", body)
+
+	code, body = update(t, s, "hostname=other.example.org&myip=203.0.113.4", "user", "pass")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "nohost\n", body)
+	ips, ok = work.Get(ipnet.IP4)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("203.0.113.3")}, ips)
+}
+
+func TestUpdateErrors(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		query string
+		code  int
+		body  string
+	}{
+		"no-hostname": {"myip=203.0.113.1", http.StatusOK, "notfqdn\n"},
+		"bad-ip":      {"hostname=home.example.org&myip=home", http.StatusBadRequest, "badip\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s, store, _ := newServer(true, 0)
+			code, body := update(t, s, tc.query, "user", "pass")
+			require.Equal(t, tc.code, code)
+			require.Equal(t, tc.body, body)
+			_, ok := store.Get(ipnet.IP4)
+			require.False(t, ok)
+		})
+	}
+}
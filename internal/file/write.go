@@ -0,0 +1,45 @@
+package file
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomically replaces the file at path with content. The content is first
+// written to a temporary file in the same directory and then renamed, so that
+// readers never see a partially written file. Unlike the readers in this
+// package, it always uses the actual file system.
+func WriteAtomically(path string, content []byte, perm fs.FileMode) error {
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+base+".*")
+	if err != nil {
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err //nolint:wrapcheck // The caller reports the path.
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+// Package health serves the health, readiness, and status endpoints of the
+// updater for Docker health checks and Kubernetes probes.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+// MinStuckGracePeriod is the shortest time the updater may be late for its
+// next round before it is considered stuck. The grace period is longer when a
+// profile has many resources to update; see [Profile.SetRoundTimeout].
+const MinStuckGracePeriod = time.Minute
+
+// A Monitor keeps track of the main loop and the rounds of all profiles.
+// The zero value is not usable; use [NewMonitor].
+type Monitor struct {
+	mutex     sync.Mutex
+	readiness config.Readiness
+	busy      bool      // whether the main loop is running a round instead of waiting
+	expected  time.Time // when the main loop should run the next round
+	profiles  []*Profile
+}
+
+// NewMonitor creates a [Monitor] for a main loop that is starting up.
+func NewMonitor(readiness config.Readiness, now time.Time) *Monitor {
+	return &Monitor{
+		mutex:     sync.Mutex{},
+		readiness: readiness,
+		busy:      true,
+		expected:  now,
+		profiles:  nil,
+	}
+}
+
+// Expect records that the main loop is waiting until t to run the next round.
+// The zero time means that the next round runs immediately.
+func (m *Monitor) Expect(t time.Time) {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.busy, m.expected = false, t
+}
+
+// Start records that the main loop stopped waiting and is running a round, a
+// reload, or the rounds for a trigger. The main loop is not stuck while it
+// runs them, because every detection and update has its own timeout.
+func (m *Monitor) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.busy = true
+}
+
+// ForProfile registers a profile and returns its status, which also receives
+// the detected addresses and the next run time as a [metrics.Recorder].
+func (m *Monitor) ForProfile(name string) *Profile {
+	p := &Profile{
+		Noop:      metrics.Noop{},
+		monitor:   m,
+		name:      name,
+		timeout:   0,
+		detected:  map[ipnet.Family][]netip.Addr{},
+		nextRun:   time.Time{},
+		last:      nil,
+		lastAt:    time.Time{},
+		succeeded: false,
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.profiles = append(m.profiles, p)
+	return p
+}
+
+// A Profile is the status of one profile. Only the detected addresses and the
+// next run time are kept from the measurements.
+type Profile struct {
+	metrics.Noop
+
+	monitor *Monitor
+	name    string
+
+	// The following fields are protected by the mutex of the monitor.
+	timeout   time.Duration // the longest a round of the profile can take
+	detected  map[ipnet.Family][]netip.Addr
+	nextRun   time.Time
+	last      *updater.Message // the message of the last round, if any
+	lastAt    time.Time
+	succeeded bool // whether any round succeeded
+}
+
+var _ metrics.Recorder = (*Profile)(nil)
+
+// SetDetectedIPs implements [metrics.Recorder].
+func (p *Profile) SetDetectedIPs(ipFamily ipnet.Family, ips []netip.Addr) {
+	p.monitor.mutex.Lock()
+	defer p.monitor.mutex.Unlock()
+	p.detected[ipFamily] = slices.Clone(ips)
+}
+
+// SetNextRun implements [metrics.Recorder].
+func (p *Profile) SetNextRun(t time.Time) {
+	p.monitor.mutex.Lock()
+	defer p.monitor.mutex.Unlock()
+	p.nextRun = t
+}
+
+// SetRoundTimeout records the longest a round of the profile can take, as
+// computed by [updater.RoundTimeout]. The main loop may be late for its next
+// round by that long, because the round of another profile may be running.
+func (p *Profile) SetRoundTimeout(timeout time.Duration) {
+	p.monitor.mutex.Lock()
+	defer p.monitor.mutex.Unlock()
+	p.timeout = timeout
+}
+
+// RecordRound records the outcome of a round of updating, including a retry.
+func (p *Profile) RecordRound(msg updater.Message, at time.Time) {
+	p.monitor.mutex.Lock()
+	defer p.monitor.mutex.Unlock()
+	p.last, p.lastAt = &msg, at
+	p.succeeded = p.succeeded || msg.HeartbeatMessage.OK
+}
+
+// gracePeriod is how long the main loop may be late for its next round. The
+// caller must hold the mutex.
+func (m *Monitor) gracePeriod() time.Duration {
+	grace := MinStuckGracePeriod
+	for _, p := range m.profiles {
+		grace = max(grace, p.timeout)
+	}
+	return grace
+}
+
+// healthy reports whether the main loop is running a round or is not late for
+// its next round by more than the grace period. The caller must hold the mutex.
+func (m *Monitor) healthy(now time.Time) bool {
+	return m.busy || !now.After(m.expected.Add(m.gracePeriod()))
+}
+
+// ready reports whether every profile is ready. The caller must hold the mutex.
+func (m *Monitor) ready() bool {
+	for _, p := range m.profiles {
+		switch m.readiness {
+		case config.ReadinessAlways:
+		case config.ReadinessAnyRound:
+			if !p.succeeded {
+				return false
+			}
+		default:
+			if p.last == nil || !p.last.HeartbeatMessage.OK {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Register adds the endpoints /healthz, /readyz, and /status to mux. The
+// /status endpoint only serves a summary, without the detected addresses and
+// the messages of the last rounds, unless the request carries the bearer
+// token. The empty token keeps the details private.
+func (m *Monitor) Register(mux *http.ServeMux, token string) {
+	mux.HandleFunc("GET /healthz", m.serveHealthz)
+	mux.HandleFunc("GET /readyz", m.serveReadyz)
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		m.serveStatus(w, trigger.Authorized(r, []byte(token)))
+	})
+}
+
+func (m *Monitor) serveHealthz(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	m.mutex.Lock()
+	healthy, expected := m.healthy(now), m.expected
+	m.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "stuck: the round expected at %s has not started\n", expected.Format(time.RFC3339))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (m *Monitor) serveReadyz(w http.ResponseWriter, _ *http.Request) {
+	m.mutex.Lock()
+	ready := m.ready()
+	m.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "not ready (READINESS=%s)\n", m.readiness)
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
+
+// status is the JSON document served at /status.
+type status struct {
+	Healthy  bool            `json:"healthy"`
+	Ready    bool            `json:"ready"`
+	Profiles []profileStatus `json:"profiles"`
+}
+
+// profileStatus is the status of a profile. The detected addresses are only
+// included in the detailed document.
+type profileStatus struct {
+	Name      string              `json:"name"`
+	NextRun   *time.Time          `json:"nextRun"`
+	Detected  map[string][]string `json:"detected,omitempty"`
+	LastRound *roundStatus        `json:"lastRound"`
+}
+
+// roundStatus is the outcome of a round. The messages and the report are only
+// included in the detailed document.
+type roundStatus struct {
+	Time         time.Time       `json:"time"`
+	OK           bool            `json:"ok"`
+	Heartbeat    []string        `json:"heartbeat,omitempty"`
+	Notification []string        `json:"notification,omitempty"`
+	Report       *updater.Report `json:"report,omitempty"`
+}
+
+// snapshot builds the status document, with the details if detailed is true.
+func (m *Monitor) snapshot(now time.Time, detailed bool) status {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := status{Healthy: m.healthy(now), Ready: m.ready(), Profiles: make([]profileStatus, 0, len(m.profiles))}
+	for _, p := range m.profiles {
+		ps := profileStatus{Name: p.name, NextRun: nil, Detected: nil, LastRound: nil}
+		if !p.nextRun.IsZero() {
+			nextRun := p.nextRun
+			ps.NextRun = &nextRun
+		}
+		if p.last != nil {
+			ps.LastRound = &roundStatus{
+				Time: p.lastAt, OK: p.last.HeartbeatMessage.OK,
+				Heartbeat: nil, Notification: nil, Report: nil,
+			}
+		}
+		if detailed {
+			ps.Detected = map[string][]string{}
+			for ipFamily, ips := range p.detected {
+				ps.Detected[ipFamily.Describe()] = make([]string, 0, len(ips))
+				for _, ip := range ips {
+					ps.Detected[ipFamily.Describe()] = append(ps.Detected[ipFamily.Describe()], ip.String())
+				}
+			}
+			if ps.LastRound != nil {
+				ps.LastRound.Heartbeat = slices.Clone(p.last.HeartbeatMessage.Lines)
+				ps.LastRound.Notification = slices.Clone(p.last.NotifierMessage)
+				ps.LastRound.Report = p.last.Report
+			}
+		}
+		s.Profiles = append(s.Profiles, ps)
+	}
+	return s
+}
+
+func (m *Monitor) serveStatus(w http.ResponseWriter, detailed bool) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(m.snapshot(time.Now(), detailed))
+}
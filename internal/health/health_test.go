@@ -0,0 +1,218 @@
+package health_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/health"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+func get(t *testing.T, m *health.Monitor, path string) (int, string) {
+	t.Helper()
+	return getWithToken(t, m, path, "")
+}
+
+func getWithToken(t *testing.T, m *health.Monitor, path, token string) (int, string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	m.Register(mux, "secret")
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func message(ok bool, line string) updater.Message {
+	return updater.Message{
+		HeartbeatMessage: heartbeat.NewMessagef(ok, "%s", line),
+		NotifierMessage:  notifier.NewMessagef("%s", line),
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	t.Parallel()
+
+	m := health.NewMonitor(config.ReadinessLastRound, time.Now())
+	code, body := get(t, m, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok\n", body)
+
+	expected := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.Expect(expected)
+	code, body = get(t, m, "/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "stuck: the round expected at 2024-03-01T12:00:00Z has not started\n", body)
+
+	m.Expect(time.Now().Add(time.Hour))
+	code, _ = get(t, m, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+
+	m.Expect(time.Time{})
+	code, _ = get(t, m, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestHealthzRunningRound(t *testing.T) {
+	t.Parallel()
+
+	m := health.NewMonitor(config.ReadinessLastRound, time.Now())
+	m.Expect(time.Now().Add(-time.Hour))
+	code, _ := get(t, m, "/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	// A round that runs for long is not stuck.
+	m.Start()
+	code, body := get(t, m, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok\n", body)
+}
+
+func TestHealthzGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	m := health.NewMonitor(config.ReadinessLastRound, time.Now())
+	m.Expect(time.Now().Add(-2 * health.MinStuckGracePeriod))
+	code, _ := get(t, m, "/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	// The main loop may wait for a long round of another profile.
+	m.ForProfile("home").SetRoundTimeout(time.Hour)
+	code, _ = get(t, m, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestReadyz(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		readiness config.Readiness
+		expected  [4]bool // before any round, after a success, after a failure, after another success
+	}{
+		"last-round": {config.ReadinessLastRound, [4]bool{false, true, false, true}},
+		"any-round":  {config.ReadinessAnyRound, [4]bool{false, true, true, true}},
+		"always":     {config.ReadinessAlways, [4]bool{true, true, true, true}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := health.NewMonitor(tc.readiness, time.Now())
+			home, work := m.ForProfile("home"), m.ForProfile("work")
+			work.RecordRound(message(true, "ok"), time.Now())
+
+			check := func(ready bool) {
+				t.Helper()
+				code, body := get(t, m, "/readyz")
+				if ready {
+					require.Equal(t, http.StatusOK, code)
+					require.Equal(t, "ready\n", body)
+				} else {
+					require.Equal(t, http.StatusServiceUnavailable, code)
+					require.Equal(t, "not ready (READINESS="+string(tc.readiness)+")\n", body)
+				}
+			}
+
+			check(tc.expected[0])
+			home.RecordRound(message(true, "ok"), time.Now())
+			check(tc.expected[1])
+			home.RecordRound(message(false, "failed"), time.Now())
+			check(tc.expected[2])
+			home.RecordRound(message(true, "ok"), time.Now())
+			check(tc.expected[3])
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	m := health.NewMonitor(config.ReadinessLastRound, time.Now())
+	p := m.ForProfile("")
+	m.ForProfile("idle")
+
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	p.SetDetectedIPs(ipnet.IP4, []netip.Addr{netip.MustParseAddr("203.0.113.1")})
+	p.SetDetectedIPs(ipnet.IP6, []netip.Addr{netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::2")})
+	p.SetNextRun(at.Add(5 * time.Minute))
+	p.RecordRound(message(true, "Set A (203.0.113.1)"), at)
+
+	summary := `{
+		"healthy": true,
+		"ready": false,
+		"profiles": [
+			{
+				"name": "",
+				"nextRun": "2024-03-01T12:05:00Z",
+				"lastRound": {"time": "2024-03-01T12:00:00Z", "ok": true}
+			},
+			{"name": "idle", "nextRun": null, "lastRound": null}
+		]
+	}`
+	for _, token := range []string{"", "wrong"} {
+		code, body := getWithToken(t, m, "/status", token)
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, summary, body)
+	}
+
+	code, body := getWithToken(t, m, "/status", "secret")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{
+		"healthy": true,
+		"ready": false,
+		"profiles": [
+			{
+				"name": "",
+				"nextRun": "2024-03-01T12:05:00Z",
+				"detected": {"IPv4": ["203.0.113.1"], "IPv6": ["2001:db8::1", "2001:db8::2"]},
+				"lastRound": {
+					"time": "2024-03-01T12:00:00Z",
+					"ok": true,
+					"heartbeat": ["Set A (203.0.113.1)"],
+					"notification": ["Set A (203.0.113.1)"]
+				}
+			},
+			{"name": "idle", "nextRun": null, "lastRound": null}
+		]
+	}`, body)
+}
+
+func TestStatusWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	m := health.NewMonitor(config.ReadinessAlways, time.Now())
+	m.ForProfile("").SetDetectedIPs(ipnet.IP4, []netip.Addr{netip.MustParseAddr("203.0.113.1")})
+
+	mux := http.NewServeMux()
+	m.Register(mux, "")
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/status", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotContains(t, w.Body.String(), "203.0.113.1")
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	health.NewMonitor(config.ReadinessAlways, time.Now()).Register(mux, "")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/healthz", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,61 @@
+// Package localdns writes configuration fragments for local DNS resolvers,
+// so that clients inside a network can resolve the managed domains to internal
+// addresses (split-horizon DNS).
+package localdns
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// Format is the syntax of a local resolver fragment.
+type Format string
+
+const (
+	// FormatHosts is the syntax of /etc/hosts.
+	FormatHosts Format = "hosts"
+	// FormatDnsmasq is the syntax of dnsmasq configuration files,
+	// using host-record= for domains and address= for wildcard domains.
+	FormatDnsmasq Format = "dnsmasq"
+	// FormatUnbound is the syntax of unbound configuration files,
+	// using local-data: for domains and redirecting local zones for wildcard domains.
+	FormatUnbound Format = "unbound"
+)
+
+// ParseFormat parses the name of a format, ignoring cases.
+func ParseFormat(name string) (Format, bool) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatHosts, FormatDnsmasq, FormatUnbound:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// SupportsWildcards reports whether wildcard domains can be written in the format.
+func (f Format) SupportsWildcards() bool {
+	return f != FormatHosts
+}
+
+// Targets are the addresses of each domain, grouped by IP families.
+// Absent families are left out of the fragment.
+type Targets = map[ipnet.Family]map[domain.Domain][]netip.Addr
+
+// Sink is a file holding a local resolver fragment.
+type Sink struct {
+	// Path is the absolute path of the file.
+	Path string
+	// Format is the syntax of the fragment.
+	Format Format
+	// ReloadCommand is the command (with its arguments) to run after the file changes.
+	// It is run directly without a shell; no command is run when it is empty.
+	ReloadCommand []string
+}
+
+// Describe gives a human-readable description of the sink.
+func (s Sink) Describe() string {
+	return s.Path
+}
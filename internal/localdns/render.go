@@ -0,0 +1,120 @@
+package localdns
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// header is the first line of every fragment. The character # starts
+// a comment in all supported formats.
+const header = "# Written by Cloudflare DDNS; manual changes will be overwritten.\n"
+
+// entry is one address of one domain.
+type entry struct {
+	domain domain.Domain
+	family ipnet.Family
+	addr   netip.Addr
+}
+
+// entries flattens the targets in a deterministic order: domains are sorted,
+// and IPv4 addresses go before IPv6 addresses.
+func entries(targets Targets) []entry {
+	seen := map[domain.Domain]bool{}
+	var domains []domain.Domain
+	for _, byDomain := range ipnet.Bindings(targets) {
+		for d := range byDomain {
+			if !seen[d] {
+				seen[d] = true
+				domains = append(domains, d)
+			}
+		}
+	}
+	domain.SortDomains(domains)
+
+	var es []entry
+	for _, d := range domains {
+		for ipFamily, byDomain := range ipnet.Bindings(targets) {
+			for _, addr := range byDomain[d] {
+				es = append(es, entry{domain: d, family: ipFamily, addr: addr})
+			}
+		}
+	}
+	return es
+}
+
+// zoneOfWildcard gives the zone under which a wildcard domain lives.
+func zoneOfWildcard(w domain.Wildcard) string {
+	return strings.TrimPrefix(strings.TrimPrefix(w.DNSNameASCII(), "*"), ".")
+}
+
+func renderHosts(b *strings.Builder, es []entry) {
+	for _, e := range es {
+		fmt.Fprintf(b, "%s\t%s\n", e.addr, e.domain.DNSNameASCII())
+	}
+}
+
+func renderDnsmasq(b *strings.Builder, es []entry) {
+	for _, e := range es {
+		if w, ok := e.domain.(domain.Wildcard); ok {
+			// address= also answers for the zone itself.
+			fmt.Fprintf(b, "address=/%s/%s\n", zoneOfWildcard(w), e.addr)
+		} else {
+			fmt.Fprintf(b, "host-record=%s,%s\n", e.domain.DNSNameASCII(), e.addr)
+		}
+	}
+}
+
+func renderUnbound(b *strings.Builder, es []entry) {
+	b.WriteString("server:\n")
+	redirected := map[string]bool{}
+	for _, e := range es {
+		name := e.domain.DNSNameASCII()
+		if w, ok := e.domain.(domain.Wildcard); ok {
+			// A redirecting zone answers all names under the zone with the data of the zone itself.
+			name = zoneOfWildcard(w)
+			if !redirected[name] {
+				redirected[name] = true
+				fmt.Fprintf(b, "\tlocal-zone: \"%s.\" redirect\n", name)
+			}
+		}
+		fmt.Fprintf(b, "\tlocal-data: \"%s. IN %s %s\"\n", name, e.family.RecordType(), e.addr)
+	}
+}
+
+// selectEntries gives the entries written for the targets. Wildcard domains
+// are skipped when the format does not support them.
+func selectEntries(format Format, targets Targets) []entry {
+	es := entries(targets)
+	if format.SupportsWildcards() {
+		return es
+	}
+	kept := es[:0]
+	for _, e := range es {
+		if _, ok := e.domain.(domain.Wildcard); !ok {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// Render gives the content of the fragment for the targets. Wildcard domains
+// are skipped when the format does not support them.
+func Render(format Format, targets Targets) []byte {
+	es := selectEntries(format, targets)
+
+	var b strings.Builder
+	b.WriteString(header)
+	switch format {
+	case FormatHosts:
+		renderHosts(&b, es)
+	case FormatDnsmasq:
+		renderDnsmasq(&b, es)
+	case FormatUnbound:
+		renderUnbound(&b, es)
+	}
+	return []byte(b.String())
+}
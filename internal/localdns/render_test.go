@@ -0,0 +1,106 @@
+package localdns_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
+)
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input  string
+		format localdns.Format
+		ok     bool
+	}{
+		"hosts":   {"hosts", localdns.FormatHosts, true},
+		"dnsmasq": {"DNSMASQ", localdns.FormatDnsmasq, true},
+		"unbound": {"Unbound", localdns.FormatUnbound, true},
+		"bind":    {"bind", "", false},
+		"empty":   {"", "", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			format, ok := localdns.ParseFormat(tc.input)
+			require.Equal(t, tc.format, format)
+			require.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func sampleTargets() localdns.Targets {
+	return localdns.Targets{
+		ipnet.IP4: {
+			domain.FQDN("www.example.org"):     {netip.MustParseAddr("192.168.1.2")},
+			domain.FQDN("app.example.org"):     {netip.MustParseAddr("192.168.1.3"), netip.MustParseAddr("192.168.1.4")},
+			domain.Wildcard("lab.example.org"): {netip.MustParseAddr("192.168.1.5")},
+		},
+		ipnet.IP6: {
+			domain.FQDN("app.example.org"):     {netip.MustParseAddr("fd00::3")},
+			domain.Wildcard("lab.example.org"): {netip.MustParseAddr("fd00::5")},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		format   localdns.Format
+		targets  localdns.Targets
+		expected string
+	}{
+		"hosts": {
+			localdns.FormatHosts, sampleTargets(),
+			`# Written by Cloudflare DDNS; manual changes will be overwritten.
+192.168.1.3	app.example.org
+192.168.1.4	app.example.org
+fd00::3	app.example.org
+192.168.1.2	www.example.org
+`,
+		},
+		"dnsmasq": {
+			localdns.FormatDnsmasq, sampleTargets(),
+			`# Written by Cloudflare DDNS; manual changes will be overwritten.
+address=/lab.example.org/192.168.1.5
+address=/lab.example.org/fd00::5
+host-record=app.example.org,192.168.1.3
+host-record=app.example.org,192.168.1.4
+host-record=app.example.org,fd00::3
+host-record=www.example.org,192.168.1.2
+`,
+		},
+		"unbound": {
+			localdns.FormatUnbound, sampleTargets(),
+			`# Written by Cloudflare DDNS; manual changes will be overwritten.
+server:
+	local-zone: "lab.example.org." redirect
+	local-data: "lab.example.org. IN A 192.168.1.5"
+	local-data: "lab.example.org. IN AAAA fd00::5"
+	local-data: "app.example.org. IN A 192.168.1.3"
+	local-data: "app.example.org. IN A 192.168.1.4"
+	local-data: "app.example.org. IN AAAA fd00::3"
+	local-data: "www.example.org. IN A 192.168.1.2"
+`,
+		},
+		"hosts/empty": {
+			localdns.FormatHosts, localdns.Targets{},
+			"# Written by Cloudflare DDNS; manual changes will be overwritten.\n",
+		},
+		"unbound/empty": {
+			localdns.FormatUnbound, localdns.Targets{ipnet.IP4: {domain.FQDN("www.example.org"): nil}},
+			"# Written by Cloudflare DDNS; manual changes will be overwritten.\nserver:\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, string(localdns.Render(tc.format, tc.targets)))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package localdns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/file"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// filePerm is the permission of newly written fragments. Resolvers often
+// run as unprivileged users, so the fragments are world-readable.
+const filePerm fs.FileMode = 0o644
+
+// isUpToDate reports whether the file already holds content. A missing file
+// is not up to date; other read errors are reported and then ignored, so
+// that the file will be rewritten.
+func (s Sink) isUpToDate(ppfmt pp.PP, content []byte) bool {
+	current, err := os.ReadFile(s.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return false
+	case err != nil:
+		ppfmt.Noticef(pp.EmojiWarning, "Failed to read the local resolver file %s: %v",
+			pp.QuoteIfUnsafeInSentence(s.Path), err)
+		return false
+	default:
+		return bytes.Equal(current, content)
+	}
+}
+
+// describeReloadCommand gives the reload command as it would be typed in a shell.
+func (s Sink) describeReloadCommand() string {
+	return pp.QuoteIfUnsafeInSentence(strings.Join(s.ReloadCommand, " "))
+}
+
+// reload runs the reload command, if any.
+func (s Sink) reload(ctx context.Context, ppfmt pp.PP) bool {
+	if len(s.ReloadCommand) == 0 {
+		return true
+	}
+
+	//nolint:gosec // The command comes from the configuration of the updater.
+	output, err := exec.CommandContext(ctx, s.ReloadCommand[0], s.ReloadCommand[1:]...).CombinedOutput()
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to run the reload command %s: %v", s.describeReloadCommand(), err)
+		if output := strings.TrimSpace(string(output)); output != "" {
+			ppfmt.Indent().Noticef(pp.EmojiBullet, "Output: %s", output)
+		}
+		ppfmt.NoticeOncef(pp.MessageLocalResolverReload, pp.EmojiHint,
+			"The reload command will only run again after the local resolver file changes")
+		return false
+	}
+
+	ppfmt.Infof(pp.EmojiNow, "Ran the reload command %s", s.describeReloadCommand())
+	return true
+}
+
+// Write writes the fragment for the targets when the file is not already up
+// to date, and then runs the reload command.
+func (s Sink) Write(ctx context.Context, ppfmt pp.PP, targets Targets) setter.ResponseCode {
+	content := Render(s.Format, targets)
+	if s.isUpToDate(ppfmt, content) {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The local resolver file %s is already up to date",
+			pp.QuoteIfUnsafeInSentence(s.Path))
+		return setter.ResponseNoop
+	}
+
+	if err := file.WriteAtomically(s.Path, content, filePerm); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to write the local resolver file %s: %v",
+			pp.QuoteIfUnsafeInSentence(s.Path), err)
+		return setter.ResponseFailed
+	}
+	n := len(selectEntries(s.Format, targets))
+	ppfmt.Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+		n, entryWord(n), pp.QuoteIfUnsafeInSentence(s.Path))
+
+	if !s.reload(ctx, ppfmt) {
+		return setter.ResponseFailed
+	}
+	return setter.ResponseUpdated
+}
+
+// Plan gives the changes that [Sink.Write] would make, without making them.
+func (s Sink) Plan(ppfmt pp.PP, targets Targets) []api.PlannedChange {
+	content := Render(s.Format, targets)
+	if s.isUpToDate(ppfmt, content) {
+		return nil
+	}
+
+	subject := "the local resolver file " + s.Path
+	n := len(selectEntries(s.Format, targets))
+	plan := []api.PlannedChange{{Subject: subject, Action: fmt.Sprintf("write %d %s", n, entryWord(n))}}
+	if len(s.ReloadCommand) > 0 {
+		plan = append(plan, api.PlannedChange{
+			Subject: subject,
+			Action:  "run the reload command " + s.describeReloadCommand(),
+		})
+	}
+	return plan
+}
+
+func entryWord(n int) string {
+	if n == 1 {
+		return "entry"
+	}
+	return "entries"
+}
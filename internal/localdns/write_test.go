@@ -0,0 +1,144 @@
+package localdns_test
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func oneTarget() localdns.Targets {
+	return localdns.Targets{
+		ipnet.IP4: {domain.FQDN("www.example.org"): {netip.MustParseAddr("192.168.1.2")}},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "hosts")
+	sink := localdns.Sink{Path: path, Format: localdns.FormatHosts, ReloadCommand: nil}
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	ctx := context.Background()
+
+	mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+		1, "entry", pp.QuoteIfUnsafeInSentence(path))
+	require.Equal(t, setter.ResponseUpdated, sink.Write(ctx, mockPP, oneTarget()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(localdns.Render(localdns.FormatHosts, oneTarget())), string(content))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	mockPP.EXPECT().Infof(pp.EmojiAlreadyDone, "The local resolver file %s is already up to date",
+		pp.QuoteIfUnsafeInSentence(path))
+	require.Equal(t, setter.ResponseNoop, sink.Write(ctx, mockPP, oneTarget()))
+
+	mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+		0, "entries", pp.QuoteIfUnsafeInSentence(path))
+	require.Equal(t, setter.ResponseUpdated, sink.Write(ctx, mockPP, localdns.Targets{}))
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestWriteFails(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "missing", "hosts")
+	sink := localdns.Sink{Path: path, Format: localdns.FormatHosts, ReloadCommand: nil}
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to write the local resolver file %s: %v",
+		pp.QuoteIfUnsafeInSentence(path), gomock.Any())
+	require.Equal(t, setter.ResponseFailed, sink.Write(context.Background(), mockPP, oneTarget()))
+}
+
+func TestWriteReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dnsmasq.conf")
+	marker := filepath.Join(dir, "reloaded")
+	sink := localdns.Sink{Path: path, Format: localdns.FormatDnsmasq, ReloadCommand: []string{"touch", marker}}
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+			1, "entry", pp.QuoteIfUnsafeInSentence(path)),
+		mockPP.EXPECT().Infof(pp.EmojiNow, "Ran the reload command %s",
+			pp.QuoteIfUnsafeInSentence("touch "+marker)),
+	)
+	require.Equal(t, setter.ResponseUpdated, sink.Write(context.Background(), mockPP, oneTarget()))
+	require.FileExists(t, marker)
+}
+
+func TestWriteReloadFails(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "unbound.conf")
+	command := []string{"sh", "-c", "echo oops; exit 3"}
+	sink := localdns.Sink{Path: path, Format: localdns.FormatUnbound, ReloadCommand: command}
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	innerPP := mocks.NewMockPP(mockCtrl)
+
+	gomock.InOrder(
+		mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+			1, "entry", pp.QuoteIfUnsafeInSentence(path)),
+		mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to run the reload command %s: %v",
+			pp.QuoteIfUnsafeInSentence("sh -c echo oops; exit 3"), gomock.Any()),
+		mockPP.EXPECT().Indent().Return(innerPP),
+		innerPP.EXPECT().Noticef(pp.EmojiBullet, "Output: %s", "oops"),
+		mockPP.EXPECT().NoticeOncef(pp.MessageLocalResolverReload, pp.EmojiHint,
+			"The reload command will only run again after the local resolver file changes"),
+	)
+	require.Equal(t, setter.ResponseFailed, sink.Write(context.Background(), mockPP, oneTarget()))
+	require.FileExists(t, path)
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "hosts")
+	sink := localdns.Sink{Path: path, Format: localdns.FormatHosts, ReloadCommand: []string{"true"}}
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "the local resolver file " + path, Action: "write 1 entry"},
+		{Subject: "the local resolver file " + path, Action: "run the reload command true"},
+	}, sink.Plan(mockPP, oneTarget()))
+	_, err := os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, localdns.Render(localdns.FormatHosts, oneTarget()), 0o600))
+	require.Empty(t, sink.Plan(mockPP, oneTarget()))
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/etc/hosts.d/ddns", localdns.Sink{
+		Path: "/etc/hosts.d/ddns", Format: localdns.FormatHosts, ReloadCommand: nil,
+	}.Describe())
+}
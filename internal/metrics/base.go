@@ -0,0 +1,68 @@
+// Package metrics collects the measurements of the updater and exposes them
+// in the Prometheus text format.
+package metrics
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// A Recorder receives the measurements of the updater and the API layer.
+// Its methods must be safe for concurrent use.
+type Recorder interface {
+	// ObserveDetection records the outcome and the duration of detecting the
+	// addresses of an IP family.
+	ObserveDetection(ipFamily ipnet.Family, ok bool, latency time.Duration)
+
+	// SetDetectedIPs records the addresses of the last successful detection.
+	SetDetectedIPs(ipFamily ipnet.Family, ips []netip.Addr)
+
+	// CountRecordUpdate records the outcome of updating the DNS records of a
+	// domain, as the short name of a setter.ResponseCode.
+	CountRecordUpdate(ipFamily ipnet.Family, domain string, code string)
+
+	// CountAPICall records an HTTP call to the Cloudflare API. The endpoint
+	// should not contain IDs, so that the number of series stays small.
+	CountAPICall(method, endpoint, status string)
+
+	// ObserveCache registers a cache of API responses. Its hits and misses are
+	// read whenever the metrics are collected. Registering another cache of
+	// the same name replaces the old one.
+	ObserveCache(name string, stats func() (hits, misses uint64))
+
+	// SetNextRun records the time of the next round of updating.
+	SetNextRun(t time.Time)
+}
+
+// Noop is a [Recorder] that discards everything.
+type Noop struct{}
+
+var _ Recorder = Noop{}
+
+// OrNoop returns r, or [Noop] if r is nil.
+func OrNoop(r Recorder) Recorder {
+	if r == nil {
+		return Noop{}
+	}
+	return r
+}
+
+// ObserveDetection implements [Recorder].
+func (Noop) ObserveDetection(ipnet.Family, bool, time.Duration) {}
+
+// SetDetectedIPs implements [Recorder].
+func (Noop) SetDetectedIPs(ipnet.Family, []netip.Addr) {}
+
+// CountRecordUpdate implements [Recorder].
+func (Noop) CountRecordUpdate(ipnet.Family, string, string) {}
+
+// CountAPICall implements [Recorder].
+func (Noop) CountAPICall(string, string, string) {}
+
+// ObserveCache implements [Recorder].
+func (Noop) ObserveCache(string, func() (uint64, uint64)) {}
+
+// SetNextRun implements [Recorder].
+func (Noop) SetNextRun(time.Time) {}
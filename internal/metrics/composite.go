@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// Composed represents the composite of multiple recorders.
+type Composed []Recorder
+
+var _ Recorder = Composed{}
+
+// NewComposed creates a new composed recorder.
+func NewComposed(recorders ...Recorder) Composed {
+	rs := make([]Recorder, 0, len(recorders))
+	for _, r := range recorders {
+		switch r := r.(type) {
+		case nil, Noop:
+			continue
+		case Composed:
+			rs = append(rs, r...)
+		default:
+			rs = append(rs, r)
+		}
+	}
+	return Composed(rs)
+}
+
+// ObserveDetection calls [Recorder.ObserveDetection] for each recorder in the group.
+func (rs Composed) ObserveDetection(ipFamily ipnet.Family, ok bool, latency time.Duration) {
+	for _, r := range rs {
+		r.ObserveDetection(ipFamily, ok, latency)
+	}
+}
+
+// SetDetectedIPs calls [Recorder.SetDetectedIPs] for each recorder in the group.
+func (rs Composed) SetDetectedIPs(ipFamily ipnet.Family, ips []netip.Addr) {
+	for _, r := range rs {
+		r.SetDetectedIPs(ipFamily, ips)
+	}
+}
+
+// CountRecordUpdate calls [Recorder.CountRecordUpdate] for each recorder in the group.
+func (rs Composed) CountRecordUpdate(ipFamily ipnet.Family, domain string, code string) {
+	for _, r := range rs {
+		r.CountRecordUpdate(ipFamily, domain, code)
+	}
+}
+
+// CountAPICall calls [Recorder.CountAPICall] for each recorder in the group.
+func (rs Composed) CountAPICall(method, endpoint, status string) {
+	for _, r := range rs {
+		r.CountAPICall(method, endpoint, status)
+	}
+}
+
+// ObserveCache calls [Recorder.ObserveCache] for each recorder in the group.
+func (rs Composed) ObserveCache(name string, stats func() (uint64, uint64)) {
+	for _, r := range rs {
+		r.ObserveCache(name, stats)
+	}
+}
+
+// SetNextRun calls [Recorder.SetNextRun] for each recorder in the group.
+func (rs Composed) SetNextRun(t time.Time) {
+	for _, r := range rs {
+		r.SetNextRun(t)
+	}
+}
@@ -0,0 +1,52 @@
+package metrics_test
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+)
+
+func TestNewComposed(t *testing.T) {
+	t.Parallel()
+
+	a, b := metrics.NewRegistry().ForProfile("a"), metrics.NewRegistry().ForProfile("b")
+	require.Empty(t, metrics.NewComposed(nil, metrics.Noop{}))
+	require.Equal(t, metrics.Composed{a, b}, metrics.NewComposed(a, metrics.NewComposed(nil, b)))
+}
+
+func TestComposed(t *testing.T) {
+	t.Parallel()
+
+	r1, r2 := metrics.NewRegistry(), metrics.NewRegistry()
+	c := metrics.NewComposed(r1.ForProfile(""), r2.ForProfile(""))
+
+	c.ObserveDetection(ipnet.IP4, true, time.Second)
+	c.SetDetectedIPs(ipnet.IP4, []netip.Addr{netip.MustParseAddr("203.0.113.1")})
+	c.CountRecordUpdate(ipnet.IP4, "a.example.org", "noop")
+	c.CountAPICall("GET", "/zones", "200")
+	c.ObserveCache("zones", func() (uint64, uint64) { return 1, 2 })
+	c.SetNextRun(time.Unix(1, 0))
+
+	var b1, b2 strings.Builder
+	_, err := r1.WriteTo(&b1)
+	require.NoError(t, err)
+	_, err = r2.WriteTo(&b2)
+	require.NoError(t, err)
+	require.Equal(t, b1.String(), b2.String())
+	for _, line := range []string{
+		`ddns_detections_total{family="IPv4",result="success"} 1`,
+		`ddns_detected_ip_info{family="IPv4",ip="203.0.113.1"} 1`,
+		`ddns_record_updates_total{family="IPv4",domain="a.example.org",result="noop"} 1`,
+		`ddns_cloudflare_api_requests_total{method="GET",endpoint="/zones",status="200"} 1`,
+		`ddns_cloudflare_api_cache_misses_total{cache="zones"} 2`,
+		`ddns_next_run_timestamp_seconds 1`,
+	} {
+		require.Contains(t, b1.String(), line+"\n")
+	}
+}
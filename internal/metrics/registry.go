@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// The names of the metrics.
+const (
+	detectionsTotal         = "ddns_detections_total"
+	detectionDuration       = "ddns_detection_duration_seconds"
+	detectedIPInfo          = "ddns_detected_ip_info"
+	recordUpdatesTotal      = "ddns_record_updates_total"
+	apiRequestsTotal        = "ddns_cloudflare_api_requests_total"
+	apiCacheHitsTotal       = "ddns_cloudflare_api_cache_hits_total"
+	apiCacheMissesTotal     = "ddns_cloudflare_api_cache_misses_total"
+	nextRunTimestampSeconds = "ddns_next_run_timestamp_seconds"
+)
+
+// A descriptor describes a metric for the HELP and TYPE lines.
+type descriptor struct {
+	name string
+	kind string // "counter", "gauge", or "summary"
+	help string
+}
+
+// descriptors lists the metrics in the order of the output.
+func descriptors() []descriptor {
+	return []descriptor{
+		{detectionsTotal, "counter", "Attempts to detect the IP addresses, by the IP family and the result."},
+		{detectionDuration, "summary", "Time spent on detecting the IP addresses, by the IP family."},
+		{detectedIPInfo, "gauge", "The IP addresses of the last successful detection, with value 1."},
+		{recordUpdatesTotal, "counter", "Updates of the DNS records, by the domain and the response of the setter."},
+		{apiRequestsTotal, "counter", "HTTP calls to the Cloudflare API, by the method, the endpoint, and the status."},
+		{apiCacheHitsTotal, "counter", "Lookups of the cached Cloudflare API responses that were hits."},
+		{apiCacheMissesTotal, "counter", "Lookups of the cached Cloudflare API responses that were misses."},
+		{nextRunTimestampSeconds, "gauge", "The Unix time of the next round of updating."},
+	}
+}
+
+type detectedKey struct {
+	profile  string
+	ipFamily ipnet.Family
+}
+
+type cacheKey struct {
+	profile string
+	name    string
+}
+
+// A Registry holds the measurements of all profiles and serves them to
+// Prometheus. The zero value is not usable; use [NewRegistry].
+type Registry struct {
+	mutex    sync.Mutex
+	samples  map[string]map[string]float64 // sample names to labels to values
+	detected map[detectedKey][]netip.Addr
+	caches   map[cacheKey]func() (uint64, uint64)
+}
+
+// NewRegistry creates an empty [Registry].
+func NewRegistry() *Registry {
+	return &Registry{
+		mutex:    sync.Mutex{},
+		samples:  map[string]map[string]float64{},
+		detected: map[detectedKey][]netip.Addr{},
+		caches:   map[cacheKey]func() (uint64, uint64){},
+	}
+}
+
+// labelEscaper escapes label values as required by the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) //nolint:gochecknoglobals
+
+// formatLabels renders pairs of label names and values, leaving out the
+// labels with empty values.
+func formatLabels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func (r *Registry) add(name, labels string, delta float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.samples[name] == nil {
+		r.samples[name] = map[string]float64{}
+	}
+	r.samples[name][labels] += delta
+}
+
+func (r *Registry) set(name, labels string, value float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.samples[name] == nil {
+		r.samples[name] = map[string]float64{}
+	}
+	r.samples[name][labels] = value
+}
+
+// snapshot copies the samples, including the ones computed on demand.
+func (r *Registry) snapshot() map[string]map[string]float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	samples := map[string]map[string]float64{}
+	for name, series := range r.samples {
+		samples[name] = maps.Clone(series)
+	}
+	put := func(name, labels string, value float64) {
+		if samples[name] == nil {
+			samples[name] = map[string]float64{}
+		}
+		samples[name][labels] = value
+	}
+	for key, ips := range r.detected {
+		for _, ip := range ips {
+			put(detectedIPInfo, formatLabels("profile", key.profile, "family", key.ipFamily.Describe(),
+				"ip", ip.String()), 1)
+		}
+	}
+	for key, stats := range r.caches {
+		hits, misses := stats()
+		labels := formatLabels("profile", key.profile, "cache", key.name)
+		put(apiCacheHitsTotal, labels, float64(hits))
+		put(apiCacheMissesTotal, labels, float64(misses))
+	}
+	return samples
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	samples := r.snapshot()
+
+	var b strings.Builder
+	for _, d := range descriptors() {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, d.kind)
+		names := []string{d.name}
+		if d.kind == "summary" {
+			names = []string{d.name + "_sum", d.name + "_count"}
+		}
+		for _, name := range names {
+			for _, labels := range slices.Sorted(maps.Keys(samples[name])) {
+				fmt.Fprintf(&b, "%s%s %s\n", name, labels, strconv.FormatFloat(samples[name][labels], 'g', -1, 64))
+			}
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err //nolint:wrapcheck // The error from the writer is returned as is.
+}
+
+// ServeHTTP implements [http.Handler].
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+// ForProfile returns a [Recorder] that labels its measurements with the name
+// of a profile. The unnamed profile has no label.
+func (r *Registry) ForProfile(profile string) Recorder {
+	return profileRecorder{registry: r, profile: profile}
+}
+
+// A profileRecorder is a [Recorder] for one profile.
+type profileRecorder struct {
+	registry *Registry
+	profile  string
+}
+
+// ObserveDetection implements [Recorder].
+func (p profileRecorder) ObserveDetection(ipFamily ipnet.Family, ok bool, latency time.Duration) {
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	p.registry.add(detectionsTotal,
+		formatLabels("profile", p.profile, "family", ipFamily.Describe(), "result", result), 1)
+
+	labels := formatLabels("profile", p.profile, "family", ipFamily.Describe())
+	p.registry.add(detectionDuration+"_sum", labels, latency.Seconds())
+	p.registry.add(detectionDuration+"_count", labels, 1)
+}
+
+// SetDetectedIPs implements [Recorder].
+func (p profileRecorder) SetDetectedIPs(ipFamily ipnet.Family, ips []netip.Addr) {
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	p.registry.detected[detectedKey{profile: p.profile, ipFamily: ipFamily}] = slices.Clone(ips)
+}
+
+// CountRecordUpdate implements [Recorder].
+func (p profileRecorder) CountRecordUpdate(ipFamily ipnet.Family, domain string, code string) {
+	p.registry.add(recordUpdatesTotal, formatLabels("profile", p.profile,
+		"family", ipFamily.Describe(), "domain", domain, "result", code), 1)
+}
+
+// CountAPICall implements [Recorder].
+func (p profileRecorder) CountAPICall(method, endpoint, status string) {
+	p.registry.add(apiRequestsTotal, formatLabels("profile", p.profile,
+		"method", method, "endpoint", endpoint, "status", status), 1)
+}
+
+// ObserveCache implements [Recorder].
+func (p profileRecorder) ObserveCache(name string, stats func() (uint64, uint64)) {
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	p.registry.caches[cacheKey{profile: p.profile, name: name}] = stats
+}
+
+// SetNextRun implements [Recorder].
+func (p profileRecorder) SetNextRun(t time.Time) {
+	p.registry.set(nextRunTimestampSeconds, formatLabels("profile", p.profile),
+		float64(t.UnixNano())/float64(time.Second))
+}
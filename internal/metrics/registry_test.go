@@ -0,0 +1,97 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry()
+	home, work := r.ForProfile("home"), r.ForProfile("")
+
+	home.ObserveDetection(ipnet.IP4, true, 1500*time.Millisecond)
+	home.ObserveDetection(ipnet.IP4, true, 500*time.Millisecond)
+	home.SetDetectedIPs(ipnet.IP4, []netip.Addr{netip.MustParseAddr("203.0.113.1")})
+	home.SetDetectedIPs(ipnet.IP4, []netip.Addr{netip.MustParseAddr("203.0.113.2")})
+	home.CountRecordUpdate(ipnet.IP4, "a.example.org", "updated")
+	work.CountRecordUpdate(ipnet.IP6, "b.example.org", "noop")
+	work.CountRecordUpdate(ipnet.IP6, "b.example.org", "noop")
+	work.CountAPICall("GET", "/zones", "200")
+	home.ObserveCache("zones", func() (uint64, uint64) { return 3, 1 })
+	work.SetNextRun(time.Unix(1700000000, 0))
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	require.NoError(t, err)
+	require.Equal(t, `# HELP ddns_detections_total Attempts to detect the IP addresses, by the IP family and the result.
+# TYPE ddns_detections_total counter
+ddns_detections_total{profile="home",family="IPv4",result="success"} 2
+# HELP ddns_detection_duration_seconds Time spent on detecting the IP addresses, by the IP family.
+# TYPE ddns_detection_duration_seconds summary
+ddns_detection_duration_seconds_sum{profile="home",family="IPv4"} 2
+ddns_detection_duration_seconds_count{profile="home",family="IPv4"} 2
+# HELP ddns_detected_ip_info The IP addresses of the last successful detection, with value 1.
+# TYPE ddns_detected_ip_info gauge
+ddns_detected_ip_info{profile="home",family="IPv4",ip="203.0.113.2"} 1
+# HELP ddns_record_updates_total Updates of the DNS records, by the domain and the response of the setter.
+# TYPE ddns_record_updates_total counter
+ddns_record_updates_total{family="IPv6",domain="b.example.org",result="noop"} 2
+ddns_record_updates_total{profile="home",family="IPv4",domain="a.example.org",result="updated"} 1
+# HELP ddns_cloudflare_api_requests_total HTTP calls to the Cloudflare API, by the method, the endpoint, and the status.
+# TYPE ddns_cloudflare_api_requests_total counter
+ddns_cloudflare_api_requests_total{method="GET",endpoint="/zones",status="200"} 1
+# HELP ddns_cloudflare_api_cache_hits_total Lookups of the cached Cloudflare API responses that were hits.
+# TYPE ddns_cloudflare_api_cache_hits_total counter
+ddns_cloudflare_api_cache_hits_total{profile="home",cache="zones"} 3
+# HELP ddns_cloudflare_api_cache_misses_total Lookups of the cached Cloudflare API responses that were misses.
+# TYPE ddns_cloudflare_api_cache_misses_total counter
+ddns_cloudflare_api_cache_misses_total{profile="home",cache="zones"} 1
+# HELP ddns_next_run_timestamp_seconds The Unix time of the next round of updating.
+# TYPE ddns_next_run_timestamp_seconds gauge
+ddns_next_run_timestamp_seconds 1.7e+09
+`, b.String())
+}
+
+func TestRegistryEscapesLabels(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry()
+	r.ForProfile("a \"b\"\\\n").SetNextRun(time.Unix(1, 0))
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `ddns_next_run_timestamp_seconds{profile="a \"b\"\\\n"} 1`+"\n")
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry()
+	r.ForProfile("").CountAPICall("GET", "/zones", "200")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), `ddns_cloudflare_api_requests_total{method="GET",endpoint="/zones",status="200"} 1`)
+}
+
+func TestNoop(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, metrics.Noop{}, metrics.OrNoop(nil))
+	r := metrics.NewRegistry().ForProfile("")
+	require.Equal(t, r, metrics.OrNoop(r))
+}
@@ -45,6 +45,83 @@ func (m *MockHandle) EXPECT() *MockHandleMockRecorder {
 	return m.recorder
 }
 
+// CheckPermissions mocks base method.
+func (m *MockHandle) CheckPermissions(ctx context.Context, ppfmt pp.PP, domains []domain.Domain, lists []api.WAFList) api.PermissionReport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPermissions", ctx, ppfmt, domains, lists)
+	ret0, _ := ret[0].(api.PermissionReport)
+	return ret0
+}
+
+// CheckPermissions indicates an expected call of CheckPermissions.
+func (mr *MockHandleMockRecorder) CheckPermissions(ctx, ppfmt, domains, lists any) *MockHandleCheckPermissionsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPermissions", reflect.TypeOf((*MockHandle)(nil).CheckPermissions), ctx, ppfmt, domains, lists)
+	return &MockHandleCheckPermissionsCall{Call: call}
+}
+
+// MockHandleCheckPermissionsCall wrap *gomock.Call
+type MockHandleCheckPermissionsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleCheckPermissionsCall) Return(arg0 api.PermissionReport) *MockHandleCheckPermissionsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleCheckPermissionsCall) Do(f func(context.Context, pp.PP, []domain.Domain, []api.WAFList) api.PermissionReport) *MockHandleCheckPermissionsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleCheckPermissionsCall) DoAndReturn(f func(context.Context, pp.PP, []domain.Domain, []api.WAFList) api.PermissionReport) *MockHandleCheckPermissionsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// CreateIPAccessRule mocks base method.
+func (m *MockHandle) CreateIPAccessRule(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet, prefix netip.Prefix, notes string) (api.ID, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIPAccessRule", ctx, ppfmt, set, prefix, notes)
+	ret0, _ := ret[0].(api.ID)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// CreateIPAccessRule indicates an expected call of CreateIPAccessRule.
+func (mr *MockHandleMockRecorder) CreateIPAccessRule(ctx, ppfmt, set, prefix, notes any) *MockHandleCreateIPAccessRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIPAccessRule", reflect.TypeOf((*MockHandle)(nil).CreateIPAccessRule), ctx, ppfmt, set, prefix, notes)
+	return &MockHandleCreateIPAccessRuleCall{Call: call}
+}
+
+// MockHandleCreateIPAccessRuleCall wrap *gomock.Call
+type MockHandleCreateIPAccessRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleCreateIPAccessRuleCall) Return(arg0 api.ID, arg1 bool) *MockHandleCreateIPAccessRuleCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleCreateIPAccessRuleCall) Do(f func(context.Context, pp.PP, api.IPAccessRuleSet, netip.Prefix, string) (api.ID, bool)) *MockHandleCreateIPAccessRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleCreateIPAccessRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.IPAccessRuleSet, netip.Prefix, string) (api.ID, bool)) *MockHandleCreateIPAccessRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // CreateRecord mocks base method.
 func (m *MockHandle) CreateRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, arg3 domain.Domain, ip netip.Addr, desiredParams api.RecordParams) (api.ID, bool) {
 	m.ctrl.T.Helper()
@@ -84,6 +161,45 @@ func (c *MockHandleCreateRecordCall) DoAndReturn(f func(context.Context, pp.PP,
 	return c
 }
 
+// CreateWAFCustomRule mocks base method.
+func (m *MockHandle) CreateWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID, rulesetID api.ID, rule api.WAFCustomRule) (api.ID, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWAFCustomRule", ctx, ppfmt, zoneID, rulesetID, rule)
+	ret0, _ := ret[0].(api.ID)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// CreateWAFCustomRule indicates an expected call of CreateWAFCustomRule.
+func (mr *MockHandleMockRecorder) CreateWAFCustomRule(ctx, ppfmt, zoneID, rulesetID, rule any) *MockHandleCreateWAFCustomRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWAFCustomRule", reflect.TypeOf((*MockHandle)(nil).CreateWAFCustomRule), ctx, ppfmt, zoneID, rulesetID, rule)
+	return &MockHandleCreateWAFCustomRuleCall{Call: call}
+}
+
+// MockHandleCreateWAFCustomRuleCall wrap *gomock.Call
+type MockHandleCreateWAFCustomRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleCreateWAFCustomRuleCall) Return(arg0 api.ID, arg1 bool) *MockHandleCreateWAFCustomRuleCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleCreateWAFCustomRuleCall) Do(f func(context.Context, pp.PP, api.ID, api.ID, api.WAFCustomRule) (api.ID, bool)) *MockHandleCreateWAFCustomRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleCreateWAFCustomRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.ID, api.ID, api.WAFCustomRule) (api.ID, bool)) *MockHandleCreateWAFCustomRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // CreateWAFListItems mocks base method.
 func (m *MockHandle) CreateWAFListItems(ctx context.Context, ppfmt pp.PP, list api.WAFList, fallbackDescription string, items []api.WAFListCreateItem) bool {
 	m.ctrl.T.Helper()
@@ -122,6 +238,44 @@ func (c *MockHandleCreateWAFListItemsCall) DoAndReturn(f func(context.Context, p
 	return c
 }
 
+// DeleteIPAccessRule mocks base method.
+func (m *MockHandle) DeleteIPAccessRule(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet, id api.ID) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIPAccessRule", ctx, ppfmt, set, id)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DeleteIPAccessRule indicates an expected call of DeleteIPAccessRule.
+func (mr *MockHandleMockRecorder) DeleteIPAccessRule(ctx, ppfmt, set, id any) *MockHandleDeleteIPAccessRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIPAccessRule", reflect.TypeOf((*MockHandle)(nil).DeleteIPAccessRule), ctx, ppfmt, set, id)
+	return &MockHandleDeleteIPAccessRuleCall{Call: call}
+}
+
+// MockHandleDeleteIPAccessRuleCall wrap *gomock.Call
+type MockHandleDeleteIPAccessRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleDeleteIPAccessRuleCall) Return(arg0 bool) *MockHandleDeleteIPAccessRuleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleDeleteIPAccessRuleCall) Do(f func(context.Context, pp.PP, api.IPAccessRuleSet, api.ID) bool) *MockHandleDeleteIPAccessRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleDeleteIPAccessRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.IPAccessRuleSet, api.ID) bool) *MockHandleDeleteIPAccessRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // DeleteRecord mocks base method.
 func (m *MockHandle) DeleteRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, arg3 domain.Domain, id api.ID, mode api.DeletionMode) bool {
 	m.ctrl.T.Helper()
@@ -160,6 +314,44 @@ func (c *MockHandleDeleteRecordCall) DoAndReturn(f func(context.Context, pp.PP,
 	return c
 }
 
+// DeleteWAFCustomRule mocks base method.
+func (m *MockHandle) DeleteWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID, rulesetID, ruleID api.ID) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWAFCustomRule", ctx, ppfmt, zoneID, rulesetID, ruleID)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DeleteWAFCustomRule indicates an expected call of DeleteWAFCustomRule.
+func (mr *MockHandleMockRecorder) DeleteWAFCustomRule(ctx, ppfmt, zoneID, rulesetID, ruleID any) *MockHandleDeleteWAFCustomRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWAFCustomRule", reflect.TypeOf((*MockHandle)(nil).DeleteWAFCustomRule), ctx, ppfmt, zoneID, rulesetID, ruleID)
+	return &MockHandleDeleteWAFCustomRuleCall{Call: call}
+}
+
+// MockHandleDeleteWAFCustomRuleCall wrap *gomock.Call
+type MockHandleDeleteWAFCustomRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleDeleteWAFCustomRuleCall) Return(arg0 bool) *MockHandleDeleteWAFCustomRuleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleDeleteWAFCustomRuleCall) Do(f func(context.Context, pp.PP, api.ID, api.ID, api.ID) bool) *MockHandleDeleteWAFCustomRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleDeleteWAFCustomRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.ID, api.ID, api.ID) bool) *MockHandleDeleteWAFCustomRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // DeleteWAFListItems mocks base method.
 func (m *MockHandle) DeleteWAFListItems(ctx context.Context, ppfmt pp.PP, list api.WAFList, fallbackDescription string, ids []api.ID) bool {
 	m.ctrl.T.Helper()
@@ -198,6 +390,44 @@ func (c *MockHandleDeleteWAFListItemsCall) DoAndReturn(f func(context.Context, p
 	return c
 }
 
+// DeleteWorkersKVValue mocks base method.
+func (m *MockHandle) DeleteWorkersKVValue(ctx context.Context, ppfmt pp.PP, key api.WorkersKVKey) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWorkersKVValue", ctx, ppfmt, key)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DeleteWorkersKVValue indicates an expected call of DeleteWorkersKVValue.
+func (mr *MockHandleMockRecorder) DeleteWorkersKVValue(ctx, ppfmt, key any) *MockHandleDeleteWorkersKVValueCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWorkersKVValue", reflect.TypeOf((*MockHandle)(nil).DeleteWorkersKVValue), ctx, ppfmt, key)
+	return &MockHandleDeleteWorkersKVValueCall{Call: call}
+}
+
+// MockHandleDeleteWorkersKVValueCall wrap *gomock.Call
+type MockHandleDeleteWorkersKVValueCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleDeleteWorkersKVValueCall) Return(arg0 bool) *MockHandleDeleteWorkersKVValueCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleDeleteWorkersKVValueCall) Do(f func(context.Context, pp.PP, api.WorkersKVKey) bool) *MockHandleDeleteWorkersKVValueCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleDeleteWorkersKVValueCall) DoAndReturn(f func(context.Context, pp.PP, api.WorkersKVKey) bool) *MockHandleDeleteWorkersKVValueCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // FinalCleanWAFList mocks base method.
 func (m *MockHandle) FinalCleanWAFList(ctx context.Context, ppfmt pp.PP, list api.WAFList, fallbackDescription string, managedFamilies map[ipnet.Family]bool) api.WAFListCleanupCode {
 	m.ctrl.T.Helper()
@@ -236,6 +466,240 @@ func (c *MockHandleFinalCleanWAFListCall) DoAndReturn(f func(context.Context, pp
 	return c
 }
 
+// GetLBPoolOrigin mocks base method.
+func (m *MockHandle) GetLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin api.LBPoolOrigin) (api.LBPoolOriginState, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLBPoolOrigin", ctx, ppfmt, origin)
+	ret0, _ := ret[0].(api.LBPoolOriginState)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetLBPoolOrigin indicates an expected call of GetLBPoolOrigin.
+func (mr *MockHandleMockRecorder) GetLBPoolOrigin(ctx, ppfmt, origin any) *MockHandleGetLBPoolOriginCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLBPoolOrigin", reflect.TypeOf((*MockHandle)(nil).GetLBPoolOrigin), ctx, ppfmt, origin)
+	return &MockHandleGetLBPoolOriginCall{Call: call}
+}
+
+// MockHandleGetLBPoolOriginCall wrap *gomock.Call
+type MockHandleGetLBPoolOriginCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleGetLBPoolOriginCall) Return(arg0 api.LBPoolOriginState, arg1 bool) *MockHandleGetLBPoolOriginCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleGetLBPoolOriginCall) Do(f func(context.Context, pp.PP, api.LBPoolOrigin) (api.LBPoolOriginState, bool)) *MockHandleGetLBPoolOriginCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleGetLBPoolOriginCall) DoAndReturn(f func(context.Context, pp.PP, api.LBPoolOrigin) (api.LBPoolOriginState, bool)) *MockHandleGetLBPoolOriginCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// GetSpectrumAppOrigins mocks base method.
+func (m *MockHandle) GetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app api.SpectrumApp) ([]api.SpectrumOrigin, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpectrumAppOrigins", ctx, ppfmt, app)
+	ret0, _ := ret[0].([]api.SpectrumOrigin)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetSpectrumAppOrigins indicates an expected call of GetSpectrumAppOrigins.
+func (mr *MockHandleMockRecorder) GetSpectrumAppOrigins(ctx, ppfmt, app any) *MockHandleGetSpectrumAppOriginsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpectrumAppOrigins", reflect.TypeOf((*MockHandle)(nil).GetSpectrumAppOrigins), ctx, ppfmt, app)
+	return &MockHandleGetSpectrumAppOriginsCall{Call: call}
+}
+
+// MockHandleGetSpectrumAppOriginsCall wrap *gomock.Call
+type MockHandleGetSpectrumAppOriginsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleGetSpectrumAppOriginsCall) Return(arg0 []api.SpectrumOrigin, arg1 bool) *MockHandleGetSpectrumAppOriginsCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleGetSpectrumAppOriginsCall) Do(f func(context.Context, pp.PP, api.SpectrumApp) ([]api.SpectrumOrigin, bool)) *MockHandleGetSpectrumAppOriginsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleGetSpectrumAppOriginsCall) DoAndReturn(f func(context.Context, pp.PP, api.SpectrumApp) ([]api.SpectrumOrigin, bool)) *MockHandleGetSpectrumAppOriginsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// GetWorkersKVValue mocks base method.
+func (m *MockHandle) GetWorkersKVValue(ctx context.Context, ppfmt pp.PP, key api.WorkersKVKey) ([]byte, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkersKVValue", ctx, ppfmt, key)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetWorkersKVValue indicates an expected call of GetWorkersKVValue.
+func (mr *MockHandleMockRecorder) GetWorkersKVValue(ctx, ppfmt, key any) *MockHandleGetWorkersKVValueCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkersKVValue", reflect.TypeOf((*MockHandle)(nil).GetWorkersKVValue), ctx, ppfmt, key)
+	return &MockHandleGetWorkersKVValueCall{Call: call}
+}
+
+// MockHandleGetWorkersKVValueCall wrap *gomock.Call
+type MockHandleGetWorkersKVValueCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleGetWorkersKVValueCall) Return(arg0 []byte, arg1 bool) *MockHandleGetWorkersKVValueCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleGetWorkersKVValueCall) Do(f func(context.Context, pp.PP, api.WorkersKVKey) ([]byte, bool)) *MockHandleGetWorkersKVValueCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleGetWorkersKVValueCall) DoAndReturn(f func(context.Context, pp.PP, api.WorkersKVKey) ([]byte, bool)) *MockHandleGetWorkersKVValueCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ListAccessGroupIPRules mocks base method.
+func (m *MockHandle) ListAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group api.AccessGroup) ([]netip.Prefix, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccessGroupIPRules", ctx, ppfmt, group)
+	ret0, _ := ret[0].([]netip.Prefix)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// ListAccessGroupIPRules indicates an expected call of ListAccessGroupIPRules.
+func (mr *MockHandleMockRecorder) ListAccessGroupIPRules(ctx, ppfmt, group any) *MockHandleListAccessGroupIPRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccessGroupIPRules", reflect.TypeOf((*MockHandle)(nil).ListAccessGroupIPRules), ctx, ppfmt, group)
+	return &MockHandleListAccessGroupIPRulesCall{Call: call}
+}
+
+// MockHandleListAccessGroupIPRulesCall wrap *gomock.Call
+type MockHandleListAccessGroupIPRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleListAccessGroupIPRulesCall) Return(arg0 []netip.Prefix, arg1 bool) *MockHandleListAccessGroupIPRulesCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleListAccessGroupIPRulesCall) Do(f func(context.Context, pp.PP, api.AccessGroup) ([]netip.Prefix, bool)) *MockHandleListAccessGroupIPRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleListAccessGroupIPRulesCall) DoAndReturn(f func(context.Context, pp.PP, api.AccessGroup) ([]netip.Prefix, bool)) *MockHandleListAccessGroupIPRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ListGatewayLocationNetworks mocks base method.
+func (m *MockHandle) ListGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location api.GatewayLocation) ([]netip.Prefix, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGatewayLocationNetworks", ctx, ppfmt, location)
+	ret0, _ := ret[0].([]netip.Prefix)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// ListGatewayLocationNetworks indicates an expected call of ListGatewayLocationNetworks.
+func (mr *MockHandleMockRecorder) ListGatewayLocationNetworks(ctx, ppfmt, location any) *MockHandleListGatewayLocationNetworksCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGatewayLocationNetworks", reflect.TypeOf((*MockHandle)(nil).ListGatewayLocationNetworks), ctx, ppfmt, location)
+	return &MockHandleListGatewayLocationNetworksCall{Call: call}
+}
+
+// MockHandleListGatewayLocationNetworksCall wrap *gomock.Call
+type MockHandleListGatewayLocationNetworksCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleListGatewayLocationNetworksCall) Return(arg0 []netip.Prefix, arg1 bool) *MockHandleListGatewayLocationNetworksCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleListGatewayLocationNetworksCall) Do(f func(context.Context, pp.PP, api.GatewayLocation) ([]netip.Prefix, bool)) *MockHandleListGatewayLocationNetworksCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleListGatewayLocationNetworksCall) DoAndReturn(f func(context.Context, pp.PP, api.GatewayLocation) ([]netip.Prefix, bool)) *MockHandleListGatewayLocationNetworksCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ListIPAccessRules mocks base method.
+func (m *MockHandle) ListIPAccessRules(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet) ([]api.IPAccessRule, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIPAccessRules", ctx, ppfmt, set)
+	ret0, _ := ret[0].([]api.IPAccessRule)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// ListIPAccessRules indicates an expected call of ListIPAccessRules.
+func (mr *MockHandleMockRecorder) ListIPAccessRules(ctx, ppfmt, set any) *MockHandleListIPAccessRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIPAccessRules", reflect.TypeOf((*MockHandle)(nil).ListIPAccessRules), ctx, ppfmt, set)
+	return &MockHandleListIPAccessRulesCall{Call: call}
+}
+
+// MockHandleListIPAccessRulesCall wrap *gomock.Call
+type MockHandleListIPAccessRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleListIPAccessRulesCall) Return(arg0 []api.IPAccessRule, arg1 bool) *MockHandleListIPAccessRulesCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleListIPAccessRulesCall) Do(f func(context.Context, pp.PP, api.IPAccessRuleSet) ([]api.IPAccessRule, bool)) *MockHandleListIPAccessRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleListIPAccessRulesCall) DoAndReturn(f func(context.Context, pp.PP, api.IPAccessRuleSet) ([]api.IPAccessRule, bool)) *MockHandleListIPAccessRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ListRecords mocks base method.
 func (m *MockHandle) ListRecords(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, arg3 domain.Domain, fallbackParams api.RecordParams) ([]api.Record, bool, bool) {
 	m.ctrl.T.Helper()
@@ -276,6 +740,46 @@ func (c *MockHandleListRecordsCall) DoAndReturn(f func(context.Context, pp.PP, i
 	return c
 }
 
+// ListWAFCustomRules mocks base method.
+func (m *MockHandle) ListWAFCustomRules(ctx context.Context, ppfmt pp.PP, zoneID api.ID) (api.ID, []api.WAFCustomRule, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWAFCustomRules", ctx, ppfmt, zoneID)
+	ret0, _ := ret[0].(api.ID)
+	ret1, _ := ret[1].([]api.WAFCustomRule)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// ListWAFCustomRules indicates an expected call of ListWAFCustomRules.
+func (mr *MockHandleMockRecorder) ListWAFCustomRules(ctx, ppfmt, zoneID any) *MockHandleListWAFCustomRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWAFCustomRules", reflect.TypeOf((*MockHandle)(nil).ListWAFCustomRules), ctx, ppfmt, zoneID)
+	return &MockHandleListWAFCustomRulesCall{Call: call}
+}
+
+// MockHandleListWAFCustomRulesCall wrap *gomock.Call
+type MockHandleListWAFCustomRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleListWAFCustomRulesCall) Return(arg0 api.ID, arg1 []api.WAFCustomRule, arg2 bool) *MockHandleListWAFCustomRulesCall {
+	c.Call = c.Call.Return(arg0, arg1, arg2)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleListWAFCustomRulesCall) Do(f func(context.Context, pp.PP, api.ID) (api.ID, []api.WAFCustomRule, bool)) *MockHandleListWAFCustomRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleListWAFCustomRulesCall) DoAndReturn(f func(context.Context, pp.PP, api.ID) (api.ID, []api.WAFCustomRule, bool)) *MockHandleListWAFCustomRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ListWAFListItems mocks base method.
 func (m *MockHandle) ListWAFListItems(ctx context.Context, ppfmt pp.PP, list api.WAFList, fallbackDescription, fallbackItemComment string) ([]api.WAFListItem, bool, bool, bool) {
 	m.ctrl.T.Helper()
@@ -317,6 +821,196 @@ func (c *MockHandleListWAFListItemsCall) DoAndReturn(f func(context.Context, pp.
 	return c
 }
 
+// PutWorkersKVValue mocks base method.
+func (m *MockHandle) PutWorkersKVValue(ctx context.Context, ppfmt pp.PP, key api.WorkersKVKey, value []byte) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutWorkersKVValue", ctx, ppfmt, key, value)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// PutWorkersKVValue indicates an expected call of PutWorkersKVValue.
+func (mr *MockHandleMockRecorder) PutWorkersKVValue(ctx, ppfmt, key, value any) *MockHandlePutWorkersKVValueCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWorkersKVValue", reflect.TypeOf((*MockHandle)(nil).PutWorkersKVValue), ctx, ppfmt, key, value)
+	return &MockHandlePutWorkersKVValueCall{Call: call}
+}
+
+// MockHandlePutWorkersKVValueCall wrap *gomock.Call
+type MockHandlePutWorkersKVValueCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandlePutWorkersKVValueCall) Return(arg0 bool) *MockHandlePutWorkersKVValueCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandlePutWorkersKVValueCall) Do(f func(context.Context, pp.PP, api.WorkersKVKey, []byte) bool) *MockHandlePutWorkersKVValueCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandlePutWorkersKVValueCall) DoAndReturn(f func(context.Context, pp.PP, api.WorkersKVKey, []byte) bool) *MockHandlePutWorkersKVValueCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetAccessGroupIPRules mocks base method.
+func (m *MockHandle) SetAccessGroupIPRules(ctx context.Context, ppfmt pp.PP, group api.AccessGroup, prefixes []netip.Prefix) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAccessGroupIPRules", ctx, ppfmt, group, prefixes)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SetAccessGroupIPRules indicates an expected call of SetAccessGroupIPRules.
+func (mr *MockHandleMockRecorder) SetAccessGroupIPRules(ctx, ppfmt, group, prefixes any) *MockHandleSetAccessGroupIPRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccessGroupIPRules", reflect.TypeOf((*MockHandle)(nil).SetAccessGroupIPRules), ctx, ppfmt, group, prefixes)
+	return &MockHandleSetAccessGroupIPRulesCall{Call: call}
+}
+
+// MockHandleSetAccessGroupIPRulesCall wrap *gomock.Call
+type MockHandleSetAccessGroupIPRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleSetAccessGroupIPRulesCall) Return(arg0 bool) *MockHandleSetAccessGroupIPRulesCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleSetAccessGroupIPRulesCall) Do(f func(context.Context, pp.PP, api.AccessGroup, []netip.Prefix) bool) *MockHandleSetAccessGroupIPRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleSetAccessGroupIPRulesCall) DoAndReturn(f func(context.Context, pp.PP, api.AccessGroup, []netip.Prefix) bool) *MockHandleSetAccessGroupIPRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetGatewayLocationNetworks mocks base method.
+func (m *MockHandle) SetGatewayLocationNetworks(ctx context.Context, ppfmt pp.PP, location api.GatewayLocation, networks []netip.Prefix) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetGatewayLocationNetworks", ctx, ppfmt, location, networks)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SetGatewayLocationNetworks indicates an expected call of SetGatewayLocationNetworks.
+func (mr *MockHandleMockRecorder) SetGatewayLocationNetworks(ctx, ppfmt, location, networks any) *MockHandleSetGatewayLocationNetworksCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGatewayLocationNetworks", reflect.TypeOf((*MockHandle)(nil).SetGatewayLocationNetworks), ctx, ppfmt, location, networks)
+	return &MockHandleSetGatewayLocationNetworksCall{Call: call}
+}
+
+// MockHandleSetGatewayLocationNetworksCall wrap *gomock.Call
+type MockHandleSetGatewayLocationNetworksCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleSetGatewayLocationNetworksCall) Return(arg0 bool) *MockHandleSetGatewayLocationNetworksCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleSetGatewayLocationNetworksCall) Do(f func(context.Context, pp.PP, api.GatewayLocation, []netip.Prefix) bool) *MockHandleSetGatewayLocationNetworksCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleSetGatewayLocationNetworksCall) DoAndReturn(f func(context.Context, pp.PP, api.GatewayLocation, []netip.Prefix) bool) *MockHandleSetGatewayLocationNetworksCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetSpectrumAppOrigins mocks base method.
+func (m *MockHandle) SetSpectrumAppOrigins(ctx context.Context, ppfmt pp.PP, app api.SpectrumApp, origins []api.SpectrumOrigin) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSpectrumAppOrigins", ctx, ppfmt, app, origins)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SetSpectrumAppOrigins indicates an expected call of SetSpectrumAppOrigins.
+func (mr *MockHandleMockRecorder) SetSpectrumAppOrigins(ctx, ppfmt, app, origins any) *MockHandleSetSpectrumAppOriginsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSpectrumAppOrigins", reflect.TypeOf((*MockHandle)(nil).SetSpectrumAppOrigins), ctx, ppfmt, app, origins)
+	return &MockHandleSetSpectrumAppOriginsCall{Call: call}
+}
+
+// MockHandleSetSpectrumAppOriginsCall wrap *gomock.Call
+type MockHandleSetSpectrumAppOriginsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleSetSpectrumAppOriginsCall) Return(arg0 bool) *MockHandleSetSpectrumAppOriginsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleSetSpectrumAppOriginsCall) Do(f func(context.Context, pp.PP, api.SpectrumApp, []api.SpectrumOrigin) bool) *MockHandleSetSpectrumAppOriginsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleSetSpectrumAppOriginsCall) DoAndReturn(f func(context.Context, pp.PP, api.SpectrumApp, []api.SpectrumOrigin) bool) *MockHandleSetSpectrumAppOriginsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// UpdateLBPoolOrigin mocks base method.
+func (m *MockHandle) UpdateLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin api.LBPoolOrigin, desired api.LBPoolOriginState) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLBPoolOrigin", ctx, ppfmt, origin, desired)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UpdateLBPoolOrigin indicates an expected call of UpdateLBPoolOrigin.
+func (mr *MockHandleMockRecorder) UpdateLBPoolOrigin(ctx, ppfmt, origin, desired any) *MockHandleUpdateLBPoolOriginCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLBPoolOrigin", reflect.TypeOf((*MockHandle)(nil).UpdateLBPoolOrigin), ctx, ppfmt, origin, desired)
+	return &MockHandleUpdateLBPoolOriginCall{Call: call}
+}
+
+// MockHandleUpdateLBPoolOriginCall wrap *gomock.Call
+type MockHandleUpdateLBPoolOriginCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleUpdateLBPoolOriginCall) Return(arg0 bool) *MockHandleUpdateLBPoolOriginCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleUpdateLBPoolOriginCall) Do(f func(context.Context, pp.PP, api.LBPoolOrigin, api.LBPoolOriginState) bool) *MockHandleUpdateLBPoolOriginCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleUpdateLBPoolOriginCall) DoAndReturn(f func(context.Context, pp.PP, api.LBPoolOrigin, api.LBPoolOriginState) bool) *MockHandleUpdateLBPoolOriginCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // UpdateRecord mocks base method.
 func (m *MockHandle) UpdateRecord(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, arg3 domain.Domain, id api.ID, ip netip.Addr, desiredParams api.RecordParams) bool {
 	m.ctrl.T.Helper()
@@ -354,3 +1048,41 @@ func (c *MockHandleUpdateRecordCall) DoAndReturn(f func(context.Context, pp.PP,
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
+
+// UpdateWAFCustomRule mocks base method.
+func (m *MockHandle) UpdateWAFCustomRule(ctx context.Context, ppfmt pp.PP, zoneID, rulesetID api.ID, rule api.WAFCustomRule) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWAFCustomRule", ctx, ppfmt, zoneID, rulesetID, rule)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UpdateWAFCustomRule indicates an expected call of UpdateWAFCustomRule.
+func (mr *MockHandleMockRecorder) UpdateWAFCustomRule(ctx, ppfmt, zoneID, rulesetID, rule any) *MockHandleUpdateWAFCustomRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWAFCustomRule", reflect.TypeOf((*MockHandle)(nil).UpdateWAFCustomRule), ctx, ppfmt, zoneID, rulesetID, rule)
+	return &MockHandleUpdateWAFCustomRuleCall{Call: call}
+}
+
+// MockHandleUpdateWAFCustomRuleCall wrap *gomock.Call
+type MockHandleUpdateWAFCustomRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandleUpdateWAFCustomRuleCall) Return(arg0 bool) *MockHandleUpdateWAFCustomRuleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandleUpdateWAFCustomRuleCall) Do(f func(context.Context, pp.PP, api.ID, api.ID, api.WAFCustomRule) bool) *MockHandleUpdateWAFCustomRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandleUpdateWAFCustomRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.ID, api.ID, api.WAFCustomRule) bool) *MockHandleUpdateWAFCustomRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/favonia/cloudflare-ddns/internal/setter (interfaces: Setter)
+// Source: github.com/favonia/cloudflare-ddns/internal/setter (interfaces: Setter,RecordSetter)
 //
 // Generated by this command:
 //
-//	mockgen -typed -destination=../mocks/mock_setter.go -package=mocks . Setter
+//	mockgen -typed -destination=../mocks/mock_setter.go -package=mocks . Setter,RecordSetter
 //
 
 // Package mocks is a generated GoMock package.
@@ -13,6 +13,7 @@ import (
 	context "context"
 	netip "net/netip"
 	reflect "reflect"
+	time "time"
 
 	api "github.com/favonia/cloudflare-ddns/internal/api"
 	domain "github.com/favonia/cloudflare-ddns/internal/domain"
@@ -46,6 +47,158 @@ func (m *MockSetter) EXPECT() *MockSetterMockRecorder {
 	return m.recorder
 }
 
+// CheckPermissions mocks base method.
+func (m *MockSetter) CheckPermissions(ctx context.Context, ppfmt pp.PP, domains []domain.Domain, lists []api.WAFList) api.PermissionReport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPermissions", ctx, ppfmt, domains, lists)
+	ret0, _ := ret[0].(api.PermissionReport)
+	return ret0
+}
+
+// CheckPermissions indicates an expected call of CheckPermissions.
+func (mr *MockSetterMockRecorder) CheckPermissions(ctx, ppfmt, domains, lists any) *MockSetterCheckPermissionsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPermissions", reflect.TypeOf((*MockSetter)(nil).CheckPermissions), ctx, ppfmt, domains, lists)
+	return &MockSetterCheckPermissionsCall{Call: call}
+}
+
+// MockSetterCheckPermissionsCall wrap *gomock.Call
+type MockSetterCheckPermissionsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterCheckPermissionsCall) Return(arg0 api.PermissionReport) *MockSetterCheckPermissionsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterCheckPermissionsCall) Do(f func(context.Context, pp.PP, []domain.Domain, []api.WAFList) api.PermissionReport) *MockSetterCheckPermissionsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterCheckPermissionsCall) DoAndReturn(f func(context.Context, pp.PP, []domain.Domain, []api.WAFList) api.PermissionReport) *MockSetterCheckPermissionsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// FinalClearAccessGroup mocks base method.
+func (m *MockSetter) FinalClearAccessGroup(ctx context.Context, ppfmt pp.PP, group api.AccessGroup, managedFamilies map[ipnet.Family]bool) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalClearAccessGroup", ctx, ppfmt, group, managedFamilies)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// FinalClearAccessGroup indicates an expected call of FinalClearAccessGroup.
+func (mr *MockSetterMockRecorder) FinalClearAccessGroup(ctx, ppfmt, group, managedFamilies any) *MockSetterFinalClearAccessGroupCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalClearAccessGroup", reflect.TypeOf((*MockSetter)(nil).FinalClearAccessGroup), ctx, ppfmt, group, managedFamilies)
+	return &MockSetterFinalClearAccessGroupCall{Call: call}
+}
+
+// MockSetterFinalClearAccessGroupCall wrap *gomock.Call
+type MockSetterFinalClearAccessGroupCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterFinalClearAccessGroupCall) Return(arg0 setter.ResponseCode) *MockSetterFinalClearAccessGroupCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterFinalClearAccessGroupCall) Do(f func(context.Context, pp.PP, api.AccessGroup, map[ipnet.Family]bool) setter.ResponseCode) *MockSetterFinalClearAccessGroupCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterFinalClearAccessGroupCall) DoAndReturn(f func(context.Context, pp.PP, api.AccessGroup, map[ipnet.Family]bool) setter.ResponseCode) *MockSetterFinalClearAccessGroupCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// FinalClearGatewayLocation mocks base method.
+func (m *MockSetter) FinalClearGatewayLocation(ctx context.Context, ppfmt pp.PP, location api.GatewayLocation, managedFamilies map[ipnet.Family]bool) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalClearGatewayLocation", ctx, ppfmt, location, managedFamilies)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// FinalClearGatewayLocation indicates an expected call of FinalClearGatewayLocation.
+func (mr *MockSetterMockRecorder) FinalClearGatewayLocation(ctx, ppfmt, location, managedFamilies any) *MockSetterFinalClearGatewayLocationCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalClearGatewayLocation", reflect.TypeOf((*MockSetter)(nil).FinalClearGatewayLocation), ctx, ppfmt, location, managedFamilies)
+	return &MockSetterFinalClearGatewayLocationCall{Call: call}
+}
+
+// MockSetterFinalClearGatewayLocationCall wrap *gomock.Call
+type MockSetterFinalClearGatewayLocationCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterFinalClearGatewayLocationCall) Return(arg0 setter.ResponseCode) *MockSetterFinalClearGatewayLocationCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterFinalClearGatewayLocationCall) Do(f func(context.Context, pp.PP, api.GatewayLocation, map[ipnet.Family]bool) setter.ResponseCode) *MockSetterFinalClearGatewayLocationCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterFinalClearGatewayLocationCall) DoAndReturn(f func(context.Context, pp.PP, api.GatewayLocation, map[ipnet.Family]bool) setter.ResponseCode) *MockSetterFinalClearGatewayLocationCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// FinalClearIPAccessRules mocks base method.
+func (m *MockSetter) FinalClearIPAccessRules(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet, managedFamilies map[ipnet.Family]bool) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalClearIPAccessRules", ctx, ppfmt, set, managedFamilies)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// FinalClearIPAccessRules indicates an expected call of FinalClearIPAccessRules.
+func (mr *MockSetterMockRecorder) FinalClearIPAccessRules(ctx, ppfmt, set, managedFamilies any) *MockSetterFinalClearIPAccessRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalClearIPAccessRules", reflect.TypeOf((*MockSetter)(nil).FinalClearIPAccessRules), ctx, ppfmt, set, managedFamilies)
+	return &MockSetterFinalClearIPAccessRulesCall{Call: call}
+}
+
+// MockSetterFinalClearIPAccessRulesCall wrap *gomock.Call
+type MockSetterFinalClearIPAccessRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterFinalClearIPAccessRulesCall) Return(arg0 setter.ResponseCode) *MockSetterFinalClearIPAccessRulesCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterFinalClearIPAccessRulesCall) Do(f func(context.Context, pp.PP, api.IPAccessRuleSet, map[ipnet.Family]bool) setter.ResponseCode) *MockSetterFinalClearIPAccessRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterFinalClearIPAccessRulesCall) DoAndReturn(f func(context.Context, pp.PP, api.IPAccessRuleSet, map[ipnet.Family]bool) setter.ResponseCode) *MockSetterFinalClearIPAccessRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // FinalClearWAFList mocks base method.
 func (m *MockSetter) FinalClearWAFList(ctx context.Context, ppfmt pp.PP, list api.WAFList, listDescription string, managedFamilies map[ipnet.Family]bool) setter.ResponseCode {
 	m.ctrl.T.Helper()
@@ -122,78 +275,710 @@ func (c *MockSetterFinalDeleteCall) DoAndReturn(f func(context.Context, pp.PP, i
 	return c
 }
 
-// SetIPs mocks base method.
-func (m *MockSetter) SetIPs(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, Domain domain.Domain, IPs []netip.Addr, fallbackParams api.RecordParams) setter.ResponseCode {
+// FinalDeleteWAFListRule mocks base method.
+func (m *MockSetter) FinalDeleteWAFListRule(ctx context.Context, ppfmt pp.PP, rule api.WAFListRule, description string) setter.ResponseCode {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetIPs", ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams)
+	ret := m.ctrl.Call(m, "FinalDeleteWAFListRule", ctx, ppfmt, rule, description)
 	ret0, _ := ret[0].(setter.ResponseCode)
 	return ret0
 }
 
-// SetIPs indicates an expected call of SetIPs.
-func (mr *MockSetterMockRecorder) SetIPs(ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams any) *MockSetterSetIPsCall {
+// FinalDeleteWAFListRule indicates an expected call of FinalDeleteWAFListRule.
+func (mr *MockSetterMockRecorder) FinalDeleteWAFListRule(ctx, ppfmt, rule, description any) *MockSetterFinalDeleteWAFListRuleCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIPs", reflect.TypeOf((*MockSetter)(nil).SetIPs), ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams)
-	return &MockSetterSetIPsCall{Call: call}
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalDeleteWAFListRule", reflect.TypeOf((*MockSetter)(nil).FinalDeleteWAFListRule), ctx, ppfmt, rule, description)
+	return &MockSetterFinalDeleteWAFListRuleCall{Call: call}
 }
 
-// MockSetterSetIPsCall wrap *gomock.Call
-type MockSetterSetIPsCall struct {
+// MockSetterFinalDeleteWAFListRuleCall wrap *gomock.Call
+type MockSetterFinalDeleteWAFListRuleCall struct {
 	*gomock.Call
 }
 
 // Return rewrite *gomock.Call.Return
-func (c *MockSetterSetIPsCall) Return(arg0 setter.ResponseCode) *MockSetterSetIPsCall {
+func (c *MockSetterFinalDeleteWAFListRuleCall) Return(arg0 setter.ResponseCode) *MockSetterFinalDeleteWAFListRuleCall {
 	c.Call = c.Call.Return(arg0)
 	return c
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockSetterSetIPsCall) Do(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, []netip.Addr, api.RecordParams) setter.ResponseCode) *MockSetterSetIPsCall {
+func (c *MockSetterFinalDeleteWAFListRuleCall) Do(f func(context.Context, pp.PP, api.WAFListRule, string) setter.ResponseCode) *MockSetterFinalDeleteWAFListRuleCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockSetterSetIPsCall) DoAndReturn(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, []netip.Addr, api.RecordParams) setter.ResponseCode) *MockSetterSetIPsCall {
+func (c *MockSetterFinalDeleteWAFListRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.WAFListRule, string) setter.ResponseCode) *MockSetterFinalDeleteWAFListRuleCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
-// SetWAFList mocks base method.
-func (m *MockSetter) SetWAFList(ctx context.Context, ppfmt pp.PP, list api.WAFList, listDescription string, targetsByFamily map[ipnet.Family]setter.WAFTargets, fallbackItemComment string) setter.ResponseCode {
+// FinalDeleteWorkersKV mocks base method.
+func (m *MockSetter) FinalDeleteWorkersKV(ctx context.Context, ppfmt pp.PP, key api.WorkersKVKey) setter.ResponseCode {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetWAFList", ctx, ppfmt, list, listDescription, targetsByFamily, fallbackItemComment)
+	ret := m.ctrl.Call(m, "FinalDeleteWorkersKV", ctx, ppfmt, key)
 	ret0, _ := ret[0].(setter.ResponseCode)
 	return ret0
 }
 
-// SetWAFList indicates an expected call of SetWAFList.
-func (mr *MockSetterMockRecorder) SetWAFList(ctx, ppfmt, list, listDescription, targetsByFamily, fallbackItemComment any) *MockSetterSetWAFListCall {
+// FinalDeleteWorkersKV indicates an expected call of FinalDeleteWorkersKV.
+func (mr *MockSetterMockRecorder) FinalDeleteWorkersKV(ctx, ppfmt, key any) *MockSetterFinalDeleteWorkersKVCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWAFList", reflect.TypeOf((*MockSetter)(nil).SetWAFList), ctx, ppfmt, list, listDescription, targetsByFamily, fallbackItemComment)
-	return &MockSetterSetWAFListCall{Call: call}
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalDeleteWorkersKV", reflect.TypeOf((*MockSetter)(nil).FinalDeleteWorkersKV), ctx, ppfmt, key)
+	return &MockSetterFinalDeleteWorkersKVCall{Call: call}
 }
 
-// MockSetterSetWAFListCall wrap *gomock.Call
-type MockSetterSetWAFListCall struct {
+// MockSetterFinalDeleteWorkersKVCall wrap *gomock.Call
+type MockSetterFinalDeleteWorkersKVCall struct {
 	*gomock.Call
 }
 
 // Return rewrite *gomock.Call.Return
-func (c *MockSetterSetWAFListCall) Return(arg0 setter.ResponseCode) *MockSetterSetWAFListCall {
+func (c *MockSetterFinalDeleteWorkersKVCall) Return(arg0 setter.ResponseCode) *MockSetterFinalDeleteWorkersKVCall {
 	c.Call = c.Call.Return(arg0)
 	return c
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockSetterSetWAFListCall) Do(f func(context.Context, pp.PP, api.WAFList, string, map[ipnet.Family]setter.WAFTargets, string) setter.ResponseCode) *MockSetterSetWAFListCall {
+func (c *MockSetterFinalDeleteWorkersKVCall) Do(f func(context.Context, pp.PP, api.WorkersKVKey) setter.ResponseCode) *MockSetterFinalDeleteWorkersKVCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockSetterSetWAFListCall) DoAndReturn(f func(context.Context, pp.PP, api.WAFList, string, map[ipnet.Family]setter.WAFTargets, string) setter.ResponseCode) *MockSetterSetWAFListCall {
+func (c *MockSetterFinalDeleteWorkersKVCall) DoAndReturn(f func(context.Context, pp.PP, api.WorkersKVKey) setter.ResponseCode) *MockSetterFinalDeleteWorkersKVCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// FinalDisableLBPoolOrigin mocks base method.
+func (m *MockSetter) FinalDisableLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin api.LBPoolOrigin) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalDisableLBPoolOrigin", ctx, ppfmt, origin)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// FinalDisableLBPoolOrigin indicates an expected call of FinalDisableLBPoolOrigin.
+func (mr *MockSetterMockRecorder) FinalDisableLBPoolOrigin(ctx, ppfmt, origin any) *MockSetterFinalDisableLBPoolOriginCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalDisableLBPoolOrigin", reflect.TypeOf((*MockSetter)(nil).FinalDisableLBPoolOrigin), ctx, ppfmt, origin)
+	return &MockSetterFinalDisableLBPoolOriginCall{Call: call}
+}
+
+// MockSetterFinalDisableLBPoolOriginCall wrap *gomock.Call
+type MockSetterFinalDisableLBPoolOriginCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterFinalDisableLBPoolOriginCall) Return(arg0 setter.ResponseCode) *MockSetterFinalDisableLBPoolOriginCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterFinalDisableLBPoolOriginCall) Do(f func(context.Context, pp.PP, api.LBPoolOrigin) setter.ResponseCode) *MockSetterFinalDisableLBPoolOriginCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterFinalDisableLBPoolOriginCall) DoAndReturn(f func(context.Context, pp.PP, api.LBPoolOrigin) setter.ResponseCode) *MockSetterFinalDisableLBPoolOriginCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetAccessGroup mocks base method.
+func (m *MockSetter) SetAccessGroup(ctx context.Context, ppfmt pp.PP, group api.AccessGroup, targetsByFamily map[ipnet.Family]setter.WAFTargets) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAccessGroup", ctx, ppfmt, group, targetsByFamily)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetAccessGroup indicates an expected call of SetAccessGroup.
+func (mr *MockSetterMockRecorder) SetAccessGroup(ctx, ppfmt, group, targetsByFamily any) *MockSetterSetAccessGroupCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccessGroup", reflect.TypeOf((*MockSetter)(nil).SetAccessGroup), ctx, ppfmt, group, targetsByFamily)
+	return &MockSetterSetAccessGroupCall{Call: call}
+}
+
+// MockSetterSetAccessGroupCall wrap *gomock.Call
+type MockSetterSetAccessGroupCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetAccessGroupCall) Return(arg0 setter.ResponseCode) *MockSetterSetAccessGroupCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetAccessGroupCall) Do(f func(context.Context, pp.PP, api.AccessGroup, map[ipnet.Family]setter.WAFTargets) setter.ResponseCode) *MockSetterSetAccessGroupCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetAccessGroupCall) DoAndReturn(f func(context.Context, pp.PP, api.AccessGroup, map[ipnet.Family]setter.WAFTargets) setter.ResponseCode) *MockSetterSetAccessGroupCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetGatewayLocation mocks base method.
+func (m *MockSetter) SetGatewayLocation(ctx context.Context, ppfmt pp.PP, location api.GatewayLocation, targetsByFamily map[ipnet.Family]setter.WAFTargets) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetGatewayLocation", ctx, ppfmt, location, targetsByFamily)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetGatewayLocation indicates an expected call of SetGatewayLocation.
+func (mr *MockSetterMockRecorder) SetGatewayLocation(ctx, ppfmt, location, targetsByFamily any) *MockSetterSetGatewayLocationCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGatewayLocation", reflect.TypeOf((*MockSetter)(nil).SetGatewayLocation), ctx, ppfmt, location, targetsByFamily)
+	return &MockSetterSetGatewayLocationCall{Call: call}
+}
+
+// MockSetterSetGatewayLocationCall wrap *gomock.Call
+type MockSetterSetGatewayLocationCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetGatewayLocationCall) Return(arg0 setter.ResponseCode) *MockSetterSetGatewayLocationCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetGatewayLocationCall) Do(f func(context.Context, pp.PP, api.GatewayLocation, map[ipnet.Family]setter.WAFTargets) setter.ResponseCode) *MockSetterSetGatewayLocationCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetGatewayLocationCall) DoAndReturn(f func(context.Context, pp.PP, api.GatewayLocation, map[ipnet.Family]setter.WAFTargets) setter.ResponseCode) *MockSetterSetGatewayLocationCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetIPAccessRules mocks base method.
+func (m *MockSetter) SetIPAccessRules(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet, targetsByFamily map[ipnet.Family]setter.WAFTargets, fallbackNotes string) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIPAccessRules", ctx, ppfmt, set, targetsByFamily, fallbackNotes)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetIPAccessRules indicates an expected call of SetIPAccessRules.
+func (mr *MockSetterMockRecorder) SetIPAccessRules(ctx, ppfmt, set, targetsByFamily, fallbackNotes any) *MockSetterSetIPAccessRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIPAccessRules", reflect.TypeOf((*MockSetter)(nil).SetIPAccessRules), ctx, ppfmt, set, targetsByFamily, fallbackNotes)
+	return &MockSetterSetIPAccessRulesCall{Call: call}
+}
+
+// MockSetterSetIPAccessRulesCall wrap *gomock.Call
+type MockSetterSetIPAccessRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetIPAccessRulesCall) Return(arg0 setter.ResponseCode) *MockSetterSetIPAccessRulesCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetIPAccessRulesCall) Do(f func(context.Context, pp.PP, api.IPAccessRuleSet, map[ipnet.Family]setter.WAFTargets, string) setter.ResponseCode) *MockSetterSetIPAccessRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetIPAccessRulesCall) DoAndReturn(f func(context.Context, pp.PP, api.IPAccessRuleSet, map[ipnet.Family]setter.WAFTargets, string) setter.ResponseCode) *MockSetterSetIPAccessRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetIPs mocks base method.
+func (m *MockSetter) SetIPs(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, Domain domain.Domain, IPs []netip.Addr, fallbackParams api.RecordParams) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIPs", ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetIPs indicates an expected call of SetIPs.
+func (mr *MockSetterMockRecorder) SetIPs(ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams any) *MockSetterSetIPsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIPs", reflect.TypeOf((*MockSetter)(nil).SetIPs), ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams)
+	return &MockSetterSetIPsCall{Call: call}
+}
+
+// MockSetterSetIPsCall wrap *gomock.Call
+type MockSetterSetIPsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetIPsCall) Return(arg0 setter.ResponseCode) *MockSetterSetIPsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetIPsCall) Do(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, []netip.Addr, api.RecordParams) setter.ResponseCode) *MockSetterSetIPsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetIPsCall) DoAndReturn(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, []netip.Addr, api.RecordParams) setter.ResponseCode) *MockSetterSetIPsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetLBPoolOrigin mocks base method.
+func (m *MockSetter) SetLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin api.LBPoolOrigin, targetsByFamily map[ipnet.Family][]netip.Addr) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLBPoolOrigin", ctx, ppfmt, origin, targetsByFamily)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetLBPoolOrigin indicates an expected call of SetLBPoolOrigin.
+func (mr *MockSetterMockRecorder) SetLBPoolOrigin(ctx, ppfmt, origin, targetsByFamily any) *MockSetterSetLBPoolOriginCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLBPoolOrigin", reflect.TypeOf((*MockSetter)(nil).SetLBPoolOrigin), ctx, ppfmt, origin, targetsByFamily)
+	return &MockSetterSetLBPoolOriginCall{Call: call}
+}
+
+// MockSetterSetLBPoolOriginCall wrap *gomock.Call
+type MockSetterSetLBPoolOriginCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetLBPoolOriginCall) Return(arg0 setter.ResponseCode) *MockSetterSetLBPoolOriginCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetLBPoolOriginCall) Do(f func(context.Context, pp.PP, api.LBPoolOrigin, map[ipnet.Family][]netip.Addr) setter.ResponseCode) *MockSetterSetLBPoolOriginCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetLBPoolOriginCall) DoAndReturn(f func(context.Context, pp.PP, api.LBPoolOrigin, map[ipnet.Family][]netip.Addr) setter.ResponseCode) *MockSetterSetLBPoolOriginCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetSpectrumApp mocks base method.
+func (m *MockSetter) SetSpectrumApp(ctx context.Context, ppfmt pp.PP, app api.SpectrumApp, targetsByFamily map[ipnet.Family][]netip.Addr) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSpectrumApp", ctx, ppfmt, app, targetsByFamily)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetSpectrumApp indicates an expected call of SetSpectrumApp.
+func (mr *MockSetterMockRecorder) SetSpectrumApp(ctx, ppfmt, app, targetsByFamily any) *MockSetterSetSpectrumAppCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSpectrumApp", reflect.TypeOf((*MockSetter)(nil).SetSpectrumApp), ctx, ppfmt, app, targetsByFamily)
+	return &MockSetterSetSpectrumAppCall{Call: call}
+}
+
+// MockSetterSetSpectrumAppCall wrap *gomock.Call
+type MockSetterSetSpectrumAppCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetSpectrumAppCall) Return(arg0 setter.ResponseCode) *MockSetterSetSpectrumAppCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetSpectrumAppCall) Do(f func(context.Context, pp.PP, api.SpectrumApp, map[ipnet.Family][]netip.Addr) setter.ResponseCode) *MockSetterSetSpectrumAppCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetSpectrumAppCall) DoAndReturn(f func(context.Context, pp.PP, api.SpectrumApp, map[ipnet.Family][]netip.Addr) setter.ResponseCode) *MockSetterSetSpectrumAppCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetWAFList mocks base method.
+func (m *MockSetter) SetWAFList(ctx context.Context, ppfmt pp.PP, list api.WAFList, listDescription string, targetsByFamily map[ipnet.Family]setter.WAFTargets, fallbackItemComment string) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWAFList", ctx, ppfmt, list, listDescription, targetsByFamily, fallbackItemComment)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetWAFList indicates an expected call of SetWAFList.
+func (mr *MockSetterMockRecorder) SetWAFList(ctx, ppfmt, list, listDescription, targetsByFamily, fallbackItemComment any) *MockSetterSetWAFListCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWAFList", reflect.TypeOf((*MockSetter)(nil).SetWAFList), ctx, ppfmt, list, listDescription, targetsByFamily, fallbackItemComment)
+	return &MockSetterSetWAFListCall{Call: call}
+}
+
+// MockSetterSetWAFListCall wrap *gomock.Call
+type MockSetterSetWAFListCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetWAFListCall) Return(arg0 setter.ResponseCode) *MockSetterSetWAFListCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetWAFListCall) Do(f func(context.Context, pp.PP, api.WAFList, string, map[ipnet.Family]setter.WAFTargets, string) setter.ResponseCode) *MockSetterSetWAFListCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetWAFListCall) DoAndReturn(f func(context.Context, pp.PP, api.WAFList, string, map[ipnet.Family]setter.WAFTargets, string) setter.ResponseCode) *MockSetterSetWAFListCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetWAFListRule mocks base method.
+func (m *MockSetter) SetWAFListRule(ctx context.Context, ppfmt pp.PP, rule api.WAFListRule, expression, description string) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWAFListRule", ctx, ppfmt, rule, expression, description)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetWAFListRule indicates an expected call of SetWAFListRule.
+func (mr *MockSetterMockRecorder) SetWAFListRule(ctx, ppfmt, rule, expression, description any) *MockSetterSetWAFListRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWAFListRule", reflect.TypeOf((*MockSetter)(nil).SetWAFListRule), ctx, ppfmt, rule, expression, description)
+	return &MockSetterSetWAFListRuleCall{Call: call}
+}
+
+// MockSetterSetWAFListRuleCall wrap *gomock.Call
+type MockSetterSetWAFListRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetWAFListRuleCall) Return(arg0 setter.ResponseCode) *MockSetterSetWAFListRuleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetWAFListRuleCall) Do(f func(context.Context, pp.PP, api.WAFListRule, string, string) setter.ResponseCode) *MockSetterSetWAFListRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetWAFListRuleCall) DoAndReturn(f func(context.Context, pp.PP, api.WAFListRule, string, string) setter.ResponseCode) *MockSetterSetWAFListRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetWorkersKV mocks base method.
+func (m *MockSetter) SetWorkersKV(ctx context.Context, ppfmt pp.PP, key api.WorkersKVKey, targetsByFamily map[ipnet.Family][]netip.Addr, now time.Time) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWorkersKV", ctx, ppfmt, key, targetsByFamily, now)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetWorkersKV indicates an expected call of SetWorkersKV.
+func (mr *MockSetterMockRecorder) SetWorkersKV(ctx, ppfmt, key, targetsByFamily, now any) *MockSetterSetWorkersKVCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWorkersKV", reflect.TypeOf((*MockSetter)(nil).SetWorkersKV), ctx, ppfmt, key, targetsByFamily, now)
+	return &MockSetterSetWorkersKVCall{Call: call}
+}
+
+// MockSetterSetWorkersKVCall wrap *gomock.Call
+type MockSetterSetWorkersKVCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterSetWorkersKVCall) Return(arg0 setter.ResponseCode) *MockSetterSetWorkersKVCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterSetWorkersKVCall) Do(f func(context.Context, pp.PP, api.WorkersKVKey, map[ipnet.Family][]netip.Addr, time.Time) setter.ResponseCode) *MockSetterSetWorkersKVCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterSetWorkersKVCall) DoAndReturn(f func(context.Context, pp.PP, api.WorkersKVKey, map[ipnet.Family][]netip.Addr, time.Time) setter.ResponseCode) *MockSetterSetWorkersKVCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// TakeChanges mocks base method.
+func (m *MockSetter) TakeChanges() setter.Changes {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeChanges")
+	ret0, _ := ret[0].(setter.Changes)
+	return ret0
+}
+
+// TakeChanges indicates an expected call of TakeChanges.
+func (mr *MockSetterMockRecorder) TakeChanges() *MockSetterTakeChangesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeChanges", reflect.TypeOf((*MockSetter)(nil).TakeChanges))
+	return &MockSetterTakeChangesCall{Call: call}
+}
+
+// MockSetterTakeChangesCall wrap *gomock.Call
+type MockSetterTakeChangesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterTakeChangesCall) Return(arg0 setter.Changes) *MockSetterTakeChangesCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterTakeChangesCall) Do(f func() setter.Changes) *MockSetterTakeChangesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterTakeChangesCall) DoAndReturn(f func() setter.Changes) *MockSetterTakeChangesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// TakePlan mocks base method.
+func (m *MockSetter) TakePlan() []api.PlannedChange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakePlan")
+	ret0, _ := ret[0].([]api.PlannedChange)
+	return ret0
+}
+
+// TakePlan indicates an expected call of TakePlan.
+func (mr *MockSetterMockRecorder) TakePlan() *MockSetterTakePlanCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakePlan", reflect.TypeOf((*MockSetter)(nil).TakePlan))
+	return &MockSetterTakePlanCall{Call: call}
+}
+
+// MockSetterTakePlanCall wrap *gomock.Call
+type MockSetterTakePlanCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSetterTakePlanCall) Return(arg0 []api.PlannedChange) *MockSetterTakePlanCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSetterTakePlanCall) Do(f func() []api.PlannedChange) *MockSetterTakePlanCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSetterTakePlanCall) DoAndReturn(f func() []api.PlannedChange) *MockSetterTakePlanCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockRecordSetter is a mock of RecordSetter interface.
+type MockRecordSetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRecordSetterMockRecorder
+	isgomock struct{}
+}
+
+// MockRecordSetterMockRecorder is the mock recorder for MockRecordSetter.
+type MockRecordSetterMockRecorder struct {
+	mock *MockRecordSetter
+}
+
+// NewMockRecordSetter creates a new mock instance.
+func NewMockRecordSetter(ctrl *gomock.Controller) *MockRecordSetter {
+	mock := &MockRecordSetter{ctrl: ctrl}
+	mock.recorder = &MockRecordSetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRecordSetter) EXPECT() *MockRecordSetterMockRecorder {
+	return m.recorder
+}
+
+// FinalDelete mocks base method.
+func (m *MockRecordSetter) FinalDelete(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, Domain domain.Domain, fallbackParams api.RecordParams) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalDelete", ctx, ppfmt, ipFamily, Domain, fallbackParams)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// FinalDelete indicates an expected call of FinalDelete.
+func (mr *MockRecordSetterMockRecorder) FinalDelete(ctx, ppfmt, ipFamily, Domain, fallbackParams any) *MockRecordSetterFinalDeleteCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalDelete", reflect.TypeOf((*MockRecordSetter)(nil).FinalDelete), ctx, ppfmt, ipFamily, Domain, fallbackParams)
+	return &MockRecordSetterFinalDeleteCall{Call: call}
+}
+
+// MockRecordSetterFinalDeleteCall wrap *gomock.Call
+type MockRecordSetterFinalDeleteCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRecordSetterFinalDeleteCall) Return(arg0 setter.ResponseCode) *MockRecordSetterFinalDeleteCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRecordSetterFinalDeleteCall) Do(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, api.RecordParams) setter.ResponseCode) *MockRecordSetterFinalDeleteCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRecordSetterFinalDeleteCall) DoAndReturn(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, api.RecordParams) setter.ResponseCode) *MockRecordSetterFinalDeleteCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetIPs mocks base method.
+func (m *MockRecordSetter) SetIPs(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, Domain domain.Domain, IPs []netip.Addr, fallbackParams api.RecordParams) setter.ResponseCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIPs", ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams)
+	ret0, _ := ret[0].(setter.ResponseCode)
+	return ret0
+}
+
+// SetIPs indicates an expected call of SetIPs.
+func (mr *MockRecordSetterMockRecorder) SetIPs(ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams any) *MockRecordSetterSetIPsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIPs", reflect.TypeOf((*MockRecordSetter)(nil).SetIPs), ctx, ppfmt, ipFamily, Domain, IPs, fallbackParams)
+	return &MockRecordSetterSetIPsCall{Call: call}
+}
+
+// MockRecordSetterSetIPsCall wrap *gomock.Call
+type MockRecordSetterSetIPsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRecordSetterSetIPsCall) Return(arg0 setter.ResponseCode) *MockRecordSetterSetIPsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRecordSetterSetIPsCall) Do(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, []netip.Addr, api.RecordParams) setter.ResponseCode) *MockRecordSetterSetIPsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRecordSetterSetIPsCall) DoAndReturn(f func(context.Context, pp.PP, ipnet.Family, domain.Domain, []netip.Addr, api.RecordParams) setter.ResponseCode) *MockRecordSetterSetIPsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// TakeChanges mocks base method.
+func (m *MockRecordSetter) TakeChanges() setter.Changes {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeChanges")
+	ret0, _ := ret[0].(setter.Changes)
+	return ret0
+}
+
+// TakeChanges indicates an expected call of TakeChanges.
+func (mr *MockRecordSetterMockRecorder) TakeChanges() *MockRecordSetterTakeChangesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeChanges", reflect.TypeOf((*MockRecordSetter)(nil).TakeChanges))
+	return &MockRecordSetterTakeChangesCall{Call: call}
+}
+
+// MockRecordSetterTakeChangesCall wrap *gomock.Call
+type MockRecordSetterTakeChangesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRecordSetterTakeChangesCall) Return(arg0 setter.Changes) *MockRecordSetterTakeChangesCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRecordSetterTakeChangesCall) Do(f func() setter.Changes) *MockRecordSetterTakeChangesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRecordSetterTakeChangesCall) DoAndReturn(f func() setter.Changes) *MockRecordSetterTakeChangesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// TakePlan mocks base method.
+func (m *MockRecordSetter) TakePlan() []api.PlannedChange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakePlan")
+	ret0, _ := ret[0].([]api.PlannedChange)
+	return ret0
+}
+
+// TakePlan indicates an expected call of TakePlan.
+func (mr *MockRecordSetterMockRecorder) TakePlan() *MockRecordSetterTakePlanCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakePlan", reflect.TypeOf((*MockRecordSetter)(nil).TakePlan))
+	return &MockRecordSetterTakePlanCall{Call: call}
+}
+
+// MockRecordSetterTakePlanCall wrap *gomock.Call
+type MockRecordSetterTakePlanCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRecordSetterTakePlanCall) Return(arg0 []api.PlannedChange) *MockRecordSetterTakePlanCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRecordSetterTakePlanCall) Do(f func() []api.PlannedChange) *MockRecordSetterTakePlanCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRecordSetterTakePlanCall) DoAndReturn(f func() []api.PlannedChange) *MockRecordSetterTakePlanCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
@@ -0,0 +1,56 @@
+// Package nftset keeps named nftables sets equal to the detected prefixes,
+// so that host firewalls can refer to the current networks.
+package nftset
+
+import (
+	"cmp"
+	"context"
+	"strings"
+)
+
+//go:generate go tool mockgen -typed -destination=../mocks/mock_nftset.go -package=mocks . Runner
+
+// Runner runs the nft executable.
+type Runner interface {
+	// Run runs nft with the arguments, feeding stdin to its standard input,
+	// and returns its standard output.
+	Run(ctx context.Context, args []string, stdin []byte) ([]byte, error)
+}
+
+// Set identifies a named nftables set.
+type Set struct {
+	// Family is the address family of the table, such as "inet".
+	Family string
+	// Table is the name of the table holding the set.
+	Table string
+	// Name is the name of the set.
+	Name string
+}
+
+// ParseSet parses a set written as "family table name", as in nft commands.
+func ParseSet(s string) (Set, bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return Set{}, false
+	}
+	switch fields[0] {
+	case "ip", "ip6", "inet", "bridge", "netdev":
+	default:
+		return Set{}, false
+	}
+	return Set{Family: fields[0], Table: fields[1], Name: fields[2]}, true
+}
+
+// Describe gives a human-readable description of the set.
+func (s Set) Describe() string {
+	return s.Family + " " + s.Table + " " + s.Name
+}
+
+// CompareSet compares two sets by their families, tables, and names.
+func CompareSet(s1, s2 Set) int {
+	return cmp.Or(
+		cmp.Compare(s1.Family, s2.Family),
+		cmp.Compare(s1.Table, s2.Table),
+		cmp.Compare(s1.Name, s2.Name),
+	)
+}
@@ -0,0 +1,40 @@
+package nftset_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
+)
+
+func TestParseSet(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input string
+		set   nftset.Set
+		ok    bool
+	}{
+		"inet":           {"inet filter home_prefixes", nftset.Set{Family: "inet", Table: "filter", Name: "home_prefixes"}, true},
+		"spaces":         {"  ip6   fw  prefixes ", nftset.Set{Family: "ip6", Table: "fw", Name: "prefixes"}, true},
+		"missing-family": {"filter home_prefixes", nftset.Set{}, false},
+		"extra":          {"inet filter home_prefixes more", nftset.Set{}, false},
+		"invalid-family": {"arp filter home_prefixes", nftset.Set{}, false},
+		"empty":          {"", nftset.Set{}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			set, ok := nftset.ParseSet(tc.input)
+			require.Equal(t, tc.set, set)
+			require.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "inet filter home_prefixes",
+		nftset.Set{Family: "inet", Table: "filter", Name: "home_prefixes"}.Describe())
+}
@@ -0,0 +1,32 @@
+package nftset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type commandRunner struct{}
+
+// NewCommandRunner creates a [Runner] running the nft executable found in PATH.
+func NewCommandRunner() Runner {
+	return commandRunner{}
+}
+
+// Run implements [Runner]. The error includes what nft printed to its standard error.
+func (commandRunner) Run(ctx context.Context, args []string, stdin []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "nft", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,292 @@
+package nftset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// element is an element of a set as listed by nft.
+type element struct {
+	// raw is the JSON value of the element, used to delete it.
+	raw json.RawMessage
+	// prefix is the prefix of the element. It is invalid for elements that are
+	// not addresses or prefixes (such as ranges), which are never kept.
+	prefix netip.Prefix
+}
+
+// contents is what is relevant about a set as listed by nft.
+type contents struct {
+	ipFamily ipnet.Family
+	interval bool
+	elements []element
+}
+
+// change is the difference between a set and its targets.
+type change struct {
+	add    []netip.Prefix
+	delete []element
+}
+
+// parseElement parses an element listed by nft. Addresses are listed as strings,
+// prefixes as {"prefix": ...}, and elements with extra data as {"elem": {"val": ...}}.
+func parseElement(raw json.RawMessage) element {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if addr, err := netip.ParseAddr(s); err == nil {
+			return element{raw: raw, prefix: netip.PrefixFrom(addr, addr.BitLen())}
+		}
+		return element{raw: raw, prefix: netip.Prefix{}}
+	}
+
+	var obj struct {
+		Prefix *struct {
+			Addr string `json:"addr"`
+			Len  int    `json:"len"`
+		} `json:"prefix"`
+		Elem *struct {
+			Val json.RawMessage `json:"val"`
+		} `json:"elem"`
+	}
+	if json.Unmarshal(raw, &obj) == nil {
+		switch {
+		case obj.Elem != nil && obj.Elem.Val != nil:
+			return parseElement(obj.Elem.Val)
+		case obj.Prefix != nil:
+			if addr, err := netip.ParseAddr(obj.Prefix.Addr); err == nil {
+				if prefix, err := addr.Prefix(obj.Prefix.Len); err == nil && prefix.Addr() == addr {
+					return element{raw: raw, prefix: prefix}
+				}
+			}
+		}
+	}
+	return element{raw: raw, prefix: netip.Prefix{}}
+}
+
+// encodePrefix encodes a prefix in the syntax of nft, as an address when it covers only one address.
+func encodePrefix(prefix netip.Prefix) any {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return map[string]any{"prefix": map[string]any{"addr": prefix.Addr().String(), "len": prefix.Bits()}}
+}
+
+// read lists the set with nft.
+func (s Set) read(ctx context.Context, ppfmt pp.PP, runner Runner) (contents, bool) {
+	output, err := runner.Run(ctx, []string{"-j", "list", "set", s.Family, s.Table, s.Name}, nil)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to read the nftables set %s: %v", s.Describe(), err)
+		ppfmt.NoticeOncef(pp.MessageNFTablesPermission, pp.EmojiHint,
+			"Make sure nft is installed, the set exists, and the updater has the capability CAP_NET_ADMIN")
+		return contents{}, false
+	}
+
+	var listing struct {
+		Nftables []struct {
+			Set *struct {
+				Type  json.RawMessage   `json:"type"`
+				Flags []string          `json:"flags"`
+				Elem  []json.RawMessage `json:"elem"`
+			} `json:"set"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &listing); err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to parse the output of nft for the nftables set %s: %v",
+			s.Describe(), err)
+		return contents{}, false
+	}
+
+	for _, object := range listing.Nftables {
+		if object.Set == nil {
+			continue
+		}
+
+		var c contents
+		var setType string
+		_ = json.Unmarshal(object.Set.Type, &setType)
+		switch setType {
+		case "ipv4_addr":
+			c.ipFamily = ipnet.IP4
+		case "ipv6_addr":
+			c.ipFamily = ipnet.IP6
+		default:
+			ppfmt.Noticef(pp.EmojiUserError,
+				"The nftables set %s has the type %s, but only ipv4_addr and ipv6_addr are supported",
+				s.Describe(), string(object.Set.Type))
+			return contents{}, false
+		}
+		c.interval = slices.Contains(object.Set.Flags, "interval")
+		for _, raw := range object.Set.Elem {
+			c.elements = append(c.elements, parseElement(raw))
+		}
+		return c, true
+	}
+
+	ppfmt.Noticef(pp.EmojiImpossible, "The output of nft does not describe the nftables set %s", s.Describe())
+	return contents{}, false
+}
+
+// diff computes how to make the set hold exactly the targets.
+func (s Set) diff(ppfmt pp.PP, c contents, targets []netip.Prefix) (change, bool) {
+	var ch change
+
+	wanted := make([]netip.Prefix, 0, len(targets))
+	for _, target := range targets {
+		wanted = append(wanted, target.Masked())
+	}
+	slices.SortFunc(wanted, netip.Prefix.Compare)
+	wanted = slices.Compact(wanted)
+
+	for _, prefix := range wanted {
+		if !c.interval && !prefix.IsSingleIP() {
+			ppfmt.Noticef(pp.EmojiUserError, "The nftables set %s needs the interval flag to hold the prefix %s",
+				s.Describe(), prefix.String())
+			return change{}, false
+		}
+	}
+
+	for _, e := range c.elements {
+		if !e.prefix.IsValid() || !slices.Contains(wanted, e.prefix) {
+			ch.delete = append(ch.delete, e)
+		}
+	}
+	for _, prefix := range wanted {
+		if !slices.ContainsFunc(c.elements, func(e element) bool { return e.prefix == prefix }) {
+			ch.add = append(ch.add, prefix)
+		}
+	}
+	return ch, true
+}
+
+// describeElement gives a human-readable description of an element.
+func describeElement(e element) string {
+	if e.prefix.IsValid() {
+		return e.prefix.String()
+	}
+	return string(e.raw)
+}
+
+// apply makes the change in one nft transaction, so that the set is never partially updated.
+func (s Set) apply(ctx context.Context, ppfmt pp.PP, runner Runner, ch change) setter.ResponseCode {
+	target := func(elems []any) map[string]any {
+		return map[string]any{"element": map[string]any{
+			"family": s.Family, "table": s.Table, "name": s.Name, "elem": elems,
+		}}
+	}
+
+	// Delete first, so that the new elements never overlap the old ones.
+	var commands []any
+	if len(ch.delete) > 0 {
+		elems := make([]any, 0, len(ch.delete))
+		for _, e := range ch.delete {
+			elems = append(elems, e.raw)
+		}
+		commands = append(commands, map[string]any{"delete": target(elems)})
+	}
+	if len(ch.add) > 0 {
+		elems := make([]any, 0, len(ch.add))
+		for _, prefix := range ch.add {
+			elems = append(elems, encodePrefix(prefix))
+		}
+		commands = append(commands, map[string]any{"add": target(elems)})
+	}
+
+	input, err := json.Marshal(map[string]any{"nftables": commands})
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the changes to the nftables set %s: %v", s.Describe(), err)
+		return setter.ResponseFailed
+	}
+	if _, err := runner.Run(ctx, []string{"-j", "-f", "-"}, input); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to update the nftables set %s: %v", s.Describe(), err)
+		ppfmt.NoticeOncef(pp.MessageNFTablesPermission, pp.EmojiHint,
+			"Make sure nft is installed, the set exists, and the updater has the capability CAP_NET_ADMIN")
+		return setter.ResponseFailed
+	}
+
+	for _, e := range ch.delete {
+		ppfmt.Noticef(pp.EmojiDeletion, "Deleted %s from the nftables set %s", describeElement(e), s.Describe())
+	}
+	for _, prefix := range ch.add {
+		ppfmt.Noticef(pp.EmojiCreation, "Added %s to the nftables set %s", prefix.String(), s.Describe())
+	}
+	return setter.ResponseUpdated
+}
+
+// targetsOf selects the targets of the family of the set. It returns false
+// when the set should be left alone.
+func (s Set) targetsOf(ppfmt pp.PP, c contents, targetsByFamily map[ipnet.Family]setter.WAFTargets,
+) ([]netip.Prefix, bool) {
+	targets, managed := targetsByFamily[c.ipFamily]
+	switch {
+	case !managed:
+		ppfmt.Noticef(pp.EmojiUserWarning, "The nftables set %s holds %s addresses, which are not managed",
+			s.Describe(), c.ipFamily.Describe())
+		return nil, false
+	case !targets.HasUsableTargets():
+		ppfmt.Infof(pp.EmojiWarning, "The nftables set %s is left alone because no %s prefixes are available",
+			s.Describe(), c.ipFamily.Describe())
+		return nil, false
+	default:
+		return targets.Prefixes, true
+	}
+}
+
+// Sync makes the set hold exactly the target prefixes of its IP family.
+// Sets of unmanaged families, or of families without usable targets, are left alone.
+func (s Set) Sync(ctx context.Context, ppfmt pp.PP, runner Runner,
+	targetsByFamily map[ipnet.Family]setter.WAFTargets,
+) setter.ResponseCode {
+	c, ok := s.read(ctx, ppfmt, runner)
+	if !ok {
+		return setter.ResponseFailed
+	}
+	targets, ok := s.targetsOf(ppfmt, c, targetsByFamily)
+	if !ok {
+		return setter.ResponseNoop
+	}
+	ch, ok := s.diff(ppfmt, c, targets)
+	if !ok {
+		return setter.ResponseFailed
+	}
+	if len(ch.add) == 0 && len(ch.delete) == 0 {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The nftables set %s is already up to date", s.Describe())
+		return setter.ResponseNoop
+	}
+	return s.apply(ctx, ppfmt, runner, ch)
+}
+
+// Plan gives the changes that [Set.Sync] would make, without making them.
+// It returns false if the changes cannot be determined.
+func (s Set) Plan(ctx context.Context, ppfmt pp.PP, runner Runner,
+	targetsByFamily map[ipnet.Family]setter.WAFTargets,
+) ([]api.PlannedChange, bool) {
+	c, ok := s.read(ctx, ppfmt, runner)
+	if !ok {
+		return nil, false
+	}
+	targets, ok := s.targetsOf(ppfmt, c, targetsByFamily)
+	if !ok {
+		return nil, true
+	}
+	ch, ok := s.diff(ppfmt, c, targets)
+	if !ok {
+		return nil, false
+	}
+
+	subject := "the nftables set " + s.Describe()
+	plan := make([]api.PlannedChange, 0, len(ch.delete)+len(ch.add))
+	for _, e := range ch.delete {
+		plan = append(plan, api.PlannedChange{Subject: subject, Action: fmt.Sprintf("delete %s", describeElement(e))})
+	}
+	for _, prefix := range ch.add {
+		plan = append(plan, api.PlannedChange{Subject: subject, Action: fmt.Sprintf("add %s", prefix.String())})
+	}
+	return plan, true
+}
@@ -0,0 +1,228 @@
+package nftset_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// vim: nowrap
+
+var homePrefixes = nftset.Set{Family: "inet", Table: "filter", Name: "home_prefixes"} //nolint:gochecknoglobals
+
+var listArgs = []string{"-j", "list", "set", "inet", "filter", "home_prefixes"} //nolint:gochecknoglobals
+
+var applyArgs = []string{"-j", "-f", "-"} //nolint:gochecknoglobals
+
+func listing(setType, flags, elems string) []byte {
+	return []byte(`{"nftables": [{"metainfo": {"json_schema_version": 1}}, {"set": {"family": "inet", "name": "home_prefixes", "table": "filter", "type": ` + setType + `, "handle": 3` + flags + elems + `}}]}`)
+}
+
+func ip6Targets(prefixes ...string) map[ipnet.Family]setter.WAFTargets {
+	ps := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		ps = append(ps, netip.MustParsePrefix(p))
+	}
+	return map[ipnet.Family]setter.WAFTargets{
+		ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{netip.MustParsePrefix("192.0.2.1/32")}),
+		ipnet.IP6: setter.NewAvailableWAFTargets(ps),
+	}
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	const interval = `, "flags": ["interval"]`
+	errNFT := errors.New("exit status 1: Error: No such file or directory")
+
+	for name, tc := range map[string]struct {
+		targets      map[ipnet.Family]setter.WAFTargets
+		listOutput   []byte
+		listErr      error
+		applyInput   string
+		applyErr     error
+		resp         setter.ResponseCode
+		prepareMocks func(*mocks.MockPP)
+	}{
+		"empty": {
+			ip6Targets("2001:db8:1:2::/56"),
+			listing(`"ipv6_addr"`, interval, ""), nil,
+			`{"nftables":[{"add":{"element":{"elem":[{"prefix":{"addr":"2001:db8:1::","len":56}}],"family":"inet","name":"home_prefixes","table":"filter"}}}]}`, nil,
+			setter.ResponseUpdated,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiCreation, "Added %s to the nftables set %s", "2001:db8:1::/56", "inet filter home_prefixes")
+			},
+		},
+		"replace": {
+			ip6Targets("2001:db8:2::/56", "2001:db8::1/128"),
+			listing(`"ipv6_addr"`, interval, `, "elem": [{"prefix": {"addr": "2001:db8:1::", "len": 56}}, "2001:db8::1", {"range": ["2001:db8:3::", "2001:db8:3::5"]}]`), nil,
+			`{"nftables":[{"delete":{"element":{"elem":[{"prefix":{"addr":"2001:db8:1::","len":56}},{"range":["2001:db8:3::","2001:db8:3::5"]}],"family":"inet","name":"home_prefixes","table":"filter"}}},{"add":{"element":{"elem":[{"prefix":{"addr":"2001:db8:2::","len":56}}],"family":"inet","name":"home_prefixes","table":"filter"}}}]}`, nil,
+			setter.ResponseUpdated,
+			func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().Noticef(pp.EmojiDeletion, "Deleted %s from the nftables set %s", "2001:db8:1::/56", "inet filter home_prefixes"),
+					m.EXPECT().Noticef(pp.EmojiDeletion, "Deleted %s from the nftables set %s", `{"range": ["2001:db8:3::", "2001:db8:3::5"]}`, "inet filter home_prefixes"),
+					m.EXPECT().Noticef(pp.EmojiCreation, "Added %s to the nftables set %s", "2001:db8:2::/56", "inet filter home_prefixes"),
+				)
+			},
+		},
+		"clear": {
+			map[ipnet.Family]setter.WAFTargets{ipnet.IP6: setter.NewAvailableWAFTargets(nil)},
+			listing(`"ipv6_addr"`, interval, `, "elem": ["2001:db8::1"]`), nil,
+			`{"nftables":[{"delete":{"element":{"elem":["2001:db8::1"],"family":"inet","name":"home_prefixes","table":"filter"}}}]}`, nil,
+			setter.ResponseUpdated,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiDeletion, "Deleted %s from the nftables set %s", "2001:db8::1/128", "inet filter home_prefixes")
+			},
+		},
+		"up-to-date": {
+			ip6Targets("2001:db8:1::/56"),
+			listing(`"ipv6_addr"`, interval, `, "elem": [{"elem": {"val": {"prefix": {"addr": "2001:db8:1::", "len": 56}}, "timeout": 3600}}]`), nil,
+			"", nil,
+			setter.ResponseNoop,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiAlreadyDone, "The nftables set %s is already up to date", "inet filter home_prefixes")
+			},
+		},
+		"ip4": {
+			ip6Targets(),
+			listing(`"ipv4_addr"`, "", `, "elem": ["192.0.2.1"]`), nil,
+			"", nil,
+			setter.ResponseNoop,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiAlreadyDone, "The nftables set %s is already up to date", "inet filter home_prefixes")
+			},
+		},
+		"unmanaged": {
+			map[ipnet.Family]setter.WAFTargets{},
+			listing(`"ipv6_addr"`, interval, ""), nil,
+			"", nil,
+			setter.ResponseNoop,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserWarning, "The nftables set %s holds %s addresses, which are not managed", "inet filter home_prefixes", "IPv6")
+			},
+		},
+		"unavailable": {
+			map[ipnet.Family]setter.WAFTargets{ipnet.IP6: setter.NewUnavailableWAFTargets()},
+			listing(`"ipv6_addr"`, interval, ""), nil,
+			"", nil,
+			setter.ResponseNoop,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Infof(pp.EmojiWarning, "The nftables set %s is left alone because no %s prefixes are available", "inet filter home_prefixes", "IPv6")
+			},
+		},
+		"no-interval": {
+			ip6Targets("2001:db8:1::/56"),
+			listing(`"ipv6_addr"`, "", ""), nil,
+			"", nil,
+			setter.ResponseFailed,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The nftables set %s needs the interval flag to hold the prefix %s", "inet filter home_prefixes", "2001:db8:1::/56")
+			},
+		},
+		"unsupported-type": {
+			ip6Targets("2001:db8:1::/56"),
+			listing(`["ipv6_addr", "inet_service"]`, "", ""), nil,
+			"", nil,
+			setter.ResponseFailed,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiUserError, "The nftables set %s has the type %s, but only ipv4_addr and ipv6_addr are supported", "inet filter home_prefixes", `["ipv6_addr", "inet_service"]`)
+			},
+		},
+		"list-fails": {
+			ip6Targets("2001:db8:1::/56"),
+			nil, errNFT,
+			"", nil,
+			setter.ResponseFailed,
+			func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().Noticef(pp.EmojiError, "Failed to read the nftables set %s: %v", "inet filter home_prefixes", errNFT),
+					m.EXPECT().NoticeOncef(pp.MessageNFTablesPermission, pp.EmojiHint, "Make sure nft is installed, the set exists, and the updater has the capability CAP_NET_ADMIN"),
+				)
+			},
+		},
+		"list-garbage": {
+			ip6Targets("2001:db8:1::/56"),
+			[]byte("not json"), nil,
+			"", nil,
+			setter.ResponseFailed,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiImpossible, "Failed to parse the output of nft for the nftables set %s: %v", "inet filter home_prefixes", gomock.Any())
+			},
+		},
+		"list-no-set": {
+			ip6Targets("2001:db8:1::/56"),
+			[]byte(`{"nftables": [{"metainfo": {}}]}`), nil,
+			"", nil,
+			setter.ResponseFailed,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiImpossible, "The output of nft does not describe the nftables set %s", "inet filter home_prefixes")
+			},
+		},
+		"apply-fails": {
+			ip6Targets("2001:db8:1::/56"),
+			listing(`"ipv6_addr"`, interval, ""), nil,
+			`{"nftables":[{"add":{"element":{"elem":[{"prefix":{"addr":"2001:db8:1::","len":56}}],"family":"inet","name":"home_prefixes","table":"filter"}}}]}`, errNFT,
+			setter.ResponseFailed,
+			func(m *mocks.MockPP) {
+				gomock.InOrder(
+					m.EXPECT().Noticef(pp.EmojiError, "Failed to update the nftables set %s: %v", "inet filter home_prefixes", errNFT),
+					m.EXPECT().NoticeOncef(pp.MessageNFTablesPermission, pp.EmojiHint, "Make sure nft is installed, the set exists, and the updater has the capability CAP_NET_ADMIN"),
+				)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			mockRunner := mocks.NewMockRunner(mockCtrl)
+			ctx := context.Background()
+
+			mockRunner.EXPECT().Run(ctx, listArgs, nil).Return(tc.listOutput, tc.listErr)
+			if tc.applyInput != "" {
+				mockRunner.EXPECT().Run(ctx, applyArgs, []byte(tc.applyInput)).Return(nil, tc.applyErr)
+			}
+			if tc.prepareMocks != nil {
+				tc.prepareMocks(mockPP)
+			}
+			require.Equal(t, tc.resp, homePrefixes.Sync(ctx, mockPP, mockRunner, tc.targets))
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockRunner := mocks.NewMockRunner(mockCtrl)
+	ctx := context.Background()
+
+	mockRunner.EXPECT().Run(ctx, listArgs, nil).
+		Return(listing(`"ipv6_addr"`, `, "flags": ["interval"]`, `, "elem": ["2001:db8::1"]`), nil)
+	plan, ok := homePrefixes.Plan(ctx, mockPP, mockRunner, ip6Targets("2001:db8:1::/56"))
+	require.True(t, ok)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "the nftables set inet filter home_prefixes", Action: "delete 2001:db8::1/128"},
+		{Subject: "the nftables set inet filter home_prefixes", Action: "add 2001:db8:1::/56"},
+	}, plan)
+
+	mockRunner.EXPECT().Run(ctx, listArgs, nil).Return(nil, errors.New("oops"))
+	mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to read the nftables set %s: %v", "inet filter home_prefixes", gomock.Any())
+	mockPP.EXPECT().NoticeOncef(pp.MessageNFTablesPermission, pp.EmojiHint, gomock.Any())
+	plan, ok = homePrefixes.Plan(ctx, mockPP, mockRunner, ip6Targets("2001:db8:1::/56"))
+	require.False(t, ok)
+	require.Nil(t, plan)
+}
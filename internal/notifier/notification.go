@@ -10,11 +10,15 @@ type Kind string
 const (
 	KindStartup           Kind = "startup"
 	KindStartupFailure    Kind = "startup failure"
+	KindPermission        Kind = "permission check"
+	KindPermissionFailure Kind = "permission check failure"
 	KindUpdate            Kind = "update"
 	KindUpdateFailure     Kind = "update failure"
 	KindSchedulingFailure Kind = "scheduling failure"
 	KindCleanup           Kind = "cleanup"
 	KindCleanupFailure    Kind = "cleanup failure"
+	KindReload            Kind = "reload"
+	KindReloadFailure     Kind = "reload failure"
 	KindShutdown          Kind = "shutdown"
 )
 
@@ -46,6 +50,10 @@ func (n Notification) description(ppfmt pp.PP) string {
 		return "a startup notification"
 	case KindStartupFailure:
 		return "a startup failure notification"
+	case KindPermission:
+		return "a permission check notification"
+	case KindPermissionFailure:
+		return "a permission check failure notification"
 	case KindUpdate:
 		return "an update notification"
 	case KindUpdateFailure:
@@ -56,6 +64,10 @@ func (n Notification) description(ppfmt pp.PP) string {
 		return "a cleanup notification"
 	case KindCleanupFailure:
 		return "a cleanup failure notification"
+	case KindReload:
+		return "a reload notification"
+	case KindReloadFailure:
+		return "a reload failure notification"
 	case KindShutdown:
 		return "a shutdown notification"
 	default:
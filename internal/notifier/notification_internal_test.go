@@ -18,11 +18,15 @@ func TestNotificationDescription(t *testing.T) {
 	}{
 		"startup":            {KindStartup, "a startup notification"},
 		"startup failure":    {KindStartupFailure, "a startup failure notification"},
+		"permission check":   {KindPermission, "a permission check notification"},
+		"permission failure": {KindPermissionFailure, "a permission check failure notification"},
 		"update":             {KindUpdate, "an update notification"},
 		"update failure":     {KindUpdateFailure, "an update failure notification"},
 		"scheduling failure": {KindSchedulingFailure, "a scheduling failure notification"},
 		"cleanup":            {KindCleanup, "a cleanup notification"},
 		"cleanup failure":    {KindCleanupFailure, "a cleanup failure notification"},
+		"reload":             {KindReload, "a reload notification"},
+		"reload failure":     {KindReloadFailure, "a reload failure notification"},
 		"shutdown":           {KindShutdown, "a shutdown notification"},
 	} {
 		t.Run(name, func(t *testing.T) {
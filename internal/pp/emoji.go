@@ -21,9 +21,11 @@ const (
 	EmojiDeletion Emoji = "💀" // deleting DNS records
 	EmojiUpdate   Emoji = "📡" // updating DNS records
 	EmojiClear    Emoji = "🧹" // clearing DNS records when exiting
+	EmojiDryRun   Emoji = "📝" // planning changes without making them
 
-	EmojiPing   Emoji = "🔔" // pinging and health checks
-	EmojiNotify Emoji = "📣" // notifications
+	EmojiPing       Emoji = "🔔" // pinging and health checks
+	EmojiNotify     Emoji = "📣" // notifications
+	EmojiPermission Emoji = "🔑" // checking credentials and permissions
 
 	EmojiTimeout     Emoji = "⌛" // Timeout or abortion
 	EmojiSignal      Emoji = "🚨" // catching signals
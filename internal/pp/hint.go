@@ -26,4 +26,81 @@ const (
 	MessageUndocumentedDebugUnavailableProvider           // Undocumented debug provider
 	MessageHostID6MACPrefix                               // mac(...) host IDs need a /64 prefix
 	MessageHostID6WAFItemsPreserved                       // Host-ID incompatibility preserved IPv6 WAF list items
+	MessageRFC2136Permission                              // TSIG keys of RFC 2136 servers
+	MessageLocalResolverReload                            // Failed reload commands of local resolvers
+	MessageNFTablesPermission                             // Running nft with enough privileges
+	MessageExperimentalDynDNS2Server                      // Addresses pushed by DynDNS2 clients
+
+	// messageFeatures is the first of the IDs of the [Feature] messages;
+	// see [MessageExperimental] and [MessagePermission].
+	messageFeatures
+)
+
+// A Feature is an experimental feature introduced in version 1.18.0. Each one
+// has its own notice that it is experimental, and the ones that manage
+// Cloudflare resources also have their own hint about the API token.
+type Feature int
+
+// All the features sharing the parameterized messages.
+const (
+	FeatureLBPoolOrigins    Feature = iota // Load balancer pool origins
+	FeatureGatewayLocations                // Zero Trust Gateway DNS locations
+	FeatureAccessGroups                    // Access group IP rules
+	FeatureIPAccessRules                   // IP Access Rules
+	FeatureSpectrumApps                    // Spectrum application origins
+	FeatureWAFListRule                     // WAF custom rule referencing the managed lists
+	FeatureWorkersKV                       // Publishing the addresses to Workers KV
+	FeatureRFC2136                         // Mirroring DNS records to RFC 2136 servers
+	FeatureLocalResolver                   // Writing local resolver fragments
+	FeatureNFTablesSets                    // Synchronizing nftables sets
 )
+
+// describe returns the name of the feature used in the messages.
+func (f Feature) describe() string {
+	switch f {
+	case FeatureLBPoolOrigins:
+		return "load balancer pool origin"
+	case FeatureGatewayLocations:
+		return "Gateway location"
+	case FeatureAccessGroups:
+		return "Access group"
+	case FeatureIPAccessRules:
+		return "IP access rule"
+	case FeatureSpectrumApps:
+		return "Spectrum app"
+	case FeatureWAFListRule:
+		return "WAF custom rule"
+	case FeatureWorkersKV:
+		return "Workers KV"
+	case FeatureRFC2136:
+		return "RFC 2136 mirroring"
+	case FeatureLocalResolver:
+		return "local resolver"
+	case FeatureNFTablesSets:
+		return "nftables set"
+	default:
+		return "unknown"
+	}
+}
+
+// MessageExperimental is the ID of the notice that a feature is experimental.
+func MessageExperimental(f Feature) ID { return messageFeatures + 2*ID(f) }
+
+// MessagePermission is the ID of the hint about the permissions that a feature
+// needs from the API token.
+func MessagePermission(f Feature) ID { return messageFeatures + 2*ID(f) + 1 }
+
+// InfoExperimental prints, at most once, that a feature is experimental.
+func InfoExperimental(ppfmt PP, f Feature) {
+	ppfmt.InfoOncef(MessageExperimental(f), EmojiExperimental,
+		"You are using the experimental %s feature available since version 1.18.0", f.describe())
+}
+
+// HintEditPermission prints, at most once, which "Edit" permission the API
+// token needs for a feature. The IDs are the other settings to double-check,
+// such as "account ID".
+func HintEditPermission(ppfmt PP, f Feature, permission string, ids ...string) {
+	ppfmt.NoticeOncef(MessagePermission(f), EmojiHint,
+		`Double-check your %s. Make sure you granted the "Edit" permission of "%s"`,
+		EnglishJoinOrEmptyLabel(append([]string{"API token"}, ids...), ""), permission)
+}
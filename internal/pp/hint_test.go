@@ -0,0 +1,34 @@
+package pp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func TestFeatureMessages(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	fmt := pp.New(&buf, false, pp.Info)
+
+	pp.InfoExperimental(fmt, pp.FeatureLBPoolOrigins)
+	pp.InfoExperimental(fmt, pp.FeatureLBPoolOrigins)
+	pp.InfoExperimental(fmt, pp.FeatureWorkersKV)
+	pp.HintEditPermission(fmt, pp.FeatureLBPoolOrigins, "Account - Load Balancing: Monitors and Pools", "account ID")
+	pp.HintEditPermission(fmt, pp.FeatureLBPoolOrigins, "Account - Load Balancing: Monitors and Pools", "account ID")
+	pp.HintEditPermission(fmt, pp.FeatureSpectrumApps, "Zone - Zone Settings", "zone ID", "application ID")
+
+	require.Equal(t, strings.Join([]string{
+		"You are using the experimental load balancer pool origin feature available since version 1.18.0",
+		"You are using the experimental Workers KV feature available since version 1.18.0",
+		`Double-check your API token and account ID. ` +
+			`Make sure you granted the "Edit" permission of "Account - Load Balancing: Monitors and Pools"`,
+		`Double-check your API token, zone ID, and application ID. ` +
+			`Make sure you granted the "Edit" permission of "Zone - Zone Settings"`,
+		"",
+	}, "\n"), buf.String())
+}
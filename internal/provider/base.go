@@ -41,6 +41,11 @@ func NewUnavailableDetectionResult() DetectionResult {
 	return protocol.NewUnavailableDetectionResult()
 }
 
+// NewPendingDetectionResult builds the managed state of raw data that is not known yet.
+func NewPendingDetectionResult() DetectionResult {
+	return protocol.NewPendingDetectionResult()
+}
+
 // Provider is the abstraction of a protocol to detect public IP addresses.
 type Provider interface {
 	Name() string
@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/provider/protocol"
+)
+
+// NewDynDNS2Server creates a [protocol.Pushed] provider that uses the addresses
+// pushed to the DynDNS2 server at DYNDNS2_ADDR. The provider has no addresses
+// until it is bound to the store of its profile with [WithPushedStore].
+func NewDynDNS2Server() Provider {
+	return protocol.Pushed{ProviderName: "dyndns2-server", Store: nil}
+}
+
+// WithPushedStore binds a provider created by [NewDynDNS2Server] to the store
+// of its profile. Other providers are returned unchanged.
+func WithPushedStore(p Provider, store *dyndns2.Store) Provider {
+	if pushed, ok := p.(protocol.Pushed); ok {
+		pushed.Store = store
+		return pushed
+	}
+	return p
+}
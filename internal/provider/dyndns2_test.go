@@ -0,0 +1,34 @@
+package provider_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/provider/protocol"
+)
+
+func TestDynDNS2ServerName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "dyndns2-server", provider.Name(provider.NewDynDNS2Server()))
+}
+
+func TestDynDNS2ServerIsExplicitEmpty(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, provider.NewDynDNS2Server().IsExplicitEmpty())
+}
+
+func TestWithPushedStore(t *testing.T) {
+	t.Parallel()
+
+	store := dyndns2.NewStore()
+	bound := provider.WithPushedStore(provider.NewDynDNS2Server(), store)
+	require.Equal(t, protocol.Pushed{ProviderName: "dyndns2-server", Store: store}, bound)
+
+	other := provider.NewStaticEmpty()
+	require.Equal(t, other, provider.WithPushedStore(other, store))
+}
@@ -25,16 +25,26 @@ type DetectionResult struct {
 	// An empty list is the explicit-empty intent ("clear").
 	Available  bool
 	RawEntries []ipnet.RawEntry
+
+	// Pending reports that the raw data is unavailable only because it is not
+	// known yet, as before the first push to the DynDNS2 server. Available is
+	// then false, but the family is skipped without reporting a failure.
+	Pending bool
 }
 
 // NewKnownDetectionResult builds the managed deterministic raw-data state.
 func NewKnownDetectionResult(rawEntries []ipnet.RawEntry) DetectionResult {
-	return DetectionResult{Available: true, RawEntries: rawEntries}
+	return DetectionResult{Available: true, RawEntries: rawEntries, Pending: false}
 }
 
 // NewUnavailableDetectionResult builds the managed temporary-unavailability state.
 func NewUnavailableDetectionResult() DetectionResult {
-	return DetectionResult{Available: false, RawEntries: nil}
+	return DetectionResult{Available: false, RawEntries: nil, Pending: false}
+}
+
+// NewPendingDetectionResult builds the managed state of raw data that is not known yet.
+func NewPendingDetectionResult() DetectionResult {
+	return DetectionResult{Available: false, RawEntries: nil, Pending: true}
 }
 
 // HasUsableRawData reports whether downstream derivation and reconciliation may proceed.
@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"context"
+
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// Pushed uses the addresses that a DynDNS2 client pushed to the updater.
+type Pushed struct {
+	// ProviderName is the name of the detection protocol.
+	ProviderName string
+
+	// Store holds the addresses pushed for the profile. It is nil until the
+	// provider is bound to a profile by [provider.WithPushedStore].
+	Store *dyndns2.Store
+}
+
+// Name of the detection protocol.
+func (p Pushed) Name() string {
+	return p.ProviderName
+}
+
+// IsExplicitEmpty reports whether the provider intentionally clears the family.
+// The pushed addresses may change between cycles.
+func (p Pushed) IsExplicitEmpty() bool {
+	return false
+}
+
+// GetRawData returns the addresses pushed last for the requested family.
+// Before any push, the raw data is pending, and nothing is reported.
+func (p Pushed) GetRawData(
+	_ context.Context, ppfmt pp.PP, ipFamily ipnet.Family, defaultPrefixLen int,
+) DetectionResult {
+	ips, ok := p.Store.Get(ipFamily)
+	if !ok {
+		return NewPendingDetectionResult()
+	}
+
+	entries, ok := NormalizeDetectedRawIPs(ppfmt, ipFamily, defaultPrefixLen, ips)
+	if !ok {
+		return NewUnavailableDetectionResult()
+	}
+	return NewKnownDetectionResult(entries)
+}
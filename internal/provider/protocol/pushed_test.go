@@ -0,0 +1,79 @@
+package protocol_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/dyndns2"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider/protocol"
+)
+
+func TestPushedGetRawData(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		pushed        []string
+		ok            bool
+		expected      []ipnet.RawEntry
+		prepareMockPP func(*mocks.MockPP)
+	}{
+		"none": {nil, false, nil, nil},
+		"pushed": {
+			[]string{"203.0.113.2", "203.0.113.1"}, true,
+			[]ipnet.RawEntry{
+				ipnet.RawEntryFrom(netip.MustParseAddr("203.0.113.1"), 32),
+				ipnet.RawEntryFrom(netip.MustParseAddr("203.0.113.2"), 32),
+			},
+			nil,
+		},
+		"invalid": {
+			[]string{"0.0.0.0"}, false, nil,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiError, "Detected IP address %s %s", "0.0.0.0", gomock.Any())
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := dyndns2.NewStore()
+			if tc.pushed != nil {
+				ips := make([]netip.Addr, 0, len(tc.pushed))
+				for _, ip := range tc.pushed {
+					ips = append(ips, netip.MustParseAddr(ip))
+				}
+				store.Set(ipnet.IP4, ips)
+			}
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			p := protocol.Pushed{ProviderName: "dyndns2-server", Store: store}
+			require.Equal(t, "dyndns2-server", p.Name())
+			require.False(t, p.IsExplicitEmpty())
+			result := p.GetRawData(context.Background(), mockPP, ipnet.IP4, 32)
+			require.Equal(t, tc.ok, result.Available)
+			require.Equal(t, tc.pushed == nil, result.Pending)
+			require.Equal(t, tc.expected, result.RawEntries)
+		})
+	}
+}
+
+func TestPushedGetRawDataWithoutStore(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	p := protocol.Pushed{ProviderName: "dyndns2-server", Store: nil}
+	result := p.GetRawData(context.Background(), mocks.NewMockPP(mockCtrl), ipnet.IP6, 64)
+	require.Equal(t, protocol.NewPendingDetectionResult(), result)
+}
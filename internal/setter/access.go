@@ -0,0 +1,48 @@
+package setter
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// accessGroup describes the "ip" include rules of an Access group for [setter.reconcilePrefixes].
+func (s setter) accessGroup(group api.AccessGroup) prefixResource {
+	return prefixResource{
+		kind:        "Access group",
+		name:        group.Describe(),
+		rangesLabel: "IP rules",
+		list: func(ctx context.Context, ppfmt pp.PP) ([]netip.Prefix, bool) {
+			return s.Handle.ListAccessGroupIPRules(ctx, ppfmt, group)
+		},
+		set: func(ctx context.Context, ppfmt pp.PP, prefixes []netip.Prefix) bool {
+			return s.Handle.SetAccessGroupIPRules(ctx, ppfmt, group, prefixes)
+		},
+		record: func(previous, current []netip.Prefix) {
+			s.journal.setAccessGroup(group, AccessGroupChanges{Previous: previous, Current: current})
+		},
+	}
+}
+
+// SetAccessGroup replaces the "ip" include rules of an Access group in the
+// managed families with the target prefixes. Rules of the other families are kept.
+func (s setter) SetAccessGroup(ctx context.Context, ppfmt pp.PP,
+	group api.AccessGroup, targetsByFamily map[ipnet.Family]WAFTargets,
+) ResponseCode {
+	return s.reconcilePrefixes(ctx, ppfmt, s.accessGroup(group), func(current []netip.Prefix) []netip.Prefix {
+		return replacePrefixesOfFamilies(current, targetsByFamily)
+	})
+}
+
+// FinalClearAccessGroup removes the "ip" include rules of the managed families
+// from an Access group during shutdown.
+func (s setter) FinalClearAccessGroup(ctx context.Context, ppfmt pp.PP,
+	group api.AccessGroup, managedFamilies map[ipnet.Family]bool,
+) ResponseCode {
+	return s.reconcilePrefixes(ctx, ppfmt, s.accessGroup(group), func(current []netip.Prefix) []netip.Prefix {
+		return keepPrefixesOfOtherFamilies(current, managedFamilies)
+	})
+}
@@ -0,0 +1,61 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// The reconciliation itself is tested with reconcilePrefixes; these tests only
+// check that the "ip" include rules of the group are read, written, and recorded.
+
+func TestSetAccessGroup(t *testing.T) {
+	t.Parallel()
+
+	group := api.AccessGroup{AccountID: "account", Name: "office"}
+	prefix4a := netip.MustParsePrefix("192.0.2.1/32")
+	prefix4b := netip.MustParsePrefix("198.51.100.0/24")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+
+	ctx, h := newSetterHarness(t)
+	h.prepare(ctx, func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+		m.EXPECT().ListAccessGroupIPRules(ctx, p, group).Return([]netip.Prefix{prefix6, prefix4a}, true)
+		m.EXPECT().SetAccessGroupIPRules(ctx, p, group, []netip.Prefix{prefix4b, prefix6}).Return(true)
+		p.EXPECT().Noticef(pp.EmojiUpdate, "Set the %s of the %s %s to %s", "IP rules", "Access group", "account/office", "198.51.100.0/24 and 2001:db8::/64")
+	})
+
+	resp := h.setter.SetAccessGroup(ctx, h.mockPP, group,
+		map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{prefix4b})})
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t,
+		setter.AccessGroupChanges{Previous: []netip.Prefix{prefix4a, prefix6}, Current: []netip.Prefix{prefix4b, prefix6}},
+		h.setter.TakeChanges().AccessGroups[group])
+}
+
+func TestFinalClearAccessGroup(t *testing.T) {
+	t.Parallel()
+
+	group := api.AccessGroup{AccountID: "account", Name: "office"}
+	prefix4 := netip.MustParsePrefix("192.0.2.1/32")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+
+	ctx, h := newSetterHarness(t)
+	h.prepare(ctx, func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+		m.EXPECT().ListAccessGroupIPRules(ctx, p, group).Return([]netip.Prefix{prefix4, prefix6}, true)
+		m.EXPECT().SetAccessGroupIPRules(ctx, p, group, []netip.Prefix{prefix6}).Return(true)
+		p.EXPECT().Noticef(pp.EmojiUpdate, "Set the %s of the %s %s to %s", "IP rules", "Access group", "account/office", "2001:db8::/64")
+	})
+
+	resp := h.setter.FinalClearAccessGroup(ctx, h.mockPP, group, map[ipnet.Family]bool{ipnet.IP4: true})
+	require.Equal(t, setter.ResponseUpdated, resp)
+}
@@ -9,6 +9,7 @@ package setter
 import (
 	"context"
 	"net/netip"
+	"time"
 
 	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/domain"
@@ -16,7 +17,7 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
-//go:generate go tool mockgen -typed -destination=../mocks/mock_setter.go -package=mocks . Setter
+//go:generate go tool mockgen -typed -destination=../mocks/mock_setter.go -package=mocks . Setter,RecordSetter
 
 // WAFTargets carries one managed family's derived WAF target prefixes for a run.
 //
@@ -44,6 +45,59 @@ func (t WAFTargets) HasUsableTargets() bool {
 
 // Setter uses [api.Handle] to reconcile DNS records and WAF lists.
 type Setter interface {
+	RecordSetter
+	LBPoolSetter
+	GatewayLocationSetter
+	AccessGroupSetter
+	IPAccessRuleSetter
+	SpectrumAppSetter
+	WAFListRuleSetter
+	WorkersKVSetter
+
+	// SetWAFList reconciles one WAF list against family target states.
+	//
+	// Contract for targetsByFamily:
+	// - map presence means the family is managed/in scope for this run
+	// - map absence means the family is out of scope and existing managed content
+	//   of that family must be preserved
+	// - present + Available=false preserves existing managed content because the
+	//   desired targets are unavailable for this run
+	// - present + Available=true with an empty target list is the explicit-empty
+	//   intent for that family
+	// - present + Available=true with a non-empty target list carries a deterministic
+	//   target set (sorted, deduplicated)
+	// - when the list is missing, preserve/unavailable/explicit-empty families do
+	//   not force structural creation by themselves
+	SetWAFList(
+		ctx context.Context,
+		ppfmt pp.PP,
+		list api.WAFList,
+		listDescription string,
+		targetsByFamily map[ipnet.Family]WAFTargets,
+		fallbackItemComment string,
+	) ResponseCode
+
+	// FinalClearWAFList removes managed WAF content during shutdown in managed family scope.
+	FinalClearWAFList(
+		ctx context.Context,
+		ppfmt pp.PP,
+		list api.WAFList,
+		listDescription string,
+		managedFamilies map[ipnet.Family]bool,
+	) ResponseCode
+
+	// CheckPermissions checks, before any update, whether the credentials can
+	// manage the given domains and WAF lists. It never changes remote state.
+	CheckPermissions(
+		ctx context.Context,
+		ppfmt pp.PP,
+		domains []domain.Domain,
+		lists []api.WAFList,
+	) api.PermissionReport
+}
+
+// A RecordSetter reconciles DNS records with one DNS authority.
+type RecordSetter interface {
 	// SetIPs sets a particular domain to the given IP addresses.
 	//
 	// Invariant: IPs must already be canonical and represent a deterministic set:
@@ -67,35 +121,169 @@ type Setter interface {
 		fallbackParams api.RecordParams,
 	) ResponseCode
 
-	// SetWAFList reconciles one WAF list against family target states.
+	// TakePlan returns the changes recorded since the last call when the
+	// handle is an [api.Planner], and nil otherwise.
+	TakePlan() []api.PlannedChange
+
+	// TakeChanges returns the changes that the reconciliation made to each
+	// domain, IP family, and other resource since the last call.
+	TakeChanges() Changes
+}
+
+// An LBPoolSetter manages load balancer pool origins.
+type LBPoolSetter interface {
+	// SetLBPoolOrigin points one load balancer pool origin to a target address.
 	//
-	// Contract for targetsByFamily:
-	// - map presence means the family is managed/in scope for this run
-	// - map absence means the family is out of scope and existing managed content
-	//   of that family must be preserved
-	// - present + Available=false preserves existing managed content because the
-	//   desired targets are unavailable for this run
-	// - present + Available=true with an empty target list is the explicit-empty
-	//   intent for that family
-	// - present + Available=true with a non-empty target list carries a deterministic
-	//   target set (sorted, deduplicated)
-	// - when the list is missing, preserve/unavailable/explicit-empty families do
-	//   not force structural creation by themselves
-	SetWAFList(
+	// The IP family of the current address of the origin selects the targets:
+	// - map absence means the family is unavailable for this run and the origin is kept
+	// - an empty target list is the explicit-empty intent and disables the origin
+	// - a non-empty target list (sorted, deduplicated) sets the address to the first
+	//   target and enables the origin, unless the address is already one of the targets
+	SetLBPoolOrigin(
 		ctx context.Context,
 		ppfmt pp.PP,
-		list api.WAFList,
-		listDescription string,
+		origin api.LBPoolOrigin,
+		targetsByFamily map[ipnet.Family][]netip.Addr,
+	) ResponseCode
+
+	// FinalDisableLBPoolOrigin disables one load balancer pool origin during shutdown.
+	FinalDisableLBPoolOrigin(
+		ctx context.Context,
+		ppfmt pp.PP,
+		origin api.LBPoolOrigin,
+	) ResponseCode
+}
+
+// A GatewayLocationSetter manages the networks of Zero Trust Gateway DNS locations.
+type GatewayLocationSetter interface {
+	// SetGatewayLocation replaces the networks of one Gateway location.
+	//
+	// Contract for targetsByFamily is the same as [Setter.SetWAFList]: networks of
+	// families that are absent or unavailable are kept, and networks of the other
+	// families are replaced with the target prefixes.
+	SetGatewayLocation(
+		ctx context.Context,
+		ppfmt pp.PP,
+		location api.GatewayLocation,
 		targetsByFamily map[ipnet.Family]WAFTargets,
-		fallbackItemComment string,
 	) ResponseCode
 
-	// FinalClearWAFList removes managed WAF content during shutdown in managed family scope.
-	FinalClearWAFList(
+	// FinalClearGatewayLocation removes the networks of the managed families
+	// from one Gateway location during shutdown.
+	FinalClearGatewayLocation(
 		ctx context.Context,
 		ppfmt pp.PP,
-		list api.WAFList,
-		listDescription string,
+		location api.GatewayLocation,
+		managedFamilies map[ipnet.Family]bool,
+	) ResponseCode
+}
+
+// An AccessGroupSetter manages the "ip" include rules of Access groups.
+type AccessGroupSetter interface {
+	// SetAccessGroup replaces the "ip" include rules of one Access group.
+	//
+	// Contract for targetsByFamily is the same as [Setter.SetWAFList]: rules of
+	// families that are absent or unavailable are kept, and rules of the other
+	// families are replaced with the target prefixes.
+	SetAccessGroup(
+		ctx context.Context,
+		ppfmt pp.PP,
+		group api.AccessGroup,
+		targetsByFamily map[ipnet.Family]WAFTargets,
+	) ResponseCode
+
+	// FinalClearAccessGroup removes the "ip" include rules of the managed
+	// families from one Access group during shutdown.
+	FinalClearAccessGroup(
+		ctx context.Context,
+		ppfmt pp.PP,
+		group api.AccessGroup,
 		managedFamilies map[ipnet.Family]bool,
 	) ResponseCode
 }
+
+// An IPAccessRuleSetter manages IP Access Rules.
+type IPAccessRuleSetter interface {
+	// SetIPAccessRules reconciles one set of IP Access Rules so that there is
+	// one managed rule per target prefix.
+	//
+	// Contract for targetsByFamily is the same as [Setter.SetWAFList]. New rules
+	// carry fallbackNotes.
+	SetIPAccessRules(
+		ctx context.Context,
+		ppfmt pp.PP,
+		set api.IPAccessRuleSet,
+		targetsByFamily map[ipnet.Family]WAFTargets,
+		fallbackNotes string,
+	) ResponseCode
+
+	// FinalClearIPAccessRules deletes the managed rules of the managed families
+	// from one set of IP Access Rules during shutdown.
+	FinalClearIPAccessRules(
+		ctx context.Context,
+		ppfmt pp.PP,
+		set api.IPAccessRuleSet,
+		managedFamilies map[ipnet.Family]bool,
+	) ResponseCode
+}
+
+// A SpectrumAppSetter manages the origins of Spectrum applications.
+type SpectrumAppSetter interface {
+	// SetSpectrumApp points the direct origins of one Spectrum application to
+	// the target addresses, keeping their schemes and ports.
+	//
+	// The IP family of the current address of each origin selects the targets,
+	// as in [LBPoolSetter.SetLBPoolOrigin], except that an origin is kept when there
+	// are no targets: Spectrum origins cannot be disabled.
+	SetSpectrumApp(
+		ctx context.Context,
+		ppfmt pp.PP,
+		app api.SpectrumApp,
+		targetsByFamily map[ipnet.Family][]netip.Addr,
+	) ResponseCode
+}
+
+// A WAFListRuleSetter manages the WAF custom rule referencing the managed lists.
+type WAFListRuleSetter interface {
+	// SetWAFListRule makes sure that the zone has exactly one custom rule with
+	// the given description, and that the rule has the given action and expression.
+	// Custom rules with other descriptions are never changed.
+	SetWAFListRule(
+		ctx context.Context,
+		ppfmt pp.PP,
+		rule api.WAFListRule,
+		expression string,
+		description string,
+	) ResponseCode
+
+	// FinalDeleteWAFListRule deletes the custom rules with the given description
+	// during shutdown.
+	FinalDeleteWAFListRule(
+		ctx context.Context,
+		ppfmt pp.PP,
+		rule api.WAFListRule,
+		description string,
+	) ResponseCode
+}
+
+// A WorkersKVSetter publishes the addresses to Workers KV.
+type WorkersKVSetter interface {
+	// SetWorkersKV publishes the target addresses as a JSON document in a
+	// Workers KV key. The addresses of an IP family without targets are kept
+	// from the current document. Nothing is written if the addresses are
+	// unchanged; otherwise, now is recorded as the time of the update.
+	SetWorkersKV(
+		ctx context.Context,
+		ppfmt pp.PP,
+		key api.WorkersKVKey,
+		targetsByFamily map[ipnet.Family][]netip.Addr,
+		now time.Time,
+	) ResponseCode
+
+	// FinalDeleteWorkersKV deletes the Workers KV key during shutdown.
+	FinalDeleteWorkersKV(
+		ctx context.Context,
+		ppfmt pp.PP,
+		key api.WorkersKVKey,
+	) ResponseCode
+}
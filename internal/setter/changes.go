@@ -0,0 +1,198 @@
+package setter
+
+import (
+	"net/netip"
+	"sync"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// RecordScope identifies the managed DNS records of one domain and one IP family.
+type RecordScope struct {
+	IPFamily ipnet.Family
+	Domain   domain.Domain
+}
+
+// RecordChanges lists what the last reconciliation did to the records in one [RecordScope].
+// Updated records carry their new IP addresses; deleted records carry their old ones.
+type RecordChanges struct {
+	Matched []api.Record
+	Updated []api.Record
+	Created []api.Record
+	Deleted []api.Record
+}
+
+// WAFListChanges lists what the last reconciliation did to the managed items of one WAF list.
+type WAFListChanges struct {
+	Matched []netip.Prefix
+	Created []netip.Prefix
+	Deleted []netip.Prefix
+}
+
+// LBPoolOriginChanges records the managed state of one load balancer pool origin
+// before and after the last reconciliation. It is only recorded when the origin
+// could be read.
+type LBPoolOriginChanges struct {
+	Previous api.LBPoolOriginState
+	Current  api.LBPoolOriginState
+}
+
+// GatewayLocationChanges records the networks of one Gateway location before
+// and after the last reconciliation. It is only recorded when the location
+// could be read.
+type GatewayLocationChanges struct {
+	Previous []netip.Prefix
+	Current  []netip.Prefix
+}
+
+// AccessGroupChanges records the IP ranges of the "ip" include rules of one
+// Access group before and after the last reconciliation. It is only recorded
+// when the group could be read.
+type AccessGroupChanges struct {
+	Previous []netip.Prefix
+	Current  []netip.Prefix
+}
+
+// IPAccessRuleChanges lists what the last reconciliation did to the managed rules
+// of one set of IP Access Rules.
+type IPAccessRuleChanges struct {
+	Matched []netip.Prefix
+	Created []netip.Prefix
+	Deleted []netip.Prefix
+}
+
+// SpectrumAppChanges records the direct origins of one Spectrum application
+// before and after the last reconciliation. It is only recorded when the
+// application could be read.
+type SpectrumAppChanges struct {
+	Previous []api.SpectrumOrigin
+	Current  []api.SpectrumOrigin
+}
+
+// WAFListRuleChanges lists what the last reconciliation did to the custom rules
+// managed by the updater in one zone, by their IDs.
+type WAFListRuleChanges struct {
+	Matched []api.ID
+	Updated []api.ID
+	Created []api.ID
+	Deleted []api.ID
+}
+
+// WorkersKVChanges records the addresses published in one Workers KV key
+// before and after the last reconciliation. It is only recorded when the key
+// could be read.
+type WorkersKVChanges struct {
+	Previous []netip.Addr
+	Current  []netip.Addr
+}
+
+// Changes collects the changes made since the last call of [Setter.TakeChanges].
+// Each reconciliation replaces the entry of its scope, so the size of Changes
+// stays bounded even if nobody takes them.
+type Changes struct {
+	Records  map[RecordScope]RecordChanges
+	WAFLists map[api.WAFList]WAFListChanges
+	// LBPoolOrigins only contains the origins that could be read.
+	LBPoolOrigins map[api.LBPoolOrigin]LBPoolOriginChanges
+	// GatewayLocations only contains the locations that could be read.
+	GatewayLocations map[api.GatewayLocation]GatewayLocationChanges
+	// AccessGroups only contains the groups that could be read.
+	AccessGroups  map[api.AccessGroup]AccessGroupChanges
+	IPAccessRules map[api.IPAccessRuleSet]IPAccessRuleChanges
+	// SpectrumApps only contains the applications that could be read.
+	SpectrumApps map[api.SpectrumApp]SpectrumAppChanges
+	WAFListRules map[api.WAFListRule]WAFListRuleChanges
+	// WorkersKV only contains the keys that could be read.
+	WorkersKV map[api.WorkersKVKey]WorkersKVChanges
+	// Mirrors holds the changes to the DNS records on each mirror, such as an
+	// RFC 2136 server, keyed by the description of the mirror.
+	Mirrors map[string]map[RecordScope]RecordChanges
+}
+
+func emptyChanges() Changes {
+	return Changes{
+		Records:          map[RecordScope]RecordChanges{},
+		WAFLists:         map[api.WAFList]WAFListChanges{},
+		LBPoolOrigins:    map[api.LBPoolOrigin]LBPoolOriginChanges{},
+		GatewayLocations: map[api.GatewayLocation]GatewayLocationChanges{},
+		AccessGroups:     map[api.AccessGroup]AccessGroupChanges{},
+		IPAccessRules:    map[api.IPAccessRuleSet]IPAccessRuleChanges{},
+		SpectrumApps:     map[api.SpectrumApp]SpectrumAppChanges{},
+		WAFListRules:     map[api.WAFListRule]WAFListRuleChanges{},
+		WorkersKV:        map[api.WorkersKVKey]WorkersKVChanges{},
+		Mirrors:          map[string]map[RecordScope]RecordChanges{},
+	}
+}
+
+type journal struct {
+	mutex   sync.Mutex
+	changes Changes
+}
+
+func newJournal() *journal {
+	return &journal{mutex: sync.Mutex{}, changes: emptyChanges()}
+}
+
+func (j *journal) setRecords(scope RecordScope, changes RecordChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.Records[scope] = changes
+}
+
+func (j *journal) setWAFList(list api.WAFList, changes WAFListChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.WAFLists[list] = changes
+}
+
+func (j *journal) setLBPoolOrigin(origin api.LBPoolOrigin, changes LBPoolOriginChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.LBPoolOrigins[origin] = changes
+}
+
+func (j *journal) setGatewayLocation(location api.GatewayLocation, changes GatewayLocationChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.GatewayLocations[location] = changes
+}
+
+func (j *journal) setAccessGroup(group api.AccessGroup, changes AccessGroupChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.AccessGroups[group] = changes
+}
+
+func (j *journal) setIPAccessRules(set api.IPAccessRuleSet, changes IPAccessRuleChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.IPAccessRules[set] = changes
+}
+
+func (j *journal) setSpectrumApp(app api.SpectrumApp, changes SpectrumAppChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.SpectrumApps[app] = changes
+}
+
+func (j *journal) setWAFListRule(rule api.WAFListRule, changes WAFListRuleChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.WAFListRules[rule] = changes
+}
+
+func (j *journal) setWorkersKV(key api.WorkersKVKey, changes WorkersKVChanges) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.changes.WorkersKV[key] = changes
+}
+
+func (j *journal) take() Changes {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	changes := j.changes
+	j.changes = emptyChanges()
+	return changes
+}
@@ -0,0 +1,84 @@
+package setter_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestTakeChanges(t *testing.T) {
+	t.Parallel()
+
+	fixture := newDNSRecordFixture()
+	ctx, h := newSetterHarness(t)
+	list := api.WAFList{AccountID: "account", Name: "list"}
+	kept := netip.MustParsePrefix("10.0.0.1/32")
+	stale := netip.MustParsePrefix("10.0.0.9/32")
+	added := netip.MustParsePrefix("10.0.0.2/32")
+	outdated := api.Record{ID: fixture.record2, IP: fixture.ip2, RecordParams: fixture.params}
+	matched := api.Record{ID: fixture.record1, IP: fixture.ip1, RecordParams: fixture.params}
+
+	h.mockPP.EXPECT().Noticef(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		expectRecordList(ctx, h.mockPP, h.mockHandle, fixture.ipFamily, fixture.domain, fixture.params,
+			[]api.Record{matched, outdated}, false, true),
+		h.mockHandle.EXPECT().DeleteRecord(ctx, h.mockPP, fixture.ipFamily, fixture.domain, fixture.record2,
+			api.RegularDeletionMode).Return(true),
+		h.mockHandle.EXPECT().ListWAFListItems(ctx, h.mockPP, list, "description", "comment").
+			Return([]api.WAFListItem{
+				{ID: "item1", Prefix: kept, Comment: "comment"},
+				{ID: "item9", Prefix: stale, Comment: "comment"},
+			}, true, false, true),
+		h.mockHandle.EXPECT().CreateWAFListItems(ctx, h.mockPP, list, "description",
+			[]api.WAFListCreateItem{{Prefix: added, Comment: "comment"}}).Return(true),
+		h.mockHandle.EXPECT().DeleteWAFListItems(ctx, h.mockPP, list, "description", []api.ID{"item9"}).
+			Return(true),
+	)
+
+	require.Equal(t, setter.ResponseUpdated, h.setter.SetIPs(ctx, h.mockPP, fixture.ipFamily, fixture.domain,
+		[]netip.Addr{fixture.ip1}, fixture.params))
+	require.Equal(t, setter.ResponseUpdated, h.setter.SetWAFList(ctx, h.mockPP, list, "description",
+		map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{kept, added})},
+		"comment"))
+
+	require.Equal(t, setter.Changes{
+		Records: map[setter.RecordScope]setter.RecordChanges{
+			{IPFamily: fixture.ipFamily, Domain: fixture.domain}: {
+				Matched: []api.Record{matched},
+				Updated: nil,
+				Created: nil,
+				Deleted: []api.Record{outdated},
+			},
+		},
+		WAFLists: map[api.WAFList]setter.WAFListChanges{
+			list: {Matched: []netip.Prefix{kept}, Created: []netip.Prefix{added}, Deleted: []netip.Prefix{stale}},
+		},
+		LBPoolOrigins:    map[api.LBPoolOrigin]setter.LBPoolOriginChanges{},
+		GatewayLocations: map[api.GatewayLocation]setter.GatewayLocationChanges{},
+		AccessGroups:     map[api.AccessGroup]setter.AccessGroupChanges{},
+		IPAccessRules:    map[api.IPAccessRuleSet]setter.IPAccessRuleChanges{},
+		SpectrumApps:     map[api.SpectrumApp]setter.SpectrumAppChanges{},
+		WAFListRules:     map[api.WAFListRule]setter.WAFListRuleChanges{},
+		WorkersKV:        map[api.WorkersKVKey]setter.WorkersKVChanges{},
+		Mirrors:          map[string]map[setter.RecordScope]setter.RecordChanges{},
+	}, h.setter.TakeChanges())
+
+	require.Equal(t, setter.Changes{
+		Records:          map[setter.RecordScope]setter.RecordChanges{},
+		WAFLists:         map[api.WAFList]setter.WAFListChanges{},
+		LBPoolOrigins:    map[api.LBPoolOrigin]setter.LBPoolOriginChanges{},
+		GatewayLocations: map[api.GatewayLocation]setter.GatewayLocationChanges{},
+		AccessGroups:     map[api.AccessGroup]setter.AccessGroupChanges{},
+		IPAccessRules:    map[api.IPAccessRuleSet]setter.IPAccessRuleChanges{},
+		SpectrumApps:     map[api.SpectrumApp]setter.SpectrumAppChanges{},
+		WAFListRules:     map[api.WAFListRule]setter.WAFListRuleChanges{},
+		WorkersKV:        map[api.WorkersKVKey]setter.WorkersKVChanges{},
+		Mirrors:          map[string]map[setter.RecordScope]setter.RecordChanges{},
+	}, h.setter.TakeChanges())
+}
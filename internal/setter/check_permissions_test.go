@@ -0,0 +1,34 @@
+package setter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestCheckPermissionsDelegates(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+
+	domains := []domain.Domain{domain.FQDN("sub.test.org")}
+	lists := []api.WAFList{{AccountID: "account", Name: "list"}}
+	report := api.PermissionReport{ //nolint:exhaustruct
+		Verified:              true,
+		Inspected:             true,
+		DomainsWithoutDNSEdit: domains,
+	}
+	mockHandle.EXPECT().CheckPermissions(gomock.Any(), mockPP, domains, lists).Return(report)
+
+	s := setter.New(mockPP, mockHandle)
+	require.Equal(t, report, s.CheckPermissions(context.Background(), mockPP, domains, lists))
+}
@@ -1,5 +1,7 @@
 package setter
 
+import "fmt"
+
 // ResponseCode encodes the minimum information to generate messages for monitors and notifiers.
 type ResponseCode int
 
@@ -24,3 +26,19 @@ const (
 	// should be deleted and we failed to finish the deletion.
 	ResponseFailed
 )
+
+// String gives a short lowercase name of the code, used in machine-readable reports.
+func (c ResponseCode) String() string {
+	switch c {
+	case ResponseNoop:
+		return "noop"
+	case ResponseUpdated:
+		return "updated"
+	case ResponseUpdating:
+		return "updating"
+	case ResponseFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("ResponseCode(%d)", int(c))
+	}
+}
@@ -0,0 +1,149 @@
+package setter_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestSetIPsDryRun(t *testing.T) {
+	t.Parallel()
+
+	fixture := newDNSRecordFixture()
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+	ctx := context.Background()
+
+	gomock.InOrder(
+		mockHandle.EXPECT().ListRecords(ctx, mockPP, fixture.ipFamily, fixture.domain, fixture.params).
+			Return([]api.Record{
+				{ID: fixture.record1, IP: netip.MustParseAddr("::3"), RecordParams: fixture.params},
+			}, false, true),
+		mockPP.EXPECT().Noticef(pp.EmojiUpdate,
+			"Would update an outdated %s record for %s to %s (ID: %s)",
+			"AAAA", "sub.test.org", fixture.ip1, fixture.record1),
+		mockPP.EXPECT().Noticef(pp.EmojiCreation,
+			"Would add a new %s record for %s with %s", "AAAA", "sub.test.org", fixture.ip2),
+	)
+
+	s := setter.New(mockPP, api.NewDryRunHandle(mockHandle))
+	resp := s.SetIPs(ctx, mockPP, fixture.ipFamily, fixture.domain,
+		[]netip.Addr{fixture.ip1, fixture.ip2}, fixture.params)
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "sub.test.org", Action: "update the AAAA record record1 to ::1"},
+		{Subject: "sub.test.org", Action: "add an AAAA record for ::2"},
+	}, s.TakePlan())
+
+	gomock.InOrder(
+		mockHandle.EXPECT().ListRecords(ctx, mockPP, fixture.ipFamily, fixture.domain, fixture.params).
+			Return([]api.Record{{ID: fixture.record1, IP: fixture.ip2, RecordParams: fixture.params}}, true, true),
+		mockPP.EXPECT().Noticef(pp.EmojiDeletion,
+			"Would delete an outdated %s record for %s (ID: %s)", "AAAA", "sub.test.org", fixture.record1),
+	)
+	resp = s.FinalDelete(ctx, mockPP, fixture.ipFamily, fixture.domain, fixture.params)
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "sub.test.org", Action: "delete the AAAA record record1"},
+	}, s.TakePlan())
+	require.Empty(t, s.TakePlan())
+}
+
+func TestSetWAFListDryRun(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+	ctx := context.Background()
+	list := api.WAFList{AccountID: "account", Name: "list"}
+
+	gomock.InOrder(
+		mockHandle.EXPECT().ListWAFListItems(ctx, mockPP, list, "description", "comment").
+			Return([]api.WAFListItem{
+				{ID: "item1", Prefix: netip.MustParsePrefix("10.0.0.1/32"), Comment: "comment"},
+			}, true, false, true),
+		mockPP.EXPECT().Noticef(pp.EmojiCreation, "Would add %s to the list %s", "10.0.0.2/32", "account/list"),
+		mockPP.EXPECT().Noticef(pp.EmojiDeletion, "Would delete %s from the list %s", "10.0.0.1/32", "account/list"),
+	)
+
+	s := setter.New(mockPP, api.NewDryRunHandle(mockHandle))
+	resp := s.SetWAFList(ctx, mockPP, list, "description", map[ipnet.Family]setter.WAFTargets{
+		ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")}),
+	}, "comment")
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "account/list", Action: "add 10.0.0.2/32"},
+		{Subject: "account/list", Action: "delete 10.0.0.1/32"},
+	}, s.TakePlan())
+}
+
+func TestSetLBPoolOriginDryRun(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+	ctx := context.Background()
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	ip := netip.MustParseAddr("10.0.0.2")
+
+	gomock.InOrder(
+		mockHandle.EXPECT().GetLBPoolOrigin(ctx, mockPP, origin).
+			Return(api.LBPoolOriginState{Address: netip.MustParseAddr("10.0.0.1"), Enabled: true}, true),
+		mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Would set the origin %s to %s", "account/pool:home", ip),
+	)
+
+	s := setter.New(mockPP, api.NewDryRunHandle(mockHandle))
+	resp := s.SetLBPoolOrigin(ctx, mockPP, origin, map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip}})
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "account/pool:home", Action: "set the address to 10.0.0.2 and enable the origin"},
+	}, s.TakePlan())
+}
+
+func TestSetGatewayLocationDryRun(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+	ctx := context.Background()
+	location := api.GatewayLocation{AccountID: "account", Name: "office"}
+	prefix := netip.MustParsePrefix("10.0.0.2/32")
+
+	gomock.InOrder(
+		mockHandle.EXPECT().ListGatewayLocationNetworks(ctx, mockPP, location).
+			Return([]netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")}, true),
+		mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Would set the %s of the %s %s to %s",
+			"networks", "Gateway location", "account/office", "10.0.0.2/32"),
+	)
+
+	s := setter.New(mockPP, api.NewDryRunHandle(mockHandle))
+	resp := s.SetGatewayLocation(ctx, mockPP, location,
+		map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{prefix})})
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "account/office", Action: "set the networks to 10.0.0.2/32"},
+	}, s.TakePlan())
+}
+
+func TestTakePlanWithoutDryRun(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+
+	require.Nil(t, setter.New(mockPP, mockHandle).TakePlan())
+}
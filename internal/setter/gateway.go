@@ -0,0 +1,48 @@
+package setter
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// gatewayLocation describes the networks of a Gateway location for [setter.reconcilePrefixes].
+func (s setter) gatewayLocation(location api.GatewayLocation) prefixResource {
+	return prefixResource{
+		kind:        "Gateway location",
+		name:        location.Describe(),
+		rangesLabel: "networks",
+		list: func(ctx context.Context, ppfmt pp.PP) ([]netip.Prefix, bool) {
+			return s.Handle.ListGatewayLocationNetworks(ctx, ppfmt, location)
+		},
+		set: func(ctx context.Context, ppfmt pp.PP, prefixes []netip.Prefix) bool {
+			return s.Handle.SetGatewayLocationNetworks(ctx, ppfmt, location, prefixes)
+		},
+		record: func(previous, current []netip.Prefix) {
+			s.journal.setGatewayLocation(location, GatewayLocationChanges{Previous: previous, Current: current})
+		},
+	}
+}
+
+// SetGatewayLocation replaces the networks of a Gateway location in the managed
+// families with the target prefixes. Networks of the other families are kept.
+func (s setter) SetGatewayLocation(ctx context.Context, ppfmt pp.PP,
+	location api.GatewayLocation, targetsByFamily map[ipnet.Family]WAFTargets,
+) ResponseCode {
+	return s.reconcilePrefixes(ctx, ppfmt, s.gatewayLocation(location), func(current []netip.Prefix) []netip.Prefix {
+		return replacePrefixesOfFamilies(current, targetsByFamily)
+	})
+}
+
+// FinalClearGatewayLocation removes the networks of the managed families from a
+// Gateway location during shutdown.
+func (s setter) FinalClearGatewayLocation(ctx context.Context, ppfmt pp.PP,
+	location api.GatewayLocation, managedFamilies map[ipnet.Family]bool,
+) ResponseCode {
+	return s.reconcilePrefixes(ctx, ppfmt, s.gatewayLocation(location), func(current []netip.Prefix) []netip.Prefix {
+		return keepPrefixesOfOtherFamilies(current, managedFamilies)
+	})
+}
@@ -0,0 +1,61 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// The reconciliation itself is tested with reconcilePrefixes; these tests only
+// check that the networks of the location are read, written, and recorded.
+
+func TestSetGatewayLocation(t *testing.T) {
+	t.Parallel()
+
+	location := api.GatewayLocation{AccountID: "account", Name: "office"}
+	prefix4a := netip.MustParsePrefix("192.0.2.1/32")
+	prefix4b := netip.MustParsePrefix("198.51.100.0/24")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+
+	ctx, h := newSetterHarness(t)
+	h.prepare(ctx, func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+		m.EXPECT().ListGatewayLocationNetworks(ctx, p, location).Return([]netip.Prefix{prefix6, prefix4a}, true)
+		m.EXPECT().SetGatewayLocationNetworks(ctx, p, location, []netip.Prefix{prefix4b, prefix6}).Return(true)
+		p.EXPECT().Noticef(pp.EmojiUpdate, "Set the %s of the %s %s to %s", "networks", "Gateway location", "account/office", "198.51.100.0/24 and 2001:db8::/64")
+	})
+
+	resp := h.setter.SetGatewayLocation(ctx, h.mockPP, location,
+		map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{prefix4b})})
+	require.Equal(t, setter.ResponseUpdated, resp)
+	require.Equal(t,
+		setter.GatewayLocationChanges{Previous: []netip.Prefix{prefix4a, prefix6}, Current: []netip.Prefix{prefix4b, prefix6}},
+		h.setter.TakeChanges().GatewayLocations[location])
+}
+
+func TestFinalClearGatewayLocation(t *testing.T) {
+	t.Parallel()
+
+	location := api.GatewayLocation{AccountID: "account", Name: "office"}
+	prefix4 := netip.MustParsePrefix("192.0.2.1/32")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+
+	ctx, h := newSetterHarness(t)
+	h.prepare(ctx, func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+		m.EXPECT().ListGatewayLocationNetworks(ctx, p, location).Return([]netip.Prefix{prefix4, prefix6}, true)
+		m.EXPECT().SetGatewayLocationNetworks(ctx, p, location, []netip.Prefix{prefix6}).Return(true)
+		p.EXPECT().Noticef(pp.EmojiUpdate, "Set the %s of the %s %s to %s", "networks", "Gateway location", "account/office", "2001:db8::/64")
+	})
+
+	resp := h.setter.FinalClearGatewayLocation(ctx, h.mockPP, location, map[ipnet.Family]bool{ipnet.IP4: true})
+	require.Equal(t, setter.ResponseUpdated, resp)
+}
@@ -0,0 +1,153 @@
+package setter
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// ruleFamilyIn reports whether the IP family of a rule is in the given set.
+func ruleFamilyIn(rule api.IPAccessRule, families map[ipnet.Family]bool) bool {
+	for ipFamily := range ipnet.All {
+		if families[ipFamily] && ipFamily.Matches(rule.Prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyIPAccessRuleChanges creates rules first, then deletes rules, to avoid
+// temporary gaps on partial failures. It returns false when any change failed.
+func (s setter) applyIPAccessRuleChanges(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet,
+	createPrefixes []netip.Prefix, notes string, deleteRules []api.IPAccessRule, changes *IPAccessRuleChanges,
+) bool {
+	for _, prefix := range createPrefixes {
+		if _, ok := s.Handle.CreateIPAccessRule(ctx, ppfmt, set, prefix, notes); !ok {
+			ppfmt.Noticef(pp.EmojiError,
+				"Could not confirm update of the IP access rules %s; they may be inconsistent", set.Describe())
+			return false
+		}
+		changes.Created = append(changes.Created, prefix)
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiCreation, "Would add a rule for %s to the IP access rules %s",
+				prefix.String(), set.Describe())
+		} else {
+			ppfmt.Noticef(pp.EmojiCreation, "Added a rule for %s to the IP access rules %s",
+				prefix.String(), set.Describe())
+		}
+	}
+
+	for _, rule := range deleteRules {
+		if !s.Handle.DeleteIPAccessRule(ctx, ppfmt, set, rule.ID) {
+			ppfmt.Noticef(pp.EmojiError,
+				"Could not confirm update of the IP access rules %s; they may be inconsistent", set.Describe())
+			return false
+		}
+		changes.Deleted = append(changes.Deleted, rule.Prefix)
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiDeletion, "Would delete the rule for %s from the IP access rules %s",
+				rule.Prefix.String(), set.Describe())
+		} else {
+			ppfmt.Noticef(pp.EmojiDeletion, "Deleted the rule for %s from the IP access rules %s",
+				rule.Prefix.String(), set.Describe())
+		}
+	}
+
+	return true
+}
+
+// SetIPAccessRules reconciles the managed IP Access Rules of a set so that there
+// is exactly one rule per target prefix in each family with usable targets.
+func (s setter) SetIPAccessRules(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet,
+	targetsByFamily map[ipnet.Family]WAFTargets, fallbackNotes string,
+) ResponseCode {
+	changes := IPAccessRuleChanges{Matched: nil, Created: nil, Deleted: nil}
+	defer func() { s.journal.setIPAccessRules(set, changes) }()
+
+	rules, ok := s.Handle.ListIPAccessRules(ctx, ppfmt, set)
+	if !ok {
+		return ResponseFailed
+	}
+
+	replaced := map[ipnet.Family]bool{}
+	var targets []netip.Prefix
+	for ipFamily, t := range ipnet.Bindings(targetsByFamily) {
+		if t.HasUsableTargets() {
+			replaced[ipFamily] = true
+			for _, prefix := range t.Prefixes {
+				targets = append(targets, prefix.Masked())
+			}
+		}
+	}
+	slices.SortFunc(targets, netip.Prefix.Compare)
+	targets = slices.Compact(targets)
+
+	// Keep one rule per target; duplicated rules and rules for other prefixes
+	// of the replaced families are deleted.
+	covered := map[netip.Prefix]bool{}
+	var deleteRules []api.IPAccessRule
+	for _, rule := range rules {
+		switch {
+		case !ruleFamilyIn(rule, replaced):
+			changes.Matched = append(changes.Matched, rule.Prefix)
+		case slices.Contains(targets, rule.Prefix.Masked()) && !covered[rule.Prefix.Masked()]:
+			covered[rule.Prefix.Masked()] = true
+			changes.Matched = append(changes.Matched, rule.Prefix)
+		default:
+			deleteRules = append(deleteRules, rule)
+		}
+	}
+	var createPrefixes []netip.Prefix
+	for _, target := range targets {
+		if !covered[target] {
+			createPrefixes = append(createPrefixes, target)
+		}
+	}
+
+	if len(createPrefixes) == 0 && len(deleteRules) == 0 {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The IP access rules %s are already up to date", set.Describe())
+		return ResponseNoop
+	}
+
+	if !s.applyIPAccessRuleChanges(ctx, ppfmt, set, createPrefixes, fallbackNotes, deleteRules, &changes) {
+		return ResponseFailed
+	}
+	return ResponseUpdated
+}
+
+// FinalClearIPAccessRules deletes the managed IP Access Rules of the managed
+// families during shutdown.
+func (s setter) FinalClearIPAccessRules(ctx context.Context, ppfmt pp.PP, set api.IPAccessRuleSet,
+	managedFamilies map[ipnet.Family]bool,
+) ResponseCode {
+	changes := IPAccessRuleChanges{Matched: nil, Created: nil, Deleted: nil}
+	defer func() { s.journal.setIPAccessRules(set, changes) }()
+
+	rules, ok := s.Handle.ListIPAccessRules(ctx, ppfmt, set)
+	if !ok {
+		return ResponseFailed
+	}
+
+	var deleteRules []api.IPAccessRule
+	for _, rule := range rules {
+		if ruleFamilyIn(rule, managedFamilies) {
+			deleteRules = append(deleteRules, rule)
+		} else {
+			changes.Matched = append(changes.Matched, rule.Prefix)
+		}
+	}
+
+	if len(deleteRules) == 0 {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The IP access rules %s were already cleaned up", set.Describe())
+		return ResponseNoop
+	}
+
+	if !s.applyIPAccessRuleChanges(ctx, ppfmt, set, nil, "", deleteRules, &changes) {
+		return ResponseFailed
+	}
+	return ResponseUpdated
+}
@@ -0,0 +1,214 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestSetIPAccessRules(t *testing.T) {
+	t.Parallel()
+
+	set := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone", Mode: api.IPAccessRuleModeBlock}
+	prefix4a := netip.MustParsePrefix("192.0.2.1/32")
+	prefix4b := netip.MustParsePrefix("198.51.100.0/24")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+	prefixes := func(ps ...netip.Prefix) []netip.Prefix { return ps }
+	rule := func(id api.ID, prefix netip.Prefix) api.IPAccessRule {
+		return api.IPAccessRule{ID: id, Prefix: prefix, Notes: "ddns"}
+	}
+
+	cases := []struct {
+		name         string
+		targets      map[ipnet.Family]setter.WAFTargets
+		resp         setter.ResponseCode
+		changes      setter.IPAccessRuleChanges
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name:    "up-to-date/response-noop",
+			targets: map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(prefixes(prefix4a))},
+			resp:    setter.ResponseNoop,
+			changes: setter.IPAccessRuleChanges{Matched: prefixes(prefix4a, prefix6), Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule("rule1", prefix4a), rule("rule2", prefix6)}, true)
+				p.EXPECT().Infof(pp.EmojiAlreadyDone, "The IP access rules %s are already up to date", "zone/zone:block")
+			},
+		},
+		{
+			name:    "outdated/create-then-delete/response-updated",
+			targets: map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(prefixes(prefix4b))},
+			resp:    setter.ResponseUpdated,
+			changes: setter.IPAccessRuleChanges{Matched: prefixes(prefix6), Created: prefixes(prefix4b), Deleted: prefixes(prefix4a)},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule("rule1", prefix4a), rule("rule2", prefix6)}, true),
+					m.EXPECT().CreateIPAccessRule(ctx, p, set, prefix4b, "ddns").Return(api.ID("rule3"), true),
+					p.EXPECT().Noticef(pp.EmojiCreation, "Added a rule for %s to the IP access rules %s", "198.51.100.0/24", "zone/zone:block"),
+					m.EXPECT().DeleteIPAccessRule(ctx, p, set, api.ID("rule1")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the rule for %s from the IP access rules %s", "192.0.2.1/32", "zone/zone:block"),
+				)
+			},
+		},
+		{
+			name:    "duplicate/response-updated",
+			targets: map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(prefixes(prefix4a))},
+			resp:    setter.ResponseUpdated,
+			changes: setter.IPAccessRuleChanges{Matched: prefixes(prefix4a), Created: nil, Deleted: prefixes(prefix4a)},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule("rule1", prefix4a), rule("rule2", prefix4a)}, true),
+					m.EXPECT().DeleteIPAccessRule(ctx, p, set, api.ID("rule2")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the rule for %s from the IP access rules %s", "192.0.2.1/32", "zone/zone:block"),
+				)
+			},
+		},
+		{
+			name: "unavailable/keep/response-updated",
+			targets: map[ipnet.Family]setter.WAFTargets{
+				ipnet.IP4: setter.NewAvailableWAFTargets(prefixes(prefix4a)),
+				ipnet.IP6: setter.NewUnavailableWAFTargets(),
+			},
+			resp:    setter.ResponseUpdated,
+			changes: setter.IPAccessRuleChanges{Matched: prefixes(prefix6), Created: prefixes(prefix4a), Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule("rule2", prefix6)}, true),
+					m.EXPECT().CreateIPAccessRule(ctx, p, set, prefix4a, "ddns").Return(api.ID("rule3"), true),
+					p.EXPECT().Noticef(pp.EmojiCreation, "Added a rule for %s to the IP access rules %s", "192.0.2.1/32", "zone/zone:block"),
+				)
+			},
+		},
+		{
+			name:    "explicit-empty/response-updated",
+			targets: map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(nil)},
+			resp:    setter.ResponseUpdated,
+			changes: setter.IPAccessRuleChanges{Matched: nil, Created: nil, Deleted: prefixes(prefix4a)},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule("rule1", prefix4a)}, true),
+					m.EXPECT().DeleteIPAccessRule(ctx, p, set, api.ID("rule1")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the rule for %s from the IP access rules %s", "192.0.2.1/32", "zone/zone:block"),
+				)
+			},
+		},
+		{
+			name:    "read-failed/response-failed",
+			targets: map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(prefixes(prefix4a))},
+			resp:    setter.ResponseFailed,
+			changes: setter.IPAccessRuleChanges{Matched: nil, Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().ListIPAccessRules(ctx, p, set).Return(nil, false)
+			},
+		},
+		{
+			name:    "create-failed/response-failed",
+			targets: map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(prefixes(prefix4b))},
+			resp:    setter.ResponseFailed,
+			changes: setter.IPAccessRuleChanges{Matched: nil, Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule("rule1", prefix4a)}, true),
+					m.EXPECT().CreateIPAccessRule(ctx, p, set, prefix4b, "ddns").Return(api.ID(""), false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the IP access rules %s; they may be inconsistent", "zone/zone:block"),
+				)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.SetIPAccessRules(ctx, h.mockPP, set, tc.targets, "ddns")
+			require.Equal(t, tc.resp, resp)
+			require.Equal(t, map[api.IPAccessRuleSet]setter.IPAccessRuleChanges{set: tc.changes},
+				h.setter.TakeChanges().IPAccessRules)
+		})
+	}
+}
+
+func TestFinalClearIPAccessRules(t *testing.T) {
+	t.Parallel()
+
+	set := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeAccount, ScopeID: "account", Mode: api.IPAccessRuleModeAllow}
+	prefix4 := netip.MustParsePrefix("192.0.2.1/32")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+	rule4 := api.IPAccessRule{ID: "rule1", Prefix: prefix4, Notes: ""}
+	rule6 := api.IPAccessRule{ID: "rule2", Prefix: prefix6, Notes: ""}
+
+	cases := []struct {
+		name         string
+		managed      map[ipnet.Family]bool
+		resp         setter.ResponseCode
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name:    "managed/response-updated",
+			managed: map[ipnet.Family]bool{ipnet.IP4: true},
+			resp:    setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule4, rule6}, true),
+					m.EXPECT().DeleteIPAccessRule(ctx, p, set, api.ID("rule1")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the rule for %s from the IP access rules %s", "192.0.2.1/32", "account/account:allow"),
+				)
+			},
+		},
+		{
+			name:    "unmanaged/response-noop",
+			managed: map[ipnet.Family]bool{ipnet.IP4: true},
+			resp:    setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule6}, true)
+				p.EXPECT().Infof(pp.EmojiAlreadyDone, "The IP access rules %s were already cleaned up", "account/account:allow")
+			},
+		},
+		{
+			name:    "delete-failed/response-failed",
+			managed: map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true},
+			resp:    setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListIPAccessRules(ctx, p, set).Return([]api.IPAccessRule{rule4, rule6}, true),
+					m.EXPECT().DeleteIPAccessRule(ctx, p, set, api.ID("rule1")).Return(false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the IP access rules %s; they may be inconsistent", "account/account:allow"),
+				)
+			},
+		},
+		{
+			name:    "read-failed/response-failed",
+			managed: map[ipnet.Family]bool{ipnet.IP4: true},
+			resp:    setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().ListIPAccessRules(ctx, p, set).Return(nil, false)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.FinalClearIPAccessRules(ctx, h.mockPP, set, tc.managed)
+			require.Equal(t, tc.resp, resp)
+		})
+	}
+}
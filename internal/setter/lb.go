@@ -0,0 +1,113 @@
+package setter
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// lbPoolOriginFamily gives the IP family of the current address of an origin.
+func lbPoolOriginFamily(state api.LBPoolOriginState) ipnet.Family {
+	if state.Address.Is4() {
+		return ipnet.IP4
+	}
+	return ipnet.IP6
+}
+
+// updateLBPoolOrigin calls the handle and reports the change.
+func (s setter) updateLBPoolOrigin(ctx context.Context, ppfmt pp.PP,
+	origin api.LBPoolOrigin, desired api.LBPoolOriginState,
+) bool {
+	if !s.Handle.UpdateLBPoolOrigin(ctx, ppfmt, origin, desired) {
+		ppfmt.Noticef(pp.EmojiError,
+			"Could not confirm update of the origin %s; its state may be inconsistent", origin.Describe())
+		return false
+	}
+
+	switch {
+	case desired.Enabled && s.DryRun:
+		ppfmt.Noticef(pp.EmojiUpdate, "Would set the origin %s to %s", origin.Describe(), desired.Address)
+	case desired.Enabled:
+		ppfmt.Noticef(pp.EmojiUpdate, "Set the origin %s to %s", origin.Describe(), desired.Address)
+	case s.DryRun:
+		ppfmt.Noticef(pp.EmojiClear, "Would disable the origin %s", origin.Describe())
+	default:
+		ppfmt.Noticef(pp.EmojiClear, "Disabled the origin %s", origin.Describe())
+	}
+	return true
+}
+
+// SetLBPoolOrigin updates the address of a load balancer pool origin.
+//
+// An origin that already uses one of the targets is kept as it is (after being
+// enabled), so that an origin is not moved between equally valid addresses.
+func (s setter) SetLBPoolOrigin(ctx context.Context, ppfmt pp.PP,
+	origin api.LBPoolOrigin, targetsByFamily map[ipnet.Family][]netip.Addr,
+) ResponseCode {
+	current, ok := s.Handle.GetLBPoolOrigin(ctx, ppfmt, origin)
+	if !ok {
+		return ResponseFailed
+	}
+
+	ipFamily := lbPoolOriginFamily(current)
+	targets, managed := targetsByFamily[ipFamily]
+	if !managed {
+		ppfmt.Infof(pp.EmojiAlreadyDone,
+			"The origin %s is kept because no %s address is available", origin.Describe(), ipFamily.Describe())
+		s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: current})
+		return ResponseNoop
+	}
+
+	desired := api.LBPoolOriginState{Address: current.Address, Enabled: false}
+	if len(targets) > 0 {
+		desired.Enabled = true
+		if !slices.Contains(targets, current.Address) {
+			desired.Address = targets[0]
+		}
+	}
+
+	if desired == current {
+		if desired.Enabled {
+			ppfmt.Infof(pp.EmojiAlreadyDone, "The origin %s is already up to date", origin.Describe())
+		} else {
+			ppfmt.Infof(pp.EmojiAlreadyDone, "The origin %s was already disabled", origin.Describe())
+		}
+		s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: current})
+		return ResponseNoop
+	}
+
+	if !s.updateLBPoolOrigin(ctx, ppfmt, origin, desired) {
+		s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: current})
+		return ResponseFailed
+	}
+	s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: desired})
+	return ResponseUpdated
+}
+
+// FinalDisableLBPoolOrigin disables a load balancer pool origin. Origins are
+// disabled instead of removed so that the pool keeps its configuration.
+func (s setter) FinalDisableLBPoolOrigin(ctx context.Context, ppfmt pp.PP, origin api.LBPoolOrigin,
+) ResponseCode {
+	current, ok := s.Handle.GetLBPoolOrigin(ctx, ppfmt, origin)
+	if !ok {
+		return ResponseFailed
+	}
+
+	if !current.Enabled {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The origin %s was already disabled", origin.Describe())
+		s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: current})
+		return ResponseNoop
+	}
+
+	desired := api.LBPoolOriginState{Address: current.Address, Enabled: false}
+	if !s.updateLBPoolOrigin(ctx, ppfmt, origin, desired) {
+		s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: current})
+		return ResponseFailed
+	}
+	s.journal.setLBPoolOrigin(origin, LBPoolOriginChanges{Previous: current, Current: desired})
+	return ResponseUpdated
+}
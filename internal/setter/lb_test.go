@@ -0,0 +1,174 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestSetLBPoolOrigin(t *testing.T) {
+	t.Parallel()
+
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	ip1 := netip.MustParseAddr("192.0.2.1")
+	ip2 := netip.MustParseAddr("192.0.2.2")
+	ip6 := netip.MustParseAddr("2001:db8::1")
+	state := func(ip netip.Addr, enabled bool) api.LBPoolOriginState {
+		return api.LBPoolOriginState{Address: ip, Enabled: enabled}
+	}
+
+	cases := []struct {
+		name         string
+		targets      map[ipnet.Family][]netip.Addr
+		resp         setter.ResponseCode
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name:    "up-to-date/response-noop",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip1, ip2}},
+			resp:    setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip2, true), true)
+				p.EXPECT().Infof(pp.EmojiAlreadyDone, "The origin %s is already up to date", "account/pool:home")
+			},
+		},
+		{
+			name:    "outdated/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip2}},
+			resp:    setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip1, true), true)
+				m.EXPECT().UpdateLBPoolOrigin(ctx, p, origin, state(ip2, true)).Return(true)
+				p.EXPECT().Noticef(pp.EmojiUpdate, "Set the origin %s to %s", "account/pool:home", ip2)
+			},
+		},
+		{
+			name:    "disabled/enable/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip1}},
+			resp:    setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip1, false), true)
+				m.EXPECT().UpdateLBPoolOrigin(ctx, p, origin, state(ip1, true)).Return(true)
+				p.EXPECT().Noticef(pp.EmojiUpdate, "Set the origin %s to %s", "account/pool:home", ip1)
+			},
+		},
+		{
+			name:    "explicit-empty/disable/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {}},
+			resp:    setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip1, true), true)
+				m.EXPECT().UpdateLBPoolOrigin(ctx, p, origin, state(ip1, false)).Return(true)
+				p.EXPECT().Noticef(pp.EmojiClear, "Disabled the origin %s", "account/pool:home")
+			},
+		},
+		{
+			name:    "explicit-empty/already-disabled/response-noop",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {}},
+			resp:    setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip1, false), true)
+				p.EXPECT().Infof(pp.EmojiAlreadyDone, "The origin %s was already disabled", "account/pool:home")
+			},
+		},
+		{
+			name:    "family-unavailable/response-noop",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip1}},
+			resp:    setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip6, true), true)
+				p.EXPECT().Infof(pp.EmojiAlreadyDone, "The origin %s is kept because no %s address is available", "account/pool:home", "IPv6")
+			},
+		},
+		{
+			name:    "read-failed/response-failed",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip1}},
+			resp:    setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(api.LBPoolOriginState{}, false)
+			},
+		},
+		{
+			name:    "update-failed/response-failed",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip2}},
+			resp:    setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(state(ip1, true), true)
+				m.EXPECT().UpdateLBPoolOrigin(ctx, p, origin, state(ip2, true)).Return(false)
+				p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the origin %s; its state may be inconsistent", "account/pool:home")
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.SetLBPoolOrigin(ctx, h.mockPP, origin, tc.targets)
+			require.Equal(t, tc.resp, resp)
+		})
+	}
+}
+
+func TestFinalDisableLBPoolOrigin(t *testing.T) {
+	t.Parallel()
+
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	ip := netip.MustParseAddr("192.0.2.1")
+
+	cases := []struct {
+		name         string
+		resp         setter.ResponseCode
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name: "enabled/response-updated",
+			resp: setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(api.LBPoolOriginState{Address: ip, Enabled: true}, true)
+				m.EXPECT().UpdateLBPoolOrigin(ctx, p, origin, api.LBPoolOriginState{Address: ip, Enabled: false}).Return(true)
+				p.EXPECT().Noticef(pp.EmojiClear, "Disabled the origin %s", "account/pool:home")
+			},
+		},
+		{
+			name: "disabled/response-noop",
+			resp: setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(api.LBPoolOriginState{Address: ip, Enabled: false}, true)
+				p.EXPECT().Infof(pp.EmojiAlreadyDone, "The origin %s was already disabled", "account/pool:home")
+			},
+		},
+		{
+			name: "read-failed/response-failed",
+			resp: setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetLBPoolOrigin(ctx, p, origin).Return(api.LBPoolOriginState{}, false)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.FinalDisableLBPoolOrigin(ctx, h.mockPP, origin)
+			require.Equal(t, tc.resp, resp)
+		})
+	}
+}
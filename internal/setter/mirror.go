@@ -0,0 +1,80 @@
+package setter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// mirroredSetter reconciles DNS records with a primary setter and then with
+// a record setter for another DNS authority. Everything else only goes to the
+// primary setter.
+type mirroredSetter struct {
+	Setter
+
+	mirror            RecordSetter
+	mirrorDescription string
+}
+
+// NewMirrored creates a Setter that also reconciles the DNS records of the same
+// domains with mirror, so that both authorities converge. mirrorDescription
+// (such as "the RFC 2136 server ns.example.org:53") is used in messages and plans.
+//
+// The mirror is reconciled even if the primary setter failed, and the worse
+// response is returned. The changes made by the mirror are reported in
+// [Changes.Mirrors] under mirrorDescription.
+func NewMirrored(primary Setter, mirror RecordSetter, mirrorDescription string) Setter {
+	return mirroredSetter{Setter: primary, mirror: mirror, mirrorDescription: mirrorDescription}
+}
+
+// SetIPs sets the IP addresses of one domain with both setters.
+func (s mirroredSetter) SetIPs(ctx context.Context, ppfmt pp.PP,
+	ipFamily ipnet.Family, domain domain.Domain, ips []netip.Addr,
+	fallbackParams api.RecordParams,
+) ResponseCode {
+	resp := s.Setter.SetIPs(ctx, ppfmt, ipFamily, domain, ips, fallbackParams)
+
+	ppfmt.Infof(pp.EmojiUpdate, "Reconciling the %s records for %s with %s",
+		ipFamily.RecordType(), domain.Describe(), s.mirrorDescription)
+	return max(resp, s.mirror.SetIPs(ctx, ppfmt.Indent(), ipFamily, domain, ips, fallbackParams))
+}
+
+// FinalDelete deletes the DNS records of one domain with both setters.
+func (s mirroredSetter) FinalDelete(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family,
+	domain domain.Domain, fallbackParams api.RecordParams,
+) ResponseCode {
+	resp := s.Setter.FinalDelete(ctx, ppfmt, ipFamily, domain, fallbackParams)
+
+	ppfmt.Infof(pp.EmojiClear, "Deleting the %s records for %s from %s",
+		ipFamily.RecordType(), domain.Describe(), s.mirrorDescription)
+	return max(resp, s.mirror.FinalDelete(ctx, ppfmt.Indent(), ipFamily, domain, fallbackParams))
+}
+
+// TakePlan returns the changes planned by both setters. The subjects of the
+// changes planned by the mirror mention the mirror.
+func (s mirroredSetter) TakePlan() []api.PlannedChange {
+	plan := s.Setter.TakePlan()
+	for _, change := range s.mirror.TakePlan() {
+		plan = append(plan, api.PlannedChange{
+			Subject: fmt.Sprintf("%s on %s", change.Subject, s.mirrorDescription),
+			Action:  change.Action,
+		})
+	}
+	return plan
+}
+
+// TakeChanges returns the changes made by both setters. The changes made to
+// the DNS records by the mirror are kept apart in [Changes.Mirrors].
+func (s mirroredSetter) TakeChanges() Changes {
+	changes := s.Setter.TakeChanges()
+	mirrored := s.mirror.TakeChanges()
+	maps.Copy(changes.Mirrors, mirrored.Mirrors)
+	changes.Mirrors[s.mirrorDescription] = mirrored.Records
+	return changes
+}
@@ -0,0 +1,144 @@
+package setter_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+const mirrorDescription = "the RFC 2136 server ns.example.org:53"
+
+func TestMirroredSetIPs(t *testing.T) {
+	t.Parallel()
+
+	domain := domain.FQDN("sub.test.org")
+	ips := []netip.Addr{netip.MustParseAddr("::1")}
+	params := api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil}
+
+	for name, tc := range map[string]struct {
+		primary setter.ResponseCode
+		mirror  setter.ResponseCode
+		resp    setter.ResponseCode
+	}{
+		"noop/noop":       {setter.ResponseNoop, setter.ResponseNoop, setter.ResponseNoop},
+		"noop/updated":    {setter.ResponseNoop, setter.ResponseUpdated, setter.ResponseUpdated},
+		"updated/noop":    {setter.ResponseUpdated, setter.ResponseNoop, setter.ResponseUpdated},
+		"failed/updated":  {setter.ResponseFailed, setter.ResponseUpdated, setter.ResponseFailed},
+		"updated/failed":  {setter.ResponseUpdated, setter.ResponseFailed, setter.ResponseFailed},
+		"updating/failed": {setter.ResponseUpdating, setter.ResponseFailed, setter.ResponseFailed},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			innerPP := mocks.NewMockPP(mockCtrl)
+			primary := mocks.NewMockSetter(mockCtrl)
+			mirror := mocks.NewMockRecordSetter(mockCtrl)
+			ctx := context.Background()
+
+			gomock.InOrder(
+				primary.EXPECT().SetIPs(ctx, mockPP, ipnet.IP6, domain, ips, params).Return(tc.primary),
+				mockPP.EXPECT().Infof(pp.EmojiUpdate, "Reconciling the %s records for %s with %s",
+					"AAAA", "sub.test.org", mirrorDescription),
+				mockPP.EXPECT().Indent().Return(innerPP),
+				mirror.EXPECT().SetIPs(ctx, innerPP, ipnet.IP6, domain, ips, params).Return(tc.mirror),
+			)
+
+			s := setter.NewMirrored(primary, mirror, mirrorDescription)
+			require.Equal(t, tc.resp, s.SetIPs(ctx, mockPP, ipnet.IP6, domain, ips, params))
+		})
+	}
+}
+
+func TestMirroredFinalDelete(t *testing.T) {
+	t.Parallel()
+
+	domain := domain.FQDN("sub.test.org")
+	params := api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil}
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	innerPP := mocks.NewMockPP(mockCtrl)
+	primary := mocks.NewMockSetter(mockCtrl)
+	mirror := mocks.NewMockRecordSetter(mockCtrl)
+	ctx := context.Background()
+
+	gomock.InOrder(
+		primary.EXPECT().FinalDelete(ctx, mockPP, ipnet.IP4, domain, params).Return(setter.ResponseNoop),
+		mockPP.EXPECT().Infof(pp.EmojiClear, "Deleting the %s records for %s from %s",
+			"A", "sub.test.org", mirrorDescription),
+		mockPP.EXPECT().Indent().Return(innerPP),
+		mirror.EXPECT().FinalDelete(ctx, innerPP, ipnet.IP4, domain, params).Return(setter.ResponseUpdated),
+	)
+
+	s := setter.NewMirrored(primary, mirror, mirrorDescription)
+	require.Equal(t, setter.ResponseUpdated, s.FinalDelete(ctx, mockPP, ipnet.IP4, domain, params))
+}
+
+func TestMirroredOthersOnlyUsePrimary(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+	primary := mocks.NewMockSetter(mockCtrl)
+	mirror := mocks.NewMockRecordSetter(mockCtrl)
+	ctx := context.Background()
+	list := api.WAFList{AccountID: "account", Name: "list"}
+	targets := map[ipnet.Family]setter.WAFTargets{ipnet.IP4: setter.NewAvailableWAFTargets(nil)}
+
+	primary.EXPECT().SetWAFList(ctx, mockPP, list, "description", targets, "comment").Return(setter.ResponseUpdated)
+
+	s := setter.NewMirrored(primary, mirror, mirrorDescription)
+	require.Equal(t, setter.ResponseUpdated, s.SetWAFList(ctx, mockPP, list, "description", targets, "comment"))
+}
+
+func TestMirroredTakePlanAndChanges(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	primary := mocks.NewMockSetter(mockCtrl)
+	mirror := mocks.NewMockRecordSetter(mockCtrl)
+	scope := setter.RecordScope{IPFamily: ipnet.IP4, Domain: domain.FQDN("sub.test.org")}
+	record := api.Record{
+		ID:           "1.1.1.1",
+		IP:           netip.MustParseAddr("1.1.1.1"),
+		RecordParams: api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: "", Tags: nil},
+	}
+	primaryChanges := setter.Changes{ //nolint:exhaustruct
+		Records: map[setter.RecordScope]setter.RecordChanges{scope: {Matched: []api.Record{record}}}, //nolint:exhaustruct
+		Mirrors: map[string]map[setter.RecordScope]setter.RecordChanges{},
+	}
+	mirrorChanges := setter.Changes{ //nolint:exhaustruct
+		Records: map[setter.RecordScope]setter.RecordChanges{scope: {Created: []api.Record{record}}}, //nolint:exhaustruct
+		Mirrors: map[string]map[setter.RecordScope]setter.RecordChanges{},
+	}
+
+	primary.EXPECT().TakePlan().Return([]api.PlannedChange{
+		{Subject: "sub.test.org", Action: "add an A record for 1.1.1.1"},
+	})
+	mirror.EXPECT().TakePlan().Return([]api.PlannedChange{
+		{Subject: "sub.test.org", Action: "add an A record for 1.1.1.1"},
+	})
+	primary.EXPECT().TakeChanges().Return(primaryChanges)
+	mirror.EXPECT().TakeChanges().Return(mirrorChanges)
+
+	s := setter.NewMirrored(primary, mirror, mirrorDescription)
+	require.Equal(t, []api.PlannedChange{
+		{Subject: "sub.test.org", Action: "add an A record for 1.1.1.1"},
+		{Subject: "sub.test.org on " + mirrorDescription, Action: "add an A record for 1.1.1.1"},
+	}, s.TakePlan())
+	require.Equal(t, setter.Changes{ //nolint:exhaustruct
+		Records: primaryChanges.Records,
+		Mirrors: map[string]map[setter.RecordScope]setter.RecordChanges{mirrorDescription: mirrorChanges.Records},
+	}, s.TakeChanges())
+}
@@ -0,0 +1,96 @@
+package setter
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// keepPrefixesOfOtherFamilies keeps the prefixes whose families are not replaced.
+func keepPrefixesOfOtherFamilies(prefixes []netip.Prefix, replaced map[ipnet.Family]bool) []netip.Prefix {
+	kept := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		keep := true
+		for ipFamily := range ipnet.All {
+			if replaced[ipFamily] && ipFamily.Matches(prefix.Addr()) {
+				keep = false
+			}
+		}
+		if keep {
+			kept = append(kept, prefix)
+		}
+	}
+	return kept
+}
+
+// replacePrefixesOfFamilies replaces the prefixes of the families with usable
+// targets and keeps the others, following the contract of [Setter.SetWAFList].
+// The result is sorted and deduplicated.
+func replacePrefixesOfFamilies(prefixes []netip.Prefix, targetsByFamily map[ipnet.Family]WAFTargets,
+) []netip.Prefix {
+	replaced := map[ipnet.Family]bool{}
+	for ipFamily, targets := range ipnet.Bindings(targetsByFamily) {
+		replaced[ipFamily] = targets.HasUsableTargets()
+	}
+
+	desired := keepPrefixesOfOtherFamilies(prefixes, replaced)
+	for ipFamily, targets := range ipnet.Bindings(targetsByFamily) {
+		if replaced[ipFamily] {
+			for _, prefix := range targets.Prefixes {
+				desired = append(desired, prefix.Masked())
+			}
+		}
+	}
+	slices.SortFunc(desired, netip.Prefix.Compare)
+	return slices.Compact(desired)
+}
+
+// prefixResource is a resource whose IP ranges are read and written as a whole,
+// such as the networks of a Gateway location or the "ip" include rules of an
+// Access group.
+type prefixResource struct {
+	kind        string // for example, "Gateway location"
+	name        string // the description of the resource
+	rangesLabel string // for example, "networks"
+	list        func(ctx context.Context, ppfmt pp.PP) ([]netip.Prefix, bool)
+	set         func(ctx context.Context, ppfmt pp.PP, prefixes []netip.Prefix) bool
+	record      func(previous, current []netip.Prefix)
+}
+
+// reconcilePrefixes reads the IP ranges of the resource, computes the desired ones
+// from them, writes them if they differ, and records the result in the journal.
+func (s setter) reconcilePrefixes(ctx context.Context, ppfmt pp.PP, r prefixResource,
+	desire func(current []netip.Prefix) []netip.Prefix,
+) ResponseCode {
+	current, ok := r.list(ctx, ppfmt)
+	if !ok {
+		return ResponseFailed
+	}
+	slices.SortFunc(current, netip.Prefix.Compare)
+	desired := desire(current)
+
+	if slices.Equal(current, desired) {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The %s %s is already up to date", r.kind, r.name)
+		r.record(current, current)
+		return ResponseNoop
+	}
+
+	if !r.set(ctx, ppfmt, desired) {
+		ppfmt.Noticef(pp.EmojiError,
+			"Could not confirm update of the %s %s; its %s may be inconsistent", r.kind, r.name, r.rangesLabel)
+		r.record(current, current)
+		return ResponseFailed
+	}
+
+	ranges := pp.EnglishJoinMapOrEmptyLabel(netip.Prefix.String, desired, "(none)")
+	if s.DryRun {
+		ppfmt.Noticef(pp.EmojiUpdate, "Would set the %s of the %s %s to %s", r.rangesLabel, r.kind, r.name, ranges)
+	} else {
+		ppfmt.Noticef(pp.EmojiUpdate, "Set the %s of the %s %s to %s", r.rangesLabel, r.kind, r.name, ranges)
+	}
+	r.record(current, desired)
+	return ResponseUpdated
+}
@@ -14,7 +14,18 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
+// recordSetter reconciles DNS records through a record-only handle.
+type recordSetter struct {
+	Records api.RecordHandle
+	// DryRun means the handle only records writes, so messages should say what would happen.
+	DryRun bool
+
+	journal *journal
+}
+
 type setter struct {
+	recordSetter
+
 	Handle api.Handle
 }
 
@@ -52,9 +63,20 @@ func (w ambiguityWarnings) warnDuplicateCanonicalTags(ppfmt pp.PP, unit string)
 	)
 }
 
+func newRecordSetter(handle api.RecordHandle) recordSetter {
+	_, dryRun := handle.(api.Planner)
+	return recordSetter{Records: handle, DryRun: dryRun, journal: newJournal()}
+}
+
 // New creates a new Setter against one handle-bound ownership scope.
 func New(_ppfmt pp.PP, handle api.Handle) Setter {
-	return setter{Handle: handle}
+	return setter{recordSetter: newRecordSetter(handle), Handle: handle}
+}
+
+// NewRecordSetter creates a new RecordSetter that only reconciles DNS records,
+// such as the one for a mirror of the DNS records.
+func NewRecordSetter(_ppfmt pp.PP, handle api.RecordHandle) RecordSetter {
+	return newRecordSetter(handle)
 }
 
 // record represents a DNS record in this package.
@@ -98,6 +120,21 @@ func partitionRecords(
 	return matched, unmatched, outdated
 }
 
+// matchedRecords returns the records that are not outdated, keeping their order.
+func matchedRecords(rs []api.Record, outdated []record) []api.Record {
+	outdatedIDs := make(map[api.ID]bool, len(outdated))
+	for _, r := range outdated {
+		outdatedIDs[r.ID] = true
+	}
+	matched := make([]api.Record, 0, len(rs)-len(outdated))
+	for _, r := range rs {
+		if !outdatedIDs[r.ID] {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
 func recordsAlreadyUpToDate(targets []netip.Addr, matched map[netip.Addr][]record, outdated []record) bool {
 	if len(outdated) != 0 {
 		return false
@@ -230,7 +267,7 @@ func reconcileAndSortRecords(
 // Provider output currently reaches this function through an address-only
 // specialization of the raw-data model.
 // The inputs are assumed to satisfy [Setter.SetIPs] invariants.
-func (s setter) SetIPs(ctx context.Context, ppfmt pp.PP,
+func (s recordSetter) SetIPs(ctx context.Context, ppfmt pp.PP,
 	ipFamily ipnet.Family, domain domain.Domain, ips []netip.Addr,
 	fallbackParams api.RecordParams,
 ) ResponseCode {
@@ -238,12 +275,20 @@ func (s setter) SetIPs(ctx context.Context, ppfmt pp.PP,
 	domainDescription := domain.Describe()
 	targets := ips
 
-	rs, cached, ok := s.Handle.ListRecords(ctx, ppfmt, ipFamily, domain, fallbackParams)
+	var changes RecordChanges
+	defer func() { s.journal.setRecords(RecordScope{IPFamily: ipFamily, Domain: domain}, changes) }()
+
+	rs, cached, ok := s.Records.ListRecords(ctx, ppfmt, ipFamily, domain, fallbackParams)
 	if !ok {
 		return ResponseFailed
 	}
+	recordByID := make(map[api.ID]api.Record, len(rs))
+	for _, r := range rs {
+		recordByID[r.ID] = r
+	}
 
 	matchedByIP, unmatchedTargets, outdatedRecords := partitionRecords(targets, rs)
+	changes.Matched = matchedRecords(rs, outdatedRecords)
 
 	// If records already satisfy all desired targets and no outdated managed records
 	// remain, we are done. Matching duplicates are tolerated residue.
@@ -283,7 +328,7 @@ func (s setter) SetIPs(ctx context.Context, ppfmt pp.PP,
 			recycled := outdatedRecords[0]
 			outdatedRecords = outdatedRecords[1:]
 			mutated = true
-			if ok := s.Handle.UpdateRecord(ctx, ppfmt, ipFamily, domain, recycled.ID, target,
+			if ok := s.Records.UpdateRecord(ctx, ppfmt, ipFamily, domain, recycled.ID, target,
 				resolvedParamsForNewTargets,
 			); !ok {
 				ppfmt.Noticef(pp.EmojiError,
@@ -291,36 +336,51 @@ func (s setter) SetIPs(ctx context.Context, ppfmt pp.PP,
 					recordType, domainDescription)
 				return ResponseFailed
 			}
-			ppfmt.Noticef(pp.EmojiUpdate,
-				"Updated an outdated %s record for %s (ID: %s)",
-				recordType, domainDescription, recycled.ID)
+			changes.Updated = append(changes.Updated,
+				api.Record{ID: recycled.ID, IP: target, RecordParams: resolvedParamsForNewTargets})
+			if s.DryRun {
+				ppfmt.Noticef(pp.EmojiUpdate,
+					"Would update an outdated %s record for %s to %s (ID: %s)",
+					recordType, domainDescription, target, recycled.ID)
+			} else {
+				ppfmt.Noticef(pp.EmojiUpdate,
+					"Updated an outdated %s record for %s (ID: %s)",
+					recordType, domainDescription, recycled.ID)
+			}
 			continue
 		}
 
 		mutated = true
-		id, ok := s.Handle.CreateRecord(ctx, ppfmt, ipFamily, domain, target, resolvedParamsForNewTargets)
+		id, ok := s.Records.CreateRecord(ctx, ppfmt, ipFamily, domain, target, resolvedParamsForNewTargets)
 		if !ok {
 			ppfmt.Noticef(pp.EmojiError,
 				"Could not confirm update of %s records for %s; the records might be inconsistent",
 				recordType, domainDescription)
 			return ResponseFailed
 		}
-		ppfmt.Noticef(pp.EmojiCreation,
-			"Added a new %s record for %s (ID: %s)", recordType, domainDescription, id)
+		changes.Created = append(changes.Created,
+			api.Record{ID: id, IP: target, RecordParams: resolvedParamsForNewTargets})
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiCreation,
+				"Would add a new %s record for %s with %s", recordType, domainDescription, target)
+		} else {
+			ppfmt.Noticef(pp.EmojiCreation,
+				"Added a new %s record for %s (ID: %s)", recordType, domainDescription, id)
+		}
 	}
 
 	// Stage 2: delete outdated/out-of-target leftovers.
 	for _, r := range outdatedRecords {
 		mutated = true
-		if ok := s.Handle.DeleteRecord(ctx, ppfmt, ipFamily, domain, r.ID, api.RegularDeletionMode); !ok {
+		if ok := s.Records.DeleteRecord(ctx, ppfmt, ipFamily, domain, r.ID, api.RegularDeletionMode); !ok {
 			ppfmt.Noticef(pp.EmojiError,
 				"Could not confirm update of %s records for %s; the records might be inconsistent",
 				recordType, domainDescription)
 			return ResponseFailed
 		}
 
-		ppfmt.Noticef(pp.EmojiDeletion,
-			"Deleted an outdated %s record for %s (ID: %s)", recordType, domainDescription, r.ID)
+		changes.Deleted = append(changes.Deleted, recordByID[r.ID])
+		s.reportRecordDeletion(ppfmt, recordType, domainDescription, r.ID)
 	}
 
 	if !mutated {
@@ -330,14 +390,27 @@ func (s setter) SetIPs(ctx context.Context, ppfmt pp.PP,
 	return ResponseUpdated
 }
 
+func (s recordSetter) reportRecordDeletion(ppfmt pp.PP, recordType, domainDescription string, id api.ID) {
+	if s.DryRun {
+		ppfmt.Noticef(pp.EmojiDeletion,
+			"Would delete an outdated %s record for %s (ID: %s)", recordType, domainDescription, id)
+	} else {
+		ppfmt.Noticef(pp.EmojiDeletion,
+			"Deleted an outdated %s record for %s (ID: %s)", recordType, domainDescription, id)
+	}
+}
+
 // FinalDelete deletes all managed DNS records.
-func (s setter) FinalDelete(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
+func (s recordSetter) FinalDelete(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Family, domain domain.Domain,
 	fallbackParams api.RecordParams,
 ) ResponseCode {
 	recordType := ipFamily.RecordType()
 	domainDescription := domain.Describe()
 
-	rs, cached, ok := s.Handle.ListRecords(ctx, ppfmt, ipFamily, domain, fallbackParams)
+	var changes RecordChanges
+	defer func() { s.journal.setRecords(RecordScope{IPFamily: ipFamily, Domain: domain}, changes) }()
+
+	rs, cached, ok := s.Records.ListRecords(ctx, ppfmt, ipFamily, domain, fallbackParams)
 	if !ok {
 		return ResponseFailed
 	}
@@ -358,8 +431,8 @@ func (s setter) FinalDelete(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Fam
 	}
 
 	allOK := true
-	for _, id := range unmatchedIDs {
-		if !s.Handle.DeleteRecord(ctx, ppfmt, ipFamily, domain, id, api.FinalDeletionMode) {
+	for i, id := range unmatchedIDs {
+		if !s.Records.DeleteRecord(ctx, ppfmt, ipFamily, domain, id, api.FinalDeletionMode) {
 			allOK = false
 
 			if ctx.Err() != nil {
@@ -371,7 +444,8 @@ func (s setter) FinalDelete(ctx context.Context, ppfmt pp.PP, ipFamily ipnet.Fam
 			continue
 		}
 
-		ppfmt.Noticef(pp.EmojiDeletion, "Deleted an outdated %s record for %s (ID: %s)", recordType, domainDescription, id)
+		changes.Deleted = append(changes.Deleted, rs[i])
+		s.reportRecordDeletion(ppfmt, recordType, domainDescription, id)
 	}
 	if !allOK {
 		ppfmt.Noticef(pp.EmojiError,
@@ -403,6 +477,9 @@ func (s setter) SetWAFList(ctx context.Context, ppfmt pp.PP,
 		deleteItems    []api.WAFListItem
 	}
 
+	var changes WAFListChanges
+	defer func() { s.journal.setWAFList(list, changes) }()
+
 	items, _, cached, ok := s.Handle.ListWAFListItems(
 		ctx, ppfmt, list, listDescription, fallbackItemComment,
 	)
@@ -475,6 +552,12 @@ func (s setter) SetWAFList(ctx context.Context, ppfmt pp.PP,
 
 	itemsToCreateCount := len(plans[ipnet.IP4].createPrefixes) + len(plans[ipnet.IP6].createPrefixes)
 
+	for _, item := range items {
+		if !slices.ContainsFunc(itemsToDelete, func(i api.WAFListItem) bool { return i.ID == item.ID }) {
+			changes.Matched = append(changes.Matched, item.Prefix)
+		}
+	}
+
 	if itemsToCreateCount == 0 && len(itemsToDelete) == 0 {
 		if cached {
 			ppfmt.Infof(pp.EmojiAlreadyDone, "The list %s is already up to date (cached)", list.Describe())
@@ -506,8 +589,14 @@ func (s setter) SetWAFList(ctx context.Context, ppfmt pp.PP,
 			return ResponseFailed
 		}
 		for _, item := range itemsToCreate {
-			ppfmt.Noticef(pp.EmojiCreation, "Added %s to the list %s",
-				item.Prefix.Masked().String(), list.Describe())
+			changes.Created = append(changes.Created, item.Prefix.Masked())
+			if s.DryRun {
+				ppfmt.Noticef(pp.EmojiCreation, "Would add %s to the list %s",
+					item.Prefix.Masked().String(), list.Describe())
+			} else {
+				ppfmt.Noticef(pp.EmojiCreation, "Added %s to the list %s",
+					item.Prefix.Masked().String(), list.Describe())
+			}
 		}
 	}
 
@@ -521,8 +610,14 @@ func (s setter) SetWAFList(ctx context.Context, ppfmt pp.PP,
 		return ResponseFailed
 	}
 	for _, item := range itemsToDelete {
-		ppfmt.Noticef(pp.EmojiDeletion, "Deleted %s from the list %s",
-			item.Prefix.Masked().String(), list.Describe())
+		changes.Deleted = append(changes.Deleted, item.Prefix)
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiDeletion, "Would delete %s from the list %s",
+				item.Prefix.Masked().String(), list.Describe())
+		} else {
+			ppfmt.Noticef(pp.EmojiDeletion, "Deleted %s from the list %s",
+				item.Prefix.Masked().String(), list.Describe())
+		}
 	}
 
 	return ResponseUpdated
@@ -539,6 +634,9 @@ func prefixContainsPrefix(container, target netip.Prefix) bool {
 func (s setter) FinalClearWAFList(ctx context.Context, ppfmt pp.PP, list api.WAFList, listDescription string,
 	managedFamilies map[ipnet.Family]bool,
 ) ResponseCode {
+	// The handle decides what to delete, so only the response is known here.
+	s.journal.setWAFList(list, WAFListChanges{Matched: nil, Created: nil, Deleted: nil})
+
 	switch s.Handle.FinalCleanWAFList(ctx, ppfmt, list, listDescription, managedFamilies) {
 	case api.WAFListCleanupNoop:
 		return ResponseNoop
@@ -552,3 +650,23 @@ func (s setter) FinalClearWAFList(ctx context.Context, ppfmt pp.PP, list api.WAF
 		return ResponseFailed
 	}
 }
+
+// CheckPermissions checks the credentials of the handle before any update.
+func (s setter) CheckPermissions(ctx context.Context, ppfmt pp.PP,
+	domains []domain.Domain, lists []api.WAFList,
+) api.PermissionReport {
+	return s.Handle.CheckPermissions(ctx, ppfmt, domains, lists)
+}
+
+// TakePlan returns the changes planned since the last call in dry-run mode.
+func (s recordSetter) TakePlan() []api.PlannedChange {
+	if h, ok := s.Records.(api.Planner); ok {
+		return h.TakePlan()
+	}
+	return nil
+}
+
+// TakeChanges returns the changes made since the last call and forgets them.
+func (s recordSetter) TakeChanges() Changes {
+	return s.journal.take()
+}
@@ -1,6 +1,7 @@
 package setter
 
 import (
+	"context"
 	"net/netip"
 	"slices"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
@@ -301,3 +303,148 @@ The 3 outdated AAAA records of sub.test.org disagree on tags; will use common se
 		},
 	}, nonMatching)
 }
+
+func TestReplacePrefixesOfFamilies(t *testing.T) {
+	t.Parallel()
+
+	prefix4a := netip.MustParsePrefix("192.0.2.1/32")
+	prefix4b := netip.MustParsePrefix("198.51.100.0/24")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+
+	for name, tc := range map[string]struct {
+		current  []netip.Prefix
+		targets  map[ipnet.Family]WAFTargets
+		expected []netip.Prefix
+	}{
+		"keep-other-family": {
+			[]netip.Prefix{prefix4a, prefix6},
+			map[ipnet.Family]WAFTargets{ipnet.IP4: NewAvailableWAFTargets([]netip.Prefix{prefix4b})},
+			[]netip.Prefix{prefix4b, prefix6},
+		},
+		"keep-unavailable": {
+			[]netip.Prefix{prefix6},
+			map[ipnet.Family]WAFTargets{
+				ipnet.IP4: NewAvailableWAFTargets([]netip.Prefix{prefix4b, prefix4a, prefix4a}),
+				ipnet.IP6: NewUnavailableWAFTargets(),
+			},
+			[]netip.Prefix{prefix4a, prefix4b, prefix6},
+		},
+		"explicit-empty": {
+			[]netip.Prefix{prefix4a},
+			map[ipnet.Family]WAFTargets{ipnet.IP4: NewAvailableWAFTargets(nil)},
+			[]netip.Prefix{},
+		},
+		"masked": {
+			nil,
+			map[ipnet.Family]WAFTargets{ipnet.IP6: NewAvailableWAFTargets([]netip.Prefix{
+				netip.MustParsePrefix("2001:db8::1/64"),
+			})},
+			[]netip.Prefix{prefix6},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, replacePrefixesOfFamilies(tc.current, tc.targets))
+		})
+	}
+}
+
+func TestKeepPrefixesOfOtherFamilies(t *testing.T) {
+	t.Parallel()
+
+	prefix4 := netip.MustParsePrefix("192.0.2.1/32")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+
+	require.Equal(t, []netip.Prefix{prefix6},
+		keepPrefixesOfOtherFamilies([]netip.Prefix{prefix4, prefix6}, map[ipnet.Family]bool{ipnet.IP4: true}))
+	require.Equal(t, []netip.Prefix{prefix4, prefix6},
+		keepPrefixesOfOtherFamilies([]netip.Prefix{prefix4, prefix6}, map[ipnet.Family]bool{ipnet.IP4: false}))
+}
+
+func TestReconcilePrefixes(t *testing.T) {
+	t.Parallel()
+
+	prefix4 := netip.MustParsePrefix("192.0.2.1/32")
+	prefix6 := netip.MustParsePrefix("2001:db8::/64")
+	replace4 := func(current []netip.Prefix) []netip.Prefix {
+		return replacePrefixesOfFamilies(current, map[ipnet.Family]WAFTargets{
+			ipnet.IP4: NewAvailableWAFTargets([]netip.Prefix{prefix4}),
+		})
+	}
+
+	type recorded struct{ previous, current []netip.Prefix }
+
+	for name, tc := range map[string]struct {
+		dryRun   bool
+		listed   []netip.Prefix
+		listOK   bool
+		setOK    bool
+		resp     ResponseCode
+		written  []netip.Prefix
+		recorded []recorded
+		output   string
+	}{
+		"up-to-date": {
+			false, []netip.Prefix{prefix6, prefix4}, true, true,
+			ResponseNoop, nil,
+			[]recorded{{[]netip.Prefix{prefix4, prefix6}, []netip.Prefix{prefix4, prefix6}}},
+			"The thing account/office is already up to date\n",
+		},
+		"updated": {
+			false, []netip.Prefix{prefix6}, true, true,
+			ResponseUpdated, []netip.Prefix{prefix4, prefix6},
+			[]recorded{{[]netip.Prefix{prefix6}, []netip.Prefix{prefix4, prefix6}}},
+			"Set the ranges of the thing account/office to 192.0.2.1/32 and 2001:db8::/64\n",
+		},
+		"dry-run": {
+			true, []netip.Prefix{prefix6}, true, true,
+			ResponseUpdated, []netip.Prefix{prefix4, prefix6},
+			[]recorded{{[]netip.Prefix{prefix6}, []netip.Prefix{prefix4, prefix6}}},
+			"Would set the ranges of the thing account/office to 192.0.2.1/32 and 2001:db8::/64\n",
+		},
+		"list-failed": {
+			false, nil, false, true,
+			ResponseFailed, nil,
+			nil,
+			"",
+		},
+		"set-failed": {
+			false, []netip.Prefix{prefix6}, true, false,
+			ResponseFailed, []netip.Prefix{prefix4, prefix6},
+			[]recorded{{[]netip.Prefix{prefix6}, []netip.Prefix{prefix6}}},
+			"Could not confirm update of the thing account/office; its ranges may be inconsistent\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf strings.Builder
+			ppfmt := pp.New(&buf, false, pp.Verbose)
+			s := setter{recordSetter: newRecordSetter(nil), Handle: nil}
+			s.DryRun = tc.dryRun
+
+			var written []netip.Prefix
+			var records []recorded
+			resource := prefixResource{
+				kind:        "thing",
+				name:        "account/office",
+				rangesLabel: "ranges",
+				list: func(context.Context, pp.PP) ([]netip.Prefix, bool) {
+					return slices.Clone(tc.listed), tc.listOK
+				},
+				set: func(_ context.Context, _ pp.PP, prefixes []netip.Prefix) bool {
+					written = prefixes
+					return tc.setOK
+				},
+				record: func(previous, current []netip.Prefix) {
+					records = append(records, recorded{previous, current})
+				},
+			}
+
+			require.Equal(t, tc.resp, s.reconcilePrefixes(context.Background(), ppfmt, resource, replace4))
+			require.Equal(t, tc.written, written)
+			require.Equal(t, tc.recorded, records)
+			require.Equal(t, tc.output, buf.String())
+		})
+	}
+}
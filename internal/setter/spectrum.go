@@ -0,0 +1,59 @@
+package setter
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// SetSpectrumApp rewrites the addresses of the direct origins of a Spectrum
+// application. The scheme and the port of each origin are kept.
+//
+// An origin that already uses one of the targets is kept as it is, so that an
+// origin is not moved between equally valid addresses.
+func (s setter) SetSpectrumApp(ctx context.Context, ppfmt pp.PP,
+	app api.SpectrumApp, targetsByFamily map[ipnet.Family][]netip.Addr,
+) ResponseCode {
+	current, ok := s.Handle.GetSpectrumAppOrigins(ctx, ppfmt, app)
+	if !ok {
+		return ResponseFailed
+	}
+
+	desired := make([]api.SpectrumOrigin, 0, len(current))
+	for _, origin := range current {
+		ipFamily := ipnet.IP6
+		if origin.Address.Is4() {
+			ipFamily = ipnet.IP4
+		}
+		if targets := targetsByFamily[ipFamily]; len(targets) > 0 && !slices.Contains(targets, origin.Address) {
+			origin.Address = targets[0]
+		}
+		desired = append(desired, origin)
+	}
+
+	if slices.Equal(current, desired) {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The Spectrum app %s is already up to date", app.Describe())
+		s.journal.setSpectrumApp(app, SpectrumAppChanges{Previous: current, Current: current})
+		return ResponseNoop
+	}
+
+	if !s.Handle.SetSpectrumAppOrigins(ctx, ppfmt, app, desired) {
+		ppfmt.Noticef(pp.EmojiError,
+			"Could not confirm update of the Spectrum app %s; its origins may be inconsistent", app.Describe())
+		s.journal.setSpectrumApp(app, SpectrumAppChanges{Previous: current, Current: current})
+		return ResponseFailed
+	}
+
+	origins := pp.EnglishJoinMapOrEmptyLabel(api.SpectrumOrigin.String, desired, "(none)")
+	if s.DryRun {
+		ppfmt.Noticef(pp.EmojiUpdate, "Would set the origins of the Spectrum app %s to %s", app.Describe(), origins)
+	} else {
+		ppfmt.Noticef(pp.EmojiUpdate, "Set the origins of the Spectrum app %s to %s", app.Describe(), origins)
+	}
+	s.journal.setSpectrumApp(app, SpectrumAppChanges{Previous: current, Current: desired})
+	return ResponseUpdated
+}
@@ -0,0 +1,128 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestSetSpectrumApp(t *testing.T) {
+	t.Parallel()
+
+	app := api.SpectrumApp{ZoneID: "zone", AppID: "app"}
+	ip4a := netip.MustParseAddr("192.0.2.1")
+	ip4b := netip.MustParseAddr("198.51.100.8")
+	ip6 := netip.MustParseAddr("2001:db8::1")
+	origin := func(scheme string, ip netip.Addr, port string) api.SpectrumOrigin {
+		return api.SpectrumOrigin{Scheme: scheme, Address: ip, Port: port}
+	}
+	origins := func(os ...api.SpectrumOrigin) []api.SpectrumOrigin { return os }
+	addrs := func(ips ...netip.Addr) []netip.Addr { return ips }
+
+	cases := []struct {
+		name         string
+		targets      map[ipnet.Family][]netip.Addr
+		resp         setter.ResponseCode
+		changes      map[api.SpectrumApp]setter.SpectrumAppChanges
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name:    "up-to-date/response-noop",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: addrs(ip4b, ip4a)},
+			resp:    setter.ResponseNoop,
+			changes: map[api.SpectrumApp]setter.SpectrumAppChanges{app: {
+				Previous: origins(origin("tcp", ip4a, "22")),
+				Current:  origins(origin("tcp", ip4a, "22")),
+			}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetSpectrumAppOrigins(ctx, p, app).Return(origins(origin("tcp", ip4a, "22")), true),
+					p.EXPECT().Infof(pp.EmojiAlreadyDone, "The Spectrum app %s is already up to date", "zone/app"),
+				)
+			},
+		},
+		{
+			name:    "outdated/keep-port/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: addrs(ip4b)},
+			resp:    setter.ResponseUpdated,
+			changes: map[api.SpectrumApp]setter.SpectrumAppChanges{app: {
+				Previous: origins(origin("tcp", ip4a, "22"), origin("udp", ip6, "27015-27020")),
+				Current:  origins(origin("tcp", ip4b, "22"), origin("udp", ip6, "27015-27020")),
+			}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetSpectrumAppOrigins(ctx, p, app).Return(
+						origins(origin("tcp", ip4a, "22"), origin("udp", ip6, "27015-27020")), true),
+					m.EXPECT().SetSpectrumAppOrigins(ctx, p, app,
+						origins(origin("tcp", ip4b, "22"), origin("udp", ip6, "27015-27020"))).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Set the origins of the Spectrum app %s to %s",
+						"zone/app", "tcp://198.51.100.8:22 and udp://[2001:db8::1]:27015-27020"),
+				)
+			},
+		},
+		{
+			name:    "empty-targets/keep/response-noop",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: addrs(), ipnet.IP6: addrs(ip6)},
+			resp:    setter.ResponseNoop,
+			changes: map[api.SpectrumApp]setter.SpectrumAppChanges{app: {
+				Previous: origins(origin("tcp", ip4a, "22")),
+				Current:  origins(origin("tcp", ip4a, "22")),
+			}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetSpectrumAppOrigins(ctx, p, app).Return(origins(origin("tcp", ip4a, "22")), true),
+					p.EXPECT().Infof(pp.EmojiAlreadyDone, "The Spectrum app %s is already up to date", "zone/app"),
+				)
+			},
+		},
+		{
+			name:    "read-failed/response-failed",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: addrs(ip4b)},
+			resp:    setter.ResponseFailed,
+			changes: map[api.SpectrumApp]setter.SpectrumAppChanges{},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetSpectrumAppOrigins(ctx, p, app).Return(nil, false)
+			},
+		},
+		{
+			name:    "set-failed/response-failed",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: addrs(ip4b)},
+			resp:    setter.ResponseFailed,
+			changes: map[api.SpectrumApp]setter.SpectrumAppChanges{app: {
+				Previous: origins(origin("tcp", ip4a, "22")),
+				Current:  origins(origin("tcp", ip4a, "22")),
+			}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetSpectrumAppOrigins(ctx, p, app).Return(origins(origin("tcp", ip4a, "22")), true),
+					m.EXPECT().SetSpectrumAppOrigins(ctx, p, app, origins(origin("tcp", ip4b, "22"))).Return(false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the Spectrum app %s; its origins may be inconsistent", "zone/app"),
+				)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.SetSpectrumApp(ctx, h.mockPP, app, tc.targets)
+			require.Equal(t, tc.resp, resp)
+			require.Equal(t, tc.changes, h.setter.TakeChanges().SpectrumApps)
+		})
+	}
+}
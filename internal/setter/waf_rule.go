@@ -0,0 +1,139 @@
+package setter
+
+import (
+	"context"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// ownedWAFCustomRules keeps the custom rules whose descriptions are exactly the marker.
+func ownedWAFCustomRules(rules []api.WAFCustomRule, description string) []api.WAFCustomRule {
+	var owned []api.WAFCustomRule
+	for _, r := range rules {
+		if r.Description == description {
+			owned = append(owned, r)
+		}
+	}
+	return owned
+}
+
+// deleteWAFCustomRules deletes the rules one by one. It returns false when any deletion failed.
+func (s setter) deleteWAFCustomRules(ctx context.Context, ppfmt pp.PP, rule api.WAFListRule, rulesetID api.ID,
+	rules []api.WAFCustomRule, description string, changes *WAFListRuleChanges,
+) bool {
+	for _, r := range rules {
+		if !s.Handle.DeleteWAFCustomRule(ctx, ppfmt, rule.ZoneID, rulesetID, r.ID) {
+			ppfmt.Noticef(pp.EmojiError,
+				"Could not confirm update of the custom rule %q in the zone %s; it may be inconsistent",
+				description, rule.ZoneID)
+			return false
+		}
+		changes.Deleted = append(changes.Deleted, r.ID)
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiDeletion, "Would delete the custom rule %q (%s) in the zone %s",
+				description, r.ID, rule.ZoneID)
+		} else {
+			ppfmt.Noticef(pp.EmojiDeletion, "Deleted the custom rule %q (%s) in the zone %s",
+				description, r.ID, rule.ZoneID)
+		}
+	}
+	return true
+}
+
+// SetWAFListRule keeps the first custom rule with the description, updates it
+// when its action, expression, or status is different, and deletes the others.
+// The rule is created when there is none.
+func (s setter) SetWAFListRule(ctx context.Context, ppfmt pp.PP, rule api.WAFListRule,
+	expression string, description string,
+) ResponseCode {
+	changes := WAFListRuleChanges{Matched: nil, Updated: nil, Created: nil, Deleted: nil}
+	defer func() { s.journal.setWAFListRule(rule, changes) }()
+
+	rulesetID, rules, ok := s.Handle.ListWAFCustomRules(ctx, ppfmt, rule.ZoneID)
+	if !ok {
+		return ResponseFailed
+	}
+
+	desired := api.WAFCustomRule{
+		ID:          "",
+		Action:      rule.Action,
+		Expression:  expression,
+		Description: description,
+		Enabled:     true,
+	}
+
+	owned := ownedWAFCustomRules(rules, description)
+	if len(owned) == 0 {
+		id, ok := s.Handle.CreateWAFCustomRule(ctx, ppfmt, rule.ZoneID, rulesetID, desired)
+		if !ok {
+			ppfmt.Noticef(pp.EmojiError,
+				"Could not confirm update of the custom rule %q in the zone %s; it may be inconsistent",
+				description, rule.ZoneID)
+			return ResponseFailed
+		}
+		changes.Created = append(changes.Created, id)
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiCreation, "Would create the custom rule %q in the zone %s", description, rule.ZoneID)
+		} else {
+			ppfmt.Noticef(pp.EmojiCreation, "Created the custom rule %q in the zone %s", description, rule.ZoneID)
+		}
+		return ResponseUpdated
+	}
+
+	kept := owned[0]
+	desired.ID = kept.ID
+	if kept == desired && len(owned) == 1 {
+		changes.Matched = append(changes.Matched, kept.ID)
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The custom rule %q in the zone %s is already up to date",
+			description, rule.ZoneID)
+		return ResponseNoop
+	}
+
+	if kept == desired {
+		changes.Matched = append(changes.Matched, kept.ID)
+	} else {
+		if !s.Handle.UpdateWAFCustomRule(ctx, ppfmt, rule.ZoneID, rulesetID, desired) {
+			ppfmt.Noticef(pp.EmojiError,
+				"Could not confirm update of the custom rule %q in the zone %s; it may be inconsistent",
+				description, rule.ZoneID)
+			return ResponseFailed
+		}
+		changes.Updated = append(changes.Updated, kept.ID)
+		if s.DryRun {
+			ppfmt.Noticef(pp.EmojiUpdate, "Would update the custom rule %q in the zone %s", description, rule.ZoneID)
+		} else {
+			ppfmt.Noticef(pp.EmojiUpdate, "Updated the custom rule %q in the zone %s", description, rule.ZoneID)
+		}
+	}
+
+	if !s.deleteWAFCustomRules(ctx, ppfmt, rule, rulesetID, owned[1:], description, &changes) {
+		return ResponseFailed
+	}
+	return ResponseUpdated
+}
+
+// FinalDeleteWAFListRule deletes the custom rules with the description during shutdown.
+func (s setter) FinalDeleteWAFListRule(ctx context.Context, ppfmt pp.PP, rule api.WAFListRule,
+	description string,
+) ResponseCode {
+	changes := WAFListRuleChanges{Matched: nil, Updated: nil, Created: nil, Deleted: nil}
+	defer func() { s.journal.setWAFListRule(rule, changes) }()
+
+	rulesetID, rules, ok := s.Handle.ListWAFCustomRules(ctx, ppfmt, rule.ZoneID)
+	if !ok {
+		return ResponseFailed
+	}
+
+	owned := ownedWAFCustomRules(rules, description)
+	if len(owned) == 0 {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The custom rule %q in the zone %s was already deleted",
+			description, rule.ZoneID)
+		return ResponseNoop
+	}
+
+	if !s.deleteWAFCustomRules(ctx, ppfmt, rule, rulesetID, owned, description, &changes) {
+		return ResponseFailed
+	}
+	return ResponseUpdated
+}
@@ -0,0 +1,206 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestSetWAFListRule(t *testing.T) {
+	t.Parallel()
+
+	rule := api.WAFListRule{ZoneID: "zone", Action: "skip"}
+	expression := "ip.src in $home"
+	customRule := func(id api.ID, action, expression, description string) api.WAFCustomRule {
+		return api.WAFCustomRule{ID: id, Action: action, Expression: expression, Description: description, Enabled: true}
+	}
+	ids := func(ids ...api.ID) []api.ID { return ids }
+
+	cases := []struct {
+		name         string
+		resp         setter.ResponseCode
+		changes      setter.WAFListRuleChanges
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name:    "up-to-date/response-noop",
+			resp:    setter.ResponseNoop,
+			changes: setter.WAFListRuleChanges{Matched: ids("rule1"), Updated: nil, Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{
+						customRule("rule0", "block", "ip.src in $bad", "other"),
+						customRule("rule1", "skip", expression, "ddns"),
+					}, true),
+					p.EXPECT().Infof(pp.EmojiAlreadyDone, "The custom rule %q in the zone %s is already up to date", "ddns", api.ID("zone")),
+				)
+			},
+		},
+		{
+			name:    "missing/create/response-updated",
+			resp:    setter.ResponseUpdated,
+			changes: setter.WAFListRuleChanges{Matched: nil, Updated: nil, Created: ids("rule2"), Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID(""), []api.WAFCustomRule{}, true),
+					m.EXPECT().CreateWAFCustomRule(ctx, p, api.ID("zone"), api.ID(""), customRule("", "skip", expression, "ddns")).Return(api.ID("rule2"), true),
+					p.EXPECT().Noticef(pp.EmojiCreation, "Created the custom rule %q in the zone %s", "ddns", api.ID("zone")),
+				)
+			},
+		},
+		{
+			name:    "outdated/update-and-deduplicate/response-updated",
+			resp:    setter.ResponseUpdated,
+			changes: setter.WAFListRuleChanges{Matched: nil, Updated: ids("rule1"), Created: nil, Deleted: ids("rule3")},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{
+						customRule("rule1", "block", "ip.src in $old", "ddns"),
+						customRule("rule3", "skip", expression, "ddns"),
+					}, true),
+					m.EXPECT().UpdateWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), customRule("rule1", "skip", expression, "ddns")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Updated the custom rule %q in the zone %s", "ddns", api.ID("zone")),
+					m.EXPECT().DeleteWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), api.ID("rule3")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the custom rule %q (%s) in the zone %s", "ddns", api.ID("rule3"), api.ID("zone")),
+				)
+			},
+		},
+		{
+			name:    "disabled/update/response-updated",
+			resp:    setter.ResponseUpdated,
+			changes: setter.WAFListRuleChanges{Matched: nil, Updated: ids("rule1"), Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				disabled := customRule("rule1", "skip", expression, "ddns")
+				disabled.Enabled = false
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{disabled}, true),
+					m.EXPECT().UpdateWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), customRule("rule1", "skip", expression, "ddns")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Updated the custom rule %q in the zone %s", "ddns", api.ID("zone")),
+				)
+			},
+		},
+		{
+			name:    "read-failed/response-failed",
+			resp:    setter.ResponseFailed,
+			changes: setter.WAFListRuleChanges{Matched: nil, Updated: nil, Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID(""), nil, false)
+			},
+		},
+		{
+			name:    "create-failed/response-failed",
+			resp:    setter.ResponseFailed,
+			changes: setter.WAFListRuleChanges{Matched: nil, Updated: nil, Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), nil, true),
+					m.EXPECT().CreateWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), customRule("", "skip", expression, "ddns")).Return(api.ID(""), false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the custom rule %q in the zone %s; it may be inconsistent", "ddns", api.ID("zone")),
+				)
+			},
+		},
+		{
+			name:    "update-failed/response-failed",
+			resp:    setter.ResponseFailed,
+			changes: setter.WAFListRuleChanges{Matched: nil, Updated: nil, Created: nil, Deleted: nil},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{
+						customRule("rule1", "block", expression, "ddns"),
+					}, true),
+					m.EXPECT().UpdateWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), customRule("rule1", "skip", expression, "ddns")).Return(false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the custom rule %q in the zone %s; it may be inconsistent", "ddns", api.ID("zone")),
+				)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.SetWAFListRule(ctx, h.mockPP, rule, expression, "ddns")
+			require.Equal(t, tc.resp, resp)
+			require.Equal(t, map[api.WAFListRule]setter.WAFListRuleChanges{rule: tc.changes},
+				h.setter.TakeChanges().WAFListRules)
+		})
+	}
+}
+
+func TestFinalDeleteWAFListRule(t *testing.T) {
+	t.Parallel()
+
+	rule := api.WAFListRule{ZoneID: "zone", Action: "block"}
+	owned := api.WAFCustomRule{ID: "rule1", Action: "block", Expression: "ip.src in $home", Description: "ddns", Enabled: true}
+	other := api.WAFCustomRule{ID: "rule2", Action: "block", Expression: "ip.src in $home", Description: "other", Enabled: true}
+
+	cases := []struct {
+		name         string
+		resp         setter.ResponseCode
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name: "owned/response-updated",
+			resp: setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{other, owned}, true),
+					m.EXPECT().DeleteWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), api.ID("rule1")).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the custom rule %q (%s) in the zone %s", "ddns", api.ID("rule1"), api.ID("zone")),
+				)
+			},
+		},
+		{
+			name: "none/response-noop",
+			resp: setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{other}, true),
+					p.EXPECT().Infof(pp.EmojiAlreadyDone, "The custom rule %q in the zone %s was already deleted", "ddns", api.ID("zone")),
+				)
+			},
+		},
+		{
+			name: "delete-failed/response-failed",
+			resp: setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID("ruleset"), []api.WAFCustomRule{owned}, true),
+					m.EXPECT().DeleteWAFCustomRule(ctx, p, api.ID("zone"), api.ID("ruleset"), api.ID("rule1")).Return(false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the custom rule %q in the zone %s; it may be inconsistent", "ddns", api.ID("zone")),
+				)
+			},
+		},
+		{
+			name: "read-failed/response-failed",
+			resp: setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().ListWAFCustomRules(ctx, p, api.ID("zone")).Return(api.ID(""), nil, false)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.FinalDeleteWAFListRule(ctx, h.mockPP, rule, "ddns")
+			require.Equal(t, tc.resp, resp)
+		})
+	}
+}
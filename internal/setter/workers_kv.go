@@ -0,0 +1,123 @@
+package setter
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// workersKVDocument is the JSON document published in Workers KV.
+type workersKVDocument struct {
+	IP4     []netip.Addr `json:"ipv4"`
+	IP6     []netip.Addr `json:"ipv6"`
+	Updated time.Time    `json:"updated"`
+}
+
+func (d workersKVDocument) addresses() []netip.Addr {
+	return slices.Concat(d.IP4, d.IP6)
+}
+
+// parseWorkersKVDocument parses the current value of a key. A missing key or
+// a value not written by the updater is treated as an empty document.
+func parseWorkersKVDocument(ppfmt pp.PP, key api.WorkersKVKey, value []byte) workersKVDocument {
+	doc := workersKVDocument{IP4: []netip.Addr{}, IP6: []netip.Addr{}, Updated: time.Time{}}
+	if value == nil {
+		return doc
+	}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		ppfmt.Noticef(pp.EmojiUserWarning,
+			"The Workers KV key %s does not hold addresses written by the updater; it will be overwritten",
+			key.Describe())
+		return workersKVDocument{IP4: []netip.Addr{}, IP6: []netip.Addr{}, Updated: time.Time{}}
+	}
+	if doc.IP4 == nil {
+		doc.IP4 = []netip.Addr{}
+	}
+	if doc.IP6 == nil {
+		doc.IP6 = []netip.Addr{}
+	}
+	return doc
+}
+
+// SetWorkersKV publishes the target addresses in a Workers KV key.
+func (s setter) SetWorkersKV(ctx context.Context, ppfmt pp.PP,
+	key api.WorkersKVKey, targetsByFamily map[ipnet.Family][]netip.Addr, now time.Time,
+) ResponseCode {
+	value, ok := s.Handle.GetWorkersKVValue(ctx, ppfmt, key)
+	if !ok {
+		return ResponseFailed
+	}
+	current := parseWorkersKVDocument(ppfmt, key, value)
+
+	desired := workersKVDocument{IP4: current.IP4, IP6: current.IP6, Updated: now.UTC()}
+	if targets, ok := targetsByFamily[ipnet.IP4]; ok {
+		desired.IP4 = slices.Clone(targets)
+	}
+	if targets, ok := targetsByFamily[ipnet.IP6]; ok {
+		desired.IP6 = slices.Clone(targets)
+	}
+	if desired.IP4 == nil {
+		desired.IP4 = []netip.Addr{}
+	}
+	if desired.IP6 == nil {
+		desired.IP6 = []netip.Addr{}
+	}
+
+	if value != nil && slices.Equal(current.IP4, desired.IP4) && slices.Equal(current.IP6, desired.IP6) {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The Workers KV key %s is already up to date", key.Describe())
+		s.journal.setWorkersKV(key, WorkersKVChanges{Previous: current.addresses(), Current: current.addresses()})
+		return ResponseNoop
+	}
+
+	encoded, err := json.Marshal(desired)
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible, "Failed to encode the addresses for the Workers KV key %s: %v",
+			key.Describe(), err)
+		return ResponseFailed
+	}
+	if !s.Handle.PutWorkersKVValue(ctx, ppfmt, key, encoded) {
+		ppfmt.Noticef(pp.EmojiError,
+			"Could not confirm update of the Workers KV key %s; it may be inconsistent", key.Describe())
+		s.journal.setWorkersKV(key, WorkersKVChanges{Previous: current.addresses(), Current: current.addresses()})
+		return ResponseFailed
+	}
+
+	addresses := pp.EnglishJoinMapOrEmptyLabel(netip.Addr.String, desired.addresses(), "(none)")
+	if s.DryRun {
+		ppfmt.Noticef(pp.EmojiUpdate, "Would publish %s in the Workers KV key %s", addresses, key.Describe())
+	} else {
+		ppfmt.Noticef(pp.EmojiUpdate, "Published %s in the Workers KV key %s", addresses, key.Describe())
+	}
+	s.journal.setWorkersKV(key, WorkersKVChanges{Previous: current.addresses(), Current: desired.addresses()})
+	return ResponseUpdated
+}
+
+// FinalDeleteWorkersKV deletes the Workers KV key.
+func (s setter) FinalDeleteWorkersKV(ctx context.Context, ppfmt pp.PP, key api.WorkersKVKey) ResponseCode {
+	value, ok := s.Handle.GetWorkersKVValue(ctx, ppfmt, key)
+	if !ok {
+		return ResponseFailed
+	}
+	if value == nil {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "The Workers KV key %s was already deleted", key.Describe())
+		return ResponseNoop
+	}
+
+	if !s.Handle.DeleteWorkersKVValue(ctx, ppfmt, key) {
+		ppfmt.Noticef(pp.EmojiError, "Could not confirm deletion of the Workers KV key %s", key.Describe())
+		return ResponseFailed
+	}
+
+	if s.DryRun {
+		ppfmt.Noticef(pp.EmojiDeletion, "Would delete the Workers KV key %s", key.Describe())
+	} else {
+		ppfmt.Noticef(pp.EmojiDeletion, "Deleted the Workers KV key %s", key.Describe())
+	}
+	return ResponseUpdated
+}
@@ -0,0 +1,209 @@
+package setter_test
+
+// vim: nowrap
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+func TestSetWorkersKV(t *testing.T) {
+	t.Parallel()
+
+	key := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	ip6 := netip.MustParseAddr("2001:db8::8")
+	oldIP4 := netip.MustParseAddr("198.51.100.1")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60))
+
+	cases := []struct {
+		name         string
+		targets      map[ipnet.Family][]netip.Addr
+		resp         setter.ResponseCode
+		changes      map[api.WorkersKVKey]setter.WorkersKVChanges
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name:    "missing/write/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}, ipnet.IP6: {ip6}},
+			resp:    setter.ResponseUpdated,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{key: {Previous: nil, Current: []netip.Addr{ip4, ip6}}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).Return(nil, true),
+					m.EXPECT().PutWorkersKVValue(ctx, p, key,
+						[]byte(`{"ipv4":["198.51.100.8"],"ipv6":["2001:db8::8"],"updated":"2026-10-19T04:00:00Z"}`)).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Published %s in the Workers KV key %s", "198.51.100.8 and 2001:db8::8", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name:    "unchanged/response-noop",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}},
+			resp:    setter.ResponseNoop,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{key: {Previous: []netip.Addr{ip4}, Current: []netip.Addr{ip4}}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).
+						Return([]byte(`{"ipv4":["198.51.100.8"],"ipv6":[],"updated":"2026-10-18T00:00:00Z"}`), true),
+					p.EXPECT().Infof(pp.EmojiAlreadyDone, "The Workers KV key %s is already up to date", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name:    "changed/keep-undetected-family/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}},
+			resp:    setter.ResponseUpdated,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{key: {Previous: []netip.Addr{oldIP4, ip6}, Current: []netip.Addr{ip4, ip6}}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).
+						Return([]byte(`{"ipv4":["198.51.100.1"],"ipv6":["2001:db8::8"],"updated":"2026-10-18T00:00:00Z"}`), true),
+					m.EXPECT().PutWorkersKVValue(ctx, p, key,
+						[]byte(`{"ipv4":["198.51.100.8"],"ipv6":["2001:db8::8"],"updated":"2026-10-19T04:00:00Z"}`)).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Published %s in the Workers KV key %s", "198.51.100.8 and 2001:db8::8", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name:    "cleared/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {}},
+			resp:    setter.ResponseUpdated,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{key: {Previous: []netip.Addr{oldIP4}, Current: nil}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).
+						Return([]byte(`{"ipv4":["198.51.100.1"],"ipv6":[],"updated":"2026-10-18T00:00:00Z"}`), true),
+					m.EXPECT().PutWorkersKVValue(ctx, p, key,
+						[]byte(`{"ipv4":[],"ipv6":[],"updated":"2026-10-19T04:00:00Z"}`)).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Published %s in the Workers KV key %s", "(none)", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name:    "invalid/overwrite/response-updated",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}},
+			resp:    setter.ResponseUpdated,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{key: {Previous: nil, Current: []netip.Addr{ip4}}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).Return([]byte("198.51.100.8"), true),
+					p.EXPECT().Noticef(pp.EmojiUserWarning, "The Workers KV key %s does not hold addresses written by the updater; it will be overwritten", "account/namespace:home"),
+					m.EXPECT().PutWorkersKVValue(ctx, p, key,
+						[]byte(`{"ipv4":["198.51.100.8"],"ipv6":[],"updated":"2026-10-19T04:00:00Z"}`)).Return(true),
+					p.EXPECT().Noticef(pp.EmojiUpdate, "Published %s in the Workers KV key %s", "198.51.100.8", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name:    "write-failed/response-failed",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}},
+			resp:    setter.ResponseFailed,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{key: {Previous: nil, Current: nil}},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).Return(nil, true),
+					m.EXPECT().PutWorkersKVValue(ctx, p, key, gomock.Any()).Return(false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm update of the Workers KV key %s; it may be inconsistent", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name:    "read-failed/response-failed",
+			targets: map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}},
+			resp:    setter.ResponseFailed,
+			changes: map[api.WorkersKVKey]setter.WorkersKVChanges{},
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetWorkersKVValue(ctx, p, key).Return(nil, false)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.SetWorkersKV(ctx, h.mockPP, key, tc.targets, now)
+			require.Equal(t, tc.resp, resp)
+			require.Equal(t, tc.changes, h.setter.TakeChanges().WorkersKV)
+		})
+	}
+}
+
+func TestFinalDeleteWorkersKV(t *testing.T) {
+	t.Parallel()
+
+	key := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+
+	cases := []struct {
+		name         string
+		resp         setter.ResponseCode
+		prepareMocks prepareSetterMocks
+	}{
+		{
+			name: "exists/response-updated",
+			resp: setter.ResponseUpdated,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).Return([]byte("{}"), true),
+					m.EXPECT().DeleteWorkersKVValue(ctx, p, key).Return(true),
+					p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted the Workers KV key %s", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name: "missing/response-noop",
+			resp: setter.ResponseNoop,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).Return(nil, true),
+					p.EXPECT().Infof(pp.EmojiAlreadyDone, "The Workers KV key %s was already deleted", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name: "delete-failed/response-failed",
+			resp: setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				gomock.InOrder(
+					m.EXPECT().GetWorkersKVValue(ctx, p, key).Return([]byte("{}"), true),
+					m.EXPECT().DeleteWorkersKVValue(ctx, p, key).Return(false),
+					p.EXPECT().Noticef(pp.EmojiError, "Could not confirm deletion of the Workers KV key %s", "account/namespace:home"),
+				)
+			},
+		},
+		{
+			name: "read-failed/response-failed",
+			resp: setter.ResponseFailed,
+			prepareMocks: func(ctx context.Context, _ func(), p *mocks.MockPP, m *mocks.MockHandle) {
+				m.EXPECT().GetWorkersKVValue(ctx, p, key).Return(nil, false)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, h := newSetterHarness(t)
+			h.prepare(ctx, tc.prepareMocks)
+
+			resp := h.setter.FinalDeleteWorkersKV(ctx, h.mockPP, key)
+			require.Equal(t, tc.resp, resp)
+		})
+	}
+}
@@ -11,9 +11,11 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 )
 
-// Handle encapsulates a channel for masked signals.
+// Handle encapsulates channels for masked signals.
 type Handle struct {
 	channel chan os.Signal
+	reload  chan os.Signal
+	trigger chan struct{}
 }
 
 // Signals contains the signals to mask and catch.
@@ -21,12 +23,29 @@ type Handle struct {
 //nolint:gochecknoglobals
 var Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
 
-// Setup masks signals in [Signals] and return the handle.
+// ReloadSignals contains the signals to reload the configuration.
+//
+//nolint:gochecknoglobals
+var ReloadSignals = []os.Signal{syscall.SIGHUP}
+
+// Setup masks signals in [Signals] and [ReloadSignals] and return the handle.
 func Setup() Handle {
 	chanSignal := make(chan os.Signal, len(Signals))
 	signal.Notify(chanSignal, Signals...)
 
-	return Handle{channel: chanSignal}
+	chanReload := make(chan os.Signal, 1)
+	signal.Notify(chanReload, ReloadSignals...)
+
+	return Handle{channel: chanSignal, reload: chanReload, trigger: make(chan struct{}, 1)}
+}
+
+// Trigger wakes up [Handle.WaitUntil] as if a signal were caught. Triggers that
+// arrive before the waiting are merged into one.
+func (h Handle) Trigger() {
+	select {
+	case h.trigger <- struct{}{}:
+	default:
+	}
 }
 
 // NotifyContext gives a copy of the context that will be canceled by signals in [Signals].
@@ -47,3 +66,36 @@ func (h Handle) WaitForSignalsUntil(ppfmt pp.PP, t time.Time) bool {
 		}
 	}
 }
+
+// An Event tells what ended [Handle.WaitUntil].
+type Event int
+
+const (
+	// Alarm means the time was reached.
+	Alarm Event = iota
+	// Stop means a signal in [Signals] was caught.
+	Stop
+	// Reload means a signal in [ReloadSignals] was caught.
+	Reload
+	// Trigger means that [Handle.Trigger] was called.
+	Trigger
+)
+
+// WaitUntil is [Handle.WaitForSignalsUntil] that also returns early when a
+// signal in [ReloadSignals] is caught or when [Handle.Trigger] is called.
+func (h Handle) WaitUntil(ppfmt pp.PP, t time.Time) Event {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case sig := <-h.channel:
+		ppfmt.Noticef(pp.EmojiSignal, "Caught signal: %v", sig)
+		return Stop
+	case sig := <-h.reload:
+		ppfmt.Noticef(pp.EmojiSignal, "Caught signal: %v", sig)
+		return Reload
+	case <-h.trigger:
+		return Trigger
+	case <-timer.C:
+		return Alarm
+	}
+}
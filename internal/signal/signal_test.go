@@ -66,6 +66,68 @@ func TestWaitForSignalsUntil(t *testing.T) {
 	}
 }
 
+//nolint:paralleltest // signals are global
+func TestWaitUntil(t *testing.T) {
+	for name, tc := range map[string]struct {
+		alarmDelay    time.Duration
+		signalDelay   time.Duration
+		signal        syscall.Signal
+		expected      signal.Event
+		prepareMockPP func(m *mocks.MockPP)
+	}{
+		"no-signal": {time.Second / 10, 0, 0, signal.Alarm, nil},
+		"sigterm": {
+			time.Second, time.Second / 10, syscall.SIGTERM, signal.Stop,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiSignal, "Caught signal: %v", syscall.SIGTERM)
+			},
+		},
+		"sighup": {
+			time.Second, time.Second / 10, syscall.SIGHUP, signal.Reload,
+			func(m *mocks.MockPP) {
+				m.EXPECT().Noticef(pp.EmojiSignal, "Caught signal: %v", syscall.SIGHUP)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			if tc.prepareMockPP != nil {
+				tc.prepareMockPP(mockPP)
+			}
+
+			done := make(chan struct{}, 1)
+			signalSelf := func() {
+				if tc.signalDelay > 0 {
+					time.Sleep(tc.signalDelay)
+					err := syscall.Kill(os.Getpid(), tc.signal)
+					require.NoError(t, err)
+				}
+				done <- struct{}{}
+			}
+
+			sig := signal.Setup()
+			go signalSelf()
+			res := sig.WaitUntil(mockPP, time.Now().Add(tc.alarmDelay))
+			<-done
+
+			require.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+//nolint:paralleltest // signals are global
+func TestWaitUntilTrigger(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	sig := signal.Setup()
+	sig.Trigger()
+	sig.Trigger() // merged with the first one
+	require.Equal(t, signal.Trigger, sig.WaitUntil(mockPP, time.Now().Add(time.Second)))
+	require.Equal(t, signal.Alarm, sig.WaitUntil(mockPP, time.Now().Add(time.Second/10)))
+}
+
 //nolint:paralleltest // signals are global
 func TestNotifyContext(t *testing.T) {
 	delta := time.Second / 10
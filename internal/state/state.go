@@ -0,0 +1,275 @@
+// Package state persists what the updater knew about the DNS records at the
+// end of its last run, so that a restarted updater can seed its caches and
+// tell what changed while it was down.
+package state
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/file"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// Version is the current version of the state file format.
+const Version = 1
+
+// filePerm is the permission of the state file. The file holds record and
+// zone IDs, so it is only readable by the updater.
+const filePerm fs.FileMode = 0o600
+
+// A Record is a managed DNS record as stored in the state file.
+type Record struct {
+	ID      api.ID     `json:"id"`
+	IP      netip.Addr `json:"ip"`
+	TTL     api.TTL    `json:"ttl"`
+	Proxied bool       `json:"proxied"`
+	Comment string     `json:"comment,omitempty"`
+	Tags    []string   `json:"tags,omitempty"`
+}
+
+// An Entry holds the managed records of one domain and one IP family.
+type Entry struct {
+	Domain    string    `json:"domain"`
+	Family    string    `json:"family"` // "IPv4" or "IPv6"
+	ZoneID    api.ID    `json:"zone_id,omitempty"`
+	AccountID api.ID    `json:"account_id,omitempty"`
+	Server    string    `json:"server,omitempty"` // the RFC 2136 server of a mirror, or empty for Cloudflare
+	Records   []Record  `json:"records"`
+	FetchedAt time.Time `json:"fetched_at"` // when the records were retrieved from the API
+	ChangedAt time.Time `json:"changed_at"` // when the addresses were last seen changing
+}
+
+// A Scope records what the saved records depend on besides the remote state.
+// Records saved in another scope must not seed the caches.
+type Scope struct {
+	Credential                 string `json:"credential"` // a fingerprint, never the credential itself
+	ManagedRecordsCommentRegex string `json:"managed_records_comment_regex"`
+}
+
+// NewScope converts the scope of the caches of a handle.
+func NewScope(scope api.RecordCacheScope) Scope {
+	return Scope{Credential: scope.Credential, ManagedRecordsCommentRegex: scope.ManagedRecordsCommentRegex}
+}
+
+// A State is the content of the state file.
+type State struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"saved_at"`
+	Scope   Scope     `json:"scope"`
+	Entries []Entry   `json:"entries"`
+}
+
+// New returns an empty state in the given scope.
+func New(scope Scope) State {
+	return State{Version: Version, SavedAt: time.Time{}, Scope: scope, Entries: nil}
+}
+
+// IsEmpty reports whether the state holds no entries.
+func (s State) IsEmpty() bool { return len(s.Entries) == 0 }
+
+// Load reads the state file at path. A missing file gives an empty state.
+// An unreadable or invalid file, or one saved in another scope, is reported
+// and also gives an empty state, because the state only saves API calls and
+// never decides what to update.
+func Load(ppfmt pp.PP, path string, scope Scope) State {
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return New(scope)
+	case err != nil:
+		ppfmt.Noticef(pp.EmojiWarning, "Failed to read the state file %s: %v; starting afresh",
+			pp.QuoteIfUnsafeInSentence(path), err)
+		return New(scope)
+	}
+
+	var s State
+	if err := json.Unmarshal(content, &s); err != nil {
+		ppfmt.Noticef(pp.EmojiWarning, "The state file %s is not valid JSON (%v); starting afresh",
+			pp.QuoteIfUnsafeInSentence(path), err)
+		return New(scope)
+	}
+	if s.Version != Version {
+		ppfmt.Noticef(pp.EmojiWarning, "The state file %s has the unsupported version %d; starting afresh",
+			pp.QuoteIfUnsafeInSentence(path), s.Version)
+		return New(scope)
+	}
+	if s.Scope != scope {
+		ppfmt.Noticef(pp.EmojiWarning,
+			"The state file %s was saved with another API credential or MANAGED_RECORDS_COMMENT_REGEX; "+
+				"starting afresh",
+			pp.QuoteIfUnsafeInSentence(path))
+		return New(scope)
+	}
+	return s
+}
+
+// Save writes the state to the file at path, replacing it atomically.
+func Save(ppfmt pp.PP, path string, s State) bool {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		ppfmt.Noticef(pp.EmojiImpossible,
+			"Could not encode the state: %v; please report this at %s", err, pp.IssueReportingURL)
+		return false
+	}
+	content = append(content, '\n')
+
+	if err := file.WriteAtomically(path, content, filePerm); err != nil {
+		ppfmt.Noticef(pp.EmojiError, "Failed to write the state file %s: %v",
+			pp.QuoteIfUnsafeInSentence(path), err)
+		return false
+	}
+	return true
+}
+
+// parseFamily is the inverse of [ipnet.Family.Describe].
+func parseFamily(s string) (ipnet.Family, bool) {
+	for ipFamily := range ipnet.All {
+		if ipFamily.Describe() == s {
+			return ipFamily, true
+		}
+	}
+	return 0, false
+}
+
+// Snapshots converts the state into snapshots for seeding the caches.
+// Entries with unknown IP families are skipped, and so are the entries of
+// mirrors, which have no caches to seed.
+func (s State) Snapshots() []api.RecordSnapshot {
+	snapshots := make([]api.RecordSnapshot, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		ipFamily, ok := parseFamily(entry.Family)
+		if !ok || entry.Server != "" {
+			continue
+		}
+		records := make([]api.Record, 0, len(entry.Records))
+		for _, r := range entry.Records {
+			records = append(records, api.Record{
+				ID: r.ID,
+				IP: r.IP,
+				RecordParams: api.RecordParams{
+					TTL:     r.TTL,
+					Proxied: r.Proxied,
+					Comment: r.Comment,
+					Tags:    r.Tags,
+				},
+			})
+		}
+		snapshots = append(snapshots, api.RecordSnapshot{
+			IPFamily:  ipFamily,
+			Domain:    entry.Domain,
+			ZoneID:    entry.ZoneID,
+			AccountID: entry.AccountID,
+			Server:    "",
+			Records:   records,
+			FetchedAt: entry.FetchedAt,
+		})
+	}
+	return snapshots
+}
+
+// addresses returns the sorted, deduplicated addresses of the entry.
+func (e Entry) addresses() []netip.Addr {
+	addrs := make([]netip.Addr, 0, len(e.Records))
+	for _, r := range e.Records {
+		addrs = append(addrs, r.IP)
+	}
+	slices.SortFunc(addrs, netip.Addr.Compare)
+	return slices.Compact(addrs)
+}
+
+func (e Entry) key() [3]string { return [3]string{e.Server, e.Family, e.Domain} }
+
+func compareEntries(a, b Entry) int {
+	return cmp.Or(cmp.Compare(a.Server, b.Server), cmp.Compare(a.Family, b.Family), cmp.Compare(a.Domain, b.Domain))
+}
+
+// Merge updates the previous state with the snapshots taken at now. Entries
+// without snapshots are kept as they are, because their caches might merely
+// have expired. ChangedAt is only moved forward when the addresses change.
+func Merge(previous State, snapshots []api.RecordSnapshot, now time.Time) State {
+	entries := map[[3]string]Entry{}
+	for _, entry := range previous.Entries {
+		entries[entry.key()] = entry
+	}
+
+	for _, snapshot := range snapshots {
+		records := make([]Record, 0, len(snapshot.Records))
+		for _, r := range snapshot.Records {
+			records = append(records, Record{
+				ID:      r.ID,
+				IP:      r.IP,
+				TTL:     r.TTL,
+				Proxied: r.Proxied,
+				Comment: r.Comment,
+				Tags:    r.Tags,
+			})
+		}
+		entry := Entry{
+			Domain:    snapshot.Domain,
+			Family:    snapshot.IPFamily.Describe(),
+			ZoneID:    snapshot.ZoneID,
+			AccountID: snapshot.AccountID,
+			Server:    snapshot.Server,
+			Records:   records,
+			FetchedAt: snapshot.FetchedAt,
+			ChangedAt: now,
+		}
+		if old, ok := entries[entry.key()]; ok && slices.Equal(old.addresses(), entry.addresses()) {
+			entry.ChangedAt = old.ChangedAt
+		}
+		entries[entry.key()] = entry
+	}
+
+	merged := State{Version: Version, SavedAt: now, Scope: previous.Scope, Entries: make([]Entry, 0, len(entries))}
+	for _, entry := range entries {
+		merged.Entries = append(merged.Entries, entry)
+	}
+	slices.SortFunc(merged.Entries, compareEntries)
+	return merged
+}
+
+func describeAddrs(addrs []netip.Addr) string {
+	return pp.EnglishJoinMapOrEmptyLabel(netip.Addr.String, addrs, "(none)")
+}
+
+// Changes describes, in sentences suitable for notifications, the addresses
+// that differ between the previous and the current states. Domains missing
+// from either state are not mentioned.
+func Changes(previous, current State) []string {
+	old := map[[3]string]Entry{}
+	for _, entry := range previous.Entries {
+		old[entry.key()] = entry
+	}
+
+	var changes []string
+	for _, entry := range current.Entries {
+		prev, ok := old[entry.key()]
+		if !ok || slices.Equal(prev.addresses(), entry.addresses()) {
+			continue
+		}
+		recordType := entry.Family
+		if ipFamily, ok := parseFamily(entry.Family); ok {
+			recordType = ipFamily.RecordType()
+		}
+		subject := entry.Domain
+		if entry.Server != "" {
+			subject = fmt.Sprintf("%s on the RFC 2136 server %s", entry.Domain, entry.Server)
+		}
+		changes = append(changes, fmt.Sprintf(
+			"Since the last run at %s, the %s records for %s changed from %s to %s.",
+			previous.SavedAt.Format(time.RFC3339), recordType, subject,
+			describeAddrs(prev.addresses()), describeAddrs(entry.addresses()),
+		))
+	}
+	return changes
+}
@@ -0,0 +1,215 @@
+package state_test
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/state"
+)
+
+var (
+	lastRun = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	thisRun = lastRun.Add(6 * time.Hour)
+	scope   = state.Scope{Credential: "fingerprint", ManagedRecordsCommentRegex: "^ddns$"}
+)
+
+func snapshot(ipFamily ipnet.Family, domain string, ips ...string) api.RecordSnapshot {
+	records := make([]api.Record, 0, len(ips))
+	for i, ip := range ips {
+		records = append(records, api.Record{
+			ID: api.ID(domain + "/" + string(rune('a'+i))),
+			IP: netip.MustParseAddr(ip),
+			RecordParams: api.RecordParams{
+				TTL: api.TTLAuto, Proxied: true, Comment: "managed", Tags: []string{"ddns"},
+			},
+		})
+	}
+	return api.RecordSnapshot{
+		IPFamily:  ipFamily,
+		Domain:    domain,
+		ZoneID:    "zone",
+		AccountID: "account",
+		Server:    "",
+		Records:   records,
+		FetchedAt: lastRun,
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	require.Equal(t, state.New(scope), state.Load(mockPP, path, scope))
+
+	s := state.Merge(state.New(scope), []api.RecordSnapshot{
+		snapshot(ipnet.IP6, "www.example.org", "2001:db8::1"),
+		snapshot(ipnet.IP4, "www.example.org", "192.0.2.1", "192.0.2.2"),
+	}, lastRun)
+	require.True(t, state.Save(mockPP, path, s))
+	require.Equal(t, s, state.Load(mockPP, path, scope))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestSaveFails(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+	mockCtrl := gomock.NewController(t)
+	mockPP := mocks.NewMockPP(mockCtrl)
+
+	mockPP.EXPECT().Noticef(pp.EmojiError, "Failed to write the state file %s: %v", path, gomock.Any())
+	require.False(t, state.Save(mockPP, path, state.New(scope)))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		content       string
+		prepareMockPP func(*mocks.MockPP, string)
+	}{
+		"not-json": {
+			"{",
+			func(m *mocks.MockPP, path string) {
+				m.EXPECT().Noticef(pp.EmojiWarning, "The state file %s is not valid JSON (%v); starting afresh", path, gomock.Any())
+			},
+		},
+		"version": {
+			`{"version":2}`,
+			func(m *mocks.MockPP, path string) {
+				m.EXPECT().Noticef(pp.EmojiWarning, "The state file %s has the unsupported version %d; starting afresh", path, 2)
+			},
+		},
+		"scope": {
+			`{"version":1,"scope":{"credential":"another","managed_records_comment_regex":"^ddns$"},"entries":[]}`,
+			func(m *mocks.MockPP, path string) {
+				m.EXPECT().Noticef(pp.EmojiWarning,
+					"The state file %s was saved with another API credential or MANAGED_RECORDS_COMMENT_REGEX; starting afresh",
+					path)
+			},
+		},
+		"no-scope": {
+			`{"version":1,"entries":[]}`,
+			func(m *mocks.MockPP, path string) {
+				m.EXPECT().Noticef(pp.EmojiWarning,
+					"The state file %s was saved with another API credential or MANAGED_RECORDS_COMMENT_REGEX; starting afresh",
+					path)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "state.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+			mockCtrl := gomock.NewController(t)
+			mockPP := mocks.NewMockPP(mockCtrl)
+			tc.prepareMockPP(mockPP, path)
+			require.Equal(t, state.New(scope), state.Load(mockPP, path, scope))
+		})
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	t.Parallel()
+
+	snapshots := []api.RecordSnapshot{
+		snapshot(ipnet.IP4, "a.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP4, "b.example.org"),
+		snapshot(ipnet.IP6, "a.example.org", "2001:db8::1"),
+	}
+	s := state.Merge(state.New(scope), snapshots, lastRun)
+	require.Equal(t, snapshots, s.Snapshots())
+
+	s.Entries[0].Family = "IPv5"
+	require.Equal(t, snapshots[1:], s.Snapshots())
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	previous := state.Merge(state.New(scope), []api.RecordSnapshot{
+		snapshot(ipnet.IP4, "kept.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP4, "same.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP4, "changed.example.org", "192.0.2.1"),
+	}, lastRun)
+
+	merged := state.Merge(previous, []api.RecordSnapshot{
+		snapshot(ipnet.IP4, "same.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP4, "changed.example.org", "192.0.2.2"),
+		snapshot(ipnet.IP4, "new.example.org", "192.0.2.3"),
+	}, thisRun)
+
+	require.Equal(t, thisRun, merged.SavedAt)
+	changedAt := map[string]time.Time{}
+	for _, entry := range merged.Entries {
+		changedAt[entry.Domain] = entry.ChangedAt
+	}
+	require.Equal(t, map[string]time.Time{
+		"changed.example.org": thisRun,
+		"kept.example.org":    lastRun,
+		"new.example.org":     thisRun,
+		"same.example.org":    lastRun,
+	}, changedAt)
+}
+
+func TestChanges(t *testing.T) {
+	t.Parallel()
+
+	previous := state.Merge(state.New(scope), []api.RecordSnapshot{
+		snapshot(ipnet.IP4, "same.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP4, "changed.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP6, "cleared.example.org", "2001:db8::1"),
+		snapshot(ipnet.IP4, "gone.example.org", "192.0.2.1"),
+	}, lastRun)
+	current := state.Merge(previous, []api.RecordSnapshot{
+		snapshot(ipnet.IP4, "same.example.org", "192.0.2.1"),
+		snapshot(ipnet.IP4, "changed.example.org", "192.0.2.2", "192.0.2.3"),
+		snapshot(ipnet.IP6, "cleared.example.org"),
+		snapshot(ipnet.IP4, "new.example.org", "192.0.2.1"),
+	}, thisRun)
+
+	require.Equal(t, []string{
+		"Since the last run at 2024-03-01T12:00:00Z, the A records for changed.example.org changed from 192.0.2.1 to 192.0.2.2 and 192.0.2.3.",
+		"Since the last run at 2024-03-01T12:00:00Z, the AAAA records for cleared.example.org changed from 2001:db8::1 to (none).",
+	}, state.Changes(previous, current))
+	require.Empty(t, state.Changes(state.New(scope), current))
+}
+
+func TestMirror(t *testing.T) {
+	t.Parallel()
+
+	mirrored := func(ips ...string) api.RecordSnapshot {
+		s := snapshot(ipnet.IP4, "a.example.org", ips...)
+		s.ZoneID, s.AccountID, s.Server = "", "", "ns.example.org:53"
+		return s
+	}
+
+	previous := state.Merge(state.New(scope), []api.RecordSnapshot{
+		snapshot(ipnet.IP4, "a.example.org", "192.0.2.1"),
+		mirrored("192.0.2.1"),
+	}, lastRun)
+	require.Len(t, previous.Entries, 2)
+	require.Equal(t, []api.RecordSnapshot{snapshot(ipnet.IP4, "a.example.org", "192.0.2.1")}, previous.Snapshots())
+
+	current := state.Merge(previous, []api.RecordSnapshot{mirrored("192.0.2.2")}, thisRun)
+	require.Equal(t, []string{
+		"Since the last run at 2024-03-01T12:00:00Z, the A records for a.example.org on the RFC 2136 server ns.example.org:53 changed from 192.0.2.1 to 192.0.2.2.", //nolint:lll
+	}, state.Changes(previous, current))
+}
@@ -0,0 +1,251 @@
+// Package trigger queues the rounds of updating that run out of schedule, for
+// example when a router reconnects and gets a new address, and serves the
+// webhook that requests them.
+package trigger
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+)
+
+// maxSourceLength is the maximum number of characters kept from the source of
+// a trigger, which ends up in the notifications.
+const maxSourceLength = 64
+
+// A Request is one or more triggers merged together.
+type Request struct {
+	// Families are the IP families to update. Nil means all of them.
+	Families map[ipnet.Family]bool
+	// Sources are who sent the triggers, in the order of arrival.
+	Sources []string
+}
+
+// merge adds another trigger to r.
+func (r *Request) merge(families map[ipnet.Family]bool, source string) {
+	switch {
+	case r.Families == nil:
+	case families == nil:
+		r.Families = nil
+	default:
+		for ipFamily := range families {
+			r.Families[ipFamily] = true
+		}
+	}
+	if !slices.Contains(r.Sources, source) {
+		r.Sources = append(r.Sources, source)
+	}
+}
+
+// DescribeSources lists the sources of the triggers in English.
+func (r Request) DescribeSources() string {
+	return strings.Join(r.Sources, ", ")
+}
+
+// A Queue holds the trigger that has not been taken by the main loop, merging
+// the triggers that arrive in the meantime. The zero value is not usable; use
+// [NewQueue].
+type Queue struct {
+	wake func()
+
+	mutex   sync.Mutex
+	pending *Request
+}
+
+// NewQueue creates a [Queue] that calls wake whenever a new trigger is pending.
+func NewQueue(wake func()) *Queue {
+	return &Queue{wake: wake, mutex: sync.Mutex{}, pending: nil}
+}
+
+// Push adds a trigger for the IP families, where nil means all of them.
+// It reports whether the trigger was merged into the pending one.
+func (q *Queue) Push(families map[ipnet.Family]bool, source string) bool {
+	q.mutex.Lock()
+	if q.pending != nil {
+		q.pending.merge(families, source)
+		q.mutex.Unlock()
+		return true
+	}
+	q.pending = &Request{Families: nil, Sources: nil}
+	if families != nil {
+		q.pending.Families = map[ipnet.Family]bool{}
+	}
+	q.pending.merge(families, source)
+	q.mutex.Unlock()
+
+	q.wake()
+	return false
+}
+
+// mergeIfPending merges a trigger into the pending one, if any.
+func (q *Queue) mergeIfPending(families map[ipnet.Family]bool, source string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.pending == nil {
+		return false
+	}
+	q.pending.merge(families, source)
+	return true
+}
+
+// Take returns the pending trigger, if any, and clears it.
+func (q *Queue) Take() (Request, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.pending == nil {
+		return Request{Families: nil, Sources: nil}, false
+	}
+	r := *q.pending
+	q.pending = nil
+	return r, true
+}
+
+// A Limiter spaces out the triggers pushed to a [Queue] from the outside, such
+// as the webhook and the DynDNS2 server, which share one limiter. The zero
+// value is not usable; use [NewLimiter].
+type Limiter struct {
+	queue       *Queue
+	minInterval time.Duration
+
+	mutex    sync.Mutex
+	accepted time.Time // when the last trigger that was not merged was accepted
+}
+
+// NewLimiter creates a [Limiter] that pushes to queue. Triggers closer than
+// minInterval are rejected unless they can be merged into the pending one.
+func NewLimiter(queue *Queue, minInterval time.Duration) *Limiter {
+	return &Limiter{
+		queue:       queue,
+		minInterval: minInterval,
+		mutex:       sync.Mutex{},
+		accepted:    time.Time{},
+	}
+}
+
+// Push adds a trigger for the IP families, where nil means all of them. It
+// reports whether the trigger was merged into the pending one and, when the
+// trigger was rejected, how long to wait before the next one is accepted.
+func (l *Limiter) Push(families map[ipnet.Family]bool, source string, now time.Time,
+) (merged bool, wait time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.queue.mergeIfPending(families, source) {
+		return true, 0
+	}
+
+	if !l.accepted.IsZero() && now.Before(l.accepted.Add(l.minInterval)) {
+		return false, l.accepted.Add(l.minInterval).Sub(now)
+	}
+	l.accepted = now
+	return l.queue.Push(families, source), 0
+}
+
+// A Server accepts the triggers from the webhook and pushes them through a
+// [Limiter]. The zero value is not usable; use [New].
+type Server struct {
+	token   []byte
+	limiter *Limiter
+}
+
+// New creates a [Server] that accepts the callers with the bearer token.
+func New(token string, limiter *Limiter) *Server {
+	return &Server{token: []byte(token), limiter: limiter}
+}
+
+// Register adds the endpoints POST /trigger and POST /trigger/{family} to mux,
+// where the family is "ipv4" or "ipv6".
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /trigger", s.serve)
+	mux.HandleFunc("POST /trigger/{family}", s.serve)
+}
+
+// parseFamily parses the optional family in the path.
+func parseFamily(val string) (map[ipnet.Family]bool, bool) {
+	switch strings.ToLower(val) {
+	case "":
+		return nil, true
+	case "ipv4", "4":
+		return map[ipnet.Family]bool{ipnet.IP4: true}, true
+	case "ipv6", "6":
+		return map[ipnet.Family]bool{ipnet.IP6: true}, true
+	default:
+		return nil, false
+	}
+}
+
+// describeSource returns the "source" query parameter, cleaned up for the
+// notifications, or the address of the caller if there is none.
+func describeSource(r *http.Request) string {
+	source := strings.Map(func(c rune) rune {
+		if unicode.IsPrint(c) {
+			return c
+		}
+		return -1
+	}, strings.TrimSpace(r.URL.Query().Get("source")))
+	if runes := []rune(source); len(runes) > maxSourceLength {
+		source = string(runes[:maxSourceLength])
+	}
+	if source != "" {
+		return source
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// Authorized checks in constant time whether the request carries the bearer
+// token. The empty token authorizes no one.
+func Authorized(r *http.Request, token []byte) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && len(token) > 0 && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), token) == 1
+}
+
+// authorized checks the bearer token of the webhook.
+func (s *Server) authorized(r *http.Request) bool {
+	return Authorized(r, s.token)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ddns"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, "unauthorized")
+		return
+	}
+
+	families, ok := parseFamily(r.PathValue("family"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "unknown IP family %q; use \"ipv4\" or \"ipv6\"\n", r.PathValue("family"))
+		return
+	}
+	source := describeSource(r)
+
+	merged, wait := s.limiter.Push(families, source, time.Now())
+	switch {
+	case merged:
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "merged into the pending update")
+	case wait > 0:
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintf(w, "too many triggers; try again in %v\n", wait.Round(time.Second))
+	default:
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "update triggered")
+	}
+}
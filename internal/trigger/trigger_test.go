@@ -0,0 +1,211 @@
+package trigger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/trigger"
+)
+
+func post(t *testing.T, s *trigger.Server, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	s.Register(mux)
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, path, nil)
+	req.RemoteAddr = "192.0.2.1:12345"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	woken := 0
+	q := trigger.NewQueue(func() { woken++ })
+	s := trigger.New("secret", trigger.NewLimiter(q, 0))
+	for _, token := range []string{"", "wrong", "secrets"} {
+		w := post(t, s, "/trigger", token)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Equal(t, `Bearer realm="ddns"`, w.Header().Get("WWW-Authenticate"))
+	}
+	require.Zero(t, woken)
+	_, ok := q.Take()
+	require.False(t, ok)
+}
+
+func TestAuthorized(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		header   string
+		token    string
+		expected bool
+	}{
+		"match":        {"Bearer secret", "secret", true},
+		"spaces":       {"Bearer  secret ", "secret", true},
+		"wrong":        {"Bearer wrong", "secret", false},
+		"basic":        {"Basic secret", "secret", false},
+		"missing":      {"", "secret", false},
+		"empty-token":  {"Bearer ", "", false},
+		"empty-header": {"", "", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			require.Equal(t, tc.expected, trigger.Authorized(req, []byte(tc.token)))
+		})
+	}
+}
+
+func TestUnknownFamily(t *testing.T) {
+	t.Parallel()
+
+	q := trigger.NewQueue(func() {})
+	s := trigger.New("secret", trigger.NewLimiter(q, 0))
+	w := post(t, s, "/trigger/ipv5", "secret")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "unknown IP family \"ipv5\"; use \"ipv4\" or \"ipv6\"\n", w.Body.String())
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		paths    []string
+		families map[ipnet.Family]bool
+	}{
+		"all":           {[]string{"/trigger"}, nil},
+		"ipv4":          {[]string{"/trigger/IPv4"}, map[ipnet.Family]bool{ipnet.IP4: true}},
+		"both":          {[]string{"/trigger/4", "/trigger/ipv6"}, map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true}},
+		"ipv6-then-all": {[]string{"/trigger/6", "/trigger"}, nil},
+		"all-then-ipv6": {[]string{"/trigger", "/trigger/6"}, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			woken := 0
+			q := trigger.NewQueue(func() { woken++ })
+			s := trigger.New("secret", trigger.NewLimiter(q, time.Hour))
+			for _, path := range tc.paths {
+				w := post(t, s, path+"?source=router", "secret")
+				require.Equal(t, http.StatusAccepted, w.Code)
+			}
+			require.Equal(t, 1, woken)
+
+			r, ok := q.Take()
+			require.True(t, ok)
+			require.Equal(t, tc.families, r.Families)
+			require.Equal(t, "router", r.DescribeSources())
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	q := trigger.NewQueue(func() {})
+	s := trigger.New("secret", trigger.NewLimiter(q, time.Hour))
+	require.Equal(t, http.StatusAccepted, post(t, s, "/trigger", "secret").Code)
+	_, ok := q.Take()
+	require.True(t, ok)
+
+	w := post(t, s, "/trigger", "secret")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "3600", w.Header().Get("Retry-After"))
+	_, ok = q.Take()
+	require.False(t, ok)
+
+	q = trigger.NewQueue(func() {})
+	s = trigger.New("secret", trigger.NewLimiter(q, 0))
+	for range 2 {
+		require.Equal(t, http.StatusAccepted, post(t, s, "/trigger", "secret").Code)
+		_, ok = q.Take()
+		require.True(t, ok)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	q := trigger.NewQueue(func() {})
+	l := trigger.NewLimiter(q, time.Minute)
+
+	merged, wait := l.Push(nil, "webhook", now)
+	require.False(t, merged)
+	require.Zero(t, wait)
+	merged, wait = l.Push(map[ipnet.Family]bool{ipnet.IP4: true}, "dyndns2", now.Add(time.Second))
+	require.True(t, merged)
+	require.Zero(t, wait)
+	_, ok := q.Take()
+	require.True(t, ok)
+
+	merged, wait = l.Push(nil, "dyndns2", now.Add(20*time.Second))
+	require.False(t, merged)
+	require.Equal(t, 40*time.Second, wait)
+	_, ok = q.Take()
+	require.False(t, ok)
+
+	merged, wait = l.Push(nil, "dyndns2", now.Add(time.Minute))
+	require.False(t, merged)
+	require.Zero(t, wait)
+	_, ok = q.Take()
+	require.True(t, ok)
+}
+
+func TestSources(t *testing.T) {
+	t.Parallel()
+
+	q := trigger.NewQueue(func() {})
+	s := trigger.New("secret", trigger.NewLimiter(q, 0))
+	post(t, s, "/trigger", "secret")
+	post(t, s, "/trigger?source=a%0Ab", "secret")
+	post(t, s, "/trigger", "secret")
+	r, ok := q.Take()
+	require.True(t, ok)
+	require.Equal(t, []string{"192.0.2.1", "ab"}, r.Sources)
+	require.Equal(t, "192.0.2.1, ab", r.DescribeSources())
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	woken := 0
+	q := trigger.NewQueue(func() { woken++ })
+	require.False(t, q.Push(map[ipnet.Family]bool{ipnet.IP4: true}, "dyndns2"))
+	require.True(t, q.Push(map[ipnet.Family]bool{ipnet.IP6: true}, "router"))
+	require.Equal(t, 1, woken)
+
+	r, ok := q.Take()
+	require.True(t, ok)
+	require.Equal(t, trigger.Request{
+		Families: map[ipnet.Family]bool{ipnet.IP4: true, ipnet.IP6: true},
+		Sources:  []string{"dyndns2", "router"},
+	}, r)
+	_, ok = q.Take()
+	require.False(t, ok)
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	trigger.New("secret", trigger.NewLimiter(trigger.NewQueue(func() {}), 0)).Register(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/trigger", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,39 @@
+package updater
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+)
+
+// DetectIPs runs the providers and the detection filters of the managed IP
+// families without changing anything. It returns the detected addresses of the
+// families whose detection succeeded, except the families whose addresses are
+// not known yet, and whether all of them succeeded.
+func DetectIPs(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig) (map[ipnet.Family][]netip.Addr, bool) {
+	ips := map[ipnet.Family][]netip.Addr{}
+	ok := true
+	for ipFamily, p := range ipnet.Bindings(c.Provider) {
+		if p == nil {
+			continue
+		}
+		rawData, msg := detectRawData(ctx, ppfmt, c, ipFamily)
+		if !msg.HeartbeatMessage.OK {
+			ok = false
+			continue
+		}
+		if !rawData.HasUsableRawData() {
+			continue
+		}
+		ips[ipFamily] = deriveDNSAddresses(rawData)
+	}
+
+	// Close all idle connections after the IP detection
+	provider.CloseIdleConnections()
+
+	return ips, ok
+}
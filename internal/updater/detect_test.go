@@ -0,0 +1,58 @@
+package updater_test
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+func TestDetectIPs(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("127.0.0.1")
+
+	mockCtrl := gomock.NewController(t)
+	conf := initUpdateConfig()
+	provider4 := mocks.NewMockProvider(mockCtrl)
+	provider6 := mocks.NewMockProvider(mockCtrl)
+	conf.Provider[ipnet.IP4] = provider4
+	conf.Provider[ipnet.IP6] = provider6
+	registry := metrics.NewRegistry()
+	conf.Metrics = registry.ForProfile("")
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	gomock.InOrder(
+		provider4.EXPECT().GetRawData(gomock.Any(), mockPP, ipnet.IP4, 32).
+			Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+		mockPP.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "127.0.0.1"),
+		mockPP.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+		provider6.EXPECT().GetRawData(gomock.Any(), mockPP, ipnet.IP6, 64).
+			Return(provider.NewUnavailableDetectionResult()),
+		mockPP.EXPECT().Noticef(pp.EmojiError, "No valid %s addresses were detected", "IPv6"),
+		mockPP.EXPECT().NoticeOncef(pp.MessageIP6DetectionFails, pp.EmojiHint, gomock.Any(), pp.ManualURL),
+	)
+
+	ips, ok := updater.DetectIPs(context.Background(), mockPP, conf)
+	require.False(t, ok)
+	require.Equal(t, map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}}, ips)
+
+	var b strings.Builder
+	_, err := registry.WriteTo(&b)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `ddns_detections_total{family="IPv4",result="success"} 1`+"\n")
+	require.Contains(t, b.String(), `ddns_detections_total{family="IPv6",result="failure"} 1`+"\n")
+	require.Contains(t, b.String(), `ddns_detection_duration_seconds_count{family="IPv4"} 1`+"\n")
+	require.Contains(t, b.String(), `ddns_detected_ip_info{family="IPv4",ip="127.0.0.1"} 1`+"\n")
+	require.NotContains(t, b.String(), `ddns_detected_ip_info{family="IPv6"`)
+}
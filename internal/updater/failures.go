@@ -0,0 +1,268 @@
+package updater
+
+import (
+	"fmt"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+)
+
+// Failures records the parts of a round of updating that failed, so that they
+// can be retried without touching the parts that succeeded.
+type Failures struct {
+	// Families are the IP families whose detection failed.
+	Families map[ipnet.Family]bool
+	// Domains are the domains whose DNS records could not be updated.
+	Domains map[ipnet.Family][]domain.Domain
+	// WAFLists are the WAF lists that could not be updated.
+	WAFLists []api.WAFList
+	// LBPoolOrigins are the load balancer pool origins that could not be updated.
+	LBPoolOrigins []api.LBPoolOrigin
+	// GatewayLocations are the Gateway locations that could not be updated.
+	GatewayLocations []api.GatewayLocation
+	// AccessGroups are the Access groups that could not be updated.
+	AccessGroups []api.AccessGroup
+	// IPAccessRules are the sets of IP Access rules that could not be updated.
+	IPAccessRules []api.IPAccessRuleSet
+	// SpectrumApps are the Spectrum applications that could not be updated.
+	SpectrumApps []api.SpectrumApp
+	// WAFListRule is whether the WAF custom rule could not be updated.
+	WAFListRule bool
+	// WorkersKV is whether the Workers KV key could not be updated.
+	WorkersKV bool
+	// LocalResolver is whether the local resolver file could not be updated.
+	LocalResolver bool
+	// NFTablesSets are the nftables sets that could not be updated.
+	NFTablesSets []nftset.Set
+}
+
+func newFailures() *Failures {
+	f := NewFamilyFailures(map[ipnet.Family]bool{})
+	return &f
+}
+
+// NewFamilyFailures returns the failures of the detection of the given IP
+// families, so that [Failures.Restrict] updates everything about them.
+func NewFamilyFailures(families map[ipnet.Family]bool) Failures {
+	return Failures{
+		Families:         families,
+		Domains:          map[ipnet.Family][]domain.Domain{},
+		WAFLists:         nil,
+		LBPoolOrigins:    nil,
+		GatewayLocations: nil,
+		AccessGroups:     nil,
+		IPAccessRules:    nil,
+		SpectrumApps:     nil,
+		WAFListRule:      false,
+		WorkersKV:        false,
+		LocalResolver:    false,
+		NFTablesSets:     nil,
+	}
+}
+
+// A nil *Failures records nothing.
+func (f *Failures) addFamily(ipFamily ipnet.Family) {
+	if f == nil {
+		return
+	}
+	f.Families[ipFamily] = true
+}
+
+func (f *Failures) addDomain(ipFamily ipnet.Family, d domain.Domain) {
+	if f == nil {
+		return
+	}
+	f.Domains[ipFamily] = append(f.Domains[ipFamily], d)
+}
+
+func (f *Failures) addWAFList(l api.WAFList) {
+	if f == nil {
+		return
+	}
+	f.WAFLists = append(f.WAFLists, l)
+}
+
+func (f *Failures) addLBPoolOrigin(o api.LBPoolOrigin) {
+	if f == nil {
+		return
+	}
+	f.LBPoolOrigins = append(f.LBPoolOrigins, o)
+}
+
+func (f *Failures) addGatewayLocation(l api.GatewayLocation) {
+	if f == nil {
+		return
+	}
+	f.GatewayLocations = append(f.GatewayLocations, l)
+}
+
+func (f *Failures) addAccessGroup(g api.AccessGroup) {
+	if f == nil {
+		return
+	}
+	f.AccessGroups = append(f.AccessGroups, g)
+}
+
+func (f *Failures) addIPAccessRules(r api.IPAccessRuleSet) {
+	if f == nil {
+		return
+	}
+	f.IPAccessRules = append(f.IPAccessRules, r)
+}
+
+func (f *Failures) addSpectrumApp(a api.SpectrumApp) {
+	if f == nil {
+		return
+	}
+	f.SpectrumApps = append(f.SpectrumApps, a)
+}
+
+func (f *Failures) addWAFListRule() {
+	if f == nil {
+		return
+	}
+	f.WAFListRule = true
+}
+
+func (f *Failures) addWorkersKV() {
+	if f == nil {
+		return
+	}
+	f.WorkersKV = true
+}
+
+func (f *Failures) addLocalResolver() {
+	if f == nil {
+		return
+	}
+	f.LocalResolver = true
+}
+
+func (f *Failures) addNFTablesSet(set nftset.Set) {
+	if f == nil {
+		return
+	}
+	f.NFTablesSets = append(f.NFTablesSets, set)
+}
+
+// needsAllFamilies reports whether a failed resource needs the addresses of
+// every IP family to be updated again.
+func (f Failures) needsAllFamilies() bool {
+	return len(f.WAFLists) > 0 || len(f.LBPoolOrigins) > 0 || len(f.GatewayLocations) > 0 ||
+		len(f.AccessGroups) > 0 || len(f.IPAccessRules) > 0 || len(f.SpectrumApps) > 0 ||
+		f.WAFListRule || f.WorkersKV || len(f.NFTablesSets) > 0
+}
+
+// IsEmpty reports whether nothing needs to be retried.
+func (f Failures) IsEmpty() bool {
+	return len(f.Families) == 0 && len(f.Domains) == 0 && !f.needsAllFamilies() && !f.LocalResolver
+}
+
+// Describe lists the failed parts in English.
+func (f Failures) Describe() string {
+	var parts []string
+	for ipFamily := range ipnet.All {
+		if f.Families[ipFamily] {
+			parts = append(parts, ipFamily.Describe()+" detection")
+		}
+	}
+	for ipFamily := range ipnet.All {
+		if ds := f.Domains[ipFamily]; len(ds) > 0 {
+			parts = append(parts, fmt.Sprintf("%s records of %s",
+				ipFamily.RecordType(), pp.EnglishJoinMapOrEmptyLabel(domain.Domain.Describe, ds, "(none)")))
+		}
+	}
+	if len(f.WAFLists) > 0 {
+		parts = append(parts, "WAF list(s) "+pp.JoinMap(api.WAFList.Describe, f.WAFLists))
+	}
+	if f.WAFListRule {
+		parts = append(parts, "the WAF custom rule")
+	}
+	if len(f.LBPoolOrigins) > 0 {
+		parts = append(parts, "LB pool origin(s) "+pp.JoinMap(api.LBPoolOrigin.Describe, f.LBPoolOrigins))
+	}
+	if len(f.GatewayLocations) > 0 {
+		parts = append(parts, "Gateway location(s) "+pp.JoinMap(api.GatewayLocation.Describe, f.GatewayLocations))
+	}
+	if len(f.AccessGroups) > 0 {
+		parts = append(parts, "Access group(s) "+pp.JoinMap(api.AccessGroup.Describe, f.AccessGroups))
+	}
+	if len(f.IPAccessRules) > 0 {
+		parts = append(parts, "IP access rules "+pp.JoinMap(api.IPAccessRuleSet.Describe, f.IPAccessRules))
+	}
+	if len(f.SpectrumApps) > 0 {
+		parts = append(parts, "Spectrum app(s) "+pp.JoinMap(api.SpectrumApp.Describe, f.SpectrumApps))
+	}
+	if len(f.NFTablesSets) > 0 {
+		parts = append(parts, "nftables set(s) "+pp.JoinMap(nftset.Set.Describe, f.NFTablesSets))
+	}
+	if f.WorkersKV {
+		parts = append(parts, "the Workers KV key")
+	}
+	if f.LocalResolver {
+		parts = append(parts, "the local resolver file")
+	}
+	return pp.EnglishJoinOrEmptyLabel(parts, "(nothing)")
+}
+
+// Restrict returns a copy of c that only manages the failed parts.
+//
+// A family whose detection failed is retried as a whole, including its share
+// of the WAF lists and of every other resource except the local resolver file,
+// which has its own providers. A failed WAF list or other resource needs the
+// addresses of every family, so all families are detected again, but only the
+// failed domains are updated.
+func (f Failures) Restrict(c *config.UpdateConfig) *config.UpdateConfig {
+	restricted := *c
+	restricted.Provider = map[ipnet.Family]provider.Provider{}
+	restricted.Domains = map[ipnet.Family][]domain.Domain{}
+	restricted.WAFLists = f.WAFLists
+	restricted.LBPoolOrigins = f.LBPoolOrigins
+	restricted.GatewayLocations = f.GatewayLocations
+	restricted.AccessGroups = f.AccessGroups
+	restricted.IPAccessRules = f.IPAccessRules
+	restricted.SpectrumApps = f.SpectrumApps
+	restricted.NFTablesSets = f.NFTablesSets
+	if !f.WAFListRule {
+		restricted.WAFListRule = api.WAFListRule{ZoneID: "", Action: ""}
+	}
+	if !f.WorkersKV {
+		restricted.WorkersKV = api.WorkersKVKey{AccountID: "", NamespaceID: "", Key: ""}
+	}
+	if !f.LocalResolver {
+		restricted.LocalResolver = nil
+	}
+
+	familyFailed := false
+	for ipFamily, p := range ipnet.Bindings(c.Provider) {
+		switch {
+		case p == nil:
+			continue
+		case f.Families[ipFamily]:
+			familyFailed = true
+			restricted.Provider[ipFamily] = p
+			restricted.Domains[ipFamily] = c.Domains[ipFamily]
+		case len(f.Domains[ipFamily]) > 0 || f.needsAllFamilies():
+			restricted.Provider[ipFamily] = p
+			restricted.Domains[ipFamily] = f.Domains[ipFamily]
+		}
+	}
+
+	if familyFailed {
+		restricted.WAFLists = c.WAFLists
+		restricted.LBPoolOrigins = c.LBPoolOrigins
+		restricted.GatewayLocations = c.GatewayLocations
+		restricted.AccessGroups = c.AccessGroups
+		restricted.IPAccessRules = c.IPAccessRules
+		restricted.SpectrumApps = c.SpectrumApps
+		restricted.WAFListRule = c.WAFListRule
+		restricted.WorkersKV = c.WorkersKV
+		restricted.NFTablesSets = c.NFTablesSets
+	}
+	return &restricted
+}
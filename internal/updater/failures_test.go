@@ -0,0 +1,130 @@
+package updater_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+func TestFailuresDescribe(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		failures updater.Failures
+		expected string
+	}{
+		"empty": {updater.Failures{Families: nil, Domains: nil, WAFLists: nil}, "(nothing)"},
+		"all": {
+			updater.Failures{
+				Families: map[ipnet.Family]bool{ipnet.IP6: true},
+				Domains:  map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain4_1, domain4_2}},
+				WAFLists: []api.WAFList{{AccountID: "account", Name: "list"}},
+			},
+			"IPv6 detection, A records of ip4.hello1 and ip4.hello2, and WAF list(s) account/list",
+		},
+		"resources": {
+			updater.Failures{
+				Families:      nil,
+				Domains:       nil,
+				WAFLists:      nil,
+				LBPoolOrigins: []api.LBPoolOrigin{{AccountID: "account", PoolID: "pool", OriginName: "origin"}},
+				WorkersKV:     true,
+				LocalResolver: true,
+			},
+			"LB pool origin(s) account/pool:origin, the Workers KV key, and the local resolver file",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, tc.failures.Describe())
+			require.Equal(t, name == "empty", tc.failures.IsEmpty())
+		})
+	}
+}
+
+func TestFailuresRestrict(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	provider4 := mocks.NewMockProvider(mockCtrl)
+	provider6 := mocks.NewMockProvider(mockCtrl)
+	list1 := api.WAFList{AccountID: "account", Name: "list1"}
+	list2 := api.WAFList{AccountID: "account", Name: "list2"}
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "origin"}
+
+	conf := initUpdateConfig()
+	conf.Provider = map[ipnet.Family]provider.Provider{ipnet.IP4: provider4, ipnet.IP6: provider6}
+	conf.Domains = map[ipnet.Family][]domain.Domain{
+		ipnet.IP4: {domain4_1, domain4_2},
+		ipnet.IP6: {domain6},
+	}
+	conf.WAFLists = []api.WAFList{list1, list2}
+	conf.LBPoolOrigins = []api.LBPoolOrigin{origin}
+
+	for name, tc := range map[string]struct {
+		failures updater.Failures
+		provider map[ipnet.Family]provider.Provider
+		domains  map[ipnet.Family][]domain.Domain
+		lists    []api.WAFList
+		origins  []api.LBPoolOrigin
+	}{
+		"family": {
+			updater.Failures{Families: map[ipnet.Family]bool{ipnet.IP6: true}, Domains: nil, WAFLists: nil},
+			map[ipnet.Family]provider.Provider{ipnet.IP6: provider6},
+			map[ipnet.Family][]domain.Domain{ipnet.IP6: {domain6}},
+			[]api.WAFList{list1, list2},
+			[]api.LBPoolOrigin{origin},
+		},
+		"domain": {
+			updater.Failures{
+				Families: nil,
+				Domains:  map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain4_2}},
+				WAFLists: nil,
+			},
+			map[ipnet.Family]provider.Provider{ipnet.IP4: provider4},
+			map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain4_2}},
+			nil,
+			nil,
+		},
+		"list": {
+			updater.Failures{Families: nil, Domains: nil, WAFLists: []api.WAFList{list2}},
+			map[ipnet.Family]provider.Provider{ipnet.IP4: provider4, ipnet.IP6: provider6},
+			map[ipnet.Family][]domain.Domain{ipnet.IP4: nil, ipnet.IP6: nil},
+			[]api.WAFList{list2},
+			nil,
+		},
+		"origin": {
+			updater.Failures{
+				Families: nil, Domains: nil, WAFLists: nil,
+				LBPoolOrigins: []api.LBPoolOrigin{origin},
+			},
+			map[ipnet.Family]provider.Provider{ipnet.IP4: provider4, ipnet.IP6: provider6},
+			map[ipnet.Family][]domain.Domain{ipnet.IP4: nil, ipnet.IP6: nil},
+			nil,
+			[]api.LBPoolOrigin{origin},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			restricted := tc.failures.Restrict(conf)
+			require.Equal(t, tc.provider, restricted.Provider)
+			require.Equal(t, tc.domains, restricted.Domains)
+			require.Equal(t, tc.lists, restricted.WAFLists)
+			require.Equal(t, tc.origins, restricted.LBPoolOrigins)
+			require.Equal(t, conf.TTL, restricted.TTL)
+		})
+	}
+
+	// The original config is untouched.
+	require.Equal(t, []api.LBPoolOrigin{origin}, conf.LBPoolOrigins)
+	require.Len(t, conf.Provider, 2)
+}
@@ -9,10 +9,14 @@ import (
 )
 
 // Message encapsulates the messages to both heartbeat services and notifiers.
+//
+// Report is the machine-readable result of a round of updating or cleanup,
+// present only when [config.UpdateConfig.Report] is set.
 type Message struct {
 	HeartbeatMessage heartbeat.Message
 	NotifierMessage  notifier.Message
 	NotificationKind notifier.Kind
+	Report           *Report
 }
 
 // Notification returns the notifier-facing message with its classification.
@@ -26,6 +30,7 @@ func newMessage() Message {
 		HeartbeatMessage: heartbeat.NewMessage(),
 		NotifierMessage:  notifier.NewMessage(),
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
 
@@ -43,6 +48,7 @@ func mergeMessages(msgs ...Message) Message {
 		HeartbeatMessage: heartbeat.MergeMessages(hms...),
 		NotifierMessage:  notifier.MergeMessages(nms...),
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
 
@@ -0,0 +1,22 @@
+package updater
+
+import (
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+)
+
+// ReportCircuitOpen replaces the messages of a round in which the circuit
+// breaker of the API handle opened. The failures of the individual domains
+// and WAF lists are all caused by the same outage, so they are summarized
+// in one failure instead. The report, if any, is kept as it is.
+func ReportCircuitOpen(msg Message, failureKind notifier.Kind, skipped int) Message {
+	return Message{
+		HeartbeatMessage: heartbeat.NewMessagef(false, "Cloudflare API unavailable (%d calls skipped)", skipped),
+		NotifierMessage: notifier.NewMessagef(
+			"The Cloudflare API kept returning server errors, "+
+				"so the updater skipped %d calls for the rest of this round and will try again in the next round.",
+			skipped),
+		NotificationKind: failureKind,
+		Report:           msg.Report,
+	}
+}
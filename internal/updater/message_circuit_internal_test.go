@@ -0,0 +1,36 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+)
+
+func TestReportCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	report := &Report{} //nolint:exhaustruct
+	msg := Message{
+		HeartbeatMessage: heartbeat.Message{OK: false, Lines: []string{
+			"Failed to set A records for a.example",
+			"Failed to set A records for b.example",
+		}},
+		NotifierMessage: notifier.Message{
+			"Could not confirm that A records of a.example were updated.",
+			"Could not confirm that A records of b.example were updated.",
+		},
+		NotificationKind: notifier.KindUpdateFailure,
+		Report:           report,
+	}
+
+	require.Equal(t, Message{
+		HeartbeatMessage: heartbeat.NewMessagef(false, "Cloudflare API unavailable (3 calls skipped)"),
+		NotifierMessage: notifier.NewMessagef("The Cloudflare API kept returning server errors, " +
+			"so the updater skipped 3 calls for the rest of this round and will try again in the next round."),
+		NotificationKind: notifier.KindCleanupFailure,
+		Report:           report,
+	}, ReportCircuitOpen(msg, notifier.KindCleanupFailure, 3))
+}
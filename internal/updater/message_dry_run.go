@@ -0,0 +1,91 @@
+package updater
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func changeWord(n int) string {
+	if n == 1 {
+		return "change"
+	}
+	return "changes"
+}
+
+// groupPlan groups the planned changes by their subjects,
+// keeping the order in which the subjects first appeared.
+func groupPlan(plan []api.PlannedChange) ([]string, map[string][]string) {
+	var subjects []string
+	actions := map[string][]string{}
+	for _, change := range plan {
+		if _, seen := actions[change.Subject]; !seen {
+			subjects = append(subjects, change.Subject)
+		}
+		actions[change.Subject] = append(actions[change.Subject], change.Action)
+	}
+	return subjects, actions
+}
+
+// reportPlan prints the full plan of a dry run, one domain or WAF list at a time.
+func reportPlan(ppfmt pp.PP, plan []api.PlannedChange) {
+	if len(plan) == 0 {
+		ppfmt.Infof(pp.EmojiAlreadyDone, "Dry run: no changes are planned")
+		return
+	}
+
+	ppfmt.Noticef(pp.EmojiDryRun, "Dry run: %d %s planned; nothing was changed", len(plan), changeWord(len(plan)))
+	subjects, actions := groupPlan(plan)
+	inner := ppfmt.Indent()
+	for _, subject := range subjects {
+		inner.Noticef(pp.EmojiBullet, "%s: %s", subject, strings.Join(actions[subject], "; "))
+	}
+}
+
+// generateDryRunMessage replaces the description of the successful updates in msg,
+// which did not happen, with the plan. Failure messages are kept as they are.
+func generateDryRunMessage(msg Message, plan []api.PlannedChange) Message {
+	var heartbeatLine string
+	if len(plan) == 0 {
+		heartbeatLine = "Dry run: no changes planned"
+	} else {
+		heartbeatLine = fmt.Sprintf("Dry run: %d %s planned", len(plan), changeWord(len(plan)))
+	}
+
+	var fragments []string
+	if len(plan) > 0 {
+		fragments = append(fragments, "Dry run; nothing was changed. Planned changes: ")
+		subjects, actions := groupPlan(plan)
+		for i, subject := range subjects {
+			if i > 0 {
+				fragments = append(fragments, "; ")
+			}
+			fragments = append(fragments, fmt.Sprintf("for %s, %s",
+				subject, pp.EnglishJoinOrEmptyLabel(actions[subject], "(none)")))
+		}
+	}
+	notifierMessage := finishNotifierMessage(fragments)
+
+	if msg.HeartbeatMessage.OK {
+		return Message{
+			HeartbeatMessage: heartbeat.NewMessagef(true, "%s", heartbeatLine),
+			NotifierMessage:  notifierMessage,
+			NotificationKind: msg.NotificationKind,
+			Report:           msg.Report,
+		}
+	}
+
+	return Message{
+		HeartbeatMessage: heartbeat.Message{
+			OK:    false,
+			Lines: append([]string{heartbeatLine}, msg.HeartbeatMessage.Lines...),
+		},
+		NotifierMessage:  notifier.MergeMessages(notifierMessage, msg.NotifierMessage),
+		NotificationKind: msg.NotificationKind,
+		Report:           msg.Report,
+	}
+}
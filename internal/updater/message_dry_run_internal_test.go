@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+)
+
+func TestGenerateDryRunMessage(t *testing.T) {
+	t.Parallel()
+
+	plan := []api.PlannedChange{
+		{Subject: "a.example", Action: "update the A record r1 to 1.2.3.4"},
+		{Subject: "account/list", Action: "add 1.2.3.4/32"},
+		{Subject: "a.example", Action: "delete the A record r2"},
+	}
+	planSentence := "Dry run; nothing was changed. Planned changes: " +
+		"for a.example, update the A record r1 to 1.2.3.4 and delete the A record r2; " +
+		"for account/list, add 1.2.3.4/32."
+
+	for name, tc := range map[string]struct {
+		msg      Message
+		plan     []api.PlannedChange
+		expected Message
+	}{
+		"ok": {
+			Message{
+				HeartbeatMessage: heartbeat.NewMessagef(true, "Set A records for a.example to 1.2.3.4"),
+				NotifierMessage:  notifier.NewMessagef("Updated A records for a.example to 1.2.3.4."),
+				NotificationKind: notifier.KindUpdate,
+				Report:           nil,
+			},
+			plan,
+			Message{
+				HeartbeatMessage: heartbeat.NewMessagef(true, "Dry run: 3 changes planned"),
+				NotifierMessage:  notifier.Message{planSentence},
+				NotificationKind: notifier.KindUpdate,
+				Report:           nil,
+			},
+		},
+		"nothing": {
+			Message{
+				HeartbeatMessage: heartbeat.NewMessagef(true, "Set A records for a.example to 1.2.3.4"),
+				NotifierMessage:  nil,
+				NotificationKind: notifier.KindUpdate,
+				Report:           nil,
+			},
+			nil,
+			Message{
+				HeartbeatMessage: heartbeat.NewMessagef(true, "Dry run: no changes planned"),
+				NotifierMessage:  nil,
+				NotificationKind: notifier.KindUpdate,
+				Report:           nil,
+			},
+		},
+		"failure": {
+			Message{
+				HeartbeatMessage: heartbeat.NewMessagef(false, "Failed to set AAAA records for b.example"),
+				NotifierMessage:  notifier.NewMessagef("Could not confirm that AAAA records of b.example were updated."),
+				NotificationKind: notifier.KindUpdateFailure,
+				Report:           nil,
+			},
+			plan[:1],
+			Message{
+				HeartbeatMessage: heartbeat.Message{OK: false, Lines: []string{
+					"Dry run: 1 change planned",
+					"Failed to set AAAA records for b.example",
+				}},
+				NotifierMessage: notifier.Message{
+					"Dry run; nothing was changed. Planned changes: for a.example, update the A record r1 to 1.2.3.4.",
+					"Could not confirm that AAAA records of b.example were updated.",
+				},
+				NotificationKind: notifier.KindUpdateFailure,
+				Report:           nil,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, generateDryRunMessage(tc.msg, tc.plan))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package updater
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+func describeDomains(domains []domain.Domain) []string {
+	descriptions := make([]string, 0, len(domains))
+	for _, d := range domains {
+		descriptions = append(descriptions, d.Describe())
+	}
+	return descriptions
+}
+
+func describeAccounts(accountIDs []api.ID) []string {
+	descriptions := make([]string, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		descriptions = append(descriptions, id.String())
+	}
+	return descriptions
+}
+
+func generatePermissionHeartbeatMessage(r api.PermissionReport) heartbeat.Message {
+	if r.HasProblems() {
+		var lines []string
+		if r.CredentialProblem != "" {
+			lines = append(lines, fmt.Sprintf("Credentials %s", r.CredentialProblem))
+		}
+		if len(r.DomainsWithoutZone) > 0 {
+			lines = append(lines, fmt.Sprintf("No zone found for %s", pp.Join(describeDomains(r.DomainsWithoutZone))))
+		}
+		if len(r.DomainsWithoutDNSEdit) > 0 {
+			lines = append(lines, fmt.Sprintf("No DNS edit permission for %s",
+				pp.Join(describeDomains(r.DomainsWithoutDNSEdit))))
+		}
+		if len(r.AccountsWithoutWAFListEdit) > 0 {
+			lines = append(lines, fmt.Sprintf("No WAF list edit permission for account(s) %s",
+				pp.Join(describeAccounts(r.AccountsWithoutWAFListEdit))))
+		}
+		return heartbeat.Message{OK: false, Lines: lines}
+	}
+
+	var lines []string
+	switch {
+	case r.Inspected:
+		lines = append(lines, "Permissions confirmed")
+	case r.Verified:
+		lines = append(lines, "Credentials verified")
+	default:
+		lines = append(lines, "Credentials not verified")
+	}
+	if !r.ExpiresOn.IsZero() {
+		lines = append(lines, fmt.Sprintf("Token expires at %s", r.ExpiresOn.Format(time.RFC3339)))
+	}
+	return heartbeat.Message{OK: true, Lines: lines}
+}
+
+func generatePermissionNotifierMessage(r api.PermissionReport) notifier.Message {
+	var fragments []string
+
+	if r.CredentialProblem != "" {
+		fragments = append(fragments, fmt.Sprintf(
+			"The credentials for Cloudflare are %s", r.CredentialProblem))
+	}
+
+	if len(r.DomainsWithoutZone) > 0 {
+		fragments = appendNotifierFragmentf(
+			fragments,
+			"No zone was found for %s",
+			"; no zone was found for %s",
+			describeDomainsInEnglish(describeDomains(r.DomainsWithoutZone)),
+		)
+	}
+
+	if len(r.DomainsWithoutDNSEdit) > 0 {
+		fragments = appendNotifierFragmentf(
+			fragments,
+			`The API token lacks the "Edit" permission of "Zone - DNS" for %s`,
+			`; the API token lacks the "Edit" permission of "Zone - DNS" for %s`,
+			describeDomainsInEnglish(describeDomains(r.DomainsWithoutDNSEdit)),
+		)
+	}
+
+	if len(r.AccountsWithoutWAFListEdit) > 0 {
+		fragments = appendNotifierFragmentf(
+			fragments,
+			`The API token lacks the "Edit" permission of "Account - Account Filter Lists" for the account(s) %s`,
+			`; the API token lacks the "Edit" permission of "Account - Account Filter Lists" for the account(s) %s`,
+			describeDomainsInEnglish(describeAccounts(r.AccountsWithoutWAFListEdit)),
+		)
+	}
+
+	if !r.ExpiresOn.IsZero() {
+		fragments = appendNotifierFragmentf(
+			fragments,
+			"The API token will expire at %s",
+			"; the API token will expire at %s",
+			r.ExpiresOn.Format(time.RFC3339),
+		)
+	}
+
+	return finishNotifierMessage(fragments)
+}
+
+func generatePermissionMessage(r api.PermissionReport) Message {
+	return classifyNotification(
+		Message{
+			HeartbeatMessage: generatePermissionHeartbeatMessage(r),
+			NotifierMessage:  generatePermissionNotifierMessage(r),
+			NotificationKind: "",
+			Report:           nil,
+		},
+		notifier.KindPermission,
+		notifier.KindPermissionFailure,
+	)
+}
@@ -0,0 +1,79 @@
+package updater
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/notifier"
+)
+
+func TestGeneratePermissionMessage(t *testing.T) {
+	t.Parallel()
+
+	expiresOn := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		report    api.PermissionReport
+		heartbeat heartbeat.Message
+		notifier  notifier.Message
+		kind      notifier.Kind
+	}{
+		"confirmed": {
+			api.PermissionReport{Verified: true, Inspected: true}, //nolint:exhaustruct
+			heartbeat.Message{OK: true, Lines: []string{"Permissions confirmed"}},
+			nil,
+			notifier.KindPermission,
+		},
+		"verified-expiring": {
+			api.PermissionReport{Verified: true, ExpiresOn: expiresOn}, //nolint:exhaustruct
+			heartbeat.Message{OK: true, Lines: []string{"Credentials verified", "Token expires at 2030-01-02T03:04:05Z"}},
+			notifier.Message{"The API token will expire at 2030-01-02T03:04:05Z."},
+			notifier.KindPermission,
+		},
+		"unverified": {
+			api.PermissionReport{}, //nolint:exhaustruct
+			heartbeat.Message{OK: true, Lines: []string{"Credentials not verified"}},
+			nil,
+			notifier.KindPermission,
+		},
+		"credentials": {
+			api.PermissionReport{Verified: true, CredentialProblem: "expired"}, //nolint:exhaustruct
+			heartbeat.Message{OK: false, Lines: []string{"Credentials expired"}},
+			notifier.Message{"The credentials for Cloudflare are expired."},
+			notifier.KindPermissionFailure,
+		},
+		"missing": {
+			api.PermissionReport{ //nolint:exhaustruct
+				Verified:                   true,
+				Inspected:                  true,
+				DomainsWithoutZone:         []domain.Domain{domain.FQDN("a.example")},
+				DomainsWithoutDNSEdit:      []domain.Domain{domain.FQDN("b.example"), domain.Wildcard("b.example")},
+				AccountsWithoutWAFListEdit: []api.ID{"account"},
+			},
+			heartbeat.Message{OK: false, Lines: []string{
+				"No zone found for a.example",
+				"No DNS edit permission for b.example, *.b.example",
+				"No WAF list edit permission for account(s) account",
+			}},
+			notifier.Message{
+				`No zone was found for a.example; ` +
+					`the API token lacks the "Edit" permission of "Zone - DNS" for b.example and *.b.example; ` +
+					`the API token lacks the "Edit" permission of "Account - Account Filter Lists" for the account(s) account.`,
+			},
+			notifier.KindPermissionFailure,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			msg := generatePermissionMessage(tc.report)
+			require.Equal(t, tc.heartbeat, msg.HeartbeatMessage)
+			require.Equal(t, tc.notifier, msg.NotifierMessage)
+			require.Equal(t, tc.kind, msg.NotificationKind)
+		})
+	}
+}
@@ -36,6 +36,7 @@ func generateDetectMessage(ipFamily ipnet.Family, ok bool) Message {
 				fmt.Sprintf("Failed to detect any %s addresses.", ipFamily.Describe()),
 			},
 			NotificationKind: "",
+			Report:           nil,
 		}
 	}
 }
@@ -51,6 +52,7 @@ func generateFilterAbortDetectMessage(ipFamily ipnet.Family) Message {
 			summary + ".",
 		},
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
 
@@ -65,6 +67,7 @@ func generateIP6DerivationFailureMessage() Message {
 			message + ".",
 		},
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
 
@@ -77,6 +80,7 @@ func generateMissingTargetSetsMessage(ipFamily ipnet.Family, domains []domain.Do
 			summary + " because of an internal error; check the logs for details.",
 		},
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
 
@@ -232,12 +236,14 @@ func generateClearOrUpdateMessage(ipFamily ipnet.Family, ips []netip.Addr, s set
 			HeartbeatMessage: generateClearHeartbeatMessage(ipFamily, s),
 			NotifierMessage:  generateClearNotifierMessage(ipFamily, s),
 			NotificationKind: "",
+			Report:           nil,
 		}
 	} else {
 		return Message{
 			HeartbeatMessage: generateUpdateHeartbeatMessage(ipFamily, ips, s),
 			NotifierMessage:  generateUpdateNotifierMessage(ipFamily, ips, s),
 			NotificationKind: "",
+			Report:           nil,
 		}
 	}
 }
@@ -307,5 +313,6 @@ func generateFinalDeleteMessage(ipFamily ipnet.Family, s setterResponses) Messag
 		HeartbeatMessage: generateFinalDeleteHeartbeatMessage(ipFamily, s),
 		NotifierMessage:  generateFinalDeleteNotifierMessage(ipFamily, s),
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
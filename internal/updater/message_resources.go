@@ -0,0 +1,128 @@
+package updater
+
+import (
+	"fmt"
+
+	"github.com/favonia/cloudflare-ddns/internal/heartbeat"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// setterResourceResponses groups the names of resources (other than DNS records
+// and WAF lists) by the responses of the setter.
+type setterResourceResponses map[setter.ResponseCode][]string
+
+func emptySetterResourceResponses() setterResourceResponses {
+	return setterResourceResponses{}
+}
+
+func (s setterResourceResponses) register(name string, code setter.ResponseCode) {
+	s[code] = append(s[code], name)
+}
+
+// generateResourceMessage follows the WAF-list messages: terse heartbeat
+// lines and fuller notifier prose. The resource is a plural noun such as
+// "LB pool origin(s)". The other words describe what happened to the resources:
+// "update"/"Updated"/"updated" during updates and, for example,
+// "disabling"/"Disabled"/"disabled" during cleanup.
+func generateResourceMessage(s setterResourceResponses, resource, noun, initialVerb, continuedVerb string) Message {
+	heartbeatMessage := heartbeat.Message{OK: true, Lines: nil}
+	var fragments []string
+
+	if names := s[setter.ResponseFailed]; len(names) > 0 {
+		heartbeatMessage = heartbeat.Message{
+			OK:    false,
+			Lines: []string{fmt.Sprintf("Could not confirm %s of %s %s", noun, resource, pp.Join(names))},
+		}
+		fragments = append(fragments, fmt.Sprintf(
+			"Could not confirm %s of %s %s", noun, resource, describeDomainsInEnglish(names)))
+	}
+
+	if names := s[setter.ResponseUpdated]; len(names) > 0 {
+		if heartbeatMessage.OK {
+			heartbeatMessage.Lines = append(heartbeatMessage.Lines, fmt.Sprintf(
+				"%s %s %s", initialVerb, resource, pp.Join(names)))
+		}
+		fragments = appendNotifierFragmentf(
+			fragments,
+			initialVerb+" "+resource+" %s",
+			"; "+continuedVerb+" %s",
+			describeDomainsInEnglish(names),
+		)
+	}
+
+	return Message{
+		HeartbeatMessage: heartbeatMessage,
+		NotifierMessage:  finishNotifierMessage(fragments),
+		NotificationKind: "",
+		Report:           nil,
+	}
+}
+
+func generateUpdateLBPoolOriginsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "LB pool origin(s)", "update", "Updated", "updated")
+}
+
+func generateFinalDisableLBPoolOriginsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "LB pool origin(s)", "disabling", "Disabled", "disabled")
+}
+
+func generateUpdateGatewayLocationsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Gateway location(s)", "update", "Updated", "updated")
+}
+
+func generateFinalClearGatewayLocationsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Gateway location(s)", "cleanup", "Cleaned", "cleaned")
+}
+
+func generateUpdateAccessGroupsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Access group(s)", "update", "Updated", "updated")
+}
+
+func generateFinalClearAccessGroupsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Access group(s)", "cleanup", "Cleaned", "cleaned")
+}
+
+func generateUpdateIPAccessRulesMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "IP access rules", "update", "Updated", "updated")
+}
+
+func generateFinalClearIPAccessRulesMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "IP access rules", "cleanup", "Cleaned", "cleaned")
+}
+
+func generateUpdateSpectrumAppsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Spectrum app(s)", "update", "Updated", "updated")
+}
+
+func generateUpdateWAFListRuleMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "WAF custom rule(s)", "update", "Updated", "updated")
+}
+
+func generateFinalDeleteWAFListRuleMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "WAF custom rule(s)", "deletion", "Deleted", "deleted")
+}
+
+func generateUpdateWorkersKVMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Workers KV key(s)", "update", "Updated", "updated")
+}
+
+func generateFinalDeleteWorkersKVMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "Workers KV key(s)", "deletion", "Deleted", "deleted")
+}
+
+func generateUpdateLocalResolverMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "local resolver file(s)", "update", "Updated", "updated")
+}
+
+func generateFinalClearLocalResolverMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "local resolver file(s)", "cleanup", "Cleaned", "cleaned")
+}
+
+func generateUpdateNFTablesSetsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "nftables set(s)", "update", "Updated", "updated")
+}
+
+func generateFinalClearNFTablesSetsMessage(s setterResourceResponses) Message {
+	return generateResourceMessage(s, "nftables set(s)", "cleanup", "Cleaned", "cleaned")
+}
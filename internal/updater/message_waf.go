@@ -80,6 +80,7 @@ func generateUpdateWAFListsMessage(s setterWAFListResponses) Message {
 		HeartbeatMessage: generateUpdateWAFListsHeartbeatMessage(s),
 		NotifierMessage:  generateUpdateWAFListsNotifierMessage(s),
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
 
@@ -141,5 +142,6 @@ func generateFinalClearWAFListsMessage(s setterWAFListResponses) Message {
 		HeartbeatMessage: generateFinalClearWAFListsHeartbeatMessage(s),
 		NotifierMessage:  generateFinalClearWAFListsNotifierMessage(s),
 		NotificationKind: "",
+		Report:           nil,
 	}
 }
@@ -0,0 +1,547 @@
+package updater
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/provider"
+	"github.com/favonia/cloudflare-ddns/internal/setter"
+)
+
+// Report is the machine-readable result of one round of reconciliation.
+// All lists are present (possibly empty) so that reports can be compared textually.
+type Report struct {
+	Kind     string          `json:"kind"`
+	OK       bool            `json:"ok"`
+	Families []FamilyReport  `json:"families"`
+	Domains  []DomainReport  `json:"domains"`
+	WAFLists []WAFListReport `json:"wafLists"`
+
+	LBPoolOrigins    []LBPoolOriginReport    `json:"lbPoolOrigins"`
+	GatewayLocations []GatewayLocationReport `json:"gatewayLocations"`
+	AccessGroups     []AccessGroupReport     `json:"accessGroups"`
+	IPAccessRules    []IPAccessRulesReport   `json:"ipAccessRules"`
+	SpectrumApps     []SpectrumAppReport     `json:"spectrumApps"`
+	WAFListRules     []WAFListRuleReport     `json:"wafListRules"`
+	WorkersKV        []WorkersKVReport       `json:"workersKV"`
+
+	MirroredDomains []MirroredDomainReport `json:"mirroredDomains"`
+}
+
+// FamilyReport records the detection result of one IP family.
+type FamilyReport struct {
+	Family     string   `json:"family"`
+	Available  bool     `json:"available"`
+	RawEntries []string `json:"rawEntries"`
+}
+
+// RecordReport is one DNS record in a [DomainReport].
+type RecordReport struct {
+	ID string `json:"id"`
+	IP string `json:"ip"`
+}
+
+// DomainReport records the reconciliation of one domain for one IP family.
+type DomainReport struct {
+	Domain   string         `json:"domain"`
+	Family   string         `json:"family"`
+	Targets  []string       `json:"targets"`
+	Matched  []RecordReport `json:"matched"`
+	Updated  []RecordReport `json:"updated"`
+	Created  []RecordReport `json:"created"`
+	Deleted  []RecordReport `json:"deleted"`
+	Response string         `json:"response"`
+}
+
+// MirroredDomainReport records the changes made to the DNS records of one
+// domain for one IP family on a mirror, such as an RFC 2136 server. The
+// response of the reconciliation is the one in the matching [DomainReport].
+type MirroredDomainReport struct {
+	Mirror  string         `json:"mirror"`
+	Domain  string         `json:"domain"`
+	Family  string         `json:"family"`
+	Matched []RecordReport `json:"matched"`
+	Updated []RecordReport `json:"updated"`
+	Created []RecordReport `json:"created"`
+	Deleted []RecordReport `json:"deleted"`
+}
+
+// WAFListReport records the reconciliation of one WAF list.
+type WAFListReport struct {
+	List     string   `json:"list"`
+	Targets  []string `json:"targets"`
+	Matched  []string `json:"matched"`
+	Created  []string `json:"created"`
+	Deleted  []string `json:"deleted"`
+	Response string   `json:"response"`
+}
+
+// LBPoolOriginReport records the reconciliation of one load balancer pool origin.
+// The addresses are empty if the origin could not be read.
+type LBPoolOriginReport struct {
+	Origin          string   `json:"origin"`
+	Targets         []string `json:"targets"`
+	PreviousAddress string   `json:"previousAddress"`
+	PreviousEnabled bool     `json:"previousEnabled"`
+	Address         string   `json:"address"`
+	Enabled         bool     `json:"enabled"`
+	Response        string   `json:"response"`
+}
+
+// GatewayLocationReport records the reconciliation of one Gateway location.
+// The networks are empty if the location could not be read.
+type GatewayLocationReport struct {
+	Location string   `json:"location"`
+	Targets  []string `json:"targets"`
+	Previous []string `json:"previous"`
+	Current  []string `json:"current"`
+	Response string   `json:"response"`
+}
+
+// AccessGroupReport records the reconciliation of one Access group.
+// The IP rules are empty if the group could not be read.
+type AccessGroupReport struct {
+	Group    string   `json:"group"`
+	Targets  []string `json:"targets"`
+	Previous []string `json:"previous"`
+	Current  []string `json:"current"`
+	Response string   `json:"response"`
+}
+
+// IPAccessRulesReport records the reconciliation of one set of IP Access Rules.
+type IPAccessRulesReport struct {
+	Rules    string   `json:"rules"`
+	Targets  []string `json:"targets"`
+	Matched  []string `json:"matched"`
+	Created  []string `json:"created"`
+	Deleted  []string `json:"deleted"`
+	Response string   `json:"response"`
+}
+
+// SpectrumAppReport records the reconciliation of one Spectrum application.
+// The origins are empty if the application could not be read.
+type SpectrumAppReport struct {
+	App      string   `json:"app"`
+	Targets  []string `json:"targets"`
+	Previous []string `json:"previous"`
+	Current  []string `json:"current"`
+	Response string   `json:"response"`
+}
+
+// WAFListRuleReport records the reconciliation of the WAF custom rule of one zone.
+// The rules are listed by their IDs.
+type WAFListRuleReport struct {
+	Rule       string   `json:"rule"`
+	Expression string   `json:"expression"`
+	Matched    []string `json:"matched"`
+	Updated    []string `json:"updated"`
+	Created    []string `json:"created"`
+	Deleted    []string `json:"deleted"`
+	Response   string   `json:"response"`
+}
+
+// WorkersKVReport records the publication of the addresses in one Workers KV key.
+// The addresses are empty if the key could not be read.
+type WorkersKVReport struct {
+	Key      string   `json:"key"`
+	Targets  []string `json:"targets"`
+	Previous []string `json:"previous"`
+	Current  []string `json:"current"`
+	Response string   `json:"response"`
+}
+
+// reportBuilder collects the parts of a [Report] while the updater runs.
+// A nil builder collects nothing, which is how reports are disabled.
+type reportBuilder struct {
+	families  []FamilyReport
+	domains   []pendingDomainReport
+	wafLists  []pendingWAFListReport
+	origins   []pendingLBPoolOriginReport
+	locations []pendingGatewayLocationReport
+	groups    []pendingAccessGroupReport
+	ruleSets  []pendingIPAccessRulesReport
+	apps      []pendingSpectrumAppReport
+	rules     []pendingWAFListRuleReport
+	kvKeys    []pendingWorkersKVReport
+}
+
+type pendingDomainReport struct {
+	scope    setter.RecordScope
+	targets  []netip.Addr
+	response setter.ResponseCode
+}
+
+type pendingWAFListReport struct {
+	list     api.WAFList
+	targets  []netip.Prefix
+	response setter.ResponseCode
+}
+
+type pendingLBPoolOriginReport struct {
+	origin   api.LBPoolOrigin
+	targets  []netip.Addr
+	response setter.ResponseCode
+}
+
+type pendingGatewayLocationReport struct {
+	location api.GatewayLocation
+	targets  []netip.Prefix
+	response setter.ResponseCode
+}
+
+type pendingAccessGroupReport struct {
+	group    api.AccessGroup
+	targets  []netip.Prefix
+	response setter.ResponseCode
+}
+
+type pendingIPAccessRulesReport struct {
+	set      api.IPAccessRuleSet
+	targets  []netip.Prefix
+	response setter.ResponseCode
+}
+
+type pendingSpectrumAppReport struct {
+	app      api.SpectrumApp
+	targets  []netip.Addr
+	response setter.ResponseCode
+}
+
+type pendingWAFListRuleReport struct {
+	rule       api.WAFListRule
+	expression string
+	response   setter.ResponseCode
+}
+
+type pendingWorkersKVReport struct {
+	key      api.WorkersKVKey
+	targets  []netip.Addr
+	response setter.ResponseCode
+}
+
+func newReportBuilder(enabled bool) *reportBuilder {
+	if !enabled {
+		return nil
+	}
+	return &reportBuilder{
+		families: nil, domains: nil, wafLists: nil,
+		origins: nil, locations: nil, groups: nil, ruleSets: nil, apps: nil, rules: nil, kvKeys: nil,
+	}
+}
+
+func (b *reportBuilder) addFamily(ipFamily ipnet.Family, rawData provider.DetectionResult) {
+	if b == nil {
+		return
+	}
+	rawEntries := make([]string, 0, len(rawData.RawEntries))
+	for _, entry := range rawData.RawEntries {
+		rawEntries = append(rawEntries, entry.String())
+	}
+	b.families = append(b.families, FamilyReport{
+		Family:     ipFamily.Describe(),
+		Available:  rawData.Available,
+		RawEntries: rawEntries,
+	})
+}
+
+func (b *reportBuilder) addDomain(ipFamily ipnet.Family, d domain.Domain, targets []netip.Addr,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	b.domains = append(b.domains, pendingDomainReport{
+		scope:    setter.RecordScope{IPFamily: ipFamily, Domain: d},
+		targets:  targets,
+		response: response,
+	})
+}
+
+func (b *reportBuilder) addWAFList(list api.WAFList, targets map[ipnet.Family]setter.WAFTargets,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var prefixes []netip.Prefix
+	for ipFamily := range ipnet.All {
+		if t, ok := targets[ipFamily]; ok && t.Available {
+			prefixes = append(prefixes, t.Prefixes...)
+		}
+	}
+	b.wafLists = append(b.wafLists, pendingWAFListReport{list: list, targets: prefixes, response: response})
+}
+
+func (b *reportBuilder) addLBPoolOrigin(origin api.LBPoolOrigin, targets map[ipnet.Family][]netip.Addr,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var addresses []netip.Addr
+	for ipFamily := range ipnet.All {
+		addresses = append(addresses, targets[ipFamily]...)
+	}
+	b.origins = append(b.origins, pendingLBPoolOriginReport{origin: origin, targets: addresses, response: response})
+}
+
+func (b *reportBuilder) addGatewayLocation(location api.GatewayLocation, targets map[ipnet.Family]setter.WAFTargets,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var prefixes []netip.Prefix
+	for ipFamily := range ipnet.All {
+		if t, ok := targets[ipFamily]; ok && t.Available {
+			prefixes = append(prefixes, t.Prefixes...)
+		}
+	}
+	b.locations = append(b.locations,
+		pendingGatewayLocationReport{location: location, targets: prefixes, response: response})
+}
+
+func (b *reportBuilder) addAccessGroup(group api.AccessGroup, targets map[ipnet.Family]setter.WAFTargets,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var prefixes []netip.Prefix
+	for ipFamily := range ipnet.All {
+		if t, ok := targets[ipFamily]; ok && t.Available {
+			prefixes = append(prefixes, t.Prefixes...)
+		}
+	}
+	b.groups = append(b.groups, pendingAccessGroupReport{group: group, targets: prefixes, response: response})
+}
+
+func (b *reportBuilder) addIPAccessRules(set api.IPAccessRuleSet, targets map[ipnet.Family]setter.WAFTargets,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var prefixes []netip.Prefix
+	for ipFamily := range ipnet.All {
+		if t, ok := targets[ipFamily]; ok && t.Available {
+			prefixes = append(prefixes, t.Prefixes...)
+		}
+	}
+	b.ruleSets = append(b.ruleSets, pendingIPAccessRulesReport{set: set, targets: prefixes, response: response})
+}
+
+func describeRecords(records []api.Record) []RecordReport {
+	reports := make([]RecordReport, 0, len(records))
+	for _, r := range records {
+		reports = append(reports, RecordReport{ID: r.ID.String(), IP: r.IP.String()})
+	}
+	return reports
+}
+
+func (b *reportBuilder) addSpectrumApp(app api.SpectrumApp, targets map[ipnet.Family][]netip.Addr,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var addresses []netip.Addr
+	for ipFamily := range ipnet.All {
+		addresses = append(addresses, targets[ipFamily]...)
+	}
+	b.apps = append(b.apps, pendingSpectrumAppReport{app: app, targets: addresses, response: response})
+}
+
+func describeStringers[T interface{ String() string }](items []T) []string {
+	ss := make([]string, 0, len(items))
+	for _, item := range items {
+		ss = append(ss, item.String())
+	}
+	return ss
+}
+
+func (b *reportBuilder) addWAFListRule(rule api.WAFListRule, expression string, response setter.ResponseCode) {
+	if b == nil {
+		return
+	}
+	b.rules = append(b.rules, pendingWAFListRuleReport{rule: rule, expression: expression, response: response})
+}
+
+func (b *reportBuilder) addWorkersKV(key api.WorkersKVKey, targets map[ipnet.Family][]netip.Addr,
+	response setter.ResponseCode,
+) {
+	if b == nil {
+		return
+	}
+	var addresses []netip.Addr
+	for ipFamily := range ipnet.All {
+		addresses = append(addresses, targets[ipFamily]...)
+	}
+	b.kvKeys = append(b.kvKeys, pendingWorkersKVReport{key: key, targets: addresses, response: response})
+}
+
+// build combines the collected parts with the changes reported by the setter.
+func (b *reportBuilder) build(kind string, ok bool, changes setter.Changes) *Report {
+	if b == nil {
+		return nil
+	}
+
+	report := &Report{
+		Kind:     kind,
+		OK:       ok,
+		Families: b.families,
+		Domains:  make([]DomainReport, 0, len(b.domains)),
+		WAFLists: make([]WAFListReport, 0, len(b.wafLists)),
+
+		LBPoolOrigins:    make([]LBPoolOriginReport, 0, len(b.origins)),
+		GatewayLocations: make([]GatewayLocationReport, 0, len(b.locations)),
+		AccessGroups:     make([]AccessGroupReport, 0, len(b.groups)),
+		IPAccessRules:    make([]IPAccessRulesReport, 0, len(b.ruleSets)),
+		SpectrumApps:     make([]SpectrumAppReport, 0, len(b.apps)),
+		WAFListRules:     make([]WAFListRuleReport, 0, len(b.rules)),
+		WorkersKV:        make([]WorkersKVReport, 0, len(b.kvKeys)),
+
+		MirroredDomains: []MirroredDomainReport{},
+	}
+	if report.Families == nil {
+		report.Families = []FamilyReport{}
+	}
+
+	for _, d := range b.domains {
+		c := changes.Records[d.scope]
+		report.Domains = append(report.Domains, DomainReport{
+			Domain:   d.scope.Domain.Describe(),
+			Family:   d.scope.IPFamily.Describe(),
+			Targets:  describeStringers(d.targets),
+			Matched:  describeRecords(c.Matched),
+			Updated:  describeRecords(c.Updated),
+			Created:  describeRecords(c.Created),
+			Deleted:  describeRecords(c.Deleted),
+			Response: d.response.String(),
+		})
+	}
+
+	for _, mirror := range slices.Sorted(maps.Keys(changes.Mirrors)) {
+		for _, d := range b.domains {
+			c, ok := changes.Mirrors[mirror][d.scope]
+			if !ok {
+				continue
+			}
+			report.MirroredDomains = append(report.MirroredDomains, MirroredDomainReport{
+				Mirror:  mirror,
+				Domain:  d.scope.Domain.Describe(),
+				Family:  d.scope.IPFamily.Describe(),
+				Matched: describeRecords(c.Matched),
+				Updated: describeRecords(c.Updated),
+				Created: describeRecords(c.Created),
+				Deleted: describeRecords(c.Deleted),
+			})
+		}
+	}
+
+	for _, l := range b.wafLists {
+		c := changes.WAFLists[l.list]
+		report.WAFLists = append(report.WAFLists, WAFListReport{
+			List:     l.list.Describe(),
+			Targets:  describeStringers(l.targets),
+			Matched:  describeStringers(c.Matched),
+			Created:  describeStringers(c.Created),
+			Deleted:  describeStringers(c.Deleted),
+			Response: l.response.String(),
+		})
+	}
+
+	for _, o := range b.origins {
+		r := LBPoolOriginReport{
+			Origin:          o.origin.Describe(),
+			Targets:         describeStringers(o.targets),
+			PreviousAddress: "",
+			PreviousEnabled: false,
+			Address:         "",
+			Enabled:         false,
+			Response:        o.response.String(),
+		}
+		if c, ok := changes.LBPoolOrigins[o.origin]; ok {
+			r.PreviousAddress = c.Previous.Address.String()
+			r.PreviousEnabled = c.Previous.Enabled
+			r.Address = c.Current.Address.String()
+			r.Enabled = c.Current.Enabled
+		}
+		report.LBPoolOrigins = append(report.LBPoolOrigins, r)
+	}
+
+	for _, l := range b.locations {
+		c := changes.GatewayLocations[l.location]
+		report.GatewayLocations = append(report.GatewayLocations, GatewayLocationReport{
+			Location: l.location.Describe(),
+			Targets:  describeStringers(l.targets),
+			Previous: describeStringers(c.Previous),
+			Current:  describeStringers(c.Current),
+			Response: l.response.String(),
+		})
+	}
+
+	for _, g := range b.groups {
+		c := changes.AccessGroups[g.group]
+		report.AccessGroups = append(report.AccessGroups, AccessGroupReport{
+			Group:    g.group.Describe(),
+			Targets:  describeStringers(g.targets),
+			Previous: describeStringers(c.Previous),
+			Current:  describeStringers(c.Current),
+			Response: g.response.String(),
+		})
+	}
+
+	for _, r := range b.ruleSets {
+		c := changes.IPAccessRules[r.set]
+		report.IPAccessRules = append(report.IPAccessRules, IPAccessRulesReport{
+			Rules:    r.set.Describe(),
+			Targets:  describeStringers(r.targets),
+			Matched:  describeStringers(c.Matched),
+			Created:  describeStringers(c.Created),
+			Deleted:  describeStringers(c.Deleted),
+			Response: r.response.String(),
+		})
+	}
+
+	for _, a := range b.apps {
+		c := changes.SpectrumApps[a.app]
+		report.SpectrumApps = append(report.SpectrumApps, SpectrumAppReport{
+			App:      a.app.Describe(),
+			Targets:  describeStringers(a.targets),
+			Previous: describeStringers(c.Previous),
+			Current:  describeStringers(c.Current),
+			Response: a.response.String(),
+		})
+	}
+
+	for _, r := range b.rules {
+		c := changes.WAFListRules[r.rule]
+		report.WAFListRules = append(report.WAFListRules, WAFListRuleReport{
+			Rule:       r.rule.Describe(),
+			Expression: r.expression,
+			Matched:    describeStringers(c.Matched),
+			Updated:    describeStringers(c.Updated),
+			Created:    describeStringers(c.Created),
+			Deleted:    describeStringers(c.Deleted),
+			Response:   r.response.String(),
+		})
+	}
+
+	for _, k := range b.kvKeys {
+		c := changes.WorkersKV[k.key]
+		report.WorkersKV = append(report.WorkersKV, WorkersKVReport{
+			Key:      k.key.Describe(),
+			Targets:  describeStringers(k.targets),
+			Previous: describeStringers(c.Previous),
+			Current:  describeStringers(c.Current),
+			Response: k.response.String(),
+		})
+	}
+
+	return report
+}
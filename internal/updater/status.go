@@ -0,0 +1,93 @@
+package updater
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/config"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/pp"
+)
+
+// RecordStatus lists the addresses of the managed DNS records of one domain in
+// one IP family.
+type RecordStatus struct {
+	IPFamily ipnet.Family
+	Domain   domain.Domain
+	IPs      []netip.Addr
+}
+
+// WAFListStatus lists the IP ranges of the managed items of one WAF list.
+type WAFListStatus struct {
+	List     api.WAFList
+	Prefixes []netip.Prefix
+}
+
+// Status is what the updater manages on Cloudflare right now.
+type Status struct {
+	Records  []RecordStatus
+	WAFLists []WAFListStatus
+}
+
+// ReadStatus lists the managed DNS records of the configured domains and the
+// managed items of the configured WAF lists without changing anything. The
+// second result tells whether everything could be listed; the parts that could
+// not be listed are left out.
+func ReadStatus(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, h api.Handle) (Status, bool) {
+	var status Status
+	allOK := true
+
+	for ipFamily, domains := range ipnet.Bindings(c.Domains) {
+		for _, d := range domains {
+			records, ok := withUpdateTimeout(ctx, c, func(ctx context.Context) ([]api.Record, bool) {
+				records, _, ok := h.ListRecords(ctx, ppfmt, ipFamily, d, api.RecordParams{
+					TTL:     c.TTL,
+					Proxied: c.Proxied[d],
+					Comment: c.RecordComment,
+					Tags:    nil,
+				})
+				return records, ok
+			})
+			if !ok {
+				allOK = false
+				continue
+			}
+
+			ips := make([]netip.Addr, 0, len(records))
+			for _, r := range records {
+				ips = append(ips, r.IP)
+			}
+			status.Records = append(status.Records, RecordStatus{IPFamily: ipFamily, Domain: d, IPs: ips})
+		}
+	}
+
+	for _, l := range c.WAFLists {
+		items, ok := withUpdateTimeout(ctx, c, func(ctx context.Context) ([]api.WAFListItem, bool) {
+			items, _, _, ok := h.ListWAFListItems(ctx, ppfmt, l, c.WAFListDescription, c.WAFListItemComment)
+			return items, ok
+		})
+		if !ok {
+			allOK = false
+			continue
+		}
+
+		prefixes := make([]netip.Prefix, 0, len(items))
+		for _, item := range items {
+			prefixes = append(prefixes, item.Prefix)
+		}
+		status.WAFLists = append(status.WAFLists, WAFListStatus{List: l, Prefixes: prefixes})
+	}
+
+	return status, allOK
+}
+
+// withUpdateTimeout runs f with UPDATE_TIMEOUT.
+func withUpdateTimeout[T any](ctx context.Context, c *config.UpdateConfig,
+	f func(context.Context) (T, bool),
+) (T, bool) {
+	ctx, cancel := context.WithTimeoutCause(ctx, c.UpdateTimeout, errTimeout)
+	defer cancel()
+	return f(ctx)
+}
@@ -0,0 +1,66 @@
+package updater_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/favonia/cloudflare-ddns/internal/api"
+	"github.com/favonia/cloudflare-ddns/internal/domain"
+	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/updater"
+)
+
+func TestReadStatus(t *testing.T) {
+	t.Parallel()
+
+	params := api.RecordParams{
+		TTL:     api.TTLAuto,
+		Proxied: false,
+		Comment: recordComment,
+		Tags:    nil,
+	}
+	list1 := api.WAFList{AccountID: "account", Name: "list1"}
+	list2 := api.WAFList{AccountID: "account", Name: "list2"}
+	ip4 := netip.MustParseAddr("127.0.0.1")
+	ip6 := netip.MustParseAddr("::1")
+	prefix := netip.MustParsePrefix("127.0.0.0/24")
+
+	mockCtrl := gomock.NewController(t)
+	conf := initUpdateConfig()
+	conf.Domains[ipnet.IP4] = []domain.Domain{domain4_1, domain4_2}
+	conf.Domains[ipnet.IP6] = []domain.Domain{domain6}
+	conf.WAFLists = []api.WAFList{list1, list2}
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockHandle := mocks.NewMockHandle(mockCtrl)
+	gomock.InOrder(
+		mockHandle.EXPECT().ListRecords(gomock.Any(), mockPP, ipnet.IP4, domain4_1, params).
+			Return([]api.Record{{ID: "r1", IP: ip4, RecordParams: params}}, false, true),
+		mockHandle.EXPECT().ListRecords(gomock.Any(), mockPP, ipnet.IP4, domain4_2, params).
+			Return(nil, false, false),
+		mockHandle.EXPECT().ListRecords(gomock.Any(), mockPP, ipnet.IP6, domain6, params).
+			Return([]api.Record{{ID: "r2", IP: ip6, RecordParams: params}}, true, true),
+		mockHandle.EXPECT().ListWAFListItems(gomock.Any(), mockPP, list1, wafListDescription, wafItemComment).
+			Return([]api.WAFListItem{{ID: "i1", Prefix: prefix, Comment: ""}}, true, false, true),
+		mockHandle.EXPECT().ListWAFListItems(gomock.Any(), mockPP, list2, wafListDescription, wafItemComment).
+			Return(nil, true, false, true),
+	)
+
+	status, ok := updater.ReadStatus(context.Background(), mockPP, conf, mockHandle)
+	require.False(t, ok)
+	require.Equal(t, updater.Status{
+		Records: []updater.RecordStatus{
+			{IPFamily: ipnet.IP4, Domain: domain4_1, IPs: []netip.Addr{ip4}},
+			{IPFamily: ipnet.IP6, Domain: domain6, IPs: []netip.Addr{ip6}},
+		},
+		WAFLists: []updater.WAFListStatus{
+			{List: list1, Prefixes: []netip.Prefix{prefix}},
+			{List: list2, Prefixes: []netip.Prefix{}},
+		},
+	}, status)
+}
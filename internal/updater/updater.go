@@ -8,12 +8,16 @@ import (
 	"fmt"
 	"net/netip"
 	"slices"
+	"time"
 
 	"github.com/favonia/cloudflare-ddns/internal/api"
 	"github.com/favonia/cloudflare-ddns/internal/config"
 	"github.com/favonia/cloudflare-ddns/internal/domain"
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
+	"github.com/favonia/cloudflare-ddns/internal/metrics"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/notifier"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
@@ -78,8 +82,16 @@ func detectRawData(
 	ctx, cancel := context.WithTimeoutCause(ctx, c.DetectionTimeout, errTimeout)
 	defer cancel()
 
+	start := time.Now()
 	rawData := c.Provider[ipFamily].GetRawData(ctx, ppfmt, ipFamily, c.DefaultPrefixLen[ipFamily])
-	return finalizeDetectedRawData(ctx, ppfmt, c, ipFamily, rawData)
+	rawData, msg := finalizeDetectedRawData(ctx, ppfmt, c, ipFamily, rawData)
+
+	m := metrics.OrNoop(c.Metrics)
+	m.ObserveDetection(ipFamily, msg.HeartbeatMessage.OK, time.Since(start))
+	if rawData.HasUsableRawData() {
+		m.SetDetectedIPs(ipFamily, deriveDNSAddresses(rawData))
+	}
+	return rawData, msg
 }
 
 func finalizeDetectedRawData(
@@ -127,6 +139,9 @@ func finalizeDetectedRawData(
 	addresses := deriveDNSAddresses(rawData)
 
 	switch {
+	case rawData.Pending:
+		return rawData, newMessage()
+
 	case filterAbort:
 		return rawData, generateFilterAbortDetectMessage(ipFamily)
 
@@ -188,6 +203,30 @@ func finalizeDetectedRawData(
 
 var errTimeout = errors.New("timeout")
 
+// RoundTimeout is the longest a round of updating can take when every
+// detection runs into DETECTION_TIMEOUT and every update runs into
+// UPDATE_TIMEOUT. Waiting for the rate limit of the Cloudflare API happens
+// within these timeouts.
+func RoundTimeout(c *config.UpdateConfig) time.Duration {
+	detections := len(c.Provider)
+	updates := len(c.WAFLists) + len(c.LBPoolOrigins) + len(c.GatewayLocations) + len(c.AccessGroups) +
+		len(c.IPAccessRules) + len(c.SpectrumApps) + len(c.NFTablesSets)
+	for _, domains := range c.Domains {
+		updates += len(domains)
+	}
+	if c.WAFListRule.ZoneID != "" {
+		updates++
+	}
+	if c.WorkersKV.Key != "" {
+		updates++
+	}
+	if c.LocalResolver != nil {
+		detections += len(c.LocalResolver.Provider)
+		updates++
+	}
+	return time.Duration(detections)*c.DetectionTimeout + time.Duration(updates)*c.UpdateTimeout
+}
+
 func wrapUpdateWithTimeout(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig,
 	f func(context.Context) setter.ResponseCode,
 ) setter.ResponseCode {
@@ -208,7 +247,8 @@ func wrapUpdateWithTimeout(ctx context.Context, ppfmt pp.PP, c *config.UpdateCon
 
 // setIPs extracts relevant settings from the configuration and calls [setter.Setter.SetIPs] with timeout.
 func setIPs(ctx context.Context, ppfmt pp.PP,
-	c *config.UpdateConfig, s setter.Setter, ipFamily ipnet.Family, targets map[domain.Domain][]netip.Addr,
+	c *config.UpdateConfig, s setter.Setter, report *reportBuilder, failures *Failures,
+	ipFamily ipnet.Family, targets map[domain.Domain][]netip.Addr,
 ) Message {
 	type targetGroup struct {
 		ips   []netip.Addr
@@ -240,18 +280,22 @@ func setIPs(ctx context.Context, ppfmt pp.PP,
 			groupIndex = len(groups) - 1
 		}
 
-		groups[groupIndex].resps.register(configuredDomain,
-			wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
-				return s.SetIPs(ctx, ppfmt, ipFamily, configuredDomain, ips, api.RecordParams{
-					TTL:     c.TTL,
-					Proxied: c.Proxied[configuredDomain],
-					Comment: c.RecordComment,
-					// The config surface does not expose non-empty fallback DNS tags yet.
-					// Nil here therefore means "the effective fallback tag set is empty", not "clear tags".
-					Tags: nil,
-				})
-			}),
-		)
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetIPs(ctx, ppfmt, ipFamily, configuredDomain, ips, api.RecordParams{
+				TTL:     c.TTL,
+				Proxied: c.Proxied[configuredDomain],
+				Comment: c.RecordComment,
+				// The config surface does not expose non-empty fallback DNS tags yet.
+				// Nil here therefore means "the effective fallback tag set is empty", not "clear tags".
+				Tags: nil,
+			})
+		})
+		groups[groupIndex].resps.register(configuredDomain, resp)
+		metrics.OrNoop(c.Metrics).CountRecordUpdate(ipFamily, configuredDomain.DNSNameASCII(), resp.String())
+		report.addDomain(ipFamily, configuredDomain, ips, resp)
+		if resp == setter.ResponseFailed {
+			failures.addDomain(ipFamily, configuredDomain)
+		}
 	}
 
 	msgs := make([]Message, 0, len(groups)+1)
@@ -309,24 +353,26 @@ func reportHostID6Problems(ppfmt pp.PP, problems []hostID6ProblemGroup, hasWAFLi
 // finalDeleteIP extracts relevant settings from the configuration
 // and calls [setter.Setter.FinalDelete] with a deadline.
 func finalDeleteIP(
-	ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter, ipFamily ipnet.Family,
+	ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter, report *reportBuilder,
+	ipFamily ipnet.Family,
 ) Message {
 	resps := emptySetterResponses()
 
 	for _, domain := range c.Domains[ipFamily] {
-		resps.register(domain,
-			wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
-				return s.FinalDelete(ctx, ppfmt, ipFamily, domain, api.RecordParams{
-					TTL:     c.TTL,
-					Proxied: c.Proxied[domain],
-					Comment: c.RecordComment,
-					// Keep final-delete reconciliation aligned with steady-state updates:
-					// current config can preserve/inherit existing tags but cannot specify
-					// non-empty fallback tags.
-					Tags: nil,
-				})
-			}),
-		)
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalDelete(ctx, ppfmt, ipFamily, domain, api.RecordParams{
+				TTL:     c.TTL,
+				Proxied: c.Proxied[domain],
+				Comment: c.RecordComment,
+				// Keep final-delete reconciliation aligned with steady-state updates:
+				// current config can preserve/inherit existing tags but cannot specify
+				// non-empty fallback tags.
+				Tags: nil,
+			})
+		})
+		resps.register(domain, resp)
+		metrics.OrNoop(c.Metrics).CountRecordUpdate(ipFamily, domain.DNSNameASCII(), resp.String())
+		report.addDomain(ipFamily, domain, nil, resp)
 	}
 
 	return generateFinalDeleteMessage(ipFamily, resps)
@@ -334,16 +380,20 @@ func finalDeleteIP(
 
 // setWAFList extracts relevant settings from the configuration and calls [setter.Setter.SetWAFList] with timeout.
 func setWAFLists(ctx context.Context, ppfmt pp.PP,
-	c *config.UpdateConfig, s setter.Setter, targets map[ipnet.Family]setter.WAFTargets,
+	c *config.UpdateConfig, s setter.Setter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family]setter.WAFTargets,
 ) Message {
 	resps := emptySetterWAFListResponses()
 
 	for _, l := range c.WAFLists {
-		resps.register(l.Describe(),
-			wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
-				return s.SetWAFList(ctx, ppfmt, l, c.WAFListDescription, targets, c.WAFListItemComment)
-			}),
-		)
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetWAFList(ctx, ppfmt, l, c.WAFListDescription, targets, c.WAFListItemComment)
+		})
+		resps.register(l.Describe(), resp)
+		report.addWAFList(l, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addWAFList(l)
+		}
 	}
 
 	return generateUpdateWAFListsMessage(resps)
@@ -351,7 +401,9 @@ func setWAFLists(ctx context.Context, ppfmt pp.PP,
 
 // finalClearWAFLists extracts relevant settings from the configuration
 // and calls [setter.Setter.FinalClearWAFList] with a deadline.
-func finalClearWAFLists(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter) Message {
+func finalClearWAFLists(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.Setter, report *reportBuilder,
+) Message {
 	resps := emptySetterWAFListResponses()
 	managedFamilies := map[ipnet.Family]bool{}
 	for ipFamily, p := range ipnet.Bindings(c.Provider) {
@@ -361,35 +413,489 @@ func finalClearWAFLists(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig
 	}
 
 	for _, l := range c.WAFLists {
-		resps.register(l.Describe(),
-			wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
-				return s.FinalClearWAFList(ctx, ppfmt, l, c.WAFListDescription, managedFamilies)
-			}),
-		)
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalClearWAFList(ctx, ppfmt, l, c.WAFListDescription, managedFamilies)
+		})
+		resps.register(l.Describe(), resp)
+		report.addWAFList(l, nil, resp)
 	}
 
 	return generateFinalClearWAFListsMessage(resps)
 }
 
+// setLBPoolOrigins extracts relevant settings from the configuration
+// and calls [setter.LBPoolSetter.SetLBPoolOrigin] with timeout.
+func setLBPoolOrigins(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.LBPoolSetter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family][]netip.Addr,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	for _, o := range c.LBPoolOrigins {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetLBPoolOrigin(ctx, ppfmt, o, targets)
+		})
+		resps.register(o.Describe(), resp)
+		report.addLBPoolOrigin(o, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addLBPoolOrigin(o)
+		}
+	}
+
+	return generateUpdateLBPoolOriginsMessage(resps)
+}
+
+// setSpectrumApps extracts relevant settings from the configuration
+// and calls [setter.SpectrumAppSetter.SetSpectrumApp] with timeout.
+func setSpectrumApps(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.SpectrumAppSetter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family][]netip.Addr,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	for _, a := range c.SpectrumApps {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetSpectrumApp(ctx, ppfmt, a, targets)
+		})
+		resps.register(a.Describe(), resp)
+		report.addSpectrumApp(a, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addSpectrumApp(a)
+		}
+	}
+
+	return generateUpdateSpectrumAppsMessage(resps)
+}
+
+// setWAFListRule extracts relevant settings from the configuration
+// and calls [setter.WAFListRuleSetter.SetWAFListRule] with timeout.
+func setWAFListRule(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.WAFListRuleSetter, report *reportBuilder, failures *Failures,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	if c.WAFListRule.ZoneID != "" {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetWAFListRule(ctx, ppfmt, c.WAFListRule, c.WAFListRuleExpression, c.WAFListRuleDescription)
+		})
+		resps.register(c.WAFListRule.Describe(), resp)
+		report.addWAFListRule(c.WAFListRule, c.WAFListRuleExpression, resp)
+		if resp == setter.ResponseFailed {
+			failures.addWAFListRule()
+		}
+	}
+
+	return generateUpdateWAFListRuleMessage(resps)
+}
+
+// finalDeleteWAFListRule extracts relevant settings from the configuration
+// and calls [setter.WAFListRuleSetter.FinalDeleteWAFListRule] with a deadline.
+func finalDeleteWAFListRule(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.WAFListRuleSetter, report *reportBuilder,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	if c.WAFListRule.ZoneID != "" {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalDeleteWAFListRule(ctx, ppfmt, c.WAFListRule, c.WAFListRuleDescription)
+		})
+		resps.register(c.WAFListRule.Describe(), resp)
+		report.addWAFListRule(c.WAFListRule, c.WAFListRuleExpression, resp)
+	}
+
+	return generateFinalDeleteWAFListRuleMessage(resps)
+}
+
+// setWorkersKV extracts relevant settings from the configuration
+// and calls [setter.WorkersKVSetter.SetWorkersKV] with timeout.
+func setWorkersKV(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.WorkersKVSetter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family][]netip.Addr,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	if c.WorkersKV.Key != "" {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetWorkersKV(ctx, ppfmt, c.WorkersKV, targets, time.Now())
+		})
+		resps.register(c.WorkersKV.Describe(), resp)
+		report.addWorkersKV(c.WorkersKV, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addWorkersKV()
+		}
+	}
+
+	return generateUpdateWorkersKVMessage(resps)
+}
+
+// finalDeleteWorkersKV extracts relevant settings from the configuration
+// and calls [setter.WorkersKVSetter.FinalDeleteWorkersKV] with a deadline.
+func finalDeleteWorkersKV(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.WorkersKVSetter, report *reportBuilder,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	if c.WorkersKV.Key != "" {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalDeleteWorkersKV(ctx, ppfmt, c.WorkersKV)
+		})
+		resps.register(c.WorkersKV.Describe(), resp)
+		report.addWorkersKV(c.WorkersKV, nil, resp)
+	}
+
+	return generateFinalDeleteWorkersKVMessage(resps)
+}
+
+// detectLocalResolverTargets detects the internal addresses for the local
+// resolver and derives the targets of its domains in the same way as DNS records.
+// Nothing is derived if the detection fails for any family, so that the
+// fragment is left alone.
+func detectLocalResolverTargets(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig) (localdns.Targets, bool) {
+	targets := localdns.Targets{}
+	for ipFamily, p := range ipnet.Bindings(c.LocalResolver.Provider) {
+		detectionCtx, cancel := context.WithTimeoutCause(ctx, c.DetectionTimeout, errTimeout)
+		rawData := p.GetRawData(detectionCtx, ppfmt, ipFamily, c.DefaultPrefixLen[ipFamily])
+		cancel()
+
+		if !rawData.Available {
+			ppfmt.Noticef(pp.EmojiError, "No valid internal %s addresses were detected for the local resolver",
+				ipFamily.Describe())
+			return nil, false
+		}
+		addresses := deriveDNSAddresses(rawData)
+		ppfmt.Infof(pp.EmojiInternet, "Detected internal %s %s for the local resolver: %s",
+			ipFamily.Describe(), addressWord(len(addresses)),
+			pp.EnglishJoinMapOrEmptyLabel(netip.Addr.String, addresses, "(none)"))
+		targets[ipFamily] = sharedDNSTargets(c.LocalResolver.Domains, addresses)
+	}
+	return targets, true
+}
+
+// writeLocalResolver writes the targets to the local resolver fragment with
+// timeout, or only plans the writing during dry runs.
+func writeLocalResolver(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, targets localdns.Targets,
+) (setter.ResponseCode, []api.PlannedChange) {
+	sink := c.LocalResolver.Sink
+	if c.DryRun {
+		plan := sink.Plan(ppfmt, targets)
+		if len(plan) == 0 {
+			ppfmt.Infof(pp.EmojiAlreadyDone, "The local resolver file %s is already up to date",
+				pp.QuoteIfUnsafeInSentence(sink.Path))
+			return setter.ResponseNoop, nil
+		}
+		return setter.ResponseUpdated, plan
+	}
+
+	return wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+		return sink.Write(ctx, ppfmt, targets)
+	}), nil
+}
+
+// setLocalResolver detects the internal addresses and writes the local resolver fragment.
+// It also returns the planned changes during dry runs.
+func setLocalResolver(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, failures *Failures,
+) (Message, []api.PlannedChange) {
+	resps := emptySetterResourceResponses()
+	var plan []api.PlannedChange
+
+	if c.LocalResolver != nil {
+		resp := setter.ResponseFailed
+		if targets, ok := detectLocalResolverTargets(ctx, ppfmt, c); ok {
+			resp, plan = writeLocalResolver(ctx, ppfmt, c, targets)
+		}
+		resps.register(c.LocalResolver.Sink.Describe(), resp)
+		if resp == setter.ResponseFailed {
+			failures.addLocalResolver()
+		}
+	}
+
+	return generateUpdateLocalResolverMessage(resps), plan
+}
+
+// finalClearLocalResolver removes all entries from the local resolver fragment.
+// It also returns the planned changes during dry runs.
+func finalClearLocalResolver(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig,
+) (Message, []api.PlannedChange) {
+	resps := emptySetterResourceResponses()
+	var plan []api.PlannedChange
+
+	if c.LocalResolver != nil {
+		var resp setter.ResponseCode
+		resp, plan = writeLocalResolver(ctx, ppfmt, c, localdns.Targets{})
+		resps.register(c.LocalResolver.Sink.Describe(), resp)
+	}
+
+	return generateFinalClearLocalResolverMessage(resps), plan
+}
+
+// syncNFTablesSet keeps one nftables set with timeout, or only plans the
+// changes during dry runs. Reading the set is not a change, so it happens
+// even during dry runs.
+func syncNFTablesSet(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, set nftset.Set,
+	targets map[ipnet.Family]setter.WAFTargets,
+) (setter.ResponseCode, []api.PlannedChange) {
+	if c.DryRun {
+		plan, ok := set.Plan(ctx, ppfmt, c.NFTablesRunner, targets)
+		switch {
+		case !ok:
+			return setter.ResponseFailed, nil
+		case len(plan) == 0:
+			ppfmt.Infof(pp.EmojiAlreadyDone, "The nftables set %s is already up to date", set.Describe())
+			return setter.ResponseNoop, nil
+		default:
+			return setter.ResponseUpdated, plan
+		}
+	}
+
+	return wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+		return set.Sync(ctx, ppfmt, c.NFTablesRunner, targets)
+	}), nil
+}
+
+// setNFTablesSets keeps the nftables sets equal to the detected prefixes.
+// It also returns the planned changes during dry runs.
+func setNFTablesSets(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, failures *Failures,
+	targets map[ipnet.Family]setter.WAFTargets,
+) (Message, []api.PlannedChange) {
+	resps := emptySetterResourceResponses()
+	var plan []api.PlannedChange
+
+	for _, set := range c.NFTablesSets {
+		resp, setPlan := syncNFTablesSet(ctx, ppfmt, c, set, targets)
+		resps.register(set.Describe(), resp)
+		if resp == setter.ResponseFailed {
+			failures.addNFTablesSet(set)
+		}
+		plan = append(plan, setPlan...)
+	}
+
+	return generateUpdateNFTablesSetsMessage(resps), plan
+}
+
+// finalClearNFTablesSets removes all elements from the nftables sets of managed families.
+// It also returns the planned changes during dry runs.
+func finalClearNFTablesSets(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig,
+) (Message, []api.PlannedChange) {
+	resps := emptySetterResourceResponses()
+	var plan []api.PlannedChange
+
+	// Clearing is the same as keeping the sets equal to no prefixes.
+	targets := map[ipnet.Family]setter.WAFTargets{}
+	for ipFamily, p := range ipnet.Bindings(c.Provider) {
+		if p != nil {
+			targets[ipFamily] = setter.NewAvailableWAFTargets(nil)
+		}
+	}
+
+	for _, set := range c.NFTablesSets {
+		resp, setPlan := syncNFTablesSet(ctx, ppfmt, c, set, targets)
+		resps.register(set.Describe(), resp)
+		plan = append(plan, setPlan...)
+	}
+
+	return generateFinalClearNFTablesSetsMessage(resps), plan
+}
+
+// finalDisableLBPoolOrigins extracts relevant settings from the configuration
+// and calls [setter.LBPoolSetter.FinalDisableLBPoolOrigin] with a deadline.
+func finalDisableLBPoolOrigins(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.LBPoolSetter, report *reportBuilder,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	for _, o := range c.LBPoolOrigins {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalDisableLBPoolOrigin(ctx, ppfmt, o)
+		})
+		resps.register(o.Describe(), resp)
+		report.addLBPoolOrigin(o, nil, resp)
+	}
+
+	return generateFinalDisableLBPoolOriginsMessage(resps)
+}
+
+// setGatewayLocations extracts relevant settings from the configuration
+// and calls [setter.GatewayLocationSetter.SetGatewayLocation] with timeout.
+func setGatewayLocations(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.GatewayLocationSetter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family]setter.WAFTargets,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	for _, l := range c.GatewayLocations {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetGatewayLocation(ctx, ppfmt, l, targets)
+		})
+		resps.register(l.Describe(), resp)
+		report.addGatewayLocation(l, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addGatewayLocation(l)
+		}
+	}
+
+	return generateUpdateGatewayLocationsMessage(resps)
+}
+
+// finalClearGatewayLocations extracts relevant settings from the configuration
+// and calls [setter.GatewayLocationSetter.FinalClearGatewayLocation] with a deadline.
+func finalClearGatewayLocations(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.GatewayLocationSetter, report *reportBuilder,
+) Message {
+	resps := emptySetterResourceResponses()
+	managedFamilies := map[ipnet.Family]bool{}
+	for ipFamily, p := range ipnet.Bindings(c.Provider) {
+		if p != nil {
+			managedFamilies[ipFamily] = true
+		}
+	}
+
+	for _, l := range c.GatewayLocations {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalClearGatewayLocation(ctx, ppfmt, l, managedFamilies)
+		})
+		resps.register(l.Describe(), resp)
+		report.addGatewayLocation(l, nil, resp)
+	}
+
+	return generateFinalClearGatewayLocationsMessage(resps)
+}
+
+// setAccessGroups extracts relevant settings from the configuration
+// and calls [setter.AccessGroupSetter.SetAccessGroup] with timeout.
+func setAccessGroups(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.AccessGroupSetter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family]setter.WAFTargets,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	for _, g := range c.AccessGroups {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetAccessGroup(ctx, ppfmt, g, targets)
+		})
+		resps.register(g.Describe(), resp)
+		report.addAccessGroup(g, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addAccessGroup(g)
+		}
+	}
+
+	return generateUpdateAccessGroupsMessage(resps)
+}
+
+// finalClearAccessGroups extracts relevant settings from the configuration
+// and calls [setter.AccessGroupSetter.FinalClearAccessGroup] with a deadline.
+func finalClearAccessGroups(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.AccessGroupSetter, report *reportBuilder,
+) Message {
+	resps := emptySetterResourceResponses()
+	managedFamilies := map[ipnet.Family]bool{}
+	for ipFamily, p := range ipnet.Bindings(c.Provider) {
+		if p != nil {
+			managedFamilies[ipFamily] = true
+		}
+	}
+
+	for _, g := range c.AccessGroups {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalClearAccessGroup(ctx, ppfmt, g, managedFamilies)
+		})
+		resps.register(g.Describe(), resp)
+		report.addAccessGroup(g, nil, resp)
+	}
+
+	return generateFinalClearAccessGroupsMessage(resps)
+}
+
+// setIPAccessRules extracts relevant settings from the configuration
+// and calls [setter.IPAccessRuleSetter.SetIPAccessRules] with timeout.
+func setIPAccessRules(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.IPAccessRuleSetter, report *reportBuilder, failures *Failures,
+	targets map[ipnet.Family]setter.WAFTargets,
+) Message {
+	resps := emptySetterResourceResponses()
+
+	for _, r := range c.IPAccessRules {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.SetIPAccessRules(ctx, ppfmt, r, targets, c.IPAccessRuleNotes)
+		})
+		resps.register(r.Describe(), resp)
+		report.addIPAccessRules(r, targets, resp)
+		if resp == setter.ResponseFailed {
+			failures.addIPAccessRules(r)
+		}
+	}
+
+	return generateUpdateIPAccessRulesMessage(resps)
+}
+
+// finalClearIPAccessRules extracts relevant settings from the configuration
+// and calls [setter.IPAccessRuleSetter.FinalClearIPAccessRules] with a deadline.
+func finalClearIPAccessRules(ctx context.Context, ppfmt pp.PP,
+	c *config.UpdateConfig, s setter.IPAccessRuleSetter, report *reportBuilder,
+) Message {
+	resps := emptySetterResourceResponses()
+	managedFamilies := map[ipnet.Family]bool{}
+	for ipFamily, p := range ipnet.Bindings(c.Provider) {
+		if p != nil {
+			managedFamilies[ipFamily] = true
+		}
+	}
+
+	for _, r := range c.IPAccessRules {
+		resp := wrapUpdateWithTimeout(ctx, ppfmt, c, func(ctx context.Context) setter.ResponseCode {
+			return s.FinalClearIPAccessRules(ctx, ppfmt, r, managedFamilies)
+		})
+		resps.register(r.Describe(), resp)
+		report.addIPAccessRules(r, nil, resp)
+	}
+
+	return generateFinalClearIPAccessRulesMessage(resps)
+}
+
 // UpdateIPs detects IP addresses and updates DNS records of managed domains.
 func UpdateIPs(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter) Message {
+	msg, _ := UpdateIPsWithFailures(ctx, ppfmt, c, s)
+	return msg
+}
+
+// UpdateIPsWithFailures is [UpdateIPs] that also returns the failed parts,
+// so that they can be retried with [Failures.Restrict].
+func UpdateIPsWithFailures(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter,
+) (Message, Failures) {
 	var msgs []Message
+	report := newReportBuilder(c.Report)
+	failures := newFailures()
 	targetsForWAF := map[ipnet.Family]setter.WAFTargets{}
 	shouldUpdateWAF := false
+	var nftablesPlan []api.PlannedChange
+	// Load balancer origins and Spectrum apps use the detected addresses directly;
+	// families whose detection failed are left out so that their origins are kept.
+	targetsForLB := map[ipnet.Family][]netip.Addr{}
+	// The Workers KV key only publishes the families whose DNS records were updated.
+	targetsForKV := map[ipnet.Family][]netip.Addr{}
 	for ipFamily, p := range ipnet.Bindings(c.Provider) {
 		if p != nil {
 			rawData, msg := detectRawData(ctx, ppfmt, c, ipFamily)
 			msgs = append(msgs, msg)
+			report.addFamily(ipFamily, rawData)
+			if !msg.HeartbeatMessage.OK {
+				failures.addFamily(ipFamily)
+			}
 
 			// Note: If we can't detect the new IP address,
 			// it's probably better to leave existing records alone.
-			if msg.HeartbeatMessage.OK {
+			if rawData.HasUsableRawData() {
 				targetsForWAF[ipFamily] = deriveWAFTargets(rawData)
+				targetsForLB[ipFamily] = deriveDNSAddresses(rawData)
 				switch ipFamily {
 				case ipnet.IP4:
 					shouldUpdateWAF = true
 					targets := sharedDNSTargets(c.Domains[ipFamily], deriveDNSAddresses(rawData))
-					msgs = append(msgs, setIPs(ctx, ppfmt, c, s, ipFamily, targets))
+					dnsMsg := setIPs(ctx, ppfmt, c, s, report, failures, ipFamily, targets)
+					msgs = append(msgs, dnsMsg)
+					if dnsMsg.HeartbeatMessage.OK {
+						targetsForKV[ipFamily] = targetsForLB[ipFamily]
+					}
 
 				case ipnet.IP6:
 					targets, problems := deriveIP6DNSTargets(c.Domains[ipFamily], c.HostID6, rawData)
@@ -400,7 +906,11 @@ func UpdateIPs(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s sette
 						continue
 					}
 					shouldUpdateWAF = true
-					msgs = append(msgs, setIPs(ctx, ppfmt, c, s, ipFamily, targets))
+					dnsMsg := setIPs(ctx, ppfmt, c, s, report, failures, ipFamily, targets)
+					msgs = append(msgs, dnsMsg)
+					if dnsMsg.HeartbeatMessage.OK {
+						targetsForKV[ipFamily] = targetsForLB[ipFamily]
+					}
 				}
 			} else {
 				targetsForWAF[ipFamily] = deriveWAFTargets(rawData)
@@ -413,32 +923,134 @@ func UpdateIPs(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s sette
 
 	// Update WAF lists only when at least one family has usable derived targets.
 	if shouldUpdateWAF {
-		msgs = append(msgs, setWAFLists(ctx, ppfmt, c, s, targetsForWAF))
+		msgs = append(msgs, setWAFLists(ctx, ppfmt, c, s, report, failures, targetsForWAF))
+		msgs = append(msgs, setWAFListRule(ctx, ppfmt, c, s, report, failures))
+		msgs = append(msgs, setGatewayLocations(ctx, ppfmt, c, s, report, failures, targetsForWAF))
+		msgs = append(msgs, setAccessGroups(ctx, ppfmt, c, s, report, failures, targetsForWAF))
+		msgs = append(msgs, setIPAccessRules(ctx, ppfmt, c, s, report, failures, targetsForWAF))
+
+		var nftablesMsg Message
+		nftablesMsg, nftablesPlan = setNFTablesSets(ctx, ppfmt, c, failures, targetsForWAF)
+		msgs = append(msgs, nftablesMsg)
 	}
 
-	return classifyNotification(
+	if len(targetsForLB) > 0 {
+		msgs = append(msgs, setLBPoolOrigins(ctx, ppfmt, c, s, report, failures, targetsForLB))
+		msgs = append(msgs, setSpectrumApps(ctx, ppfmt, c, s, report, failures, targetsForLB))
+	}
+	if len(targetsForKV) > 0 {
+		// Publish the addresses last, so that readers of the Workers KV key
+		// see them only after the other resources have been updated.
+		msgs = append(msgs, setWorkersKV(ctx, ppfmt, c, s, report, failures, targetsForKV))
+	}
+	if c.WorkersKV.Key != "" && len(targetsForKV) < len(targetsForLB) {
+		// The families held back by failed DNS updates are published when they are retried.
+		failures.addWorkersKV()
+	}
+
+	// The local resolver has its own providers of internal addresses.
+	localResolverMsg, localResolverPlan := setLocalResolver(ctx, ppfmt, c, failures)
+	msgs = append(msgs, localResolverMsg)
+
+	msg := classifyNotification(
 		mergeMessages(msgs...),
 		notifier.KindUpdate,
 		notifier.KindUpdateFailure,
 	)
+	if report != nil {
+		msg.Report = report.build("update", msg.HeartbeatMessage.OK, s.TakeChanges())
+	}
+	if c.DryRun {
+		plan := slices.Concat(s.TakePlan(), nftablesPlan, localResolverPlan)
+		reportPlan(ppfmt, plan)
+		msg = generateDryRunMessage(msg, plan)
+	}
+	return msg, *failures
 }
 
 // FinalDeleteIPs removes all DNS records of managed domains.
 func FinalDeleteIPs(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter) Message {
 	var msgs []Message
+	report := newReportBuilder(c.Report)
 
 	for ipFamily, provider := range ipnet.Bindings(c.Provider) {
 		if provider != nil {
-			msgs = append(msgs, finalDeleteIP(ctx, ppfmt, c, s, ipFamily))
+			msgs = append(msgs, finalDeleteIP(ctx, ppfmt, c, s, report, ipFamily))
 		}
 	}
 
+	// Delete the WAF custom rule before the lists it references
+	msgs = append(msgs, finalDeleteWAFListRule(ctx, ppfmt, c, s, report))
+
 	// Clear WAF lists
-	msgs = append(msgs, finalClearWAFLists(ctx, ppfmt, c, s))
+	msgs = append(msgs, finalClearWAFLists(ctx, ppfmt, c, s, report))
+
+	// Disable load balancer pool origins
+	msgs = append(msgs, finalDisableLBPoolOrigins(ctx, ppfmt, c, s, report))
+
+	// Clear Gateway locations
+	msgs = append(msgs, finalClearGatewayLocations(ctx, ppfmt, c, s, report))
 
-	return classifyNotification(
+	// Clear Access groups
+	msgs = append(msgs, finalClearAccessGroups(ctx, ppfmt, c, s, report))
+
+	// Clear IP Access Rules
+	msgs = append(msgs, finalClearIPAccessRules(ctx, ppfmt, c, s, report))
+
+	// Delete the Workers KV key
+	msgs = append(msgs, finalDeleteWorkersKV(ctx, ppfmt, c, s, report))
+
+	// Clear the nftables sets
+	nftablesMsg, nftablesPlan := finalClearNFTablesSets(ctx, ppfmt, c)
+	msgs = append(msgs, nftablesMsg)
+
+	// Clear the local resolver file
+	localResolverMsg, localResolverPlan := finalClearLocalResolver(ctx, ppfmt, c)
+	msgs = append(msgs, localResolverMsg)
+
+	msg := classifyNotification(
 		mergeMessages(msgs...),
 		notifier.KindCleanup,
 		notifier.KindCleanupFailure,
 	)
+	if report != nil {
+		msg.Report = report.build("cleanup", msg.HeartbeatMessage.OK, s.TakeChanges())
+	}
+	if c.DryRun {
+		plan := slices.Concat(s.TakePlan(), nftablesPlan, localResolverPlan)
+		reportPlan(ppfmt, plan)
+		msg = generateDryRunMessage(msg, plan)
+	}
+	return msg
+}
+
+// CheckPermissions checks, before the first update, whether the credentials can
+// manage every managed domain and WAF list.
+func CheckPermissions(ctx context.Context, ppfmt pp.PP, c *config.UpdateConfig, s setter.Setter) Message {
+	if ppfmt.IsShowing(pp.Info) {
+		ppfmt.Infof(pp.EmojiPermission, "Checking the permissions of the credentials . . .")
+		ppfmt = ppfmt.Indent()
+	}
+
+	seen := map[domain.Domain]bool{}
+	var domains []domain.Domain
+	for _, ds := range c.Domains {
+		for _, d := range ds {
+			if !seen[d] {
+				seen[d] = true
+				domains = append(domains, d)
+			}
+		}
+	}
+	domain.SortDomains(domains)
+
+	ctx, cancel := context.WithTimeout(ctx, c.UpdateTimeout)
+	defer cancel()
+
+	report := s.CheckPermissions(ctx, ppfmt, domains, c.WAFLists)
+	if !report.HasProblems() && report.Inspected {
+		ppfmt.Infof(pp.EmojiGood, "The credentials can manage all the domains and WAF lists")
+	}
+
+	return generatePermissionMessage(report)
 }
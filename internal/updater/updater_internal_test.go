@@ -335,6 +335,7 @@ func TestClassifyNotification(t *testing.T) {
 				HeartbeatMessage: heartbeat.NewMessagef(tc.ok, "heartbeat"),
 				NotifierMessage:  notifier.NewMessagef("notification"),
 				NotificationKind: "",
+				Report:           nil,
 			}
 			got := classifyNotification(
 				msg,
@@ -374,7 +375,7 @@ func TestSetIPsSkipsManagedDomainWithoutTargets(t *testing.T) {
 			Return(setter.ResponseUpdated),
 	)
 
-	msg := setIPs(context.Background(), ppfmt, conf, s, ipnet.IP4, dnsTargetsByDomain{
+	msg := setIPs(context.Background(), ppfmt, conf, s, nil, nil, ipnet.IP4, dnsTargetsByDomain{
 		present: {ip},
 	})
 
@@ -388,5 +389,6 @@ func TestSetIPsSkipsManagedDomainWithoutTargets(t *testing.T) {
 			"Updated A records for present.example to 192.0.2.1.",
 		},
 		NotificationKind: "",
+		Report:           nil,
 	}, msg)
 }
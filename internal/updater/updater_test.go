@@ -6,6 +6,8 @@ import (
 	"context"
 	"maps"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -20,7 +22,9 @@ import (
 	"github.com/favonia/cloudflare-ddns/internal/hostid6"
 	"github.com/favonia/cloudflare-ddns/internal/ipfilter"
 	"github.com/favonia/cloudflare-ddns/internal/ipnet"
+	"github.com/favonia/cloudflare-ddns/internal/localdns"
 	"github.com/favonia/cloudflare-ddns/internal/mocks"
+	"github.com/favonia/cloudflare-ddns/internal/nftset"
 	"github.com/favonia/cloudflare-ddns/internal/notifier"
 	"github.com/favonia/cloudflare-ddns/internal/pp"
 	"github.com/favonia/cloudflare-ddns/internal/provider"
@@ -243,6 +247,57 @@ func runFinalDeleteIPsScenario(
 	return updater.FinalDeleteIPs(ctx, mockPP, conf, mockSetter)
 }
 
+func TestUpdateIPsWithFailures(t *testing.T) {
+	t.Parallel()
+
+	params := api.RecordParams{
+		TTL:     api.TTLAuto,
+		Proxied: false,
+		Comment: recordComment,
+		Tags:    nil,
+	}
+	list1 := api.WAFList{AccountID: "account", Name: "list1"}
+	list2 := api.WAFList{AccountID: "account", Name: "list2"}
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "origin"}
+	ip4 := netip.MustParseAddr("127.0.0.1")
+
+	mockCtrl := gomock.NewController(t)
+	conf := initUpdateConfig()
+	conf.Domains[ipnet.IP4] = []domain.Domain{domain4_1, domain4_2}
+	conf.WAFLists = []api.WAFList{list1, list2}
+	conf.LBPoolOrigins = []api.LBPoolOrigin{origin}
+	mockProvider := mocks.NewMockProvider(mockCtrl)
+	conf.Provider[ipnet.IP4] = mockProvider
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	gomock.InOrder(
+		mockProvider.EXPECT().GetRawData(gomock.Any(), mockPP, ipnet.IP4, 32).
+			Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+		mockPP.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "127.0.0.1"),
+		mockPP.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+		mockSetter.EXPECT().SetIPs(gomock.Any(), mockPP, ipnet.IP4, domain4_1, []netip.Addr{ip4}, params).
+			Return(setter.ResponseFailed),
+		mockSetter.EXPECT().SetIPs(gomock.Any(), mockPP, ipnet.IP4, domain4_2, []netip.Addr{ip4}, params).
+			Return(setter.ResponseUpdated),
+		mockSetter.EXPECT().SetWAFList(gomock.Any(), mockPP, list1, wafListDescription,
+			wafTargets([]netip.Addr{ip4}, nil), wafItemComment).Return(setter.ResponseNoop),
+		mockSetter.EXPECT().SetWAFList(gomock.Any(), mockPP, list2, wafListDescription,
+			wafTargets([]netip.Addr{ip4}, nil), wafItemComment).Return(setter.ResponseFailed),
+		mockSetter.EXPECT().SetLBPoolOrigin(gomock.Any(), mockPP, origin,
+			map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}}).Return(setter.ResponseFailed),
+	)
+
+	msg, failures := updater.UpdateIPsWithFailures(context.Background(), mockPP, conf, mockSetter)
+	require.False(t, msg.HeartbeatMessage.OK)
+	require.Equal(t, updater.Failures{
+		Families:      map[ipnet.Family]bool{},
+		Domains:       map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain4_1}},
+		WAFLists:      []api.WAFList{list2},
+		LBPoolOrigins: []api.LBPoolOrigin{origin},
+	}, failures)
+}
+
 func TestUpdateIPsMultiple(t *testing.T) {
 	t.Parallel()
 
@@ -364,6 +419,7 @@ func TestUpdateIPsMultiple(t *testing.T) {
 				},
 				NotifierMessage:  notifier.Message(tc.notifierMessages),
 				NotificationKind: wantKind,
+				Report:           nil,
 			}, resp)
 		})
 	}
@@ -472,6 +528,7 @@ func TestFinalDeleteIPsMultiple(t *testing.T) {
 				},
 				NotifierMessage:  notifier.Message(tc.notifierMessages),
 				NotificationKind: wantKind,
+				Report:           nil,
 			}, resp)
 		})
 	}
@@ -734,6 +791,7 @@ func TestUpdateIPs(t *testing.T) {
 				},
 				NotifierMessage:  notifier.Message(tc.notifierMessages),
 				NotificationKind: wantKind,
+				Report:           nil,
 			}, resp)
 		})
 	}
@@ -768,6 +826,7 @@ func TestUpdateIPsDetectionFilterPartial(t *testing.T) {
 		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Set A records for ip4.hello to 198.51.100.8"}},
 		NotifierMessage:  notifier.Message{"Updated A records for ip4.hello to 198.51.100.8."},
 		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
 	}, resp)
 }
 
@@ -802,6 +861,7 @@ func TestUpdateIPsDetectionFilterReportsMultipleDropped(t *testing.T) {
 		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Set A records for ip4.hello to 198.51.100.8"}},
 		NotifierMessage:  notifier.Message{"Updated A records for ip4.hello to 198.51.100.8."},
 		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
 	}, resp)
 }
 
@@ -831,6 +891,7 @@ func TestUpdateIPsDetectionFilterKeepingAllReportsFilteredDetection(t *testing.T
 		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Set A records for ip4.hello to 198.51.100.8"}},
 		NotifierMessage:  notifier.Message{"Updated A records for ip4.hello to 198.51.100.8."},
 		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
 	}, resp)
 }
 
@@ -862,6 +923,7 @@ func TestUpdateIPsDetectionFilterKeepingAllReportsFilteredPluralDetection(t *tes
 		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Set A records for ip4.hello to 198.51.100.8, 198.51.100.9"}},
 		NotifierMessage:  notifier.Message{"Updated A records for ip4.hello to 198.51.100.8 and 198.51.100.9."},
 		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
 	}, resp)
 }
 
@@ -913,6 +975,7 @@ func TestUpdateIPsDetectionFilterToNoneAbortsFamily(t *testing.T) {
 			"Updated WAF list(s) 12341234/list.",
 		},
 		NotificationKind: notifier.KindUpdateFailure,
+		Report:           nil,
 	}, resp)
 }
 
@@ -941,6 +1004,7 @@ func TestUpdateIPsDetectionFilterDoesNotChangeExplicitClear(t *testing.T) {
 		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Cleared A records for ip4.hello"}},
 		NotifierMessage:  notifier.Message{"Cleared A records for ip4.hello."},
 		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
 	}, resp)
 }
 
@@ -966,6 +1030,7 @@ func TestUpdateIPsDetectionFilterDoesNotChangeProviderUnavailable(t *testing.T)
 		HeartbeatMessage: heartbeat.Message{OK: false, Lines: []string{"Failed to detect any IPv4 addresses"}},
 		NotifierMessage:  notifier.Message{"Failed to detect any IPv4 addresses."},
 		NotificationKind: notifier.KindUpdateFailure,
+		Report:           nil,
 	}, resp)
 }
 
@@ -1011,6 +1076,7 @@ func TestUpdateIPsHostID6Preflight(t *testing.T) {
 			HeartbeatMessage: heartbeat.Message{OK: true, Lines: nil},
 			NotifierMessage:  nil,
 			NotificationKind: notifier.KindUpdate,
+			Report:           nil,
 		}, resp)
 	})
 
@@ -1045,6 +1111,7 @@ func TestUpdateIPsHostID6Preflight(t *testing.T) {
 			HeartbeatMessage: heartbeat.Message{OK: true, Lines: nil},
 			NotifierMessage:  nil,
 			NotificationKind: notifier.KindUpdate,
+			Report:           nil,
 		}, resp)
 	})
 
@@ -1084,6 +1151,7 @@ func TestUpdateIPsHostID6Preflight(t *testing.T) {
 				"Updated WAF list(s) 12341234/list.",
 			},
 			NotificationKind: notifier.KindUpdate,
+			Report:           nil,
 		}, resp)
 	})
 
@@ -1131,6 +1199,7 @@ func TestUpdateIPsHostID6Preflight(t *testing.T) {
 				"No AAAA records were changed because a hostid6 setting is incompatible with the detected IPv6 prefixes.",
 			},
 			NotificationKind: notifier.KindUpdateFailure,
+			Report:           nil,
 		}, resp)
 	})
 
@@ -1174,6 +1243,7 @@ func TestUpdateIPsHostID6Preflight(t *testing.T) {
 				"No AAAA records were changed because a hostid6 setting is incompatible with the detected IPv6 prefixes.",
 			},
 			NotificationKind: notifier.KindUpdateFailure,
+			Report:           nil,
 		}, resp)
 	})
 
@@ -1221,6 +1291,7 @@ func TestUpdateIPsHostID6Preflight(t *testing.T) {
 				"No AAAA records were changed because a hostid6 setting is incompatible with the detected IPv6 prefixes.",
 			},
 			NotificationKind: notifier.KindUpdateFailure,
+			Report:           nil,
 		}, resp)
 	})
 }
@@ -1327,6 +1398,7 @@ func TestUpdateIPsTimeouts(t *testing.T) {
 					},
 					NotifierMessage:  notifier.Message(tc.notifierMessages),
 					NotificationKind: wantKind,
+					Report:           nil,
 				}, resp)
 			})
 		})
@@ -1500,6 +1572,7 @@ func TestFinalDeleteIPs(t *testing.T) {
 				},
 				NotifierMessage:  notifier.Message(tc.notifierMessages),
 				NotificationKind: wantKind,
+				Report:           nil,
 			}, resp)
 		})
 	}
@@ -1580,8 +1653,927 @@ func TestFinalDeleteIPsTimeouts(t *testing.T) {
 					},
 					NotifierMessage:  notifier.Message(tc.notifierMessages),
 					NotificationKind: wantKind,
+					Report:           nil,
 				}, resp)
 			})
 		})
 	}
 }
+
+func TestCheckPermissions(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Domains[ipnet.IP4] = []domain.Domain{domain4_1, domain4}
+	conf.Domains[ipnet.IP6] = []domain.Domain{domain4}
+	conf.WAFLists = []api.WAFList{{AccountID: "account", Name: "list"}}
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	gomock.InOrder(
+		mockPP.EXPECT().IsShowing(pp.Info).Return(true),
+		mockPP.EXPECT().Infof(pp.EmojiPermission, "Checking the permissions of the credentials . . ."),
+		mockPP.EXPECT().Indent().Return(mockPP),
+		mockSetter.EXPECT().CheckPermissions(gomock.Any(), mockPP,
+			[]domain.Domain{domain4, domain4_1}, conf.WAFLists,
+		).Return(api.PermissionReport{Verified: true, Inspected: true}), //nolint:exhaustruct
+		mockPP.EXPECT().Infof(pp.EmojiGood, "The credentials can manage all the domains and WAF lists"),
+	)
+
+	msg := updater.CheckPermissions(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, heartbeat.Message{OK: true, Lines: []string{"Permissions confirmed"}}, msg.HeartbeatMessage)
+	require.True(t, msg.Notification().IsEmpty())
+}
+
+func TestFinalDeleteIPsDryRun(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+
+	params := api.RecordParams{
+		TTL:     api.TTLAuto,
+		Proxied: false,
+		Comment: recordComment,
+		Tags:    nil,
+	}
+	list := api.WAFList{AccountID: "account", Name: "list"}
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP4] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains[ipnet.IP4] = []domain.Domain{domain4}
+	conf.WAFLists = []api.WAFList{list}
+	conf.DryRun = true
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	innerPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	gomock.InOrder(
+		mockSetter.EXPECT().FinalDelete(gomock.Any(), mockPP, ipnet.IP4, domain4, params).Return(setter.ResponseUpdated),
+		mockSetter.EXPECT().FinalClearWAFList(gomock.Any(), mockPP, list, wafListDescription, gomock.Any()).
+			Return(setter.ResponseUpdated),
+		mockSetter.EXPECT().TakePlan().Return([]api.PlannedChange{
+			{Subject: "ip4.hello", Action: "delete the A record record1"},
+			{Subject: "account/list", Action: "delete 1.2.3.4/32"},
+		}),
+		mockPP.EXPECT().Noticef(pp.EmojiDryRun, "Dry run: %d %s planned; nothing was changed", 2, "changes"),
+		mockPP.EXPECT().Indent().Return(innerPP),
+		innerPP.EXPECT().Noticef(pp.EmojiBullet, "%s: %s", "ip4.hello", "delete the A record record1"),
+		innerPP.EXPECT().Noticef(pp.EmojiBullet, "%s: %s", "account/list", "delete 1.2.3.4/32"),
+	)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Dry run: 2 changes planned"}},
+		NotifierMessage: notifier.Message{
+			"Dry run; nothing was changed. Planned changes: " +
+				"for ip4.hello, delete the A record record1; for account/list, delete 1.2.3.4/32.",
+		},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsReport(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	params := api.RecordParams{TTL: api.TTLAuto, Proxied: false, Comment: recordComment, Tags: nil}
+	list := api.WAFList{AccountID: "account", Name: "list"}
+
+	resp := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains[ipnet.IP4] = []domain.Domain{domain4}
+			conf.WAFLists = []api.WAFList{list}
+			conf.Report = true
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetIPs(gomock.Any(), p, ipnet.IP4, domain4, []netip.Addr{ip4}, params).
+					Return(setter.ResponseUpdated),
+				s.EXPECT().SetWAFList(gomock.Any(), p, list, wafListDescription,
+					wafTargets([]netip.Addr{ip4}, nil), wafItemComment).
+					Return(setter.ResponseNoop),
+				s.EXPECT().TakeChanges().Return(setter.Changes{
+					Records: map[setter.RecordScope]setter.RecordChanges{
+						{IPFamily: ipnet.IP4, Domain: domain4}: {
+							Matched: nil,
+							Updated: []api.Record{{ID: "record1", IP: ip4, RecordParams: params}},
+							Created: nil,
+							Deleted: []api.Record{{ID: "record2", IP: netip.MustParseAddr("192.0.2.1"), RecordParams: params}},
+						},
+					},
+					WAFLists: map[api.WAFList]setter.WAFListChanges{
+						list: {Matched: []netip.Prefix{netip.MustParsePrefix("198.51.100.8/32")}, Created: nil, Deleted: nil},
+					},
+					LBPoolOrigins:    nil,
+					GatewayLocations: nil,
+					AccessGroups:     nil,
+					IPAccessRules:    nil,
+					SpectrumApps:     nil,
+					WAFListRules:     nil,
+					WorkersKV:        nil,
+					Mirrors: map[string]map[setter.RecordScope]setter.RecordChanges{
+						"the RFC 2136 server ns.hello:53": {
+							{IPFamily: ipnet.IP4, Domain: domain4}: {
+								Matched: nil,
+								Updated: nil,
+								Created: []api.Record{{ID: "198.51.100.8", IP: ip4, RecordParams: params}},
+								Deleted: nil,
+							},
+						},
+					},
+				}),
+			)
+		})
+
+	require.Equal(t, &updater.Report{
+		Kind: "update",
+		OK:   true,
+		Families: []updater.FamilyReport{
+			{Family: "IPv4", Available: true, RawEntries: []string{"198.51.100.8/32"}},
+		},
+		Domains: []updater.DomainReport{{
+			Domain:   "ip4.hello",
+			Family:   "IPv4",
+			Targets:  []string{"198.51.100.8"},
+			Matched:  []updater.RecordReport{},
+			Updated:  []updater.RecordReport{{ID: "record1", IP: "198.51.100.8"}},
+			Created:  []updater.RecordReport{},
+			Deleted:  []updater.RecordReport{{ID: "record2", IP: "192.0.2.1"}},
+			Response: "updated",
+		}},
+		WAFLists: []updater.WAFListReport{{
+			List:     "account/list",
+			Targets:  []string{"198.51.100.8/32"},
+			Matched:  []string{"198.51.100.8/32"},
+			Created:  []string{},
+			Deleted:  []string{},
+			Response: "noop",
+		}},
+		LBPoolOrigins:    []updater.LBPoolOriginReport{},
+		GatewayLocations: []updater.GatewayLocationReport{},
+		AccessGroups:     []updater.AccessGroupReport{},
+		IPAccessRules:    []updater.IPAccessRulesReport{},
+		SpectrumApps:     []updater.SpectrumAppReport{},
+		WAFListRules:     []updater.WAFListRuleReport{},
+		WorkersKV:        []updater.WorkersKVReport{},
+		MirroredDomains: []updater.MirroredDomainReport{{
+			Mirror:  "the RFC 2136 server ns.hello:53",
+			Domain:  "ip4.hello",
+			Family:  "IPv4",
+			Matched: []updater.RecordReport{},
+			Updated: []updater.RecordReport{},
+			Created: []updater.RecordReport{{ID: "198.51.100.8", IP: "198.51.100.8"}},
+			Deleted: []updater.RecordReport{},
+		}},
+	}, resp.Report)
+}
+
+func TestUpdateIPsLBPoolOrigins(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	origin1 := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	origin2 := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "lab"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true, ipnet.IP6: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.LBPoolOrigins = []api.LBPoolOrigin{origin1, origin2}
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			// Only the families detected in this round are passed on; the origins
+			// of the other family are kept by the setter.
+			targets := map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}}
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				pv[ipnet.IP6].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP6, 64).
+					Return(provider.NewUnavailableDetectionResult()),
+				p.EXPECT().Noticef(pp.EmojiError, "No valid %s addresses were detected", "IPv6"),
+				p.EXPECT().NoticeOncef(pp.MessageIP6DetectionFails, pp.EmojiHint, gomock.Any(), pp.ManualURL),
+				s.EXPECT().SetLBPoolOrigin(gomock.Any(), p, origin1, targets).Return(setter.ResponseUpdated),
+				s.EXPECT().SetLBPoolOrigin(gomock.Any(), p, origin2, targets).Return(setter.ResponseFailed),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: false, Lines: []string{
+			"Failed to detect any IPv6 addresses",
+			"Could not confirm update of LB pool origin(s) account/pool:lab",
+		}},
+		NotifierMessage: notifier.Message{
+			"Failed to detect any IPv6 addresses.",
+			"Could not confirm update of LB pool origin(s) account/pool:lab; updated account/pool:home.",
+		},
+		NotificationKind: notifier.KindUpdateFailure,
+		Report:           nil,
+	}, msg)
+}
+
+func TestFinalDeleteIPsLBPoolOrigins(t *testing.T) {
+	t.Parallel()
+
+	origin := api.LBPoolOrigin{AccountID: "account", PoolID: "pool", OriginName: "home"}
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP4] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.LBPoolOrigins = []api.LBPoolOrigin{origin}
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	mockSetter.EXPECT().FinalDisableLBPoolOrigin(gomock.Any(), mockPP, origin).Return(setter.ResponseUpdated)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Disabled LB pool origin(s) account/pool:home"}},
+		NotifierMessage:  notifier.Message{"Disabled LB pool origin(s) account/pool:home."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsGatewayLocations(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	location := api.GatewayLocation{AccountID: "account", Name: "office"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true, ipnet.IP6: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.GatewayLocations = []api.GatewayLocation{location}
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			targets := map[ipnet.Family]setter.WAFTargets{
+				ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{netip.PrefixFrom(ip4, 32)}),
+				ipnet.IP6: setter.NewUnavailableWAFTargets(),
+			}
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				pv[ipnet.IP6].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP6, 64).
+					Return(provider.NewUnavailableDetectionResult()),
+				p.EXPECT().Noticef(pp.EmojiError, "No valid %s addresses were detected", "IPv6"),
+				p.EXPECT().NoticeOncef(pp.MessageIP6DetectionFails, pp.EmojiHint, gomock.Any(), pp.ManualURL),
+				s.EXPECT().SetGatewayLocation(gomock.Any(), p, location, targets).Return(setter.ResponseFailed),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: false, Lines: []string{
+			"Failed to detect any IPv6 addresses",
+			"Could not confirm update of Gateway location(s) account/office",
+		}},
+		NotifierMessage: notifier.Message{
+			"Failed to detect any IPv6 addresses.",
+			"Could not confirm update of Gateway location(s) account/office.",
+		},
+		NotificationKind: notifier.KindUpdateFailure,
+		Report:           nil,
+	}, msg)
+}
+
+func TestFinalDeleteIPsGatewayLocations(t *testing.T) {
+	t.Parallel()
+
+	location := api.GatewayLocation{AccountID: "account", Name: "office"}
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP4] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.GatewayLocations = []api.GatewayLocation{location}
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	mockSetter.EXPECT().FinalClearGatewayLocation(gomock.Any(), mockPP, location,
+		map[ipnet.Family]bool{ipnet.IP4: true}).Return(setter.ResponseUpdated)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Cleaned Gateway location(s) account/office"}},
+		NotifierMessage:  notifier.Message{"Cleaned Gateway location(s) account/office."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsAccessGroups(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	group := api.AccessGroup{AccountID: "account", Name: "office"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.AccessGroups = []api.AccessGroup{group}
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			targets := map[ipnet.Family]setter.WAFTargets{
+				ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{netip.PrefixFrom(ip4, 32)}),
+			}
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetAccessGroup(gomock.Any(), p, group, targets).Return(setter.ResponseUpdated),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated Access group(s) account/office"}},
+		NotifierMessage:  notifier.Message{"Updated Access group(s) account/office."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+}
+
+func TestFinalDeleteIPsAccessGroups(t *testing.T) {
+	t.Parallel()
+
+	group := api.AccessGroup{AccountID: "account", Name: "office"}
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP6] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.AccessGroups = []api.AccessGroup{group}
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	mockSetter.EXPECT().FinalClearAccessGroup(gomock.Any(), mockPP, group,
+		map[ipnet.Family]bool{ipnet.IP6: true}).Return(setter.ResponseFailed)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: false, Lines: []string{"Could not confirm cleanup of Access group(s) account/office"}},
+		NotifierMessage:  notifier.Message{"Could not confirm cleanup of Access group(s) account/office."},
+		NotificationKind: notifier.KindCleanupFailure,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsIPAccessRules(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	set := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeZone, ScopeID: "zone", Mode: api.IPAccessRuleModeBlock}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.IPAccessRules = []api.IPAccessRuleSet{set}
+			conf.IPAccessRuleNotes = "ddns"
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			targets := map[ipnet.Family]setter.WAFTargets{
+				ipnet.IP4: setter.NewAvailableWAFTargets([]netip.Prefix{netip.PrefixFrom(ip4, 32)}),
+			}
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetIPAccessRules(gomock.Any(), p, set, targets, "ddns").Return(setter.ResponseUpdated),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated IP access rules zone/zone:block"}},
+		NotifierMessage:  notifier.Message{"Updated IP access rules zone/zone:block."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+}
+
+func TestFinalDeleteIPsIPAccessRules(t *testing.T) {
+	t.Parallel()
+
+	set := api.IPAccessRuleSet{Scope: api.IPAccessRuleScopeAccount, ScopeID: "account", Mode: api.IPAccessRuleModeAllow}
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP6] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.IPAccessRules = []api.IPAccessRuleSet{set}
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	mockSetter.EXPECT().FinalClearIPAccessRules(gomock.Any(), mockPP, set,
+		map[ipnet.Family]bool{ipnet.IP6: true}).Return(setter.ResponseUpdated)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Cleaned IP access rules account/account:allow"}},
+		NotifierMessage:  notifier.Message{"Cleaned IP access rules account/account:allow."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsSpectrumApps(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	app := api.SpectrumApp{ZoneID: "zone", AppID: "app"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.SpectrumApps = []api.SpectrumApp{app}
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetSpectrumApp(gomock.Any(), p, app,
+					map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}}).Return(setter.ResponseUpdated),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated Spectrum app(s) zone/app"}},
+		NotifierMessage:  notifier.Message{"Updated Spectrum app(s) zone/app."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsWAFListRule(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	list := api.WAFList{AccountID: "account", Name: "list"}
+	rule := api.WAFListRule{ZoneID: "zone", Action: "skip"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.WAFLists = []api.WAFList{list}
+			conf.WAFListRule = rule
+			conf.WAFListRuleExpression = "ip.src in $list"
+			conf.WAFListRuleDescription = "ddns"
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetWAFList(gomock.Any(), p, list, wafListDescription,
+					wafTargets([]netip.Addr{ip4}, nil), wafItemComment).Return(setter.ResponseNoop),
+				s.EXPECT().SetWAFListRule(gomock.Any(), p, rule, "ip.src in $list", "ddns").Return(setter.ResponseUpdated),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated WAF custom rule(s) zone:skip"}},
+		NotifierMessage:  notifier.Message{"Updated WAF custom rule(s) zone:skip."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+}
+
+func TestFinalDeleteIPsWAFListRule(t *testing.T) {
+	t.Parallel()
+
+	list := api.WAFList{AccountID: "account", Name: "list"}
+	rule := api.WAFListRule{ZoneID: "zone", Action: "block"}
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP6] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.WAFLists = []api.WAFList{list}
+	conf.WAFListRule = rule
+	conf.WAFListRuleDescription = "ddns"
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	gomock.InOrder(
+		mockSetter.EXPECT().FinalDeleteWAFListRule(gomock.Any(), mockPP, rule, "ddns").Return(setter.ResponseUpdated),
+		mockSetter.EXPECT().FinalClearWAFList(gomock.Any(), mockPP, list, wafListDescription, gomock.Any()).Return(setter.ResponseNoop),
+	)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Deleted WAF custom rule(s) zone:block"}},
+		NotifierMessage:  notifier.Message{"Deleted WAF custom rule(s) zone:block."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsWorkersKV(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	key := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.WorkersKV = key
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetWorkersKV(gomock.Any(), p, key,
+					map[ipnet.Family][]netip.Addr{ipnet.IP4: {ip4}}, gomock.Any()).Return(setter.ResponseUpdated),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated Workers KV key(s) account/namespace:home"}},
+		NotifierMessage:  notifier.Message{"Updated Workers KV key(s) account/namespace:home."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsProviderPending(t *testing.T) {
+	t.Parallel()
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains[ipnet.IP4] = []domain.Domain{domain4}
+			conf.WAFLists = []api.WAFList{{AccountID: "account", Name: "list"}}
+		},
+		func(p *mocks.MockPP, pv mockProviders, _ *mocks.MockSetter) {
+			pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+				Return(provider.NewPendingDetectionResult())
+		})
+
+	require.True(t, msg.HeartbeatMessage.OK)
+	require.True(t, msg.HeartbeatMessage.IsEmpty())
+	require.True(t, msg.NotifierMessage.IsEmpty())
+}
+
+func TestUpdateIPsWorkersKVSkippedWhenDetectionFails(t *testing.T) {
+	t.Parallel()
+
+	key := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.WorkersKV = key
+		},
+		func(p *mocks.MockPP, pv mockProviders, _ *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(provider.NewUnavailableDetectionResult()),
+				p.EXPECT().Noticef(pp.EmojiError, "No valid %s addresses were detected", "IPv4"),
+				p.EXPECT().NoticeOncef(pp.MessageIP4DetectionFails, pp.EmojiHint, "If your network does not support IPv4, you can stop managing it with IP4_PROVIDER=none"),
+			)
+		})
+
+	require.False(t, msg.HeartbeatMessage.OK)
+}
+
+func TestUpdateIPsWorkersKVSkippedWhenDNSFails(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	domain4 := domain.FQDN("ip4.hello")
+	key := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{ipnet.IP4: {domain4}}
+			conf.WorkersKV = key
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				s.EXPECT().SetIPs(gomock.Any(), p, ipnet.IP4, domain4, []netip.Addr{ip4}, gomock.Any()).
+					Return(setter.ResponseFailed),
+			)
+		})
+
+	// The key is left alone so that its readers never see addresses missing from DNS.
+	require.False(t, msg.HeartbeatMessage.OK)
+}
+
+func TestFinalDeleteIPsWorkersKV(t *testing.T) {
+	t.Parallel()
+
+	key := api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "home"}
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP4] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.WorkersKV = key
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	mockSetter.EXPECT().FinalDeleteWorkersKV(gomock.Any(), mockPP, key).Return(setter.ResponseUpdated)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Deleted Workers KV key(s) account/namespace:home"}},
+		NotifierMessage:  notifier.Message{"Deleted Workers KV key(s) account/namespace:home."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func localResolverConfig(path string, p provider.Provider) *config.LocalResolverConfig {
+	return &config.LocalResolverConfig{
+		Sink:     localdns.Sink{Path: path, Format: localdns.FormatHosts, ReloadCommand: nil},
+		Provider: map[ipnet.Family]provider.Provider{ipnet.IP4: p},
+		Domains:  []domain.Domain{domain.FQDN("app.example.org")},
+	}
+}
+
+func TestUpdateIPsLocalResolver(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	internal := netip.MustParseAddr("192.168.1.2")
+	path := filepath.Join(t.TempDir(), "hosts")
+	mockCtrl := gomock.NewController(t)
+	local := mocks.NewMockProvider(mockCtrl)
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.LocalResolver = localResolverConfig(path, local)
+		},
+		func(p *mocks.MockPP, pv mockProviders, _ *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				local.EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{internal})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected internal %s %s for the local resolver: %s",
+					"IPv4", "address", "192.168.1.2"),
+				p.EXPECT().Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+					1, "entry", pp.QuoteIfUnsafeInSentence(path)),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated local resolver file(s) " + path}},
+		NotifierMessage:  notifier.Message{"Updated local resolver file(s) " + path + "."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "192.168.1.2\tapp.example.org\n")
+}
+
+func TestUpdateIPsLocalResolverDetectionFails(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	path := filepath.Join(t.TempDir(), "hosts")
+	mockCtrl := gomock.NewController(t)
+	local := mocks.NewMockProvider(mockCtrl)
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.LocalResolver = localResolverConfig(path, local)
+		},
+		func(p *mocks.MockPP, pv mockProviders, _ *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				local.EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(provider.NewUnavailableDetectionResult()),
+				p.EXPECT().Noticef(pp.EmojiError, "No valid internal %s addresses were detected for the local resolver",
+					"IPv4"),
+			)
+		})
+
+	require.False(t, msg.HeartbeatMessage.OK)
+	require.Equal(t, []string{"Could not confirm update of local resolver file(s) " + path}, msg.HeartbeatMessage.Lines)
+	require.NoFileExists(t, path)
+}
+
+func TestUpdateIPsLocalResolverDryRun(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	internal := netip.MustParseAddr("192.168.1.2")
+	path := filepath.Join(t.TempDir(), "hosts")
+	mockCtrl := gomock.NewController(t)
+	local := mocks.NewMockProvider(mockCtrl)
+	innerPP := mocks.NewMockPP(mockCtrl)
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.LocalResolver = localResolverConfig(path, local)
+			conf.DryRun = true
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				local.EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{internal})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected internal %s %s for the local resolver: %s",
+					"IPv4", "address", "192.168.1.2"),
+				s.EXPECT().TakePlan().Return(nil),
+				p.EXPECT().Noticef(pp.EmojiDryRun, "Dry run: %d %s planned; nothing was changed", 1, "change"),
+				p.EXPECT().Indent().Return(innerPP),
+				innerPP.EXPECT().Noticef(pp.EmojiBullet, "%s: %s", "the local resolver file "+path, "write 1 entry"),
+			)
+		})
+
+	require.Equal(t, heartbeat.Message{OK: true, Lines: []string{"Dry run: 1 change planned"}}, msg.HeartbeatMessage)
+	require.NoFileExists(t, path)
+}
+
+func TestFinalDeleteIPsLocalResolver(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(path, []byte("192.168.1.2\tapp.example.org\n"), 0o600))
+	mockCtrl := gomock.NewController(t)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP4] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.LocalResolver = localResolverConfig(path, mocks.NewMockProvider(mockCtrl))
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	mockPP.EXPECT().Noticef(pp.EmojiUpdate, "Wrote %d %s to the local resolver file %s",
+		0, "entries", pp.QuoteIfUnsafeInSentence(path))
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Cleaned local resolver file(s) " + path}},
+		NotifierMessage:  notifier.Message{"Cleaned local resolver file(s) " + path + "."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(localdns.Render(localdns.FormatHosts, localdns.Targets{})), string(content))
+}
+
+var homePrefixes = nftset.Set{Family: "inet", Table: "filter", Name: "home_prefixes"} //nolint:gochecknoglobals
+
+func homePrefixesListing(elems string) []byte {
+	return []byte(`{"nftables": [{"set": {"family": "inet", "name": "home_prefixes", "table": "filter", "type": "ipv4_addr", "flags": ["interval"], "elem": [` + elems + `]}}]}`)
+}
+
+func TestUpdateIPsNFTablesSets(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	mockCtrl := gomock.NewController(t)
+	runner := mocks.NewMockRunner(mockCtrl)
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.NFTablesSets = []nftset.Set{homePrefixes}
+			conf.NFTablesRunner = runner
+		},
+		func(p *mocks.MockPP, pv mockProviders, _ *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				runner.EXPECT().Run(gomock.Any(), []string{"-j", "list", "set", "inet", "filter", "home_prefixes"}, nil).
+					Return(homePrefixesListing(`"198.51.100.1"`), nil),
+				runner.EXPECT().Run(gomock.Any(), []string{"-j", "-f", "-"},
+					[]byte(`{"nftables":[{"delete":{"element":{"elem":["198.51.100.1"],"family":"inet","name":"home_prefixes","table":"filter"}}},{"add":{"element":{"elem":["198.51.100.8"],"family":"inet","name":"home_prefixes","table":"filter"}}}]}`)).
+					Return(nil, nil),
+				p.EXPECT().Noticef(pp.EmojiDeletion, "Deleted %s from the nftables set %s",
+					"198.51.100.1/32", "inet filter home_prefixes"),
+				p.EXPECT().Noticef(pp.EmojiCreation, "Added %s to the nftables set %s",
+					"198.51.100.8/32", "inet filter home_prefixes"),
+			)
+		})
+
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Updated nftables set(s) inet filter home_prefixes"}},
+		NotifierMessage:  notifier.Message{"Updated nftables set(s) inet filter home_prefixes."},
+		NotificationKind: notifier.KindUpdate,
+		Report:           nil,
+	}, msg)
+}
+
+func TestUpdateIPsNFTablesSetsDryRun(t *testing.T) {
+	t.Parallel()
+
+	ip4 := netip.MustParseAddr("198.51.100.8")
+	mockCtrl := gomock.NewController(t)
+	runner := mocks.NewMockRunner(mockCtrl)
+	innerPP := mocks.NewMockPP(mockCtrl)
+
+	msg := runConfiguredUpdateIPsScenario(t, providerEnablers{ipnet.IP4: true},
+		func(conf *config.UpdateConfig) {
+			conf.Domains = map[ipnet.Family][]domain.Domain{}
+			conf.NFTablesSets = []nftset.Set{homePrefixes}
+			conf.NFTablesRunner = runner
+			conf.DryRun = true
+		},
+		func(p *mocks.MockPP, pv mockProviders, s *mocks.MockSetter) {
+			gomock.InOrder(
+				pv[ipnet.IP4].EXPECT().GetRawData(gomock.Any(), p, ipnet.IP4, 32).
+					Return(detectionResult(ipnet.IP4, []netip.Addr{ip4})),
+				p.EXPECT().Infof(pp.EmojiInternet, "Detected %s address: %s", "IPv4", "198.51.100.8"),
+				p.EXPECT().Suppress(pp.MessageIP4DetectionFails),
+				runner.EXPECT().Run(gomock.Any(), []string{"-j", "list", "set", "inet", "filter", "home_prefixes"}, nil).
+					Return(homePrefixesListing(""), nil),
+				s.EXPECT().TakePlan().Return(nil),
+				p.EXPECT().Noticef(pp.EmojiDryRun, "Dry run: %d %s planned; nothing was changed", 1, "change"),
+				p.EXPECT().Indent().Return(innerPP),
+				innerPP.EXPECT().Noticef(pp.EmojiBullet, "%s: %s",
+					"the nftables set inet filter home_prefixes", "add 198.51.100.8/32"),
+			)
+		})
+
+	require.Equal(t, heartbeat.Message{OK: true, Lines: []string{"Dry run: 1 change planned"}}, msg.HeartbeatMessage)
+}
+
+func TestFinalDeleteIPsNFTablesSets(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	runner := mocks.NewMockRunner(mockCtrl)
+
+	conf := initUpdateConfig()
+	conf.Provider[ipnet.IP4] = mocks.NewMockProvider(mockCtrl)
+	conf.Domains = map[ipnet.Family][]domain.Domain{}
+	conf.NFTablesSets = []nftset.Set{homePrefixes}
+	conf.NFTablesRunner = runner
+
+	mockPP := mocks.NewMockPP(mockCtrl)
+	mockSetter := mocks.NewMockSetter(mockCtrl)
+	gomock.InOrder(
+		runner.EXPECT().Run(gomock.Any(), []string{"-j", "list", "set", "inet", "filter", "home_prefixes"}, nil).
+			Return(homePrefixesListing(`{"prefix": {"addr": "198.51.100.0", "len": 24}}`), nil),
+		runner.EXPECT().Run(gomock.Any(), []string{"-j", "-f", "-"},
+			[]byte(`{"nftables":[{"delete":{"element":{"elem":[{"prefix":{"addr":"198.51.100.0","len":24}}],"family":"inet","name":"home_prefixes","table":"filter"}}}]}`)).
+			Return(nil, nil),
+		mockPP.EXPECT().Noticef(pp.EmojiDeletion, "Deleted %s from the nftables set %s",
+			"198.51.100.0/24", "inet filter home_prefixes"),
+	)
+
+	msg := updater.FinalDeleteIPs(context.Background(), mockPP, conf, mockSetter)
+	require.Equal(t, updater.Message{
+		HeartbeatMessage: heartbeat.Message{OK: true, Lines: []string{"Cleaned nftables set(s) inet filter home_prefixes"}},
+		NotifierMessage:  notifier.Message{"Cleaned nftables set(s) inet filter home_prefixes."},
+		NotificationKind: notifier.KindCleanup,
+		Report:           nil,
+	}, msg)
+}
+
+func TestRoundTimeout(t *testing.T) {
+	t.Parallel()
+
+	conf := initUpdateConfig()
+	conf.DetectionTimeout = 5 * time.Second
+	conf.UpdateTimeout = 30 * time.Second
+	require.Equal(t, 10*time.Second, updater.RoundTimeout(conf))
+
+	conf.Domains = map[ipnet.Family][]domain.Domain{
+		ipnet.IP4: {domain4, domain4_1},
+		ipnet.IP6: {domain6},
+	}
+	conf.WAFLists = []api.WAFList{{AccountID: "account", Name: "list"}}
+	conf.GatewayLocations = []api.GatewayLocation{{AccountID: "account", Name: "office"}}
+	conf.WAFListRule = api.WAFListRule{ZoneID: "zone", Action: "block"}
+	conf.WorkersKV = api.WorkersKVKey{AccountID: "account", NamespaceID: "namespace", Key: "key"}
+	require.Equal(t, 10*time.Second+7*30*time.Second, updater.RoundTimeout(conf))
+}